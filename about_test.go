@@ -0,0 +1,47 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+func TestAboutInfoText(t *testing.T) {
+	info := aboutInfo{
+		version:    "1.2.3",
+		revision:   "abc123",
+		modified:   true,
+		mpvVersion: "mpv 0.37.0",
+		host:       "https://music.example.com",
+	}
+
+	text := info.text()
+	for _, expected := range []string{"1.2.3", "abc123 (modified)", "mpv 0.37.0", "https://music.example.com", "Loading"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected %q in %q", expected, text)
+		}
+	}
+
+	info.server = &subsonic.SubsonicResponse{Type: "navidrome", ServerVersion: "0.52.0", Version: "1.16.1", OpenSubsonic: true}
+	info.extensions = []subsonic.OpenSubsonicExtension{{Name: "songLyrics", Versions: []int{1}}, {Name: "transcodeOffset", Versions: []int{1, 2}}}
+	text = info.text()
+	for _, expected := range []string{"navidrome", "0.52.0", "1.16.1", "songLyrics (1)", "transcodeOffset (1, 2)"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected %q in %q", expected, text)
+		}
+	}
+	if strings.Contains(text, "Loading") {
+		t.Errorf("expected no loading text in %q", text)
+	}
+
+	info.server = nil
+	info.serverErr = errors.New("connection refused")
+	if text = info.text(); !strings.Contains(text, "connection refused") {
+		t.Errorf("expected the error in %q", text)
+	}
+}
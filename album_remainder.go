@@ -0,0 +1,50 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// albumRemainder returns the songs of an album that follow the song with the
+// ID, in album order. It's empty if the song isn't on the album.
+func albumRemainder(songs []subsonic.SubsonicEntity, id string) []subsonic.SubsonicEntity {
+	for i, song := range songs {
+		if song.Id == id {
+			return songs[i+1:]
+		}
+	}
+	return nil
+}
+
+// queueAlbumRemainder inserts the rest of the song's album after it, see
+// client.auto-queue-album. Songs whose album can't be fetched, e.g. without
+// album tags, are left alone.
+func (ui *Ui) queueAlbumRemainder(song mpvplayer.QueueItem, index int) {
+	if song.AlbumId == "" {
+		return
+	}
+	response, err := ui.connection.GetAlbum(song.AlbumId)
+	if err != nil {
+		ui.logger.PrintError("queueAlbumRemainder", err)
+		return
+	}
+	remainder := albumRemainder(response.Album.Song, song.Id)
+	if len(remainder) == 0 {
+		return
+	}
+
+	album := stringOr(response.Album.Name, response.Album.Title)
+	ui.startQueueInsert(index + 1)
+	ui.setQueueSource(queueSourceOf("album", album))
+	for i := range remainder {
+		ui.addSongToQueue(&remainder[i])
+	}
+	if report := ui.finishQueueAdd(); report.skipped == 0 && report.added > 0 {
+		ui.showNotice(fmt.Sprintf("Queued the %d following songs of %s", report.added, album))
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlbumRemainder(t *testing.T) {
+	songs := []subsonic.SubsonicEntity{{Id: "1"}, {Id: "2"}, {Id: "3"}}
+	assert.Equal(t, []subsonic.SubsonicEntity{{Id: "2"}, {Id: "3"}}, albumRemainder(songs, "1"))
+	assert.Empty(t, albumRemainder(songs, "3"))
+	// not on the album
+	assert.Empty(t, albumRemainder(songs, "4"))
+}
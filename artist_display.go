@@ -0,0 +1,46 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"strings"
+)
+
+// ArtistDisplay decides which artist is shown for songs in lists and the
+// status bar.
+type ArtistDisplay string
+
+const (
+	// ArtistDisplayTrack shows the song's own artist
+	ArtistDisplayTrack ArtistDisplay = "track"
+	// ArtistDisplayAlbum shows the album artist, e.g. "Various Artists"
+	ArtistDisplayAlbum ArtistDisplay = "album"
+	// ArtistDisplayAlbumFeat shows "album artist feat. track artist" when
+	// they differ
+	ArtistDisplayAlbumFeat ArtistDisplay = "album-feat"
+)
+
+// Artist returns the artist to show for a song by artist on an album by
+// albumArtist. Either may be empty, then the other one is used.
+func (d ArtistDisplay) Artist(artist, albumArtist string) string {
+	if albumArtist == "" {
+		return artist
+	}
+	if artist == "" {
+		return albumArtist
+	}
+
+	switch d {
+	case ArtistDisplayAlbum:
+		return albumArtist
+	case ArtistDisplayAlbumFeat:
+		// the track artist often already credits the album artist
+		if strings.HasPrefix(strings.ToLower(artist), strings.ToLower(albumArtist)) {
+			return artist
+		}
+		return albumArtist + " feat. " + artist
+	}
+	// unset is ArtistDisplayTrack
+	return artist
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArtistDisplay(t *testing.T) {
+	assert.Equal(t, "B", ArtistDisplay("").Artist("B", "Various Artists"))
+	assert.Equal(t, "B", ArtistDisplayTrack.Artist("B", "Various Artists"))
+	assert.Equal(t, "Various Artists", ArtistDisplayAlbum.Artist("B", "Various Artists"))
+	assert.Equal(t, "Various Artists feat. B", ArtistDisplayAlbumFeat.Artist("B", "Various Artists"))
+
+	// the track artist already names the album artist
+	assert.Equal(t, "A", ArtistDisplayAlbumFeat.Artist("A", "A"))
+	assert.Equal(t, "A feat. B", ArtistDisplayAlbumFeat.Artist("A feat. B", "A"))
+
+	// missing artists fall back to the other one
+	assert.Equal(t, "B", ArtistDisplayAlbum.Artist("B", ""))
+	assert.Equal(t, "A", ArtistDisplayTrack.Artist("", "A"))
+}
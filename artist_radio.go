@@ -0,0 +1,273 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
+)
+
+const (
+	// songs queued per radio batch
+	radioBatchSize = 20
+	// a new batch is queued once fewer songs than this are left to play
+	radioRefillBelow = 5
+	// percent of seed artist songs, see client.radio-seed-ratio
+	defaultRadioSeedRatio = 50
+	// songs played last that the radio doesn't queue again
+	radioRecentPlays = 100
+)
+
+// radioSeed is what a radio is started from, an artist or an album.
+type radioSeed struct {
+	// shown in notices and as the queue source
+	name string
+	// the seed artist, for getTopSongs
+	artist string
+	// ID3 artist ID for getSimilarSongs2, or any ID for getSimilarSongs
+	// without id3
+	similarId string
+	id3       bool
+}
+
+// artistRadio keeps the queue filled with songs of the seed artist and of
+// similar artists. Must only be used from the gui goroutine.
+type artistRadio struct {
+	// nil while no radio runs
+	seed *radioSeed
+	// percent of songs by the seed artist
+	ratio    int
+	fetching bool
+	// songs queued by this radio, they aren't queued again
+	queued map[string]struct{}
+	// batches of a stopped or replaced radio are dropped
+	seq int
+}
+
+func loadArtistRadio() artistRadio {
+	ratio := defaultRadioSeedRatio
+	if viper.IsSet("client.radio-seed-ratio") {
+		ratio = viper.GetInt("client.radio-seed-ratio")
+	}
+	return artistRadio{ratio: ratio}
+}
+
+// radioPools are the songs a radio batch is picked from.
+type radioPools struct {
+	// songs by the seed artist
+	seed []subsonic.SubsonicEntity
+	// songs by similar artists
+	discovery []subsonic.SubsonicEntity
+	// fill in when both run out
+	random []subsonic.SubsonicEntity
+}
+
+// splitByArtist returns the songs by artist and the others.
+func splitByArtist(songs []subsonic.SubsonicEntity, artist string) (by, others []subsonic.SubsonicEntity) {
+	for _, song := range songs {
+		if strings.EqualFold(song.Artist, artist) {
+			by = append(by, song)
+		} else {
+			others = append(others, song)
+		}
+	}
+	return by, others
+}
+
+// fetchRadioPools fetches the top songs of the seed artist, songs of similar
+// artists and random songs. Similar songs by the seed artist count as seed
+// songs, so the radio works without top songs too. The requests are
+// uncached, so it may be called from any goroutine.
+func fetchRadioPools(connection *subsonic.SubsonicConnection, seed radioSeed, size int, filter contentFilter) (radioPools, error) {
+	var pools radioPools
+
+	if seed.artist != "" {
+		// servers without last.fm access may fail this, similar songs by
+		// the seed artist fill in
+		if response, err := connection.GetTopSongs(seed.artist, size); err == nil {
+			pools.seed = filter.filterSongs(response.TopSongs.Song)
+		}
+	}
+
+	var similar []subsonic.SubsonicEntity
+	if seed.id3 {
+		response, err := connection.GetSimilarSongs2(seed.similarId, size*2)
+		if err != nil {
+			return pools, err
+		}
+		similar = response.SimilarSongs2.Song
+	} else {
+		response, err := connection.GetRandomSongs(seed.similarId, "similar")
+		if err != nil {
+			return pools, err
+		}
+		similar = response.SimilarSongs.Song
+	}
+	bySeed, others := splitByArtist(filter.filterSongs(similar), seed.artist)
+	pools.seed = append(pools.seed, bySeed...)
+	pools.discovery = others
+
+	response, err := connection.GetFilteredRandomSongs("", 0, 0, size)
+	if err != nil {
+		return pools, err
+	}
+	pools.random = filter.filterSongs(response.RandomSongs.Song)
+
+	// top songs come most popular first, don't always start with them
+	rand.Shuffle(len(pools.seed), func(i, j int) {
+		pools.seed[i], pools.seed[j] = pools.seed[j], pools.seed[i]
+	})
+	rand.Shuffle(len(pools.discovery), func(i, j int) {
+		pools.discovery[i], pools.discovery[j] = pools.discovery[j], pools.discovery[i]
+	})
+	return pools, nil
+}
+
+// mixRadioSongs picks up to size songs, ratio percent of them from the seed
+// pool and the rest from the discovery pool. When one runs out, the other
+// fills in, then the random songs. Songs in skip and ones picked already are
+// left out.
+func mixRadioSongs(pools radioPools, ratio, size int, skip map[string]struct{}) []subsonic.SubsonicEntity {
+	picked := map[string]struct{}{}
+	next := func(pool *[]subsonic.SubsonicEntity) (subsonic.SubsonicEntity, bool) {
+		for len(*pool) > 0 {
+			song := (*pool)[0]
+			*pool = (*pool)[1:]
+			if song.Id == "" || song.IsDirectory {
+				continue
+			}
+			if _, ok := skip[song.Id]; ok {
+				continue
+			}
+			if _, ok := picked[song.Id]; ok {
+				continue
+			}
+			picked[song.Id] = struct{}{}
+			return song, true
+		}
+		return subsonic.SubsonicEntity{}, false
+	}
+
+	var songs []subsonic.SubsonicEntity
+	seedSongs := 0
+	for len(songs) < size {
+		first, second := &pools.seed, &pools.discovery
+		// keep the seed songs at ratio percent of the songs picked
+		wantSeed := seedSongs*100 < ratio*(len(songs)+1)
+		if !wantSeed {
+			first, second = second, first
+		}
+
+		song, ok := next(first)
+		fromSeed := ok && wantSeed
+		if !ok {
+			song, ok = next(second)
+			fromSeed = ok && !wantSeed
+		}
+		if !ok {
+			song, ok = next(&pools.random)
+		}
+		if !ok {
+			break
+		}
+		if fromSeed {
+			seedSongs++
+		}
+		songs = append(songs, song)
+	}
+	return songs
+}
+
+// startRadio replaces the running radio with one seeded from seed. Its first
+// songs play right away.
+func (ui *Ui) startRadio(seed radioSeed) {
+	ui.radio.seq++
+	ui.radio.seed = &seed
+	ui.radio.fetching = false
+	ui.radio.queued = map[string]struct{}{}
+	ui.showNotice("Starting radio from " + seed.name)
+	ui.fetchRadioBatch(queuePlayNow)
+}
+
+// stopRadio stops queueing radio songs, the queued ones stay.
+func (ui *Ui) stopRadio() {
+	if ui.radio.seed == nil {
+		return
+	}
+	ui.radio.seq++
+	ui.radio.seed = nil
+	ui.radio.fetching = false
+	ui.showNotice("Radio stopped")
+}
+
+// extendRadio queues the next batch of the radio once the queue runs low.
+// It's called when a song starts or playback stops.
+func (ui *Ui) extendRadio() {
+	if ui.radio.seed == nil || ui.radio.fetching {
+		return
+	}
+	if len(ui.player.GetQueueCopy())-1 >= radioRefillBelow {
+		return
+	}
+	ui.fetchRadioBatch(queueAppend)
+}
+
+// radioSkipIds returns the songs the next batch leaves out: the ones in the
+// queue, the ones played last and the ones the radio queued before.
+func (ui *Ui) radioSkipIds() map[string]struct{} {
+	skip := map[string]struct{}{}
+	for id := range ui.radio.queued {
+		skip[id] = struct{}{}
+	}
+	for _, item := range ui.player.GetQueueCopy() {
+		skip[item.Id] = struct{}{}
+	}
+	history := ui.sessionStats.history
+	for _, entry := range history[max(0, len(history)-radioRecentPlays):] {
+		skip[entry.song.Id] = struct{}{}
+	}
+	return skip
+}
+
+// fetchRadioBatch fetches the next songs of the radio in the background and
+// queues them with mode. The radio stops if there are no new songs.
+func (ui *Ui) fetchRadioBatch(mode queueMode) {
+	seed := *ui.radio.seed
+	seq := ui.radio.seq
+	ratio := ui.radio.ratio
+	skip := ui.radioSkipIds()
+	filter := ui.contentFilter
+	ui.radio.fetching = true
+
+	go func() {
+		pools, err := fetchRadioPools(ui.connection, seed, radioBatchSize, filter)
+		songs := mixRadioSongs(pools, ratio, radioBatchSize, skip)
+		ui.app.QueueUpdateDraw(func() {
+			if seq != ui.radio.seq {
+				return
+			}
+			ui.radio.fetching = false
+			if err != nil {
+				ui.logger.PrintError("fetchRadioBatch", err)
+			}
+			if len(songs) == 0 {
+				ui.radio.seed = nil
+				ui.showNotice(fmt.Sprintf("Radio stopped, no new songs for %s", seed.name))
+				return
+			}
+
+			ui.startQueueAdd(mode)
+			ui.setQueueSource(queueSourceOf("radio", seed.name))
+			for i := range songs {
+				ui.radio.queued[songs[i].Id] = struct{}{}
+				ui.addSongToQueue(&songs[i])
+			}
+			ui.finishQueueAdd()
+		})
+	}()
+}
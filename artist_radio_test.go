@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func radioSongs(ids ...string) []subsonic.SubsonicEntity {
+	songs := make([]subsonic.SubsonicEntity, len(ids))
+	for i, id := range ids {
+		songs[i] = subsonic.SubsonicEntity{Id: id}
+	}
+	return songs
+}
+
+func radioIds(songs []subsonic.SubsonicEntity) []string {
+	ids := make([]string, len(songs))
+	for i, song := range songs {
+		ids[i] = song.Id
+	}
+	return ids
+}
+
+func TestMixRadioSongsRatio(t *testing.T) {
+	pools := radioPools{
+		seed:      radioSongs("s1", "s2", "s3", "s4"),
+		discovery: radioSongs("d1", "d2", "d3", "d4"),
+	}
+
+	assert.Equal(t, []string{"s1", "d1", "s2", "d2"}, radioIds(mixRadioSongs(pools, 50, 4, nil)))
+	assert.Equal(t, []string{"s1", "d1", "d2", "d3"}, radioIds(mixRadioSongs(pools, 25, 4, nil)))
+	assert.Equal(t, []string{"d1", "d2", "d3"}, radioIds(mixRadioSongs(pools, 0, 3, nil)))
+	assert.Equal(t, []string{"s1", "s2", "s3"}, radioIds(mixRadioSongs(pools, 100, 3, nil)))
+}
+
+func TestMixRadioSongsFillsIn(t *testing.T) {
+	pools := radioPools{
+		seed:      radioSongs("s1"),
+		discovery: radioSongs("d1", "d2"),
+		random:    radioSongs("r1", "r2", "r3"),
+	}
+
+	// the discovery pool fills in for the seed, then the random songs
+	assert.Equal(t, []string{"s1", "d1", "d2", "r1", "r2"}, radioIds(mixRadioSongs(pools, 50, 5, nil)))
+	// not enough songs
+	assert.Len(t, mixRadioSongs(pools, 50, 10, nil), 6)
+}
+
+func TestMixRadioSongsDeduplicates(t *testing.T) {
+	pools := radioPools{
+		seed:      radioSongs("s1", "s2", "", "s3"),
+		discovery: radioSongs("s2", "d1", "d2"),
+		random:    radioSongs("d1", "r1"),
+	}
+	skip := map[string]struct{}{"s1": {}, "d2": {}}
+
+	assert.Equal(t, []string{"s2", "d1", "s3", "r1"}, radioIds(mixRadioSongs(pools, 50, 10, skip)))
+}
+
+func TestSplitByArtist(t *testing.T) {
+	songs := []subsonic.SubsonicEntity{
+		{Id: "1", Artist: "Seed"},
+		{Id: "2", Artist: "Other"},
+		{Id: "3", Artist: "seed"},
+	}
+
+	by, others := splitByArtist(songs, "Seed")
+	assert.Equal(t, []string{"1", "3"}, radioIds(by))
+	assert.Equal(t, []string{"2"}, radioIds(others))
+}
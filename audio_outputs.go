@@ -0,0 +1,112 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spf13/viper"
+)
+
+// the latency offset of an output may delay it by up to this much
+const maxOutputOffsetMs = 5000
+
+// parseAudioOutputs decodes the [[outputs]] config tables, each with an mpv
+// audio device and an optional latency offset.
+func parseAudioOutputs(value interface{}) ([]mpvplayer.AudioOutput, error) {
+	tables, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list of [[outputs]] tables, got %v", value)
+	}
+
+	var outputs []mpvplayer.AudioOutput
+	devices := map[string]bool{}
+	for i, item := range tables {
+		table, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("entry %d: expected a table, got %v", i+1, item)
+		}
+
+		var output mpvplayer.AudioOutput
+		for key, v := range table {
+			var err error
+			switch key {
+			case "device":
+				var isStr bool
+				if output.Device, isStr = v.(string); !isStr {
+					err = fmt.Errorf("expected a string, got %v", v)
+				}
+			case "offset-ms":
+				var offset int
+				if offset, err = toInt(v); err == nil {
+					if offset < 0 || offset > maxOutputOffsetMs {
+						err = fmt.Errorf("must be between 0 and %d", maxOutputOffsetMs)
+					}
+					output.Offset = time.Duration(offset) * time.Millisecond
+				}
+			default:
+				err = errors.New("unknown property")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: %s: %v", i+1, key, err)
+			}
+		}
+
+		if output.Device == "" {
+			return nil, fmt.Errorf("entry %d: device is required", i+1)
+		}
+		if devices[output.Device] {
+			return nil, fmt.Errorf("entry %d: device %q is used twice", i+1, output.Device)
+		}
+		devices[output.Device] = true
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+func isAudioOutputList(value interface{}) error {
+	_, err := parseAudioOutputs(value)
+	return err
+}
+
+// loadAudioOutputs returns the outputs from the config. Errors have already
+// been reported by validateConfig.
+func loadAudioOutputs() []mpvplayer.AudioOutput {
+	if !viper.IsSet("outputs") {
+		return nil
+	}
+	outputs, _ := parseAudioOutputs(viper.Get("outputs"))
+	return outputs
+}
+
+// availableOutputs drops the outputs whose device mpv doesn't list, so a
+// missing speaker doesn't silence the others. If mpv can't list devices,
+// only the first output is kept.
+func availableOutputs(outputs []mpvplayer.AudioOutput, devices []mpvplayer.AudioDevice, listErr error) (available []mpvplayer.AudioOutput, missing []string) {
+	if listErr != nil {
+		if len(outputs) > 1 {
+			for _, output := range outputs[1:] {
+				missing = append(missing, output.Device)
+			}
+			outputs = outputs[:1]
+		}
+		return outputs, missing
+	}
+
+	known := map[string]bool{}
+	for _, device := range devices {
+		known[device.Name] = true
+	}
+	for _, output := range outputs {
+		if known[output.Device] {
+			available = append(available, output)
+		} else {
+			missing = append(missing, output.Device)
+		}
+	}
+	return available, missing
+}
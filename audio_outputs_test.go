@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAudioOutputs(t *testing.T) {
+	outputs, err := parseAudioOutputs([]interface{}{
+		map[string]interface{}{"device": "pulse/speakers"},
+		map[string]interface{}{"device": "pulse/bluetooth", "offset-ms": int64(180)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []mpvplayer.AudioOutput{
+		{Device: "pulse/speakers"},
+		{Device: "pulse/bluetooth", Offset: 180 * time.Millisecond},
+	}, outputs)
+
+	for name, value := range map[string]interface{}{
+		"not a list":     "pulse/speakers",
+		"no device":      []interface{}{map[string]interface{}{"offset-ms": int64(10)}},
+		"negative":       []interface{}{map[string]interface{}{"device": "a", "offset-ms": int64(-10)}},
+		"too long":       []interface{}{map[string]interface{}{"device": "a", "offset-ms": int64(60000)}},
+		"unknown key":    []interface{}{map[string]interface{}{"device": "a", "volume": int64(50)}},
+		"device twice":   []interface{}{map[string]interface{}{"device": "a"}, map[string]interface{}{"device": "a"}},
+		"not a table":    []interface{}{"a"},
+		"device not str": []interface{}{map[string]interface{}{"device": int64(1)}},
+	} {
+		_, err := parseAudioOutputs(value)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestAvailableOutputs(t *testing.T) {
+	outputs := []mpvplayer.AudioOutput{{Device: "a"}, {Device: "b"}, {Device: "c"}}
+	devices := []mpvplayer.AudioDevice{{Name: "auto"}, {Name: "a"}, {Name: "c"}}
+
+	available, missing := availableOutputs(outputs, devices, nil)
+	assert.Equal(t, []mpvplayer.AudioOutput{{Device: "a"}, {Device: "c"}}, available)
+	assert.Equal(t, []string{"b"}, missing)
+
+	// without a device list only a single output is used
+	available, missing = availableOutputs(outputs, nil, errors.New("unsupported"))
+	assert.Equal(t, []mpvplayer.AudioOutput{{Device: "a"}}, available)
+	assert.Equal(t, []string{"b", "c"}, missing)
+}
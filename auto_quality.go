@@ -0,0 +1,140 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// pings averaged to decide whether the connection is slow
+	autoQualitySamples = 3
+	// ping interval if server.auto-quality is on without
+	// server.ping-interval-s
+	autoQualityPingInterval = 30 * time.Second
+)
+
+// Defaults of the server.auto-quality-* keys.
+const (
+	defaultAutoQualitySlow    = 500 * time.Millisecond
+	defaultAutoQualityFast    = 150 * time.Millisecond
+	defaultAutoQualityBitRate = 128
+)
+
+// autoQuality lowers the transcoding bitrate while the server pings slowly,
+// see server.auto-quality. Only used from the gui goroutine.
+type autoQuality struct {
+	enabled bool
+	// average round-trip time above which the connection is slow, and below
+	// which it's fast again
+	slow time.Duration
+	fast time.Duration
+	// kbps streamed at most while the connection is slow
+	bitRate int
+
+	// latencies of the last pings, oldest first
+	samples []time.Duration
+	// the connection is slow
+	low bool
+}
+
+// loadAutoQuality reads the server.auto-quality keys. The config was
+// validated at startup.
+func loadAutoQuality() autoQuality {
+	a := autoQuality{
+		enabled: viper.GetBool("server.auto-quality"),
+		slow:    defaultAutoQualitySlow,
+		fast:    defaultAutoQualityFast,
+		bitRate: defaultAutoQualityBitRate,
+	}
+	if viper.IsSet("server.auto-quality-slow-ms") {
+		a.slow = time.Duration(viper.GetInt("server.auto-quality-slow-ms")) * time.Millisecond
+	}
+	if viper.IsSet("server.auto-quality-fast-ms") {
+		a.fast = time.Duration(viper.GetInt("server.auto-quality-fast-ms")) * time.Millisecond
+	}
+	// keep a gap so the quality doesn't flip on every ping
+	a.fast = min(a.fast, a.slow)
+	if viper.IsSet("server.auto-quality-bitrate") {
+		a.bitRate = viper.GetInt("server.auto-quality-bitrate")
+	}
+	return a
+}
+
+// update records the latency of a ping and reports whether the connection
+// became slow or fast again. Failed pings are left out, an unreachable server
+// isn't streamed from anyway.
+func (a *autoQuality) update(latency time.Duration, err error) bool {
+	if !a.enabled || err != nil {
+		return false
+	}
+	a.samples = append(a.samples, latency)
+	if len(a.samples) > autoQualitySamples {
+		a.samples = a.samples[1:]
+	}
+
+	var sum time.Duration
+	for _, sample := range a.samples {
+		sum += sample
+	}
+	average := sum / time.Duration(len(a.samples))
+
+	switch {
+	case !a.low && average > a.slow:
+		a.low = true
+	case a.low && average < a.fast:
+		a.low = false
+	default:
+		return false
+	}
+	return true
+}
+
+// maxBitRate returns the bitrate to stream with when the user chose
+// userBitRate, 0 being the original files.
+func (a *autoQuality) maxBitRate(userBitRate int) int {
+	if !a.low || (userBitRate > 0 && userBitRate <= a.bitRate) {
+		return userBitRate
+	}
+	return a.bitRate
+}
+
+// text returns the quality for the top bar, "" if auto quality is off.
+func (a *autoQuality) text(userBitRate int) string {
+	if !a.enabled {
+		return ""
+	}
+	bitRate := a.maxBitRate(userBitRate)
+	if bitRate == 0 {
+		return "[gray]auto:[-] original"
+	}
+	if a.low {
+		return fmt.Sprintf("[gray]auto:[-] [yellow]%d kbps[-]", bitRate)
+	}
+	return fmt.Sprintf("[gray]auto:[-] %d kbps", bitRate)
+}
+
+// applyMaxBitRate streams songs added from now on with the user's bitrate,
+// or the lower one of auto quality. Must be called from the gui goroutine.
+func (ui *Ui) applyMaxBitRate() {
+	ui.connection.MaxBitRate = ui.autoQuality.maxBitRate(ui.serverSettings.MaxBitRate)
+}
+
+// updateAutoQuality records a ping for auto quality and switches the bitrate
+// if the connection became slow or fast again. Must be called from the gui
+// goroutine.
+func (ui *Ui) updateAutoQuality(latency time.Duration, err error) {
+	if !ui.autoQuality.update(latency, err) {
+		return
+	}
+	ui.applyMaxBitRate()
+	if ui.autoQuality.low {
+		ui.showNotice(fmt.Sprintf("Slow connection, streaming at most %d kbps (for songs added from now on)", ui.connection.MaxBitRate))
+	} else {
+		ui.showNotice("Fast connection again, back to your quality (for songs added from now on)")
+	}
+}
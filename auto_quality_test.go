@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoQuality(t *testing.T) {
+	a := autoQuality{enabled: true, slow: 500 * time.Millisecond, fast: 150 * time.Millisecond, bitRate: 128}
+	ms := time.Millisecond
+
+	assert.False(t, a.update(100*ms, nil))
+	assert.False(t, a.update(400*ms, nil))
+	// average of 100, 400 and 1200
+	assert.True(t, a.update(1200*ms, nil))
+	assert.True(t, a.low)
+	assert.Equal(t, 128, a.maxBitRate(0))
+	assert.Equal(t, 128, a.maxBitRate(320))
+	// lower than the auto bitrate
+	assert.Equal(t, 96, a.maxBitRate(96))
+
+	// failed pings don't count
+	assert.False(t, a.update(0, errors.New("timeout")))
+	// between the thresholds it stays slow
+	assert.False(t, a.update(100*ms, nil))
+	assert.False(t, a.update(100*ms, nil))
+	assert.True(t, a.update(100*ms, nil))
+	assert.False(t, a.low)
+	assert.Equal(t, 320, a.maxBitRate(320))
+	assert.Equal(t, "[gray]auto:[-] original", a.text(0))
+}
+
+func TestAutoQualityDisabled(t *testing.T) {
+	a := autoQuality{slow: time.Millisecond, bitRate: 128}
+	assert.False(t, a.update(time.Second, nil))
+	assert.Equal(t, 0, a.maxBitRate(0))
+	assert.Equal(t, "", a.text(0))
+}
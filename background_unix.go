@@ -0,0 +1,52 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+//go:build !windows
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"syscall"
+	"time"
+)
+
+// restoreSignals bring stmps back from background mode.
+var restoreSignals = []os.Signal{syscall.SIGUSR1}
+
+// waitForEnter blocks until a line was read from stdin or cancel is closed.
+// stdin is read through a non-blocking duplicate so that the read can be
+// interrupted; a blocked read left behind would swallow input meant for the
+// TUI later on.
+func waitForEnter(cancel <-chan struct{}) {
+	fd, err := syscall.Dup(syscall.Stdin)
+	if err != nil {
+		<-cancel
+		return
+	}
+	if err = syscall.SetNonblock(fd, true); err != nil {
+		_ = syscall.Close(fd)
+		<-cancel
+		return
+	}
+	// the non-blocking flag is shared with stdin, reset it when done
+	defer func() {
+		_ = syscall.SetNonblock(syscall.Stdin, false)
+	}()
+
+	stdin := os.NewFile(uintptr(fd), "stdin")
+	defer stdin.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-cancel:
+			_ = stdin.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	_, _ = bufio.NewReader(stdin).ReadString('\n')
+}
@@ -0,0 +1,20 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+//go:build windows
+
+package main
+
+import (
+	"bufio"
+	"os"
+)
+
+// restoreSignals bring stmps back from background mode. There are no
+// suitable signals on Windows, only Enter works there.
+var restoreSignals []os.Signal
+
+// waitForEnter blocks until a line was read from stdin.
+func waitForEnter(_ <-chan struct{}) {
+	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+}
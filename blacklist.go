@@ -0,0 +1,96 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"sort"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+)
+
+// setBlacklist replaces the blacklisted song IDs of the filter.
+func (f contentFilter) setBlacklist(ids []string) {
+	for id := range f.blacklist {
+		delete(f.blacklist, id)
+	}
+	for _, id := range ids {
+		if id != "" {
+			f.blacklist[id] = true
+		}
+	}
+}
+
+// toggleBlacklisted adds the song to the blacklist, or removes it if it is
+// on it. It returns whether the song is blacklisted now.
+func (f contentFilter) toggleBlacklisted(id string) bool {
+	if f.blacklist[id] {
+		delete(f.blacklist, id)
+		return false
+	}
+	f.blacklist[id] = true
+	return true
+}
+
+func (f contentFilter) isBlacklisted(id string) bool {
+	return f.blacklist[id]
+}
+
+// blacklistIds returns the blacklisted song IDs sorted, as they're stored.
+func (f contentFilter) blacklistIds() []string {
+	ids := make([]string, 0, len(f.blacklist))
+	for id := range f.blacklist {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// blacklistTarget returns the song that 'B' acts on: the selected one on the
+// queue page, the playing one elsewhere.
+func (ui *Ui) blacklistTarget() (id, title string) {
+	if ui.menuWidget.GetActivePage() == PageQueue {
+		return ui.queuePage.selectedItem()
+	}
+	song, err := ui.player.GetQueueItem(0)
+	if err != nil {
+		return "", ""
+	}
+	return song.Id, song.Title
+}
+
+// handleToggleBlacklist puts the song on the server's blacklist or takes it
+// off again. The blacklist is saved right away.
+func (ui *Ui) handleToggleBlacklist() {
+	id, title := ui.blacklistTarget()
+	if id == "" {
+		ui.showNotice("No song selected or playing")
+		return
+	}
+
+	if ui.contentFilter.toggleBlacklisted(id) {
+		ui.showNotice("Never playing " + title + " in random modes")
+	} else {
+		ui.showNotice("Removed " + title + " from the blacklist")
+	}
+	ui.serverSettings.Blacklist = ui.contentFilter.blacklistIds()
+	if serverStatePath() != "" {
+		ui.storeServerSettings()
+	}
+	ui.queuePage.UpdateQueue()
+}
+
+// skipBlacklistedSong skips the song that started playing if it is
+// blacklisted and client.skip-blacklisted is set. It reports whether it
+// skipped.
+func (ui *Ui) skipBlacklistedSong(song mpvplayer.QueueItem) bool {
+	if !ui.skipBlacklisted || !ui.contentFilter.isBlacklisted(song.Id) {
+		return false
+	}
+	ui.logger.Printf("skipping blacklisted song %s", song.Id)
+	ui.showNotice("Skipped blacklisted " + song.Title)
+	if err := ui.player.PlayNextTrack(); err != nil {
+		ui.logger.PrintError("skipBlacklistedSong", err)
+	}
+	return true
+}
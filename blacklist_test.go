@@ -0,0 +1,55 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlacklistFilter(t *testing.T) {
+	filter := newContentFilter(nil, false)
+	filter.setBlacklist([]string{"2", ""})
+
+	songs := []subsonic.SubsonicEntity{{Id: "1"}, {Id: "2"}, {Id: "3"}}
+	filtered := filter.filterSongs(songs)
+	assert.Len(t, filtered, 2)
+	assert.Equal(t, "1", filtered[0].Id)
+	assert.Equal(t, "3", filtered[1].Id)
+
+	assert.False(t, filter.allowsSong(&subsonic.SubsonicEntity{Id: "2"}))
+	assert.True(t, filter.allowsSong(&subsonic.SubsonicEntity{Id: "3"}))
+}
+
+func TestToggleBlacklisted(t *testing.T) {
+	filter := newContentFilter(nil, false)
+
+	assert.True(t, filter.toggleBlacklisted("b"))
+	assert.True(t, filter.toggleBlacklisted("a"))
+	assert.True(t, filter.isBlacklisted("b"))
+	assert.Equal(t, []string{"a", "b"}, filter.blacklistIds())
+
+	assert.False(t, filter.toggleBlacklisted("b"))
+	assert.False(t, filter.isBlacklisted("b"))
+	assert.Equal(t, []string{"a"}, filter.blacklistIds())
+
+	// replacing drops the old entries
+	filter.setBlacklist([]string{"c"})
+	assert.Equal(t, []string{"c"}, filter.blacklistIds())
+}
+
+func TestBlacklistPerServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), serverStateFileName)
+
+	assert.NoError(t, saveServerSettings(path, "admin@http://local", serverSettings{Volume: 50, Blacklist: []string{"1", "2"}}))
+	assert.NoError(t, saveServerSettings(path, "admin@http://remote", serverSettings{Volume: 50}))
+
+	local, err := loadServerSettings(path, "admin@http://local", serverSettings{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, local.Blacklist)
+
+	remote, err := loadServerSettings(path, "admin@http://remote", serverSettings{})
+	assert.NoError(t, err)
+	assert.Empty(t, remote.Blacklist)
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// queue source of songs started from a bookmark
+const queueSourceBookmark = "bookmark"
+
+// findBookmark returns the bookmark of the song with the ID, nil if it has
+// none. Bookmarks are identified by their song.
+func findBookmark(bookmarks []subsonic.Bookmark, id string) *subsonic.Bookmark {
+	for i := range bookmarks {
+		if bookmarks[i].Entry.Id == id {
+			return &bookmarks[i]
+		}
+	}
+	return nil
+}
+
+// fetchBookmark returns the bookmark of the song with the ID from the
+// server, nil if it has none. Used by --resume-bookmark.
+func fetchBookmark(connection *subsonic.SubsonicConnection, id string) (*subsonic.Bookmark, error) {
+	response, err := connection.GetBookmarks()
+	if err != nil {
+		return nil, err
+	}
+	return findBookmark(response.Bookmarks.Bookmarks, id), nil
+}
+
+// playBookmark replaces the queue with the bookmarked song and plays it from
+// the bookmarked position.
+func (ui *Ui) playBookmark(bookmark subsonic.Bookmark) {
+	ui.player.ClearQueue()
+	queueItem := ui.makeQueueItem(&bookmark.Entry, "")
+	queueItem.Source = queueSourceBookmark
+	ui.player.AddToQueue(&queueItem)
+	ui.queuePage.UpdateQueue()
+
+	// bookmarks are in milliseconds, mpv starts at whole seconds
+	position := int(bookmark.Position / 1000)
+	if err := ui.player.PlayFrom(position, false); err != nil {
+		ui.logger.PrintError("playBookmark", err)
+		return
+	}
+	min, sec := iSecondsToMinAndSec(position)
+	ui.showNotice(fmt.Sprintf("Resuming %s at %d:%02d", queueItem.Title, min, sec))
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindBookmark(t *testing.T) {
+	bookmarks := []subsonic.Bookmark{
+		{Position: 61000, Entry: subsonic.SubsonicEntity{Id: "ch1"}},
+		{Position: 1500, Entry: subsonic.SubsonicEntity{Id: "ch2"}},
+	}
+
+	bookmark := findBookmark(bookmarks, "ch2")
+	if assert.NotNil(t, bookmark) {
+		assert.Equal(t, int64(1500), bookmark.Position)
+	}
+	assert.Nil(t, findBookmark(bookmarks, "ch3"))
+	assert.Nil(t, findBookmark(nil, "ch1"))
+}
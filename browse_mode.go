@@ -0,0 +1,183 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// BrowseMode decides what the browser page is built from.
+type BrowseMode string
+
+const (
+	// BrowseFolder shows the server's folder hierarchy (getIndexes and
+	// getMusicDirectory), i.e. the file layout
+	BrowseFolder BrowseMode = "folder"
+	// BrowseId3 shows artists and albums by their tags (getArtists,
+	// getArtist and getAlbum)
+	BrowseId3 BrowseMode = "id3"
+)
+
+// fetchBrowseIndexes returns the artist index of the browse mode.
+func fetchBrowseIndexes(connection *subsonic.SubsonicConnection, mode BrowseMode) (subsonic.SubsonicIndexes, error) {
+	if mode == BrowseId3 {
+		response, err := connection.GetArtists()
+		if err != nil {
+			return subsonic.SubsonicIndexes{}, err
+		}
+		return response.Artists, nil
+	}
+
+	// unset is BrowseFolder
+	response, err := connection.GetIndexes()
+	if err != nil {
+		return subsonic.SubsonicIndexes{}, err
+	}
+	return response.Indexes, nil
+}
+
+// artistDirectory presents a tagged artist like a folder with its albums as
+// subfolders.
+func artistDirectory(artist subsonic.Artist) subsonic.SubsonicDirectory {
+	directory := subsonic.SubsonicDirectory{
+		Id:   artist.Id,
+		Name: artist.Name,
+	}
+	for _, album := range artist.Album {
+		directory.Entities = append(directory.Entities, subsonic.SubsonicEntity{
+			Id:          album.Id,
+			IsDirectory: true,
+			Parent:      artist.Id,
+			Title:       stringOr(album.Name, album.Title),
+			AlbumId:     album.Id,
+			ArtistId:    stringOr(album.ArtistId, artist.Id),
+			Artist:      album.Artist,
+			Duration:    album.Duration,
+			Year:        album.Year,
+			Genre:       album.Genre,
+			PlayCount:   album.PlayCount,
+			Created:     album.Created,
+			CoverArtId:  album.CoverArt,
+		})
+	}
+	return directory
+}
+
+// albumDirectory presents a tagged album like a folder with its songs. Its
+// parent is the album's artist.
+func albumDirectory(album subsonic.Album) subsonic.SubsonicDirectory {
+	return subsonic.SubsonicDirectory{
+		Id:       album.Id,
+		Parent:   album.ArtistId,
+		Name:     stringOr(album.Name, album.Title),
+		Entities: album.Song,
+	}
+}
+
+// isBrowseArtist reports whether id is one of the artists in the artist
+// list.
+func (b *BrowserPage) isBrowseArtist(id string) bool {
+	for _, artist := range b.artists {
+		if artist.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// getDirectory returns the contents of an artist, album or folder in the
+// browse mode. The result is a copy and may be modified.
+func (b *BrowserPage) getDirectory(id string) (subsonic.SubsonicDirectory, error) {
+	return fetchDirectory(b.ui.connection, b.browseMode, b.isBrowseArtist(id), id)
+}
+
+// fetchDirectory gets an artist, album or folder in the browse mode, artist
+// tells tagged artists from albums. It's safe to call in the background.
+func fetchDirectory(connection *subsonic.SubsonicConnection, mode BrowseMode, artist bool, id string) (subsonic.SubsonicDirectory, error) {
+	if mode != BrowseId3 {
+		response, err := connection.GetMusicDirectory(id)
+		if err != nil {
+			return subsonic.SubsonicDirectory{}, err
+		}
+		return response.Directory, nil
+	}
+
+	if artist {
+		response, err := connection.GetArtist(id)
+		if err != nil {
+			return subsonic.SubsonicDirectory{}, err
+		}
+		if response.Status != "ok" {
+			return subsonic.SubsonicDirectory{}, fmt.Errorf("server error %d: %s", response.Error.Code, response.Error.Message)
+		}
+		return artistDirectory(response.Artist), nil
+	}
+
+	response, err := connection.GetAlbum(id)
+	if err != nil {
+		return subsonic.SubsonicDirectory{}, err
+	}
+	if response.Status != "ok" {
+		return subsonic.SubsonicDirectory{}, fmt.Errorf("server error %d: %s", response.Error.Code, response.Error.Message)
+	}
+	return albumDirectory(response.Album), nil
+}
+
+// toggleBrowseMode switches between browsing folders and tags. The artists of
+// the other mode are fetched in the background, see setBrowseMode.
+func (b *BrowserPage) toggleBrowseMode() {
+	if b.switchingMode {
+		return
+	}
+	mode, label := BrowseId3, "tags"
+	if b.browseMode == BrowseId3 {
+		mode, label = BrowseFolder, "folders"
+	}
+
+	b.switchingMode = true
+	b.ui.showNotice("Switching to browsing " + label + "…")
+	go func() {
+		indexes, err := fetchBrowseIndexes(b.ui.connection, mode)
+		b.ui.app.QueueUpdateDraw(func() {
+			b.switchingMode = false
+			if err != nil {
+				b.logger.PrintError("toggleBrowseMode", err)
+				b.ui.showNotice("Switching to browsing " + label + " failed")
+				return
+			}
+			b.setBrowseMode(mode, indexes)
+			b.ui.showNotice("Browsing " + label)
+		})
+	}()
+}
+
+// setBrowseMode shows the artists of the browse mode, with the selected
+// artist kept if it's found there by name.
+func (b *BrowserPage) setBrowseMode(mode BrowseMode, indexes subsonic.SubsonicIndexes) {
+	selectedName := ""
+	if idx := b.artistList.GetCurrentItem(); idx >= 0 && idx < len(b.artistIdList) {
+		selectedName = b.artistName(b.artistIdList[idx])
+	}
+
+	b.browseMode = mode
+	b.currentDirectory = nil
+	b.entityList.Clear()
+	// adding the first artist opens it
+	b.setArtists(indexes)
+
+	names := map[string]string{}
+	for _, artist := range b.artists {
+		names[artist.Id] = artist.Name
+	}
+	for i, id := range b.artistIdList {
+		if selectedName != "" && strings.EqualFold(names[id], selectedName) {
+			b.artistList.SetCurrentItem(i)
+			break
+		}
+	}
+	b.ui.app.SetFocus(b.artistList)
+}
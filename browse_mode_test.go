@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArtistDirectory(t *testing.T) {
+	artist := subsonic.Artist{
+		Id:   "ar-1",
+		Name: "Artist",
+		Album: []subsonic.Album{
+			{Id: "al-1", Name: "First", Year: 1999, CoverArt: "co-1"},
+			{Id: "al-2", Title: "Second", ArtistId: "ar-2"},
+		},
+	}
+
+	directory := artistDirectory(artist)
+	assert.Equal(t, "ar-1", directory.Id)
+	assert.Equal(t, "", directory.Parent)
+	assert.Len(t, directory.Entities, 2)
+
+	first := directory.Entities[0]
+	assert.True(t, first.IsDirectory)
+	assert.Equal(t, "al-1", first.Id)
+	assert.Equal(t, "First", first.Title)
+	assert.Equal(t, "ar-1", first.Parent)
+	assert.Equal(t, "ar-1", first.ArtistId)
+	assert.Equal(t, 1999, first.Year)
+	assert.Equal(t, "co-1", first.CoverArtId)
+
+	second := directory.Entities[1]
+	assert.Equal(t, "Second", second.Title)
+	assert.Equal(t, "ar-2", second.ArtistId)
+}
+
+func TestAlbumDirectory(t *testing.T) {
+	album := subsonic.Album{
+		Id:       "al-1",
+		Name:     "First",
+		ArtistId: "ar-1",
+		Song:     subsonic.SubsonicEntities{{Id: "tr-1"}, {Id: "tr-2"}},
+	}
+
+	directory := albumDirectory(album)
+	assert.Equal(t, "al-1", directory.Id)
+	assert.Equal(t, "ar-1", directory.Parent)
+	assert.Equal(t, "First", directory.Name)
+	assert.Len(t, directory.Entities, 2)
+}
@@ -0,0 +1,148 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// bulkStarIds returns the items starred together with an album or artist:
+// the album or artist itself and its songs. In the folder layout the folder
+// is starred by its ID, in the tag layout by album or artist ID.
+func bulkStarIds(mode BrowseMode, id string, artist bool, songIds []string) subsonic.StarIds {
+	items := subsonic.StarIds{Ids: songIds}
+	switch {
+	case mode != BrowseId3:
+		items.Ids = append([]string{id}, songIds...)
+	case artist:
+		items.ArtistIds = []string{id}
+	default:
+		items.AlbumIds = []string{id}
+	}
+	return items
+}
+
+// setStarredIds updates the local starred state of the items.
+func setStarredIds(starredItems map[string]struct{}, items subsonic.StarIds, star bool) {
+	for _, ids := range [][]string{items.Ids, items.AlbumIds, items.ArtistIds} {
+		for _, id := range ids {
+			if star {
+				starredItems[id] = struct{}{}
+			} else {
+				delete(starredItems, id)
+			}
+		}
+	}
+}
+
+// collectSongIds returns the IDs of the songs in the folder and its
+// subfolders, artist tells a tagged artist from an album. It doesn't touch
+// the page, so it's safe to call in the background.
+func (b *BrowserPage) collectSongIds(mode BrowseMode, artist bool, id string) []string {
+	directory, err := fetchDirectory(b.ui.connection, mode, artist, id)
+	if err != nil {
+		b.logger.Printf("collectSongIds: fetchDirectory %s -- %v", id, err)
+		return nil
+	}
+
+	var ids []string
+	for _, entity := range directory.Entities {
+		if entity.IsDirectory {
+			// an artist's directories are albums
+			ids = append(ids, b.collectSongIds(mode, false, entity.Id)...)
+		} else {
+			ids = append(ids, entity.Id)
+		}
+	}
+	return ids
+}
+
+// handleStarArtist stars the selected artist and all its songs, or unstars
+// them if the artist is starred.
+func (b *BrowserPage) handleStarArtist() {
+	idx := b.artistList.GetCurrentItem()
+	if idx < 0 || idx >= len(b.artistIdList) {
+		return
+	}
+	id := b.artistIdList[idx]
+	b.bulkStar(id, b.artistName(id), true)
+}
+
+// handleStarEntity stars the selected album and all its songs, or unstars
+// them if the album is starred. On a song it does the same for the album
+// that is shown.
+func (b *BrowserPage) handleStarEntity() {
+	if b.currentDirectory == nil {
+		return
+	}
+	currentIndex := b.entityList.GetCurrentItem()
+	if b.currentDirectory.Parent != "" {
+		// account for [..] entry that we show, see handleEntitySelected()
+		currentIndex--
+	}
+	if currentIndex >= 0 && currentIndex < len(b.currentDirectory.Entities) {
+		if entity := b.currentDirectory.Entities[currentIndex]; entity.IsDirectory {
+			b.bulkStar(entity.Id, entity.Title, false)
+			return
+		}
+	}
+	b.bulkStar(b.currentDirectory.Id, b.currentDirectory.Name, b.isBrowseArtist(b.currentDirectory.Id))
+}
+
+// bulkStar collects the songs of a folder and stars or unstars them and the
+// folder in the background, in batches of subsonic.StarBatchSize IDs.
+// Progress is shown as notices.
+func (b *BrowserPage) bulkStar(id, name string, artist bool) {
+	if b.starring {
+		b.ui.showNotice("Still starring, try again when it's done")
+		return
+	}
+
+	_, starred := b.ui.starIdList[id]
+	star := !starred
+	mode := b.browseMode
+	action := "Starring"
+	if !star {
+		action = "Unstarring"
+	}
+
+	b.starring = true
+	b.ui.showNotice(fmt.Sprintf("%s %s", action, name))
+	b.ui.pendingWork.run(func() {
+		items := bulkStarIds(mode, id, artist, b.collectSongIds(mode, artist, id))
+		songs := items.Len() - 1
+		b.ui.app.QueueUpdateDraw(func() {
+			b.ui.showNotice(fmt.Sprintf("%s %s and %d songs", action, name, songs))
+		})
+
+		total := items.Len()
+		done := 0
+		err := b.ui.connection.SetStarred(star, items, func(batch subsonic.StarIds) {
+			done += batch.Len()
+			progress := done
+			b.ui.app.QueueUpdateDraw(func() {
+				setStarredIds(b.ui.starIdList, batch, star)
+				if progress < total {
+					b.ui.showNotice(fmt.Sprintf("%s %s: %d/%d", action, name, progress, total))
+				}
+			})
+		})
+
+		b.ui.app.QueueUpdateDraw(func() {
+			b.starring = false
+			if err != nil {
+				b.logger.PrintError("bulkStar", err)
+				b.ui.showNotice(fmt.Sprintf("%s %s failed after %d of %d items", action, name, done, total))
+			} else if star {
+				b.ui.showNotice(fmt.Sprintf("Starred %s and %d songs", name, songs))
+			} else {
+				b.ui.showNotice(fmt.Sprintf("Unstarred %s and %d songs", name, songs))
+			}
+			b.UpdateStars()
+			b.ui.queuePage.UpdateQueue()
+		})
+	})
+}
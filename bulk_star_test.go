@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkStarIds(t *testing.T) {
+	songs := []string{"s1", "s2"}
+
+	assert.Equal(t, subsonic.StarIds{Ids: []string{"f1", "s1", "s2"}}, bulkStarIds(BrowseFolder, "f1", true, songs))
+	assert.Equal(t, subsonic.StarIds{Ids: songs, ArtistIds: []string{"ar1"}}, bulkStarIds(BrowseId3, "ar1", true, songs))
+	assert.Equal(t, subsonic.StarIds{Ids: songs, AlbumIds: []string{"al1"}}, bulkStarIds(BrowseId3, "al1", false, songs))
+}
+
+func TestSetStarredIds(t *testing.T) {
+	starred := map[string]struct{}{"s3": {}}
+	items := subsonic.StarIds{Ids: []string{"s1"}, AlbumIds: []string{"al1"}}
+
+	setStarredIds(starred, items, true)
+	assert.Contains(t, starred, "s1")
+	assert.Contains(t, starred, "al1")
+
+	setStarredIds(starred, items, false)
+	assert.Equal(t, map[string]struct{}{"s3": {}}, starred)
+}
+
+func TestArtistListText(t *testing.T) {
+	starred := map[string]struct{}{"1": {}}
+	assert.Equal(t, "AC/DC [red]♥", artistListText("AC/DC", "1", starred))
+	assert.Equal(t, "[Various[]", artistListText("[Various]", "2", starred))
+}
@@ -0,0 +1,183 @@
+package cast
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:MediaServer:1</deviceType>
+    <friendlyName>Receiver</friendlyName>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+        <friendlyName>Living Room</friendlyName>
+        <serviceList>
+          <service>
+            <serviceType>urn:schemas-upnp-org:service:RenderingControl:1</serviceType>
+            <controlURL>/upnp/control/rendering</controlURL>
+          </service>
+          <service>
+            <serviceType>urn:schemas-upnp-org:service:AVTransport:1</serviceType>
+            <controlURL>upnp/control/transport</controlURL>
+          </service>
+        </serviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+func TestParseDeviceDescription(t *testing.T) {
+	device, err := parseDeviceDescription("http://192.168.1.20:49152/desc/device.xml", []byte(testDescription))
+	assert.NoError(t, err)
+	assert.Equal(t, Device{
+		Name:                "Living Room",
+		Location:            "http://192.168.1.20:49152/desc/device.xml",
+		AVTransportURL:      "http://192.168.1.20:49152/desc/upnp/control/transport",
+		RenderingControlURL: "http://192.168.1.20:49152/upnp/control/rendering",
+	}, device)
+
+	_, err = parseDeviceDescription("http://192.168.1.20/", []byte(`<root><device><deviceType>urn:schemas-upnp-org:device:MediaServer:1</deviceType></device></root>`))
+	assert.Error(t, err)
+}
+
+func TestParseSearchResponse(t *testing.T) {
+	response := "HTTP/1.1 200 OK\r\n" +
+		"CACHE-CONTROL: max-age=1800\r\n" +
+		"LOCATION: http://192.168.1.20:49152/desc/device.xml\r\n" +
+		"ST: urn:schemas-upnp-org:device:MediaRenderer:1\r\n\r\n"
+	assert.Equal(t, "http://192.168.1.20:49152/desc/device.xml", parseSearchResponse([]byte(response)))
+	assert.Equal(t, "", parseSearchResponse([]byte("garbage")))
+}
+
+func TestTime(t *testing.T) {
+	assert.Equal(t, "0:03:25", formatTime(205))
+	assert.Equal(t, "1:00:01", formatTime(3601))
+	assert.Equal(t, 205.0, parseTime("0:03:25"))
+	assert.Equal(t, 205.5, parseTime("00:03:25.500"))
+	assert.Equal(t, 0.0, parseTime("NOT_IMPLEMENTED"))
+}
+
+func TestSoapCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `"urn:schemas-upnp-org:service:AVTransport:1#GetTransportInfo"`, r.Header.Get("SOAPAction"))
+		body, _ := io.ReadAll(r.Body)
+		assert.True(t, strings.Contains(string(body), "<InstanceID>0</InstanceID>"))
+
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>
+<u:GetTransportInfoResponse xmlns:u="urn:schemas-upnp-org:service:AVTransport:1">
+<CurrentTransportState>PLAYING</CurrentTransportState>
+</u:GetTransportInfoResponse></s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+
+	data, err := soapCall(server.Client(), server.URL, avTransportType, "GetTransportInfo", soapArg{"InstanceID", "0"})
+	assert.NoError(t, err)
+	assert.Equal(t, statePlaying, soapValue(data, "CurrentTransportState"))
+}
+
+func TestRendererTogglePlayPause(t *testing.T) {
+	var actions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		soapAction := r.Header.Get("SOAPAction")
+		actions = append(actions, soapAction[strings.LastIndex(soapAction, "#")+1:len(soapAction)-1])
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body></s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+
+	r := &Renderer{
+		device: Device{AVTransportURL: server.URL},
+		client: server.Client(),
+		state:  statePlaying,
+	}
+	assert.NoError(t, r.TogglePlayPause())
+	r.state = statePaused
+	assert.NoError(t, r.TogglePlayPause())
+	assert.Equal(t, []string{"Pause", "Play"}, actions)
+}
+
+func TestRendererCallbacks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>
+<CurrentTransportState>PLAYING</CurrentTransportState><RelTime>0:00:05</RelTime>
+</s:Body></s:Envelope>`))
+	}))
+	defer server.Close()
+
+	r := &Renderer{
+		device: Device{AVTransportURL: server.URL},
+		client: server.Client(),
+		state:  stateNoMedia,
+	}
+	playing := 0
+	r.OnPlaying(func() { playing++ })
+
+	// callbacks may be registered while the device is polled
+	registered := make(chan struct{})
+	go func() {
+		defer close(registered)
+		r.OnSeek(func() {})
+	}()
+	assert.NoError(t, r.update())
+	<-registered
+
+	assert.Equal(t, 1, playing)
+	assert.Equal(t, 5.0, r.GetTimePos())
+}
+
+func TestRendererLoadingIsNoTrackEnd(t *testing.T) {
+	r := &Renderer{state: statePlaying, loading: true, uri: "http://server/new"}
+	ended := 0
+	r.OnTrackEnd(func() { ended++ })
+
+	now := time.Now()
+	// the previous track is still reported until the device switches
+	r.observe(statePlaying, "http://server/old", 100, now)
+	// buffering the new track
+	r.observe(stateStopped, "http://server/new", 0, now.Add(time.Second))
+	r.observe(stateTransitioning, "http://server/new", 0, now.Add(2*time.Second))
+	r.observe(stateNoMedia, "http://server/new", 0, now.Add(3*time.Second))
+	assert.Equal(t, 0, ended)
+	assert.True(t, r.loading)
+
+	// stopping after the new track played is its end
+	r.observe(statePlaying, "http://server/new", 0, now.Add(4*time.Second))
+	assert.False(t, r.loading)
+	r.observe(stateStopped, "", 0, now.Add(5*time.Second))
+	assert.Equal(t, 1, ended)
+}
+
+func TestRendererSeekTolerance(t *testing.T) {
+	r := &Renderer{state: stateNoMedia}
+	seeks := 0
+	r.OnSeek(func() { seeks++ })
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		r.observe(statePlaying, "", float64(i), now.Add(time.Duration(i)*time.Second))
+	}
+	// a late answer isn't a seek
+	r.observe(statePlaying, "", 6, now.Add(5500*time.Millisecond))
+	assert.Equal(t, 0, seeks)
+
+	r.observe(statePlaying, "", 60, now.Add(6500*time.Millisecond))
+	assert.Equal(t, 1, seeks)
+
+	// paused right after the last poll, the position doesn't move while paused
+	r.observe(statePaused, "", 60, now.Add(10*time.Second))
+	r.observe(statePaused, "", 60, now.Add(20*time.Second))
+	assert.Equal(t, 1, seeks)
+	r.observe(statePaused, "", 30, now.Add(21*time.Second))
+	assert.Equal(t, 2, seeks)
+}
@@ -0,0 +1,208 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+// Package cast sends playback to DLNA/UPnP media renderers on the local
+// network.
+package cast
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	ssdpAddress = "239.255.255.250:1900"
+
+	mediaRendererType    = "urn:schemas-upnp-org:device:MediaRenderer:1"
+	avTransportType      = "urn:schemas-upnp-org:service:AVTransport:1"
+	renderingControlType = "urn:schemas-upnp-org:service:RenderingControl:1"
+)
+
+// Device is a media renderer found on the network.
+type Device struct {
+	Name string
+	// URL of the device description
+	Location string
+
+	AVTransportURL string
+	// empty if the device has no volume control
+	RenderingControlURL string
+}
+
+// Discover sends an SSDP search for media renderers and collects the devices
+// that answer within timeout.
+func Discover(timeout time.Duration) ([]Device, error) {
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	addr, err := net.ResolveUDPAddr("udp4", ssdpAddress)
+	if err != nil {
+		return nil, err
+	}
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddress + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n" +
+		"ST: " + mediaRendererType + "\r\n\r\n"
+	if _, err := conn.WriteTo([]byte(search), addr); err != nil {
+		return nil, err
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	// devices answer once per network interface, keep the first answer
+	var locations []string
+	seen := map[string]bool{}
+	buf := make([]byte, 4096)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		location := parseSearchResponse(buf[:n])
+		if location != "" && !seen[location] {
+			seen[location] = true
+			locations = append(locations, location)
+		}
+	}
+
+	client := &http.Client{Timeout: timeout}
+	devices := make([]Device, 0, len(locations))
+	for _, location := range locations {
+		device, err := fetchDevice(client, location)
+		if err != nil {
+			// not every answering device is usable, skip it
+			continue
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// parseSearchResponse returns the description location from an SSDP search
+// response, or "" if the response isn't valid.
+func parseSearchResponse(data []byte) string {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	return resp.Header.Get("Location")
+}
+
+func fetchDevice(client *http.Client, location string) (Device, error) {
+	resp, err := client.Get(location)
+	if err != nil {
+		return Device{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Device{}, fmt.Errorf("device description: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Device{}, err
+	}
+	return parseDeviceDescription(location, data)
+}
+
+type descriptionRoot struct {
+	URLBase string            `xml:"URLBase"`
+	Device  descriptionDevice `xml:"device"`
+}
+
+type descriptionDevice struct {
+	DeviceType   string               `xml:"deviceType"`
+	FriendlyName string               `xml:"friendlyName"`
+	Services     []descriptionService `xml:"serviceList>service"`
+	Devices      []descriptionDevice  `xml:"deviceList>device"`
+}
+
+type descriptionService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+// parseDeviceDescription reads the name and control URLs of a media renderer
+// from its UPnP device description. Relative URLs are resolved against
+// URLBase or, if that's missing, against location.
+func parseDeviceDescription(location string, data []byte) (Device, error) {
+	var root descriptionRoot
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return Device{}, err
+	}
+
+	base, err := url.Parse(location)
+	if err != nil {
+		return Device{}, err
+	}
+	if root.URLBase != "" {
+		if base, err = url.Parse(root.URLBase); err != nil {
+			return Device{}, err
+		}
+	}
+
+	// the renderer may be embedded in another device, e.g. an AV receiver
+	renderer := findDevice(root.Device, mediaRendererType)
+	if renderer == nil {
+		return Device{}, errors.New("not a media renderer")
+	}
+
+	device := Device{
+		Name:     renderer.FriendlyName,
+		Location: location,
+	}
+	for _, service := range renderer.Services {
+		controlURL, err := base.Parse(service.ControlURL)
+		if err != nil {
+			continue
+		}
+		switch service.ServiceType {
+		case avTransportType:
+			device.AVTransportURL = controlURL.String()
+		case renderingControlType:
+			device.RenderingControlURL = controlURL.String()
+		}
+	}
+
+	if device.AVTransportURL == "" {
+		return Device{}, errors.New("media renderer has no AVTransport service")
+	}
+	if device.Name == "" {
+		device.Name = base.Host
+	}
+	return device, nil
+}
+
+func findDevice(device descriptionDevice, deviceType string) *descriptionDevice {
+	if device.DeviceType == deviceType {
+		return &device
+	}
+	for _, embedded := range device.Devices {
+		if found := findDevice(embedded, deviceType); found != nil {
+			return found
+		}
+	}
+	return nil
+}
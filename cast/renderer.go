@@ -0,0 +1,383 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cast
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spezifisch/stmps/logger"
+	"github.com/spezifisch/stmps/remote"
+)
+
+// how often the transport state is polled from the device
+const pollInterval = time.Second
+
+// how far the polled position may drift from the expected one before it's
+// reported as a seek, covers the latency of the position requests
+const seekTolerance = 2.0
+
+// AVTransport transport states
+const (
+	statePlaying       = "PLAYING"
+	statePaused        = "PAUSED_PLAYBACK"
+	stateStopped       = "STOPPED"
+	stateNoMedia       = "NO_MEDIA_PRESENT"
+	stateTransitioning = "TRANSITIONING"
+)
+
+// Renderer controls playback on a media renderer. Its state is polled from
+// the device, callbacks are invoked from the polling goroutine. The methods
+// that talk to the device block until it answers.
+type Renderer struct {
+	device Device
+	client *http.Client
+	logger logger.LoggerInterface
+
+	mutex   sync.Mutex
+	state   string
+	timePos float64
+	// when state and timePos were polled
+	polled time.Time
+	volume int
+	// URI set by Load
+	uri string
+	// set by Load until the device plays uri, renderers may report STOPPED
+	// or NO_MEDIA_PRESENT while buffering which isn't the end of the track
+	loading bool
+	// position to seek to once the loaded track plays
+	pendingSeek int
+	// set by Stop so that stopping isn't reported as the end of the track
+	stopRequested bool
+
+	// callbacks, registered and read with the mutex held
+	cbOnPaused     []func()
+	cbOnStopped    []func()
+	cbOnPlaying    []func()
+	cbOnSeek       []func()
+	cbOnSongChange []func(remote.TrackInterface)
+	cbOnTrackEnd   []func()
+
+	// NextTrackHandler and PreviousTrackHandler implement NextTrack and
+	// PreviousTrack, the renderer doesn't know the queue.
+	NextTrackHandler     func() error
+	PreviousTrackHandler func() error
+
+	done chan struct{}
+}
+
+var _ remote.ControlledPlayer = (*Renderer)(nil)
+
+// NewRenderer starts controlling device. Close must be called when done.
+func NewRenderer(device Device, logger logger.LoggerInterface) *Renderer {
+	r := &Renderer{
+		device: device,
+		client: &http.Client{Timeout: 5 * time.Second},
+		logger: logger,
+		state:  stateNoMedia,
+		volume: -1,
+		done:   make(chan struct{}),
+	}
+	go r.poll()
+	return r
+}
+
+// Close stops polling the device. Playback on the device isn't stopped.
+func (r *Renderer) Close() {
+	close(r.done)
+}
+
+// Name returns the name of the device.
+func (r *Renderer) Name() string {
+	return r.device.Name
+}
+
+// Load starts playing uri on the device, starting at position seconds.
+func (r *Renderer) Load(uri string, track remote.TrackInterface, position int) error {
+	r.mutex.Lock()
+	r.stopRequested = true
+	r.loading = true
+	r.uri = uri
+	r.mutex.Unlock()
+
+	// most renderers refuse a new URI while playing
+	_ = r.transportAction("Stop")
+
+	if _, err := soapCall(r.client, r.device.AVTransportURL, avTransportType, "SetAVTransportURI",
+		soapArg{"InstanceID", "0"},
+		soapArg{"CurrentURI", uri},
+		soapArg{"CurrentURIMetaData", didlMetadata(uri, track)},
+	); err != nil {
+		r.mutex.Lock()
+		r.loading = false
+		r.mutex.Unlock()
+		return err
+	}
+
+	r.mutex.Lock()
+	r.timePos = 0
+	r.pendingSeek = position
+	r.stopRequested = false
+	onSongChange := r.cbOnSongChange
+	r.mutex.Unlock()
+
+	for _, cb := range onSongChange {
+		cb(track)
+	}
+	return r.playAction()
+}
+
+func (r *Renderer) transportAction(action string) error {
+	_, err := soapCall(r.client, r.device.AVTransportURL, avTransportType, action, soapArg{"InstanceID", "0"})
+	return err
+}
+
+func (r *Renderer) playAction() error {
+	_, err := soapCall(r.client, r.device.AVTransportURL, avTransportType, "Play",
+		soapArg{"InstanceID", "0"},
+		soapArg{"Speed", "1"},
+	)
+	return err
+}
+
+func (r *Renderer) poll() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			if err := r.update(); err != nil {
+				r.logger.PrintError("cast", err)
+			}
+		}
+	}
+}
+
+// update fetches the transport state and position and invokes the callbacks
+// for changes.
+func (r *Renderer) update() error {
+	data, err := soapCall(r.client, r.device.AVTransportURL, avTransportType, "GetTransportInfo", soapArg{"InstanceID", "0"})
+	if err != nil {
+		return err
+	}
+	state := soapValue(data, "CurrentTransportState")
+
+	data, err = soapCall(r.client, r.device.AVTransportURL, avTransportType, "GetPositionInfo", soapArg{"InstanceID", "0"})
+	if err != nil {
+		return err
+	}
+	timePos := parseTime(soapValue(data, "RelTime"))
+
+	r.observe(state, soapValue(data, "TrackURI"), timePos, time.Now())
+	return nil
+}
+
+// observe applies a polled state and position and invokes the callbacks for
+// changes. trackURI may be empty if the device doesn't report it.
+func (r *Renderer) observe(state, trackURI string, timePos float64, now time.Time) {
+	r.mutex.Lock()
+	previous := r.state
+	r.state = state
+
+	wasLoading := r.loading
+	pendingSeek := 0
+	if r.loading && state == statePlaying && (trackURI == "" || trackURI == r.uri) {
+		r.loading = false
+		pendingSeek = r.pendingSeek
+		r.pendingSeek = 0
+	}
+
+	// while playing the position moves on by up to the time since the last
+	// poll, less if playback was paused or resumed in between
+	lowest, highest := r.timePos, r.timePos
+	if previous == statePlaying || state == statePlaying {
+		highest += now.Sub(r.polled).Seconds()
+	}
+	seeked := !wasLoading && !r.polled.IsZero() &&
+		(timePos < lowest-seekTolerance || timePos > highest+seekTolerance)
+	r.timePos = timePos
+	r.polled = now
+
+	trackEnded := previous == statePlaying && (state == stateStopped || state == stateNoMedia) &&
+		!r.stopRequested && !r.loading
+	onPlaying, onPaused, onStopped := r.cbOnPlaying, r.cbOnPaused, r.cbOnStopped
+	onSeek, onTrackEnd := r.cbOnSeek, r.cbOnTrackEnd
+	r.mutex.Unlock()
+
+	if pendingSeek > 0 {
+		if err := r.SeekAbsolute(pendingSeek); err != nil {
+			r.logger.PrintError("cast: resume position", err)
+		}
+	}
+
+	if state != previous {
+		switch state {
+		case statePlaying:
+			invoke(onPlaying)
+		case statePaused:
+			invoke(onPaused)
+		case stateStopped, stateNoMedia:
+			invoke(onStopped)
+		}
+	}
+	if seeked {
+		invoke(onSeek)
+	}
+	if trackEnded {
+		invoke(onTrackEnd)
+	}
+}
+
+func invoke(callbacks []func()) {
+	for _, cb := range callbacks {
+		cb()
+	}
+}
+
+// OnTrackEnd registers a callback which is invoked when the device finished
+// playing the loaded track.
+func (r *Renderer) OnTrackEnd(cb func()) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cbOnTrackEnd = append(r.cbOnTrackEnd, cb)
+}
+
+// GetVolume returns the last volume set on the device, or -1 if it's unknown.
+func (r *Renderer) GetVolume() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.volume
+}
+
+// remote.ControlledPlayer
+
+func (r *Renderer) IsSeeking() (bool, error) {
+	return false, nil
+}
+
+func (r *Renderer) IsPaused() (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.state == statePaused, nil
+}
+
+func (r *Renderer) IsPlaying() (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.state == statePlaying || r.state == stateTransitioning, nil
+}
+
+func (r *Renderer) OnPaused(cb func()) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cbOnPaused = append(r.cbOnPaused, cb)
+}
+
+func (r *Renderer) OnStopped(cb func()) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cbOnStopped = append(r.cbOnStopped, cb)
+}
+
+func (r *Renderer) OnPlaying(cb func()) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cbOnPlaying = append(r.cbOnPlaying, cb)
+}
+
+func (r *Renderer) OnSeek(cb func()) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cbOnSeek = append(r.cbOnSeek, cb)
+}
+
+func (r *Renderer) OnSongChange(cb func(track remote.TrackInterface)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cbOnSongChange = append(r.cbOnSongChange, cb)
+}
+
+func (r *Renderer) GetTimePos() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.timePos
+}
+
+func (r *Renderer) Play() error {
+	r.mutex.Lock()
+	r.stopRequested = false
+	r.mutex.Unlock()
+	return r.playAction()
+}
+
+// Pause toggles between playing and paused, like mpvplayer.Player.Pause.
+func (r *Renderer) Pause() error {
+	return r.TogglePlayPause()
+}
+
+func (r *Renderer) TogglePlayPause() error {
+	if playing, _ := r.IsPlaying(); playing {
+		return r.transportAction("Pause")
+	}
+	return r.Play()
+}
+
+func (r *Renderer) Stop() error {
+	r.mutex.Lock()
+	r.stopRequested = true
+	r.mutex.Unlock()
+	return r.transportAction("Stop")
+}
+
+func (r *Renderer) SeekAbsolute(position int) error {
+	_, err := soapCall(r.client, r.device.AVTransportURL, avTransportType, "Seek",
+		soapArg{"InstanceID", "0"},
+		soapArg{"Unit", "REL_TIME"},
+		soapArg{"Target", formatTime(position)},
+	)
+	return err
+}
+
+func (r *Renderer) NextTrack() error {
+	if r.NextTrackHandler == nil {
+		return errors.New("no next track handler")
+	}
+	return r.NextTrackHandler()
+}
+
+func (r *Renderer) PreviousTrack() error {
+	if r.PreviousTrackHandler == nil {
+		return errors.New("no previous track handler")
+	}
+	return r.PreviousTrackHandler()
+}
+
+func (r *Renderer) SetVolume(percentValue int) error {
+	if r.device.RenderingControlURL == "" {
+		return errors.New("device has no volume control")
+	}
+	if percentValue < 0 {
+		percentValue = 0
+	} else if percentValue > 100 {
+		percentValue = 100
+	}
+
+	if _, err := soapCall(r.client, r.device.RenderingControlURL, renderingControlType, "SetVolume",
+		soapArg{"InstanceID", "0"},
+		soapArg{"Channel", "Master"},
+		soapArg{"DesiredVolume", strconv.Itoa(percentValue)},
+	); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	r.volume = percentValue
+	r.mutex.Unlock()
+	return nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package cast
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/spezifisch/stmps/remote"
+)
+
+type soapArg struct {
+	name  string
+	value string
+}
+
+// soapCall invokes a UPnP action and returns the response body.
+func soapCall(client *http.Client, controlURL, serviceType, action string, args ...soapArg) ([]byte, error) {
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	body.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, serviceType)
+	for _, arg := range args {
+		fmt.Fprintf(&body, "<%s>", arg.name)
+		if err := xml.EscapeText(&body, []byte(arg.value)); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&body, "</%s>", arg.name)
+	}
+	fmt.Fprintf(&body, "</u:%s></s:Body></s:Envelope>", action)
+
+	req, err := http.NewRequest(http.MethodPost, controlURL, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, serviceType, action))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if description := soapValue(data, "errorDescription"); description != "" {
+			return nil, fmt.Errorf("%s: %s", action, description)
+		}
+		return nil, fmt.Errorf("%s: %s", action, resp.Status)
+	}
+	return data, nil
+}
+
+// soapValue returns the text of the first element with the given name in a
+// SOAP response, or "" if there is none.
+func soapValue(data []byte, name string) string {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == name {
+			var value string
+			if err := decoder.DecodeElement(&value, &start); err != nil {
+				return ""
+			}
+			return value
+		}
+	}
+}
+
+// didlMetadata describes a track for SetAVTransportURI, renderers with a
+// display show it.
+func didlMetadata(uri string, track remote.TrackInterface) string {
+	var b strings.Builder
+	b.WriteString(`<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">`)
+	b.WriteString(`<item id="0" parentID="-1" restricted="1">`)
+	writeElement(&b, "dc:title", track.GetTitle())
+	writeElement(&b, "upnp:artist", track.GetArtist())
+	writeElement(&b, "upnp:album", track.GetAlbum())
+	b.WriteString(`<upnp:class>object.item.audioItem.musicTrack</upnp:class>`)
+	fmt.Fprintf(&b, `<res protocolInfo="http-get:*:*:*" duration="%s">`, formatTime(track.GetDuration()))
+	_ = xml.EscapeText(&b, []byte(uri))
+	b.WriteString(`</res></item></DIDL-Lite>`)
+	return b.String()
+}
+
+func writeElement(b *strings.Builder, name, value string) {
+	fmt.Fprintf(b, "<%s>", name)
+	_ = xml.EscapeText(b, []byte(value))
+	fmt.Fprintf(b, "</%s>", name)
+}
+
+// formatTime formats seconds as H:MM:SS, the time format of AVTransport.
+func formatTime(seconds int) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// parseTime parses an AVTransport time like 0:03:25 or 0:03:25.500 into
+// seconds. Values like NOT_IMPLEMENTED are returned as 0.
+func parseTime(value string) float64 {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	var seconds float64
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
@@ -0,0 +1,421 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spezifisch/stmps/remote"
+	"github.com/spf13/viper"
+)
+
+// CurrentConfigVersion is written to config-version when a config is migrated.
+const CurrentConfigVersion = 1
+
+// configValidator checks a single config value. value is whatever viper has
+// stored for the key, which is the type decoded from TOML (string, int64,
+// bool, ...) or a string if it was set from the command line.
+type configValidator func(value interface{}) error
+
+// knownConfigKeys lists every config key stmps understands. Keys not in this
+// list are reported as unknown, which mostly catches typos. A nil validator
+// means any value is accepted.
+var knownConfigKeys = map[string]configValidator{
+	"config-version": isIntInRange(0, CurrentConfigVersion),
+
+	"auth.username":  isNonEmptyString,
+	"auth.password":  isString,
+	"auth.plaintext": isBool,
+
+	"server.host":                  isServerUrl,
+	"server.scrobble":              isBool,
+	"server.scrobble-mode":         isOneOf(string(ScrobbleThreshold), string(ScrobbleOnlyComplete)),
+	"server.web-ui-url":            isString,
+	"server.max-bitrate":           isIntInRange(0, 2000),
+	"server.original-streams":      isBool,
+	"server.compression":           isBool,
+	"server.scrobble-queue-size":   isIntInRange(0, 100000),
+	"server.now-playing-refresh-s": isIntInRange(0, 3600),
+	"server.ping-interval-s":       isIntInRange(0, 3600),
+	"server.auto-quality":          isBool,
+	"server.auto-quality-slow-ms":  isIntInRange(1, 60000),
+	"server.auto-quality-fast-ms":  isIntInRange(1, 60000),
+	"server.auto-quality-bitrate":  isIntInRange(32, 320),
+
+	"client.random-songs":           isIntInRange(0, 500),
+	"client.top-songs":              isIntInRange(1, 100),
+	"client.browse-mode":            isOneOf(string(BrowseFolder), string(BrowseId3)),
+	"client.duplicate-policy":       isOneOf(string(DuplicatesAllow), string(DuplicatesSkip), string(DuplicatesJump)),
+	"client.album-duplicate-policy": isOneOf(string(AlbumDuplicatesMissing), string(AlbumDuplicatesAll), string(AlbumDuplicatesSkip)),
+	"client.auto-queue-album":       isBool,
+	"client.search-history":         isIntInRange(0, 1000),
+	"client.save-search-history":    isBool,
+	"client.blocked-genres":         isStringList,
+	"client.skip-explicit":          isBool,
+	"client.skip-blacklisted":       isBool,
+	"client.history-export-path":    isString,
+	"client.format-filter":          isOneOf(FormatFilterOff, FormatFilterLossless, FormatFilterBitrate),
+	"client.min-bitrate":            isIntInRange(1, 10000),
+	"client.supported-formats":      isStringList,
+	"client.now-playing-file":       isString,
+	"client.now-playing-format":     isNowPlayingFormat,
+	"client.now-playing-elapsed":    isBool,
+	"client.resume-last-session":    isOneOf(string(ResumeOff), string(ResumeOn), string(ResumePaused)),
+	"client.max-queue-length":       isIntInRange(0, 1000000),
+	"client.shutdown-timeout-s":     isIntInRange(0, 300),
+	"client.radio-seed-ratio":       isIntInRange(0, 100),
+
+	"player.skip-debounce-ms":          isIntInRange(0, 10000),
+	"player.trim-silence":              isBool,
+	"player.silence-threshold-db":      isNumberInRange(-100, 0),
+	"player.silence-duration-ms":       isIntInRange(100, 60000),
+	"player.voice-boost":               isBool,
+	"player.voice-boost-spoken-word":   isBool,
+	"player.voice-threshold-db":        isNumberInRange(-60, 0),
+	"player.voice-ratio":               isNumberInRange(1, 20),
+	"player.voice-makeup-db":           isNumberInRange(0, 36),
+	"player.stall-timeout-s":           isIntInRange(0, 3600),
+	"player.stall-action":              isOneOf(mpvplayer.StallActionRetry, mpvplayer.StallActionSkip),
+	"player.seek-wraps-tracks":         isBool,
+	"player.previous-restart-s":        isIntInRange(0, 60),
+	"player.seek-mode":                 isOneOf(mpvplayer.SeekExact, mpvplayer.SeekKeyframe),
+	"player.seek-unit":                 isOneOf(SeekUnitSeconds, SeekUnitPercent),
+	"player.seek-short":                isIntInRange(1, 3600),
+	"player.seek-long":                 isIntInRange(1, 3600),
+	"player.seek-unknown-length":       isOneOf(SeekUnknownLengthOff, SeekUnknownLengthSeconds),
+	"player.pause-during-seek":         isBool,
+	"player.mpv-config":                isString,
+	"player.mpv-scripts":               isString,
+	"player.max-restarts":              isIntInRange(0, 100),
+	"player.gapless":                   isBool,
+	"player.low-latency":               isBool,
+	"player.cache":                     isBool,
+	"player.gapless-within-album-only": isBool,
+	// validated when mpv is set up, see loadDecodingOptions
+	"player.gapless-audio":          nil,
+	"player.audio-pitch-correction": nil,
+	"player.audio-spdif":            nil,
+	"player.volume":                 isIntInRange(0, 100),
+	"player.replaygain":             isOneOf(replayGainModes...),
+	"player.fade-in-ms":             isIntInRange(0, 10000),
+	"player.fade-out-ms":            isIntInRange(0, 10000),
+	"player.pause-others-on-play":   isBool,
+
+	"macros":                          isMacroList,
+	"ui.spinner":                      isString,
+	"ui.refresh-ms":                   isIntInRange(0, 10000),
+	"ui.confirm-quit":                 isBool,
+	"ui.wrap-lists":                   isBool,
+	"ui.show-format":                  isBool,
+	"ui.status-format":                isStatusFormat,
+	"ui.show-queue-source":            isBool,
+	"ui.views":                        isViewList,
+	"ui.startup-view":                 isOneOf(allViews...),
+	"ui.remember-positions":           isBool,
+	"ui.idle-timeout-s":               isIntInRange(0, 86400),
+	"ui.display-artist":               isOneOf(string(ArtistDisplayTrack), string(ArtistDisplayAlbum), string(ArtistDisplayAlbumFeat)),
+	"ui.media-controls-art":           isOneOf(string(remote.FallbackArtIcon), string(remote.FallbackArtColor), string(remote.FallbackArtNone)),
+	"ui.media-controls-clear-on-stop": isBool,
+	"ui.cover-art":                    isBool,
+	"ui.waveform":                     isBool,
+	"ui.waveform-height":              isIntInRange(1, 8),
+	"ui.columns.browser":              isColumnList,
+	"ui.columns.queue":                isColumnList,
+	"ui.columns.playlists":            isColumnList,
+	"ui.columns.search":               isColumnList,
+	"ui.columns.decades":              isColumnList,
+
+	"sort.artists":           isOneOf(artistSortKeys...),
+	"sort.artists-direction": isOneOf(sortAscending, sortDescending),
+	"sort.albums":            isOneOf(albumSortKeys...),
+	"sort.albums-direction":  isOneOf(sortAscending, sortDescending),
+	"sort.songs":             isOneOf(songSortKeys...),
+	"sort.songs-direction":   isOneOf(sortAscending, sortDescending),
+	"sort.ignore-articles":   isBool,
+
+	"smart-mix": isSmartMixList,
+	"outputs":   isAudioOutputList,
+}
+
+var requiredConfigKeys = []string{"auth.username", "auth.password", "server.host"}
+
+// deprecatedConfigKeys maps old config keys to their new names. Configs
+// containing old keys get rewritten by migrateConfig. Add an entry here and
+// bump CurrentConfigVersion when renaming a key.
+var deprecatedConfigKeys = map[string]string{}
+
+// validateConfig checks the loaded viper config. All problems are collected
+// instead of stopping at the first one. Problems which make stmps unusable are
+// returned as errors, the rest as warnings.
+func validateConfig() (warnings []string, err error) {
+	var problems []string
+
+	for _, key := range requiredConfigKeys {
+		if !viper.IsSet(key) {
+			problems = append(problems, fmt.Sprintf("property %s is required", key))
+		}
+	}
+
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+	for _, key := range keys {
+		validate, known := knownConfigKeys[key]
+		if !known {
+			if newKey, deprecated := deprecatedConfigKeys[key]; deprecated {
+				warnings = append(warnings, fmt.Sprintf("property %s is deprecated, use %s instead", key, newKey))
+			} else {
+				warnings = append(warnings, fmt.Sprintf("unknown property %s", key))
+			}
+			continue
+		}
+		if validate == nil {
+			continue
+		}
+		if verr := validate(viper.Get(key)); verr != nil {
+			problems = append(problems, fmt.Sprintf("property %s: %s", key, verr))
+		}
+	}
+
+	if len(problems) > 0 {
+		err = errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	return
+}
+
+func isString(value interface{}) error {
+	if _, ok := value.(string); !ok {
+		return fmt.Errorf("expected a string, got %v", value)
+	}
+	return nil
+}
+
+func isStringList(value interface{}) error {
+	list, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("expected a list of strings, got %v", value)
+	}
+	for _, entry := range list {
+		if _, ok := entry.(string); !ok {
+			return fmt.Errorf("expected a string, got %v", entry)
+		}
+	}
+	return nil
+}
+
+func isNonEmptyString(value interface{}) error {
+	if s, ok := value.(string); !ok {
+		return fmt.Errorf("expected a string, got %v", value)
+	} else if s == "" {
+		return errors.New("must not be empty")
+	}
+	return nil
+}
+
+func isBool(value interface{}) error {
+	switch v := value.(type) {
+	case bool:
+		return nil
+	case string:
+		// set from command line
+		if _, err := strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("expected true or false, got %q", v)
+		}
+		return nil
+	}
+	return fmt.Errorf("expected true or false, got %v", value)
+}
+
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("expected a number, got %v", value)
+}
+
+func isIntInRange(min, max int64) configValidator {
+	return func(value interface{}) error {
+		i, err := toInt64(value)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %v", value)
+		}
+		if i < min || i > max {
+			return fmt.Errorf("%d is out of range [%d, %d]", i, min, max)
+		}
+		return nil
+	}
+}
+
+func isOneOf(allowed ...string) configValidator {
+	return func(value interface{}) error {
+		s, ok := value.(string)
+		if !ok || !containsString(allowed, s) {
+			return fmt.Errorf("expected one of %s, got %v", strings.Join(allowed, ", "), value)
+		}
+		return nil
+	}
+}
+
+func toFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("expected a number, got %v", value)
+}
+
+func isNumberInRange(min, max float64) configValidator {
+	return func(value interface{}) error {
+		f, err := toFloat64(value)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %v", value)
+		}
+		if f < min || f > max {
+			return fmt.Errorf("%g is out of range [%g, %g]", f, min, max)
+		}
+		return nil
+	}
+}
+
+func isServerUrl(value interface{}) error {
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return fmt.Errorf("expected a URL, got %v", value)
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q must start with http:// or https://", s)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host name", s)
+	}
+	if port := u.Port(); port != "" {
+		if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("port %s is out of range [1, 65535]", port)
+		}
+	}
+	return nil
+}
+
+// migrateConfig rewrites deprecated keys in the config file to their new
+// names and sets config-version. The original file is kept as a backup. It
+// returns the list of renamed keys; nothing is written if there's nothing to
+// migrate.
+func migrateConfig(path string) (renamed []string, err error) {
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	var data map[string]interface{}
+	if err = toml.Unmarshal(original, &data); err != nil {
+		return
+	}
+
+	oldKeys := make([]string, 0, len(deprecatedConfigKeys))
+	for oldKey := range deprecatedConfigKeys {
+		oldKeys = append(oldKeys, oldKey)
+	}
+	sort.Strings(oldKeys)
+
+	for _, oldKey := range oldKeys {
+		value, present := tomlTakeKey(data, oldKey)
+		if !present {
+			continue
+		}
+		newKey := deprecatedConfigKeys[oldKey]
+		if tomlHasKey(data, newKey) {
+			return nil, fmt.Errorf("both %s and its replacement %s are set", oldKey, newKey)
+		}
+		tomlSetKey(data, newKey, value)
+		renamed = append(renamed, fmt.Sprintf("%s -> %s", oldKey, newKey))
+	}
+
+	if len(renamed) == 0 {
+		return
+	}
+
+	data["config-version"] = CurrentConfigVersion
+	migrated, err := toml.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	backupPath := path + ".bak"
+	if err = os.WriteFile(backupPath, original, 0600); err != nil {
+		return nil, fmt.Errorf("failed to back up config to %s: %v", backupPath, err)
+	}
+	if err = os.WriteFile(path, migrated, 0600); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// tomlParentTable returns the table containing a dotted key in decoded TOML
+// data and the last key component. If create is set, missing tables are
+// created on the way.
+func tomlParentTable(data map[string]interface{}, key string, create bool) (map[string]interface{}, string) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		table, ok := data[part].(map[string]interface{})
+		if !ok {
+			if !create {
+				return nil, ""
+			}
+			table = map[string]interface{}{}
+			data[part] = table
+		}
+		data = table
+	}
+	return data, parts[len(parts)-1]
+}
+
+func tomlHasKey(data map[string]interface{}, key string) bool {
+	table, last := tomlParentTable(data, key, false)
+	if table == nil {
+		return false
+	}
+	_, ok := table[last]
+	return ok
+}
+
+// tomlTakeKey removes a dotted key from decoded TOML data and returns its value.
+func tomlTakeKey(data map[string]interface{}, key string) (interface{}, bool) {
+	table, last := tomlParentTable(data, key, false)
+	if table == nil {
+		return nil, false
+	}
+	value, ok := table[last]
+	if ok {
+		delete(table, last)
+	}
+	return value, ok
+}
+
+// tomlSetKey sets a dotted key in decoded TOML data, creating tables as needed.
+func tomlSetKey(data map[string]interface{}, key string, value interface{}) {
+	table, last := tomlParentTable(data, key, true)
+	table[last] = value
+}
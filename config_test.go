@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	path := filepath.Join(t.TempDir(), "stmp.toml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	viper.SetConfigFile(path)
+	if err := viper.ReadInConfig(); err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	return path
+}
+
+func TestValidateConfigValid(t *testing.T) {
+	loadTestConfig(t, `
+[auth]
+username = 'admin'
+password = 'password'
+
+[server]
+host = 'https://example.com:4533'
+`)
+
+	warnings, err := validateConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+}
+
+func TestValidateConfigListsAllProblems(t *testing.T) {
+	loadTestConfig(t, `
+[auth]
+password = 'password'
+plaintex = true
+
+[server]
+host = 'ftp://example.com:99999'
+
+[client]
+random-songs = 9000
+`)
+
+	warnings, err := validateConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "auth.username is required")
+	assert.Contains(t, err.Error(), "server.host")
+	assert.Contains(t, err.Error(), "client.random-songs")
+	assert.Equal(t, []string{"unknown property auth.plaintex"}, warnings)
+}
+
+func TestMigrateConfig(t *testing.T) {
+	deprecatedConfigKeys["client.old-name"] = "client.random-songs"
+	defer delete(deprecatedConfigKeys, "client.old-name")
+
+	path := loadTestConfig(t, `
+[auth]
+username = 'admin'
+password = 'password'
+
+[server]
+host = 'https://example.com'
+
+[client]
+old-name = 23
+`)
+
+	renamed, err := migrateConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"client.old-name -> client.random-songs"}, renamed)
+
+	backup, err := os.ReadFile(path + ".bak")
+	assert.NoError(t, err)
+	assert.Contains(t, string(backup), "old-name = 23")
+
+	assert.NoError(t, viper.ReadInConfig())
+	assert.Equal(t, 23, viper.GetInt("client.random-songs"))
+	assert.Equal(t, CurrentConfigVersion, viper.GetInt("config-version"))
+	assert.False(t, viper.IsSet("client.old-name"))
+
+	// nothing left to do the second time
+	renamed, err = migrateConfig(path)
+	assert.NoError(t, err)
+	assert.Empty(t, renamed)
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"strings"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
+)
+
+// contentFilter keeps unwanted songs out of the random modes: random songs,
+// similar songs and smart mixes. Songs already in the queue aren't filtered.
+type contentFilter struct {
+	// lowercased genre names
+	blockedGenres map[string]bool
+	// skip songs the server flags as explicit, servers without the
+	// OpenSubsonic explicitStatus field never flag songs
+	skipExplicit bool
+	// IDs of the songs on the server's blacklist, shared with
+	// ui.serverSettings, see blacklist.go
+	blacklist map[string]bool
+}
+
+func newContentFilter(blockedGenres []string, skipExplicit bool) contentFilter {
+	filter := contentFilter{
+		blockedGenres: map[string]bool{},
+		skipExplicit:  skipExplicit,
+		blacklist:     map[string]bool{},
+	}
+	for _, genre := range blockedGenres {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			filter.blockedGenres[strings.ToLower(genre)] = true
+		}
+	}
+	return filter
+}
+
+// loadContentFilter returns the filter of the client.blocked-genres and
+// client.skip-explicit config.
+func loadContentFilter() contentFilter {
+	return newContentFilter(viper.GetStringSlice("client.blocked-genres"), viper.GetBool("client.skip-explicit"))
+}
+
+// allowed reports whether a song with this ID, genres and explicit flag
+// passes the filter.
+func (f contentFilter) allowed(id string, genres []string, explicit bool) bool {
+	if f.blacklist[id] {
+		return false
+	}
+	if f.skipExplicit && explicit {
+		return false
+	}
+	for _, genre := range genres {
+		if f.blockedGenres[strings.ToLower(strings.TrimSpace(genre))] {
+			return false
+		}
+	}
+	return true
+}
+
+func (f contentFilter) allowsSong(song *subsonic.SubsonicEntity) bool {
+	return f.allowed(song.Id, song.GetGenres(), song.IsExplicit())
+}
+
+// filterSongs returns the songs that pass the filter.
+func (f contentFilter) filterSongs(songs []subsonic.SubsonicEntity) []subsonic.SubsonicEntity {
+	var filtered []subsonic.SubsonicEntity
+	for i := range songs {
+		if f.allowsSong(&songs[i]) {
+			filtered = append(filtered, songs[i])
+		}
+	}
+	return filtered
+}
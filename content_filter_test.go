@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentFilterGenres(t *testing.T) {
+	filter := newContentFilter([]string{" Christmas ", "", "spoken word"}, false)
+
+	songs := []subsonic.SubsonicEntity{
+		{Id: "1", Genre: "Rock"},
+		{Id: "2", Genre: "christmas"},
+		{Id: "3", Genre: "Pop", Genres: []subsonic.Genre{{Name: "Pop"}, {Name: "Spoken Word"}}},
+		{Id: "4"},
+	}
+	filtered := filter.filterSongs(songs)
+	assert.Len(t, filtered, 2)
+	assert.Equal(t, "1", filtered[0].Id)
+	assert.Equal(t, "4", filtered[1].Id)
+}
+
+func TestContentFilterExplicit(t *testing.T) {
+	explicit := subsonic.SubsonicEntity{Id: "1", ExplicitStatus: "explicit"}
+	clean := subsonic.SubsonicEntity{Id: "2", ExplicitStatus: "clean"}
+	unknown := subsonic.SubsonicEntity{Id: "3"}
+
+	filter := newContentFilter(nil, true)
+	assert.False(t, filter.allowsSong(&explicit))
+	assert.True(t, filter.allowsSong(&clean))
+	assert.True(t, filter.allowsSong(&unknown))
+
+	// off by default
+	assert.True(t, newContentFilter(nil, false).allowsSong(&explicit))
+}
+
+func TestIsStringList(t *testing.T) {
+	assert.NoError(t, isStringList([]interface{}{"a", "b"}))
+	assert.NoError(t, isStringList([]interface{}{}))
+	assert.Error(t, isStringList("a"))
+	assert.Error(t, isStringList([]interface{}{"a", 1}))
+}
@@ -0,0 +1,231 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// contextKind is the kind of item a context menu is opened on.
+type contextKind int
+
+const (
+	contextArtist contextKind = iota
+	contextAlbum
+	contextSong
+)
+
+// contextItem is the item a context menu is opened on, with what the page it
+// is selected on knows about it.
+type contextItem struct {
+	kind contextKind
+	id   string
+	name string
+	// the artist and album to go to, empty if unknown
+	artistId   string
+	artistName string
+	albumId    string
+	// adds the item to the queue the way the page's keys do
+	queue func(mode queueMode)
+}
+
+func songContextItem(song *subsonic.SubsonicEntity, queue func(mode queueMode)) contextItem {
+	return contextItem{
+		kind:       contextSong,
+		id:         song.Id,
+		name:       song.Title,
+		artistId:   song.ArtistId,
+		artistName: song.Artist,
+		albumId:    stringOr(song.AlbumId, song.Parent),
+		queue:      queue,
+	}
+}
+
+// contextAction is an entry of the context menu.
+type contextAction struct {
+	label string
+	// the kinds of items the action applies to
+	kinds []contextKind
+	// also hides the action for items it can't handle, may be nil
+	applies func(ui *Ui, item contextItem) bool
+	run     func(ui *Ui, item contextItem) error
+}
+
+var allContextKinds = []contextKind{contextArtist, contextAlbum, contextSong}
+
+// contextActions is the registry of actions the context menu is built from,
+// in menu order.
+var contextActions = []contextAction{
+	{
+		label: "Play",
+		kinds: allContextKinds,
+		run: func(_ *Ui, item contextItem) error {
+			item.queue(queuePlayNow)
+			return nil
+		},
+	},
+	{
+		label: "Play next",
+		kinds: allContextKinds,
+		run: func(_ *Ui, item contextItem) error {
+			item.queue(queueNext)
+			return nil
+		},
+	},
+	{
+		label: "Add to queue",
+		kinds: allContextKinds,
+		run: func(_ *Ui, item contextItem) error {
+			item.queue(queueAppend)
+			return nil
+		},
+	},
+	{
+		label: "Add to playlist…",
+		kinds: []contextKind{contextSong},
+		run: func(ui *Ui, item contextItem) error {
+			if len(ui.playlists) == 0 {
+				return errors.New("no playlists available, create one first")
+			}
+			names := make([]string, len(ui.playlists))
+			for i, playlist := range ui.playlists {
+				names[i] = playlist.Name
+			}
+			ui.contextMenuWidget.showChoices("add to playlist", names, func(index int) error {
+				playlist := ui.playlists[index]
+				if err := ui.connection.AddSongToPlaylist(string(playlist.Id), item.id); err != nil {
+					return err
+				}
+				ui.playlistPage.UpdatePlaylists()
+				ui.showNotice(fmt.Sprintf("Added %s to %s", item.name, playlist.Name))
+				return nil
+			})
+			return nil
+		},
+	},
+	{
+		label: "Star/unstar",
+		kinds: allContextKinds,
+		run: func(ui *Ui, item contextItem) error {
+			return ui.toggleItemStar(item.id)
+		},
+	},
+	{
+		label: "Rate…",
+		kinds: allContextKinds,
+		run: func(ui *Ui, item contextItem) error {
+			ratings := []string{"No rating", "★", "★★", "★★★", "★★★★", "★★★★★"}
+			ui.contextMenuWidget.showChoices("rate", ratings, func(rating int) error {
+				if err := ui.connection.SetRating(item.id, rating); err != nil {
+					return err
+				}
+				ui.showNotice(fmt.Sprintf("Rated %s: %s", item.name, ratings[rating]))
+				return nil
+			})
+			return nil
+		},
+	},
+	{
+		label: "Go to artist",
+		kinds: []contextKind{contextAlbum, contextSong},
+		applies: func(ui *Ui, item contextItem) bool {
+			return item.artistId != "" || item.artistName != ""
+		},
+		run: func(ui *Ui, item contextItem) error {
+			return ui.browserPage.goToArtist(item.artistId, item.artistName)
+		},
+	},
+	{
+		label: "Go to album",
+		kinds: []contextKind{contextSong},
+		applies: func(ui *Ui, item contextItem) bool {
+			return item.albumId != ""
+		},
+		run: func(ui *Ui, item contextItem) error {
+			// show the album among the artist's if the artist can be found
+			if err := ui.browserPage.goToArtist(item.artistId, item.artistName); err == errBrowserDisabled {
+				return err
+			}
+			return ui.browserPage.goToAlbum(item.albumId)
+		},
+	},
+	{
+		label: "Share",
+		kinds: allContextKinds,
+		run: func(ui *Ui, item contextItem) error {
+			ui.copyShareUrl(item.id, item.name)
+			return nil
+		},
+	},
+}
+
+// contextActionsFor returns the actions of the registry that apply to item.
+func (ui *Ui) contextActionsFor(item contextItem) []contextAction {
+	var actions []contextAction
+	for _, action := range contextActions {
+		if !containsKind(action.kinds, item.kind) {
+			continue
+		}
+		if action.applies != nil && !action.applies(ui, item) {
+			continue
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+func containsKind(kinds []contextKind, kind contextKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// selectedContextItem returns the item selected on the active page, false if
+// there's none or the page has no context menu.
+func (ui *Ui) selectedContextItem() (contextItem, bool) {
+	switch ui.menuWidget.GetActivePage() {
+	case PageBrowser:
+		return ui.browserPage.selectedContextItem()
+	case PageSearch:
+		return ui.searchPage.selectedContextItem()
+	}
+	return contextItem{}, false
+}
+
+// toggleItemStar stars or unstars a song, album or artist.
+func (ui *Ui) toggleItemStar(id string) error {
+	_, remove := ui.starIdList[id]
+	if _, err := ui.connection.ToggleStar(id, ui.starIdList); err != nil {
+		return err
+	}
+	if remove {
+		delete(ui.starIdList, id)
+		ui.showNotice("Unstarred")
+	} else {
+		ui.starIdList[id] = struct{}{}
+		ui.showNotice("Starred")
+	}
+	ui.browserPage.UpdateStars()
+	ui.queuePage.UpdateQueue()
+	return nil
+}
+
+func (k contextKind) String() string {
+	switch k {
+	case contextArtist:
+		return "artist"
+	case contextAlbum:
+		return "album"
+	case contextSong:
+		return "song"
+	}
+	return strconv.Itoa(int(k))
+}
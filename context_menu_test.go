@@ -0,0 +1,46 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+func contextLabels(actions []contextAction) []string {
+	labels := make([]string, len(actions))
+	for i, action := range actions {
+		labels[i] = action.label
+	}
+	return labels
+}
+
+func TestContextActionsFor(t *testing.T) {
+	ui := &Ui{}
+
+	artist := contextItem{kind: contextArtist, id: "ar1", name: "Artist"}
+	expected := []string{"Play", "Play next", "Add to queue", "Star/unstar", "Rate…", "Share"}
+	if labels := contextLabels(ui.contextActionsFor(artist)); !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected artist actions %v, got %v", expected, labels)
+	}
+
+	song := songContextItem(&subsonic.SubsonicEntity{Id: "s1", Title: "Song", Artist: "Artist", Parent: "al1"}, nil)
+	expected = []string{"Play", "Play next", "Add to queue", "Add to playlist…", "Star/unstar", "Rate…", "Go to artist", "Go to album", "Share"}
+	if labels := contextLabels(ui.contextActionsFor(song)); !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected song actions %v, got %v", expected, labels)
+	}
+	if song.albumId != "al1" {
+		t.Errorf("expected the parent as album, got %q", song.albumId)
+	}
+
+	// nothing to go to
+	album := contextItem{kind: contextAlbum, id: "al1", name: "Album"}
+	for _, label := range contextLabels(ui.contextActionsFor(album)) {
+		if label == "Go to artist" || label == "Add to playlist…" {
+			t.Errorf("expected no %q for an album without artist", label)
+		}
+	}
+}
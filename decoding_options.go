@@ -0,0 +1,74 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// values mpv accepts for gapless-audio
+var gaplessAudioModes = []string{"yes", "weak", "no"}
+
+// codecs mpv can pass through to the receiver with audio-spdif
+var spdifCodecs = []string{"ac3", "dts", "dts-hd", "eac3", "truehd"}
+
+// loadDecodingOptions returns the mpv options for player.gapless-audio,
+// player.audio-pitch-correction and player.audio-spdif. Values mpv wouldn't
+// accept are left out and described in rejected, so they can be logged
+// instead of failing the startup.
+func loadDecodingOptions() (options [][2]string, rejected []string) {
+	if viper.IsSet("player.gapless-audio") {
+		value := strings.ToLower(fmt.Sprint(viper.Get("player.gapless-audio")))
+		if value == "true" || value == "false" {
+			value = mpvFlag(value == "true")
+		}
+		if containsString(gaplessAudioModes, value) {
+			options = append(options, [2]string{"gapless-audio", value})
+		} else {
+			rejected = append(rejected, fmt.Sprintf("player.gapless-audio: %v isn't one of %s", viper.Get("player.gapless-audio"), strings.Join(gaplessAudioModes, ", ")))
+		}
+	}
+
+	if viper.IsSet("player.audio-pitch-correction") {
+		switch value := viper.Get("player.audio-pitch-correction").(type) {
+		case bool:
+			options = append(options, [2]string{"audio-pitch-correction", mpvFlag(value)})
+		case string:
+			if value == "yes" || value == "no" {
+				options = append(options, [2]string{"audio-pitch-correction", value})
+				break
+			}
+			rejected = append(rejected, fmt.Sprintf("player.audio-pitch-correction: %s isn't true or false", value))
+		default:
+			rejected = append(rejected, fmt.Sprintf("player.audio-pitch-correction: %v isn't true or false", value))
+		}
+	}
+
+	if viper.IsSet("player.audio-spdif") {
+		var codecs []string
+		for _, codec := range strings.Split(viper.GetString("player.audio-spdif"), ",") {
+			codec = strings.ToLower(strings.TrimSpace(codec))
+			switch {
+			case codec == "":
+			case containsString(spdifCodecs, codec):
+				codecs = append(codecs, codec)
+			default:
+				rejected = append(rejected, fmt.Sprintf("player.audio-spdif: unknown codec %s, mpv passes through %s", codec, strings.Join(spdifCodecs, ", ")))
+			}
+		}
+		// an empty list decodes everything in software, which is mpv's default
+		options = append(options, [2]string{"audio-spdif", strings.Join(codecs, ",")})
+	}
+	return
+}
+
+func mpvFlag(on bool) string {
+	if on {
+		return "yes"
+	}
+	return "no"
+}
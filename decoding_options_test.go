@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodingOptionsDefault(t *testing.T) {
+	loadTestConfig(t, "")
+	options, rejected := loadDecodingOptions()
+	assert.Empty(t, options)
+	assert.Empty(t, rejected)
+}
+
+func TestDecodingOptions(t *testing.T) {
+	loadTestConfig(t, `
+[player]
+gapless-audio = 'Yes'
+audio-pitch-correction = false
+audio-spdif = 'ac3, DTS'
+`)
+	options, rejected := loadDecodingOptions()
+	assert.Equal(t, [][2]string{
+		{"gapless-audio", "yes"},
+		{"audio-pitch-correction", "no"},
+		{"audio-spdif", "ac3,dts"},
+	}, options)
+	assert.Empty(t, rejected)
+}
+
+func TestDecodingOptionsRejected(t *testing.T) {
+	loadTestConfig(t, `
+[auth]
+username = 'admin'
+password = 'secret'
+
+[server]
+host = 'https://example.com'
+
+[player]
+gapless-audio = 'sometimes'
+audio-pitch-correction = 3
+audio-spdif = 'ac3,mp3'
+`)
+	options, rejected := loadDecodingOptions()
+	// valid codecs are still passed through
+	assert.Equal(t, [][2]string{{"audio-spdif", "ac3"}}, options)
+	assert.Len(t, rejected, 3)
+
+	// the startup doesn't fail
+	_, err := validateConfig()
+	assert.NoError(t, err)
+}
@@ -4,45 +4,156 @@
 package main
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/spezifisch/stmps/logger"
 	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spf13/viper"
 )
 
+// ScrobbleMode decides when a play is submitted to the server.
+type ScrobbleMode string
+
+const (
+	// ScrobbleThreshold submits songs after half their duration or 4 minutes
+	ScrobbleThreshold ScrobbleMode = "threshold"
+	// ScrobbleOnlyComplete submits songs that played until their end
+	ScrobbleOnlyComplete ScrobbleMode = "complete"
+)
+
+// songs shorter than this many seconds are never scrobbled
+const scrobbleMinDuration = 30
+
+// songs are scrobbled after being played for half their duration, but at most
+// this many seconds
+const scrobbleMaxDelay = 240
+
+// scrobbleDelay returns how long a song of duration seconds has to be played
+// to be scrobbled, false if it's too short to be scrobbled.
+func scrobbleDelay(duration int) (time.Duration, bool) {
+	if tooShortToScrobble(duration) {
+		return 0, false
+	}
+	return time.Duration(min(duration/2, scrobbleMaxDelay)) * time.Second, true
+}
+
+// tooShortToScrobble reflects whether a song of duration seconds is too short
+// to be scrobbled. Songs of unknown length, e.g. radio streams, have no half
+// to reach and are never scrobbled.
+func tooShortToScrobble(duration int) bool {
+	return duration <= scrobbleMinDuration
+}
+
+// "now playing" of songs up to this many seconds isn't refreshed, it lasts
+// long enough on last.fm and ListenBrainz
+const nowPlayingRefreshMinDuration = 300
+
+// nowPlayingRefreshDelay returns when to send "now playing" again for a song
+// of duration seconds, or 0 if it doesn't need a refresh.
+func nowPlayingRefreshDelay(interval time.Duration, duration int) time.Duration {
+	if interval <= 0 || duration <= nowPlayingRefreshMinDuration {
+		return 0
+	}
+	return interval
+}
+
 type eventLoop struct {
 	// scrobbles are handled by background loop
-	scrobbleNowPlaying      chan string
+	scrobbleNowPlayingTimer *time.Timer
 	scrobbleSubmissionTimer *time.Timer
+	// sends "now playing" again while a long song plays
+	scrobbleRefreshTimer *time.Timer
+	// see server.now-playing-refresh-s, 0 disables
+	nowPlayingRefresh time.Duration
+	// retries unsent scrobbles
+	scrobbleRetryTicker *time.Ticker
+	// pings the server, see server.ping-interval-s, 0 disables
+	pingTimer    *time.Timer
+	pingInterval time.Duration
+	serverHealth serverHealth
+	// IDs of completed songs to submit in ScrobbleOnlyComplete mode
+	scrobbleCompleted chan string
+
+	// closed on shutdown, the loops return
+	quit  chan struct{}
+	loops pendingWork
 }
 
 func (ui *Ui) initEventLoops() {
 	el := &eventLoop{
-		scrobbleNowPlaying: make(chan string, 5),
+		scrobbleCompleted: make(chan string, 5),
+		quit:              make(chan struct{}),
+		nowPlayingRefresh: time.Duration(viper.GetInt("server.now-playing-refresh-s")) * time.Second,
+		pingInterval:      time.Duration(viper.GetInt("server.ping-interval-s")) * time.Second,
+	}
+	if el.pingInterval <= 0 && ui.autoQuality.enabled {
+		// auto quality goes by the pings
+		el.pingInterval = autoQualityPingInterval
 	}
 	ui.eventLoop = el
 
+	// create reused timer to send "now playing" once rapid skips have settled
+	el.scrobbleNowPlayingTimer = time.NewTimer(0)
+	if !el.scrobbleNowPlayingTimer.Stop() {
+		<-el.scrobbleNowPlayingTimer.C
+	}
+
+	el.scrobbleRetryTicker = time.NewTicker(scrobbleRetryInterval)
+
 	// create reused timer to scrobble after delay
 	el.scrobbleSubmissionTimer = time.NewTimer(0)
 	if !el.scrobbleSubmissionTimer.Stop() {
 		<-el.scrobbleSubmissionTimer.C
 	}
+
+	// create reused timer to refresh "now playing"
+	el.scrobbleRefreshTimer = time.NewTimer(0)
+	if !el.scrobbleRefreshTimer.Stop() {
+		<-el.scrobbleRefreshTimer.C
+	}
+
+	// the first ping goes out right away
+	el.pingTimer = time.NewTimer(0)
+	if el.pingInterval <= 0 && !el.pingTimer.Stop() {
+		<-el.pingTimer.C
+	}
 }
 
 func (ui *Ui) runEventLoops() {
-	go ui.guiEventLoop()
-	go ui.backgroundEventLoop()
+	ui.eventLoop.loops.run(ui.guiEventLoop)
+	ui.eventLoop.loops.run(ui.backgroundEventLoop)
+}
+
+// stop makes the loops return after what they're doing, see stopBackground.
+func (el *eventLoop) stop() {
+	close(el.quit)
+}
+
+// stopTimers stops the timers of the loops once they returned.
+func (el *eventLoop) stopTimers() {
+	el.scrobbleNowPlayingTimer.Stop()
+	el.scrobbleSubmissionTimer.Stop()
+	el.scrobbleRefreshTimer.Stop()
+	el.scrobbleRetryTicker.Stop()
+	el.pingTimer.Stop()
 }
 
 // handle ui updates
 func (ui *Ui) guiEventLoop() {
 	ui.addStarredToList()
+	ui.app.QueueUpdateDraw(ui.browserPage.updateArtistStars)
 	events := 0.0
 	fpsTimer := time.NewTimer(0)
+	defer fpsTimer.Stop()
 
 	for {
 		events++
 
 		select {
+		case <-ui.eventLoop.quit:
+			return
+
 		case <-fpsTimer.C:
 			fpsTimer.Reset(10 * time.Second)
 			// ui.logger.Printf("guiEventLoop: %f events per second", events/10.0)
@@ -51,6 +162,11 @@ func (ui *Ui) guiEventLoop() {
 		case msg := <-ui.logger.Prints:
 			// handle log page output
 			ui.logPage.Print(msg)
+			if logger.IsError(msg) {
+				ui.app.QueueUpdateDraw(func() {
+					ui.notify(severityError, msg)
+				})
+			}
 
 		case mpvEvent := <-ui.mpvEvents:
 			events++
@@ -64,14 +180,48 @@ func (ui *Ui) guiEventLoop() {
 				statusData := mpvEvent.Data.(mpvplayer.StatusData) // TODO is this safe to access? maybe we need a copy
 
 				ui.app.QueueUpdateDraw(func() {
+					if ui.castRenderer != nil {
+						// progress comes from the cast device
+						return
+					}
+					if song, err := ui.player.GetQueueItem(0); err == nil {
+						ui.sessionStats.observePosition(song.Id, statusData.Position)
+					}
+					ui.nowPlayingFile.setPosition(int(statusData.Position))
 					ui.playerStatus.SetText(formatPlayerStatus(statusData.Volume, statusData.Position, statusData.Duration))
+					ui.progressWidget.SetProgress(statusData.Position, statusData.Duration)
+					ui.waveformWidget.SetProgress(statusData.Position, statusData.Duration)
+				})
+
+			case mpvplayer.EventBuffering:
+				bufferingData := mpvEvent.Data.(mpvplayer.BufferingData)
+				statusText := ""
+				if bufferingData.Buffering {
+					statusText = fmt.Sprintf("[orange::b]Buffering… %d%%[::-]", bufferingData.Percent)
+					statusText += ui.formatSongForStatusBar(&bufferingData.Item)
+				}
+
+				ui.app.QueueUpdateDraw(func() {
+					ui.setBufferingStatus(statusText)
 				})
 
 			case mpvplayer.EventStopped:
 				ui.logger.Print("mpvEvent: stopped")
+				ui.eventLoop.scrobbleRefreshTimer.Stop()
 				ui.app.QueueUpdateDraw(func() {
-					ui.startStopStatus.SetText("[red::b]Stopped[::-]")
+					ui.pauseOthers.playbackHalted()
+					if ui.castRenderer != nil {
+						// local playback was handed over to the cast device
+						return
+					}
+					ui.setBufferingStatus("")
+					ui.setPlaybackStatus("[red::b]Stopped[::-]")
+					ui.nowPlayingFile.setState(nowPlayingStopped)
+					ui.progressWidget.CancelSeekPreview()
+					ui.progressWidget.SetProgress(0, 0)
+					ui.waveformWidget.ClearSong()
 					ui.queuePage.UpdateQueue()
+					ui.extendRadio()
 				})
 
 			case mpvplayer.EventPlaying:
@@ -81,7 +231,7 @@ func (ui *Ui) guiEventLoop() {
 				var currentSong mpvplayer.QueueItem
 				if mpvEvent.Data != nil {
 					currentSong = mpvEvent.Data.(mpvplayer.QueueItem) // TODO is this safe to access? maybe we need a copy
-					statusText += formatSongForStatusBar(&currentSong)
+					statusText += ui.formatSongForStatusBar(&currentSong)
 
 					// Update MprisPlayer with new track info
 					if ui.mprisPlayer != nil {
@@ -89,21 +239,21 @@ func (ui *Ui) guiEventLoop() {
 					}
 
 					if ui.connection.Scrobble {
-						// scrobble "now playing" event (delegate to background event loop)
-						ui.eventLoop.scrobbleNowPlaying <- currentSong.Id
+						// scrobble "now playing" event (delegate to background event loop).
+						// this waits for the skip settle window so that tracks which are
+						// skipped through quickly don't get reported.
+						// the previous song's refresh starts over with this one.
+						ui.eventLoop.scrobbleRefreshTimer.Stop()
+						ui.eventLoop.scrobbleNowPlayingTimer.Reset(ui.player.SkipDebounce)
+					}
 
+					if ui.connection.Scrobble && ui.scrobbleMode != ScrobbleOnlyComplete {
 						// scrobble "submission" after song has been playing a bit
 						// see: https://www.last.fm/api/scrobbling
 						// A track should only be scrobbled when the following conditions have been met:
 						// The track must be longer than 30 seconds. And the track has been played for
 						// at least half its duration, or for 4 minutes (whichever occurs earlier.)
-						if currentSong.Duration > 30 {
-							scrobbleDelay := currentSong.Duration / 2
-							if scrobbleDelay > 240 {
-								scrobbleDelay = 240
-							}
-							scrobbleDuration := time.Duration(scrobbleDelay) * time.Second
-
+						if scrobbleDuration, ok := scrobbleDelay(currentSong.Duration); ok {
 							ui.eventLoop.scrobbleSubmissionTimer.Reset(scrobbleDuration)
 							ui.logger.Printf("scrobbler: timer started, %v", scrobbleDuration)
 						} else {
@@ -113,8 +263,21 @@ func (ui *Ui) guiEventLoop() {
 				}
 
 				ui.app.QueueUpdateDraw(func() {
-					ui.startStopStatus.SetText(statusText)
+					if ui.castRenderer != nil {
+						ui.castLocalSong(currentSong)
+						return
+					}
+					if ui.skipBlacklistedSong(currentSong) {
+						return
+					}
+					ui.onPlaying()
+					ui.setPlaybackStatus(statusText)
+					ui.nowPlayingFile.setSong(currentSong)
+					if currentSong.Id != "" {
+						ui.waveformWidget.SetSong(currentSong)
+					}
 					ui.queuePage.UpdateQueue()
+					ui.extendRadio()
 				})
 
 			case mpvplayer.EventPaused:
@@ -124,11 +287,14 @@ func (ui *Ui) guiEventLoop() {
 				var currentSong mpvplayer.QueueItem
 				if mpvEvent.Data != nil {
 					currentSong = mpvEvent.Data.(mpvplayer.QueueItem) // TODO is this safe to access? maybe we need a copy
-					statusText += formatSongForStatusBar(&currentSong)
+					statusText += ui.formatSongForStatusBar(&currentSong)
 				}
+				ui.eventLoop.scrobbleRefreshTimer.Stop()
 
 				ui.app.QueueUpdateDraw(func() {
-					ui.startStopStatus.SetText(statusText)
+					ui.setPlaybackStatus(statusText)
+					ui.pauseOthers.playbackHalted()
+					ui.nowPlayingFile.setState(nowPlayingPaused)
 				})
 
 			case mpvplayer.EventUnpaused:
@@ -138,11 +304,55 @@ func (ui *Ui) guiEventLoop() {
 				var currentSong mpvplayer.QueueItem
 				if mpvEvent.Data != nil {
 					currentSong = mpvEvent.Data.(mpvplayer.QueueItem) // TODO is this safe to access? maybe we need a copy
-					statusText += formatSongForStatusBar(&currentSong)
+					statusText += ui.formatSongForStatusBar(&currentSong)
+				}
+				if ui.connection.Scrobble && ui.eventLoop.nowPlayingRefresh > 0 {
+					// "now playing" may have expired while paused, send it
+					// again and resume refreshing
+					ui.eventLoop.scrobbleNowPlayingTimer.Reset(ui.player.SkipDebounce)
 				}
 
 				ui.app.QueueUpdateDraw(func() {
-					ui.startStopStatus.SetText(statusText)
+					ui.setPlaybackStatus(statusText)
+					ui.onPlaying()
+					ui.nowPlayingFile.setState(nowPlayingPlaying)
+				})
+
+			case mpvplayer.EventTrackEnded:
+				trackEnd := mpvEvent.Data.(mpvplayer.TrackEndData)
+				ui.app.QueueUpdateDraw(func() {
+					if trackEnd.Err != nil {
+						ui.trackFailed(trackEnd)
+					}
+					if ui.sessionStats.trackEnded(trackEnd.Item, trackEnd.Completed) {
+						ui.showNotice(fmt.Sprintf("%d songs played this session", len(ui.sessionStats.plays)))
+					}
+				})
+
+				if !ui.connection.Scrobble || ui.scrobbleMode != ScrobbleOnlyComplete {
+					continue
+				}
+				// every completed play counts, also when the same song is played again
+				if !trackEnd.Completed {
+					ui.logger.Printf("scrobbler: %s skipped, not scrobbling", trackEnd.Item.Id)
+				} else if tooShortToScrobble(trackEnd.Item.Duration) {
+					ui.logger.Printf("scrobbler: track too short")
+				} else {
+					ui.eventLoop.scrobbleCompleted <- trackEnd.Item.Id
+				}
+
+			case mpvplayer.EventRestarted:
+				restarts := mpvEvent.Data.(int)
+				ui.app.QueueUpdateDraw(func() {
+					ui.showNotice(fmt.Sprintf("mpv stopped working and was restarted (%d of %d)", restarts, ui.player.MaxRestarts))
+				})
+
+			case mpvplayer.EventFailed:
+				err := mpvEvent.Data.(error)
+				ui.app.QueueUpdateDraw(func() {
+					ui.setBufferingStatus("")
+					ui.setPlaybackStatus("[red::b]mpv failed[::-]")
+					ui.showMessageBox(fmt.Sprintf("%v\n\nPlayback doesn't work until stmps is restarted.", err))
 				})
 
 			default:
@@ -154,13 +364,23 @@ func (ui *Ui) guiEventLoop() {
 
 // loop for blocking background tasks that would otherwise block the ui
 func (ui *Ui) backgroundEventLoop() {
+	// scrobbles left over from earlier sessions
+	if ui.scrobbleQueue.Len() > 0 {
+		ui.retryScrobbles()
+	}
+
 	for {
 		select {
-		case songId := <-ui.eventLoop.scrobbleNowPlaying:
-			// scrobble now playing
-			if _, err := ui.connection.ScrobbleSubmission(songId, false); err != nil {
-				ui.logger.PrintError("scrobble nowplaying", err)
-			}
+		case <-ui.eventLoop.quit:
+			return
+
+		case <-ui.eventLoop.scrobbleNowPlayingTimer.C:
+			// scrobble now playing for the track we landed on
+			ui.scrobbleNowPlaying()
+
+		case <-ui.eventLoop.scrobbleRefreshTimer.C:
+			// long song still playing, keep its "now playing" from expiring
+			ui.scrobbleNowPlaying()
 
 		case <-ui.eventLoop.scrobbleSubmissionTimer.C:
 			// scrobble submission delay elapsed
@@ -170,14 +390,82 @@ func (ui *Ui) backgroundEventLoop() {
 			} else {
 				// it's still playing
 				ui.logger.Printf("scrobbling: %s", currentSong.Id)
-				if _, err := ui.connection.ScrobbleSubmission(currentSong.Id, true); err != nil {
-					ui.logger.PrintError("scrobble submission", err)
-				}
+				ui.submitScrobble(currentSong.Id)
 			}
+
+		case id := <-ui.eventLoop.scrobbleCompleted:
+			// song played until its end
+			ui.logger.Printf("scrobbling: %s", id)
+			ui.submitScrobble(id)
+
+		case <-ui.eventLoop.scrobbleRetryTicker.C:
+			if ui.scrobbleQueue.Len() > 0 {
+				ui.retryScrobbles()
+			}
+
+		case <-ui.eventLoop.pingTimer.C:
+			ui.eventLoop.pingTimer.Reset(ui.pingServer(ui.eventLoop.pingInterval))
 		}
 	}
 }
 
+// scrobbleNowPlaying sends "now playing" for the current song and schedules
+// its refresh if it's a long one. Called from the background loop.
+func (ui *Ui) scrobbleNowPlaying() {
+	currentSong, err := ui.player.GetPlayingTrack()
+	if err != nil {
+		// paused or stopped, playing again sends it
+		ui.logger.Printf("not scrobbling now playing: %v", err)
+		return
+	}
+	if _, err := ui.connection.ScrobbleSubmission(currentSong.Id, false); err != nil {
+		ui.logger.PrintError("scrobble nowplaying", err)
+	}
+	if delay := nowPlayingRefreshDelay(ui.eventLoop.nowPlayingRefresh, currentSong.Duration); delay > 0 {
+		ui.eventLoop.scrobbleRefreshTimer.Reset(delay)
+	}
+}
+
+// submitScrobble submits a play. If that fails, it's queued and retried
+// later with the time it happened. Queued scrobbles go first, so the server
+// gets the plays in order.
+func (ui *Ui) submitScrobble(id string) {
+	scrobble := pendingScrobble{Id: id, PlayedAt: time.Now()}
+	retry := ui.scrobbleQueue.Len() > 0
+	if !retry {
+		_, err := ui.connection.ScrobbleSubmission(id, true)
+		if err == nil {
+			return
+		}
+		ui.logger.PrintError("scrobble submission", err)
+	}
+
+	if err := ui.scrobbleQueue.Add(scrobble); err != nil {
+		ui.logger.PrintError("scrobble queue", err)
+	}
+	if retry {
+		ui.retryScrobbles()
+	} else {
+		ui.app.QueueUpdateDraw(ui.updateScrobbleStatus)
+	}
+}
+
+// retryScrobbles submits the queued scrobbles until one fails. Called from
+// the background loop.
+func (ui *Ui) retryScrobbles() {
+	sent, err := ui.scrobbleQueue.Flush(func(scrobble pendingScrobble) error {
+		_, err := ui.connection.ScrobbleSubmissionAt(scrobble.Id, scrobble.PlayedAt)
+		return err
+	})
+	if sent > 0 {
+		ui.logger.Printf("scrobbler: sent %d queued scrobbles", sent)
+	}
+	if err != nil {
+		ui.logger.Printf("scrobbler: %d scrobbles queued, retrying later: %v", ui.scrobbleQueue.Len(), err)
+	}
+	ui.app.QueueUpdateDraw(ui.updateScrobbleStatus)
+}
+
 func (ui *Ui) addStarredToList() {
 	response, err := ui.connection.GetStarred()
 	if err != nil {
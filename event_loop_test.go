@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNowPlayingRefreshDelay(t *testing.T) {
+	interval := 3 * time.Minute
+
+	assert.Equal(t, interval, nowPlayingRefreshDelay(interval, 1200))
+	// short songs don't need a refresh
+	assert.Equal(t, time.Duration(0), nowPlayingRefreshDelay(interval, nowPlayingRefreshMinDuration))
+	assert.Equal(t, time.Duration(0), nowPlayingRefreshDelay(interval, 200))
+	// disabled
+	assert.Equal(t, time.Duration(0), nowPlayingRefreshDelay(0, 1200))
+}
+
+func TestScrobbleDelay(t *testing.T) {
+	delay, ok := scrobbleDelay(200)
+	assert.True(t, ok)
+	assert.Equal(t, 100*time.Second, delay)
+	// at most 4 minutes
+	delay, ok = scrobbleDelay(1200)
+	assert.True(t, ok)
+	assert.Equal(t, 240*time.Second, delay)
+	// too short
+	_, ok = scrobbleDelay(scrobbleMinDuration)
+	assert.False(t, ok)
+	assert.True(t, tooShortToScrobble(scrobbleMinDuration))
+
+	// unknown length, e.g. radio streams
+	_, ok = scrobbleDelay(0)
+	assert.False(t, ok)
+	assert.True(t, tooShortToScrobble(0))
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/mpvplayer"
+)
+
+// number of failed songs kept, older ones are dropped
+const maxFailedTracks = 100
+
+// failedTrack is a song that couldn't be played in this session.
+type failedTrack struct {
+	item   mpvplayer.QueueItem
+	at     time.Time
+	reason string
+}
+
+// format returns the entry as a line of the failed songs list.
+func (f failedTrack) format() string {
+	return "[gray]" + f.at.Local().Format("15:04:05") + "[-] " + tview.Escape(f.item.Title) +
+		" [gray]- " + tview.Escape(f.reason) + "[-]"
+}
+
+// failedTracks keeps the songs that failed, oldest first. A song that fails
+// again only keeps its latest entry.
+type failedTracks struct {
+	entries []failedTrack
+	size    int
+}
+
+func newFailedTracks(size int) *failedTracks {
+	return &failedTracks{size: size}
+}
+
+func (l *failedTracks) add(track failedTrack) {
+	for i, entry := range l.entries {
+		if entry.item.Id == track.item.Id {
+			l.entries = append(l.entries[:i], l.entries[i+1:]...)
+			break
+		}
+	}
+	l.entries = append(l.entries, track)
+	if len(l.entries) > l.size {
+		l.entries = l.entries[len(l.entries)-l.size:]
+	}
+}
+
+// take removes the entry at index and returns it.
+func (l *failedTracks) take(index int) (failedTrack, bool) {
+	if index < 0 || index >= len(l.entries) {
+		return failedTrack{}, false
+	}
+	track := l.entries[index]
+	l.entries = append(l.entries[:index], l.entries[index+1:]...)
+	return track, true
+}
+
+// takeAll empties the list and returns its entries.
+func (l *failedTracks) takeAll() []failedTrack {
+	entries := l.entries
+	l.entries = nil
+	return entries
+}
+
+// trackFailed remembers a song that couldn't be played. Must be called from
+// the gui goroutine.
+func (ui *Ui) trackFailed(end mpvplayer.TrackEndData) {
+	ui.failedTracks.add(failedTrack{item: end.Item, at: time.Now(), reason: end.Err.Error()})
+	ui.notify(severityError, fmt.Sprintf("Couldn't play %s: %v", end.Item.Title, end.Err))
+	if ui.failedTracksWidget != nil && ui.failedTracksWidget.visible {
+		ui.failedTracksWidget.updateList()
+	}
+}
+
+// retryFailedTracks adds the songs to the end of the queue again, and starts
+// playing if nothing is loaded.
+func (ui *Ui) retryFailedTracks(tracks []failedTrack) {
+	if len(tracks) == 0 {
+		return
+	}
+
+	ui.startQueueAdd(queueAppend)
+	for _, track := range tracks {
+		item := track.item
+		ui.addQueueItem(&item)
+	}
+	if report := ui.finishQueueAdd(); report.skipped == 0 {
+		ui.showNotice(fmt.Sprintf("Retrying %d failed songs", report.added))
+	}
+
+	if ui.castRenderer != nil {
+		return
+	}
+	if loaded, err := ui.player.IsSongLoaded(); err != nil {
+		ui.logger.PrintError("retryFailedTracks: IsSongLoaded", err)
+	} else if !loaded {
+		if err := ui.player.Play(); err != nil {
+			ui.logger.PrintError("retryFailedTracks: Play", err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailedTracks(t *testing.T) {
+	tracks := newFailedTracks(2)
+	at := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tracks.add(failedTrack{item: mpvplayer.QueueItem{Id: "1"}, at: at, reason: "timeout"})
+	tracks.add(failedTrack{item: mpvplayer.QueueItem{Id: "2"}, at: at, reason: "timeout"})
+	// failing again moves the song to the end with the new reason
+	tracks.add(failedTrack{item: mpvplayer.QueueItem{Id: "1"}, at: at, reason: "loading failed"})
+	assert.Len(t, tracks.entries, 2)
+	assert.Equal(t, "2", tracks.entries[0].item.Id)
+	assert.Equal(t, "loading failed", tracks.entries[1].reason)
+
+	// the oldest entry is dropped
+	tracks.add(failedTrack{item: mpvplayer.QueueItem{Id: "3"}, at: at, reason: "timeout"})
+	assert.Equal(t, "1", tracks.entries[0].item.Id)
+
+	track, ok := tracks.take(1)
+	assert.True(t, ok)
+	assert.Equal(t, "3", track.item.Id)
+	_, ok = tracks.take(1)
+	assert.False(t, ok)
+
+	all := tracks.takeAll()
+	assert.Len(t, all, 1)
+	assert.Empty(t, tracks.entries)
+}
+
+func TestFailedTrackFormat(t *testing.T) {
+	track := failedTrack{
+		item:   mpvplayer.QueueItem{Title: "Song [live]"},
+		at:     time.Now(),
+		reason: "loading failed",
+	}
+	assert.Contains(t, track.format(), "Song [live[]")
+	assert.Contains(t, track.format(), "loading failed")
+}
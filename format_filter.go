@@ -0,0 +1,141 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
+)
+
+// Which songs the browser shows, see formatFilter.
+const (
+	FormatFilterOff      = "off"
+	FormatFilterLossless = "lossless"
+	FormatFilterBitrate  = "bitrate"
+)
+
+// bitrate of FormatFilterBitrate if client.min-bitrate isn't set
+const defaultMinBitrate = 256
+
+// file suffixes and content types of lossless formats. ALAC usually comes
+// in .m4a files like lossy AAC, it's only recognized by its content type.
+var (
+	losslessSuffixes     = []string{"flac", "alac", "wav", "aif", "aiff", "ape", "wv"}
+	losslessContentTypes = []string{"audio/flac", "audio/x-flac", "audio/x-alac", "audio/wav", "audio/x-wav", "audio/aiff", "audio/x-aiff", "audio/x-ape", "audio/x-wavpack"}
+)
+
+// isLossless reports whether the song's file has a lossless format.
+func isLossless(song subsonic.SubsonicEntity) bool {
+	return containsString(losslessSuffixes, strings.ToLower(song.Suffix)) ||
+		containsString(losslessContentTypes, strings.ToLower(song.ContentType))
+}
+
+// formatLabel returns the file format and bitrate of a song, e.g.
+// "FLAC 1011k", or "" if the server doesn't report them.
+func formatLabel(song subsonic.SubsonicEntity) string {
+	format := song.Suffix
+	if format == "" {
+		// e.g. audio/mpeg
+		_, format, _ = strings.Cut(song.ContentType, "/")
+		format = strings.TrimPrefix(format, "x-")
+	}
+	label := strings.ToUpper(format)
+	if song.BitRate > 0 {
+		label = strings.TrimSpace(fmt.Sprintf("%s %dk", label, song.BitRate))
+	}
+	return label
+}
+
+// formatFilter hides songs by file format or bitrate. Folders and albums are
+// always shown.
+type formatFilter struct {
+	mode string
+	// kbps songs need for FormatFilterBitrate
+	minBitrate int
+}
+
+// loadFormatFilter reads client.format-filter and client.min-bitrate. The
+// config was validated at startup.
+func loadFormatFilter() formatFilter {
+	filter := formatFilter{mode: FormatFilterOff, minBitrate: defaultMinBitrate}
+	if mode := viper.GetString("client.format-filter"); mode != "" {
+		filter.mode = mode
+	}
+	if viper.IsSet("client.min-bitrate") {
+		filter.minBitrate = viper.GetInt("client.min-bitrate")
+	}
+	return filter
+}
+
+func (f formatFilter) allows(entity subsonic.SubsonicEntity) bool {
+	if entity.IsDirectory {
+		return true
+	}
+	switch f.mode {
+	case FormatFilterLossless:
+		return isLossless(entity)
+	case FormatFilterBitrate:
+		return entity.BitRate >= f.minBitrate
+	}
+	return true
+}
+
+// filter returns the entities the filter allows.
+func (f formatFilter) filter(entities []subsonic.SubsonicEntity) []subsonic.SubsonicEntity {
+	if f.mode == FormatFilterOff {
+		return entities
+	}
+	var allowed []subsonic.SubsonicEntity
+	for _, entity := range entities {
+		if f.allows(entity) {
+			allowed = append(allowed, entity)
+		}
+	}
+	return allowed
+}
+
+// cycle switches to the next mode: off, lossless, bitrate.
+func (f *formatFilter) cycle() {
+	switch f.mode {
+	case FormatFilterOff:
+		f.mode = FormatFilterLossless
+	case FormatFilterLossless:
+		f.mode = FormatFilterBitrate
+	default:
+		f.mode = FormatFilterOff
+	}
+}
+
+func (f formatFilter) String() string {
+	switch f.mode {
+	case FormatFilterLossless:
+		return "lossless songs only"
+	case FormatFilterBitrate:
+		return fmt.Sprintf("songs with %d kbps or more only", f.minBitrate)
+	}
+	return "all songs"
+}
+
+// formatColumn returns the format label of a song for the end of a list
+// entry, if ui.show-format is on.
+func (ui *Ui) formatColumn(song subsonic.SubsonicEntity) string {
+	if !ui.showFormat || song.IsDirectory {
+		return ""
+	}
+	if label := formatLabel(song); label != "" {
+		return " [gray]" + label + "[-]"
+	}
+	return ""
+}
+
+// toggleFormatColumn shows or hides the format of songs in the browser and
+// search results.
+func (ui *Ui) toggleFormatColumn() {
+	ui.showFormat = !ui.showFormat
+	ui.browserPage.reloadEntityList()
+	ui.searchPage.updateResults()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsLossless(t *testing.T) {
+	assert.True(t, isLossless(subsonic.SubsonicEntity{Suffix: "FLAC"}))
+	assert.True(t, isLossless(subsonic.SubsonicEntity{Suffix: "m4a", ContentType: "audio/x-alac"}))
+	assert.False(t, isLossless(subsonic.SubsonicEntity{Suffix: "m4a", ContentType: "audio/mp4"}))
+	assert.False(t, isLossless(subsonic.SubsonicEntity{Suffix: "mp3", ContentType: "audio/mpeg"}))
+	assert.False(t, isLossless(subsonic.SubsonicEntity{}))
+}
+
+func TestFormatLabel(t *testing.T) {
+	assert.Equal(t, "FLAC 1011k", formatLabel(subsonic.SubsonicEntity{Suffix: "flac", BitRate: 1011}))
+	assert.Equal(t, "MPEG", formatLabel(subsonic.SubsonicEntity{ContentType: "audio/mpeg"}))
+	assert.Equal(t, "FLAC", formatLabel(subsonic.SubsonicEntity{ContentType: "audio/x-flac"}))
+	assert.Equal(t, "320k", formatLabel(subsonic.SubsonicEntity{BitRate: 320}))
+	assert.Equal(t, "", formatLabel(subsonic.SubsonicEntity{}))
+}
+
+func TestFormatFilter(t *testing.T) {
+	entities := []subsonic.SubsonicEntity{
+		{Id: "dir", IsDirectory: true},
+		{Id: "flac", Suffix: "flac", BitRate: 900},
+		{Id: "mp3", Suffix: "mp3", BitRate: 320},
+		{Id: "ogg", Suffix: "ogg", BitRate: 128},
+	}
+	ids := func(entities []subsonic.SubsonicEntity) (ids []string) {
+		for _, e := range entities {
+			ids = append(ids, e.Id)
+		}
+		return
+	}
+
+	filter := formatFilter{mode: FormatFilterOff, minBitrate: 256}
+	assert.Equal(t, []string{"dir", "flac", "mp3", "ogg"}, ids(filter.filter(entities)))
+
+	filter.cycle()
+	assert.Equal(t, FormatFilterLossless, filter.mode)
+	assert.Equal(t, []string{"dir", "flac"}, ids(filter.filter(entities)))
+
+	filter.cycle()
+	assert.Equal(t, FormatFilterBitrate, filter.mode)
+	assert.Equal(t, []string{"dir", "flac", "mp3"}, ids(filter.filter(entities)))
+	assert.Equal(t, "songs with 256 kbps or more only", filter.String())
+
+	filter.cycle()
+	assert.Equal(t, FormatFilterOff, filter.mode)
+}
+
+func TestLoadFormatFilter(t *testing.T) {
+	loadTestConfig(t, "")
+	assert.Equal(t, formatFilter{mode: FormatFilterOff, minBitrate: defaultMinBitrate}, loadFormatFilter())
+
+	loadTestConfig(t, "[client]\nformat-filter = 'bitrate'\nmin-bitrate = 320\n")
+	assert.Equal(t, formatFilter{mode: FormatFilterBitrate, minBitrate: 320}, loadFormatFilter())
+}
@@ -12,6 +12,8 @@ require (
 )
 
 require (
+	github.com/atotto/clipboard v0.1.4
+	github.com/pelletier/go-toml/v2 v2.2.3
 	github.com/spezifisch/tview-command v0.0.0-20241013143719-94366d6323e2
 	github.com/stretchr/testify v1.9.0
 	github.com/supersonic-app/go-mpv v0.1.0
@@ -27,7 +29,6 @@ require (
 	github.com/magiconair/properties v1.8.7 // indirect
 	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/mitchellh/mapstructure v1.5.0 // indirect
-	github.com/pelletier/go-toml/v2 v2.2.3 // indirect
 	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/sagikazarmark/locafero v0.6.0 // indirect
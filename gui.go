@@ -5,26 +5,44 @@ package main
 
 import (
 	"fmt"
+	"text/template"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/cast"
 	"github.com/spezifisch/stmps/logger"
 	"github.com/spezifisch/stmps/mpvplayer"
 	"github.com/spezifisch/stmps/remote"
 	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
 )
 
 // struct contains all the updatable elements of the Ui
 type Ui struct {
-	app   *tview.Application
-	pages *tview.Pages
+	app      *tview.Application
+	pages    *tview.Pages
+	rootFlex *tview.Flex
 
 	// top bar
+	topBarFlex      *tview.Flex
 	startStopStatus *tview.TextView
+	nextSongStatus  *tview.TextView
+	scrobbleStatus  *tview.TextView
+	serverStatus    *tview.TextView
 	playerStatus    *tview.TextView
 
+	// text shown in startStopStatus, temporarily replaced by notices
+	playbackStatus string
+	noticeActive   bool
+	noticeSeq      int
+	// shown instead of playbackStatus while the stream is buffering
+	bufferingStatus string
+
 	// bottom bar
-	menuWidget *MenuWidget
+	waveformWidget *WaveformWidget
+	progressWidget *ProgressWidget
+	menuWidget     *MenuWidget
 
 	// browser page
 	browserPage *BrowserPage
@@ -38,22 +56,128 @@ type Ui struct {
 	// search page
 	searchPage *SearchPage
 
+	// recently added page
+	newPage *NewPage
+
+	// starred page
+	starredPage *StarredPage
+
+	// stats page
+	statsPage    *StatsPage
+	decadesPage  *DecadesPage
+	sessionStats *sessionStats
+
 	// log page
 	logPage *LogPage
 
 	// modals
 	addToPlaylistList    *tview.List
 	messageBox           *tview.Modal
+	quitModal            *tview.Modal
 	helpModal            tview.Primitive
 	helpWidget           *HelpWidget
 	selectPlaylistModal  tview.Primitive
 	selectPlaylistWidget *PlaylistSelectionWidget
+	smartMixModal        tview.Primitive
+	smartMixWidget       *SmartMixWidget
+	castModal            tview.Primitive
+	castWidget           *CastWidget
+	outputsModal         tview.Primitive
+	outputsWidget        *OutputsWidget
+	notificationsModal   tview.Primitive
+	notificationsWidget  *NotificationsWidget
+	failedTracksModal    tview.Primitive
+	failedTracksWidget   *FailedTracksWidget
+	aboutModal           tview.Primitive
+	aboutWidget          *AboutWidget
+	contextMenuModal     tview.Primitive
+	contextMenuWidget    *ContextMenuWidget
+
+	// enabled main pages in menu order, see views.go
+	views []string
+
+	// recent notices and errors, see notifications.go
+	notifications *notificationLog
+	// songs that couldn't be played, see failed_tracks.go
+	failedTracks *failedTracks
+
+	// named smart mixes from the config, and the ones saved in this session
+	smartMixes []smartMix
 
 	starIdList map[string]struct{}
 
+	// cached ping response, see serverType()
+	serverInfo *subsonic.SubsonicResponse
+	// the server's license state, empty if it doesn't report one, see
+	// license.go
+	licenseText string
+
 	eventLoop   *eventLoop
 	mpvEvents   chan mpvplayer.UiEvent
 	mprisPlayer *remote.MprisPlayer
+	// what the OS media controls control, the cast device while casting
+	remotePlayer *remote.SwitchedPlayer
+	// writes to the server that quitting waits for, see shutdown()
+	pendingWork pendingWork
+
+	// media renderer that playback is sent to, nil for local playback
+	castRenderer *cast.Renderer
+	// calls to castRenderer, see castCall
+	castCalls chan func()
+
+	// which artist song lists and the status bar show
+	artistDisplay ArtistDisplay
+	// how the status bar shows the current song, see ui.status-format
+	statusFormat *template.Template
+
+	// action sequences bound to keys, from the [[macros]] config
+	macros map[rune]macro
+
+	// screensaver after ui.idle-timeout-s
+	idle idleState
+
+	// library scan started or followed with Ctrl-R
+	libraryScan libraryScan
+
+	// songs the random modes leave out
+	contentFilter contentFilter
+	// keeps the queue filled once started with 'M'
+	radio artistRadio
+	// skip blacklisted songs that come up in the queue
+	skipBlacklisted bool
+	pauseOthers     pauseOthers
+	// the current song for streaming overlays
+	nowPlayingFile *nowPlayingFile
+
+	// show the file format and bitrate of songs in the browser and search
+	showFormat bool
+	// songs the browser shows
+	formatFilter formatFilter
+	// how far the seek keys jump
+	seekSteps seekSteps
+
+	// what addSongToQueue does with songs already in the queue
+	duplicatePolicy DuplicateQueuePolicy
+	queueAdds       queueAddReport
+
+	// when plays are scrobbled, if server.scrobble is set
+	scrobbleMode ScrobbleMode
+	// scrobbles that failed to submit, retried by the background loop
+	scrobbleQueue *scrobbleQueue
+
+	// playback settings of the connected server, stored on quit
+	serverProfile  string
+	serverSettings serverSettings
+	// remembered gains of songs, see track_gain.go
+	trackGains trackGains
+	// lowers the bitrate on slow connections, see auto_quality.go
+	autoQuality autoQuality
+	// last server health shown in the top bar
+	serverHealthText string
+
+	// selected entry and scroll offset of the views by page name, see
+	// view_positions.go
+	viewPositions map[string]viewPosition
 
 	playlists  []subsonic.SubsonicPlaylist
 	connection *subsonic.SubsonicConnection
@@ -61,6 +185,9 @@ type Ui struct {
 	logger     *logger.Logger
 }
 
+// how long notices replace the playback status
+const noticeDuration = 5 * time.Second
+
 const (
 	// page identifiers (use these instead of hardcoding page names for showing/hiding)
 	PageBrowser   = "browser"
@@ -68,6 +195,10 @@ const (
 	PagePlaylists = "playlists"
 	PageSearch    = "search"
 	PageLog       = "log"
+	PageNew       = "new"
+	PageStats     = "stats"
+	PageDecades   = "decades"
+	PageStarred   = "starred"
 
 	PageDeletePlaylist = "deletePlaylist"
 	PageNewPlaylist    = "newPlaylist"
@@ -75,33 +206,74 @@ const (
 	PageMessageBox     = "messageBox"
 	PageHelpBox        = "helpBox"
 	PageSelectPlaylist = "selectPlaylist"
+	PageQuitConfirm    = "quitConfirm"
+	PageSmartMix       = "smartMix"
+	PageCast           = "cast"
+	PageOutputs        = "outputs"
+	PageNotifications  = "notifications"
+	PageFailedTracks   = "failedTracks"
+	PageAbout          = "about"
+	PageContextMenu    = "contextMenu"
+	PageStatsExport    = "stats-export"
+	PageImportPlaylist = "importPlaylist"
 )
 
-func InitGui(indexes *[]subsonic.SubsonicIndex,
+func InitGui(indexes *subsonic.SubsonicIndexes,
 	connection *subsonic.SubsonicConnection,
 	player *mpvplayer.Player,
 	logger *logger.Logger,
-	mprisPlayer *remote.MprisPlayer) (ui *Ui) {
+	mprisPlayer *remote.MprisPlayer,
+	remotePlayer *remote.SwitchedPlayer) (ui *Ui) {
 	ui = &Ui{
-		starIdList: map[string]struct{}{},
+		starIdList:   map[string]struct{}{},
+		sessionStats: newSessionStats(),
 
 		eventLoop: nil, // initialized by initEventLoops()
 		mpvEvents: make(chan mpvplayer.UiEvent, 5),
 
-		playlists:   []subsonic.SubsonicPlaylist{},
-		connection:  connection,
-		player:      player,
-		logger:      logger,
-		mprisPlayer: mprisPlayer,
+		artistDisplay:   ArtistDisplay(viper.GetString("ui.display-artist")),
+		statusFormat:    loadStatusFormat(logger),
+		macros:          loadMacros(),
+		views:           loadViews(),
+		notifications:   newNotificationLog(maxNotifications),
+		failedTracks:    newFailedTracks(maxFailedTracks),
+		contentFilter:   loadContentFilter(),
+		radio:           loadArtistRadio(),
+		skipBlacklisted: viper.GetBool("client.skip-blacklisted"),
+		showFormat:      viper.GetBool("ui.show-format"),
+		formatFilter:    loadFormatFilter(),
+		seekSteps:       loadSeekSteps(),
+		autoQuality:     loadAutoQuality(),
+		pauseOthers:     pauseOthers{enabled: viper.GetBool("player.pause-others-on-play")},
+		nowPlayingFile:  loadNowPlayingFile(logger),
+		idle:            idleState{timeout: time.Duration(viper.GetInt("ui.idle-timeout-s")) * time.Second},
+		duplicatePolicy: DuplicateQueuePolicy(viper.GetString("client.duplicate-policy")),
+		scrobbleMode:    ScrobbleMode(viper.GetString("server.scrobble-mode")),
+
+		playlists:    []subsonic.SubsonicPlaylist{},
+		connection:   connection,
+		player:       player,
+		logger:       logger,
+		mprisPlayer:  mprisPlayer,
+		remotePlayer: remotePlayer,
 	}
 
 	ui.initEventLoops()
+	ui.restoreServerSettings()
+	ui.loadScrobbleQueue()
 
 	ui.app = tview.NewApplication()
 	ui.pages = tview.NewPages()
 
+	connection.AuthFailed = func(err error) {
+		ui.app.QueueUpdateDraw(func() {
+			ui.showNotice("Server rejected the login, check auth.username and auth.password")
+		})
+	}
+
 	// status text at the top
 	statusLeft := fmt.Sprintf("[::b]%s[::-] v%s", clientName, clientVersion)
+	ui.playbackStatus = statusLeft
 	ui.startStopStatus = tview.NewTextView().SetText(statusLeft).
 		SetTextAlign(tview.AlignLeft).
 		SetDynamicColors(true).
@@ -110,12 +282,32 @@ func InitGui(indexes *[]subsonic.SubsonicIndex,
 		return action, nil
 	})
 
+	// upcoming song, only shown if there is one
+	ui.nextSongStatus = tview.NewTextView().
+		SetTextAlign(tview.AlignRight).
+		SetDynamicColors(true).
+		SetScrollable(false)
+
+	// unsent scrobbles, only shown if there are any
+	ui.scrobbleStatus = tview.NewTextView().
+		SetTextAlign(tview.AlignRight).
+		SetDynamicColors(true).
+		SetScrollable(false)
+
+	// server health, only shown if server.ping-interval-s is set
+	ui.serverStatus = tview.NewTextView().
+		SetTextAlign(tview.AlignRight).
+		SetDynamicColors(true).
+		SetScrollable(false)
+
 	statusRight := formatPlayerStatus(0, 0, 0)
 	ui.playerStatus = tview.NewTextView().SetText(statusRight).
 		SetTextAlign(tview.AlignRight).
 		SetDynamicColors(true).
 		SetScrollable(false)
 
+	ui.waveformWidget = ui.createWaveformWidget(ui.panelVisibility(panelWaveform, "ui.waveform", false), viper.GetInt("ui.waveform-height"))
+	ui.progressWidget = ui.createProgressWidget()
 	ui.menuWidget = ui.createMenuWidget()
 	ui.helpWidget = ui.createHelpWidget()
 	ui.selectPlaylistWidget = ui.createPlaylistSelectionWidget()
@@ -135,6 +327,38 @@ func InitGui(indexes *[]subsonic.SubsonicIndex,
 
 	ui.selectPlaylistModal = makeModal(ui.selectPlaylistWidget.Root, 80, 5)
 
+	// quit confirmation
+	ui.quitModal = ui.createQuitModal()
+
+	// smart mix builder
+	ui.smartMixes = loadSmartMixes()
+	ui.smartMixWidget = ui.createSmartMixWidget()
+	ui.smartMixModal = makeModal(ui.smartMixWidget.Root, 70, 28)
+
+	// cast device picker
+	ui.castWidget = ui.createCastWidget()
+	ui.castModal = makeModal(ui.castWidget.Root, 60, 16)
+
+	// audio output picker
+	ui.outputsWidget = ui.createOutputsWidget()
+	ui.outputsModal = makeModal(ui.outputsWidget.Root, 70, 20)
+
+	// notifications panel
+	ui.notificationsWidget = ui.createNotificationsWidget()
+	ui.notificationsModal = makeModal(ui.notificationsWidget.Root, 100, 24)
+
+	// songs that failed to play
+	ui.failedTracksWidget = ui.createFailedTracksWidget()
+	ui.failedTracksModal = makeModal(ui.failedTracksWidget.Root, 100, 24)
+
+	// stmps, server and mpv versions
+	ui.aboutWidget = ui.createAboutWidget()
+	ui.aboutModal = makeModal(ui.aboutWidget.Root, 80, 20)
+
+	// actions for the selected item
+	ui.contextMenuWidget = ui.createContextMenuWidget()
+	ui.contextMenuModal = makeModal(ui.contextMenuWidget.Root, 50, 14)
+
 	// help box modal
 	ui.helpModal = makeModal(ui.helpWidget.Root, 80, 30)
 	ui.helpWidget.Root.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -149,9 +373,14 @@ func InitGui(indexes *[]subsonic.SubsonicIndex,
 	})
 
 	// top bar: status text
-	topBarFlex := tview.NewFlex().SetDirection(tview.FlexColumn).
+	ui.topBarFlex = tview.NewFlex().SetDirection(tview.FlexColumn).
 		AddItem(ui.startStopStatus, 0, 1, false).
+		AddItem(ui.nextSongStatus, 0, 0, false).
+		AddItem(ui.scrobbleStatus, 0, 0, false).
+		AddItem(ui.serverStatus, 0, 0, false).
 		AddItem(ui.playerStatus, 20, 0, false)
+	ui.updateScrobbleStatus()
+	ui.updateServerStatus("")
 
 	// browser page
 	ui.browserPage = ui.createBrowserPage(indexes)
@@ -165,9 +394,27 @@ func InitGui(indexes *[]subsonic.SubsonicIndex,
 	// search page
 	ui.searchPage = ui.createSearchPage()
 
+	if !ui.playedDatesSupported() {
+		// songs can only be sorted by when they were played with OpenSubsonic
+		ui.browserPage.sortOrders.songs.hide(sortKeyPlayed)
+		ui.searchPage.sortOrders.songs.hide(sortKeyPlayed)
+	}
+
 	// log page
 	ui.logPage = ui.createLogPage()
 
+	// recently added page
+	ui.newPage = ui.createNewPage()
+
+	// stats page
+	ui.statsPage = ui.createStatsPage()
+	ui.decadesPage = ui.createDecadesPage()
+
+	// starred page
+	ui.starredPage = ui.createStarredPage()
+
+	ui.setListWrap(viper.GetBool("ui.wrap-lists"))
+
 	ui.pages.AddPage(PageBrowser, ui.browserPage.Root, true, true).
 		AddPage(PageQueue, ui.queuePage.Root, true, false).
 		AddPage(PagePlaylists, ui.playlistPage.Root, true, false).
@@ -178,22 +425,49 @@ func InitGui(indexes *[]subsonic.SubsonicIndex,
 		AddPage(PageSelectPlaylist, ui.selectPlaylistModal, true, false).
 		AddPage(PageMessageBox, ui.messageBox, true, false).
 		AddPage(PageHelpBox, ui.helpModal, true, false).
-		AddPage(PageLog, ui.logPage.Root, true, false)
-
-	rootFlex := tview.NewFlex().
+		AddPage(PageQuitConfirm, ui.quitModal, true, false).
+		AddPage(PageSmartMix, ui.smartMixModal, true, false).
+		AddPage(PageCast, ui.castModal, true, false).
+		AddPage(PageOutputs, ui.outputsModal, true, false).
+		AddPage(PageNotifications, ui.notificationsModal, true, false).
+		AddPage(PageFailedTracks, ui.failedTracksModal, true, false).
+		AddPage(PageAbout, ui.aboutModal, true, false).
+		AddPage(PageContextMenu, ui.contextMenuModal, true, false).
+		AddPage(PageLog, ui.logPage.Root, true, false).
+		AddPage(PageNew, ui.newPage.Root, true, false).
+		AddPage(PageStats, ui.statsPage.Root, true, false).
+		AddPage(PageDecades, ui.decadesPage.Root, true, false).
+		AddPage(PageStarred, ui.starredPage.Root, true, false).
+		AddPage(PageStatsExport, ui.statsPage.ExportModal, true, false).
+		AddPage(PageImportPlaylist, ui.playlistPage.ImportPlaylistModal, true, false)
+
+	ui.rootFlex = tview.NewFlex().
 		SetDirection(tview.FlexRow).
-		AddItem(topBarFlex, 1, 0, false).
+		AddItem(ui.topBarFlex, 1, 0, false).
 		AddItem(ui.pages, 0, 1, true).
+		AddItem(ui.waveformWidget, ui.waveformWidget.Height(), 0, false).
+		AddItem(ui.progressWidget, 1, 0, false).
 		AddItem(ui.menuWidget.Root, 1, 0, false)
 
 	// add main input handler
-	rootFlex.SetInputCapture(ui.handlePageInput)
+	ui.rootFlex.SetInputCapture(ui.handlePageInput)
 
-	ui.app.SetRoot(rootFlex, true).
-		SetFocus(rootFlex).
+	ui.app.SetRoot(ui.rootFlex, true).
+		SetFocus(ui.rootFlex).
 		EnableMouse(true)
 
+	ui.restoreViewPositions()
+
+	// show the configured view first
+	start := startupView(ui.views)
+	if configured := viper.GetString("ui.startup-view"); configured != "" && configured != start {
+		ui.logger.Printf("ui.startup-view %s isn't in ui.views, starting with %s", configured, start)
+	}
+	ui.ShowPage(start)
+
 	ui.playlistPage.UpdatePlaylists()
+	ui.resumeLastSession()
+	ui.checkLicense()
 
 	return ui
 }
@@ -208,6 +482,9 @@ func (ui *Ui) Run() error {
 	// run mpv event handler
 	go ui.player.EventLoop()
 
+	// show the screensaver when idle
+	ui.startIdleTimer()
+
 	// gui main loop (blocking)
 	return ui.app.Run()
 }
@@ -234,11 +511,71 @@ func (ui *Ui) ShowSelectPlaylist() {
 	ui.selectPlaylistWidget.visible = true
 }
 
+// toggleWaveform shows or hides the waveform above the progress bar.
+func (ui *Ui) toggleWaveform() {
+	ui.waveformWidget.Toggle()
+	ui.rootFlex.ResizeItem(ui.waveformWidget, ui.waveformWidget.Height(), 0)
+	ui.storePanelVisibility(panelWaveform, ui.waveformWidget.IsEnabled())
+}
+
+// toggleCoverArt shows or hides the cover art on the queue page.
+func (ui *Ui) toggleCoverArt() {
+	visible := !ui.queuePage.IsCoverArtVisible()
+	ui.queuePage.SetCoverArtVisible(visible)
+	ui.storePanelVisibility(panelCoverArt, visible)
+}
+
 func (ui *Ui) CloseSelectPlaylist() {
 	ui.pages.HidePage(PageSelectPlaylist)
 	ui.selectPlaylistWidget.visible = false
 }
 
+// setPlaybackStatus sets the playback state shown in the top left. Must be
+// called from the gui goroutine.
+func (ui *Ui) setPlaybackStatus(text string) {
+	ui.playbackStatus = text
+	ui.updateStatusText()
+}
+
+// setBufferingStatus shows text instead of the playback status until it's
+// called with an empty text. Must be called from the gui goroutine.
+func (ui *Ui) setBufferingStatus(text string) {
+	ui.bufferingStatus = text
+	ui.updateStatusText()
+}
+
+func (ui *Ui) updateStatusText() {
+	if ui.noticeActive {
+		return
+	}
+	if ui.bufferingStatus != "" {
+		ui.startStopStatus.SetText(ui.bufferingStatus)
+	} else {
+		ui.startStopStatus.SetText(ui.playbackStatus)
+	}
+}
+
+// showNotice shows a short message in place of the playback status for a
+// few seconds. Must be called from the gui goroutine.
+func (ui *Ui) showNotice(text string) {
+	ui.noticeActive = true
+	ui.noticeSeq++
+	seq := ui.noticeSeq
+	ui.startStopStatus.SetText("[yellow::b]" + tview.Escape(text) + "[::-]")
+	ui.notify(severityInfo, text)
+
+	time.AfterFunc(noticeDuration, func() {
+		ui.app.QueueUpdateDraw(func() {
+			// a newer notice is still showing
+			if seq != ui.noticeSeq {
+				return
+			}
+			ui.noticeActive = false
+			ui.updateStatusText()
+		})
+	})
+}
+
 func (ui *Ui) showMessageBox(text string) {
 	ui.pages.ShowPage(PageMessageBox)
 	ui.messageBox.SetText(text)
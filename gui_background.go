@@ -0,0 +1,112 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spf13/viper"
+)
+
+const (
+	quitButtonQuit       = "Quit"
+	quitButtonBackground = "Background"
+	quitButtonCancel     = "Cancel"
+)
+
+func (ui *Ui) createQuitModal() *tview.Modal {
+	modal := tview.NewModal().
+		SetText("Quit stmps?").
+		AddButtons([]string{quitButtonQuit, quitButtonBackground, quitButtonCancel}).
+		SetBackgroundColor(tcell.ColorBlack)
+	modal.SetDoneFunc(func(_ int, buttonLabel string) {
+		ui.closeQuitConfirmation()
+		switch buttonLabel {
+		case quitButtonQuit:
+			ui.Quit()
+		case quitButtonBackground:
+			ui.runInBackground()
+		}
+	})
+	modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'y', 'Q':
+			ui.closeQuitConfirmation()
+			ui.Quit()
+			return nil
+		case 'z':
+			ui.closeQuitConfirmation()
+			ui.runInBackground()
+			return nil
+		case 'n':
+			ui.closeQuitConfirmation()
+			return nil
+		}
+		return event
+	})
+	return modal
+}
+
+// requestQuit quits, or asks first if ui.confirm-quit is set.
+func (ui *Ui) requestQuit() {
+	if !viper.GetBool("ui.confirm-quit") {
+		ui.Quit()
+		return
+	}
+
+	ui.pages.ShowPage(PageQuitConfirm)
+	ui.pages.SendToFront(PageQuitConfirm)
+	ui.app.SetFocus(ui.quitModal)
+}
+
+func (ui *Ui) closeQuitConfirmation() {
+	ui.pages.HidePage(PageQuitConfirm)
+	_, prim := ui.pages.GetFrontPage()
+	ui.app.SetFocus(prim)
+}
+
+// runInBackground hands the terminal back while playback continues. The TUI
+// is restored when Enter is pressed or one of restoreSignals is received.
+//
+// tview's Suspend() is called outside of the gui goroutine, so the event
+// loops keep running and queued updates are processed in the meantime; they
+// just don't draw anything while the screen is suspended.
+func (ui *Ui) runInBackground() {
+	go func() {
+		ui.app.Suspend(func() {
+			sigs := make(chan os.Signal, 1)
+			if len(restoreSignals) > 0 {
+				signal.Notify(sigs, restoreSignals...)
+				defer signal.Stop(sigs)
+			}
+
+			fmt.Printf("%s keeps playing in the background.\n", clientName)
+			if len(restoreSignals) > 0 {
+				fmt.Printf("Press Enter or send %v to pid %d to return.\n", restoreSignals[0], os.Getpid())
+			} else {
+				fmt.Println("Press Enter to return.")
+			}
+
+			cancel := make(chan struct{})
+			entered := make(chan struct{})
+			go func() {
+				waitForEnter(cancel)
+				close(entered)
+			}()
+
+			select {
+			case <-entered:
+			case <-sigs:
+				close(cancel)
+				<-entered
+			}
+		})
+		ui.logger.Print("returning from background")
+		ui.app.Sync()
+	}()
+}
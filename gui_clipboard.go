@@ -0,0 +1,87 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+// selectedItem returns ID and name of the item selected on the active page.
+func (ui *Ui) selectedItem() (id, name string) {
+	switch ui.menuWidget.GetActivePage() {
+	case PageBrowser:
+		return ui.browserPage.selectedItem()
+	case PageQueue:
+		return ui.queuePage.selectedItem()
+	case PagePlaylists:
+		return ui.playlistPage.selectedItem()
+	case PageSearch:
+		return ui.searchPage.selectedItem()
+	case PageNew:
+		return ui.newPage.selectedItem()
+	case PageDecades:
+		return ui.decadesPage.selectedItem()
+	case PageStarred:
+		return ui.starredPage.selectedItem()
+	}
+	return "", ""
+}
+
+// copyOrShow copies text to the clipboard. Without a clipboard (e.g. in an
+// ssh session) the text is shown in the status bar instead.
+func (ui *Ui) copyOrShow(label, text string) {
+	if err := copyToClipboard(text); err != nil {
+		ui.logger.Printf("clipboard: %v", err)
+		ui.logger.Printf("%s: %s", label, text)
+		ui.showNotice(label + ": " + text)
+		return
+	}
+	ui.showNotice("Copied " + label + " to clipboard")
+}
+
+func (ui *Ui) handleCopySelectedId() {
+	id, name := ui.selectedItem()
+	if id == "" {
+		ui.showNotice("Nothing selected")
+		return
+	}
+	ui.logger.Printf("copying ID of %q", name)
+	ui.copyOrShow("ID", id)
+}
+
+func (ui *Ui) handleCopyNowPlaying() {
+	song, err := ui.player.GetQueueItem(0)
+	if err != nil {
+		ui.showNotice("Nothing playing")
+		return
+	}
+	text := song.Title
+	if song.Artist != "" {
+		text = song.Artist + " - " + song.Title
+	}
+	ui.copyOrShow("now playing", text)
+}
+
+// handleCopyShareUrl copies the share URL of the selected item. Looking up
+// or creating the share happens in the background.
+func (ui *Ui) handleCopyShareUrl() {
+	id, name := ui.selectedItem()
+	if id == "" {
+		ui.showNotice("Nothing selected")
+		return
+	}
+	ui.copyShareUrl(id, name)
+}
+
+// copyShareUrl copies the share URL of the item with the ID, name is shown
+// if that fails.
+func (ui *Ui) copyShareUrl(id, name string) {
+	go func() {
+		shareUrl, err := ui.connection.GetShareUrl(id)
+		ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ui.logger.PrintError("GetShareUrl", err)
+				ui.showNotice("Sharing " + name + " failed")
+				return
+			}
+			ui.copyOrShow("share URL", shareUrl)
+		})
+	}()
+}
@@ -4,63 +4,185 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"log"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/spezifisch/stmps/mpvplayer"
 	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
 )
 
+// DuplicateQueuePolicy decides what happens when a song that's already in
+// the queue is added again.
+type DuplicateQueuePolicy string
+
+const (
+	// DuplicatesAllow adds the song again
+	DuplicatesAllow DuplicateQueuePolicy = "allow"
+	// DuplicatesSkip doesn't add the song
+	DuplicatesSkip DuplicateQueuePolicy = "skip"
+	// DuplicatesJump doesn't add the song and selects the queued one instead
+	DuplicatesJump DuplicateQueuePolicy = "jump"
+)
+
+// AlbumDuplicatePolicy decides what happens when an album is added while some
+// of its songs are already in the queue.
+type AlbumDuplicatePolicy string
+
+const (
+	// AlbumDuplicatesMissing adds the songs that aren't queued yet
+	AlbumDuplicatesMissing AlbumDuplicatePolicy = "missing"
+	// AlbumDuplicatesAll adds all songs again
+	AlbumDuplicatesAll AlbumDuplicatePolicy = "all"
+	// AlbumDuplicatesSkip doesn't add the album at all
+	AlbumDuplicatesSkip AlbumDuplicatePolicy = "skip"
+)
+
+// albumDuplicatePolicy returns the configured album policy. Unset, it follows
+// the song policy: albums are added in full if duplicates are allowed, and
+// only their missing songs otherwise.
+func albumDuplicatePolicy(configured string, songs DuplicateQueuePolicy) AlbumDuplicatePolicy {
+	if configured != "" {
+		return AlbumDuplicatePolicy(configured)
+	}
+	if songs == DuplicatesSkip || songs == DuplicatesJump {
+		return AlbumDuplicatesMissing
+	}
+	return AlbumDuplicatesAll
+}
+
+// queueMode decides whether adding songs interrupts playback.
+type queueMode int
+
+const (
+	// queueAppend adds songs to the end of the queue without interrupting
+	queueAppend queueMode = iota
+	// queuePlayNow inserts songs after the current one and skips to them
+	queuePlayNow
+	// queueInsert inserts songs at a queue index without interrupting, see
+	// startQueueInsert
+	queueInsert
+	// queueNext inserts songs after the current one without interrupting
+	queueNext
+)
+
+// runeQueueMode returns queuePlayNow for the "play now" key and queueAppend
+// for anything else.
+func runeQueueMode(r rune) queueMode {
+	if r == 'e' {
+		return queuePlayNow
+	}
+	return queueAppend
+}
+
+// queueAddReport counts the outcome of addSongToQueue calls until
+// finishQueueAdd is called.
+type queueAddReport struct {
+	mode    queueMode
+	added   int
+	skipped int
+	// albums not added since they were partly in the queue, see
+	// AlbumDuplicatesSkip
+	skippedAlbums int
+	// songs not added since the queue was full, see client.max-queue-length
+	full int
+	// queue index of the first skipped duplicate
+	firstDuplicate int
+	// overrides the duplicate policy while adding an album's songs, see
+	// addAlbumSongsToQueue
+	allowDuplicates bool
+	skipDuplicates  bool
+	// queue index where queuePlayNow, queueInsert and queueNext insert the first
+	// song
+	insertAt int
+	// source of the added songs, see setQueueSource
+	source string
+}
+
+// Sources of queued songs, see QueueItem.Source. Songs from playlists,
+// albums and artists are tagged with their name, see queueSourceOf.
+const (
+	// single songs picked by hand
+	queueSourceManual = "manual"
+	// random and similar songs, smart mixes
+	queueSourceRadio = "radio"
+)
+
+// queueSourceOf returns the source of songs queued from a playlist, album,
+// artist or decade, e.g. "playlist:Name".
+func queueSourceOf(kind, name string) string {
+	return kind + ":" + name
+}
+
 func (ui *Ui) handlePageInput(event *tcell.EventKey) *tcell.EventKey {
 	// we don't want any of these firing if we're trying to add a new playlist
 	focused := ui.app.GetFocus()
-	if ui.playlistPage.IsNewPlaylistInputFocused(focused) || ui.browserPage.IsSearchFocused(focused) || focused == ui.searchPage.searchField || ui.selectPlaylistWidget.visible {
+	if ui.playlistPage.IsNewPlaylistInputFocused(focused) || ui.playlistPage.IsImportInputFocused(focused) || ui.statsPage.IsExportInputFocused(focused) || ui.browserPage.IsSearchFocused(focused) || focused == ui.searchPage.searchField || ui.selectPlaylistWidget.visible || ui.smartMixWidget.visible || ui.castWidget.visible || ui.outputsWidget.visible || ui.notificationsWidget.visible || ui.failedTracksWidget.visible || ui.aboutWidget.visible || ui.contextMenuWidget.visible || focused == ui.quitModal {
 		return event
 	}
 
-	switch event.Rune() {
-	case '1':
-		ui.ShowPage(PageBrowser)
+	// the seek preview grabs all keys while it's active
+	if ui.progressWidget.IsPreviewing() {
+		return ui.progressWidget.HandleSeekPreviewInput(event)
+	}
 
-	case '2':
-		ui.ShowPage(PageQueue)
+	if event.Key() == tcell.KeyCtrlR {
+		// start a library scan, or follow the running one
+		ui.handleLibraryScan()
+		return nil
+	}
 
-	case '3':
-		ui.ShowPage(PagePlaylists)
+	switch event.Rune() {
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		// the enabled views in the order of ui.views
+		ui.showViewAt(int(event.Rune() - '1'))
 
-	case '4':
-		ui.ShowPage(PageSearch)
+	case ']':
+		ui.cycleView(1)
 
-	case '5':
-		ui.ShowPage(PageLog)
+	case '[':
+		ui.cycleView(-1)
 
 	case '?':
 		ui.ShowHelp()
 
 	case 'Q':
-		ui.Quit()
+		ui.requestQuit()
+
+	case 'z':
+		// keep playing with the TUI suspended
+		ui.runInBackground()
 
 	case 'r':
 		// add random songs to queue
 		ui.handleAddRandomSongs("", "random")
 
+	case 'm':
+		// build a queue from filters or a saved smart mix
+		ui.ShowSmartMix()
+
 	case 'D':
 		// clear queue and stop playing
+		ui.stopRadio()
 		ui.player.ClearQueue()
 		ui.queuePage.UpdateQueue()
 
 	case 'p':
 		// toggle playing/pause
-		err := ui.player.Pause()
-		if err != nil {
-			ui.logger.PrintError("handlePageInput: Pause", err)
+		if ui.castRenderer != nil {
+			ui.castTogglePause()
+		} else if err := ui.player.TogglePlayPause(); err != nil {
+			ui.logger.PrintError("handlePageInput: TogglePlayPause", err)
 		}
 
 	case 'P':
 		// stop playing without changes to queue
 		ui.logger.Print("key stop")
-		err := ui.player.Stop()
-		if err != nil {
+		if ui.castRenderer != nil {
+			ui.castStop()
+		} else if err := ui.player.FadeOutAndStop(); err != nil {
 			ui.logger.PrintError("handlePageInput: Stop", err)
 		}
 
@@ -72,41 +194,172 @@ func (ui *Ui) handlePageInput(event *tcell.EventKey) *tcell.EventKey {
 
 	case '-':
 		// volume-
-		if err := ui.player.AdjustVolume(-5); err != nil {
+		if ui.castRenderer != nil {
+			ui.castAdjustVolume(-5)
+		} else if err := ui.player.AdjustVolume(-5); err != nil {
 			ui.logger.PrintError("handlePageInput: AdjustVolume-", err)
 		}
 
 	case '+', '=':
 		// volume+
-		if err := ui.player.AdjustVolume(5); err != nil {
+		if ui.castRenderer != nil {
+			ui.castAdjustVolume(5)
+		} else if err := ui.player.AdjustVolume(5); err != nil {
 			ui.logger.PrintError("handlePageInput: AdjustVolume+", err)
 		}
 
 	case '.':
-		// <<
-		if err := ui.player.Seek(10); err != nil {
-			ui.logger.PrintError("handlePageInput: Seek+", err)
-		}
+		// >>
+		ui.handleSeekKey(false, false)
 
 	case ',':
-		// >>
-		if err := ui.player.Seek(-10); err != nil {
-			ui.logger.PrintError("handlePageInput: Seek-", err)
+		// <<
+		ui.handleSeekKey(false, true)
+
+	case '\'':
+		// >>>
+		ui.handleSeekKey(true, false)
+
+	case ';':
+		// <<<
+		ui.handleSeekKey(true, true)
+
+	case '0':
+		// play the current song again from the start
+		if ui.castRenderer != nil {
+			ui.castSeekTo(0)
+		} else if err := ui.player.Restart(); err != nil {
+			ui.logger.PrintError("handlePageInput: Restart", err)
 		}
+		ui.queuePage.UpdateQueue()
+
+	case 'g':
+		// choose seek position on the progress bar
+		ui.progressWidget.StartSeekPreview()
+
+	case 'K':
+		// switch between exact and fast keyframe seeks
+		ui.toggleSeekMode()
+
+	case 'v':
+		// switch to the next audio track of the current song
+		ui.cycleAudioTrack()
 
 	case '>':
 		// skip to next track
-		if err := ui.player.PlayNextTrack(); err != nil {
+		if ui.castRenderer != nil {
+			ui.castNextTrack()
+		} else if err := ui.player.PlayNextTrack(); err != nil {
 			ui.logger.PrintError("handlePageInput: Next", err)
 		}
 		ui.queuePage.UpdateQueue()
 
-	case 's':
-		if err := ui.connection.StartScan(); err != nil {
-			ui.logger.PrintError("startScan:", err)
+	case '<':
+		// restart the current track, or skip to the previous one near its start
+		if ui.castRenderer != nil {
+			ui.castPreviousTrack()
+		} else if err := ui.player.PreviousTrack(); err != nil {
+			ui.logger.PrintError("handlePageInput: Previous", err)
+		}
+		ui.queuePage.UpdateQueue()
+
+	case 'C':
+		// send playback to a DLNA/UPnP renderer
+		ui.ShowCast()
+
+	case 'Y':
+		// choose the audio outputs to play through
+		ui.ShowOutputs()
+
+	case 'E':
+		// review recent notices and errors
+		ui.ShowNotifications()
+
+	case 'F':
+		// review and retry the songs that failed to play
+		ui.ShowFailedTracks()
+
+	case 'w':
+		// stmps, server and mpv versions
+		ui.ShowAbout()
+
+	case 'x':
+		// actions for the selected artist, album or song
+		if !ui.ShowContextMenu() {
+			// e.g. the export key of the stats page
+			return event
 		}
 
+	case 'o':
+		// open current item in the server's web interface
+		ui.handleOpenWebUI()
+
+	case 'c':
+		// copy "Artist - Title" of the current song
+		ui.handleCopyNowPlaying()
+
+	case 'i':
+		// copy ID of the selected item
+		ui.handleCopySelectedId()
+
+	case 'u':
+		// copy share URL of the selected item
+		ui.handleCopyShareUrl()
+
+	case 'U':
+		// copy stream URL of the current song
+		ui.handleCopyStreamUrl()
+
+	case 'W':
+		// show/hide the waveform of the current track
+		ui.toggleWaveform()
+
+	case 'I':
+		// show/hide the cover art on the queue page
+		ui.toggleCoverArt()
+
+	case 'b':
+		// cycle the transcoding bitrate for this server
+		ui.cycleMaxBitRate()
+
+	case 'G':
+		// cycle the ReplayGain mode for this server
+		ui.cycleReplayGain()
+
+	case 'T':
+		// toggle silence trimming for this session
+		ui.player.TrimSilence = !ui.player.TrimSilence
+		if ui.player.TrimSilence {
+			ui.showNotice("Silence trimming on (from next track)")
+		} else {
+			ui.showNotice("Silence trimming off (from next track)")
+		}
+
+	case 'h':
+		// toggle the voice boost for all songs for this session
+		voiceBoost := !ui.player.VoiceBoostOn()
+		ui.player.SetVoiceBoost(voiceBoost)
+		if voiceBoost {
+			ui.showNotice("Voice boost on")
+		} else if ui.player.VoiceBoostSpokenWord {
+			ui.showNotice("Voice boost off (still on for podcasts and audiobooks)")
+		} else {
+			ui.showNotice("Voice boost off")
+		}
+
+	case 'B':
+		// put the song on the blacklist, or take it off
+		ui.handleToggleBlacklist()
+
+	case 'J':
+		// remember the volume change as the current song's gain
+		ui.handleRememberTrackGain()
+
 	default:
+		if m, ok := ui.macros[event.Rune()]; ok && event.Key() == tcell.KeyRune {
+			ui.runMacro(m)
+			return nil
+		}
 		return event
 	}
 
@@ -114,13 +367,30 @@ func (ui *Ui) handlePageInput(event *tcell.EventKey) *tcell.EventKey {
 }
 
 func (ui *Ui) ShowPage(name string) {
+	ui.rememberViewPosition(ui.menuWidget.GetActivePage())
+	if name == PageNew {
+		ui.newPage.Update(false)
+	}
+	if name == PageStats {
+		ui.statsPage.Update(false)
+	}
+	if name == PageStarred {
+		ui.starredPage.Update()
+	}
+	if name == PagePlaylists {
+		ui.playlistPage.resumeLoad()
+	} else {
+		ui.playlistPage.cancelLoad()
+	}
 	ui.pages.SwitchToPage(name)
 	ui.menuWidget.SetActivePage(name)
+	ui.restoreViewPosition(name)
 	_, prim := ui.pages.GetFrontPage()
 	ui.app.SetFocus(prim)
 }
 
 func (ui *Ui) Quit() {
+	ui.stopCasting()
 	if len(ui.queuePage.queueData.playerQueue) > 0 {
 		ids := make([]string, len(ui.queuePage.queueData.playerQueue))
 		for i, it := range ui.queuePage.queueData.playerQueue {
@@ -136,13 +406,128 @@ func (ui *Ui) Quit() {
 		// bad data. Therefore, we ignore errors.
 		_ = ui.connection.SavePlayQueue([]string{"XXX"}, "XXX", 0)
 	}
-	ui.player.Quit()
+	playing, err := ui.player.IsPlaying()
+	ui.serverSettings.Paused = err == nil && !playing
+	ui.storeViewPositions()
+	ui.storeServerSettings()
+	ui.nowPlayingFile.clear()
+	// mpv is shut down by shutdown() once the gui stopped
 	ui.app.Stop()
 }
 
+// loadSearchHistory returns the search history, with the stored queries if
+// client.save-search-history is set.
+func (ui *Ui) loadSearchHistory() *searchHistory {
+	size := defaultSearchHistorySize
+	if viper.IsSet("client.search-history") {
+		size = viper.GetInt("client.search-history")
+	}
+
+	var queries []string
+	if viper.GetBool("client.save-search-history") {
+		var err error
+		if queries, err = loadSearchHistory(serverStatePath()); err != nil {
+			ui.logger.PrintError("loadSearchHistory", err)
+		}
+	}
+	return newSearchHistory(size, queries)
+}
+
+// panelVisibility returns whether a panel is shown at startup: as it was last
+// toggled, or as set in configKey if it was never toggled.
+func (ui *Ui) panelVisibility(panel, configKey string, fallback bool) bool {
+	visible, stored, err := loadPanelVisibility(serverStatePath(), panel)
+	if err != nil {
+		ui.logger.PrintError("panelVisibility", err)
+	}
+	if stored {
+		return visible
+	}
+	if viper.IsSet(configKey) {
+		return viper.GetBool(configKey)
+	}
+	return fallback
+}
+
+// storePanelVisibility remembers a toggled panel for the next start.
+func (ui *Ui) storePanelVisibility(panel string, visible bool) {
+	if serverStatePath() == "" {
+		return
+	}
+	if err := savePanelVisibility(serverStatePath(), panel, visible); err != nil {
+		ui.logger.PrintError("storePanelVisibility", err)
+	}
+}
+
+// restoreServerSettings applies the playback settings stored for the
+// connected server, or the defaults from the config for new servers.
+func (ui *Ui) restoreServerSettings() {
+	ui.serverProfile = serverProfile(ui.connection.Username, ui.connection.Host)
+	settings, err := loadServerSettings(serverStatePath(), ui.serverProfile, defaultServerSettings())
+	if err != nil {
+		ui.logger.PrintError("restoreServerSettings", err)
+	}
+	ui.serverSettings = settings
+	ui.contentFilter.setBlacklist(settings.Blacklist)
+	ui.trackGains = settings.TrackGains
+	if ui.trackGains == nil {
+		ui.trackGains = trackGains{}
+	}
+
+	if err := ui.player.SetVolume(settings.Volume); err != nil {
+		ui.logger.PrintError("restoreServerSettings: SetVolume", err)
+	}
+	if err := ui.player.SetReplayGain(settings.ReplayGain); err != nil {
+		ui.logger.PrintError("restoreServerSettings: SetReplayGain", err)
+	}
+	ui.applyMaxBitRate()
+}
+
+// storeServerSettings saves the current playback settings for the connected
+// server.
+func (ui *Ui) storeServerSettings() {
+	if volume, err := ui.player.GetVolume(); err != nil {
+		ui.logger.PrintError("storeServerSettings: GetVolume", err)
+	} else {
+		ui.serverSettings.Volume = volume
+	}
+
+	if err := saveServerSettings(serverStatePath(), ui.serverProfile, ui.serverSettings); err != nil {
+		ui.logger.PrintError("storeServerSettings", err)
+	}
+}
+
+// cycleMaxBitRate switches to the next transcoding bitrate. Songs already in
+// the queue keep the bitrate they were added with.
+func (ui *Ui) cycleMaxBitRate() {
+	ui.serverSettings.MaxBitRate = nextMaxBitRate(ui.serverSettings.MaxBitRate)
+	ui.applyMaxBitRate()
+	ui.updateServerStatus(ui.serverHealthText)
+
+	notice := fmt.Sprintf("Transcoding to %d kbps", ui.serverSettings.MaxBitRate)
+	if ui.serverSettings.MaxBitRate == 0 {
+		notice = "Streaming original files"
+	}
+	if ui.connection.MaxBitRate != ui.serverSettings.MaxBitRate {
+		notice += fmt.Sprintf(", %d kbps while the connection is slow", ui.connection.MaxBitRate)
+	}
+	ui.showNotice(notice + " (for songs added from now on)")
+}
+
+func (ui *Ui) cycleReplayGain() {
+	mode := nextReplayGainMode(ui.serverSettings.ReplayGain)
+	if err := ui.player.SetReplayGain(mode); err != nil {
+		ui.logger.PrintError("cycleReplayGain", err)
+		return
+	}
+	ui.serverSettings.ReplayGain = mode
+	ui.showNotice("ReplayGain: " + mode)
+}
+
 func (ui *Ui) handleAddRandomSongs(Id string, randomType string) {
+	ui.startQueueAdd(queueAppend)
 	ui.addRandomSongsToQueue(Id, randomType)
-	ui.queuePage.UpdateQueue()
+	ui.finishQueueAdd()
 }
 
 func (ui *Ui) addRandomSongsToQueue(Id string, randomType string) {
@@ -150,26 +535,174 @@ func (ui *Ui) addRandomSongsToQueue(Id string, randomType string) {
 	if err != nil {
 		ui.logger.Printf("addRandomSongsToQueue %s", err.Error())
 	}
+	ui.setQueueSource(queueSourceRadio)
 	switch randomType {
 	case "random":
-		for _, e := range response.RandomSongs.Song {
+		for _, e := range ui.contentFilter.filterSongs(response.RandomSongs.Song) {
 			ui.addSongToQueue(&e)
 		}
 	case "similar":
-		for _, e := range response.SimilarSongs.Song {
+		for _, e := range ui.contentFilter.filterSongs(response.SimilarSongs.Song) {
 			ui.addSongToQueue(&e)
 		}
 	}
 }
 
-// make sure to call ui.QueuePage.UpdateQueue() after this
+// startQueueAdd begins adding songs with the given mode. Call addSongToQueue
+// for each song and ui.finishQueueAdd() when done.
+func (ui *Ui) startQueueAdd(mode queueMode) {
+	ui.queueAdds = queueAddReport{mode: mode}
+	if (mode == queuePlayNow || mode == queueNext) && len(ui.player.GetQueueCopy()) > 0 {
+		// keep the current song in front of the new ones
+		ui.queueAdds.insertAt = 1
+	}
+}
+
+// setQueueSource tags the songs added until finishQueueAdd with source.
+// Without it they count as queueSourceManual.
+func (ui *Ui) setQueueSource(source string) {
+	ui.queueAdds.source = source
+}
+
+// startQueueInsert begins adding songs at the queue index, e.g. to continue
+// after songs added before. Call addSongToQueue for each song and
+// ui.finishQueueAdd() when done.
+func (ui *Ui) startQueueInsert(index int) {
+	ui.queueAdds = queueAddReport{mode: queueInsert, insertAt: index}
+}
+
+// make sure to call ui.finishQueueAdd() after this
 func (ui *Ui) addSongToQueue(entity *subsonic.SubsonicEntity) {
-	uri := ui.connection.GetPlayUrl(entity)
+	queueItem := ui.makeQueueItem(entity, "")
+	ui.addQueueItem(&queueItem)
+}
+
+func (ui *Ui) addQueueItem(queueItem *mpvplayer.QueueItem) {
+	if queueItem.Source == "" {
+		queueItem.Source = stringOr(ui.queueAdds.source, queueSourceManual)
+	}
+	// anything but skip and jump (i.e. unset) allows duplicates
+	skipDuplicates := ui.duplicatePolicy == DuplicatesSkip || ui.duplicatePolicy == DuplicatesJump
+	if (skipDuplicates && !ui.queueAdds.allowDuplicates) || ui.queueAdds.skipDuplicates {
+		if index := ui.player.QueueIndex(queueItem.Id); index >= 0 {
+			ui.queueAdds.noteDuplicate(index)
+			ui.queueAdds.skipped++
+			return
+		}
+	}
+
+	var added bool
+	if ui.queueAdds.mode != queueAppend {
+		added = ui.player.InsertIntoQueue(ui.queueAdds.insertAt+ui.queueAdds.added, queueItem)
+	} else {
+		added = ui.player.AddToQueue(queueItem)
+	}
+	if !added {
+		ui.queueAdds.full++
+		return
+	}
+	ui.queueAdds.added++
+}
+
+// addAlbumSongsToQueue adds the songs of an album as client.album-duplicate-policy
+// says if some of them are already in the queue. Make sure to call
+// ui.finishQueueAdd() after this.
+func (ui *Ui) addAlbumSongsToQueue(songs []subsonic.SubsonicEntity) {
+	policy := albumDuplicatePolicy(viper.GetString("client.album-duplicate-policy"), ui.duplicatePolicy)
+	switch policy {
+	case AlbumDuplicatesSkip:
+		for i := range songs {
+			if index := ui.player.QueueIndex(songs[i].Id); index >= 0 {
+				ui.queueAdds.noteDuplicate(index)
+				ui.queueAdds.skippedAlbums++
+				return
+			}
+		}
+	case AlbumDuplicatesAll:
+		ui.queueAdds.allowDuplicates = true
+	case AlbumDuplicatesMissing:
+		ui.queueAdds.skipDuplicates = true
+	}
+	for i := range songs {
+		ui.addSongToQueue(&songs[i])
+	}
+	ui.queueAdds.allowDuplicates = false
+	ui.queueAdds.skipDuplicates = false
+}
+
+// noteDuplicate remembers the queue index of the first duplicate.
+func (r *queueAddReport) noteDuplicate(index int) {
+	if r.skipped == 0 && r.skippedAlbums == 0 {
+		r.firstDuplicate = index
+	}
+}
+
+// notice describes the added and skipped songs for the status bar.
+func (r queueAddReport) notice() string {
+	if r.added == 0 && r.skipped == 1 && r.skippedAlbums == 0 && r.full == 0 {
+		return fmt.Sprintf("Already in the queue at position %d", r.firstDuplicate+1)
+	}
+	notice := fmt.Sprintf("Added %d songs", r.added)
+	if r.skipped > 0 {
+		notice += fmt.Sprintf(", skipped %d already in the queue", r.skipped)
+	}
+	if r.skippedAlbums == 1 {
+		notice += ", skipped 1 album partly in the queue"
+	} else if r.skippedAlbums > 1 {
+		notice += fmt.Sprintf(", skipped %d albums partly in the queue", r.skippedAlbums)
+	}
+	if r.full > 0 {
+		notice += fmt.Sprintf(", %d not added since the queue is full", r.full)
+	}
+	return notice
+}
+
+// finishQueueAdd updates the queue page after one or more addSongToQueue
+// calls and reports skipped duplicates and songs that didn't fit. With queuePlayNow, playback skips to
+// the first added song. With DuplicatesJump the queue page is shown with the
+// first duplicate selected. Playback isn't changed for duplicates, since
+// playing a song further down the queue would drop the songs before it.
+func (ui *Ui) finishQueueAdd() queueAddReport {
+	report := ui.queueAdds
+	ui.queueAdds = queueAddReport{}
+
+	if report.mode == queuePlayNow && report.added > 0 {
+		if err := ui.player.SkipToQueueItem(report.insertAt); err != nil {
+			ui.logger.PrintError("finishQueueAdd: SkipToQueueItem", err)
+		}
+		if report.insertAt > 0 && report.firstDuplicate > 0 {
+			// the current song was dropped
+			report.firstDuplicate--
+		}
+		// a single song picked by hand is followed by the rest of its album
+		if report.added == 1 && report.source == "" && viper.GetBool("client.auto-queue-album") {
+			if song, err := ui.player.GetQueueItem(0); err == nil {
+				ui.queueAlbumRemainder(song, 0)
+			}
+		}
+	}
+	ui.queuePage.UpdateQueue()
+
+	if report.skipped == 0 && report.skippedAlbums == 0 && report.full == 0 {
+		return report
+	}
+
+	if ui.duplicatePolicy == DuplicatesJump && (report.skipped > 0 || report.skippedAlbums > 0) {
+		ui.ShowPage(PageQueue)
+		ui.queuePage.queueList.Select(report.firstDuplicate, 0)
+	}
+	ui.showNotice(report.notice())
+	return report
+}
 
+// makeQueueItem looks up the album name and artist of a song and fills a
+// QueueItem with them. If the song has no artist, fallbackArtist is used.
+func (ui *Ui) makeQueueItem(entity *subsonic.SubsonicEntity, fallbackArtist string) mpvplayer.QueueItem {
 	response, err := ui.connection.GetAlbum(entity.Parent)
 	album := ""
+	albumArtist := entity.GetAlbumArtist()
 	if err != nil {
-		ui.logger.PrintError("addSongToQueue", err)
+		ui.logger.PrintError("makeQueueItem", err)
 	} else {
 		switch {
 		case response.Album.Name != "":
@@ -179,54 +712,113 @@ func (ui *Ui) addSongToQueue(entity *subsonic.SubsonicEntity) {
 		case response.Album.Album != "":
 			album = response.Album.Album
 		}
+		albumArtist = stringOr(albumArtist, response.Album.Artist)
 	}
 
-	queueItem := &mpvplayer.QueueItem{
+	return mpvplayer.QueueItem{
 		Id:          entity.Id,
-		Uri:         uri,
+		Uri:         ui.connection.GetPlayUrl(entity),
 		Title:       entity.GetSongTitle(),
-		Artist:      entity.Artist,
+		Artist:      stringOr(entity.Artist, fallbackArtist),
+		AlbumArtist: albumArtist,
 		Duration:    entity.Duration,
 		Album:       album,
+		AlbumId:     stringOr(entity.AlbumId, entity.Parent),
+		ArtistId:    entity.ArtistId,
 		TrackNumber: entity.Track,
 		CoverArtId:  entity.CoverArtId,
 		DiscNumber:  entity.DiscNumber,
+		Year:        entity.Year,
+		Genres:      entity.GetGenres(),
+		Comment:     entity.Comment,
+		Played:      entity.LastPlayed(),
+		Type:        entity.Type,
+		TrackGain:   ui.trackGains[entity.Id],
 	}
-	ui.player.AddToQueue(queueItem)
 }
 
+// makeSongHandler returns a handler that plays the song now, see
+// queuePlayNow.
 func makeSongHandler(entity *subsonic.SubsonicEntity, ui *Ui, fallbackArtist string) func() {
 	// make copy of values so this function can be used inside a loop iterating over entities
-	id := entity.Id
 	// TODO: Why aren't we doing all of this _inside_ the returned func?
-	uri := ui.connection.GetPlayUrl(entity)
-	title := entity.Title
-	artist := stringOr(entity.Artist, fallbackArtist)
-	duration := entity.Duration
-	track := entity.Track
-	coverArtId := entity.CoverArtId
-	disc := entity.DiscNumber
+	queueItem := ui.makeQueueItem(entity, fallbackArtist)
+	// keep the original title, not the file name fallback
+	queueItem.Title = entity.Title
 
-	response, err := ui.connection.GetAlbum(entity.Parent)
-	album := ""
+	return func() {
+		ui.startQueueAdd(queuePlayNow)
+		ui.addQueueItem(&queueItem)
+		ui.finishQueueAdd()
+	}
+}
+
+// loadScrobbleQueue reads the scrobbles that couldn't be submitted in earlier
+// sessions. They're sent with the first retry.
+func (ui *Ui) loadScrobbleQueue() {
+	size := defaultScrobbleQueueSize
+	if viper.IsSet("server.scrobble-queue-size") {
+		size = viper.GetInt("server.scrobble-queue-size")
+	}
+	queue, err := loadScrobbleQueue(scrobbleQueuePath(), size)
 	if err != nil {
-		ui.logger.PrintError("makeSongHandler", err)
-	} else {
-		switch {
-		case response.Album.Name != "":
-			album = response.Album.Name
-		case response.Album.Title != "":
-			album = response.Album.Title
-		case response.Album.Album != "":
-			album = response.Album.Album
-		}
+		ui.logger.PrintError("loadScrobbleQueue", err)
 	}
+	ui.scrobbleQueue = queue
+}
 
-	return func() {
-		if err := ui.player.PlayUri(id, uri, title, artist, album, duration, track, disc, coverArtId); err != nil {
-			ui.logger.PrintError("SongHandler Play", err)
-			return
+// updateScrobbleStatus shows the number of unsent scrobbles in the top bar.
+// Must be called from the gui goroutine.
+func (ui *Ui) updateScrobbleStatus() {
+	pending := ui.scrobbleQueue.Len()
+	if pending == 0 {
+		ui.scrobbleStatus.SetText("")
+		ui.topBarFlex.ResizeItem(ui.scrobbleStatus, 0, 0)
+		return
+	}
+	text := fmt.Sprintf("%d unsent scrobbles", pending)
+	if pending == 1 {
+		text = "1 unsent scrobble"
+	}
+	ui.scrobbleStatus.SetText("[yellow]" + text + "[-]")
+	ui.topBarFlex.ResizeItem(ui.scrobbleStatus, len(text)+2, 0)
+}
+
+// cycleAudioTrack switches to the next audio track of the current song, e.g.
+// a commentary, and shows which one plays.
+func (ui *Ui) cycleAudioTrack() {
+	if ui.castRenderer != nil {
+		ui.showNotice("Audio tracks can't be switched while casting")
+		return
+	}
+	track, number, tracks, err := ui.player.CycleAudioTrack()
+	if errors.Is(err, mpvplayer.ErrSingleAudioTrack) {
+		if tracks == 0 {
+			ui.showNotice("Nothing is playing")
+		} else {
+			ui.showNotice("This song has a single audio track")
 		}
-		ui.queuePage.UpdateQueue()
+		return
+	} else if err != nil {
+		ui.logger.PrintError("cycleAudioTrack", err)
+		return
+	}
+	ui.showNotice(fmt.Sprintf("Audio track %d/%d: %s", number, tracks, track))
+}
+
+// toggleSeekMode switches between exact and keyframe seeks for this session.
+func (ui *Ui) toggleSeekMode() {
+	mode := mpvplayer.SeekKeyframe
+	if ui.player.SeekMode() == mpvplayer.SeekKeyframe {
+		mode = mpvplayer.SeekExact
+	}
+	if err := ui.player.SetSeekMode(mode); err != nil {
+		ui.logger.PrintError("toggleSeekMode", err)
+		return
+	}
+	if mode == mpvplayer.SeekKeyframe {
+		ui.showNotice("Seeking to keyframes: fast, may be off by a few seconds")
+	} else {
+		ui.showNotice("Seeking exactly: precise, may be slow on transcoded streams")
 	}
 }
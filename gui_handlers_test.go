@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlbumDuplicatePolicy(t *testing.T) {
+	// unset, it follows the song policy
+	assert.Equal(t, AlbumDuplicatesAll, albumDuplicatePolicy("", ""))
+	assert.Equal(t, AlbumDuplicatesAll, albumDuplicatePolicy("", DuplicatesAllow))
+	assert.Equal(t, AlbumDuplicatesMissing, albumDuplicatePolicy("", DuplicatesSkip))
+	assert.Equal(t, AlbumDuplicatesMissing, albumDuplicatePolicy("", DuplicatesJump))
+
+	assert.Equal(t, AlbumDuplicatesSkip, albumDuplicatePolicy("skip", DuplicatesAllow))
+	assert.Equal(t, AlbumDuplicatesAll, albumDuplicatePolicy("all", DuplicatesSkip))
+}
+
+func TestQueueAddReportNotice(t *testing.T) {
+	assert.Equal(t, "Already in the queue at position 3", queueAddReport{skipped: 1, firstDuplicate: 2}.notice())
+	assert.Equal(t, "Added 5 songs, skipped 2 already in the queue", queueAddReport{added: 5, skipped: 2}.notice())
+	assert.Equal(t, "Added 0 songs, skipped 1 album partly in the queue", queueAddReport{skippedAlbums: 1}.notice())
+	assert.Equal(t, "Added 8 songs, skipped 1 already in the queue, skipped 2 albums partly in the queue",
+		queueAddReport{added: 8, skipped: 1, skippedAlbums: 2}.notice())
+	assert.Equal(t, "Added 3 songs, 2 not added since the queue is full", queueAddReport{added: 3, full: 2}.notice())
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 
 	"github.com/rivo/tview"
-	"github.com/spezifisch/stmps/mpvplayer"
 	"github.com/spezifisch/stmps/subsonic"
 )
 
@@ -28,31 +27,22 @@ func formatPlayerStatus(volume int64, position int64, duration int64) string {
 	}
 
 	positionMin, positionSec := secondsToMinAndSec(position)
+	if duration == 0 && position > 0 {
+		// songs of unknown length, e.g. radio streams, only show the elapsed time
+		return fmt.Sprintf("[%d%%][::b][%02d:%02d live]", volume, positionMin, positionSec)
+	}
 	durationMin, durationSec := secondsToMinAndSec(duration)
 
 	return fmt.Sprintf("[%d%%][::b][%02d:%02d/%02d:%02d]", volume,
 		positionMin, positionSec, durationMin, durationSec)
 }
 
-func formatSongForStatusBar(currentSong *mpvplayer.QueueItem) (text string) {
-	if currentSong == nil {
-		return
-	}
-	if currentSong.Title != "" {
-		text += "[::-] [white]" + tview.Escape(currentSong.Title)
-	}
-	if currentSong.Artist != "" {
-		text += " [gray]by [white]" + tview.Escape(currentSong.Artist)
-	}
-	return
-}
-
-func formatSongForPlaylistEntry(entity subsonic.SubsonicEntity) (text string) {
+func formatSongForPlaylistEntry(entity subsonic.SubsonicEntity, display ArtistDisplay) (text string) {
 	if entity.Title != "" {
 		text += "[::-] [white]" + tview.Escape(entity.Title)
 	}
-	if entity.Artist != "" {
-		text += " [gray]by [white]" + tview.Escape(entity.Artist)
+	if artist := display.Artist(entity.Artist, entity.GetAlbumArtist()); artist != "" {
+		text += " [gray]by [white]" + tview.Escape(artist)
 	}
 	return
 }
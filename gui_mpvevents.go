@@ -6,5 +6,9 @@ package main
 import "github.com/spezifisch/stmps/mpvplayer"
 
 func (ui *Ui) SendEvent(event mpvplayer.UiEvent) {
-	ui.mpvEvents <- event
+	select {
+	case ui.mpvEvents <- event:
+	case <-ui.eventLoop.quit:
+		// shutting down, the gui loop doesn't take events anymore
+	}
 }
@@ -7,10 +7,37 @@ const helpPlayback = `
 p      play/pause
 P      stop
 >      next song
+<      restart song, previous song near its start
 -/=(+) volume down/volume up
-,/.    seek -10/+10 seconds
+,/.    seek back/forward (default 10 seconds)
+;/'    seek back/forward further (default 60 seconds)
+0      restart current song
+g      seek preview (Left/Right, Enter/Esc)
+K      toggle exact/keyframe seeking
+v      next audio track of current song
 r      add 50 random songs to queue
-s      start server library scan
+m      smart mix builder
+C      cast to DLNA/UPnP device
+Y      choose audio outputs
+[/]    previous/next view
+E      show recent notices and errors
+F      show songs that failed to play, retry them
+w      show stmps, server and mpv versions
+Ctrl-R start server library scan
+B      toggle blacklist for selected (queue) or current song
+J      remember volume change for current song, or forget it
+o      open item in server web interface
+c      copy "Artist - Title" of current song
+i      copy ID of selected item
+u      copy share URL of selected item
+U      copy stream URL of current song
+T      toggle silence trimming
+h      toggle voice boost
+G      cycle ReplayGain mode
+b      cycle transcoding bitrate
+W      toggle waveform of current song
+I      toggle cover art on queue page
+z      keep playing in the background
 `
 
 const helpPageBrowser = `
@@ -18,14 +45,39 @@ artist tab
   R     refresh the list
   /     Search artists
   a     Add all artist songs to queue
+  e     play all artist songs now
+  t     Add artist's top songs to queue
+  M     start radio from artist
+  L     star/unstar artist with all songs
+  x     actions for artist
+  d     filter albums by year
+  H     switch between folders and tags
   n     Continue search forward
   N     Continue search backwards
+  O     cycle sort key
+  V     reverse sort direction
 song tab
-  ENTER play song (clears current queue)
+  ENTER play song now
+  e     play album or song now
   a     add album or song to queue
   A     add song to playlist
   y     toggle star on song/album
+  L     star/unstar album with all songs
+  x     actions for album or song
+  f     show/hide song formats
+  l     cycle format filter
+  d     filter by year
+  H     switch between folders and tags
+  t     add artist's top songs to queue
+  M     start radio from album's artist
+  TAB   go to top songs
   R     refresh the list
+  O     cycle sort key
+  V     reverse sort direction
+top songs
+  ENTER/e play song now
+  a     add song to queue
+  t     add all top songs to queue
 ESC   Close search
 `
 
@@ -37,13 +89,44 @@ k     move selected song up in queue
 j     move selected song down in queue
 s     save queue as a playlist
 S     shuffle the current queue
+Z     reshuffle the songs after the current one
 l     load last queue from server
+R     refresh songs from server, e.g. after tag edits
 `
 
 const helpPagePlaylists = `
 n     new playlist
 d     delete playlist
+l     import playlist file (M3U, PLS, XSPF)
 a     add playlist or song to queue
+e     play playlist or song now
+ENTER play song now (song list)
+`
+
+const helpPageStats = `
+R     refetch all-time stats
+x     export session history to CSV or JSON
+`
+
+const helpPageNew = `
+ENTER/e play song now
+a     add song to queue
+R     refetch the list
+`
+
+const helpPageDecades = `
+Right/ENTER show the albums or songs
+Left  back to the decades or albums
+a     add decade, album or song to queue
+e     play decade, album or song now
+V     toggle oldest/newest first
+`
+
+const helpPageStarred = `
+ENTER/e play song now
+a     add song to queue
+O     toggle newest first/by title
+R     refetch the list
 `
 
 const helpSearchPage = `
@@ -51,10 +134,17 @@ artist, album, or song column
   Down/Up navigate within the column
   Left    previous column
   Right   next column
-  Enter/a recursively add item to quue
+  Enter/e recursively play item now
+  a       recursively add item to queue
+  M       start radio (artist, album column)
+  x       actions for item
   /       start search
+  O       cycle sort key
+  V       reverse sort direction
+  f       show/hide song formats (song column)
 search field
   Enter   search for text
+  Up/Down recall older/newer queries
   Esc     cancel search
 
 Note: unlike browser, columns navigate
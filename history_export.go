@@ -0,0 +1,132 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// exportedPlay is a history entry as written by the export.
+type exportedPlay struct {
+	Timestamp string `json:"timestamp"`
+	Id        string `json:"id"`
+	Title     string `json:"title"`
+	Artist    string `json:"artist"`
+	Album     string `json:"album"`
+	// length of the song in seconds
+	Duration int `json:"duration"`
+	// seconds the song was heard
+	Listened  int  `json:"listened"`
+	Completed bool `json:"completed"`
+}
+
+var exportColumns = []string{"timestamp", "id", "title", "artist", "album", "duration", "listened", "completed"}
+
+func newExportedPlay(entry historyEntry) exportedPlay {
+	return exportedPlay{
+		Timestamp: entry.started.Format(time.RFC3339),
+		Id:        entry.song.Id,
+		Title:     entry.song.Title,
+		Artist:    entry.song.Artist,
+		Album:     entry.song.Album,
+		Duration:  entry.song.Duration,
+		Listened:  entry.seconds,
+		Completed: entry.completed,
+	}
+}
+
+func writeHistoryCSV(w io.Writer, history []historyEntry) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(exportColumns); err != nil {
+		return err
+	}
+	for _, entry := range history {
+		play := newExportedPlay(entry)
+		record := []string{
+			play.Timestamp,
+			play.Id,
+			play.Title,
+			play.Artist,
+			play.Album,
+			strconv.Itoa(play.Duration),
+			strconv.Itoa(play.Listened),
+			strconv.FormatBool(play.Completed),
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func writeHistoryJSON(w io.Writer, history []historyEntry) error {
+	plays := make([]exportedPlay, len(history))
+	for i, entry := range history {
+		plays[i] = newExportedPlay(entry)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plays)
+}
+
+// expandHome replaces a leading ~ with the home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// defaultHistoryExportPath returns client.history-export-path, or a file
+// named after today in the home directory.
+func defaultHistoryExportPath(now time.Time) string {
+	if path := viper.GetString("client.history-export-path"); path != "" {
+		return path
+	}
+	return "~/stmps-history-" + now.Format("2006-01-02") + ".csv"
+}
+
+// exportHistory writes the history to path, as JSON for .json files and as
+// CSV for .csv files. It returns the expanded path.
+func exportHistory(path string, history []historyEntry) (string, error) {
+	path, err := expandHome(strings.TrimSpace(path))
+	if err != nil {
+		return "", err
+	}
+
+	var write func(io.Writer, []historyEntry) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		write = writeHistoryCSV
+	case ".json":
+		write = writeHistoryJSON
+	default:
+		return "", fmt.Errorf("%s: the file name must end in .csv or .json", path)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := write(f, history); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
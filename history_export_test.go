@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/stretchr/testify/assert"
+)
+
+var testHistory = []historyEntry{
+	{
+		song:      mpvplayer.QueueItem{Id: "1", Title: "Intro, Part 1", Artist: "A", Album: "X", Duration: 200},
+		started:   time.Date(2023, 5, 1, 14, 0, 0, 0, time.UTC),
+		seconds:   200,
+		completed: true,
+	},
+	{
+		song:    mpvplayer.QueueItem{Id: "2", Title: "Skipped", Artist: "B", Album: "Y", Duration: 180},
+		started: time.Date(2023, 5, 1, 14, 3, 20, 0, time.UTC),
+		seconds: 4,
+	},
+}
+
+func TestExportHistoryCSV(t *testing.T) {
+	path, err := exportHistory(filepath.Join(t.TempDir(), "history.csv"), testHistory)
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"timestamp,id,title,artist,album,duration,listened,completed",
+		`2023-05-01T14:00:00Z,1,"Intro, Part 1",A,X,200,200,true`,
+		"2023-05-01T14:03:20Z,2,Skipped,B,Y,180,4,false",
+		"",
+	}, "\n"), string(data))
+}
+
+func TestExportHistoryJSON(t *testing.T) {
+	path, err := exportHistory(filepath.Join(t.TempDir(), "history.JSON"), testHistory)
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var plays []exportedPlay
+	assert.NoError(t, json.Unmarshal(data, &plays))
+	assert.Len(t, plays, 2)
+	assert.Equal(t, exportedPlay{
+		Timestamp: "2023-05-01T14:03:20Z",
+		Id:        "2",
+		Title:     "Skipped",
+		Artist:    "B",
+		Album:     "Y",
+		Duration:  180,
+		Listened:  4,
+	}, plays[1])
+}
+
+func TestExportHistoryFormat(t *testing.T) {
+	_, err := exportHistory(filepath.Join(t.TempDir(), "history.txt"), testHistory)
+	assert.Error(t, err)
+}
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	assert.NoError(t, err)
+
+	path, err := expandHome("~/stats.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, "stats.csv"), path)
+
+	path, err = expandHome("/tmp/~stats.csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/~stats.csv", path)
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// how often the scan status is polled while the server scans
+const scanPollInterval = 2 * time.Second
+
+// libraryScan tracks the server's library scan while stmps follows it.
+type libraryScan struct {
+	running bool
+	// the server refused to let the user scan
+	denied bool
+}
+
+// scanProgressText describes a running scan.
+func scanProgressText(status subsonic.ScanStatus) string {
+	if status.Count == 0 {
+		return "Scanning library..."
+	}
+	return fmt.Sprintf("Scanning library: %d items", status.Count)
+}
+
+// handleLibraryScan starts a library scan and follows its progress in the
+// status bar. If the server is already scanning, that scan is followed.
+// Only admins may scan, other users get a notice. Must be called from the
+// gui goroutine.
+func (ui *Ui) handleLibraryScan() {
+	if ui.libraryScan.running {
+		ui.showNotice("The library scan is still running")
+		return
+	}
+	if ui.libraryScan.denied {
+		ui.showNotice("Library scans need an admin account")
+		return
+	}
+
+	ui.libraryScan.running = true
+	ui.showNotice("Starting library scan...")
+
+	go func() {
+		status, err := ui.startLibraryScan()
+		for err == nil && status.Scanning {
+			progress := scanProgressText(status)
+			ui.app.QueueUpdateDraw(func() {
+				ui.showNotice(progress)
+			})
+			time.Sleep(scanPollInterval)
+			status, err = ui.connection.GetScanStatus()
+		}
+
+		var indexes subsonic.SubsonicIndexes
+		if err == nil {
+			// the artists may have changed
+			indexes, err = fetchBrowseIndexes(ui.connection, ui.browserPage.browseMode)
+		}
+
+		ui.app.QueueUpdateDraw(func() {
+			ui.libraryScan.running = false
+			if errors.Is(err, subsonic.ErrNotAuthorized) {
+				ui.libraryScan.denied = true
+				ui.showNotice("Library scans need an admin account")
+				return
+			}
+			if err != nil {
+				ui.logger.PrintError("libraryScan", err)
+				ui.showNotice("Library scan failed, see log")
+				return
+			}
+
+			ui.connection.ClearCache()
+			ui.browserPage.refreshArtists(indexes)
+			// refetched the next time the page is shown
+			ui.newPage.fetchedAt = time.Time{}
+			ui.showNotice(fmt.Sprintf("Library scan finished: %d items", status.Count))
+		})
+	}()
+}
+
+// startLibraryScan checks that the user is an admin and starts a scan unless
+// one is running. It returns the scan status.
+func (ui *Ui) startLibraryScan() (subsonic.ScanStatus, error) {
+	// some servers don't implement getUser, startScan then tells
+	user, err := ui.connection.GetUser(ui.connection.Username)
+	if err != nil {
+		ui.logger.PrintError("libraryScan: GetUser", err)
+	} else if !user.AdminRole {
+		return subsonic.ScanStatus{}, subsonic.ErrNotAuthorized
+	}
+
+	status, err := ui.connection.GetScanStatus()
+	if err != nil {
+		return status, err
+	}
+	if status.Scanning {
+		return status, nil
+	}
+	if err := ui.connection.StartScan(); err != nil {
+		return status, err
+	}
+	return subsonic.ScanStatus{Scanning: true}, nil
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanProgressText(t *testing.T) {
+	assert.Equal(t, "Scanning library...", scanProgressText(subsonic.ScanStatus{Scanning: true}))
+	assert.Equal(t, "Scanning library: 1234 items", scanProgressText(subsonic.ScanStatus{Scanning: true, Count: 1234}))
+}
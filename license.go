@@ -0,0 +1,57 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// licenseText describes the server's license at now, and whether the server
+// might restrict features because of it, e.g. when a trial expired.
+func licenseText(license subsonic.SubsonicLicense, now time.Time) (text string, restricted bool) {
+	date := func(t time.Time) string {
+		return t.Local().Format("2006-01-02")
+	}
+	expires := parseSubsonicTime(license.LicenseExpires)
+	trialExpires := parseSubsonicTime(license.TrialExpires)
+
+	if !license.Valid {
+		if !trialExpires.IsZero() && trialExpires.Before(now) {
+			return "trial expired on " + date(trialExpires), true
+		}
+		return "not licensed", true
+	}
+	switch {
+	case !expires.IsZero() && expires.Before(now):
+		return "expired on " + date(expires), true
+	case !expires.IsZero():
+		return "licensed until " + date(expires), false
+	case !trialExpires.IsZero() && trialExpires.After(now):
+		return "trial until " + date(trialExpires), false
+	}
+	return "licensed", false
+}
+
+// checkLicense fetches the server's license state in the background and
+// warns if features might be restricted. Servers that don't implement
+// getLicense are left alone.
+func (ui *Ui) checkLicense() {
+	go func() {
+		license, err := ui.connection.GetLicense()
+		if err != nil {
+			ui.logger.Printf("server doesn't report a license: %v", err)
+			return
+		}
+		text, restricted := licenseText(license, time.Now())
+		ui.app.QueueUpdateDraw(func() {
+			ui.licenseText = text
+			if restricted {
+				ui.showNotice(fmt.Sprintf("Server license: %s, some features might be restricted", text))
+			}
+		})
+	}()
+}
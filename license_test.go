@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLicenseText(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+	check := func(license subsonic.SubsonicLicense, text string, restricted bool) {
+		t.Helper()
+		gotText, gotRestricted := licenseText(license, now)
+		assert.Equal(t, text, gotText)
+		assert.Equal(t, restricted, gotRestricted)
+	}
+
+	// e.g. OpenSubsonic servers
+	check(subsonic.SubsonicLicense{Valid: true}, "licensed", false)
+	check(subsonic.SubsonicLicense{Valid: true, LicenseExpires: "2025-01-31T12:00:00Z"}, "licensed until 2025-01-31", false)
+	check(subsonic.SubsonicLicense{Valid: true, TrialExpires: "2024-06-20T12:00:00Z"}, "trial until 2024-06-20", false)
+	check(subsonic.SubsonicLicense{Valid: false, TrialExpires: "2024-05-20T12:00:00Z"}, "trial expired on 2024-05-20", true)
+	check(subsonic.SubsonicLicense{Valid: true, LicenseExpires: "2024-05-01T12:00:00Z"}, "expired on 2024-05-01", true)
+	check(subsonic.SubsonicLicense{}, "not licensed", true)
+}
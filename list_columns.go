@@ -0,0 +1,198 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
+)
+
+// Optional columns of song lists, see ui.columns.
+const (
+	columnTrack    = "track"
+	columnDuration = "duration"
+	columnAlbum    = "album"
+)
+
+var allColumns = []string{columnTrack, columnDuration, columnAlbum}
+
+// views with a ui.columns entry
+var columnViews = []string{PageBrowser, PageQueue, PagePlaylists, PageSearch, PageDecades}
+
+// longer album names are cut off, so the durations stay in sight
+const albumColumnWidth = 30
+
+// listColumns are the optional columns of the song list of a view.
+type listColumns struct {
+	track    bool
+	duration bool
+	album    bool
+}
+
+// parseColumns checks a ui.columns list: known columns, in any order.
+func parseColumns(value interface{}) (listColumns, error) {
+	var columns listColumns
+	list, ok := value.([]interface{})
+	if !ok {
+		return columns, fmt.Errorf("expected a list of columns, got %v", value)
+	}
+	for _, entry := range list {
+		switch entry {
+		case columnTrack:
+			columns.track = true
+		case columnDuration:
+			columns.duration = true
+		case columnAlbum:
+			columns.album = true
+		default:
+			return columns, fmt.Errorf("expected one of %s, got %v", strings.Join(allColumns, ", "), entry)
+		}
+	}
+	return columns, nil
+}
+
+func isColumnList(value interface{}) error {
+	_, err := parseColumns(value)
+	return err
+}
+
+// loadListColumns returns the columns of ui.columns.<view>, or defaults if
+// it isn't set. The config was validated at startup.
+func loadListColumns(view string, defaults listColumns) listColumns {
+	key := "ui.columns." + view
+	if !viper.IsSet(key) {
+		return defaults
+	}
+	columns, err := parseColumns(viper.Get(key))
+	if err != nil {
+		return defaults
+	}
+	return columns
+}
+
+// cutText shortens text to width characters, marking the cut with "…".
+func cutText(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func formatTrackNumber(track int) string {
+	if track <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("[gray]%d.[-]", track)
+}
+
+func formatDuration(seconds int) string {
+	if seconds <= 0 {
+		return ""
+	}
+	min, sec := iSecondsToMinAndSec(seconds)
+	return fmt.Sprintf("[gray]%d:%02d[-]", min, sec)
+}
+
+// layout returns an empty column layout for rows made by cells.
+func (c listColumns) layout() *columnLayout {
+	var alignRight []bool
+	if c.track {
+		alignRight = append(alignRight, true)
+	}
+	alignRight = append(alignRight, false)
+	if c.album {
+		alignRight = append(alignRight, false)
+	}
+	if c.duration {
+		alignRight = append(alignRight, true)
+	}
+	return &columnLayout{alignRight: alignRight}
+}
+
+// cells returns the cells of a list row: the track number, the formatted
+// title, the album and the duration, as far as they're enabled. Albums and
+// folders leave the song columns empty.
+func (c listColumns) cells(entity subsonic.SubsonicEntity, title string) []string {
+	song := !entity.IsDirectory
+	var cells []string
+	if c.track {
+		cells = append(cells, "")
+		if song {
+			cells[len(cells)-1] = formatTrackNumber(entity.Track)
+		}
+	}
+	cells = append(cells, title)
+	if c.album {
+		cells = append(cells, "")
+		if song {
+			cells[len(cells)-1] = "[gray]" + tview.Escape(cutText(entity.Album, albumColumnWidth)) + "[-]"
+		}
+	}
+	if c.duration {
+		cells = append(cells, "")
+		if song {
+			cells[len(cells)-1] = formatDuration(entity.Duration)
+		}
+	}
+	return cells
+}
+
+// columnLayout aligns the rows of a list into columns. Cells may contain
+// color tags. Every column is as wide as its widest cell, the last one isn't
+// padded.
+type columnLayout struct {
+	// per column, e.g. for numbers
+	alignRight []bool
+	rows       [][]string
+	widths     []int
+}
+
+// add appends a row.
+func (l *columnLayout) add(cells []string) {
+	for i, cell := range cells {
+		if i >= len(l.widths) {
+			l.widths = append(l.widths, 0)
+		}
+		l.widths[i] = max(l.widths[i], tview.TaggedStringWidth(cell))
+	}
+	l.rows = append(l.rows, cells)
+}
+
+// line returns a row padded to the column widths. Rows added later may be
+// formatted with it too, e.g. to update a single list entry.
+func (l *columnLayout) line(cells []string) string {
+	var line strings.Builder
+	for i, cell := range cells {
+		if i > 0 {
+			line.WriteString(" ")
+		}
+		padding := 0
+		if i < len(l.widths) {
+			padding = max(0, l.widths[i]-tview.TaggedStringWidth(cell))
+		}
+		right := i < len(l.alignRight) && l.alignRight[i]
+		if right {
+			line.WriteString(strings.Repeat(" ", padding))
+		}
+		line.WriteString(cell)
+		if !right && i < len(cells)-1 {
+			line.WriteString(strings.Repeat(" ", padding))
+		}
+	}
+	return line.String()
+}
+
+// lines returns the rows added, aligned.
+func (l *columnLayout) lines() []string {
+	lines := make([]string, len(l.rows))
+	for i, cells := range l.rows {
+		lines[i] = l.line(cells)
+	}
+	return lines
+}
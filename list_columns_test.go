@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseColumns(t *testing.T) {
+	columns, err := parseColumns([]interface{}{"duration", "track"})
+	assert.NoError(t, err)
+	assert.Equal(t, listColumns{track: true, duration: true}, columns)
+
+	columns, err = parseColumns([]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, listColumns{}, columns)
+
+	assert.Error(t, isColumnList([]interface{}{"artist"}))
+	assert.Error(t, isColumnList("track"))
+}
+
+func TestColumnLayout(t *testing.T) {
+	layout := &columnLayout{alignRight: []bool{true, false, true}}
+	layout.add([]string{"1.", "Intro", "0:59"})
+	layout.add([]string{"12.", "[white]Longer title[-]", "12:30"})
+	layout.add([]string{"", "Hidden track", ""})
+
+	assert.Equal(t, []string{
+		" 1. Intro         0:59",
+		"12. [white]Longer title[-] 12:30",
+		"    Hidden track      ",
+	}, layout.lines())
+
+	// the last column isn't padded if it's left aligned
+	layout = &columnLayout{alignRight: []bool{false, false}}
+	layout.add([]string{"a", "x"})
+	layout.add([]string{"abc", "y"})
+	assert.Equal(t, []string{"a   x", "abc y"}, layout.lines())
+}
+
+func TestListColumnCells(t *testing.T) {
+	song := subsonic.SubsonicEntity{Id: "1", Track: 3, Album: "Blue [Train]", Duration: 421}
+	columns := listColumns{track: true, duration: true, album: true}
+
+	assert.Equal(t, []string{"[gray]3.[-]", "Title", "[gray]Blue [Train[][-]", "[gray]7:01[-]"}, columns.cells(song, "Title"))
+	assert.Equal(t, []string{"Title"}, listColumns{}.cells(song, "Title"))
+
+	// albums leave the song columns empty
+	album := subsonic.SubsonicEntity{Id: "2", IsDirectory: true, Album: "Blue Train"}
+	assert.Equal(t, []string{"", "[Blue Train]", "", ""}, columns.cells(album, "[Blue Train]"))
+}
+
+func TestCutText(t *testing.T) {
+	assert.Equal(t, "short", cutText("short", 10))
+	assert.Equal(t, "a very l…", cutText("a very long album", 9))
+}
+
+func TestQueueColumns(t *testing.T) {
+	assert.Equal(t, []int{queueColumnStar, queueColumnTitle, queueColumnArtist, queueColumnDuration}, queueColumns(defaultQueueColumns))
+	assert.Equal(t, []int{queueColumnStar, queueColumnTrack, queueColumnTitle, queueColumnArtist, queueColumnAlbum}, queueColumns(listColumns{track: true, album: true}))
+}
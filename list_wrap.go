@@ -0,0 +1,38 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import "github.com/rivo/tview"
+
+// setListWrap decides whether moving down from the last item of the page
+// lists selects the first one, and up from the first the last one, see
+// ui.wrap-lists. The dialogs keep tview's default of wrapping. The browser
+// search (n/N) always wraps.
+func (ui *Ui) setListWrap(wrap bool) {
+	lists := []*tview.List{
+		ui.browserPage.artistList,
+		ui.browserPage.entityList,
+		ui.browserPage.topSongsList,
+		ui.playlistPage.playlistList,
+		ui.playlistPage.selectedPlaylist,
+		ui.searchPage.artistList,
+		ui.searchPage.albumList,
+		ui.searchPage.songList,
+		ui.decadesPage.decadeList,
+		ui.decadesPage.albumList,
+		ui.decadesPage.songList,
+	}
+	for _, list := range lists {
+		list.SetWrapAround(wrap)
+	}
+
+	tables := []*tview.Table{
+		ui.queuePage.queueList,
+		ui.newPage.songTable,
+		ui.starredPage.songTable,
+	}
+	for _, table := range tables {
+		table.SetWrapSelection(wrap, false)
+	}
+}
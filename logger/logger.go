@@ -3,7 +3,13 @@
 
 package logger
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
+
+// lines of PrintError start with this
+const errorPrefix = "Error("
 
 type Logger struct {
 	Prints chan string
@@ -22,5 +28,10 @@ func (l *Logger) Printf(s string, as ...interface{}) {
 }
 
 func (l *Logger) PrintError(source string, err error) {
-	l.Printf("Error(%s) -> %s", source, err.Error())
+	l.Printf(errorPrefix+"%s) -> %s", source, err.Error())
+}
+
+// IsError reports whether a line was printed with PrintError.
+func IsError(line string) bool {
+	return strings.HasPrefix(line, errorPrefix)
 }
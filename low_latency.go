@@ -0,0 +1,53 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import "github.com/spf13/viper"
+
+// mpv options without a cache, for streams that arrive faster than they're
+// played
+var noCacheMpvOptions = [][2]string{
+	{"cache", "no"},
+	{"demuxer-max-bytes", "2MiB"},
+}
+
+// latencySettings are the settings player.low-latency changes. Each can be
+// set on its own, which takes precedence over the preset.
+type latencySettings struct {
+	// player.cache: let mpv cache the stream ahead
+	cache bool
+	// player.gapless: load the next song ahead of time
+	gapless bool
+	// server.original-streams: ask the server not to transcode
+	originalStreams bool
+}
+
+// loadLatencySettings resolves the player.low-latency preset and the
+// settings that override it.
+func loadLatencySettings() latencySettings {
+	lowLatency := viper.GetBool("player.low-latency")
+	settings := latencySettings{
+		cache: !lowLatency,
+		// gapless is off by default, the preset doesn't turn it on
+		originalStreams: lowLatency,
+	}
+	if viper.IsSet("player.cache") {
+		settings.cache = viper.GetBool("player.cache")
+	}
+	if viper.IsSet("player.gapless") {
+		settings.gapless = viper.GetBool("player.gapless")
+	}
+	if viper.IsSet("server.original-streams") {
+		settings.originalStreams = viper.GetBool("server.original-streams")
+	}
+	return settings
+}
+
+// mpvOptions returns the mpv options for the settings.
+func (s latencySettings) mpvOptions() [][2]string {
+	if s.cache {
+		return nil
+	}
+	return noCacheMpvOptions
+}
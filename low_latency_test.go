@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencySettingsDefault(t *testing.T) {
+	loadTestConfig(t, `
+[server]
+host = 'https://example.com'
+`)
+
+	settings := loadLatencySettings()
+	assert.Equal(t, latencySettings{cache: true}, settings)
+	assert.Empty(t, settings.mpvOptions())
+}
+
+func TestLatencySettingsPreset(t *testing.T) {
+	loadTestConfig(t, `
+[player]
+low-latency = true
+`)
+
+	settings := loadLatencySettings()
+	assert.Equal(t, latencySettings{originalStreams: true}, settings)
+	assert.Equal(t, noCacheMpvOptions, settings.mpvOptions())
+}
+
+func TestLatencySettingsOverride(t *testing.T) {
+	loadTestConfig(t, `
+[server]
+original-streams = false
+
+[player]
+low-latency = true
+cache = true
+gapless = true
+`)
+
+	settings := loadLatencySettings()
+	assert.Equal(t, latencySettings{cache: true, gapless: true}, settings)
+	assert.Empty(t, settings.mpvOptions())
+}
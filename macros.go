@@ -0,0 +1,295 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+)
+
+// macroAction is an action that macros can run. arg is the text after the
+// action name, e.g. the playlist name of "add-to-playlist Favorites".
+type macroAction struct {
+	// the action takes an argument
+	hasArg bool
+	run    func(ui *Ui, arg string) error
+}
+
+// macroActions is the registry of actions that macros are built from.
+var macroActions = map[string]macroAction{
+	// toggle play/pause
+	"pause": {
+		run: func(ui *Ui, _ string) error {
+			if ui.castRenderer != nil {
+				ui.castTogglePause()
+				return nil
+			}
+			return ui.player.TogglePlayPause()
+		},
+	},
+	// stop playing
+	"stop": {
+		run: func(ui *Ui, _ string) error {
+			if ui.castRenderer != nil {
+				ui.castStop()
+				return nil
+			}
+			return ui.player.FadeOutAndStop()
+		},
+	},
+	// skip to the next song
+	"next": {
+		run: func(ui *Ui, _ string) error {
+			defer ui.queuePage.UpdateQueue()
+			if ui.castRenderer != nil {
+				ui.castNextTrack()
+				return nil
+			}
+			return ui.player.PlayNextTrack()
+		},
+	},
+	// change the volume by a percentage, e.g. -10
+	"volume": {
+		hasArg: true,
+		run: func(ui *Ui, arg string) error {
+			delta, err := strconv.Atoi(arg)
+			if err != nil {
+				return err
+			}
+			if ui.castRenderer != nil {
+				ui.castAdjustVolume(delta)
+				return nil
+			}
+			return ui.player.AdjustVolume(delta)
+		},
+	},
+	// seek by seconds, e.g. 30 or -10
+	"seek": {
+		hasArg: true,
+		run: func(ui *Ui, arg string) error {
+			delta, err := strconv.Atoi(arg)
+			if err != nil {
+				return err
+			}
+			if ui.castRenderer != nil {
+				ui.castSeek(delta)
+				return nil
+			}
+			return ui.player.Seek(delta)
+		},
+	},
+	// star the current song
+	"star-current": {
+		run: func(ui *Ui, _ string) error {
+			return ui.setCurrentSongStar(true)
+		},
+	},
+	// remove the star from the current song
+	"unstar-current": {
+		run: func(ui *Ui, _ string) error {
+			return ui.setCurrentSongStar(false)
+		},
+	},
+	// add the current song to the named playlist
+	"add-to-playlist": {
+		hasArg: true,
+		run: func(ui *Ui, arg string) error {
+			return ui.addCurrentSongToPlaylist(arg)
+		},
+	},
+	// add random songs to the queue
+	"random-songs": {
+		run: func(ui *Ui, _ string) error {
+			ui.handleAddRandomSongs("", "random")
+			return nil
+		},
+	},
+	// clear the queue and stop playing
+	"clear-queue": {
+		run: func(ui *Ui, _ string) error {
+			ui.stopRadio()
+			ui.player.ClearQueue()
+			ui.queuePage.UpdateQueue()
+			return nil
+		},
+	},
+	// show a page: browser, queue, playlists, search, log, new, stats,
+	// decades
+	"page": {
+		hasArg: true,
+		run: func(ui *Ui, arg string) error {
+			if !containsString(allViews, arg) {
+				return fmt.Errorf("unknown page %q", arg)
+			}
+			if !ui.isViewEnabled(arg) {
+				return fmt.Errorf("page %q isn't in ui.views", arg)
+			}
+			ui.ShowPage(arg)
+			return nil
+		},
+	},
+}
+
+// macroStep is an action of a macro with its argument.
+type macroStep struct {
+	name string
+	arg  string
+}
+
+func (s macroStep) String() string {
+	if s.arg == "" {
+		return s.name
+	}
+	return s.name + " " + s.arg
+}
+
+// macro binds a key to actions that are run in order.
+type macro struct {
+	key   rune
+	steps []macroStep
+}
+
+// parseMacroStep parses "name" or "name argument" and checks it against the
+// action registry.
+func parseMacroStep(text string) (macroStep, error) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(text), " ")
+	step := macroStep{name: name, arg: strings.TrimSpace(arg)}
+
+	action, ok := macroActions[step.name]
+	if !ok {
+		return step, fmt.Errorf("unknown action %q", step.name)
+	}
+	if action.hasArg && step.arg == "" {
+		return step, fmt.Errorf("action %q needs an argument", step.name)
+	}
+	if !action.hasArg && step.arg != "" {
+		return step, fmt.Errorf("action %q takes no argument", step.name)
+	}
+	return step, nil
+}
+
+// parseMacros reads the [[macros]] tables of the config, each with a key and
+// a list of actions.
+func parseMacros(value interface{}) ([]macro, error) {
+	tables, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("expected a list of [[macros]] tables")
+	}
+
+	var macros []macro
+	seen := map[rune]bool{}
+	for i, entry := range tables {
+		table, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("macro %d: expected a table", i+1)
+		}
+
+		key, _ := table["key"].(string)
+		if utf8.RuneCountInString(key) != 1 {
+			return nil, fmt.Errorf("macro %d: key must be a single character, got %q", i+1, key)
+		}
+		m := macro{key: []rune(key)[0]}
+		if seen[m.key] {
+			return nil, fmt.Errorf("macro %d: key %q is used twice", i+1, key)
+		}
+		seen[m.key] = true
+
+		actions, ok := table["actions"].([]interface{})
+		if !ok || len(actions) == 0 {
+			return nil, fmt.Errorf("macro %q: expected a list of actions", key)
+		}
+		for _, a := range actions {
+			text, ok := a.(string)
+			if !ok {
+				return nil, fmt.Errorf("macro %q: expected an action name, got %v", key, a)
+			}
+			step, err := parseMacroStep(text)
+			if err != nil {
+				return nil, fmt.Errorf("macro %q: %v", key, err)
+			}
+			m.steps = append(m.steps, step)
+		}
+		macros = append(macros, m)
+	}
+	return macros, nil
+}
+
+func isMacroList(value interface{}) error {
+	_, err := parseMacros(value)
+	return err
+}
+
+// loadMacros returns the configured macros by key. The config was validated
+// at startup.
+func loadMacros() map[rune]macro {
+	macros := map[rune]macro{}
+	if !viper.IsSet("macros") {
+		return macros
+	}
+	parsed, _ := parseMacros(viper.Get("macros"))
+	for _, m := range parsed {
+		macros[m.key] = m
+	}
+	return macros
+}
+
+// runMacro runs the steps of a macro in order. It stops at the first step
+// that fails and reports it.
+func (ui *Ui) runMacro(m macro) {
+	for i, step := range m.steps {
+		if err := macroActions[step.name].run(ui, step.arg); err != nil {
+			ui.logger.PrintError(fmt.Sprintf("macro %c: step %d (%s)", m.key, i+1, step), err)
+			ui.showNotice(fmt.Sprintf("Macro %c stopped, step %d (%s) failed: %v", m.key, i+1, step, err))
+			return
+		}
+	}
+}
+
+// setCurrentSongStar stars or unstars the song at the head of the queue.
+func (ui *Ui) setCurrentSongStar(starred bool) error {
+	queue := ui.player.GetQueueCopy()
+	if len(queue) == 0 {
+		return errors.New("nothing is playing")
+	}
+	id := queue[0].Id
+	if _, isStarred := ui.starIdList[id]; isStarred == starred {
+		return nil
+	}
+
+	// ToggleStar unstars songs in the list, and stars the others
+	if _, err := ui.connection.ToggleStar(id, ui.starIdList); err != nil {
+		return err
+	}
+	if starred {
+		ui.starIdList[id] = struct{}{}
+	} else {
+		delete(ui.starIdList, id)
+	}
+	ui.queuePage.UpdateQueue()
+	return nil
+}
+
+// addCurrentSongToPlaylist adds the song at the head of the queue to the
+// playlist with the given name.
+func (ui *Ui) addCurrentSongToPlaylist(name string) error {
+	queue := ui.player.GetQueueCopy()
+	if len(queue) == 0 {
+		return errors.New("nothing is playing")
+	}
+	for _, playlist := range ui.playlists {
+		if playlist.Name == name {
+			if err := ui.connection.AddSongToPlaylist(string(playlist.Id), queue[0].Id); err != nil {
+				return err
+			}
+			ui.playlistPage.UpdatePlaylists()
+			return nil
+		}
+	}
+	return fmt.Errorf("no playlist named %q", name)
+}
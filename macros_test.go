@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMacros(t *testing.T) {
+	config := []interface{}{
+		map[string]interface{}{
+			"key":     "F",
+			"actions": []interface{}{"star-current", "add-to-playlist  My Favorites ", "next"},
+		},
+		map[string]interface{}{
+			"key":     "ä",
+			"actions": []interface{}{"volume -10"},
+		},
+	}
+
+	macros, err := parseMacros(config)
+	assert.NoError(t, err)
+	assert.Len(t, macros, 2)
+	assert.Equal(t, 'F', macros[0].key)
+	assert.Equal(t, []macroStep{
+		{name: "star-current"},
+		{name: "add-to-playlist", arg: "My Favorites"},
+		{name: "next"},
+	}, macros[0].steps)
+	assert.Equal(t, 'ä', macros[1].key)
+	assert.Equal(t, "volume -10", macros[1].steps[0].String())
+}
+
+func TestParseMacrosErrors(t *testing.T) {
+	macro := func(key string, actions ...interface{}) map[string]interface{} {
+		return map[string]interface{}{"key": key, "actions": actions}
+	}
+
+	testCases := []struct {
+		name   string
+		config interface{}
+	}{
+		{"not a list", "F"},
+		{"long key", []interface{}{macro("Fx", "next")}},
+		{"missing key", []interface{}{macro("", "next")}},
+		{"duplicate key", []interface{}{macro("F", "next"), macro("F", "pause")}},
+		{"no actions", []interface{}{macro("F")}},
+		{"unknown action", []interface{}{macro("F", "next", "dance")}},
+		{"missing argument", []interface{}{macro("F", "add-to-playlist")}},
+		{"unexpected argument", []interface{}{macro("F", "next 2")}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseMacros(tc.config)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package mpvplayer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/supersonic-app/go-mpv"
+)
+
+// ErrSingleAudioTrack is returned when the current song has no other audio
+// track to switch to.
+var ErrSingleAudioTrack = errors.New("the song has a single audio track")
+
+// AudioTrack is an audio track of the current song, e.g. a commentary or
+// another language.
+type AudioTrack struct {
+	// Id is mpv's track ID, the value of the aid property
+	Id       int    `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Lang     string `json:"lang"`
+	Selected bool   `json:"selected"`
+}
+
+func (t AudioTrack) String() string {
+	name := t.Title
+	if name == "" {
+		name = fmt.Sprintf("track %d", t.Id)
+	}
+	if t.Lang != "" {
+		name += " (" + t.Lang + ")"
+	}
+	return name
+}
+
+// parseAudioTracks returns the audio tracks of mpv's track-list property.
+func parseAudioTracks(text string) ([]AudioTrack, error) {
+	var tracks []AudioTrack
+	if err := json.Unmarshal([]byte(text), &tracks); err != nil {
+		return nil, fmt.Errorf("track-list: %v", err)
+	}
+	audio := tracks[:0]
+	for _, track := range tracks {
+		if track.Type == "audio" {
+			audio = append(audio, track)
+		}
+	}
+	return audio, nil
+}
+
+// nextAudioTrack returns the index of the track after the selected one,
+// wrapping around to the first.
+func nextAudioTrack(tracks []AudioTrack) int {
+	for i, track := range tracks {
+		if track.Selected {
+			return (i + 1) % len(tracks)
+		}
+	}
+	return 0
+}
+
+// AudioTracks returns the audio tracks of the current song.
+func (p *Player) AudioTracks() ([]AudioTrack, error) {
+	value, err := p.getProperty("track-list", mpv.FORMAT_STRING)
+	if err != nil {
+		return nil, err
+	}
+	text, ok := value.(string)
+	if !ok || text == "" {
+		return nil, nil
+	}
+	return parseAudioTracks(text)
+}
+
+// CycleAudioTrack switches to the next audio track of the current song. It
+// returns the track and its number, counted from 1, among tracks. Songs
+// with one audio track return ErrSingleAudioTrack.
+func (p *Player) CycleAudioTrack() (track AudioTrack, number, tracks int, err error) {
+	audioTracks, err := p.AudioTracks()
+	if err != nil {
+		return AudioTrack{}, 0, 0, err
+	}
+	if len(audioTracks) < 2 {
+		return AudioTrack{}, 0, len(audioTracks), ErrSingleAudioTrack
+	}
+
+	index := nextAudioTrack(audioTracks)
+	if err := p.setPropertyString("aid", strconv.Itoa(audioTracks[index].Id)); err != nil {
+		return AudioTrack{}, 0, 0, err
+	}
+	return audioTracks[index], index + 1, len(audioTracks), nil
+}
@@ -0,0 +1,41 @@
+package mpvplayer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAudioTracks(t *testing.T) {
+	text := `[
+		{"id": 1, "type": "video", "title": "Cover", "selected": true},
+		{"id": 1, "type": "audio", "lang": "eng", "selected": true},
+		{"id": 2, "type": "audio", "title": "Commentary", "lang": "eng"},
+		{"id": 3, "type": "audio", "title": "Instrumental"}
+	]`
+	tracks, err := parseAudioTracks(text)
+	assert.NoError(t, err)
+	assert.Equal(t, []AudioTrack{
+		{Id: 1, Type: "audio", Lang: "eng", Selected: true},
+		{Id: 2, Type: "audio", Title: "Commentary", Lang: "eng"},
+		{Id: 3, Type: "audio", Title: "Instrumental"},
+	}, tracks)
+
+	assert.Equal(t, "track 1 (eng)", tracks[0].String())
+	assert.Equal(t, "Commentary (eng)", tracks[1].String())
+	assert.Equal(t, "Instrumental", tracks[2].String())
+
+	_, err = parseAudioTracks("garbage")
+	assert.Error(t, err)
+}
+
+func TestNextAudioTrack(t *testing.T) {
+	tracks := []AudioTrack{{Id: 1}, {Id: 2}, {Id: 3}}
+	// none selected, e.g. with aid=no
+	assert.Equal(t, 0, nextAudioTrack(tracks))
+	tracks[1].Selected = true
+	assert.Equal(t, 2, nextAudioTrack(tracks))
+	tracks[1].Selected = false
+	tracks[2].Selected = true
+	assert.Equal(t, 0, nextAudioTrack(tracks))
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package mpvplayer
+
+import (
+	"fmt"
+	"time"
+)
+
+// What to do when playback stalls for longer than Player.StallTimeout.
+const (
+	StallActionRetry = "retry"
+	StallActionSkip  = "skip"
+)
+
+// reply userdata for observed cache properties, see EventLoop
+const observeBuffering = 1
+
+// updateBuffering reads the cache state after one of the cache properties
+// changed and tells the UI if it differs from the last report.
+func (p *Player) updateBuffering() {
+	buffering, err := p.getPropertyBool("paused-for-cache")
+	if err != nil {
+		// no file loaded
+		buffering = false
+	}
+	percent, err := p.getPropertyInt64("cache-buffering-state")
+	if err != nil {
+		percent = 0
+	}
+
+	if buffering == p.bufferingState.Buffering && percent == p.bufferingState.Percent {
+		return
+	}
+
+	if buffering != p.bufferingState.Buffering {
+		if buffering {
+			p.logger.Printf("mpv: buffering")
+			p.startStallTimer()
+		} else {
+			p.logger.Printf("mpv: buffering done")
+			p.stopStallTimer()
+		}
+	}
+
+	p.bufferingState = BufferingData{
+		Buffering: buffering,
+		Percent:   percent,
+	}
+	if len(p.queue) > 0 { // TODO mutex queue access
+		p.bufferingState.Item = p.queue[0]
+	}
+	p.sendGuiDataEvent(EventBuffering, p.bufferingState)
+}
+
+func (p *Player) startStallTimer() {
+	p.stallMutex.Lock()
+	defer p.stallMutex.Unlock()
+
+	if p.StallTimeout <= 0 || p.stallTimer != nil {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(p.StallTimeout, func() {
+		p.inEventLoop(func() {
+			p.stallMutex.Lock()
+			stalled := p.stallTimer == timer
+			if stalled {
+				p.stallTimer = nil
+			}
+			p.stallMutex.Unlock()
+
+			// buffering finished before EventLoop got to it
+			if !stalled {
+				return
+			}
+			if err := p.handleStall(); err != nil {
+				p.logger.PrintError("handleStall", err)
+			}
+		})
+	})
+	p.stallTimer = timer
+}
+
+func (p *Player) stopStallTimer() {
+	p.stallMutex.Lock()
+	defer p.stallMutex.Unlock()
+
+	if p.stallTimer != nil {
+		p.stallTimer.Stop()
+		p.stallTimer = nil
+	}
+}
+
+// handleStall is called by EventLoop when buffering took longer than
+// StallTimeout.
+func (p *Player) handleStall() error {
+	if len(p.queue) == 0 {
+		return nil
+	}
+
+	if p.StallAction == StallActionSkip {
+		p.logger.Printf("mpv: stalled for %v, skipping", p.StallTimeout)
+		return p.PlayNextTrack()
+	}
+
+	// reload the stream where it stalled
+	position := int64(p.remoteState.timePos)
+	p.logger.Printf("mpv: stalled for %v, reloading at %ds", p.StallTimeout, position)
+	if err := p.setPropertyString("start", fmt.Sprintf("+%d", position)); err != nil {
+		return err
+	}
+	p.resetStartOption = true
+	p.replaceInProgress = true
+	return p.loadFile(p.queue[0].Uri, false)
+}
@@ -0,0 +1,32 @@
+package mpvplayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStallInEventLoop(t *testing.T) {
+	p := &Player{
+		StallTimeout: time.Millisecond,
+		loopCalls:    make(chan func(), 1),
+		loopDone:     make(chan struct{}),
+	}
+
+	p.startStallTimer()
+	// the timer leaves skipping or reloading to EventLoop
+	var call func()
+	select {
+	case call = <-p.loopCalls:
+	case <-time.After(time.Second):
+		t.Fatal("stall not passed to EventLoop")
+	}
+
+	// buffering finished before EventLoop got to it, nothing is done
+	p.stopStallTimer()
+	call()
+	p.stallMutex.Lock()
+	assert.Nil(t, p.stallTimer)
+	p.stallMutex.Unlock()
+}
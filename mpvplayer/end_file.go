@@ -0,0 +1,51 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package mpvplayer
+
+import (
+	"errors"
+
+	"github.com/supersonic-app/go-mpv"
+)
+
+// MPV_END_FILE_REASON_ERROR, the file couldn't be played
+const endFileReasonError = 4
+
+// endFileEvent mirrors the start of mpv_event_end_file, which go-mpv doesn't
+// wrap.
+type endFileEvent struct {
+	reason int32
+	error  int32
+}
+
+// endFileError returns why mpv couldn't play the file of an END_FILE event,
+// nil if it ended for another reason.
+func endFileError(evt *mpv.Event) error {
+	if evt.Data == nil {
+		return nil
+	}
+	data := (*endFileEvent)(evt.Data)
+	if data.reason != endFileReasonError {
+		return nil
+	}
+	return describeMpvError(mpv.Error(data.error))
+}
+
+// describeMpvError turns the errors a stream can fail with into something a
+// listener understands.
+func describeMpvError(code mpv.Error) error {
+	switch code {
+	case mpv.ERROR_SUCCESS:
+		return errors.New("playback failed")
+	case mpv.ERROR_LOADING_FAILED:
+		return errors.New("loading failed (network or server error)")
+	case mpv.ERROR_UNKNOWN_FORMAT:
+		return errors.New("unknown audio format")
+	case mpv.ERROR_NOTHING_TO_PLAY:
+		return errors.New("no audio in the stream")
+	case mpv.ERROR_AO_INIT_FAILED:
+		return errors.New("audio output failed")
+	}
+	return code
+}
@@ -0,0 +1,21 @@
+package mpvplayer
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supersonic-app/go-mpv"
+)
+
+func TestEndFileError(t *testing.T) {
+	// the end of the song
+	eof := endFileEvent{reason: 0}
+	assert.NoError(t, endFileError(&mpv.Event{Event_Id: mpv.EVENT_END_FILE, Data: unsafe.Pointer(&eof)}))
+
+	failed := endFileEvent{reason: endFileReasonError, error: int32(mpv.ERROR_LOADING_FAILED)}
+	err := endFileError(&mpv.Event{Event_Id: mpv.EVENT_END_FILE, Data: unsafe.Pointer(&failed)})
+	assert.EqualError(t, err, "loading failed (network or server error)")
+
+	assert.NoError(t, endFileError(&mpv.Event{Event_Id: mpv.EVENT_END_FILE}))
+}
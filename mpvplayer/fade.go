@@ -0,0 +1,217 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package mpvplayer
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/supersonic-app/go-mpv"
+)
+
+// time between two volume changes of a fade
+const fadeStep = 20 * time.Millisecond
+
+// fadeState tracks a volume fade. While a fade is active, mpv's volume is
+// level times target, and target is the volume the user has set.
+type fadeState struct {
+	mutex  sync.Mutex
+	active bool
+	// incremented by every new or cancelled fade, so the running one stops
+	seq    int
+	target int
+	level  float64
+	// fading out before pausing or stopping
+	out bool
+	// fade in once the loaded file starts
+	pendingIn bool
+}
+
+// fadeVolume returns the volume for a fade at level of target.
+func fadeVolume(target int, level float64) int {
+	return int(math.Round(float64(target) * math.Max(0, math.Min(1, level))))
+}
+
+// fadeLevel returns the level at elapsed of a fade from one level to another
+// that takes duration.
+func fadeLevel(from, to float64, elapsed, duration time.Duration) float64 {
+	if duration <= 0 || elapsed >= duration {
+		return to
+	}
+	return from + (to-from)*float64(elapsed)/float64(duration)
+}
+
+// takeVolume starts a fade if none is active, with the current volume as
+// its target and the given level. Call with the mutex held.
+func (p *Player) takeVolume(level float64) {
+	if p.fade.active {
+		return
+	}
+	volume, err := p.getPropertyInt64("volume")
+	if err != nil {
+		p.logger.PrintError("fade: volume", err)
+	}
+	p.fade.active = true
+	p.fade.target = int(volume)
+	p.fade.level = level
+}
+
+// prepareFadeIn silences the player before playback starts or resumes.
+// startFade(1, ...) then fades in. The user's volume is kept.
+func (p *Player) prepareFadeIn() {
+	p.fade.mutex.Lock()
+	defer p.fade.mutex.Unlock()
+
+	p.takeVolume(0)
+	p.fade.seq++
+	p.fade.level = 0
+	p.fade.out = false
+	if err := p.setProperty("volume", mpv.FORMAT_INT64, 0); err != nil {
+		p.logger.PrintError("fade: silence", err)
+	}
+}
+
+// startFade ramps the volume from the current level to level to within
+// duration, then has EventLoop call done unless the fade was cancelled. The
+// fade's goroutine only sets the volume. Fading in ends the fade, a fade out
+// keeps the volume down until done has paused or stopped playback and called
+// cancelFade.
+func (p *Player) startFade(to float64, duration time.Duration, done func()) {
+	p.fade.mutex.Lock()
+	p.takeVolume(1)
+	p.fade.seq++
+	seq := p.fade.seq
+	from := p.fade.level
+	p.fade.out = to == 0
+	p.fade.mutex.Unlock()
+
+	go func() {
+		start := time.Now()
+		ticker := time.NewTicker(fadeStep)
+		defer ticker.Stop()
+
+		for {
+			elapsed := time.Since(start)
+			p.fade.mutex.Lock()
+			if seq != p.fade.seq {
+				p.fade.mutex.Unlock()
+				return
+			}
+			p.fade.level = fadeLevel(from, to, elapsed, duration)
+			volume := fadeVolume(p.fade.target, p.fade.level)
+			p.fade.mutex.Unlock()
+
+			if err := p.setProperty("volume", mpv.FORMAT_INT64, volume); err != nil {
+				p.logger.PrintError("fade: volume", err)
+			}
+			if elapsed >= duration {
+				break
+			}
+			<-ticker.C
+		}
+
+		if !p.isFade(seq) {
+			return
+		}
+		if done != nil {
+			p.inEventLoop(func() {
+				// not cancelled before EventLoop got to it
+				if p.isFade(seq) {
+					done()
+				}
+			})
+		}
+		if to == 1 {
+			p.cancelFade()
+		}
+	}()
+}
+
+// isFade reports whether seq is the running fade's.
+func (p *Player) isFade(seq int) bool {
+	p.fade.mutex.Lock()
+	defer p.fade.mutex.Unlock()
+
+	return p.fade.active && seq == p.fade.seq
+}
+
+// cancelFade stops a running fade and restores the user's volume.
+func (p *Player) cancelFade() {
+	p.fade.mutex.Lock()
+	defer p.fade.mutex.Unlock()
+
+	p.fade.pendingIn = false
+	if !p.fade.active {
+		return
+	}
+	p.fade.active = false
+	p.fade.out = false
+	p.fade.seq++
+	if err := p.setProperty("volume", mpv.FORMAT_INT64, p.fade.target); err != nil {
+		p.logger.PrintError("fade: restore volume", err)
+	}
+}
+
+// isFadingOut reports whether a pause or stop is waiting for its fade out.
+func (p *Player) isFadingOut() bool {
+	p.fade.mutex.Lock()
+	defer p.fade.mutex.Unlock()
+
+	return p.fade.active && p.fade.out
+}
+
+// fadeInLoaded starts the fade in prepared before a file was loaded.
+func (p *Player) fadeInLoaded() {
+	p.fade.mutex.Lock()
+	pending := p.fade.pendingIn
+	p.fade.pendingIn = false
+	p.fade.mutex.Unlock()
+
+	if pending {
+		p.startFade(1, p.FadeIn, nil)
+	}
+}
+
+// userVolume returns the volume the user has set, which differs from mpv's
+// volume during a fade.
+func (p *Player) userVolume() (int, error) {
+	p.fade.mutex.Lock()
+	defer p.fade.mutex.Unlock()
+
+	if p.fade.active {
+		return p.fade.target, nil
+	}
+	volume, err := p.getPropertyInt64("volume")
+	return int(volume), err
+}
+
+// setUserVolume changes the volume. During a fade the fade's target is
+// changed, so the fade continues toward the new volume.
+func (p *Player) setUserVolume(volume int) error {
+	p.fade.mutex.Lock()
+	defer p.fade.mutex.Unlock()
+
+	if p.fade.active {
+		p.fade.target = volume
+		volume = fadeVolume(volume, p.fade.level)
+	}
+	return p.setProperty("volume", mpv.FORMAT_INT64, volume)
+}
+
+// FadeOutAndStop stops playing after fading out, see Stop.
+func (p *Player) FadeOutAndStop() error {
+	if p.FadeOut <= 0 || p.stopped {
+		return p.Stop()
+	}
+	if playing, err := p.IsPlaying(); err != nil || !playing {
+		return p.Stop()
+	}
+	p.startFade(0, p.FadeOut, func() {
+		if err := p.Stop(); err != nil {
+			p.logger.PrintError("fade: stop", err)
+		}
+	})
+	return nil
+}
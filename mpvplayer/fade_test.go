@@ -0,0 +1,60 @@
+package mpvplayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spezifisch/stmps/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFadeLevel(t *testing.T) {
+	assert.Equal(t, 0.0, fadeLevel(0, 1, 0, time.Second))
+	assert.Equal(t, 0.25, fadeLevel(0, 1, 250*time.Millisecond, time.Second))
+	assert.Equal(t, 1.0, fadeLevel(0, 1, 2*time.Second, time.Second))
+	// a fade out from a partly faded in level
+	assert.Equal(t, 0.25, fadeLevel(0.5, 0, 500*time.Millisecond, time.Second))
+	// disabled fades jump to the end
+	assert.Equal(t, 1.0, fadeLevel(0, 1, 0, 0))
+}
+
+func TestFadeVolume(t *testing.T) {
+	// fades ramp toward the user's volume, not 100%
+	assert.Equal(t, 60, fadeVolume(60, 1))
+	assert.Equal(t, 30, fadeVolume(60, 0.5))
+	assert.Equal(t, 0, fadeVolume(60, 0))
+	assert.Equal(t, 60, fadeVolume(60, 1.5))
+	assert.Equal(t, 0, fadeVolume(60, -1))
+}
+
+func TestFadeDoneInEventLoop(t *testing.T) {
+	p, err := NewPlayer(logger.Init())
+	if !assert.NoError(t, err) {
+		return
+	}
+	nextCall := func() func() {
+		select {
+		case call := <-p.loopCalls:
+			return call
+		case <-time.After(time.Second):
+			t.Fatal("fade not passed to EventLoop")
+			return nil
+		}
+	}
+
+	// the fade's goroutine leaves pausing or stopping to EventLoop
+	done := 0
+	p.startFade(0, 0, func() { done++ })
+	call := nextCall()
+	assert.Equal(t, 0, done)
+	call()
+	assert.Equal(t, 1, done)
+	p.cancelFade()
+
+	// cancelled before EventLoop got to it, e.g. by pressing pause again
+	p.startFade(0, 0, func() { done++ })
+	call = nextCall()
+	p.cancelFade()
+	call()
+	assert.Equal(t, 1, done)
+}
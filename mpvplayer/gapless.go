@@ -0,0 +1,131 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package mpvplayer
+
+import (
+	"github.com/supersonic-app/go-mpv"
+)
+
+// Gapless playback works by appending the next queue item to mpv's playlist
+// while the current one plays. mpv then continues with it without reopening
+// the audio output, instead of us loading it after the current file ended.
+
+// gaplessHint is the gapless flag of the playing file's tags, e.g. the pgap
+// atom iTunes writes to tracks of albums meant to be heard without breaks.
+type gaplessHint int
+
+const (
+	gaplessHintNone gaplessHint = iota
+	// the album flows into its next track
+	gaplessHintOn
+	// the track should be followed by a normal transition
+	gaplessHintOff
+)
+
+// tag that ffmpeg reports the gapless flag as
+const gaplessHintTag = "gapless_playback"
+
+// parseGaplessHint reads the value of the gapless tag.
+func parseGaplessHint(value string) gaplessHint {
+	switch value {
+	case "1", "true", "yes":
+		return gaplessHintOn
+	case "0", "false", "no":
+		return gaplessHintOff
+	}
+	return gaplessHintNone
+}
+
+// readGaplessHint gets the gapless flag of the loaded file. Files without the
+// tag have none.
+func (p *Player) readGaplessHint() gaplessHint {
+	value, err := p.getProperty("metadata/by-key/"+gaplessHintTag, mpv.FORMAT_STRING)
+	if err != nil || value == nil {
+		return gaplessHintNone
+	}
+	text, _ := value.(string)
+	return parseGaplessHint(text)
+}
+
+// isAlbumContinuation reports whether next directly follows prev on the same
+// album: the next track on the same disc, or the first track of the next
+// disc.
+func isAlbumContinuation(prev, next QueueItem) bool {
+	if prev.AlbumId == "" || prev.AlbumId != next.AlbumId {
+		return false
+	}
+
+	prevDisc, nextDisc := prev.DiscNumber, next.DiscNumber
+	// songs without disc numbers are on the first disc
+	if prevDisc == 0 {
+		prevDisc = 1
+	}
+	if nextDisc == 0 {
+		nextDisc = 1
+	}
+
+	if nextDisc == prevDisc {
+		return next.TrackNumber == prev.TrackNumber+1
+	}
+	return nextDisc == prevDisc+1 && next.TrackNumber == 1
+}
+
+// isGaplessTransition decides whether next follows prev without a gap. The
+// gapless flag of prev overrides the settings: a flagged track flows into
+// the next track of its album even with gapless off, and a track flagged
+// otherwise gets a normal transition even with gapless on.
+func isGaplessTransition(gapless, withinAlbumOnly bool, hint gaplessHint, prev, next QueueItem) bool {
+	switch hint {
+	case gaplessHintOn:
+		return isAlbumContinuation(prev, next)
+	case gaplessHintOff:
+		return false
+	}
+	if !gapless {
+		return false
+	}
+	return !withinAlbumOnly || isAlbumContinuation(prev, next)
+}
+
+// gaplessNext returns the queue item that should follow the current one
+// without a gap, or false if the transition should be a normal one.
+func (p *Player) gaplessNext() (QueueItem, bool) {
+	if p.stopped || len(p.queue) < 2 { // TODO mutex queue access
+		return QueueItem{}, false
+	}
+	if !isGaplessTransition(p.Gapless, p.GaplessWithinAlbumOnly, p.gaplessHint, p.queue[0], p.queue[1]) {
+		return QueueItem{}, false
+	}
+	return p.queue[1], true
+}
+
+// updatePreload makes mpv's playlist match the queue: the item returned by
+// gaplessNext is appended after the current file, anything else is removed.
+// It's called whenever the current file or the item after it may have
+// changed.
+func (p *Player) updatePreload() {
+	want := ""
+	if next, ok := p.gaplessNext(); ok {
+		want = next.Uri
+	}
+	if want == p.preloadedUri {
+		return
+	}
+
+	if p.preloadedUri != "" {
+		// removes everything but the current file
+		if err := p.command([]string{"playlist-clear"}); err != nil {
+			p.logger.PrintError("gapless: playlist-clear", err)
+			return
+		}
+		p.preloadedUri = ""
+	}
+	if want != "" {
+		if err := p.command([]string{"loadfile", want, "append"}); err != nil {
+			p.logger.PrintError("gapless: append", err)
+			return
+		}
+		p.preloadedUri = want
+	}
+}
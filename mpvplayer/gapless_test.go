@@ -0,0 +1,33 @@
+package mpvplayer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGaplessHint(t *testing.T) {
+	assert.Equal(t, gaplessHintOn, parseGaplessHint("1"))
+	assert.Equal(t, gaplessHintOff, parseGaplessHint("0"))
+	assert.Equal(t, gaplessHintNone, parseGaplessHint(""))
+	assert.Equal(t, gaplessHintNone, parseGaplessHint("maybe"))
+}
+
+func TestIsGaplessTransition(t *testing.T) {
+	track1 := QueueItem{AlbumId: "a", TrackNumber: 1}
+	track2 := QueueItem{AlbumId: "a", TrackNumber: 2}
+	other := QueueItem{AlbumId: "b", TrackNumber: 5}
+
+	// without a flag the settings decide
+	assert.True(t, isGaplessTransition(true, false, gaplessHintNone, track1, other))
+	assert.False(t, isGaplessTransition(true, true, gaplessHintNone, track1, other))
+	assert.True(t, isGaplessTransition(true, true, gaplessHintNone, track1, track2))
+	assert.False(t, isGaplessTransition(false, false, gaplessHintNone, track1, track2))
+
+	// a flagged album flows even with gapless off, but not into other albums
+	assert.True(t, isGaplessTransition(false, false, gaplessHintOn, track1, track2))
+	assert.False(t, isGaplessTransition(true, false, gaplessHintOn, track1, other))
+
+	// a track flagged otherwise never flows
+	assert.False(t, isGaplessTransition(true, false, gaplessHintOff, track1, track2))
+}
@@ -4,74 +4,148 @@
 package mpvplayer
 
 import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
 	"github.com/supersonic-app/go-mpv"
 )
 
+// capacity of Player.loopCalls, so the GUI rarely waits for EventLoop
+const loopCallsSize = 16
+
+// EventLoop handles mpv's events until Quit. If mpv dies or handling an
+// event panics, mpv is restarted and the current song resumed, see
+// restartMpv.
 func (p *Player) EventLoop() {
-	if err := p.instance.ObserveProperty(0, "playback-time", mpv.FORMAT_INT64); err != nil {
+	defer close(p.loopDone)
+	for {
+		err := p.handleEvents()
+		if err == nil {
+			// quit
+			return
+		}
+		p.logger.PrintError("mpv.EventLoop", err)
+		if err = p.restartMpv(err); err != nil {
+			p.logger.PrintError("mpv.EventLoop", err)
+			p.sendGuiDataEvent(EventFailed, err)
+			// wait for the quit signal
+			for {
+				select {
+				case evt := <-p.mpvEvents:
+					if evt == nil {
+						return
+					}
+				case <-p.loopCalls:
+					// there's no mpv to do them with
+				}
+			}
+		}
+	}
+}
+
+// inEventLoop has EventLoop run call, which owns the queue and the playback
+// state. Timers and other goroutines use it instead of changing them
+// directly. Calls are dropped once EventLoop has returned.
+func (p *Player) inEventLoop(call func()) {
+	select {
+	case p.loopCalls <- call:
+	case <-p.loopDone:
+	}
+}
+
+func (p *Player) observeProperties() {
+	if err := p.observeProperty(0, "playback-time", mpv.FORMAT_INT64); err != nil {
 		p.logger.PrintError("Observe1", err)
 	}
-	if err := p.instance.ObserveProperty(0, "duration", mpv.FORMAT_INT64); err != nil {
+	if err := p.observeProperty(0, "duration", mpv.FORMAT_INT64); err != nil {
 		p.logger.PrintError("Observe2", err)
 	}
-	if err := p.instance.ObserveProperty(0, "volume", mpv.FORMAT_INT64); err != nil {
+	if err := p.observeProperty(0, "volume", mpv.FORMAT_INT64); err != nil {
 		p.logger.PrintError("Observe3", err)
 	}
+	if err := p.observeProperty(observeBuffering, "paused-for-cache", mpv.FORMAT_FLAG); err != nil {
+		p.logger.PrintError("Observe4", err)
+	}
+	if err := p.observeProperty(observeBuffering, "cache-buffering-state", mpv.FORMAT_INT64); err != nil {
+		p.logger.PrintError("Observe5", err)
+	}
+}
+
+// handleEvents handles mpv's events. It returns nil on the quit signal, or
+// why mpv can't be used anymore.
+func (p *Player) handleEvents() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Printf("mpv.EventLoop: panic: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("event handling panicked: %v", r)
+		}
+	}()
+	p.observeProperties()
+
+	for {
+		var evt *mpv.Event
+		select {
+		case call := <-p.loopCalls:
+			call()
+			continue
+		case evt = <-p.mpvEvents:
+		}
 
-	for evt := range p.mpvEvents {
 		if evt == nil {
 			// quit signal
-			break
+			return nil
+		} else if evt.Event_Id == mpv.EVENT_SHUTDOWN {
+			return errMpvShutdown
+		} else if evt.Event_Id == mpv.EVENT_PROPERTY_CHANGE && evt.Reply_Userdata == observeBuffering {
+			p.updateBuffering()
 		} else if evt.Event_Id == mpv.EVENT_PROPERTY_CHANGE {
 			// one of our observed properties changed. which one is probably extractable from evt.Data.. somehow.
-
-			position, err := p.getPropertyInt64("playback-time")
+			p.throttledSendStatus()
+		} else if evt.Event_Id == mpv.EVENT_END_FILE {
+			err := endFileError(evt)
 			if err != nil {
-				p.logger.Printf("mpv.EventLoop (%s): GetProperty %s -- %s", evt.Event_Id.String(), "playback-time", err.Error())
+				p.logger.Printf("mpv.EventLoop: %s failed: %v", p.loadedItem.Id, err)
 			}
-			duration, err := p.getPropertyInt64("duration")
-			if err != nil {
-				p.logger.Printf("mpv.EventLoop (%s): GetProperty %s -- %s", evt.Event_Id.String(), "duration", err.Error())
-			}
-			volume, err := p.getPropertyInt64("volume")
-			if err != nil {
-				p.logger.Printf("mpv.EventLoop (%s): GetProperty %s -- %s", evt.Event_Id.String(), "volume", err.Error())
+			p.sendTrackEnded(!p.replaceInProgress && !p.stopped && err == nil, err)
+			if p.replaceInProgress {
+				// we don't want to update anything if we're in the process of replacing the current track
+				continue
 			}
 
-			statusData := StatusData{
-				Volume:   volume,
-				Position: position,
-				Duration: duration,
-			}
-			p.remoteState.timePos = float64(statusData.Position)
-			p.sendGuiDataEvent(EventStatus, statusData)
-		} else if evt.Event_Id == mpv.EVENT_END_FILE && !p.replaceInProgress {
-			// we don't want to update anything if we're in the process of replacing the current track
-
 			if p.stopped {
 				// this is feedback for a user-requested stop
 				// don't delete the first track so it gets started from the beginning when pressing play
 				p.logger.Print("mpv.EventLoop: mpv stopped")
 				p.stopped = true
+				p.syncMirrors()
 				p.sendGuiEvent(EventStopped)
 			} else {
 				// advance queue and play next track
 				if len(p.queue) > 0 {
+					p.rememberPlayed()
 					p.queue = p.queue[1:]
 				}
 
-				if len(p.queue) > 0 {
-					if err := p.instance.Command([]string{"loadfile", p.queue[0].Uri}); err != nil {
+				if len(p.queue) > 0 && p.preloadedUri != "" && p.preloadedUri == p.queue[0].Uri {
+					// mpv continues with the preloaded song on its own
+					p.preloadedUri = ""
+				} else if len(p.queue) > 0 {
+					if err := p.loadFile(p.queue[0].Uri, true); err != nil {
 						p.logger.PrintError("mpv.EventLoop: load next", err)
 					}
 				} else {
 					// no remaining tracks
 					p.logger.Print("mpv.EventLoop: stopping (auto)")
 					p.stopped = true
+					p.syncMirrors()
 					p.sendGuiEvent(EventStopped)
 				}
 			}
 		} else if evt.Event_Id == mpv.EVENT_START_FILE {
+			// show the new track's position right away
+			p.resetStatusThrottle()
+
 			p.replaceInProgress = false
 			p.stopped = false
 
@@ -79,6 +153,13 @@ func (p *Player) EventLoop() {
 			if len(p.queue) > 0 {
 				currentSong = p.queue[0]
 			}
+			p.loadedItem = currentSong
+			p.applyTrackGain(currentSong)
+			// a preloaded song starts without loadFile
+			p.updateFilters()
+			p.gaplessHint = gaplessHintNone
+			p.updatePreload()
+			p.syncMirrors()
 
 			if paused, err := p.IsPaused(); err != nil {
 				p.logger.PrintError("mpv.EventLoop: IsPaused", err)
@@ -87,7 +168,24 @@ func (p *Player) EventLoop() {
 			} else {
 				p.sendGuiDataEvent(EventPaused, currentSong)
 			}
-		} else if evt.Event_Id == mpv.EVENT_IDLE || evt.Event_Id == mpv.EVENT_NONE {
+		} else if evt.Event_Id == mpv.EVENT_FILE_LOADED {
+			p.fadeInLoaded()
+			// the tags may ask for a different transition to the next song
+			if hint := p.readGaplessHint(); hint != p.gaplessHint {
+				p.gaplessHint = hint
+				p.updatePreload()
+			}
+			if p.resetStartOption {
+				// a stalled stream was reloaded at its last position, see handleStall
+				p.resetStartOption = false
+				if err := p.setPropertyString("start", "none"); err != nil {
+					p.logger.PrintError("mpv.EventLoop: reset start", err)
+				}
+			}
+		} else if evt.Event_Id == mpv.EVENT_PLAYBACK_RESTART {
+			// a seek is done or a file started playing
+			p.resumeAfterSeek()
+		} else if evt.Event_Id == mpv.EVENT_IDLE || evt.Event_Id == mpv.EVENT_NONE || evt.Event_Id == mpv.EVENT_SEEK {
 			continue
 		} else {
 			p.logger.Printf("mpv.EventLoop: unhandled event id %v", evt.Event_Id)
@@ -96,6 +194,99 @@ func (p *Player) EventLoop() {
 	}
 }
 
+// throttledSendStatus sends a status event, but at most once per
+// StatusInterval. If the last one was sent too recently, EventLoop sends a
+// single event when the interval has passed so that the final state is never
+// missed.
+func (p *Player) throttledSendStatus() {
+	p.statusMutex.Lock()
+	defer p.statusMutex.Unlock()
+
+	if p.statusTimer != nil {
+		// already scheduled
+		return
+	}
+
+	wait := p.StatusInterval - time.Since(p.lastStatus)
+	if wait <= 0 {
+		p.lastStatus = time.Now()
+		p.sendStatus()
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(wait, func() {
+		p.inEventLoop(func() {
+			p.statusMutex.Lock()
+			scheduled := p.statusTimer == timer
+			if scheduled {
+				p.statusTimer = nil
+				p.lastStatus = time.Now()
+			}
+			p.statusMutex.Unlock()
+
+			// a new song's status was sent right away meanwhile
+			if scheduled {
+				p.sendStatus()
+			}
+		})
+	})
+	p.statusTimer = timer
+}
+
+// resetStatusThrottle makes the next status event go out immediately.
+func (p *Player) resetStatusThrottle() {
+	p.statusMutex.Lock()
+	defer p.statusMutex.Unlock()
+
+	if p.statusTimer != nil {
+		p.statusTimer.Stop()
+		p.statusTimer = nil
+	}
+	p.lastStatus = time.Time{}
+}
+
+// sendStatus reports the position, duration and volume. It's called by
+// EventLoop, which owns remoteState.
+func (p *Player) sendStatus() {
+	position, err := p.getPropertyInt64("playback-time")
+	if err != nil {
+		p.logger.Printf("mpv.sendStatus: GetProperty %s -- %s", "playback-time", err.Error())
+	}
+	// streams of unknown length, e.g. radio, have no duration, they're
+	// reported with 0 instead of logging an error on every update
+	duration, err := p.getPropertyInt64("duration")
+	if err != nil {
+		duration = 0
+	}
+	volume, err := p.userVolume()
+	if err != nil {
+		p.logger.Printf("mpv.sendStatus: GetProperty %s -- %s", "volume", err.Error())
+	} else {
+		p.remoteState.volume = volume
+	}
+
+	statusData := StatusData{
+		Volume:   int64(volume),
+		Position: position,
+		Duration: duration,
+	}
+	p.remoteState.timePos = float64(statusData.Position)
+	p.sendGuiDataEvent(EventStatus, statusData)
+	// also corrects drift of the other outputs
+	p.syncMirrors()
+}
+
+// sendTrackEnded reports the end of the loaded song, once per loaded song.
+func (p *Player) sendTrackEnded(completed bool, err error) {
+	if p.loadedItem.Id == "" {
+		return
+	}
+	data := TrackEndData{Item: p.loadedItem, Completed: completed, Err: err}
+	p.loadedItem = QueueItem{}
+	p.sendGuiDataEvent(EventTrackEnded, data)
+}
+
 func (p *Player) sendGuiEvent(typ UiEventType) {
 	if p.eventConsumer != nil {
 		p.eventConsumer.SendEvent(UiEvent{
@@ -9,8 +9,58 @@ import (
 	"github.com/supersonic-app/go-mpv"
 )
 
+// mpvInstance is the part of mpv.Mpv the player calls through
+// Player.instance. Tests stand in for mpv with it.
+type mpvInstance interface {
+	Command(command []string) error
+	SetProperty(name string, format mpv.Format, data interface{}) error
+	SetPropertyString(name, value string) error
+	GetProperty(name string, format mpv.Format) (interface{}, error)
+	ObserveProperty(reply uint64, name string, format mpv.Format) error
+	Wakeup()
+	TerminateDestroy()
+}
+
+// The mpv calls below hold instanceMutex, so restartMpv can't replace and
+// destroy the instance while another goroutine uses it.
+
+func (p *Player) command(args []string) error {
+	p.instanceMutex.RLock()
+	defer p.instanceMutex.RUnlock()
+
+	return p.instance.Command(args)
+}
+
+func (p *Player) setProperty(name string, format mpv.Format, value interface{}) error {
+	p.instanceMutex.RLock()
+	defer p.instanceMutex.RUnlock()
+
+	return p.instance.SetProperty(name, format, value)
+}
+
+func (p *Player) setPropertyString(name, value string) error {
+	p.instanceMutex.RLock()
+	defer p.instanceMutex.RUnlock()
+
+	return p.instance.SetPropertyString(name, value)
+}
+
+func (p *Player) getProperty(name string, format mpv.Format) (interface{}, error) {
+	p.instanceMutex.RLock()
+	defer p.instanceMutex.RUnlock()
+
+	return p.instance.GetProperty(name, format)
+}
+
+func (p *Player) observeProperty(reply uint64, name string, format mpv.Format) error {
+	p.instanceMutex.RLock()
+	defer p.instanceMutex.RUnlock()
+
+	return p.instance.ObserveProperty(reply, name, format)
+}
+
 func (p *Player) getPropertyInt64(name string) (int64, error) {
-	value, err := p.instance.GetProperty(name, mpv.FORMAT_INT64)
+	value, err := p.getProperty(name, mpv.FORMAT_INT64)
 	if err != nil {
 		return 0, err
 	} else if value == nil {
@@ -20,7 +70,7 @@ func (p *Player) getPropertyInt64(name string) (int64, error) {
 }
 
 func (p *Player) getPropertyBool(name string) (bool, error) {
-	value, err := p.instance.GetProperty(name, mpv.FORMAT_FLAG)
+	value, err := p.getProperty(name, mpv.FORMAT_FLAG)
 	if err != nil {
 		return false, err
 	} else if value == nil {
@@ -15,6 +15,14 @@ const (
 	EventPaused
 	// UI status update, data: StatusData
 	EventStatus
+	// playback paused/resumed for buffering, data: BufferingData
+	EventBuffering
+	// song ended or was interrupted, data: TrackEndData
+	EventTrackEnded
+	// mpv died and was restarted, data: number of restarts (int)
+	EventRestarted
+	// mpv died and couldn't be restarted, data: error
+	EventFailed
 )
 
 type UiEvent struct {
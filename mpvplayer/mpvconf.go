@@ -0,0 +1,147 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package mpvplayer
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spezifisch/stmps/logger"
+	"github.com/supersonic-app/go-mpv"
+)
+
+// MpvConfig holds user-supplied mpv settings applied when the player is
+// created.
+type MpvConfig struct {
+	// Options are set after the ones stmps needs and before ConfigFile, e.g.
+	// for presets like the low latency mode
+	Options [][2]string
+	// ConfigFile is an mpv.conf-style file. Its options are set after the
+	// ones stmps needs, so they take precedence.
+	ConfigFile string
+	// ScriptDir is a directory whose *.lua files are loaded as mpv scripts.
+	ScriptDir string
+}
+
+type mpvOption struct {
+	name  string
+	value string
+	line  int
+}
+
+// parseMpvConf reads options in mpv.conf syntax: one "name=value" or "name"
+// (a flag, set to yes) per line, "no-name" sets a flag to no. Values may be
+// quoted. Comments start with #. Profile sections aren't supported, the
+// options in them are skipped and returned separately.
+func parseMpvConf(r io.Reader) (options []mpvOption, skipped []mpvOption, err error) {
+	scanner := bufio.NewScanner(r)
+	inProfile := false
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			// options after [default] apply normally, others belong to a profile
+			inProfile = line != "[default]"
+			continue
+		}
+
+		option := parseMpvConfLine(line)
+		option.line = lineNumber
+		if inProfile {
+			skipped = append(skipped, option)
+		} else {
+			options = append(options, option)
+		}
+	}
+	return options, skipped, scanner.Err()
+}
+
+func parseMpvConfLine(line string) mpvOption {
+	line = strings.TrimPrefix(line, "--")
+
+	name, value, hasValue := strings.Cut(line, "=")
+	name = strings.TrimSpace(name)
+	value = strings.TrimSpace(value)
+
+	if !hasValue {
+		if flag, ok := strings.CutPrefix(name, "no-"); ok {
+			return mpvOption{name: flag, value: "no"}
+		}
+		return mpvOption{name: name, value: "yes"}
+	}
+
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return mpvOption{name: name, value: value[1 : end+1]}
+		}
+	}
+	// unquoted values end at a comment
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return mpvOption{name: name, value: value}
+}
+
+// applyMpvConfig sets config.Options and the options from config.ConfigFile.
+// Options mpv rejects are logged and skipped. Must be called before the instance is initialized.
+func applyMpvConfig(m *mpv.Mpv, config MpvConfig, logger logger.LoggerInterface) {
+	for _, option := range config.Options {
+		if err := m.SetOptionString(option[0], option[1]); err != nil {
+			logger.Printf("mpv rejected %s=%s: %v", option[0], option[1], err)
+		}
+	}
+	if config.ConfigFile == "" {
+		return
+	}
+
+	f, err := os.Open(config.ConfigFile)
+	if err != nil {
+		logger.PrintError("mpv config", err)
+		return
+	}
+	defer f.Close()
+
+	options, skipped, err := parseMpvConf(f)
+	if err != nil {
+		logger.PrintError("mpv config", err)
+	}
+	for _, option := range skipped {
+		logger.Printf("mpv config: %s:%d: profiles aren't supported, ignoring %s", config.ConfigFile, option.line, option.name)
+	}
+	for _, option := range options {
+		if err := m.SetOptionString(option.name, option.value); err != nil {
+			logger.Printf("mpv config: %s:%d: mpv rejected %s=%s: %v", config.ConfigFile, option.line, option.name, option.value, err)
+		}
+	}
+}
+
+// loadMpvScripts loads the Lua scripts in config.ScriptDir in alphabetical
+// order. Must be called after the instance is initialized.
+func loadMpvScripts(m *mpv.Mpv, config MpvConfig, logger logger.LoggerInterface) {
+	if config.ScriptDir == "" {
+		return
+	}
+
+	scripts, err := filepath.Glob(filepath.Join(config.ScriptDir, "*.lua"))
+	if err != nil {
+		logger.PrintError("mpv scripts", err)
+		return
+	}
+	sort.Strings(scripts)
+	for _, script := range scripts {
+		if err := m.Command([]string{"load-script", script}); err != nil {
+			logger.Printf("mpv scripts: loading %s failed: %v", script, err)
+		} else {
+			logger.Printf("mpv scripts: loaded %s", script)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package mpvplayer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMpvConf(t *testing.T) {
+	conf := `
+# resampling
+audio-samplerate=48000
+af="lavfi=[loudnorm]"
+--audio-exclusive
+no-audio-pitch-correction
+volume-max = 150 # allow boost
+
+[loud]
+volume=130
+`
+	options, skipped, err := parseMpvConf(strings.NewReader(conf))
+	assert.NoError(t, err)
+	assert.Equal(t, []mpvOption{
+		{name: "audio-samplerate", value: "48000", line: 3},
+		{name: "af", value: "lavfi=[loudnorm]", line: 4},
+		{name: "audio-exclusive", value: "yes", line: 5},
+		{name: "audio-pitch-correction", value: "no", line: 6},
+		{name: "volume-max", value: "150", line: 7},
+	}, options)
+	assert.Equal(t, []mpvOption{{name: "volume", value: "130", line: 10}}, skipped)
+}
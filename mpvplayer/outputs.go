@@ -0,0 +1,278 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package mpvplayer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/supersonic-app/go-mpv"
+)
+
+// ErrOutputsUnsupported is returned when mpv can't list the audio devices,
+// so outputs can't be chosen.
+var ErrOutputsUnsupported = errors.New("mpv doesn't list audio devices on this platform")
+
+// mirrors that drifted further than this from the player are seeked back in
+// line
+const mirrorDriftTolerance = 0.5
+
+// AudioDevice is an audio output that mpv can play through.
+type AudioDevice struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// AudioOutput is a device that stmps plays through.
+type AudioOutput struct {
+	// Device is the mpv audio device name, see AudioDevices
+	Device string
+	// Offset delays this output, to line it up with outputs that have a
+	// higher latency, e.g. Bluetooth speakers
+	Offset time.Duration
+}
+
+// mirrorOutput is another mpv instance that plays the same song as the
+// player through another device. syncMirrors keeps it in line.
+type mirrorOutput struct {
+	output   AudioOutput
+	instance *mpv.Mpv
+	// song the mirror plays, "" if stopped
+	uri string
+}
+
+// mirrors holds the outputs besides the player's own.
+type mirrors struct {
+	mutex   sync.Mutex
+	outputs []*mirrorOutput
+}
+
+// parseAudioDevices parses mpv's audio-device-list property.
+func parseAudioDevices(text string) ([]AudioDevice, error) {
+	var devices []AudioDevice
+	if err := json.Unmarshal([]byte(text), &devices); err != nil {
+		return nil, fmt.Errorf("audio-device-list: %v", err)
+	}
+	return devices, nil
+}
+
+// formatAudioDelay returns mpv's audio-delay value for an offset.
+func formatAudioDelay(offset time.Duration) string {
+	return strconv.FormatFloat(offset.Seconds(), 'f', 3, 64)
+}
+
+// AudioDevices returns the devices mpv can play through.
+func (p *Player) AudioDevices() ([]AudioDevice, error) {
+	value, err := p.getProperty("audio-device-list", mpv.FORMAT_STRING)
+	if err != nil {
+		return nil, err
+	}
+	text, ok := value.(string)
+	if !ok || text == "" {
+		return nil, ErrOutputsUnsupported
+	}
+	return parseAudioDevices(text)
+}
+
+// Outputs returns the outputs stmps plays through, the player's own first.
+// It's empty if the default device is used.
+func (p *Player) Outputs() []AudioOutput {
+	p.mirrors.mutex.Lock()
+	defer p.mirrors.mutex.Unlock()
+
+	if p.output.Device == "" {
+		return nil
+	}
+	outputs := []AudioOutput{p.output}
+	for _, mirror := range p.mirrors.outputs {
+		outputs = append(outputs, mirror.output)
+	}
+	return outputs
+}
+
+// SetOutputs plays through all outputs at once. The first one is the
+// player's own, each further one gets its own mpv instance that follows the
+// player. Empty outputs go back to the default device. If an output can't be
+// opened, the ones that could are kept and the error is returned.
+func (p *Player) SetOutputs(outputs []AudioOutput) error {
+	p.mirrors.mutex.Lock()
+	defer p.mirrors.mutex.Unlock()
+
+	for _, mirror := range p.mirrors.outputs {
+		mirror.instance.TerminateDestroy()
+	}
+	p.mirrors.outputs = nil
+
+	p.output = AudioOutput{}
+	if len(outputs) > 0 {
+		p.output = outputs[0]
+	}
+	if err := p.applyOutput(); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, output := range outputs[min(1, len(outputs)):] {
+		mirror, err := newMirrorOutput(output)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("output %s: %v", output.Device, err))
+			continue
+		}
+		p.mirrors.outputs = append(p.mirrors.outputs, mirror)
+	}
+	p.syncMirrorsLocked()
+	return errors.Join(errs...)
+}
+
+// applyOutput sets the device and offset of the player's own output in mpv.
+func (p *Player) applyOutput() error {
+	device := p.output.Device
+	if device == "" {
+		device = "auto"
+	}
+	if err := p.setPropertyString("audio-device", device); err != nil {
+		return fmt.Errorf("output %s: %v", device, err)
+	}
+	if err := p.setPropertyString("audio-delay", formatAudioDelay(p.output.Offset)); err != nil {
+		return fmt.Errorf("output %s: %v", device, err)
+	}
+	return nil
+}
+
+// newMirrorOutput starts an idle mpv instance for output.
+func newMirrorOutput(output AudioOutput) (*mirrorOutput, error) {
+	m := mpv.Create()
+	options := [][2]string{
+		{"audio-display", "no"},
+		{"video", "no"},
+		{"terminal", "no"},
+		{"idle", "yes"},
+		{"demuxer-max-bytes", "30MiB"},
+		{"audio-client-name", "stmp"},
+		{"audio-device", output.Device},
+		{"audio-delay", formatAudioDelay(output.Offset)},
+	}
+	for _, option := range options {
+		if err := m.SetOptionString(option[0], option[1]); err != nil {
+			m.TerminateDestroy()
+			return nil, fmt.Errorf("%s: %v", option[0], err)
+		}
+	}
+	if err := m.Initialize(); err != nil {
+		m.TerminateDestroy()
+		return nil, err
+	}
+
+	go func() {
+		for {
+			evt := m.WaitEvent(1)
+			if evt == nil || evt.Event_Id == mpv.EVENT_SHUTDOWN {
+				return
+			}
+			if evt.Event_Id == mpv.EVENT_FILE_LOADED {
+				// the start position of sync only applies to its song
+				_ = m.SetPropertyString("start", "none")
+			}
+		}
+	}()
+	return &mirrorOutput{output: output, instance: m}, nil
+}
+
+func (p *Player) closeMirrors() {
+	p.mirrors.mutex.Lock()
+	defer p.mirrors.mutex.Unlock()
+
+	for _, mirror := range p.mirrors.outputs {
+		mirror.instance.TerminateDestroy()
+	}
+	p.mirrors.outputs = nil
+}
+
+// syncMirrors makes the other outputs play the player's song at its
+// position, paused, volume and mute state. It's called after the player
+// changes and with every status update, which also corrects drift.
+func (p *Player) syncMirrors() {
+	p.mirrors.mutex.Lock()
+	defer p.mirrors.mutex.Unlock()
+
+	p.syncMirrorsLocked()
+}
+
+func (p *Player) syncMirrorsLocked() {
+	if len(p.mirrors.outputs) == 0 {
+		return
+	}
+
+	uri := ""
+	if loaded, err := p.IsSongLoaded(); err == nil && loaded && !p.stopped {
+		uri = p.loadedItem.Uri
+	}
+	paused, _ := p.IsPaused()
+	position, _ := getPropertyFloat64(p.getProperty, "time-pos")
+	volume, _ := p.getProperty("volume", mpv.FORMAT_INT64)
+	mute, _ := p.getProperty("mute", mpv.FORMAT_FLAG)
+
+	for _, mirror := range p.mirrors.outputs {
+		if err := mirror.sync(uri, paused, position); err != nil {
+			p.logger.PrintError("output "+mirror.output.Device, err)
+		}
+		if volume != nil {
+			_ = mirror.instance.SetProperty("volume", mpv.FORMAT_INT64, volume)
+		}
+		if mute != nil {
+			_ = mirror.instance.SetProperty("mute", mpv.FORMAT_FLAG, mute)
+		}
+	}
+}
+
+// sync makes the mirror play uri at position, or stop for an empty uri.
+func (m *mirrorOutput) sync(uri string, paused bool, position float64) error {
+	if uri == "" {
+		if m.uri == "" {
+			return nil
+		}
+		m.uri = ""
+		return m.instance.Command([]string{"stop"})
+	}
+
+	start := strconv.FormatFloat(position, 'f', 3, 64)
+	if uri != m.uri {
+		m.uri = uri
+		if err := m.instance.SetProperty("pause", mpv.FORMAT_FLAG, paused); err != nil {
+			return err
+		}
+		if err := m.instance.SetPropertyString("start", start); err != nil {
+			return err
+		}
+		return m.instance.Command([]string{"loadfile", uri})
+	}
+
+	if err := m.instance.SetProperty("pause", mpv.FORMAT_FLAG, paused); err != nil {
+		return err
+	}
+	mirrorPosition, err := getPropertyFloat64(m.instance.GetProperty, "time-pos")
+	if err != nil {
+		// still loading
+		return nil
+	}
+	if math.Abs(mirrorPosition-position) > mirrorDriftTolerance {
+		return m.instance.Command([]string{"seek", start, "absolute"})
+	}
+	return nil
+}
+
+func getPropertyFloat64(get func(string, mpv.Format) (interface{}, error), name string) (float64, error) {
+	value, err := get(name, mpv.FORMAT_DOUBLE)
+	if err != nil {
+		return 0, err
+	} else if value == nil {
+		return 0, errors.New("nil value")
+	}
+	return value.(float64), nil
+}
@@ -0,0 +1,25 @@
+package mpvplayer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAudioDevices(t *testing.T) {
+	devices, err := parseAudioDevices(`[{"name":"auto","description":"Autoselect device"},{"name":"pulse/alsa_output.usb","description":"USB Speakers"}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []AudioDevice{
+		{Name: "auto", Description: "Autoselect device"},
+		{Name: "pulse/alsa_output.usb", Description: "USB Speakers"},
+	}, devices)
+
+	_, err = parseAudioDevices("auto")
+	assert.Error(t, err)
+}
+
+func TestFormatAudioDelay(t *testing.T) {
+	assert.Equal(t, "0.000", formatAudioDelay(0))
+	assert.Equal(t, "0.180", formatAudioDelay(180*time.Millisecond))
+}
@@ -5,8 +5,10 @@ package mpvplayer
 
 import (
 	"errors"
+	"fmt"
 	"math/rand"
-	"strconv"
+	"sync"
+	"time"
 
 	"github.com/spezifisch/stmps/logger"
 	"github.com/spezifisch/stmps/remote"
@@ -15,19 +17,173 @@ import (
 
 type PlayerQueue []QueueItem
 
+// DefaultSkipDebounce is the default settle window for rapid skips, see
+// Player.SkipDebounce.
+const DefaultSkipDebounce = 300 * time.Millisecond
+
+// DefaultPreviousRestartThreshold is the default for
+// Player.PreviousRestartThreshold.
+const DefaultPreviousRestartThreshold = 3 * time.Second
+
+// DefaultStatusInterval is the default for Player.StatusInterval.
+const DefaultStatusInterval = 250 * time.Millisecond
+
+// ReplayGain modes, see Player.SetReplayGain.
+const (
+	ReplayGainOff   = "off"
+	ReplayGainTrack = "track"
+	ReplayGainAlbum = "album"
+)
+
 type Player struct {
-	instance      *mpv.Mpv
+	// replaced by restartMpv, use the mpv calls in helpers.go
+	instance      mpvInstance
+	instanceMutex sync.RWMutex
 	mpvEvents     chan *mpv.Event
 	eventConsumer EventConsumer
-	queue         PlayerQueue
-	logger        logger.LoggerInterface
+	// closed to stop mpvEngineEventHandler, which closes engineDone when it
+	// returns
+	engineStop chan struct{}
+	engineDone chan struct{}
+	queue      PlayerQueue
+	logger     logger.LoggerInterface
+	// mpv options and scripts, applied again when mpv is restarted
+	config MpvConfig
+	// work of timers and other goroutines run by EventLoop, see inEventLoop
+	loopCalls chan func()
+	// closed when EventLoop returns
+	loopDone chan struct{}
+
+	// MaxRestarts is how often mpv is restarted after it died, see
+	// EventLoop. Zero disables restarting.
+	MaxRestarts int
+	restarts    int
 
 	replaceInProgress bool
 	stopped           bool
+	// song mpv is playing, reported in EventTrackEnded
+	loadedItem QueueItem
+
+	// SkipDebounce is the settle window for consecutive skips. A skip that
+	// follows the previous one within this window doesn't load the track
+	// right away, only the track the user lands on is loaded once the window
+	// has passed without further skips. Zero disables debouncing.
+	SkipDebounce time.Duration
+
+	skipMutex sync.Mutex
+	lastSkip  time.Time
+	skipTimer *time.Timer
+
+	// TrimSilence enables removing silence at track boundaries, see
+	// silenceFilter. Changes apply from the next track on.
+	TrimSilence bool
+	// SilenceThreshold is the level in dB below which audio counts as silence.
+	SilenceThreshold float64
+	// SilenceDuration is how long trailing silence must be to get trimmed.
+	SilenceDuration time.Duration
+
+	// VoiceBoost compresses the dynamic range of every song to keep quiet
+	// speech audible, see voiceFilter. Use SetVoiceBoost once EventLoop
+	// runs.
+	VoiceBoost bool
+	// VoiceBoostSpokenWord turns the voice boost on for podcasts and
+	// audiobooks.
+	VoiceBoostSpokenWord bool
+	// settings of the voice boost compressor, see DefaultVoiceBoost
+	VoiceCompressor VoiceCompressor
+
+	// our audio filters currently set in mpv, in chain order
+	audioFilters []string
+	// guards audioFilters and VoiceBoost, songs are loaded by the GUI too
+	filterMutex sync.Mutex
+
+	// Gapless makes the next song follow the current one without a gap, see
+	// updatePreload.
+	Gapless bool
+	// GaplessWithinAlbumOnly limits gapless transitions to consecutive tracks
+	// of the same album. Other songs follow after a normal transition.
+	GaplessWithinAlbumOnly bool
+
+	// URI of the next song appended to mpv's playlist, "" if none
+	preloadedUri string
+	// gapless flag of the loaded song's tags, known once it's loaded
+	gaplessHint gaplessHint
+
+	// SeekWrapsTracks makes seeking past the end of a track go to the next
+	// one, and seeking back from its first seconds go to the previous one.
+	// Otherwise seeks are clamped to the current track.
+	SeekWrapsTracks bool
+	// PreviousRestartThreshold is how long a song has to play before
+	// PreviousTrack restarts it instead of going to the previous song. 0
+	// always goes to the previous song.
+	PreviousRestartThreshold time.Duration
+	// SeekExact or SeekKeyframe, see SetSeekMode
+	seekMode string
+	// PauseDuringSeek pauses playback while mpv seeks and resumes it once
+	// playback restarts, which avoids glitches on some transcoded streams
+	PauseDuringSeek bool
+	// set while playback is paused for a seek, see pauseForSeek
+	seekPaused bool
+	seekMutex  sync.Mutex
+
+	// songs that were removed from the queue after playing, oldest first
+	played []QueueItem
+	// MaxQueueLength limits the queue and the played songs together. When
+	// it's exceeded, the oldest played songs are dropped, but
+	// minPlayedHistory of them are kept. Songs aren't added to a queue that
+	// is MaxQueueLength long. Zero disables the limit, the history still
+	// holds up to playedHistorySize songs.
+	MaxQueueLength int
+	// played songs dropped for MaxQueueLength
+	evicted int
+
+	// StatusInterval is the minimum time between two status events (playback
+	// position, duration, volume). Other events are sent immediately.
+	StatusInterval time.Duration
+
+	statusMutex sync.Mutex
+	lastStatus  time.Time
+	statusTimer *time.Timer
+
+	// StallTimeout is how long buffering may take before StallAction is
+	// taken. Zero disables this.
+	StallTimeout time.Duration
+	// StallAction is StallActionRetry or StallActionSkip.
+	StallAction string
+
+	bufferingState   BufferingData
+	stallMutex       sync.Mutex
+	stallTimer       *time.Timer
+	resetStartOption bool
+
+	// FadeIn is how long the volume ramps up when playback starts or
+	// resumes, FadeOut how long it ramps down before pausing or stopping.
+	// Zero disables the fade. Fades ramp toward the user's volume, see
+	// fadeState.
+	FadeIn  time.Duration
+	FadeOut time.Duration
+
+	fade fadeState
+
+	// the player's own audio output, see SetOutputs
+	output  AudioOutput
+	mirrors mirrors
+
+	// settings that live in mpv, restored after a restart
+	replayGain string
+	muted      bool
+
+	// gain of the loaded song and the volume it started at, see
+	// applyTrackGain
+	gainMutex   sync.Mutex
+	trackGain   float64
+	startVolume int
 
 	// player state
 	remoteState struct {
 		timePos float64
+		// last volume the user had, -1 until it's known
+		volume int
 	}
 
 	// callbacks
@@ -41,7 +197,54 @@ type Player struct {
 var _ remote.ControlledPlayer = (*Player)(nil)
 
 func NewPlayer(logger logger.LoggerInterface) (player *Player, err error) {
-	m := mpv.Create()
+	return NewPlayerWithConfig(logger, MpvConfig{})
+}
+
+// NewPlayerWithConfig creates a player with the user's mpv options and
+// scripts. Problems with them are logged, they don't make this fail.
+func NewPlayerWithConfig(logger logger.LoggerInterface, config MpvConfig) (player *Player, err error) {
+	m, err := newMpv(logger, config)
+	if err != nil {
+		return
+	}
+
+	player = &Player{
+		instance:          m,
+		mpvEvents:         make(chan *mpv.Event),
+		engineStop:        make(chan struct{}),
+		engineDone:        make(chan struct{}),
+		loopCalls:         make(chan func(), loopCallsSize),
+		loopDone:          make(chan struct{}),
+		eventConsumer:     nil, // must be set by calling RegisterEventConsumer()
+		queue:             make([]QueueItem, 0),
+		logger:            logger,
+		config:            config,
+		MaxRestarts:       DefaultMaxRestarts,
+		replaceInProgress: false,
+		stopped:           true,
+		SkipDebounce:      DefaultSkipDebounce,
+		SeekWrapsTracks:   true,
+
+		PreviousRestartThreshold: DefaultPreviousRestartThreshold,
+		seekMode:                 SeekExact,
+		SilenceThreshold:         DefaultSilenceThreshold,
+		SilenceDuration:          DefaultSilenceDuration,
+		VoiceBoostSpokenWord:     true,
+		VoiceCompressor:          DefaultVoiceCompressor,
+		StatusInterval:           DefaultStatusInterval,
+		StallAction:              StallActionRetry,
+	}
+	player.remoteState.volume = -1
+	player.startVolume = -1
+
+	go player.mpvEngineEventHandler(m, player.engineStop, player.engineDone)
+	return
+}
+
+// newMpv creates and initializes an mpv instance with the user's options and
+// scripts.
+func newMpv(logger logger.LoggerInterface, config MpvConfig) (m *mpv.Mpv, err error) {
+	m = mpv.Create()
 
 	// cargo-cult what supersonic does
 	if err = m.SetOptionString("audio-display", "no"); err != nil {
@@ -59,34 +262,57 @@ func NewPlayer(logger logger.LoggerInterface) (player *Player, err error) {
 	if err = m.SetOptionString("audio-client-name", "stmp"); err != nil {
 		return
 	}
+	applyMpvConfig(m, config, logger)
 
 	if err = m.Initialize(); err != nil {
 		return
 	}
-
-	player = &Player{
-		instance:          m,
-		mpvEvents:         make(chan *mpv.Event),
-		eventConsumer:     nil, // must be set by calling RegisterEventConsumer()
-		queue:             make([]QueueItem, 0),
-		logger:            logger,
-		replaceInProgress: false,
-		stopped:           true,
-	}
-
-	go player.mpvEngineEventHandler(m)
+	loadMpvScripts(m, config, logger)
 	return
 }
 
-func (p *Player) mpvEngineEventHandler(instance *mpv.Mpv) {
+// mpvEngineEventHandler passes the events of instance to EventLoop until stop
+// is closed or mpv shut down. done is closed when it returns.
+func (p *Player) mpvEngineEventHandler(instance *mpv.Mpv, stop, done chan struct{}) {
+	defer close(done)
+	defer func() {
+		if r := recover(); r != nil {
+			// EventLoop restarts mpv as if it died
+			p.logger.Printf("mpv: event handler panicked: %v", r)
+			select {
+			case p.mpvEvents <- &mpv.Event{Event_Id: mpv.EVENT_SHUTDOWN}:
+			case <-stop:
+			}
+		}
+	}()
 	for {
 		evt := instance.WaitEvent(1)
-		p.mpvEvents <- evt
+		select {
+		case p.mpvEvents <- evt:
+			if evt.Event_Id == mpv.EVENT_SHUTDOWN {
+				// the instance is dead, it only has to be destroyed
+				return
+			}
+		case <-stop:
+			return
+		}
 	}
 }
 
+// Quit stops the event handlers and shuts mpv down. The mpv handle is only
+// destroyed once nothing waits for its events anymore.
 func (p *Player) Quit() {
+	p.resetStatusThrottle()
+	p.stopStallTimer()
 	p.mpvEvents <- nil
+	p.closeMirrors()
+
+	p.instanceMutex.Lock()
+	defer p.instanceMutex.Unlock()
+
+	close(p.engineStop)
+	p.instance.Wakeup()
+	<-p.engineDone
 	p.instance.TerminateDestroy()
 }
 
@@ -97,18 +323,19 @@ func (p *Player) RegisterEventConsumer(consumer EventConsumer) {
 func (p *Player) PlayNextTrack() error {
 	if len(p.queue) >= 1 {
 		// advance queue if any tracks left
+		p.rememberPlayed()
 		p.queue = p.queue[1:]
 
 		if len(p.queue) > 0 {
 			// replace currently playing song with next song
 			if loaded, err := p.IsSongLoaded(); err != nil {
 				p.logger.PrintError("PlayNextTrack", err)
-			} else if loaded {
+			} else if loaded || p.isLoadPending() {
 				p.replaceInProgress = true
 				if err := p.temporaryStop(); err != nil {
 					p.logger.PrintError("temporaryStop", err)
 				}
-				return p.instance.Command([]string{"loadfile", p.queue[0].Uri})
+				return p.debouncedLoadQueueHead()
 			}
 		} else {
 			// stop with empty queue
@@ -125,25 +352,106 @@ func (p *Player) PlayNextTrack() error {
 	return nil
 }
 
-func (p *Player) PlayUri(id, uri, title, artist, album string, duration, track, disc int, coverArtId string) error {
-	p.queue = []QueueItem{{id, uri, title, artist, duration, album, track, coverArtId, disc}}
+// debouncedLoadQueueHead loads the first queue item, unless the previous skip
+// happened less than SkipDebounce ago. In that case loading is deferred until
+// the skips have settled, so that only the final track gets streamed. The
+// deferred load is done by EventLoop.
+func (p *Player) debouncedLoadQueueHead() error {
+	p.skipMutex.Lock()
+	defer p.skipMutex.Unlock()
+
+	now := time.Now()
+	rapid := p.SkipDebounce > 0 && now.Sub(p.lastSkip) < p.SkipDebounce
+	p.lastSkip = now
+
+	if p.skipTimer != nil {
+		p.skipTimer.Stop()
+		p.skipTimer = nil
+	}
+
+	if !rapid {
+		// single skips stay instant
+		return p.loadFile(p.queue[0].Uri, false)
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(p.SkipDebounce, func() {
+		p.inEventLoop(func() {
+			p.skipMutex.Lock()
+			pending := p.skipTimer == timer
+			if pending {
+				p.skipTimer = nil
+			}
+			p.skipMutex.Unlock()
+
+			// cancelled or replaced by another skip meanwhile
+			if !pending || len(p.queue) == 0 {
+				return
+			}
+			if err := p.loadFile(p.queue[0].Uri, false); err != nil {
+				p.logger.PrintError("debounced loadfile", err)
+			}
+		})
+	})
+	p.skipTimer = timer
+	return nil
+}
+
+// isLoadPending reflects whether a deferred load is waiting for skips to settle.
+func (p *Player) isLoadPending() bool {
+	p.skipMutex.Lock()
+	defer p.skipMutex.Unlock()
+
+	return p.skipTimer != nil
+}
+
+// cancelDebouncedLoad drops a pending deferred load, e.g. because the queue
+// was cleared.
+func (p *Player) cancelDebouncedLoad() {
+	p.skipMutex.Lock()
+	defer p.skipMutex.Unlock()
+
+	if p.skipTimer != nil {
+		p.skipTimer.Stop()
+		p.skipTimer = nil
+	}
+}
+
+// SkipToQueueItem drops the queue items before index and starts playing the
+// item at index, replacing the current song.
+func (p *Player) SkipToQueueItem(index int) error {
+	if index < 0 || index >= len(p.queue) { // TODO mutex queue access
+		return fmt.Errorf("SkipToQueueItem bad index %d (len %d)", index, len(p.queue))
+	}
+
+	p.cancelDebouncedLoad()
+	p.queue = p.queue[index:]
 	p.replaceInProgress = true
 	if ip, e := p.IsPaused(); ip && e == nil {
 		if err := p.Pause(); err != nil {
 			p.logger.PrintError("Pause", err)
 		}
 	}
-	return p.instance.Command([]string{"loadfile", uri})
+	return p.loadFile(p.queue[0].Uri, false)
 }
 
 func (p *Player) Stop() error {
 	p.logger.Printf("stopping (user)")
+	p.cancelDebouncedLoad()
+	p.stopStallTimer()
+	p.keepSeekPause()
 	p.stopped = true
-	return p.instance.Command([]string{"stop"})
+	// stop also clears mpv's playlist
+	p.preloadedUri = ""
+	err := p.command([]string{"stop"})
+	p.cancelFade()
+	p.syncMirrors()
+	return err
 }
 
 func (p *Player) temporaryStop() error {
-	return p.instance.Command([]string{"stop"})
+	p.preloadedUri = ""
+	return p.command([]string{"stop"})
 }
 
 func (p *Player) IsSongLoaded() (bool, error) {
@@ -175,6 +483,25 @@ func (p *Player) Test() {
 // If stopped, the song starts playing.
 // The state after the toggle is returned, or an error.
 func (p *Player) Pause() (err error) {
+	defer p.syncMirrors()
+
+	if p.keepSeekPause() {
+		// paused for a seek, stay paused once it's done
+		if len(p.queue) > 0 {
+			p.sendGuiDataEvent(EventPaused, p.queue[0])
+		}
+		return
+	}
+
+	if p.isFadingOut() {
+		// pressed again while fading out, keep playing
+		p.startFade(1, p.FadeIn, nil)
+		if len(p.queue) > 0 {
+			p.sendGuiDataEvent(EventUnpaused, p.queue[0])
+		}
+		return
+	}
+
 	loaded, err := p.IsSongLoaded()
 	if err != nil {
 		return
@@ -185,17 +512,36 @@ func (p *Player) Pause() (err error) {
 	}
 
 	if loaded && !p.stopped {
+		currentSong := QueueItem{}
+		if len(p.queue) > 0 {
+			currentSong = p.queue[0]
+		}
+
+		if !paused && p.FadeOut > 0 {
+			// pause once faded out
+			p.startFade(0, p.FadeOut, func() {
+				if err := p.setProperty("pause", mpv.FORMAT_FLAG, true); err != nil {
+					p.logger.PrintError("fade: pause", err)
+				}
+				p.cancelFade()
+				p.syncMirrors()
+			})
+			p.sendGuiDataEvent(EventPaused, currentSong)
+			return
+		}
+		if paused && p.FadeIn > 0 {
+			p.prepareFadeIn()
+		}
+
 		// toggle pause if not stopped
-		err = p.instance.Command([]string{"cycle", "pause"})
+		err = p.command([]string{"cycle", "pause"})
 		if err != nil {
 			p.logger.PrintError("cycle pause", err)
 			return
 		}
 		paused = !paused
-
-		currentSong := QueueItem{}
-		if len(p.queue) > 0 {
-			currentSong = p.queue[0]
+		if !paused && p.FadeIn > 0 {
+			p.startFade(1, p.FadeIn, nil)
 		}
 
 		if paused {
@@ -206,7 +552,14 @@ func (p *Player) Pause() (err error) {
 	} else {
 		if len(p.queue) > 0 {
 			currentSong := p.queue[0]
-			err = p.instance.Command([]string{"loadfile", currentSong.Uri})
+			if p.FadeIn > 0 {
+				// fades in once the file is loaded, see fadeInLoaded
+				p.prepareFadeIn()
+				p.fade.mutex.Lock()
+				p.fade.pendingIn = true
+				p.fade.mutex.Unlock()
+			}
+			err = p.loadFile(currentSong.Uri, false)
 			if err != nil {
 				p.logger.PrintError("loadfile", err)
 				return
@@ -214,7 +567,7 @@ func (p *Player) Pause() (err error) {
 
 			if p.stopped {
 				p.stopped = false
-				if err = p.instance.SetProperty("pause", mpv.FORMAT_FLAG, false); err != nil {
+				if err = p.setProperty("pause", mpv.FORMAT_FLAG, false); err != nil {
 					p.logger.PrintError("setprop pause", err)
 				}
 
@@ -239,20 +592,66 @@ func (p *Player) SetVolume(percentValue int) error {
 		percentValue = 0
 	}
 
-	return p.instance.SetProperty("volume", mpv.FORMAT_INT64, percentValue)
+	return p.setUserVolume(percentValue)
+}
+
+// GetVolume returns the volume in percent. During a fade this is the volume
+// the fade ramps toward or returns to.
+func (p *Player) GetVolume() (int, error) {
+	return p.userVolume()
+}
+
+// MpvVersion returns the version of libmpv, e.g. "mpv 0.38.0".
+func (p *Player) MpvVersion() (string, error) {
+	value, err := p.getProperty("mpv-version", mpv.FORMAT_STRING)
+	if err != nil {
+		return "", err
+	}
+	version, ok := value.(string)
+	if !ok {
+		return "", errors.New("no mpv version")
+	}
+	return version, nil
+}
+
+// SetReplayGain sets the ReplayGain mode, one of ReplayGainOff,
+// ReplayGainTrack or ReplayGainAlbum.
+func (p *Player) SetReplayGain(mode string) error {
+	p.replayGain = mode
+	if mode == ReplayGainOff {
+		mode = "no"
+	}
+	return p.setPropertyString("replaygain", mode)
+}
+
+// SetMute mutes or unmutes mpv without changing the volume.
+func (p *Player) SetMute(mute bool) error {
+	p.muted = mute
+	value := "no"
+	if mute {
+		value = "yes"
+	}
+	defer p.syncMirrors()
+	return p.setPropertyString("mute", value)
 }
 
 func (p *Player) AdjustVolume(increment int) error {
-	volume, err := p.getPropertyInt64("volume")
+	volume, err := p.userVolume()
 	if err != nil {
 		return err
 	}
 
-	return p.SetVolume(int(volume) + increment)
+	return p.SetVolume(volume + increment)
 }
 
+// Seek moves the playback position by increment seconds, see
+// SeekWrapsTracks for seeks beyond the current track.
 func (p *Player) Seek(increment int) error {
-	return p.instance.Command([]string{"seek", strconv.Itoa(increment)})
+	position, err := p.getPropertyInt64("playback-time")
+	if err != nil {
+		return err
+	}
+	return p.seekTo(int(position) + increment)
 }
 
 // accessed from gui context
@@ -274,14 +673,75 @@ func (p *Player) DeleteQueueItem(index int) {
 			}
 		} else {
 			p.queue = append(p.queue[:index], p.queue[index+1:]...)
+			p.updatePreload()
 		}
 	} else {
 		p.ClearQueue()
 	}
 }
 
-func (p *Player) AddToQueue(item *QueueItem) {
+// AddToQueue appends an item to the queue. It returns false if the queue is
+// full, see MaxQueueLength.
+func (p *Player) AddToQueue(item *QueueItem) bool {
+	if p.queueFull() {
+		return false
+	}
 	p.queue = append(p.queue, *item)
+	p.evictPlayed()
+	p.updatePreload()
+	return true
+}
+
+// InsertIntoQueue inserts an item before the given queue index. Indices past
+// the end of the queue append the item. It returns false if the queue is
+// full, see MaxQueueLength.
+func (p *Player) InsertIntoQueue(index int, item *QueueItem) bool {
+	// TODO mutex queue access
+	if p.queueFull() {
+		return false
+	}
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(p.queue) {
+		p.queue = append(p.queue, *item)
+	} else {
+		p.queue = append(p.queue[:index+1], p.queue[index:]...)
+		p.queue[index] = *item
+	}
+	p.evictPlayed()
+	p.updatePreload()
+	return true
+}
+
+// queueFull returns true if no more songs may be added, see MaxQueueLength.
+func (p *Player) queueFull() bool {
+	return p.MaxQueueLength > 0 && len(p.queue) >= p.MaxQueueLength // TODO mutex queue access
+}
+
+// QueueIndex returns the index of the first queue item with the given ID, or
+// -1 if it isn't queued.
+func (p *Player) QueueIndex(id string) int {
+	for i := range p.queue { // TODO mutex queue access
+		if p.queue[i].Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// UpdateQueueItems calls update for every queue item with the given ID and
+// returns how many there were. The song keeps playing, update mustn't change
+// the ID or URI.
+func (p *Player) UpdateQueueItems(id string, update func(item *QueueItem)) int {
+	updated := 0
+	for i := range p.queue { // TODO mutex queue access
+		if p.queue[i].Id == id {
+			update(&p.queue[i])
+			updated++
+		}
+	}
+	return updated
 }
 
 func (p *Player) MoveSongUp(index int) {
@@ -294,6 +754,7 @@ func (p *Player) MoveSongUp(index int) {
 		return
 	}
 	p.queue[index-1], p.queue[index] = p.queue[index], p.queue[index-1]
+	p.updatePreload()
 }
 
 func (p *Player) MoveSongDown(index int) {
@@ -306,6 +767,7 @@ func (p *Player) MoveSongDown(index int) {
 		return
 	}
 	p.queue[index], p.queue[index+1] = p.queue[index+1], p.queue[index]
+	p.updatePreload()
 }
 
 func (p *Player) Shuffle() {
@@ -315,6 +777,21 @@ func (p *Player) Shuffle() {
 		rb := rand.Intn(max)
 		p.queue[ra], p.queue[rb] = p.queue[rb], p.queue[ra]
 	}
+	p.updatePreload()
+}
+
+// ShuffleRemaining shuffles the songs after the current one, which keeps
+// playing. It returns how many songs were shuffled.
+func (p *Player) ShuffleRemaining() int {
+	if len(p.queue) < 2 {
+		return 0
+	}
+	remaining := p.queue[1:]
+	rand.Shuffle(len(remaining), func(i, j int) {
+		remaining[i], remaining[j] = remaining[j], remaining[i]
+	})
+	p.updatePreload()
+	return len(remaining)
 }
 
 func (p *Player) GetQueueItem(index int) (QueueItem, error) {
@@ -377,10 +854,14 @@ func (p *Player) IsSeeking() (bool, error) {
 }
 
 func (p *Player) SeekAbsolute(position int) error {
-	return p.instance.Command([]string{"seek", strconv.Itoa(position), "absolute"})
+	return p.seekTo(position)
 }
 
 func (p *Player) Play() error {
+	if p.isSeekPaused() {
+		// resumes once the seek is done
+		return nil
+	}
 	if isPlaying, err := p.IsPlaying(); err != nil {
 		return err
 	} else if !isPlaying {
@@ -389,13 +870,12 @@ func (p *Player) Play() error {
 	return nil
 }
 
-func (p *Player) NextTrack() error {
-	return p.PlayNextTrack()
+// TogglePlayPause pauses a playing song and resumes otherwise. Pause
+// already toggles, fades and pauses for seeks included.
+func (p *Player) TogglePlayPause() error {
+	return p.Pause()
 }
 
-func (p *Player) PreviousTrack() (err error) {
-	if err = p.Stop(); err != nil {
-		return
-	}
-	return p.Pause()
+func (p *Player) NextTrack() error {
+	return p.PlayNextTrack()
 }
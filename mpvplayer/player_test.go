@@ -0,0 +1,145 @@
+package mpvplayer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/supersonic-app/go-mpv"
+)
+
+// fakeMpv stands in for mpv, it keeps the properties set and cycles pause.
+type fakeMpv struct {
+	properties map[string]interface{}
+	commands   []string
+}
+
+func (m *fakeMpv) Command(command []string) error {
+	m.commands = append(m.commands, strings.Join(command, " "))
+	if strings.Join(command, " ") == "cycle pause" {
+		m.properties["pause"] = !m.properties["pause"].(bool)
+	}
+	return nil
+}
+
+func (m *fakeMpv) SetProperty(name string, format mpv.Format, data interface{}) error {
+	m.properties[name] = data
+	return nil
+}
+
+func (m *fakeMpv) SetPropertyString(name, value string) error {
+	m.properties[name] = value
+	return nil
+}
+
+func (m *fakeMpv) GetProperty(name string, format mpv.Format) (interface{}, error) {
+	return m.properties[name], nil
+}
+
+func (m *fakeMpv) ObserveProperty(reply uint64, name string, format mpv.Format) error {
+	return nil
+}
+
+func (m *fakeMpv) Wakeup() {}
+
+func (m *fakeMpv) TerminateDestroy() {}
+
+func TestShuffleRemaining(t *testing.T) {
+	p := &Player{}
+	assert.Equal(t, 0, p.ShuffleRemaining())
+
+	ids := []string{"current", "a", "b", "c", "d", "e"}
+	for _, id := range ids {
+		p.queue = append(p.queue, QueueItem{Id: id})
+	}
+	assert.Equal(t, 5, p.ShuffleRemaining())
+
+	// the current song stays first, the others are all still there
+	assert.Equal(t, "current", p.queue[0].Id)
+	var shuffled []string
+	for _, item := range p.queue {
+		shuffled = append(shuffled, item.Id)
+	}
+	assert.ElementsMatch(t, ids, shuffled)
+}
+
+func TestDebouncedLoadInEventLoop(t *testing.T) {
+	p := &Player{
+		SkipDebounce: time.Millisecond,
+		loopCalls:    make(chan func(), 1),
+		loopDone:     make(chan struct{}),
+		queue:        PlayerQueue{{Id: "1"}},
+		lastSkip:     time.Now(),
+	}
+
+	assert.NoError(t, p.debouncedLoadQueueHead())
+	assert.True(t, p.isLoadPending())
+
+	// the timer leaves the load to EventLoop
+	var call func()
+	select {
+	case call = <-p.loopCalls:
+	case <-time.After(time.Second):
+		t.Fatal("debounced load not passed to EventLoop")
+	}
+	assert.True(t, p.isLoadPending())
+
+	// cancelled before EventLoop got to it, nothing is loaded
+	p.cancelDebouncedLoad()
+	call()
+	assert.False(t, p.isLoadPending())
+}
+
+func TestDeferredStatusInEventLoop(t *testing.T) {
+	p := &Player{
+		StatusInterval: time.Millisecond,
+		loopCalls:      make(chan func(), 1),
+		loopDone:       make(chan struct{}),
+		lastStatus:     time.Now(),
+	}
+
+	p.throttledSendStatus()
+
+	// the timer leaves the status to EventLoop
+	var call func()
+	select {
+	case call = <-p.loopCalls:
+	case <-time.After(time.Second):
+		t.Fatal("deferred status not passed to EventLoop")
+	}
+
+	// sent right away before EventLoop got to it, nothing is sent twice
+	p.resetStatusThrottle()
+	call()
+	assert.True(t, p.lastStatus.IsZero())
+}
+
+func TestTogglePlayPause(t *testing.T) {
+	instance := &fakeMpv{properties: map[string]interface{}{
+		"idle-active": false,
+		"pause":       false,
+	}}
+	p := &Player{
+		instance: instance,
+		queue:    PlayerQueue{{Id: "1"}},
+	}
+	events := &recordedEvents{}
+	p.RegisterEventConsumer(events)
+
+	assert.NoError(t, p.TogglePlayPause())
+	playing, err := p.IsPlaying()
+	assert.NoError(t, err)
+	assert.False(t, playing)
+
+	assert.NoError(t, p.TogglePlayPause())
+	playing, err = p.IsPlaying()
+	assert.NoError(t, err)
+	assert.True(t, playing)
+
+	assert.Equal(t, []string{"cycle pause", "cycle pause"}, instance.commands)
+	assert.Equal(t, []UiEvent{
+		{Type: EventPaused, Data: QueueItem{Id: "1"}},
+		{Type: EventUnpaused, Data: QueueItem{Id: "1"}},
+	}, events.events)
+}
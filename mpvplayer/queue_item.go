@@ -4,24 +4,47 @@
 package mpvplayer
 
 import (
+	"time"
+
 	"github.com/spezifisch/stmps/remote"
 )
 
 type QueueItem struct {
-	Id          string
-	Uri         string
-	Title       string
-	Artist      string
+	Id     string
+	Uri    string
+	Title  string
+	Artist string
+	// empty if the server doesn't report it
+	AlbumArtist string
 	Duration    int
 	Album       string
+	AlbumId     string
+	ArtistId    string
 	TrackNumber int
 	CoverArtId  string
 	DiscNumber  int
+	Year        int
+	Genres      []string
+	// comment tag, empty if the server doesn't report it
+	Comment string
+	// where the song was queued from, e.g. "playlist:Name"
+	Source string
+	// media type reported by the server, e.g. "music" or TypePodcast
+	Type string
+	// when the song was last played, zero if never or the server doesn't
+	// report it
+	Played time.Time
+	// gain in dB applied on top of the volume while the song plays, see
+	// Player.SetTrackGain
+	TrackGain float64
 }
 
 var _ remote.TrackInterface = (*QueueItem)(nil)
 
 func (q QueueItem) GetAlbumArtist() string {
+	if q.AlbumArtist != "" {
+		return q.AlbumArtist
+	}
 	return q.Artist
 }
 
@@ -60,3 +83,7 @@ func (q QueueItem) GetTrackNumber() int {
 func (q QueueItem) GetDiscNumber() int {
 	return q.DiscNumber
 }
+
+func (q QueueItem) GetYear() int {
+	return q.Year
+}
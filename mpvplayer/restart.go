@@ -0,0 +1,115 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package mpvplayer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/supersonic-app/go-mpv"
+)
+
+// DefaultMaxRestarts is the default for Player.MaxRestarts.
+const DefaultMaxRestarts = 3
+
+var errMpvShutdown = errors.New("mpv shut down")
+
+// restartMpv replaces the mpv instance after it died or its events couldn't
+// be handled, cause says why. The player's settings are applied to the new
+// instance and the current song continues where it was. Once MaxRestarts is
+// reached the player gives up, the error says so.
+func (p *Player) restartMpv(cause error) error {
+	if p.restarts >= p.MaxRestarts {
+		return fmt.Errorf("mpv failed, giving up after %d restarts: %v", p.restarts, cause)
+	}
+
+	// what the old instance still knows, a dead one doesn't answer
+	paused, err := p.IsPaused()
+	if err != nil {
+		paused = false
+	}
+	volume, err := p.userVolume()
+	if err != nil {
+		volume = p.remoteState.volume
+	}
+	position := int(p.remoteState.timePos)
+
+	instance, err := newMpv(p.logger, p.config)
+	if err != nil {
+		return fmt.Errorf("mpv failed, restarting it failed too: %v", err)
+	}
+	p.restarts++
+	p.logger.Printf("mpv: restarting (%d of %d), %v", p.restarts, p.MaxRestarts, cause)
+
+	p.replaceInstance(instance)
+
+	p.restoreSettings(volume)
+	if !p.stopped && len(p.queue) > 0 { // TODO mutex queue access
+		if err := p.PlayFrom(position, paused); err != nil {
+			p.logger.PrintError("mpv: resume", err)
+		}
+	}
+	p.sendGuiDataEvent(EventRestarted, p.restarts)
+	return nil
+}
+
+// replaceInstance makes instance the player's mpv and destroys the old one.
+// Other goroutines' mpv calls wait until it's done.
+func (p *Player) replaceInstance(instance *mpv.Mpv) {
+	p.instanceMutex.Lock()
+	defer p.instanceMutex.Unlock()
+
+	old, oldStop, oldDone := p.instance, p.engineStop, p.engineDone
+	p.instance = instance
+	p.engineStop = make(chan struct{})
+	p.engineDone = make(chan struct{})
+	go p.mpvEngineEventHandler(instance, p.engineStop, p.engineDone)
+
+	close(oldStop)
+	old.Wakeup()
+	<-oldDone
+	old.TerminateDestroy()
+}
+
+// restoreSettings applies the player's settings to a new mpv instance and
+// forgets the state of the old one. volume is the user's volume, -1 if it's
+// unknown.
+func (p *Player) restoreSettings(volume int) {
+	p.cancelDebouncedLoad()
+	p.stopStallTimer()
+	p.keepSeekPause()
+	p.cancelFade()
+	p.preloadedUri = ""
+	p.filterMutex.Lock()
+	p.audioFilters = nil
+	p.filterMutex.Unlock()
+	p.loadedItem = QueueItem{}
+
+	if volume >= 0 {
+		if err := p.setUserVolume(volume); err != nil {
+			p.logger.PrintError("mpv: restore volume", err)
+		}
+	}
+	if p.muted {
+		if err := p.SetMute(true); err != nil {
+			p.logger.PrintError("mpv: restore mute", err)
+		}
+	}
+	if p.replayGain != "" {
+		if err := p.SetReplayGain(p.replayGain); err != nil {
+			p.logger.PrintError("mpv: restore replaygain", err)
+		}
+	}
+	if gain := p.TrackGain(); gain != 0 {
+		if err := p.SetTrackGain(gain); err != nil {
+			p.logger.PrintError("mpv: restore track gain", err)
+		}
+	}
+	if err := p.SetSeekMode(p.seekMode); err != nil {
+		p.logger.PrintError("mpv: restore seek mode", err)
+	}
+	if err := p.applyOutput(); err != nil {
+		p.logger.PrintError("mpv: restore output", err)
+	}
+}
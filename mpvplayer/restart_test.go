@@ -0,0 +1,70 @@
+package mpvplayer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spezifisch/stmps/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedEvents struct {
+	events []UiEvent
+}
+
+func (r *recordedEvents) SendEvent(event UiEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestRestartMpv(t *testing.T) {
+	p, err := NewPlayer(logger.Init())
+	if !assert.NoError(t, err) {
+		return
+	}
+	// stands in for EventLoop, which restarts mpv and handles its events
+	go func() {
+		for range p.mpvEvents {
+		}
+	}()
+	defer p.Quit()
+	events := &recordedEvents{}
+	p.RegisterEventConsumer(events)
+	oldDone := p.engineDone
+
+	// mpv calls of other goroutines wait for the new instance
+	calls := make(chan struct{})
+	go func() {
+		defer close(calls)
+		for range 100 {
+			_, _ = p.IsPaused()
+		}
+	}()
+	assert.NoError(t, p.restartMpv(errMpvShutdown))
+	<-calls
+
+	assert.Equal(t, 1, p.restarts)
+	// the old instance's events aren't handled anymore
+	select {
+	case <-oldDone:
+	default:
+		t.Error("old event handler still running")
+	}
+	if assert.Len(t, events.events, 1) {
+		assert.Equal(t, UiEvent{Type: EventRestarted, Data: 1}, events.events[0])
+	}
+}
+
+func TestRestartMpvGivesUp(t *testing.T) {
+	p := &Player{MaxRestarts: 2, restarts: 2}
+	err := p.restartMpv(errors.New("event handling panicked"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "giving up after 2 restarts")
+		assert.Contains(t, err.Error(), "event handling panicked")
+	}
+	assert.Equal(t, 2, p.restarts)
+
+	// restarting disabled
+	p = &Player{}
+	assert.Error(t, p.restartMpv(errMpvShutdown))
+	assert.Equal(t, 0, p.restarts)
+}
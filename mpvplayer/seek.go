@@ -0,0 +1,323 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package mpvplayer
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/supersonic-app/go-mpv"
+)
+
+// seeking back this close to the start of a track goes to the previous one,
+// see Player.SeekWrapsTracks
+const seekPreviousThreshold = 3
+
+// number of played songs remembered for seeking back into them
+const playedHistorySize = 50
+
+// played songs kept when evicting for Player.MaxQueueLength
+const minPlayedHistory = 5
+
+// How seeks are done, see Player.SetSeekMode.
+const (
+	// SeekExact lands on the requested position, which can take a while on
+	// transcoded streams
+	SeekExact = "exact"
+	// SeekKeyframe jumps to the closest keyframe, which is fast but may be
+	// off by a few seconds
+	SeekKeyframe = "keyframe"
+)
+
+// seekCommand returns the mpv command that seeks to target seconds.
+func seekCommand(target int, mode string) []string {
+	flags := "absolute+exact"
+	if mode == SeekKeyframe {
+		flags = "absolute+keyframes"
+	}
+	return []string{"seek", strconv.Itoa(target), flags}
+}
+
+// SetSeekMode sets how Seek and SeekAbsolute seek, SeekExact or
+// SeekKeyframe. mpv's hr-seek option follows it, so seeks that mpv does on
+// its own, e.g. when resuming at a position, are done the same way.
+func (p *Player) SetSeekMode(mode string) error {
+	hrSeek := "yes"
+	if mode == SeekKeyframe {
+		hrSeek = "no"
+	}
+	if err := p.setPropertyString("hr-seek", hrSeek); err != nil {
+		return err
+	}
+	p.seekMode = mode
+	return nil
+}
+
+// SeekMode returns how seeks are done, see SetSeekMode.
+func (p *Player) SeekMode() string {
+	return p.seekMode
+}
+
+// seekTarget decides what a seek to target seconds does in a track of the
+// given duration. It returns the position to seek to, or a track change:
+// +1 for the next track, -1 for the previous one. position is where the seek
+// started. duration 0 means unknown and doesn't limit forward seeks.
+func seekTarget(position, target, duration int, wrap bool) (int, int) {
+	if duration > 0 && target >= duration {
+		if wrap {
+			return 0, 1
+		}
+		// stay just before the end, so the track isn't over
+		return duration - 1, 0
+	}
+	if target < 0 {
+		if wrap && position < seekPreviousThreshold {
+			return 0, -1
+		}
+		return 0, 0
+	}
+	return target, 0
+}
+
+func (p *Player) seekTo(target int) error {
+	position, err := p.getPropertyInt64("playback-time")
+	if err != nil {
+		return err
+	}
+	duration, err := p.getPropertyInt64("duration")
+	if err != nil {
+		// streams may not report a duration yet
+		duration = 0
+	}
+
+	target, change := seekTarget(int(position), target, int(duration), p.SeekWrapsTracks)
+	switch change {
+	case 1:
+		// with a single song in the queue this stops, like the song ending
+		return p.PlayNextTrack()
+	case -1:
+		return p.playPreviousTrack()
+	}
+	if !p.PauseDuringSeek || !p.pauseForSeek() {
+		// otherwise resumeAfterSeek syncs the mirrors once it plays again,
+		// so they don't pause for the seek
+		defer p.syncMirrors()
+	}
+	if err := p.command(seekCommand(target, p.seekMode)); err != nil {
+		// there's no playback restart to wait for
+		p.resumeAfterSeek()
+		return err
+	}
+	return nil
+}
+
+// pauseForSeek pauses playback until the seek is done, see resumeAfterSeek.
+// If it's paused already, e.g. by the user, it's left alone. It returns
+// whether playback is paused for a seek.
+func (p *Player) pauseForSeek() bool {
+	p.seekMutex.Lock()
+	defer p.seekMutex.Unlock()
+
+	if p.seekPaused {
+		// a previous seek isn't done yet
+		return true
+	}
+	paused, err := p.IsPaused()
+	if err != nil || paused {
+		return false
+	}
+	if err := p.setProperty("pause", mpv.FORMAT_FLAG, true); err != nil {
+		p.logger.PrintError("pauseForSeek", err)
+		return false
+	}
+	p.seekPaused = true
+	return true
+}
+
+// resumeAfterSeek resumes playback that was paused for a seek.
+func (p *Player) resumeAfterSeek() {
+	p.seekMutex.Lock()
+	defer p.seekMutex.Unlock()
+
+	if !p.seekPaused {
+		return
+	}
+	p.seekPaused = false
+	if err := p.setProperty("pause", mpv.FORMAT_FLAG, false); err != nil {
+		p.logger.PrintError("resumeAfterSeek", err)
+	}
+	p.syncMirrors()
+}
+
+func (p *Player) isSeekPaused() bool {
+	p.seekMutex.Lock()
+	defer p.seekMutex.Unlock()
+
+	return p.seekPaused
+}
+
+// keepSeekPause makes playback that was paused for a seek stay paused, e.g.
+// because the user paused meanwhile. It returns whether it was paused for a
+// seek.
+func (p *Player) keepSeekPause() bool {
+	p.seekMutex.Lock()
+	defer p.seekMutex.Unlock()
+
+	paused := p.seekPaused
+	p.seekPaused = false
+	return paused
+}
+
+// rememberPlayed adds the current song to the played history before it's
+// removed from the queue.
+func (p *Player) rememberPlayed() {
+	if len(p.queue) == 0 { // TODO mutex queue access
+		return
+	}
+	p.played = append(p.played, p.queue[0])
+	if len(p.played) > playedHistorySize {
+		p.played = p.played[len(p.played)-playedHistorySize:]
+	}
+	p.evictPlayed()
+}
+
+// evictPlayed drops the oldest played songs while the queue and the played
+// songs exceed MaxQueueLength. Songs that are still to come are never
+// dropped, AddToQueue and InsertIntoQueue refuse new ones instead.
+func (p *Player) evictPlayed() {
+	if p.MaxQueueLength <= 0 {
+		return
+	}
+	excess := len(p.queue) + len(p.played) - p.MaxQueueLength // TODO mutex queue access
+	n := min(excess, len(p.played)-minPlayedHistory)
+	if n <= 0 {
+		return
+	}
+	// copied, so the dropped songs can be freed
+	p.played = append([]QueueItem(nil), p.played[n:]...)
+	p.evicted += n
+}
+
+// Evicted returns the number of played songs dropped for MaxQueueLength.
+func (p *Player) Evicted() int {
+	return p.evicted
+}
+
+// playPreviousTrack puts the last played song back in front of the queue and
+// plays it. Without played songs the current one restarts.
+func (p *Player) playPreviousTrack() error {
+	if len(p.played) == 0 {
+		return p.command([]string{"seek", "0", "absolute"})
+	}
+
+	p.restoreLastPlayed()
+	p.cancelDebouncedLoad()
+	p.replaceInProgress = true
+	return p.loadFile(p.queue[0].Uri, false)
+}
+
+// PreviousTrack restarts the current song once it has played for longer
+// than PreviousRestartThreshold, so a second press goes to the previous
+// song. Near the start it plays the previous song right away. Stopped, the
+// previous song is played.
+func (p *Player) PreviousTrack() error {
+	loaded, err := p.IsSongLoaded()
+	if err != nil {
+		return err
+	}
+	if loaded && !p.stopped {
+		position, err := p.getPropertyInt64("playback-time")
+		if err != nil {
+			return err
+		}
+		if p.PreviousRestarts(float64(position)) {
+			return p.Restart()
+		}
+		return p.playPreviousTrack()
+	}
+
+	p.RestorePrevious()
+	return p.Restart()
+}
+
+// PreviousRestarts reports whether PreviousTrack restarts a song that has
+// played for position seconds rather than going to the previous one.
+func (p *Player) PreviousRestarts(position float64) bool {
+	return p.PreviousRestartThreshold > 0 && position*float64(time.Second) > float64(p.PreviousRestartThreshold)
+}
+
+// RestorePrevious moves the last played song back to the front of the queue
+// without playing it, e.g. while casting. It returns false if there's none.
+func (p *Player) RestorePrevious() bool {
+	if len(p.played) == 0 {
+		return false
+	}
+	p.restoreLastPlayed()
+	return true
+}
+
+// restoreLastPlayed moves the last played song back to the front of the
+// queue.
+func (p *Player) restoreLastPlayed() {
+	previous := p.played[len(p.played)-1]
+	p.played = p.played[:len(p.played)-1]
+	p.queue = append(PlayerQueue{previous}, p.queue...) // TODO mutex queue access
+}
+
+// PlayFrom loads the first song of the queue and starts it at position
+// seconds, e.g. to resume where the last session stopped. With paused it's
+// loaded paused.
+func (p *Player) PlayFrom(position int, paused bool) error {
+	if len(p.queue) == 0 { // TODO mutex queue access
+		return nil
+	}
+	if position > 0 {
+		if err := p.setPropertyString("start", fmt.Sprintf("+%d", position)); err != nil {
+			return err
+		}
+		// reset once loaded, see EVENT_FILE_LOADED
+		p.resetStartOption = true
+	}
+	if err := p.setProperty("pause", mpv.FORMAT_FLAG, paused); err != nil {
+		return err
+	}
+	p.cancelDebouncedLoad()
+	// a song that's still loaded ends without advancing the queue
+	p.replaceInProgress = true
+	p.stopped = false
+	return p.loadFile(p.queue[0].Uri, false)
+}
+
+// Restart plays the current song again from its start. Streams that can't
+// seek are reloaded. If the queue has run out, the song that just ended is
+// put back and played again.
+func (p *Player) Restart() error {
+	loaded, err := p.IsSongLoaded()
+	if err != nil {
+		return err
+	}
+
+	if loaded && !p.stopped {
+		if seekable, err := p.getPropertyBool("seekable"); err != nil || seekable || len(p.queue) == 0 {
+			return p.seekTo(0)
+		}
+		p.cancelDebouncedLoad()
+		p.replaceInProgress = true
+		return p.loadFile(p.queue[0].Uri, false)
+	}
+
+	if len(p.queue) == 0 {
+		if len(p.played) == 0 {
+			return nil
+		}
+		p.restoreLastPlayed()
+	}
+	p.cancelDebouncedLoad()
+	if err := p.loadFile(p.queue[0].Uri, false); err != nil {
+		return err
+	}
+	p.stopped = false
+	return p.setProperty("pause", mpv.FORMAT_FLAG, false)
+}
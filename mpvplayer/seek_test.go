@@ -0,0 +1,109 @@
+package mpvplayer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeekCommand(t *testing.T) {
+	assert.Equal(t, []string{"seek", "42", "absolute+exact"}, seekCommand(42, SeekExact))
+	assert.Equal(t, []string{"seek", "42", "absolute+keyframes"}, seekCommand(42, SeekKeyframe))
+}
+
+func TestKeepSeekPause(t *testing.T) {
+	p := &Player{}
+	assert.False(t, p.keepSeekPause())
+
+	p.seekPaused = true
+	assert.True(t, p.isSeekPaused())
+	// kept paused, nothing is resumed after the seek
+	assert.True(t, p.keepSeekPause())
+	assert.False(t, p.isSeekPaused())
+	p.resumeAfterSeek()
+	assert.False(t, p.isSeekPaused())
+}
+
+func TestRestoreLastPlayed(t *testing.T) {
+	p := &Player{
+		queue:  PlayerQueue{{Id: "3"}},
+		played: []QueueItem{{Id: "1"}, {Id: "2"}},
+	}
+	p.restoreLastPlayed()
+	assert.Equal(t, PlayerQueue{{Id: "2"}, {Id: "3"}}, p.queue)
+	assert.Equal(t, []QueueItem{{Id: "1"}}, p.played)
+}
+
+func TestEvictPlayed(t *testing.T) {
+	p := &Player{}
+	for i := 0; i < 10; i++ {
+		p.played = append(p.played, QueueItem{Id: string(rune('a' + i))})
+	}
+	p.queue = PlayerQueue{{Id: "x"}, {Id: "y"}}
+
+	// unlimited
+	p.evictPlayed()
+	assert.Len(t, p.played, 10)
+
+	p.MaxQueueLength = 8
+	p.evictPlayed()
+	assert.Equal(t, 4, p.Evicted())
+	assert.Len(t, p.played, 6)
+	assert.Equal(t, "e", p.played[0].Id)
+
+	// a small history is kept, songs to come stay
+	p.MaxQueueLength = 3
+	p.evictPlayed()
+	assert.Equal(t, 5, p.Evicted())
+	assert.Len(t, p.played, minPlayedHistory)
+	assert.Len(t, p.queue, 2)
+}
+
+func TestPreviousRestarts(t *testing.T) {
+	p := &Player{PreviousRestartThreshold: DefaultPreviousRestartThreshold}
+	assert.False(t, p.PreviousRestarts(0))
+	assert.False(t, p.PreviousRestarts(3))
+	assert.True(t, p.PreviousRestarts(3.5))
+
+	// always goes back
+	p.PreviousRestartThreshold = 0
+	assert.False(t, p.PreviousRestarts(120))
+}
+
+func TestRestorePrevious(t *testing.T) {
+	p := &Player{queue: PlayerQueue{{Id: "2"}}}
+	assert.False(t, p.RestorePrevious())
+	assert.Equal(t, PlayerQueue{{Id: "2"}}, p.queue)
+
+	p.played = []QueueItem{{Id: "1"}}
+	assert.True(t, p.RestorePrevious())
+	assert.Equal(t, PlayerQueue{{Id: "1"}, {Id: "2"}}, p.queue)
+}
+
+func TestQueueFull(t *testing.T) {
+	p := &Player{MaxQueueLength: 2}
+
+	assert.True(t, p.AddToQueue(&QueueItem{Id: "1"}))
+	assert.True(t, p.InsertIntoQueue(0, &QueueItem{Id: "2"}))
+	assert.False(t, p.AddToQueue(&QueueItem{Id: "3"}))
+	assert.False(t, p.InsertIntoQueue(1, &QueueItem{Id: "4"}))
+	assert.Equal(t, PlayerQueue{{Id: "2"}, {Id: "1"}}, p.queue)
+}
+
+func TestPauseForSeek(t *testing.T) {
+	instance := &fakeMpv{properties: map[string]interface{}{"pause": false}}
+	p := &Player{instance: instance}
+
+	assert.True(t, p.pauseForSeek())
+	assert.Equal(t, true, instance.properties["pause"])
+	// still paused for the previous seek
+	assert.True(t, p.pauseForSeek())
+
+	p.resumeAfterSeek()
+	assert.Equal(t, false, instance.properties["pause"])
+
+	// paused by the user, nothing to resume after the seek
+	instance.properties["pause"] = true
+	assert.False(t, p.pauseForSeek())
+	assert.False(t, p.isSeekPaused())
+}
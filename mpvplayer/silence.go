@@ -0,0 +1,132 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package mpvplayer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Defaults for Player.SilenceThreshold and Player.SilenceDuration.
+const (
+	DefaultSilenceThreshold = -60.0
+	DefaultSilenceDuration  = 2 * time.Second
+)
+
+// label of our entry in mpv's audio filter chain
+const silenceFilterLabel = "@stmps-silence"
+
+// silenceFilter returns the audio filter used for the next track, or "" if
+// nothing should be trimmed.
+//
+// Trailing silence is only removed if it's longer than SilenceDuration. Since
+// mpv filters work on the stream, this applies to long silences anywhere in a
+// track. Leading silence is only removed if the track is started by a natural
+// transition from the previous one, so that a track chosen by the user always
+// starts at the beginning.
+func (p *Player) silenceFilter(transition bool) string {
+	if !p.TrimSilence {
+		return ""
+	}
+
+	threshold := fmt.Sprintf("%gdB", p.SilenceThreshold)
+	params := []string{
+		"stop_periods=-1",
+		fmt.Sprintf("stop_duration=%g", p.SilenceDuration.Seconds()),
+		"stop_threshold=" + threshold,
+	}
+	if transition {
+		params = append(params,
+			"start_periods=1",
+			"start_threshold="+threshold)
+	}
+	return fmt.Sprintf("%s:lavfi=[silenceremove=%s]", silenceFilterLabel, strings.Join(params, ":"))
+}
+
+// setAudioFilters puts our filters into mpv's audio filter chain in the
+// given order, empty ones are left out. Only our labeled filters are touched,
+// filters from the user's mpv config stay in place. Call with filterMutex
+// held.
+func (p *Player) setAudioFilters(filters ...string) {
+	var wanted []string
+	for _, filter := range filters {
+		if filter != "" {
+			wanted = append(wanted, filter)
+		}
+	}
+	if slices.Equal(wanted, p.audioFilters) {
+		return
+	}
+
+	// removed and added again to keep the order
+	for _, filter := range p.audioFilters {
+		if err := p.command([]string{"af", "remove", filterLabel(filter)}); err != nil {
+			p.logger.PrintError("remove audio filter", err)
+		}
+	}
+	p.audioFilters = nil
+	for _, filter := range wanted {
+		if err := p.command([]string{"af", "add", filter}); err != nil {
+			p.logger.PrintError("add audio filter", err)
+		} else {
+			p.audioFilters = append(p.audioFilters, filter)
+		}
+	}
+}
+
+// currentFilter returns our filter with the label that's set in mpv, "" if
+// there's none. Call with filterMutex held.
+func (p *Player) currentFilter(label string) string {
+	for _, filter := range p.audioFilters {
+		if filterLabel(filter) == label {
+			return filter
+		}
+	}
+	return ""
+}
+
+func filterLabel(filter string) string {
+	label, _, _ := strings.Cut(filter, ":")
+	return label
+}
+
+// updateFilters sets the filters for the song mpv started, which may have
+// been preloaded and started without loadFile, see updatePreload. Leading
+// silence isn't trimmed on such gapless transitions: that needs the filter
+// chain to start over, which would put the gap back in. Turning trimming on
+// or off still applies.
+func (p *Player) updateFilters() {
+	p.filterMutex.Lock()
+	defer p.filterMutex.Unlock()
+
+	silence := ""
+	if p.TrimSilence {
+		silence = p.currentFilter(silenceFilterLabel)
+		if silence == "" {
+			silence = p.silenceFilter(false)
+		}
+	}
+	p.setAudioFilters(silence, p.voiceFilter(p.loadedItem))
+}
+
+// loadFile starts playing uri. transition is set when the track follows the
+// previous one without user interaction.
+func (p *Player) loadFile(uri string, transition bool) error {
+	item := QueueItem{Uri: uri}
+	if len(p.queue) > 0 && p.queue[0].Uri == uri {
+		item = p.queue[0]
+	}
+	p.filterMutex.Lock()
+	p.setAudioFilters(p.silenceFilter(transition), p.voiceFilter(item))
+	p.filterMutex.Unlock()
+	// a new song cancels a pause or stop that waits for its fade out
+	if p.isFadingOut() {
+		p.cancelFade()
+	}
+	// replacing the current file also clears mpv's playlist
+	p.preloadedUri = ""
+	return p.command([]string{"loadfile", uri})
+}
@@ -0,0 +1,33 @@
+package mpvplayer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateFiltersOfPreloadedSong(t *testing.T) {
+	instance := &fakeMpv{properties: map[string]interface{}{}}
+	p := &Player{
+		instance:         instance,
+		TrimSilence:      true,
+		SilenceThreshold: DefaultSilenceThreshold,
+		SilenceDuration:  DefaultSilenceDuration,
+	}
+
+	// started by the user, the filter is kept for the gapless transition
+	// so the audio doesn't restart
+	assert.NoError(t, p.loadFile("song1", false))
+	instance.commands = nil
+	p.updateFilters()
+	assert.Empty(t, instance.commands)
+	assert.Equal(t, []string{p.silenceFilter(false)}, p.audioFilters)
+
+	// toggled off and on again, the preloaded song only trims trailing silence
+	p.TrimSilence = false
+	p.updateFilters()
+	assert.Empty(t, p.audioFilters)
+	p.TrimSilence = true
+	p.updateFilters()
+	assert.Equal(t, []string{p.silenceFilter(false)}, p.audioFilters)
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package mpvplayer
+
+import "github.com/supersonic-app/go-mpv"
+
+// SetTrackGain sets a gain in dB on top of the volume and ReplayGain, e.g.
+// a remembered offset of the current song. It needs mpv 0.36 or newer.
+func (p *Player) SetTrackGain(db float64) error {
+	p.gainMutex.Lock()
+	p.trackGain = db
+	p.gainMutex.Unlock()
+
+	return p.setProperty("volume-gain", mpv.FORMAT_DOUBLE, db)
+}
+
+// TrackGain returns the gain in dB set with SetTrackGain.
+func (p *Player) TrackGain() float64 {
+	p.gainMutex.Lock()
+	defer p.gainMutex.Unlock()
+
+	return p.trackGain
+}
+
+// StartVolume returns the user's volume when the loaded song started, -1 if
+// it's unknown.
+func (p *Player) StartVolume() int {
+	p.gainMutex.Lock()
+	defer p.gainMutex.Unlock()
+
+	return p.startVolume
+}
+
+// applyTrackGain sets the gain of a song that starts, paused or not, so the
+// previous song's gain doesn't carry over, and notes the volume it starts at.
+func (p *Player) applyTrackGain(item QueueItem) {
+	volume, err := p.userVolume()
+	if err != nil {
+		volume = -1
+	}
+	p.gainMutex.Lock()
+	p.startVolume = volume
+	changed := item.TrackGain != p.trackGain
+	p.gainMutex.Unlock()
+
+	if !changed {
+		return
+	}
+	if err := p.SetTrackGain(item.TrackGain); err != nil {
+		p.logger.PrintError("applyTrackGain", err)
+	}
+}
@@ -0,0 +1,21 @@
+package mpvplayer
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyTrackGain(t *testing.T) {
+	p, err := NewPlayer(logger.Init())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	p.applyTrackGain(QueueItem{Id: "1", TrackGain: -6})
+	assert.Equal(t, -6.0, p.TrackGain())
+	// the next song doesn't keep the gain, also when it starts paused
+	p.applyTrackGain(QueueItem{Id: "2"})
+	assert.Equal(t, 0.0, p.TrackGain())
+}
@@ -9,3 +9,19 @@ type StatusData struct {
 	Position int64
 	Duration int64
 }
+
+// TrackEndData reports how a song stopped playing. Completed is set if it
+// reached its end by itself, and unset if it was skipped, replaced or stopped.
+// Err is set if the song couldn't be played, e.g. because the stream failed.
+type TrackEndData struct {
+	Item      QueueItem
+	Completed bool
+	Err       error
+}
+
+// BufferingData reports whether playback is paused to fill the cache
+type BufferingData struct {
+	Buffering bool
+	Percent   int64
+	Item      QueueItem
+}
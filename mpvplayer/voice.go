@@ -0,0 +1,87 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package mpvplayer
+
+import "fmt"
+
+// Media types of spoken-word songs, see QueueItem.Type.
+const (
+	TypePodcast   = "podcast"
+	TypeAudiobook = "audiobook"
+)
+
+// label of the voice boost in mpv's audio filter chain
+const voiceFilterLabel = "@stmps-voice"
+
+// VoiceCompressor are the settings of ffmpeg's acompressor used for the
+// voice boost. Levels are in dB.
+type VoiceCompressor struct {
+	// level above which the signal is compressed
+	Threshold float64
+	// how much it's compressed, e.g. 4 for 4:1
+	Ratio float64
+	// gain applied after compression to make quiet parts louder
+	Makeup float64
+}
+
+// DefaultVoiceCompressor is the default for Player.VoiceCompressor.
+var DefaultVoiceCompressor = VoiceCompressor{Threshold: -24, Ratio: 4, Makeup: 8}
+
+// IsSpokenWord reports whether the song is a podcast episode or audiobook.
+func (q QueueItem) IsSpokenWord() bool {
+	return q.Type == TypePodcast || q.Type == TypeAudiobook
+}
+
+// VoiceBoostFor reports whether the voice boost is applied to the song.
+func (p *Player) VoiceBoostFor(item QueueItem) bool {
+	p.filterMutex.Lock()
+	defer p.filterMutex.Unlock()
+
+	return p.voiceBoostFor(item)
+}
+
+// Call with filterMutex held.
+func (p *Player) voiceBoostFor(item QueueItem) bool {
+	return p.VoiceBoost || (p.VoiceBoostSpokenWord && item.IsSpokenWord())
+}
+
+// voiceFilter returns the audio filter of the voice boost for the song, or ""
+// if it's off. ReplayGain and the volume are applied by mpv after the
+// filters, so they still work as usual. Call with filterMutex held.
+func (p *Player) voiceFilter(item QueueItem) string {
+	if !p.voiceBoostFor(item) {
+		return ""
+	}
+	c := p.VoiceCompressor
+	return fmt.Sprintf("%s:lavfi=[acompressor=threshold=%gdB:ratio=%g:attack=20:release=250:makeup=%gdB]",
+		voiceFilterLabel, c.Threshold, c.Ratio, c.Makeup)
+}
+
+// SetVoiceBoost turns the voice boost for all songs on or off. Unlike
+// silence trimming it applies to the current song right away, EventLoop
+// changes the filter since it owns the loaded song.
+func (p *Player) SetVoiceBoost(on bool) {
+	p.filterMutex.Lock()
+	p.VoiceBoost = on
+	p.filterMutex.Unlock()
+
+	p.inEventLoop(p.updateVoiceFilter)
+}
+
+// VoiceBoostOn reports whether the voice boost is on for all songs.
+func (p *Player) VoiceBoostOn() bool {
+	p.filterMutex.Lock()
+	defer p.filterMutex.Unlock()
+
+	return p.VoiceBoost
+}
+
+// updateVoiceFilter sets the voice boost for the loaded song, keeping the
+// silence filter.
+func (p *Player) updateVoiceFilter() {
+	p.filterMutex.Lock()
+	defer p.filterMutex.Unlock()
+
+	p.setAudioFilters(p.currentFilter(silenceFilterLabel), p.voiceFilter(p.loadedItem))
+}
@@ -0,0 +1,47 @@
+package mpvplayer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVoiceFilter(t *testing.T) {
+	p := &Player{VoiceBoostSpokenWord: true, VoiceCompressor: DefaultVoiceCompressor}
+	song := QueueItem{Id: "1", Type: "music"}
+	episode := QueueItem{Id: "2", Type: TypePodcast}
+
+	assert.Equal(t, "", p.voiceFilter(song))
+	filter := p.voiceFilter(episode)
+	assert.Equal(t, "@stmps-voice:lavfi=[acompressor=threshold=-24dB:ratio=4:attack=20:release=250:makeup=8dB]", filter)
+	assert.Equal(t, voiceFilterLabel, filterLabel(filter))
+
+	// on for everything
+	p.VoiceBoost = true
+	assert.NotEqual(t, "", p.voiceFilter(song))
+
+	p.VoiceBoost = false
+	p.VoiceBoostSpokenWord = false
+	assert.Equal(t, "", p.voiceFilter(QueueItem{Type: TypeAudiobook}))
+}
+
+func TestSetVoiceBoost(t *testing.T) {
+	instance := &fakeMpv{properties: map[string]interface{}{}}
+	p := &Player{
+		instance:        instance,
+		VoiceCompressor: DefaultVoiceCompressor,
+		loopCalls:       make(chan func(), 1),
+		loopDone:        make(chan struct{}),
+		loadedItem:      QueueItem{Id: "1"},
+	}
+
+	p.SetVoiceBoost(true)
+	assert.True(t, p.VoiceBoostOn())
+	assert.Empty(t, instance.commands)
+
+	// EventLoop changes the filter of the loaded song
+	(<-p.loopCalls)()
+	filter := p.voiceFilter(p.loadedItem)
+	assert.Equal(t, []string{"af add " + filter}, instance.commands)
+	assert.Equal(t, []string{filter}, p.audioFilters)
+}
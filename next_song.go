@@ -0,0 +1,45 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/mpvplayer"
+)
+
+// nextQueueItem returns the song that plays after the current one, nil at
+// the end of the queue. The head of the queue is the current song and is
+// removed when it ends; shuffling reorders the queue itself, so the queue
+// order is the play order. stmps has no repeat modes and stops at the end of
+// the queue.
+func nextQueueItem(queue mpvplayer.PlayerQueue) *mpvplayer.QueueItem {
+	if len(queue) < 2 {
+		return nil
+	}
+	return &queue[1]
+}
+
+// formatNextSong returns the "next: Artist - Title" preview for the top bar.
+func formatNextSong(next *mpvplayer.QueueItem, display ArtistDisplay) string {
+	if next == nil {
+		return ""
+	}
+	text := "[gray]next:[-] "
+	if artist := display.Artist(next.Artist, next.AlbumArtist); artist != "" {
+		text += tview.Escape(artist) + " - "
+	}
+	return text + tview.Escape(next.Title)
+}
+
+// updateNextSong shows the upcoming song in the top bar, and hides the
+// preview if there's none. Must be called from the gui goroutine.
+func (ui *Ui) updateNextSong(queue mpvplayer.PlayerQueue) {
+	text := formatNextSong(nextQueueItem(queue), ui.artistDisplay)
+	ui.nextSongStatus.SetText(text)
+	if text == "" {
+		ui.topBarFlex.ResizeItem(ui.nextSongStatus, 0, 0)
+	} else {
+		ui.topBarFlex.ResizeItem(ui.nextSongStatus, 0, 1)
+	}
+}
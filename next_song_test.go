@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextQueueItem(t *testing.T) {
+	assert.Nil(t, nextQueueItem(nil))
+	assert.Nil(t, nextQueueItem(mpvplayer.PlayerQueue{{Id: "1"}}))
+
+	queue := mpvplayer.PlayerQueue{{Id: "1"}, {Id: "2"}, {Id: "3"}}
+	next := nextQueueItem(queue)
+	if assert.NotNil(t, next) {
+		assert.Equal(t, "2", next.Id)
+	}
+}
+
+func TestFormatNextSong(t *testing.T) {
+	assert.Equal(t, "", formatNextSong(nil, ArtistDisplayTrack))
+
+	next := &mpvplayer.QueueItem{Title: "Song [live]", Artist: "Band", AlbumArtist: "Various Artists"}
+	assert.Equal(t, "[gray]next:[-] Band - Song [live[]", formatNextSong(next, ArtistDisplayTrack))
+	assert.Equal(t, "[gray]next:[-] Various Artists - Song [live[]", formatNextSong(next, ArtistDisplayAlbum))
+
+	assert.Equal(t, "[gray]next:[-] Untitled", formatNextSong(&mpvplayer.QueueItem{Title: "Untitled"}, ArtistDisplayTrack))
+}
@@ -0,0 +1,75 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// number of notifications kept, older ones are dropped
+const maxNotifications = 200
+
+type severity int
+
+const (
+	// notices shown in the status bar
+	severityInfo severity = iota
+	// errors printed to the log
+	severityError
+)
+
+// notification is an entry of the notifications panel.
+type notification struct {
+	at       time.Time
+	severity severity
+	text     string
+}
+
+// format returns the notification as a line of the panel, colored by
+// severity.
+func (n notification) format() string {
+	label, color := "info ", "yellow"
+	if n.severity == severityError {
+		label, color = "error", "red"
+	}
+	return "[gray]" + n.at.Local().Format("15:04:05") + "[-] [" + color + "::b]" + label + "[-::-] " + tview.Escape(n.text)
+}
+
+// notificationLog keeps the most recent notifications, oldest first.
+type notificationLog struct {
+	entries []notification
+	size    int
+}
+
+func newNotificationLog(size int) *notificationLog {
+	return &notificationLog{size: size}
+}
+
+func (l *notificationLog) add(n notification) {
+	l.entries = append(l.entries, n)
+	if len(l.entries) > l.size {
+		l.entries = l.entries[len(l.entries)-l.size:]
+	}
+}
+
+// text returns all notifications, one per line.
+func (l *notificationLog) text() string {
+	lines := make([]string, len(l.entries))
+	for i, n := range l.entries {
+		lines[i] = n.format()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// notify adds a notification and updates the panel if it's shown. Must be
+// called from the gui goroutine.
+func (ui *Ui) notify(level severity, text string) {
+	ui.notifications.add(notification{at: time.Now(), severity: level, text: text})
+	if ui.notificationsWidget != nil && ui.notificationsWidget.visible {
+		ui.notificationsWidget.update()
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationLogCap(t *testing.T) {
+	log := newNotificationLog(3)
+	for i := 1; i <= 5; i++ {
+		log.add(notification{text: fmt.Sprintf("notice %d", i)})
+	}
+	assert.Len(t, log.entries, 3)
+	assert.Equal(t, "notice 3", log.entries[0].text)
+	assert.Equal(t, "notice 5", log.entries[2].text)
+	assert.Len(t, strings.Split(log.text(), "\n"), 3)
+}
+
+func TestNotificationFormat(t *testing.T) {
+	at := time.Date(2023, 5, 1, 14, 3, 9, 0, time.Local)
+
+	info := notification{at: at, severity: severityInfo, text: "Copied ID to clipboard"}
+	assert.Equal(t, "[gray]14:03:09[-] [yellow::b]info [-::-] Copied ID to clipboard", info.format())
+
+	// errors are red, tags in the text are escaped
+	failed := notification{at: at, severity: severityError, text: "Error(scrobble) -> [500]"}
+	assert.Equal(t, "[gray]14:03:09[-] [red::b]error[-::-] Error(scrobble) -> [500[]", failed.format())
+}
@@ -0,0 +1,196 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/spezifisch/stmps/logger"
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spf13/viper"
+)
+
+const defaultNowPlayingFormat = "{{.Artist}} - {{.Title}}"
+
+// Playback states passed to the now playing template as .State
+const (
+	nowPlayingPlaying = "playing"
+	nowPlayingPaused  = "paused"
+	nowPlayingStopped = "stopped"
+)
+
+// nowPlayingInfo is what the now playing template is executed with.
+type nowPlayingInfo struct {
+	mpvplayer.QueueItem
+	// playback position in seconds
+	Position int
+	// nowPlayingPlaying or nowPlayingPaused
+	State string
+}
+
+// nowPlayingFile keeps a text file with the current song up to date, e.g.
+// for a streaming overlay, see client.now-playing-file. It's only used from
+// the gui goroutine.
+type nowPlayingFile struct {
+	// empty disables the file
+	path     string
+	template *template.Template
+	// also rewrite the file when the position changes
+	elapsed bool
+
+	info nowPlayingInfo
+	// the text in the file, unchanged text isn't written again
+	written string
+	// the last write error, repeated ones aren't logged
+	lastError string
+
+	logger logger.LoggerInterface
+}
+
+// formatFuncs are the functions of the song templates: minutes formats
+// seconds as m:ss.
+var formatFuncs = template.FuncMap{
+	"minutes": func(seconds int) string {
+		min, sec := iSecondsToMinAndSec(seconds)
+		return fmt.Sprintf("%d:%02d", min, sec)
+	},
+}
+
+// parseNowPlayingFormat parses the template of the now playing file.
+func parseNowPlayingFormat(format string) (*template.Template, error) {
+	return template.New("now playing").Funcs(formatFuncs).Parse(format)
+}
+
+func isNowPlayingFormat(value interface{}) error {
+	format, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("expected a string, got %T", value)
+	}
+	_, err := parseNowPlayingFormat(format)
+	return err
+}
+
+// loadNowPlayingFile returns the now playing file of the config, disabled
+// if client.now-playing-file is unset.
+func loadNowPlayingFile(logger logger.LoggerInterface) *nowPlayingFile {
+	f := &nowPlayingFile{logger: logger}
+	path, err := expandHome(viper.GetString("client.now-playing-file"))
+	if err != nil {
+		logger.PrintError("loadNowPlayingFile", err)
+		return f
+	}
+	if path == "" {
+		return f
+	}
+
+	format := defaultNowPlayingFormat
+	if viper.IsSet("client.now-playing-format") {
+		format = viper.GetString("client.now-playing-format")
+	}
+	tmpl, err := parseNowPlayingFormat(format)
+	if err != nil {
+		// the config was validated, this is only a safeguard
+		logger.PrintError("loadNowPlayingFile", err)
+		return f
+	}
+
+	f.path = path
+	f.template = tmpl
+	f.elapsed = viper.GetBool("client.now-playing-elapsed")
+	// nothing plays yet, don't show what played last time
+	if err := writeFileAtomic(path, nil); err != nil {
+		logger.PrintError("loadNowPlayingFile", err)
+	}
+	return f
+}
+
+// text returns the file content for the current state, empty while stopped.
+func (f *nowPlayingFile) text() (string, error) {
+	if f.info.State == nowPlayingStopped || !f.info.IsValid() {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := f.template.Execute(&buf, f.info); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// setSong is called when a song starts playing.
+func (f *nowPlayingFile) setSong(song mpvplayer.QueueItem) {
+	f.info = nowPlayingInfo{QueueItem: song, State: nowPlayingPlaying}
+	f.update()
+}
+
+// setState is called when playback is paused, resumed or stopped.
+func (f *nowPlayingFile) setState(state string) {
+	f.info.State = state
+	if state == nowPlayingStopped {
+		f.info.Position = 0
+	}
+	f.update()
+}
+
+// setPosition is called with playback position updates. They're only
+// written with client.now-playing-elapsed.
+func (f *nowPlayingFile) setPosition(position int) {
+	f.info.Position = position
+	if f.elapsed {
+		f.update()
+	}
+}
+
+// clear empties the file, e.g. when stmps quits.
+func (f *nowPlayingFile) clear() {
+	f.setState(nowPlayingStopped)
+}
+
+func (f *nowPlayingFile) update() {
+	if f.path == "" {
+		return
+	}
+	text, err := f.text()
+	if err == nil && text == f.written {
+		return
+	}
+	if err == nil {
+		err = writeFileAtomic(f.path, []byte(text))
+	}
+	if err != nil {
+		if err.Error() != f.lastError {
+			f.logger.PrintError("nowPlayingFile", err)
+		}
+		f.lastError = err.Error()
+		return
+	}
+	f.written = text
+	f.lastError = ""
+}
+
+// writeFileAtomic replaces the file at path with data, so that readers
+// never see a partly written file. The data is written to a temporary file
+// in the same folder and renamed over path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spezifisch/stmps/logger"
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsNowPlayingFormat(t *testing.T) {
+	assert.NoError(t, isNowPlayingFormat("{{.Artist}} - {{.Title}} {{minutes .Position}}"))
+	assert.Error(t, isNowPlayingFormat("{{.Artist"))
+	assert.Error(t, isNowPlayingFormat(42))
+}
+
+func TestNowPlayingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "now-playing.txt")
+	tmpl, err := parseNowPlayingFormat("{{.Artist}} - {{.Title}}{{if eq .State \"paused\"}} (paused){{end}} {{minutes .Position}}/{{minutes .Duration}}")
+	assert.NoError(t, err)
+	f := &nowPlayingFile{path: path, template: tmpl, logger: logger.Init()}
+	read := func() string {
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		return string(data)
+	}
+
+	f.setSong(mpvplayer.QueueItem{Id: "1", Artist: "Artist", Title: "Song", Duration: 200})
+	assert.Equal(t, "Artist - Song 0:00/3:20", read())
+
+	// position updates need client.now-playing-elapsed
+	f.setPosition(65)
+	assert.Equal(t, "Artist - Song 0:00/3:20", read())
+	f.elapsed = true
+	f.setPosition(66)
+	assert.Equal(t, "Artist - Song 1:06/3:20", read())
+
+	f.setState(nowPlayingPaused)
+	assert.Equal(t, "Artist - Song (paused) 1:06/3:20", read())
+
+	f.clear()
+	assert.Equal(t, "", read())
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestNowPlayingFileDisabled(t *testing.T) {
+	f := &nowPlayingFile{}
+	f.setSong(mpvplayer.QueueItem{Id: "1"})
+	f.clear()
+}
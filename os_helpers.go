@@ -0,0 +1,43 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+
+	"github.com/atotto/clipboard"
+)
+
+// openInBrowser opens url with the OS default handler. It doesn't wait for
+// the browser to exit.
+func openInBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// reap the process in the background
+	go func() {
+		_ = cmd.Wait()
+	}()
+	return nil
+}
+
+// copyToClipboard puts text into the OS clipboard.
+func copyToClipboard(text string) error {
+	if clipboard.Unsupported {
+		return errors.New("no clipboard available")
+	}
+	return clipboard.WriteAll(text)
+}
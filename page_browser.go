@@ -4,39 +4,81 @@
 package main
 
 import (
-	"sort"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"github.com/spezifisch/stmps/logger"
 	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
 )
 
+// number of top songs shown per artist, unless client.top-songs is set
+const defaultTopSongsCount = 10
+
 type BrowserPage struct {
 	Root               *tview.Flex
 	AddToPlaylistModal tview.Primitive
 
 	artistFlex *tview.Flex
+	entityFlex *tview.Flex
+
+	artistList    *tview.List
+	entityList    *tview.List
+	artistImage   *tview.Image
+	topSongsList  *tview.List
+	searchField   *tview.InputField
+	yearField     *tview.InputField
+	artistImageOn bool
+
+	// artist whose image should be shown
+	artistImageId string
 
-	artistList  *tview.List
-	entityList  *tview.List
-	searchField *tview.InputField
+	// top songs of the selected artist, fetched in the background and
+	// cached per artist ID. The list is hidden while there are none.
+	topSongsArtistId string
+	topSongs         []subsonic.SubsonicEntity
+	topSongsCache    map[string][]subsonic.SubsonicEntity
 
 	currentDirectory *subsonic.SubsonicDirectory
-	artistIdList     []string
+	// artist whose albums are shown, marked as expanded in the artist list
+	expandedArtistId string
+	// artist whose albums are fetched in the background, see showArtist
+	loadingArtistId string
+	artists         []subsonic.SubsonicArtist // in server order
+	browseMode      BrowseMode
+	artistIdList    []string
+	sortOrders      sortOrders
+	// the artists of the other browse mode are fetched, see toggleBrowseMode
+	switchingMode bool
+	// a bulk star is running, see bulk_star.go
+	starring bool
+	// hides albums and songs of other years, see year_filter.go
+	yearFilter yearFilter
+
+	// optional columns of the album/song list, see ui.columns
+	columns listColumns
+	// alignment of the album/song list, for updating single entries
+	entityLayout *columnLayout
 
 	// external refs
 	ui     *Ui
 	logger logger.LoggerInterface
 }
 
-func (ui *Ui) createBrowserPage(indexes *[]subsonic.SubsonicIndex) *BrowserPage {
+func (ui *Ui) createBrowserPage(indexes *subsonic.SubsonicIndexes) *BrowserPage {
 	browserPage := BrowserPage{
 		ui:     ui,
 		logger: ui.logger,
 
 		currentDirectory: nil,
 		artistIdList:     []string{},
+		sortOrders:       loadSortOrders(),
+		browseMode:       BrowseMode(viper.GetString("client.browse-mode")),
+		topSongsCache:    map[string][]subsonic.SubsonicEntity{},
+		columns:          loadListColumns(PageBrowser, listColumns{}),
 	}
 
 	// artist list
@@ -47,12 +89,7 @@ func (ui *Ui) createBrowserPage(indexes *[]subsonic.SubsonicIndex) *BrowserPage
 		SetTitleAlign(tview.AlignLeft).
 		SetBorder(true)
 
-	for _, index := range *indexes {
-		for _, artist := range index.Artists {
-			browserPage.artistList.AddItem(tview.Escape(artist.Name), "", 0, nil)
-			browserPage.artistIdList = append(browserPage.artistIdList, artist.Id)
-		}
-	}
+	browserPage.setArtists(*indexes)
 
 	// album list
 	browserPage.entityList = tview.NewList().
@@ -78,9 +115,40 @@ func (ui *Ui) createBrowserPage(indexes *[]subsonic.SubsonicIndex) *BrowserPage
 			ui.app.SetFocus(browserPage.artistList)
 		})
 
+	// year filter input, shown instead of the search bar
+	browserPage.yearField = tview.NewInputField().
+		SetLabel("years (e.g. 1994, 1990-1999):").
+		SetFieldBackgroundColor(tcell.ColorBlack).
+		SetDoneFunc(func(key tcell.Key) {
+			if key == tcell.KeyEnter && !browserPage.setYearFilter(browserPage.yearField.GetText()) {
+				return
+			}
+			browserPage.showSearchField(false)
+			ui.app.SetFocus(browserPage.entityList)
+		})
+
+	// top songs below the album/song list, only shown if there are any
+	browserPage.topSongsList = tview.NewList().
+		ShowSecondaryText(false).
+		SetSelectedFocusOnly(true)
+	browserPage.topSongsList.Box.
+		SetTitle(" top songs ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+	browserPage.topSongsList.SetSelectedFunc(func(index int, _, _ string, _ rune) {
+		if index < len(browserPage.topSongs) {
+			makeSongHandler(&browserPage.topSongs[index], ui, "")()
+		}
+	})
+
+	// artist image above the album/song list, only shown if there is one
+	browserPage.artistImage = tview.NewImage()
+	browserPage.entityFlex = tview.NewFlex().SetDirection(tview.FlexRow)
+	browserPage.layoutEntityFlex()
+
 	browserPage.artistFlex = tview.NewFlex().SetDirection(tview.FlexColumn).
 		AddItem(browserPage.artistList, 0, 1, true).
-		AddItem(browserPage.entityList, 0, 1, false)
+		AddItem(browserPage.entityFlex, 0, 1, false)
 
 	browserPage.Root = tview.NewFlex().SetDirection(tview.FlexRow)
 	browserPage.showSearchField(false) // add artist/search items
@@ -99,7 +167,10 @@ func (ui *Ui) createBrowserPage(indexes *[]subsonic.SubsonicIndex) *BrowserPage
 
 		switch event.Rune() {
 		case 'a':
-			browserPage.handleAddArtistToQueue()
+			browserPage.handleAddArtistToQueue(queueAppend)
+			return nil
+		case 'e':
+			browserPage.handleAddArtistToQueue(queuePlayNow)
 			return nil
 		case '/':
 			browserPage.showSearchField(true)
@@ -115,34 +186,39 @@ func (ui *Ui) createBrowserPage(indexes *[]subsonic.SubsonicIndex) *BrowserPage
 			return nil
 		case 'S':
 			browserPage.handleAddRandomSongs("similar")
+		case 't':
+			browserPage.handleAddTopSongsToQueue()
+			return nil
+		case 'M':
+			browserPage.handleStartArtistRadio()
+			return nil
+		case 'L':
+			browserPage.handleStarArtist()
+			return nil
+		case 'd':
+			browserPage.showYearField()
+			return nil
+		case 'H':
+			browserPage.toggleBrowseMode()
+			return nil
+		case 'O':
+			browserPage.sortOrders.artists.cycle()
+			browserPage.handleArtistSortChanged()
+			return nil
+		case 'V':
+			browserPage.sortOrders.artists.reverse()
+			browserPage.handleArtistSortChanged()
+			return nil
 		case 'R':
-			goBackTo := browserPage.artistList.GetCurrentItem()
 			// REFRESH artists
-			indexResponse, err := ui.connection.GetIndexes()
+			indexes, err := fetchBrowseIndexes(ui.connection, browserPage.browseMode)
 			if err != nil {
 				ui.logger.Printf("Error fetching indexes from server: %s\n", err)
 				return event
 			}
 
-			browserPage.artistList.Clear()
-			browserPage.artistIdList = []string{}
 			ui.connection.ClearCache()
-
-			// Sort the indexes before adding to the list
-			for _, index := range indexResponse.Indexes.Index {
-				sort.Slice(index.Artists, func(i, j int) bool {
-					return index.Artists[i].Name < index.Artists[j].Name
-				})
-				for _, artist := range index.Artists {
-					browserPage.artistList.AddItem(tview.Escape(artist.Name), "", 0, nil)
-					browserPage.artistIdList = append(browserPage.artistIdList, artist.Id)
-				}
-			}
-
-			// Try to put the user to about where they were
-			if goBackTo < browserPage.artistList.GetItemCount() {
-				browserPage.artistList.SetCurrentItem(goBackTo)
-			}
+			browserPage.refreshArtists(indexes)
 			return nil
 		}
 		return event
@@ -150,7 +226,9 @@ func (ui *Ui) createBrowserPage(indexes *[]subsonic.SubsonicIndex) *BrowserPage
 
 	browserPage.artistList.SetChangedFunc(func(index int, _ string, _ string, _ rune) {
 		if index < len(browserPage.artistIdList) {
-			browserPage.handleEntitySelected(browserPage.artistIdList[index])
+			browserPage.showArtist(browserPage.artistIdList[index])
+			browserPage.updateArtistImage(browserPage.artistIdList[index])
+			browserPage.updateTopSongs(browserPage.artistIdList[index])
 		}
 	})
 
@@ -191,14 +269,50 @@ func (ui *Ui) createBrowserPage(indexes *[]subsonic.SubsonicIndex) *BrowserPage
 			ui.app.SetFocus(browserPage.artistList)
 			return nil
 		}
+		if event.Key() == tcell.KeyTab && len(browserPage.topSongs) > 0 {
+			ui.app.SetFocus(browserPage.topSongsList)
+			return nil
+		}
+		if event.Rune() == 't' {
+			browserPage.handleAddTopSongsToQueue()
+			return nil
+		}
+		if event.Rune() == 'M' {
+			browserPage.handleStartEntityRadio()
+			return nil
+		}
 		if event.Rune() == 'a' {
-			browserPage.handleAddEntityToQueue()
+			browserPage.handleAddEntityToQueue(queueAppend)
+			return nil
+		}
+		if event.Rune() == 'e' {
+			browserPage.handleAddEntityToQueue(queuePlayNow)
 			return nil
 		}
 		if event.Rune() == 'y' {
 			browserPage.handleToggleEntityStar()
 			return nil
 		}
+		if event.Rune() == 'L' {
+			browserPage.handleStarEntity()
+			return nil
+		}
+		if event.Rune() == 'f' {
+			ui.toggleFormatColumn()
+			return nil
+		}
+		if event.Rune() == 'l' {
+			browserPage.cycleFormatFilter()
+			return nil
+		}
+		if event.Rune() == 'd' {
+			browserPage.showYearField()
+			return nil
+		}
+		if event.Rune() == 'H' {
+			browserPage.toggleBrowseMode()
+			return nil
+		}
 		if event.Rune() == 'A' {
 			// only makes sense to add to a playlist if there are playlists
 			if ui.playlistPage.GetCount() > 0 {
@@ -216,22 +330,278 @@ func (ui *Ui) createBrowserPage(indexes *[]subsonic.SubsonicIndex) *BrowserPage
 			//ui.logger.Printf("refreshing artist idx %d, entity %s (%s)", artistIdx, entity, ui.connection.directoryCache[entity].Directory.Name)
 			ui.connection.RemoveCacheEntry(entity)
 			browserPage.handleEntitySelected(browserPage.artistIdList[artistIdx])
+			delete(browserPage.topSongsCache, entity)
+			browserPage.topSongsArtistId = ""
+			browserPage.updateTopSongs(entity)
 			return nil
 		}
 		if event.Rune() == 'S' {
 			browserPage.handleAddRandomSongs("similar")
 		}
+		if event.Rune() == 'O' {
+			browserPage.entitySortOrder().cycle()
+			browserPage.handleEntitySortChanged()
+			return nil
+		}
+		if event.Rune() == 'V' {
+			browserPage.entitySortOrder().reverse()
+			browserPage.handleEntitySortChanged()
+			return nil
+		}
+		return event
+	})
+
+	browserPage.topSongsList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyLeft:
+			ui.app.SetFocus(browserPage.artistList)
+			return nil
+		case tcell.KeyTab, tcell.KeyBacktab:
+			ui.app.SetFocus(browserPage.entityList)
+			return nil
+		}
+		switch event.Rune() {
+		case 'a':
+			browserPage.handleAddTopSongToQueue(queueAppend)
+			return nil
+		case 'e':
+			browserPage.handleAddTopSongToQueue(queuePlayNow)
+			return nil
+		case 't':
+			browserPage.handleAddTopSongsToQueue()
+			return nil
+		}
 		return event
 	})
 
 	// open first artist by default so we don't get stuck when there's only one artist
 	if len(browserPage.artistIdList) > 0 {
 		browserPage.handleEntitySelected(browserPage.artistIdList[0])
+		browserPage.updateArtistImage(browserPage.artistIdList[0])
+		browserPage.updateTopSongs(browserPage.artistIdList[0])
 	}
 
 	return &browserPage
 }
 
+// refreshArtists replaces the artist list after a refresh and keeps the
+// selection at about the same place.
+func (b *BrowserPage) refreshArtists(indexes subsonic.SubsonicIndexes) {
+	goBackTo := b.artistList.GetCurrentItem()
+	b.setArtists(indexes)
+
+	// Try to put the user to about where they were
+	if goBackTo < b.artistList.GetItemCount() {
+		b.artistList.SetCurrentItem(goBackTo)
+	}
+}
+
+// setArtists replaces the artist list with the artists of the given indexes.
+func (b *BrowserPage) setArtists(indexes subsonic.SubsonicIndexes) {
+	b.setIgnoredArticles(indexes.IgnoredArticles)
+	b.artists = nil
+	for _, index := range indexes.Index {
+		b.artists = append(b.artists, index.Artists...)
+	}
+	b.updateArtistList()
+}
+
+// setIgnoredArticles takes over the articles the server ignores when sorting
+// artists, for the browser and the search page.
+func (b *BrowserPage) setIgnoredArticles(text string) {
+	articles := parseIgnoredArticles(text)
+	b.sortOrders.ignoredArticles = articles
+	if b.ui.searchPage != nil {
+		b.ui.searchPage.sortOrders.ignoredArticles = articles
+	}
+}
+
+// updateArtistList fills the artist list using the current sort order.
+func (b *BrowserPage) updateArtistList() {
+	b.artistList.Clear()
+	b.artistIdList = []string{}
+	for _, artist := range b.sortOrders.sortArtists(b.artists) {
+		b.artistList.AddItem(b.artistText(artist.Name, artist.Id), "", 0, nil)
+		b.artistIdList = append(b.artistIdList, artist.Id)
+	}
+}
+
+// updateArtistStars shows which artists are starred.
+func (b *BrowserPage) updateArtistStars() {
+	for i, id := range b.artistIdList {
+		b.artistList.SetItemText(i, b.artistText(b.artistName(id), id), "")
+	}
+}
+
+// artistText returns the artist list entry with a marker: ▾ for the artist
+// whose albums are shown, ▸ for the others.
+func (b *BrowserPage) artistText(name, id string) string {
+	marker := "▸ "
+	if id == b.expandedArtistId {
+		marker = "▾ "
+	}
+	return marker + artistListText(name, id, b.ui.starIdList)
+}
+
+// setExpandedArtist moves the expanded marker to the artist.
+func (b *BrowserPage) setExpandedArtist(artistId string) {
+	previous := b.expandedArtistId
+	b.expandedArtistId = artistId
+	for i, id := range b.artistIdList {
+		if id == previous || id == artistId {
+			b.artistList.SetItemText(i, b.artistText(b.artistName(id), id), "")
+		}
+	}
+}
+
+// showArtist shows the albums of an artist. They're only fetched once the
+// artist is selected, in the background so that moving through the artist
+// list doesn't wait for the server, and are cached for the next time.
+func (b *BrowserPage) showArtist(artistId string) {
+	if b.ui.connection.IsCached(artistId) {
+		b.loadingArtistId = ""
+		b.handleEntitySelected(artistId)
+		return
+	}
+
+	b.loadingArtistId = artistId
+	b.setExpandedArtist(artistId)
+	// nothing to add to the queue until the albums are there
+	b.currentDirectory = &subsonic.SubsonicDirectory{Id: artistId}
+	b.entityList.Clear()
+	b.entityList.Box.SetTitle(b.entityListTitle(" album (loading…) "))
+
+	mode := b.browseMode
+	go func() {
+		_, err := fetchDirectory(b.ui.connection, mode, true, artistId)
+		b.ui.app.QueueUpdateDraw(func() {
+			if b.loadingArtistId != artistId {
+				// another artist was selected meanwhile
+				return
+			}
+			b.loadingArtistId = ""
+			if err != nil {
+				b.logger.Printf("showArtist: getDirectory %s -- %v", artistId, err)
+				b.entityList.Box.SetTitle(b.entityListTitle(" album (failed) "))
+				return
+			}
+			b.handleEntitySelected(artistId)
+		})
+	}()
+}
+
+// artistListText returns the artist list entry, with a heart if the artist
+// is starred.
+func artistListText(name, id string, starredItems map[string]struct{}) string {
+	if _, hasStar := starredItems[id]; hasStar {
+		return tview.Escape(name) + " [red]♥"
+	}
+	return tview.Escape(name)
+}
+
+func (b *BrowserPage) handleArtistSortChanged() {
+	var selectedId string
+	if idx := b.artistList.GetCurrentItem(); idx >= 0 && idx < len(b.artistIdList) {
+		selectedId = b.artistIdList[idx]
+	}
+
+	b.updateArtistList()
+
+	for i, id := range b.artistIdList {
+		if id == selectedId {
+			b.artistList.SetCurrentItem(i)
+			break
+		}
+	}
+	b.ui.showNotice("Artists sorted by " + b.sortOrders.artists.String())
+}
+
+// entitySortOrder returns the album order if the album/song list shows
+// albums, and the song order otherwise.
+func (b *BrowserPage) entitySortOrder() *sortOrder {
+	if b.currentDirectory != nil {
+		for _, entity := range b.currentDirectory.Entities {
+			if entity.IsDirectory {
+				return &b.sortOrders.albums
+			}
+		}
+	}
+	return &b.sortOrders.songs
+}
+
+func (b *BrowserPage) handleEntitySortChanged() {
+	if b.currentDirectory == nil {
+		return
+	}
+	selectedId, _ := b.selectedEntityItem()
+
+	b.handleEntitySelected(b.currentDirectory.Id)
+
+	for i, entity := range b.currentDirectory.Entities {
+		if entity.Id == selectedId {
+			if b.currentDirectory.Parent != "" {
+				// account for [..] entry
+				i++
+			}
+			b.entityList.SetCurrentItem(i)
+			break
+		}
+	}
+
+	label := "Songs"
+	if b.entitySortOrder() == &b.sortOrders.albums {
+		label = "Albums"
+	}
+	b.ui.showNotice(label + " sorted by " + b.entitySortOrder().String())
+}
+
+func (b *BrowserPage) showArtistImage(visible bool) {
+	b.artistImageOn = visible
+	b.layoutEntityFlex()
+}
+
+// layoutEntityFlex arranges the artist image, the album/song list and the
+// top songs, leaving out the image and the top songs if there are none.
+func (b *BrowserPage) layoutEntityFlex() {
+	b.entityFlex.Clear()
+	if b.artistImageOn {
+		b.entityFlex.AddItem(b.artistImage, 0, 1, false)
+	}
+	b.entityFlex.AddItem(b.entityList, 0, 2, true)
+	if len(b.topSongs) > 0 {
+		b.entityFlex.AddItem(b.topSongsList, len(b.topSongs)+2, 0, false)
+	}
+}
+
+// updateArtistImage fetches the image of an artist in the background and
+// shows it above the album list. The image is hidden if the artist has none.
+func (b *BrowserPage) updateArtistImage(artistId string) {
+	if artistId == b.artistImageId {
+		return
+	}
+	b.artistImageId = artistId
+	b.showArtistImage(false)
+
+	id3 := b.browseMode == BrowseId3
+	go func() {
+		art, err := b.ui.connection.GetArtistImage(artistId, id3)
+		b.ui.app.QueueUpdateDraw(func() {
+			if b.artistImageId != artistId {
+				// another artist was selected in the meantime
+				return
+			}
+			if err != nil {
+				b.logger.Printf("error fetching artist image for %s: %v", artistId, err)
+				return
+			}
+			if art != nil {
+				b.artistImage.SetImage(art)
+				b.showArtistImage(true)
+			}
+		})
+	}()
+}
+
 func (b *BrowserPage) showSearchField(visible bool) {
 	b.Root.Clear()
 	b.Root.AddItem(b.artistFlex, 0, 1, true)
@@ -241,25 +611,248 @@ func (b *BrowserPage) showSearchField(visible bool) {
 	}
 }
 
+// showYearField shows the year filter input with the current filter.
+func (b *BrowserPage) showYearField() {
+	b.Root.Clear()
+	b.Root.AddItem(b.artistFlex, 0, 1, true)
+	b.Root.AddItem(b.yearField, 1, 0, false)
+	b.yearField.SetText(b.yearFilter.input())
+	b.ui.app.SetFocus(b.yearField)
+}
+
+// setYearFilter applies the year filter typed in, it reports whether the
+// text was valid.
+func (b *BrowserPage) setYearFilter(text string) bool {
+	filter, err := parseYearFilter(text)
+	if err != nil {
+		b.ui.showNotice(err.Error())
+		return false
+	}
+	b.yearFilter = filter
+	b.reloadEntityList()
+	b.ui.showNotice("Browser shows " + filter.String())
+	return true
+}
+
 func (b *BrowserPage) IsSearchFocused(focused tview.Primitive) bool {
-	return focused == b.searchField
+	return focused == b.searchField || focused == b.yearField
 }
 
 func (b *BrowserPage) UpdateStars() {
-	// reload album/song list if one is open
+	b.updateArtistStars()
+	b.reloadEntityList()
+}
+
+// reloadEntityList reloads the album/song list if one is open, keeping the
+// selection.
+func (b *BrowserPage) reloadEntityList() {
+	if b.currentDirectory == nil {
+		return
+	}
+	current := b.entityList.GetCurrentItem()
+	b.handleEntitySelected(b.currentDirectory.Id)
+	b.entityList.SetCurrentItem(current)
+}
+
+// entityListTitle adds the format and year filters to the title of the
+// album/song list if they hide entries.
+func (b *BrowserPage) entityListTitle(title string) string {
+	if b.ui.formatFilter.mode != FormatFilterOff {
+		title += "(" + b.ui.formatFilter.String() + ") "
+	}
+	if !b.yearFilter.isOff() {
+		title += "(" + b.yearFilter.String() + ") "
+	}
+	return title
+}
+
+// cycleFormatFilter switches between showing all songs, lossless ones only
+// and ones above client.min-bitrate only.
+func (b *BrowserPage) cycleFormatFilter() {
+	b.ui.formatFilter.cycle()
+	b.reloadEntityList()
+	b.ui.showNotice("Browser shows " + b.ui.formatFilter.String())
+}
+
+// selectedItem returns ID and name of the focused artist or the selected
+// album/song.
+func (b *BrowserPage) selectedItem() (id, name string) {
+	if b.ui.app.GetFocus() == b.artistList {
+		idx := b.artistList.GetCurrentItem()
+		if idx >= 0 && idx < len(b.artistIdList) {
+			return b.artistIdList[idx], b.artistName(b.artistIdList[idx])
+		}
+		return "", ""
+	}
+	if b.ui.app.GetFocus() == b.topSongsList {
+		idx := b.topSongsList.GetCurrentItem()
+		if idx >= 0 && idx < len(b.topSongs) {
+			return b.topSongs[idx].Id, b.topSongs[idx].Title
+		}
+		return "", ""
+	}
+	return b.selectedEntityItem()
+}
+
+// selectedEntityItem returns ID and name of the selected album/song.
+func (b *BrowserPage) selectedEntityItem() (id, name string) {
+	if b.currentDirectory == nil {
+		return "", ""
+	}
+	currentIndex := b.entityList.GetCurrentItem()
+	if b.currentDirectory.Parent != "" {
+		// account for [..] entry that we show, see handleEntitySelected()
+		currentIndex--
+	}
+	if currentIndex < 0 || currentIndex >= len(b.currentDirectory.Entities) {
+		return "", ""
+	}
+	entity := b.currentDirectory.Entities[currentIndex]
+	return entity.Id, entity.Title
+}
+
+// selectedContextItem returns the focused artist, or the selected album or
+// song, for the context menu.
+func (b *BrowserPage) selectedContextItem() (contextItem, bool) {
+	switch b.ui.app.GetFocus() {
+	case b.artistList:
+		id, name := b.selectedItem()
+		if id == "" {
+			return contextItem{}, false
+		}
+		return contextItem{kind: contextArtist, id: id, name: name, queue: b.handleAddArtistToQueue}, true
+	case b.topSongsList:
+		idx := b.topSongsList.GetCurrentItem()
+		if idx < 0 || idx >= len(b.topSongs) {
+			return contextItem{}, false
+		}
+		return songContextItem(&b.topSongs[idx], b.handleAddTopSongToQueue), true
+	case b.entityList:
+		if b.currentDirectory == nil {
+			return contextItem{}, false
+		}
+		currentIndex := b.entityList.GetCurrentItem()
+		if b.currentDirectory.Parent != "" {
+			// account for [..] entry that we show, see handleEntitySelected()
+			currentIndex--
+		}
+		if currentIndex < 0 || currentIndex >= len(b.currentDirectory.Entities) {
+			return contextItem{}, false
+		}
+		entity := &b.currentDirectory.Entities[currentIndex]
+		if !entity.IsDirectory {
+			return songContextItem(entity, b.handleAddEntityToQueue), true
+		}
+		return contextItem{
+			kind:       contextAlbum,
+			id:         entity.Id,
+			name:       entity.Title,
+			artistId:   entity.ArtistId,
+			artistName: entity.Artist,
+			queue:      b.handleAddEntityToQueue,
+		}, true
+	}
+	return contextItem{}, false
+}
+
+var errBrowserDisabled = errors.New("the browser isn't in ui.views")
+
+// goToArtist shows the browser with the artist selected. Artists are found
+// by ID, or else by name since folder IDs differ from tag IDs.
+func (b *BrowserPage) goToArtist(id, name string) error {
+	if !b.ui.isViewEnabled(PageBrowser) {
+		return errBrowserDisabled
+	}
+
+	names := map[string]string{}
+	for _, artist := range b.artists {
+		names[artist.Id] = artist.Name
+	}
+	index := -1
+	for i, artistId := range b.artistIdList {
+		if id != "" && artistId == id {
+			index = i
+			break
+		}
+		if index < 0 && name != "" && strings.EqualFold(names[artistId], name) {
+			index = i
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("artist %s isn't in the browser", stringOr(name, id))
+	}
+
+	b.ui.ShowPage(PageBrowser)
+	b.showSearchField(false)
+	b.artistList.SetCurrentItem(index)
+	b.ui.app.SetFocus(b.artistList)
+	return nil
+}
+
+// goToAlbum selects the album in the album/song list if it's shown there,
+// and opens it otherwise.
+func (b *BrowserPage) goToAlbum(id string) error {
+	if !b.ui.isViewEnabled(PageBrowser) {
+		return errBrowserDisabled
+	}
+	b.ui.ShowPage(PageBrowser)
+	defer b.ui.app.SetFocus(b.entityList)
+
 	if b.currentDirectory != nil {
-		b.handleEntitySelected(b.currentDirectory.Id)
+		for i, entity := range b.currentDirectory.Entities {
+			if entity.Id != id {
+				continue
+			}
+			if b.currentDirectory.Parent != "" {
+				// account for [..] entry that we show, see handleEntitySelected()
+				i++
+			}
+			b.entityList.SetCurrentItem(i)
+			return nil
+		}
 	}
+	b.handleEntitySelected(id)
+	return nil
 }
 
-func (b *BrowserPage) handleAddArtistToQueue() {
+// selectedWebUITarget returns what to open in the web UI for the current
+// selection: the focused artist, or the album of the selected entity.
+func (b *BrowserPage) selectedWebUITarget() (kind, id string) {
+	if b.ui.app.GetFocus() == b.artistList {
+		idx := b.artistList.GetCurrentItem()
+		if idx >= 0 && idx < len(b.artistIdList) {
+			return webUIArtist, b.artistIdList[idx]
+		}
+		return "", ""
+	}
+
+	if b.currentDirectory == nil {
+		return "", ""
+	}
+	currentIndex := b.entityList.GetCurrentItem()
+	if b.currentDirectory.Parent != "" {
+		// account for [..] entry that we show, see handleEntitySelected()
+		currentIndex--
+	}
+	if currentIndex < 0 || currentIndex >= len(b.currentDirectory.Entities) {
+		return webUIArtist, b.currentDirectory.Id
+	}
+
+	entity := b.currentDirectory.Entities[currentIndex]
+	if entity.IsDirectory {
+		return webUIAlbum, entity.Id
+	}
+	return webUIAlbum, stringOr(entity.AlbumId, entity.Parent)
+}
+
+func (b *BrowserPage) handleAddArtistToQueue(mode queueMode) {
 	currentIndex := b.artistList.GetCurrentItem()
 	if b.artistList.GetCurrentItem() < 0 {
 		return
 	}
 
-	sort.Sort(b.currentDirectory.Entities)
-
+	b.ui.startQueueAdd(mode)
+	b.ui.setQueueSource(queueSourceOf("artist", b.currentDirectory.Name))
 	for _, entity := range b.currentDirectory.Entities {
 		if entity.IsDirectory {
 			b.addDirectoryToQueue(&entity)
@@ -272,7 +865,7 @@ func (b *BrowserPage) handleAddArtistToQueue() {
 		b.artistList.SetCurrentItem(currentIndex + 1)
 	}
 
-	b.ui.queuePage.UpdateQueue()
+	b.ui.finishQueueAdd()
 }
 
 func (b *BrowserPage) handleAddRandomSongs(randomType string) {
@@ -287,11 +880,12 @@ func (b *BrowserPage) handleAddRandomSongs(randomType string) {
 
 	entity := b.currentDirectory.Entities[currentIndex]
 
+	b.ui.startQueueAdd(queueAppend)
 	b.ui.addRandomSongsToQueue(entity.Id, randomType)
-	b.ui.queuePage.UpdateQueue()
+	b.ui.finishQueueAdd()
 }
 
-func (b *BrowserPage) handleAddEntityToQueue() {
+func (b *BrowserPage) handleAddEntityToQueue(mode queueMode) {
 	currentIndex := b.entityList.GetCurrentItem()
 	if currentIndex < 0 {
 		return
@@ -313,13 +907,15 @@ func (b *BrowserPage) handleAddEntityToQueue() {
 
 	entity := b.currentDirectory.Entities[currentIndex]
 
+	b.ui.startQueueAdd(mode)
 	if entity.IsDirectory {
+		b.ui.setQueueSource(queueSourceOf("album", entity.Title))
 		b.addDirectoryToQueue(&entity)
 	} else {
 		b.ui.addSongToQueue(&entity)
 	}
 
-	b.ui.queuePage.UpdateQueue()
+	b.ui.finishQueueAdd()
 }
 
 func (b *BrowserPage) handleEntitySelected(directoryId string) {
@@ -327,30 +923,38 @@ func (b *BrowserPage) handleEntitySelected(directoryId string) {
 		return
 	}
 
-	if response, err := b.ui.connection.GetMusicDirectory(directoryId); err != nil || response == nil {
-		b.logger.Printf("handleEntitySelected: GetMusicDirectory %s -- %v", directoryId, err)
+	if directory, err := b.getDirectory(directoryId); err != nil {
+		b.logger.Printf("handleEntitySelected: getDirectory %s -- %v", directoryId, err)
 		return
 	} else {
-		b.currentDirectory = &response.Directory
-		sort.Sort(response.Directory.Entities)
+		// sort a copy, the response is cached in server order
+		directory.Entities = b.yearFilter.filter(b.ui.formatFilter.filter(b.sortOrders.sortEntities(directory.Entities)))
+		b.currentDirectory = &directory
+	}
+	if b.isBrowseArtist(directoryId) {
+		b.setExpandedArtist(directoryId)
 	}
 
 	b.entityList.Clear()
 	if b.currentDirectory.Parent != "" {
 		// has parent entity
-		b.entityList.Box.SetTitle(" song ")
+		b.entityList.Box.SetTitle(b.entityListTitle(" song "))
 		b.entityList.AddItem(
 			tview.Escape("[..]"), "", 0,
 			b.makeEntityHandler(b.currentDirectory.Parent))
 	} else {
 		// no parent
-		b.entityList.Box.SetTitle(" album ")
+		b.entityList.Box.SetTitle(b.entityListTitle(" album "))
 	}
 
+	b.entityLayout = b.columns.layout()
 	for _, entity := range b.currentDirectory.Entities {
-		var handler func()
-		title := entityListTextFormat(entity, b.ui.starIdList) // handles escaping
+		b.entityLayout.add(b.entityCells(entity))
+	}
 
+	for i, line := range b.entityLayout.lines() {
+		entity := b.currentDirectory.Entities[i]
+		var handler func()
 		if entity.IsDirectory {
 			// it's an album/directory
 			handler = b.makeEntityHandler(entity.Id)
@@ -359,10 +963,16 @@ func (b *BrowserPage) handleEntitySelected(directoryId string) {
 			handler = makeSongHandler(&entity, b.ui, b.currentDirectory.Name)
 		}
 
-		b.entityList.AddItem(title, "", 0, handler)
+		b.entityList.AddItem(line, "", 0, handler)
 	}
 }
 
+// entityCells returns the columns of an album/song list entry.
+func (b *BrowserPage) entityCells(entity subsonic.SubsonicEntity) []string {
+	title := entityListTextFormat(entity, b.ui.starIdList, b.ui.artistDisplay) + b.ui.formatColumn(entity) // handles escaping
+	return b.columns.cells(entity, title)
+}
+
 func (b *BrowserPage) makeEntityHandler(directoryId string) func() {
 	return func() {
 		b.handleEntitySelected(directoryId)
@@ -397,16 +1007,20 @@ func (b *BrowserPage) handleToggleEntityStar() {
 	}
 
 	// update entity list entry
-	text := entityListTextFormat(entity, b.ui.starIdList)
-	b.entityList.SetItemText(originalIndex, text, "")
+	b.entityList.SetItemText(originalIndex, b.entityLayout.line(b.entityCells(entity)), "")
 
 	b.ui.queuePage.UpdateQueue()
 }
 
-func entityListTextFormat(entity subsonic.SubsonicEntity, starredItems map[string]struct{}) string {
-	title := entity.Title
+func entityListTextFormat(entity subsonic.SubsonicEntity, starredItems map[string]struct{}, display ArtistDisplay) string {
+	title := tview.Escape(entity.Title)
 	if entity.IsDirectory {
-		title = "[" + title + "]"
+		title = tview.Escape("[" + entity.Title + "]")
+	} else if albumArtist := entity.GetAlbumArtist(); albumArtist != "" {
+		// songs of compilations get their artist, the album artist is known
+		if artist := display.Artist(entity.Artist, albumArtist); artist != albumArtist {
+			title += " [gray]by " + tview.Escape(artist) + "[-]"
+		}
 	}
 
 	star := ""
@@ -414,25 +1028,29 @@ func entityListTextFormat(entity subsonic.SubsonicEntity, starredItems map[strin
 	if hasStar {
 		star = " [red]♥"
 	}
-	return tview.Escape(title) + star
+	return title + star
 }
 
 func (b *BrowserPage) addDirectoryToQueue(entity *subsonic.SubsonicEntity) {
-	response, err := b.ui.connection.GetMusicDirectory(entity.Id)
+	directory, err := b.getDirectory(entity.Id)
 	if err != nil {
-		b.logger.Printf("addDirectoryToQueue: GetMusicDirectory %s -- %s", entity.Id, err.Error())
+		b.logger.Printf("addDirectoryToQueue: getDirectory %s -- %s", entity.Id, err.Error())
 		return
 	}
 
-	sort.Sort(response.Directory.Entities)
-	for _, e := range response.Directory.Entities {
+	// the songs of a directory are added as an album
+	var songs []subsonic.SubsonicEntity
+	for _, e := range b.yearFilter.filter(b.ui.formatFilter.filter(b.sortOrders.sortEntities(directory.Entities))) {
 		if e.IsDirectory {
+			b.ui.addAlbumSongsToQueue(songs)
+			songs = nil
 			b.addDirectoryToQueue(&e)
 		} else {
 			// TODO maybe BrowserPage gets its own version of this function that uses dirname as artist name as fallback
-			b.ui.addSongToQueue(&e)
+			songs = append(songs, e)
 		}
 	}
+	b.ui.addAlbumSongsToQueue(songs)
 }
 
 func (b *BrowserPage) search() {
@@ -506,3 +1124,149 @@ func (b *BrowserPage) handleAddSongToPlaylist(playlist *subsonic.SubsonicPlaylis
 		b.entityList.SetCurrentItem(currentIndex + 1)
 	}
 }
+
+// topSongsCount returns how many top songs are shown and queued with 't'.
+func topSongsCount() int {
+	if count := viper.GetInt("client.top-songs"); count > 0 {
+		return count
+	}
+	return defaultTopSongsCount
+}
+
+func (b *BrowserPage) artistName(artistId string) string {
+	for _, artist := range b.artists {
+		if artist.Id == artistId {
+			return artist.Name
+		}
+	}
+	return ""
+}
+
+// updateTopSongs fetches the top songs of an artist in the background and
+// shows them below the album list.
+func (b *BrowserPage) updateTopSongs(artistId string) {
+	if artistId == b.topSongsArtistId {
+		return
+	}
+	b.topSongsArtistId = artistId
+
+	if songs, ok := b.topSongsCache[artistId]; ok {
+		b.setTopSongs(songs)
+		return
+	}
+	b.setTopSongs(nil)
+
+	name := b.artistName(artistId)
+	if name == "" {
+		return
+	}
+
+	go func() {
+		response, err := b.ui.connection.GetTopSongs(name, topSongsCount())
+		b.ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				b.logger.Printf("error fetching top songs for %s: %v", name, err)
+				return
+			}
+			songs := response.TopSongs.Song
+			b.topSongsCache[artistId] = songs
+			if b.topSongsArtistId == artistId {
+				b.setTopSongs(songs)
+			}
+		})
+	}()
+}
+
+func (b *BrowserPage) setTopSongs(songs []subsonic.SubsonicEntity) {
+	if len(songs) == 0 && b.ui.app.GetFocus() == b.topSongsList {
+		b.ui.app.SetFocus(b.entityList)
+	}
+
+	b.topSongs = songs
+	b.topSongsList.Clear()
+	for i, song := range songs {
+		text := fmt.Sprintf("%2d. %s", i+1, tview.Escape(song.GetSongTitle()))
+		if song.PlayCount > 0 {
+			text += fmt.Sprintf(" [gray](%d plays)[-]", song.PlayCount)
+		}
+		b.topSongsList.AddItem(text, "", 0, nil)
+	}
+	b.layoutEntityFlex()
+}
+
+// handleAddTopSongToQueue adds the selected top song to the queue.
+func (b *BrowserPage) handleAddTopSongToQueue(mode queueMode) {
+	currentIndex := b.topSongsList.GetCurrentItem()
+	if currentIndex < 0 || currentIndex >= len(b.topSongs) {
+		return
+	}
+
+	b.ui.startQueueAdd(mode)
+	b.ui.addSongToQueue(&b.topSongs[currentIndex])
+	if currentIndex+1 < b.topSongsList.GetItemCount() {
+		b.topSongsList.SetCurrentItem(currentIndex + 1)
+	}
+	b.ui.finishQueueAdd()
+}
+
+// handleAddTopSongsToQueue adds all top songs of the selected artist to the
+// queue, in rank order.
+func (b *BrowserPage) handleAddTopSongsToQueue() {
+	if len(b.topSongs) == 0 {
+		b.ui.showNotice("No top songs for this artist")
+		return
+	}
+
+	b.ui.startQueueAdd(queueAppend)
+	b.ui.setQueueSource(queueSourceOf("artist", b.artistName(b.topSongsArtistId)))
+	for i := range b.topSongs {
+		b.ui.addSongToQueue(&b.topSongs[i])
+	}
+	b.ui.finishQueueAdd()
+}
+
+// handleStartArtistRadio starts a radio seeded from the focused artist.
+func (b *BrowserPage) handleStartArtistRadio() {
+	index := b.artistList.GetCurrentItem()
+	if index < 0 || index >= len(b.artistIdList) {
+		return
+	}
+	id := b.artistIdList[index]
+	name := b.artistName(id)
+	b.ui.startRadio(radioSeed{
+		name:      name,
+		artist:    name,
+		similarId: id,
+		id3:       b.browseMode == BrowseId3,
+	})
+}
+
+// handleStartEntityRadio starts a radio seeded from the artist of the
+// selected album or song.
+func (b *BrowserPage) handleStartEntityRadio() {
+	if b.currentDirectory == nil {
+		return
+	}
+	currentIndex := b.entityList.GetCurrentItem()
+	if b.currentDirectory.Parent != "" {
+		// account for [..] entry that we show, see handleEntitySelected()
+		currentIndex--
+	}
+	if currentIndex < 0 || currentIndex >= len(b.currentDirectory.Entities) {
+		return
+	}
+
+	entity := b.currentDirectory.Entities[currentIndex]
+	seed := radioSeed{
+		name:   entity.Title,
+		artist: stringOr(entity.Artist, b.artistName(b.topSongsArtistId)),
+		id3:    b.browseMode == BrowseId3,
+	}
+	if seed.id3 {
+		seed.similarId = stringOr(entity.ArtistId, b.topSongsArtistId)
+	} else {
+		// getSimilarSongs takes albums and songs too
+		seed.similarId = entity.Id
+	}
+	b.ui.startRadio(seed)
+}
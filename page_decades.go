@@ -0,0 +1,403 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/logger"
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+const (
+	// the oldest decade offered
+	firstDecade = 1920
+	// albums listed per decade, the most getAlbumList2 returns at once
+	decadeAlbumCount = 500
+	// albums fetched and queued per step when queueing a decade
+	decadeQueueChunkSize = 5
+)
+
+// DecadesPage browses the albums released in a decade, oldest or newest
+// first.
+type DecadesPage struct {
+	Root *tview.Flex
+
+	decadeList *tview.List
+	albumList  *tview.List
+	songList   *tview.List
+
+	// decades of decadeList, newest first
+	decades []int
+	// year order of the album list and of queueing a decade
+	descending bool
+
+	// decade of the album list, 0 if none is shown
+	decade int
+	albums []subsonic.Album
+	songs  []subsonic.SubsonicEntity
+	// the artist of the shown songs
+	songsArtist string
+
+	// a newer fetch or queueing cancels the running one
+	loadSeq  int
+	albumSeq int
+	queueSeq int
+
+	// optional columns of the song list, see ui.columns
+	columns listColumns
+
+	// external refs
+	ui     *Ui
+	logger logger.LoggerInterface
+}
+
+// decadesUntil returns the decades from the one of year back to firstDecade.
+func decadesUntil(year int) []int {
+	var decades []int
+	for decade := year - year%10; decade >= firstDecade; decade -= 10 {
+		decades = append(decades, decade)
+	}
+	return decades
+}
+
+// decadeYears returns the fromYear and toYear of getAlbumList2 for the
+// decade. Swapping them makes the server list the newest albums first.
+func decadeYears(decade int, descending bool) (fromYear, toYear int) {
+	if descending {
+		return decade + 9, decade
+	}
+	return decade, decade + 9
+}
+
+func formatDecadeAlbum(album subsonic.Album) string {
+	return fmt.Sprintf("[gray]%d[-]  %s - %s", album.Year,
+		tview.Escape(stringOr(album.Name, album.Title)), tview.Escape(album.Artist))
+}
+
+func (ui *Ui) createDecadesPage() *DecadesPage {
+	decadesPage := DecadesPage{
+		ui:      ui,
+		logger:  ui.logger,
+		decades: decadesUntil(time.Now().Year()),
+		columns: loadListColumns(PageDecades, listColumns{}),
+	}
+
+	decadesPage.decadeList = tview.NewList().
+		ShowSecondaryText(false).
+		SetSelectedFocusOnly(true)
+	decadesPage.decadeList.Box.
+		SetTitle(" decade ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+	for i, decade := range decadesPage.decades {
+		i := i
+		decadesPage.decadeList.AddItem(fmt.Sprintf("%ds", decade), "", 0, func() {
+			decadesPage.showDecade(decadesPage.decades[i])
+		})
+	}
+
+	decadesPage.albumList = tview.NewList().
+		ShowSecondaryText(false).
+		SetSelectedFocusOnly(true)
+	decadesPage.albumList.Box.
+		SetTitle(" albums ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+
+	decadesPage.songList = tview.NewList().
+		ShowSecondaryText(false)
+	decadesPage.songList.Box.
+		SetTitle(" songs ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+
+	decadesPage.Root = tview.NewFlex().SetDirection(tview.FlexColumn).
+		AddItem(decadesPage.decadeList, 12, 0, true).
+		AddItem(decadesPage.albumList, 0, 1, false).
+		AddItem(decadesPage.songList, 0, 1, false)
+
+	decadesPage.decadeList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRight {
+			decadesPage.showDecade(decadesPage.selectedDecade())
+			return nil
+		}
+		switch event.Rune() {
+		case 'a', 'e':
+			decadesPage.handleAddDecadeToQueue(runeQueueMode(event.Rune()))
+			return nil
+		case 'V':
+			decadesPage.toggleOrder()
+			return nil
+		}
+		return event
+	})
+
+	decadesPage.albumList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyLeft:
+			ui.app.SetFocus(decadesPage.decadeList)
+			return nil
+		case tcell.KeyRight:
+			decadesPage.showAlbum(decadesPage.albumList.GetCurrentItem())
+			return nil
+		}
+		switch event.Rune() {
+		case 'a', 'e':
+			decadesPage.handleAddAlbumToQueue(runeQueueMode(event.Rune()))
+			return nil
+		case 'V':
+			decadesPage.toggleOrder()
+			return nil
+		}
+		return event
+	})
+
+	decadesPage.songList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyLeft {
+			ui.app.SetFocus(decadesPage.albumList)
+			return nil
+		}
+		if event.Rune() == 'a' || event.Rune() == 'e' {
+			decadesPage.handleAddSongToQueue(runeQueueMode(event.Rune()))
+			return nil
+		}
+		return event
+	})
+
+	return &decadesPage
+}
+
+func (d *DecadesPage) selectedDecade() int {
+	index := d.decadeList.GetCurrentItem()
+	if index < 0 || index >= len(d.decades) {
+		return 0
+	}
+	return d.decades[index]
+}
+
+// fetchDecade returns the albums of the decade in the chosen order. It only
+// makes an uncached request, so it may be called from any goroutine.
+func (d *DecadesPage) fetchDecade(decade int, descending bool) ([]subsonic.Album, error) {
+	fromYear, toYear := decadeYears(decade, descending)
+	response, err := d.ui.connection.GetAlbumListByYear(fromYear, toYear, decadeAlbumCount, 0)
+	if err != nil {
+		return nil, err
+	}
+	return response.AlbumList2.Albums, nil
+}
+
+// showDecade lists the albums of the decade. They're fetched in the
+// background and focused when they arrive.
+func (d *DecadesPage) showDecade(decade int) {
+	if decade == 0 {
+		return
+	}
+	d.loadSeq++
+	seq := d.loadSeq
+	descending := d.descending
+	d.albumList.Box.SetTitle(fmt.Sprintf(" albums %ds (loading...) ", decade))
+
+	go func() {
+		albums, err := d.fetchDecade(decade, descending)
+		d.ui.app.QueueUpdateDraw(func() {
+			if seq != d.loadSeq {
+				return
+			}
+			if err != nil {
+				d.logger.PrintError("DecadesPage.showDecade", err)
+				d.albumList.Box.SetTitle(fmt.Sprintf(" albums %ds (failed) ", decade))
+				return
+			}
+			d.decade = decade
+			d.albums = albums
+			d.updateAlbumList()
+			d.ui.app.SetFocus(d.albumList)
+		})
+	}()
+}
+
+func (d *DecadesPage) updateAlbumList() {
+	d.albumList.Clear()
+	for i, album := range d.albums {
+		i := i
+		d.albumList.AddItem(formatDecadeAlbum(album), "", 0, func() {
+			d.showAlbum(i)
+		})
+	}
+
+	order := "oldest first"
+	if d.descending {
+		order = "newest first"
+	}
+	d.albumList.Box.SetTitle(fmt.Sprintf(" albums %ds (%d, %s) ", d.decade, len(d.albums), order))
+}
+
+// toggleOrder switches between oldest and newest first, and refetches the
+// shown decade.
+func (d *DecadesPage) toggleOrder() {
+	d.descending = !d.descending
+	if d.decade != 0 {
+		d.showDecade(d.decade)
+	}
+}
+
+// showAlbum lists the songs of the album at index and focuses them. They're
+// fetched in the background.
+func (d *DecadesPage) showAlbum(index int) {
+	if index < 0 || index >= len(d.albums) {
+		return
+	}
+	d.albumSeq++
+	seq := d.albumSeq
+	album := d.albums[index]
+
+	go func() {
+		response, err := d.ui.connection.GetAlbum(album.Id)
+		d.ui.app.QueueUpdateDraw(func() {
+			if seq != d.albumSeq {
+				return
+			}
+			if err != nil {
+				d.logger.PrintError("DecadesPage.showAlbum", err)
+				return
+			}
+			d.showSongs(album, response.Album.Song)
+		})
+	}()
+}
+
+// showSongs lists the songs of the album and focuses them.
+func (d *DecadesPage) showSongs(album subsonic.Album, songs []subsonic.SubsonicEntity) {
+	d.songs = songs
+	d.songsArtist = album.Artist
+	d.songList.Clear()
+	layout := d.columns.layout()
+	for _, song := range d.songs {
+		layout.add(d.columns.cells(song, formatSongForPlaylistEntry(song, d.ui.artistDisplay)))
+	}
+	for i, line := range layout.lines() {
+		d.songList.AddItem(line, "", 0, makeSongHandler(&d.songs[i], d.ui, d.songsArtist))
+	}
+	d.songList.Box.SetTitle(" " + tview.Escape(stringOr(album.Name, album.Title)) + " ")
+	d.ui.app.SetFocus(d.songList)
+}
+
+// albumSongs returns the songs of an album, or none if it can't be fetched.
+// It may be called from any goroutine.
+func (d *DecadesPage) albumSongs(album subsonic.Album) []subsonic.SubsonicEntity {
+	response, err := d.ui.connection.GetAlbum(album.Id)
+	if err != nil {
+		d.logger.PrintError("DecadesPage.albumSongs", err)
+		return nil
+	}
+	return response.Album.Song
+}
+
+// handleAddDecadeToQueue queues the albums of the selected decade in the
+// chosen year order. Albums are fetched a few at a time, so the first ones
+// play while the rest are added.
+func (d *DecadesPage) handleAddDecadeToQueue(mode queueMode) {
+	decade := d.selectedDecade()
+	if decade == 0 {
+		return
+	}
+	if decade == d.decade {
+		d.queueAlbums(decade, d.albums, mode)
+		return
+	}
+
+	descending := d.descending
+	d.ui.showNotice(fmt.Sprintf("Fetching the albums of the %ds", decade))
+	go func() {
+		albums, err := d.fetchDecade(decade, descending)
+		d.ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				d.logger.PrintError("DecadesPage.handleAddDecadeToQueue", err)
+				d.ui.showNotice(fmt.Sprintf("Couldn't fetch the albums of the %ds", decade))
+				return
+			}
+			d.queueAlbums(decade, albums, mode)
+		})
+	}()
+}
+
+func (d *DecadesPage) queueAlbums(decade int, albums []subsonic.Album, mode queueMode) {
+	if len(albums) == 0 {
+		d.ui.showNotice(fmt.Sprintf("No albums from the %ds", decade))
+		return
+	}
+
+	d.queueSeq++
+	seq := d.queueSeq
+	d.ui.fetchAndQueueInChunks(len(albums), decadeQueueChunkSize, mode, queueSourceOf("decade", fmt.Sprintf("%ds", decade)),
+		func() bool {
+			return seq != d.queueSeq
+		},
+		func(from, to int) []subsonic.SubsonicEntity {
+			var songs []subsonic.SubsonicEntity
+			for _, album := range albums[from:to] {
+				songs = append(songs, d.albumSongs(album)...)
+			}
+			return songs
+		},
+		func(to int) {
+			if to < len(albums) {
+				d.ui.showNotice(fmt.Sprintf("Queueing the %ds: %d/%d albums", decade, to, len(albums)))
+			}
+		})
+}
+
+func (d *DecadesPage) handleAddAlbumToQueue(mode queueMode) {
+	index := d.albumList.GetCurrentItem()
+	if index < 0 || index >= len(d.albums) {
+		return
+	}
+
+	album := d.albums[index]
+	go func() {
+		songs := d.albumSongs(album)
+		d.ui.app.QueueUpdateDraw(func() {
+			d.ui.startQueueAdd(mode)
+			d.ui.setQueueSource(queueSourceOf("album", stringOr(album.Name, album.Title)))
+			d.ui.addAlbumSongsToQueue(songs)
+			d.ui.finishQueueAdd()
+		})
+	}()
+}
+
+func (d *DecadesPage) handleAddSongToQueue(mode queueMode) {
+	index := d.songList.GetCurrentItem()
+	if index < 0 || index >= len(d.songs) {
+		return
+	}
+
+	d.ui.startQueueAdd(mode)
+	d.ui.addSongToQueue(&d.songs[index])
+	d.ui.finishQueueAdd()
+
+	if index+1 < d.songList.GetItemCount() {
+		d.songList.SetCurrentItem(index + 1)
+	}
+}
+
+// selectedItem returns ID and name of the selected song, or album if the
+// album list has focus.
+func (d *DecadesPage) selectedItem() (id, name string) {
+	if d.albumList.HasFocus() {
+		index := d.albumList.GetCurrentItem()
+		if index < 0 || index >= len(d.albums) {
+			return "", ""
+		}
+		return d.albums[index].Id, stringOr(d.albums[index].Name, d.albums[index].Title)
+	}
+	index := d.songList.GetCurrentItem()
+	if index < 0 || index >= len(d.songs) {
+		return "", ""
+	}
+	return d.songs[index].Id, d.songs[index].Title
+}
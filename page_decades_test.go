@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecadesUntil(t *testing.T) {
+	decades := decadesUntil(2023)
+	assert.Equal(t, 2020, decades[0])
+	assert.Equal(t, 2010, decades[1])
+	assert.Equal(t, firstDecade, decades[len(decades)-1])
+
+	assert.Equal(t, []int{firstDecade}, decadesUntil(firstDecade+9))
+}
+
+func TestDecadeYears(t *testing.T) {
+	from, to := decadeYears(1980, false)
+	assert.Equal(t, 1980, from)
+	assert.Equal(t, 1989, to)
+
+	// newest first
+	from, to = decadeYears(1980, true)
+	assert.Equal(t, 1989, from)
+	assert.Equal(t, 1980, to)
+}
+
+func TestFormatDecadeAlbum(t *testing.T) {
+	album := subsonic.Album{Title: "Live [1984]", Artist: "Band", Year: 1984}
+	assert.Equal(t, "[gray]1984[-]  Live [1984[] - Band", formatDecadeAlbum(album))
+}
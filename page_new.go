@@ -0,0 +1,217 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/logger"
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+const (
+	// number of newest albums whose songs make up the feed
+	newFeedAlbumCount = 20
+	// the feed isn't refetched when switching to the page within this time
+	newFeedCacheDuration = 5 * time.Minute
+)
+
+// newSong is a feed entry: a song with the time it was added to the library.
+type newSong struct {
+	song  subsonic.SubsonicEntity
+	added time.Time
+}
+
+// NewPage shows a feed of recently added songs.
+type NewPage struct {
+	Root *tview.Flex
+
+	songTable *tview.Table
+
+	songs     []newSong
+	fetchedAt time.Time
+	loading   bool
+
+	// external refs
+	ui     *Ui
+	logger logger.LoggerInterface
+}
+
+func (ui *Ui) createNewPage() *NewPage {
+	newPage := NewPage{
+		ui:     ui,
+		logger: ui.logger,
+	}
+
+	newPage.songTable = tview.NewTable().
+		SetSelectable(true, false). // rows selectable
+		SetSelectedStyle(tcell.StyleDefault.Background(tcell.ColorLightGray).Foreground(tcell.ColorBlack))
+	newPage.songTable.Box.
+		SetTitle(" recently added ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+
+	newPage.songTable.SetSelectedFunc(func(row, _ int) {
+		newPage.handlePlaySong(row)
+	})
+	newPage.songTable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'a':
+			newPage.handleAddSongToQueue(queueAppend)
+			return nil
+		case 'e':
+			newPage.handleAddSongToQueue(queuePlayNow)
+			return nil
+		case 'R':
+			newPage.Update(true)
+			return nil
+		}
+		return event
+	})
+
+	newPage.Root = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(newPage.songTable, 0, 1, true)
+
+	return &newPage
+}
+
+// Update fetches the feed in the background unless it was fetched recently.
+// force always refetches it. Must be called from the gui goroutine.
+func (n *NewPage) Update(force bool) {
+	if n.loading {
+		return
+	}
+	if !force && time.Since(n.fetchedAt) < newFeedCacheDuration {
+		return
+	}
+
+	n.loading = true
+	n.songTable.Box.SetTitle(" recently added (loading...) ")
+
+	go func() {
+		songs, err := n.fetchNewSongs(force)
+		n.ui.app.QueueUpdateDraw(func() {
+			n.loading = false
+			if err != nil {
+				n.logger.PrintError("NewPage.Update", err)
+				n.songTable.Box.SetTitle(" recently added (failed) ")
+				return
+			}
+			n.songs = songs
+			n.fetchedAt = time.Now()
+			n.updateTable()
+		})
+	}()
+}
+
+// fetchNewSongs gets the newest albums and returns their songs, newest first.
+// If refresh is set, cached album responses are refetched.
+func (n *NewPage) fetchNewSongs(refresh bool) ([]newSong, error) {
+	response, err := n.ui.connection.GetAlbumList2("newest", newFeedAlbumCount, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var songs []newSong
+	for _, album := range response.AlbumList2.Albums {
+		if refresh {
+			n.ui.connection.RemoveCacheEntry(album.Id)
+		}
+		albumResponse, err := n.ui.connection.GetAlbum(album.Id)
+		if err != nil {
+			n.logger.Printf("fetchNewSongs: GetAlbum %s -- %v", album.Id, err)
+			continue
+		}
+
+		albumAdded := parseSubsonicTime(album.Created)
+		for _, song := range albumResponse.Album.Song {
+			added := parseSubsonicTime(song.Created)
+			if added.IsZero() {
+				added = albumAdded
+			}
+			songs = append(songs, newSong{song: song, added: added})
+		}
+	}
+
+	// newest first, songs added at the same time stay in album order
+	sort.SliceStable(songs, func(i, j int) bool {
+		return songs[i].added.After(songs[j].added)
+	})
+	return songs, nil
+}
+
+// parseSubsonicTime parses ISO 8601 timestamps as used by Subsonic. The zero
+// time is returned for empty or invalid timestamps.
+func parseSubsonicTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func (n *NewPage) updateTable() {
+	n.ui.rememberViewPosition(PageNew)
+	n.songTable.Clear()
+	for row, entry := range n.songs {
+		added := "?"
+		if !entry.added.IsZero() {
+			added = entry.added.Local().Format("2006-01-02")
+		}
+		min, sec := secondsToMinAndSec(int64(entry.song.Duration))
+
+		n.songTable.SetCell(row, 0, tview.NewTableCell(added).SetTextColor(tcell.ColorGray))
+		n.songTable.SetCell(row, 1, tview.NewTableCell(tview.Escape(entry.song.GetSongTitle())).SetExpansion(2))
+		n.songTable.SetCell(row, 2, tview.NewTableCell(tview.Escape(n.ui.artistDisplay.Artist(entry.song.Artist, entry.song.GetAlbumArtist()))).SetExpansion(1))
+		n.songTable.SetCell(row, 3, tview.NewTableCell(fmt.Sprintf("%3d:%02d", min, sec)).SetAlign(tview.AlignRight))
+	}
+	n.songTable.Box.SetTitle(fmt.Sprintf(" recently added (%d) ", len(n.songs)))
+	n.ui.restoreViewPosition(PageNew)
+}
+
+func (n *NewPage) selectedSong() *subsonic.SubsonicEntity {
+	row, _ := n.songTable.GetSelection()
+	if row < 0 || row >= len(n.songs) {
+		return nil
+	}
+	return &n.songs[row].song
+}
+
+// handlePlaySong plays the song in the given row now, see queuePlayNow.
+func (n *NewPage) handlePlaySong(row int) {
+	if row < 0 || row >= len(n.songs) {
+		return
+	}
+	makeSongHandler(&n.songs[row].song, n.ui, "")()
+}
+
+func (n *NewPage) handleAddSongToQueue(mode queueMode) {
+	song := n.selectedSong()
+	if song == nil {
+		return
+	}
+
+	n.ui.startQueueAdd(mode)
+	n.ui.addSongToQueue(song)
+	n.ui.finishQueueAdd()
+
+	row, _ := n.songTable.GetSelection()
+	if row+1 < n.songTable.GetRowCount() {
+		n.songTable.Select(row+1, 0)
+	}
+}
+
+// selectedItem returns ID and title of the selected song.
+func (n *NewPage) selectedItem() (id, name string) {
+	song := n.selectedSong()
+	if song == nil {
+		return "", ""
+	}
+	return song.Id, song.Title
+}
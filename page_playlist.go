@@ -19,9 +19,11 @@ type PlaylistPage struct {
 	Root                *tview.Flex
 	NewPlaylistModal    tview.Primitive
 	DeletePlaylistModal tview.Primitive
+	ImportPlaylistModal tview.Primitive
 
 	playlistList     *tview.List
 	newPlaylistInput *tview.InputField
+	importInput      *tview.InputField
 	selectedPlaylist *tview.List
 
 	// external refs
@@ -30,6 +32,18 @@ type PlaylistPage struct {
 
 	updatingMutex sync.Locker
 	isUpdating    bool
+
+	// the song list and queueing a playlist are loaded in steps, a new load
+	// cancels the running one, see playlist_loader.go
+	loadSeq     int
+	songsLoaded bool
+	queueSeq    int
+
+	// a playlist file is being imported, see importPlaylist
+	importing bool
+
+	// optional columns of the song list, see ui.columns
+	columns listColumns
 }
 
 func (ui *Ui) createPlaylistPage() *PlaylistPage {
@@ -37,6 +51,7 @@ func (ui *Ui) createPlaylistPage() *PlaylistPage {
 		ui:            ui,
 		logger:        ui.logger,
 		updatingMutex: &sync.Mutex{},
+		columns:       loadListColumns(PagePlaylists, listColumns{}),
 	}
 
 	// left half: playlists
@@ -100,14 +115,37 @@ func (ui *Ui) createPlaylistPage() *PlaylistPage {
 
 	playlistPage.NewPlaylistModal = makeModal(newPlaylistFlex, 58, 3)
 
+	// "import playlist" modal
+	playlistPage.importInput = tview.NewInputField().
+		SetLabel("File: ").
+		SetFieldWidth(60)
+	playlistPage.importInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter:
+			playlistPage.importPlaylist(playlistPage.importInput.GetText())
+			fallthrough
+		case tcell.KeyEscape:
+			ui.pages.HidePage(PageImportPlaylist)
+			ui.app.SetFocus(playlistPage.playlistList)
+			return nil
+		}
+		return event
+	})
+	importFlex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(playlistPage.importInput, 0, 1, true)
+	importFlex.SetTitle("Import playlist file (.m3u, .pls or .xspf)").
+		SetBorder(true)
+	playlistPage.ImportPlaylistModal = makeModal(importFlex, 68, 3)
+
 	// main list input handler
 	playlistPage.playlistList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyRight {
 			ui.app.SetFocus(playlistPage.selectedPlaylist)
 			return nil
 		}
-		if event.Rune() == 'a' {
-			playlistPage.handleAddPlaylistToQueue()
+		if event.Rune() == 'a' || event.Rune() == 'e' {
+			playlistPage.handleAddPlaylistToQueue(runeQueueMode(event.Rune()))
 			return nil
 		}
 		if event.Rune() == 'n' {
@@ -119,6 +157,11 @@ func (ui *Ui) createPlaylistPage() *PlaylistPage {
 			ui.pages.ShowPage(PageDeletePlaylist)
 			return nil
 		}
+		if event.Rune() == 'l' {
+			ui.pages.ShowPage(PageImportPlaylist)
+			ui.app.SetFocus(playlistPage.importInput)
+			return nil
+		}
 
 		return event
 	})
@@ -128,8 +171,8 @@ func (ui *Ui) createPlaylistPage() *PlaylistPage {
 			ui.app.SetFocus(playlistPage.playlistList)
 			return nil
 		}
-		if event.Rune() == 'a' {
-			playlistPage.handleAddPlaylistSongToQueue()
+		if event.Rune() == 'a' || event.Rune() == 'e' {
+			playlistPage.handleAddPlaylistSongToQueue(runeQueueMode(event.Rune()))
 			return nil
 		}
 		return event
@@ -184,10 +227,34 @@ func (p *PlaylistPage) IsNewPlaylistInputFocused(focused tview.Primitive) bool {
 	return focused == p.newPlaylistInput
 }
 
+func (p *PlaylistPage) IsImportInputFocused(focused tview.Primitive) bool {
+	return focused == p.importInput
+}
+
 func (p *PlaylistPage) GetCount() int {
 	return p.playlistList.GetItemCount()
 }
 
+// selectedItem returns ID and name of the selected playlist, or of the
+// selected song if the song list is focused.
+func (p *PlaylistPage) selectedItem() (id, name string) {
+	playlistIndex := p.playlistList.GetCurrentItem()
+	if playlistIndex < 0 || playlistIndex >= len(p.ui.playlists) {
+		return "", ""
+	}
+	playlist := p.ui.playlists[playlistIndex]
+
+	if p.ui.app.GetFocus() == p.selectedPlaylist {
+		entityIndex := p.selectedPlaylist.GetCurrentItem()
+		if entityIndex < 0 || entityIndex >= len(playlist.Entries) {
+			return "", ""
+		}
+		entity := playlist.Entries[entityIndex]
+		return entity.Id, entity.Title
+	}
+	return string(playlist.Id), playlist.Name
+}
+
 func (p *PlaylistPage) UpdatePlaylists() {
 	// There's a potential race condition here and, albeit highly unlikely to ever get hit,
 	// we'll put in some protection
@@ -203,7 +270,10 @@ func (p *PlaylistPage) UpdatePlaylists() {
 		spinnerText = []rune("▉▊▋▌▍▎▏▎▍▌▋▊▉")
 	}
 	spinnerMax := len(spinnerText) - 1
-	playlistsButton := buttonOrder[PAGE_PLAYLISTS]
+	playlistsButton := PagePlaylists
+	playlistsNumber := p.ui.menuWidget.pageNumber(playlistsButton)
+	// no spinner if the playlists view is disabled
+	button := p.ui.menuWidget.buttons[playlistsButton]
 	stop := make(chan bool)
 	go func() {
 		var idx int
@@ -212,6 +282,9 @@ func (p *PlaylistPage) UpdatePlaylists() {
 		for {
 			select {
 			case <-timer.C:
+				if button == nil {
+					continue
+				}
 				p.ui.app.QueueUpdateDraw(func() {
 					var format string
 					if playlistsButton == p.ui.menuWidget.activeButton {
@@ -219,8 +292,8 @@ func (p *PlaylistPage) UpdatePlaylists() {
 					} else {
 						format = "%d: [red]%c[white]%s"
 					}
-					label := fmt.Sprintf(format, PAGE_PLAYLISTS+1, spinnerText[idx], playlistsButton)
-					p.ui.menuWidget.buttons[playlistsButton].SetLabel(label)
+					label := fmt.Sprintf(format, playlistsNumber, spinnerText[idx], playlistsButton)
+					button.SetLabel(label)
 					idx++
 					if idx > spinnerMax {
 						idx = 0
@@ -228,14 +301,17 @@ func (p *PlaylistPage) UpdatePlaylists() {
 				})
 			case <-stop:
 				p.ui.app.QueueUpdateDraw(func() {
+					if button == nil {
+						return
+					}
 					var format string
 					if playlistsButton == p.ui.menuWidget.activeButton {
 						format = "%d: [::b]%s[::-]"
 					} else {
 						format = "%d: %s"
 					}
-					label := fmt.Sprintf(format, PAGE_PLAYLISTS+1, playlistsButton)
-					p.ui.menuWidget.buttons[playlistsButton].SetLabel(label)
+					label := fmt.Sprintf(format, playlistsNumber, playlistsButton)
+					button.SetLabel(label)
 				})
 				close(stop)
 				return
@@ -260,12 +336,14 @@ func (p *PlaylistPage) UpdatePlaylists() {
 		defer p.updatingMutex.Unlock()
 		p.ui.playlists = response.Playlists.Playlists
 		p.ui.app.QueueUpdateDraw(func() {
+			p.ui.rememberViewPosition(PagePlaylists)
 			p.playlistList.Clear()
 			p.ui.addToPlaylistList.Clear()
 
 			for _, playlist := range p.ui.playlists {
 				p.addPlaylist(playlist)
 			}
+			p.ui.restoreViewPosition(PagePlaylists)
 
 			p.isUpdating = false
 		})
@@ -278,7 +356,7 @@ func (p *PlaylistPage) addPlaylist(playlist subsonic.SubsonicPlaylist) {
 	p.ui.addToPlaylistList.AddItem(tview.Escape(playlist.Name), "", 0, nil)
 }
 
-func (p *PlaylistPage) handleAddPlaylistSongToQueue() {
+func (p *PlaylistPage) handleAddPlaylistSongToQueue(mode queueMode) {
 	playlistIndex := p.playlistList.GetCurrentItem()
 	entityIndex := p.selectedPlaylist.GetCurrentItem()
 	if playlistIndex < 0 || playlistIndex >= p.playlistList.GetItemCount() {
@@ -297,12 +375,14 @@ func (p *PlaylistPage) handleAddPlaylistSongToQueue() {
 	}
 
 	entity := p.ui.playlists[playlistIndex].Entries[entityIndex]
+	p.ui.startQueueAdd(mode)
+	p.ui.setQueueSource(queueSourceOf("playlist", p.ui.playlists[playlistIndex].Name))
 	p.ui.addSongToQueue(&entity)
 
-	p.ui.queuePage.UpdateQueue()
+	p.ui.finishQueueAdd()
 }
 
-func (p *PlaylistPage) handleAddPlaylistToQueue() {
+func (p *PlaylistPage) handleAddPlaylistToQueue(mode queueMode) {
 	currentIndex := p.playlistList.GetCurrentItem()
 	if currentIndex < 0 || currentIndex >= p.playlistList.GetItemCount() || currentIndex >= len(p.ui.playlists) {
 		return
@@ -314,22 +394,13 @@ func (p *PlaylistPage) handleAddPlaylistToQueue() {
 	}
 
 	playlist := p.ui.playlists[currentIndex]
-	for _, entity := range playlist.Entries {
-		p.ui.addSongToQueue(&entity)
-	}
-
-	p.ui.queuePage.UpdateQueue()
+	p.queuePlaylistSongs(playlist.Entries, mode, queueSourceOf("playlist", playlist.Name))
 }
 
 func (p *PlaylistPage) handlePlaylistSelected(playlist subsonic.SubsonicPlaylist) {
 	p.selectedPlaylist.Clear()
 	p.selectedPlaylist.SetSelectedFocusOnly(true)
-
-	for _, entity := range playlist.Entries {
-		handler := makeSongHandler(&entity, p.ui, entity.Artist)
-		line := formatSongForPlaylistEntry(entity)
-		p.selectedPlaylist.AddItem(line, "", 0, handler)
-	}
+	p.showPlaylistSongs(playlist)
 }
 
 func (p *PlaylistPage) newPlaylist(name string) {
@@ -19,14 +19,43 @@ import (
 	"github.com/spezifisch/stmps/logger"
 	"github.com/spezifisch/stmps/mpvplayer"
 	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
 )
 
 // TODO show total # of entries somewhere (top?)
 
-// columns: star, title, artist, duration
-const queueDataColumns = 4
 const starIcon = "♥"
 
+// columns of the queue table
+const (
+	queueColumnStar = iota
+	queueColumnTrack
+	queueColumnTitle
+	queueColumnArtist
+	queueColumnAlbum
+	queueColumnDuration
+)
+
+// the queue shows durations unless ui.columns.queue is set
+var defaultQueueColumns = listColumns{duration: true}
+
+// queueColumns returns the columns of the queue table with the optional
+// columns.
+func queueColumns(columns listColumns) []int {
+	table := []int{queueColumnStar}
+	if columns.track {
+		table = append(table, queueColumnTrack)
+	}
+	table = append(table, queueColumnTitle, queueColumnArtist)
+	if columns.album {
+		table = append(table, queueColumnAlbum)
+	}
+	if columns.duration {
+		table = append(table, queueColumnDuration)
+	}
+	return table
+}
+
 // data for rendering queue table
 type queueData struct {
 	tview.TableContentReadOnly
@@ -35,6 +64,14 @@ type queueData struct {
 	playerQueue mpvplayer.PlayerQueue
 	// we also need to know which elements are starred
 	starIdList map[string]struct{}
+	// blacklisted songs are greyed out
+	blacklist map[string]bool
+	// and which artist to show
+	artistDisplay ArtistDisplay
+	// show where songs were queued from after their title
+	showSource bool
+	// shown columns, queueColumns(defaultQueueColumns) if nil
+	columns []int
 }
 
 var _ tview.TableContent = (*queueData)(nil)
@@ -45,8 +82,13 @@ type QueuePage struct {
 	queueList *tview.Table
 	queueData queueData
 
+	infoFlex *tview.Flex
 	songInfo *tview.TextView
 	coverArt *tview.Image
+	// hidden cover art isn't fetched
+	coverArtVisible bool
+	// set while the songs are fetched again, see handleRefreshQueue
+	refreshing bool
 
 	// external refs
 	ui     *Ui
@@ -67,13 +109,19 @@ func init() {
 	}
 }
 
-func (ui *Ui) createQueuePage() *QueuePage {
-	tmpl := template.New("song info").Funcs(template.FuncMap{
+// newSongInfoTemplate parses the template of the song info panel, which is
+// executed with a QueueItem.
+func newSongInfoTemplate() (*template.Template, error) {
+	return template.New("song info").Funcs(template.FuncMap{
 		"formatTime": func(i int) string {
 			return (time.Duration(i) * time.Second).String()
 		},
-	})
-	songInfoTemplate, err := tmpl.Parse(songInfoTemplateString)
+		"escape": tview.Escape,
+	}).Parse(songInfoTemplateString)
+}
+
+func (ui *Ui) createQueuePage() *QueuePage {
+	songInfoTemplate, err := newSongInfoTemplate()
 	if err != nil {
 		ui.logger.PrintError("createQueuePage", err)
 	}
@@ -110,29 +158,12 @@ func (ui *Ui) createQueuePage() *QueuePage {
 				queuePage.ui.ShowSelectPlaylist()
 			case 'S':
 				queuePage.shuffle()
+			case 'Z':
+				queuePage.shuffleRemaining()
 			case 'l':
-				go func() {
-					ssr, err := queuePage.ui.connection.LoadPlayQueue()
-					if err != nil {
-						queuePage.logger.Printf("unable to load play queue from server: %s", err)
-						return
-					}
-					queuePage.queueList.Clear()
-					queuePage.queueData.Clear()
-					if ssr.PlayQueue.Entries != nil {
-						for _, ent := range ssr.PlayQueue.Entries {
-							ui.addSongToQueue(&ent)
-						}
-						ui.queuePage.UpdateQueue()
-						if err := ui.player.Play(); err != nil {
-							queuePage.logger.Printf("error playing: %s", err)
-						}
-						if err = ui.player.Seek(ssr.PlayQueue.Position); err != nil {
-							queuePage.logger.Printf("unable to seek to position %s: %s", time.Duration(ssr.PlayQueue.Position)*time.Second, err)
-						}
-						_ = ui.player.Pause()
-					}
-				}()
+				ui.resumeSession(true)
+			case 'R':
+				queuePage.handleRefreshQueue()
 
 			default:
 				return event
@@ -154,20 +185,26 @@ func (ui *Ui) createQueuePage() *QueuePage {
 	queuePage.coverArt = tview.NewImage()
 	queuePage.coverArt.SetImage(STMPS_LOGO)
 
-	infoFlex := tview.NewFlex().SetDirection(tview.FlexRow).
+	queuePage.infoFlex = tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(queuePage.songInfo, 0, 1, false).
 		AddItem(queuePage.coverArt, 0, 1, false)
-	infoFlex.SetBorder(true)
-	infoFlex.SetTitle(" song info ")
+	queuePage.infoFlex.SetBorder(true)
+	queuePage.infoFlex.SetTitle(" song info ")
 
 	// flex wrapper
 	queuePage.Root = tview.NewFlex().SetDirection(tview.FlexColumn).
 		AddItem(queuePage.queueList, 0, 2, true).
-		AddItem(infoFlex, 0, 1, false)
+		AddItem(queuePage.infoFlex, 0, 1, false)
+
+	queuePage.SetCoverArtVisible(ui.panelVisibility(panelCoverArt, "ui.cover-art", true))
 
 	// private data
 	queuePage.queueData = queueData{
-		starIdList: ui.starIdList,
+		starIdList:    ui.starIdList,
+		blacklist:     ui.contentFilter.blacklist,
+		artistDisplay: ui.artistDisplay,
+		showSource:    viper.GetBool("ui.show-queue-source"),
+		columns:       queueColumns(loadListColumns(PageQueue, defaultQueueColumns)),
 	}
 
 	return &queuePage
@@ -180,6 +217,11 @@ func (q *QueuePage) changeSelection(row, column int) {
 		return
 	}
 	currentSong := q.queueData.playerQueue[row]
+	_ = q.songInfoTemplate.Execute(q.songInfo, currentSong)
+	if !q.coverArtVisible || q.ui.isIdle() {
+		return
+	}
+
 	art := STMPS_LOGO
 	if currentSong.CoverArtId != "" {
 		if nart, err := q.ui.connection.GetCoverArt(currentSong.CoverArtId); err == nil {
@@ -193,7 +235,24 @@ func (q *QueuePage) changeSelection(row, column int) {
 		}
 	}
 	q.coverArt.SetImage(art)
-	_ = q.songInfoTemplate.Execute(q.songInfo, currentSong)
+}
+
+func (q *QueuePage) IsCoverArtVisible() bool {
+	return q.coverArtVisible
+}
+
+// SetCoverArtVisible shows or hides the cover art below the song info, which
+// then uses the full height.
+func (q *QueuePage) SetCoverArtVisible(visible bool) {
+	q.coverArtVisible = visible
+	if visible {
+		q.infoFlex.ResizeItem(q.coverArt, 0, 1)
+		// fetch the art of the selected song
+		q.changeSelection(q.queueList.GetSelection())
+	} else {
+		q.infoFlex.ResizeItem(q.coverArt, 0, 0)
+		q.coverArt.SetImage(nil)
+	}
 }
 
 func (q *QueuePage) UpdateQueue() {
@@ -209,6 +268,16 @@ func (q *QueuePage) getSelectedItem() (index int, err error) {
 	return
 }
 
+// selectedItem returns ID and title of the selected queue entry.
+func (q *QueuePage) selectedItem() (id, name string) {
+	index, err := q.getSelectedItem()
+	if err != nil || index >= len(q.queueData.playerQueue) {
+		return "", ""
+	}
+	song := q.queueData.playerQueue[index]
+	return song.Id, song.Title
+}
+
 // button handler
 func (q *QueuePage) handleDeleteFromQueue() {
 	currentIndex, err := q.getSelectedItem()
@@ -255,6 +324,15 @@ func (q *QueuePage) handleToggleStar() {
 	q.ui.browserPage.UpdateStars()
 }
 
+// queueTitle returns the title of the queue, which shows how many played
+// songs were dropped for client.max-queue-length.
+func queueTitle(evicted int) string {
+	if evicted == 0 {
+		return " queue "
+	}
+	return fmt.Sprintf(" queue (%d played songs evicted) ", evicted)
+}
+
 // re-read queue data from mpvplayer which is the authoritative source for the queue
 func (q *QueuePage) updateQueue() {
 	queueWasEmpty := len(q.queueData.playerQueue) == 0
@@ -262,6 +340,8 @@ func (q *QueuePage) updateQueue() {
 	// tell tview table to update its data
 	q.queueData.playerQueue = q.ui.player.GetQueueCopy()
 	q.queueList.SetContent(&q.queueData)
+	q.queueList.SetTitle(queueTitle(q.ui.player.Evicted()))
+	q.ui.updateNextSong(q.queueData.playerQueue)
 
 	// by default we're scrolled down after initially adding rows, fix this
 	if queueWasEmpty {
@@ -332,14 +412,14 @@ func (q *QueuePage) moveSongDown() {
 	q.updateQueue()
 }
 
-// saveQueue persists the current queue as a playlist. It presents the user
-// with a way of choosing the playlist name, and if a playlist with the
-// same name already exists it requires the user to confirm that they
-// want to overwrite the existing playlist.
+// saveQueue persists the current queue as a playlist, in queue order. If a
+// playlist with the same name already exists, it's replaced with overwrite,
+// and another playlist with that name is created without. The playlist's ID
+// and song count are shown when it's saved.
 //
 // Errors are reported to the user and require confirmation to dismiss,
 // and logged.
-func (q *QueuePage) saveQueue(playlistName string) {
+func (q *QueuePage) saveQueue(playlistName string, overwrite bool) {
 	// When updating an existing playlist, there are two options:
 	// updatePlaylist, and createPlaylist. createPlaylist on an
 	// existing playlist is a replace function.
@@ -358,7 +438,7 @@ func (q *QueuePage) saveQueue(playlistName string) {
 
 	var playlistId string
 	for _, p := range q.ui.playlists {
-		if p.Name == playlistName {
+		if overwrite && p.Name == playlistName {
 			playlistId = string(p.Id)
 			break
 		}
@@ -389,9 +469,26 @@ func (q *QueuePage) saveQueue(playlistName string) {
 			q.ui.playlists = append(q.ui.playlists, response.Playlist)
 		}
 		q.ui.playlistPage.handlePlaylistSelected(response.Playlist)
+		q.ui.showNotice(savedPlaylistNotice(playlistName, response.Playlist, playlistId != "", len(songIds)))
 	}
 }
 
+// savedPlaylistNotice reports a saved queue. Servers that don't return the
+// playlist, i.e. before API 1.14, leave out its ID.
+func savedPlaylistNotice(name string, playlist subsonic.SubsonicPlaylist, replaced bool, songs int) string {
+	action := "Saved"
+	if replaced {
+		action = "Replaced"
+	}
+	if playlist.SongCount > 0 {
+		songs = playlist.SongCount
+	}
+	if playlist.Id == "" {
+		return fmt.Sprintf("%s playlist %s with %d songs", action, name, songs)
+	}
+	return fmt.Sprintf("%s playlist %s (ID %s) with %d songs", action, name, playlist.Id, songs)
+}
+
 // shuffle randomly shuffles entries in the queue, updates it, and moves
 // the selected-item to the new first entry.
 func (q *QueuePage) shuffle() {
@@ -407,15 +504,30 @@ func (q *QueuePage) shuffle() {
 	q.updateQueue()
 }
 
+// shuffleRemaining shuffles the songs after the current one, without
+// interrupting it.
+func (q *QueuePage) shuffleRemaining() {
+	if len(q.queueData.playerQueue) < 3 {
+		return
+	}
+
+	current := q.queueData.playerQueue[0]
+	shuffled := q.ui.player.ShuffleRemaining()
+
+	q.updateQueue()
+	q.ui.showNotice(fmt.Sprintf("Reshuffled the %d songs after %s", shuffled, current.Title))
+}
+
 // queueData methods, used by tview to lazily render the table
 func (q *queueData) GetCell(row, column int) *tview.TableCell {
-	if row >= len(q.playerQueue) || column >= queueDataColumns || row < 0 || column < 0 {
+	columns := q.tableColumns()
+	if row >= len(q.playerQueue) || column >= len(columns) || row < 0 || column < 0 {
 		return nil
 	}
 	song := q.playerQueue[row]
 
-	switch column {
-	case 0: // star
+	switch columns[column] {
+	case queueColumnStar:
 		text := " "
 		color := tcell.ColorDefault
 		if _, starred := q.starIdList[song.Id]; starred {
@@ -429,19 +541,48 @@ func (q *queueData) GetCell(row, column int) *tview.TableCell {
 			MaxWidth:    1,
 			Transparent: true,
 		}
-	case 1: // title
+	case queueColumnTrack:
+		text := ""
+		if song.TrackNumber > 0 {
+			text = fmt.Sprintf("%d.", song.TrackNumber)
+		}
 		return &tview.TableCell{
-			Text:        tview.Escape(song.Title),
+			Text:        text,
+			Color:       tcell.ColorGray,
+			Align:       tview.AlignRight,
+			Expansion:   0,
+			Transparent: true,
+		}
+	case queueColumnTitle:
+		color := tcell.ColorDefault
+		if q.blacklist[song.Id] {
+			color = tcell.ColorGray
+		}
+		text := tview.Escape(song.Title)
+		if q.showSource && song.Source != "" {
+			text += " [gray]" + tview.Escape(song.Source) + "[-]"
+		}
+		return &tview.TableCell{
+			Text:        text,
+			Color:       color,
 			Expansion:   1,
 			Transparent: true,
 		}
-	case 2: // artist
+	case queueColumnArtist:
 		return &tview.TableCell{
-			Text:        tview.Escape(song.Artist),
+			Text:        tview.Escape(q.artistDisplay.Artist(song.Artist, song.AlbumArtist)),
 			Expansion:   1,
 			Transparent: true,
 		}
-	case 3: // duration
+	case queueColumnAlbum:
+		return &tview.TableCell{
+			Text:        tview.Escape(song.Album),
+			Color:       tcell.ColorGray,
+			Expansion:   1,
+			MaxWidth:    albumColumnWidth,
+			Transparent: true,
+		}
+	case queueColumnDuration:
 		min, sec := iSecondsToMinAndSec(song.Duration)
 		text := fmt.Sprintf("%3d:%02d", min, sec)
 		return &tview.TableCell{
@@ -463,7 +604,14 @@ func (q *queueData) GetRowCount() int {
 
 // Return the total number of columns in the table.
 func (q *queueData) GetColumnCount() int {
-	return queueDataColumns
+	return len(q.tableColumns())
+}
+
+func (q *queueData) tableColumns() []int {
+	if q.columns == nil {
+		return queueColumns(defaultQueueColumns)
+	}
+	return q.columns
 }
 
 var songInfoTemplateString = `[blue::b]Title:[-:-:-:-] [green::i]{{.Title}}[-:-:-:-] [yellow::i]({{formatTime .Duration}})[-:-:-:-]
@@ -471,7 +619,9 @@ var songInfoTemplateString = `[blue::b]Title:[-:-:-:-] [green::i]{{.Title}}[-:-:
 [blue::b]Album:[-:-:-:-] [::i]{{.GetAlbum}}[-:-:-:-]
 [blue::b]Disc:[-:-:-:-] [::i]{{.GetDiscNumber}}[-:-:-:-]  [blue::b]Track:[-:-:-:-] [::i]{{.GetTrackNumber}}[-:-:-:-]
 [blue::b]Year:[-:-:-:-] [::i]{{.GetYear}}[-:-:-:-]
-`
+{{if not .Played.IsZero}}[blue::b]Last played:[-:-:-:-] [::i]{{.Played.Local.Format "2006-01-02 15:04"}}[-:-:-:-]
+{{end}}{{with .Comment}}[blue::b]Comment:[-:-:-:-] [::i]{{escape .}}[-:-:-:-] [gray](read-only)[-]
+{{end}}`
 
 //go:embed docs/stmps_logo.png
 var _stmps_logo []byte
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/logger"
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSongInfoComment(t *testing.T) {
+	tmpl, err := newSongInfoTemplate()
+	assert.NoError(t, err)
+
+	var info strings.Builder
+	assert.NoError(t, tmpl.Execute(&info, mpvplayer.QueueItem{Title: "Song", Comment: "cue at 1:32 [intro]"}))
+	assert.Contains(t, info.String(), "Comment:")
+	assert.Contains(t, info.String(), "cue at 1:32 [intro[]")
+
+	// no comment line without a comment
+	info.Reset()
+	assert.NoError(t, tmpl.Execute(&info, mpvplayer.QueueItem{Title: "Song"}))
+	assert.NotContains(t, info.String(), "Comment:")
+}
+
+func TestQueueSourceCell(t *testing.T) {
+	data := queueData{playerQueue: mpvplayer.PlayerQueue{{Title: "Song", Source: queueSourceOf("playlist", "Road [Trip]")}}}
+	assert.Equal(t, "Song", data.GetCell(0, 1).Text)
+
+	data.showSource = true
+	assert.Equal(t, "Song [gray]playlist:Road [Trip[][-]", data.GetCell(0, 1).Text)
+}
+
+func TestQueueTitle(t *testing.T) {
+	assert.Equal(t, " queue ", queueTitle(0))
+	assert.Equal(t, " queue (12 played songs evicted) ", queueTitle(12))
+}
+
+func TestSavedPlaylistNotice(t *testing.T) {
+	playlist := subsonic.SubsonicPlaylist{Id: "pl-7", Name: "Mix", SongCount: 12}
+	assert.Equal(t, "Saved playlist Mix (ID pl-7) with 12 songs", savedPlaylistNotice("Mix", playlist, false, 12))
+	assert.Equal(t, "Replaced playlist Mix (ID pl-7) with 12 songs", savedPlaylistNotice("Mix", playlist, true, 10))
+	// servers without the playlist in the response
+	assert.Equal(t, "Saved playlist Mix with 3 songs", savedPlaylistNotice("Mix", subsonic.SubsonicPlaylist{}, false, 3))
+}
+
+// newKeyTestUi builds the whole TUI against a server that answers every
+// request with an empty response, for sending keys through it.
+func newKeyTestUi(t *testing.T) *Ui {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"subsonic-response":{"status":"ok","version":"1.16.1"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	log := logger.Init()
+	connection := subsonic.Init(log)
+	connection.Host = server.URL
+	player, err := mpvplayer.NewPlayer(log)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	// takes the quit signal, nothing receives the player's events
+	go player.EventLoop()
+	t.Cleanup(player.Quit)
+
+	return InitGui(&subsonic.SubsonicIndexes{}, connection, player, log, nil, nil)
+}
+
+func TestQueuePageSaveKey(t *testing.T) {
+	ui := newKeyTestUi(t)
+	ui.player.AddToQueue(&mpvplayer.QueueItem{Id: "1", Title: "Song"})
+	ui.queuePage.UpdateQueue()
+	ui.ShowPage(PageQueue)
+
+	// the root's input capture, handlePageInput, sees the key first
+	key := tcell.NewEventKey(tcell.KeyRune, 's', tcell.ModNone)
+	ui.rootFlex.InputHandler()(key, func(p tview.Primitive) { ui.app.SetFocus(p) })
+
+	assert.True(t, ui.selectPlaylistWidget.visible)
+	assert.False(t, ui.libraryScan.running)
+}
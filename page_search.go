@@ -5,13 +5,13 @@ package main
 
 import (
 	"fmt"
-	"sort"
 	"strings"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 	"github.com/spezifisch/stmps/logger"
 	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
 )
 
 type SearchPage struct {
@@ -29,6 +29,11 @@ type SearchPage struct {
 	albums  []*subsonic.Album
 	songs   []*subsonic.SubsonicEntity
 
+	sortOrders sortOrders
+	history    *searchHistory
+	// optional columns of the song list, see ui.columns
+	columns listColumns
+
 	// external refs
 	ui     *Ui
 	logger logger.LoggerInterface
@@ -36,9 +41,16 @@ type SearchPage struct {
 
 func (ui *Ui) createSearchPage() *SearchPage {
 	searchPage := SearchPage{
+		sortOrders: loadSortOrders(),
+		history:    ui.loadSearchHistory(),
+		columns:    loadListColumns(PageSearch, listColumns{}),
+
 		ui:     ui,
 		logger: ui.logger,
 	}
+	if ui.browserPage != nil {
+		searchPage.sortOrders.ignoredArticles = ui.browserPage.sortOrders.ignoredArticles
+	}
 
 	// artist list
 	searchPage.artistList = tview.NewList().
@@ -68,6 +80,8 @@ func (ui *Ui) createSearchPage() *SearchPage {
 	searchPage.searchField = tview.NewInputField().
 		SetLabel("search:").
 		SetFieldBackgroundColor(tcell.ColorBlack).
+		SetPlaceholderStyle(tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorGray)).
+		SetPlaceholder(searchPage.history.placeholder()).
 		SetDoneFunc(func(key tcell.Key) {
 			searchPage.aproposFocus()
 		})
@@ -92,24 +106,39 @@ func (ui *Ui) createSearchPage() *SearchPage {
 		case tcell.KeyEnter:
 			if len(searchPage.artists) != 0 {
 				idx := searchPage.artistList.GetCurrentItem()
-				searchPage.addArtistToQueue(searchPage.artists[idx])
+				searchPage.addArtistToQueue(searchPage.artists[idx], queuePlayNow)
 				return nil
 			}
 			return event
 		}
 
 		switch event.Rune() {
-		case 'a':
+		case 'a', 'e':
 			if len(searchPage.artists) != 0 {
 				idx := searchPage.artistList.GetCurrentItem()
 				searchPage.logger.Printf("artistList adding (%d) %s", idx, searchPage.artists[idx].Name)
-				searchPage.addArtistToQueue(searchPage.artists[idx])
+				searchPage.addArtistToQueue(searchPage.artists[idx], runeQueueMode(event.Rune()))
+				return nil
+			}
+			return event
+		case 'M':
+			if len(searchPage.artists) != 0 {
+				artist := searchPage.artists[searchPage.artistList.GetCurrentItem()]
+				searchPage.ui.startRadio(radioSeed{name: artist.Name, artist: artist.Name, similarId: artist.Id, id3: true})
 				return nil
 			}
 			return event
 		case '/':
 			searchPage.ui.app.SetFocus(searchPage.searchField)
 			return nil
+		case 'O':
+			searchPage.sortOrders.artists.cycle()
+			searchPage.handleSortChanged(&searchPage.sortOrders.artists, "Artists")
+			return nil
+		case 'V':
+			searchPage.sortOrders.artists.reverse()
+			searchPage.handleSortChanged(&searchPage.sortOrders.artists, "Artists")
+			return nil
 		}
 
 		return event
@@ -125,24 +154,44 @@ func (ui *Ui) createSearchPage() *SearchPage {
 		case tcell.KeyEnter:
 			if len(searchPage.albums) != 0 {
 				idx := searchPage.albumList.GetCurrentItem()
-				searchPage.addAlbumToQueue(searchPage.albums[idx])
+				searchPage.addAlbumToQueue(searchPage.albums[idx], queuePlayNow)
 				return nil
 			}
 			return event
 		}
 
 		switch event.Rune() {
-		case 'a':
+		case 'a', 'e':
 			if len(searchPage.albums) != 0 {
 				idx := searchPage.albumList.GetCurrentItem()
 				searchPage.logger.Printf("albumList adding (%d) %s", idx, searchPage.albums[idx].Name)
-				searchPage.addAlbumToQueue(searchPage.albums[idx])
+				searchPage.addAlbumToQueue(searchPage.albums[idx], runeQueueMode(event.Rune()))
+				return nil
+			}
+			return event
+		case 'M':
+			if len(searchPage.albums) != 0 {
+				album := searchPage.albums[searchPage.albumList.GetCurrentItem()]
+				searchPage.ui.startRadio(radioSeed{
+					name:      stringOr(album.Name, album.Title),
+					artist:    album.Artist,
+					similarId: album.ArtistId,
+					id3:       true,
+				})
 				return nil
 			}
 			return event
 		case '/':
 			searchPage.ui.app.SetFocus(searchPage.searchField)
 			return nil
+		case 'O':
+			searchPage.sortOrders.albums.cycle()
+			searchPage.handleSortChanged(&searchPage.sortOrders.albums, "Albums")
+			return nil
+		case 'V':
+			searchPage.sortOrders.albums.reverse()
+			searchPage.handleSortChanged(&searchPage.sortOrders.albums, "Albums")
+			return nil
 		}
 
 		return event
@@ -156,27 +205,36 @@ func (ui *Ui) createSearchPage() *SearchPage {
 			ui.app.SetFocus(searchPage.artistList)
 			return nil
 		case tcell.KeyEnter:
-			if len(searchPage.artists) != 0 {
+			if len(searchPage.songs) != 0 {
 				idx := searchPage.songList.GetCurrentItem()
-				ui.addSongToQueue(searchPage.songs[idx])
-				ui.queuePage.UpdateQueue()
+				searchPage.addSongToQueue(searchPage.songs[idx], queuePlayNow)
 				return nil
 			}
 			return event
 		}
 
 		switch event.Rune() {
-		case 'a':
-			if len(searchPage.artists) != 0 {
+		case 'a', 'e':
+			if len(searchPage.songs) != 0 {
 				idx := searchPage.songList.GetCurrentItem()
-				ui.addSongToQueue(searchPage.songs[idx])
-				ui.queuePage.updateQueue()
+				searchPage.addSongToQueue(searchPage.songs[idx], runeQueueMode(event.Rune()))
 				return nil
 			}
 			return event
+		case 'f':
+			ui.toggleFormatColumn()
+			return nil
 		case '/':
 			searchPage.ui.app.SetFocus(searchPage.searchField)
 			return nil
+		case 'O':
+			searchPage.sortOrders.songs.cycle()
+			searchPage.handleSortChanged(&searchPage.sortOrders.songs, "Songs")
+			return nil
+		case 'V':
+			searchPage.sortOrders.songs.reverse()
+			searchPage.handleSortChanged(&searchPage.sortOrders.songs, "Songs")
+			return nil
 		}
 
 		return event
@@ -184,7 +242,18 @@ func (ui *Ui) createSearchPage() *SearchPage {
 	search := make(chan string, 5)
 	searchPage.searchField.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Key() {
-		case tcell.KeyUp, tcell.KeyESC:
+		case tcell.KeyUp:
+			// recall previous queries like a shell
+			if query, ok := searchPage.history.older(searchPage.searchField.GetText()); ok {
+				searchPage.searchField.SetText(query)
+			} else if len(searchPage.history.queries) == 0 {
+				searchPage.aproposFocus()
+			}
+		case tcell.KeyDown:
+			if query, ok := searchPage.history.newer(); ok {
+				searchPage.searchField.SetText(query)
+			}
+		case tcell.KeyESC:
 			searchPage.aproposFocus()
 		case tcell.KeyEnter:
 			search <- ""
@@ -196,6 +265,7 @@ func (ui *Ui) createSearchPage() *SearchPage {
 			searchPage.songs = make([]*subsonic.SubsonicEntity, 0)
 
 			queryStr := searchPage.searchField.GetText()
+			searchPage.addToHistory(queryStr)
 			search <- queryStr
 		default:
 			return event
@@ -244,25 +314,20 @@ func (s *SearchPage) search(search chan string) {
 		s.ui.app.QueueUpdate(func() {
 			for _, artist := range res.SearchResults.Artist {
 				if strings.Contains(strings.ToLower(artist.Name), query) {
-					s.artistList.AddItem(tview.Escape(artist.Name), "", 0, nil)
 					s.artists = append(s.artists, &artist)
 				}
 			}
-			s.artistList.Box.SetTitle(fmt.Sprintf(" artist matches (%d) ", len(s.artists)))
 			for _, album := range res.SearchResults.Album {
 				if strings.Contains(strings.ToLower(album.Name), query) {
-					s.albumList.AddItem(tview.Escape(album.Name), "", 0, nil)
 					s.albums = append(s.albums, &album)
 				}
 			}
-			s.albumList.Box.SetTitle(fmt.Sprintf(" album matches (%d) ", len(s.albums)))
 			for _, song := range res.SearchResults.Song {
 				if strings.Contains(strings.ToLower(song.Title), query) {
-					s.songList.AddItem(tview.Escape(song.Title), "", 0, nil)
 					s.songs = append(s.songs, &song)
 				}
 			}
-			s.songList.Box.SetTitle(fmt.Sprintf(" song matches (%d) ", len(s.songs)))
+			s.updateResults()
 		})
 
 		artOff += len(res.SearchResults.Artist)
@@ -272,7 +337,86 @@ func (s *SearchPage) search(search chan string) {
 	}
 }
 
-func (s *SearchPage) addArtistToQueue(entity subsonic.Ider) {
+// addToHistory remembers a query and stores the history if
+// client.save-search-history is set.
+func (s *SearchPage) addToHistory(query string) {
+	s.history.add(query)
+	s.searchField.SetPlaceholder(s.history.placeholder())
+
+	if viper.GetBool("client.save-search-history") {
+		if err := saveSearchHistory(serverStatePath(), s.history.queries); err != nil {
+			s.logger.PrintError("saveSearchHistory", err)
+		}
+	}
+}
+
+// updateResults sorts the search results and refills the lists. The cursor
+// positions are kept.
+func (s *SearchPage) updateResults() {
+	s.sortOrders.sortSearchArtists(s.artists)
+	s.sortOrders.sortSearchAlbums(s.albums)
+	s.sortOrders.sortSearchSongs(s.songs)
+
+	artistIdx := s.artistList.GetCurrentItem()
+	s.artistList.Clear()
+	for _, artist := range s.artists {
+		s.artistList.AddItem(tview.Escape(artist.Name), "", 0, nil)
+	}
+	s.artistList.SetCurrentItem(artistIdx)
+	s.artistList.Box.SetTitle(fmt.Sprintf(" artist matches (%d) ", len(s.artists)))
+
+	albumIdx := s.albumList.GetCurrentItem()
+	s.albumList.Clear()
+	for _, album := range s.albums {
+		s.albumList.AddItem(tview.Escape(album.Name), "", 0, nil)
+	}
+	s.albumList.SetCurrentItem(albumIdx)
+	s.albumList.Box.SetTitle(fmt.Sprintf(" album matches (%d) ", len(s.albums)))
+
+	songIdx := s.songList.GetCurrentItem()
+	s.songList.Clear()
+	layout := s.columns.layout()
+	for _, song := range s.songs {
+		layout.add(s.columns.cells(*song, tview.Escape(song.Title)+s.ui.formatColumn(*song)))
+	}
+	for _, line := range layout.lines() {
+		s.songList.AddItem(line, "", 0, nil)
+	}
+	s.songList.SetCurrentItem(songIdx)
+	s.songList.Box.SetTitle(fmt.Sprintf(" song matches (%d) ", len(s.songs)))
+}
+
+// handleSortChanged resorts the results after the sort order of a column was
+// changed and moves the cursor back to the previously selected item.
+func (s *SearchPage) handleSortChanged(order *sortOrder, label string) {
+	selectedId, _ := s.selectedItem()
+	s.updateResults()
+
+	switch s.ui.app.GetFocus() {
+	case s.artistList:
+		for i, artist := range s.artists {
+			if artist.Id == selectedId {
+				s.artistList.SetCurrentItem(i)
+			}
+		}
+	case s.albumList:
+		for i, album := range s.albums {
+			if album.Id == selectedId {
+				s.albumList.SetCurrentItem(i)
+			}
+		}
+	case s.songList:
+		for i, song := range s.songs {
+			if song.Id == selectedId {
+				s.songList.SetCurrentItem(i)
+			}
+		}
+	}
+
+	s.ui.showNotice(label + " sorted by " + order.String())
+}
+
+func (s *SearchPage) addArtistToQueue(entity subsonic.Ider, mode queueMode) {
 	response, err := s.ui.connection.GetArtist(entity.ID())
 	if err != nil {
 		s.logger.Printf("addArtistToQueue: GetArtist %s -- %s", entity.ID(), err.Error())
@@ -280,49 +424,116 @@ func (s *SearchPage) addArtistToQueue(entity subsonic.Ider) {
 	}
 
 	artistId := response.Artist.Id
+	s.ui.startQueueAdd(mode)
+	s.ui.setQueueSource(queueSourceOf("artist", response.Artist.Name))
 	for _, album := range response.Artist.Album {
 		response, err = s.ui.connection.GetAlbum(album.Id)
 		if err != nil {
 			s.logger.Printf("error getting album %s while adding artist to queue", album.Id)
-			return
+			break
 		}
-		sort.Sort(response.Album.Song)
+		songs := append(subsonic.SubsonicEntities(nil), response.Album.Song...)
+		s.sortOrders.sortSongs(songs)
 		// We make sure we add only albums who's artists match the artist
 		// being added; this prevents collection albums with many different
 		// artists that show up in the Album column having _all_ of the songs
 		// on the album -- even ones that don't match the artist -- from
 		// being added when the user adds an album from the search results.
-		for _, e := range response.Album.Song {
+		var artistSongs []subsonic.SubsonicEntity
+		for _, e := range songs {
 			// Depending on the server implementation, the server may or may not
 			// respond with a list of artists. If either the Artist field matches,
 			// or the artist name is in a list of artists, then we add the song.
 			if e.ArtistId == artistId {
-				s.ui.addSongToQueue(&e)
+				artistSongs = append(artistSongs, e)
 				continue
 			}
 			for _, art := range e.Artists {
 				if art.Id == artistId {
-					s.ui.addSongToQueue(&e)
+					artistSongs = append(artistSongs, e)
 					break
 				}
 			}
 		}
+		// the album duplicate policy applies to the songs of each album
+		s.ui.addAlbumSongsToQueue(artistSongs)
 	}
 
-	s.ui.queuePage.UpdateQueue()
+	s.ui.finishQueueAdd()
 }
 
-func (s *SearchPage) addAlbumToQueue(entity subsonic.Ider) {
+func (s *SearchPage) addAlbumToQueue(entity subsonic.Ider, mode queueMode) {
 	response, err := s.ui.connection.GetAlbum(entity.ID())
 	if err != nil {
 		s.logger.Printf("addToQueue: GetMusicDirectory %s -- %s", entity.ID(), err.Error())
 		return
 	}
-	sort.Sort(response.Album.Song)
-	for _, e := range response.Album.Song {
-		s.ui.addSongToQueue(&e)
+	songs := append(subsonic.SubsonicEntities(nil), response.Album.Song...)
+	s.sortOrders.sortSongs(songs)
+	s.ui.startQueueAdd(mode)
+	s.ui.setQueueSource(queueSourceOf("album", stringOr(response.Album.Name, response.Album.Title)))
+	s.ui.addAlbumSongsToQueue(songs)
+	s.ui.finishQueueAdd()
+}
+
+func (s *SearchPage) addSongToQueue(entity *subsonic.SubsonicEntity, mode queueMode) {
+	s.ui.startQueueAdd(mode)
+	s.ui.addSongToQueue(entity)
+	s.ui.finishQueueAdd()
+}
+
+// selectedItem returns ID and name of the selected entry in the focused column.
+func (s *SearchPage) selectedItem() (id, name string) {
+	switch s.ui.app.GetFocus() {
+	case s.artistList:
+		if idx := s.artistList.GetCurrentItem(); idx >= 0 && idx < len(s.artists) {
+			return s.artists[idx].Id, s.artists[idx].Name
+		}
+	case s.albumList:
+		if idx := s.albumList.GetCurrentItem(); idx >= 0 && idx < len(s.albums) {
+			return s.albums[idx].Id, s.albums[idx].Name
+		}
+	case s.songList:
+		if idx := s.songList.GetCurrentItem(); idx >= 0 && idx < len(s.songs) {
+			return s.songs[idx].Id, s.songs[idx].Title
+		}
+	}
+	return "", ""
+}
+
+// selectedContextItem returns the selected entry in the focused column for
+// the context menu.
+func (s *SearchPage) selectedContextItem() (contextItem, bool) {
+	switch s.ui.app.GetFocus() {
+	case s.artistList:
+		if idx := s.artistList.GetCurrentItem(); idx >= 0 && idx < len(s.artists) {
+			artist := s.artists[idx]
+			return contextItem{
+				kind:  contextArtist,
+				id:    artist.Id,
+				name:  artist.Name,
+				queue: func(mode queueMode) { s.addArtistToQueue(artist, mode) },
+			}, true
+		}
+	case s.albumList:
+		if idx := s.albumList.GetCurrentItem(); idx >= 0 && idx < len(s.albums) {
+			album := s.albums[idx]
+			return contextItem{
+				kind:       contextAlbum,
+				id:         album.Id,
+				name:       stringOr(album.Name, album.Title),
+				artistId:   album.ArtistId,
+				artistName: album.Artist,
+				queue:      func(mode queueMode) { s.addAlbumToQueue(album, mode) },
+			}, true
+		}
+	case s.songList:
+		if idx := s.songList.GetCurrentItem(); idx >= 0 && idx < len(s.songs) {
+			song := s.songs[idx]
+			return songContextItem(song, func(mode queueMode) { s.addSongToQueue(song, mode) }), true
+		}
 	}
-	s.ui.queuePage.UpdateQueue()
+	return contextItem{}, false
 }
 
 func (s *SearchPage) aproposFocus() {
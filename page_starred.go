@@ -0,0 +1,194 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/logger"
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// starredSong is an entry of the starred view: a song with the time it was
+// starred, zero if the server doesn't report it.
+type starredSong struct {
+	song    subsonic.SubsonicEntity
+	starred time.Time
+}
+
+// StarredPage lists the starred songs, most recently starred first.
+type StarredPage struct {
+	Root *tview.Flex
+
+	songTable *tview.Table
+
+	songs   []starredSong
+	byName  bool
+	loading bool
+
+	// external refs
+	ui     *Ui
+	logger logger.LoggerInterface
+}
+
+func (ui *Ui) createStarredPage() *StarredPage {
+	starredPage := StarredPage{
+		ui:     ui,
+		logger: ui.logger,
+	}
+
+	starredPage.songTable = tview.NewTable().
+		SetSelectable(true, false). // rows selectable
+		SetSelectedStyle(tcell.StyleDefault.Background(tcell.ColorLightGray).Foreground(tcell.ColorBlack))
+	starredPage.songTable.Box.
+		SetTitle(" starred ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+
+	starredPage.songTable.SetSelectedFunc(func(row, _ int) {
+		starredPage.handlePlaySong(row)
+	})
+	starredPage.songTable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'a':
+			starredPage.handleAddSongToQueue(queueAppend)
+			return nil
+		case 'e':
+			starredPage.handleAddSongToQueue(queuePlayNow)
+			return nil
+		case 'O':
+			starredPage.byName = !starredPage.byName
+			starredPage.sortSongs()
+			starredPage.updateTable()
+			return nil
+		case 'R':
+			starredPage.Update()
+			return nil
+		}
+		return event
+	})
+
+	starredPage.Root = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(starredPage.songTable, 0, 1, true)
+
+	return &starredPage
+}
+
+// Update fetches the starred songs in the background. Must be called from
+// the gui goroutine.
+func (s *StarredPage) Update() {
+	if s.loading {
+		return
+	}
+
+	s.loading = true
+	s.songTable.Box.SetTitle(" starred (loading...) ")
+
+	go func() {
+		response, err := s.ui.connection.GetStarred2()
+		s.ui.app.QueueUpdateDraw(func() {
+			s.loading = false
+			if err != nil {
+				s.logger.PrintError("StarredPage.Update", err)
+				s.songTable.Box.SetTitle(" starred (failed) ")
+				return
+			}
+			s.songs = s.songs[:0]
+			for _, song := range response.Starred2.Song {
+				s.songs = append(s.songs, starredSong{song: song, starred: parseSubsonicTime(song.Starred)})
+			}
+			s.sortSongs()
+			s.updateTable()
+		})
+	}()
+}
+
+// sortSongs sorts the songs by title, or by when they were starred, newest
+// first. Songs without a starred date follow in the server's order, so
+// servers that don't report it keep their order.
+func (s *StarredPage) sortSongs() {
+	sortStarredSongs(s.songs, s.byName)
+}
+
+func sortStarredSongs(songs []starredSong, byName bool) {
+	sort.SliceStable(songs, func(i, j int) bool {
+		a, b := songs[i], songs[j]
+		if byName {
+			return compareFold(a.song.GetSongTitle(), b.song.GetSongTitle()) < 0
+		}
+		if a.starred.IsZero() || b.starred.IsZero() {
+			return !a.starred.IsZero() && b.starred.IsZero()
+		}
+		return a.starred.After(b.starred)
+	})
+}
+
+func (s *StarredPage) updateTable() {
+	s.ui.rememberViewPosition(PageStarred)
+	s.songTable.Clear()
+	for row, entry := range s.songs {
+		starred := "?"
+		if !entry.starred.IsZero() {
+			starred = entry.starred.Local().Format("2006-01-02")
+		}
+		min, sec := secondsToMinAndSec(int64(entry.song.Duration))
+
+		s.songTable.SetCell(row, 0, tview.NewTableCell(starred).SetTextColor(tcell.ColorGray))
+		s.songTable.SetCell(row, 1, tview.NewTableCell(tview.Escape(entry.song.GetSongTitle())).SetExpansion(2))
+		s.songTable.SetCell(row, 2, tview.NewTableCell(tview.Escape(s.ui.artistDisplay.Artist(entry.song.Artist, entry.song.GetAlbumArtist()))).SetExpansion(1))
+		s.songTable.SetCell(row, 3, tview.NewTableCell(fmt.Sprintf("%3d:%02d", min, sec)).SetAlign(tview.AlignRight))
+	}
+	order := "newest first"
+	if s.byName {
+		order = "by title"
+	}
+	s.songTable.Box.SetTitle(fmt.Sprintf(" starred (%d, %s) ", len(s.songs), order))
+	s.ui.restoreViewPosition(PageStarred)
+}
+
+func (s *StarredPage) selectedSong() *subsonic.SubsonicEntity {
+	row, _ := s.songTable.GetSelection()
+	if row < 0 || row >= len(s.songs) {
+		return nil
+	}
+	return &s.songs[row].song
+}
+
+// handlePlaySong plays the song in the given row now, see queuePlayNow.
+func (s *StarredPage) handlePlaySong(row int) {
+	if row < 0 || row >= len(s.songs) {
+		return
+	}
+	makeSongHandler(&s.songs[row].song, s.ui, "")()
+}
+
+func (s *StarredPage) handleAddSongToQueue(mode queueMode) {
+	song := s.selectedSong()
+	if song == nil {
+		return
+	}
+
+	s.ui.startQueueAdd(mode)
+	s.ui.addSongToQueue(song)
+	s.ui.finishQueueAdd()
+
+	row, _ := s.songTable.GetSelection()
+	if row+1 < s.songTable.GetRowCount() {
+		s.songTable.Select(row+1, 0)
+	}
+}
+
+// selectedItem returns ID and title of the selected song.
+func (s *StarredPage) selectedItem() (id, name string) {
+	song := s.selectedSong()
+	if song == nil {
+		return "", ""
+	}
+	return song.Id, song.Title
+}
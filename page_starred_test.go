@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func starredSongIds(songs []starredSong) (ids []string) {
+	for _, s := range songs {
+		ids = append(ids, s.song.Id)
+	}
+	return
+}
+
+func TestSortStarredSongs(t *testing.T) {
+	starred := func(id, title, date string) starredSong {
+		return starredSong{song: subsonic.SubsonicEntity{Id: id, Title: title}, starred: parseSubsonicTime(date)}
+	}
+	songs := []starredSong{
+		starred("old", "b", "2023-01-01T10:00:00Z"),
+		starred("unknown1", "d", ""),
+		starred("new", "C", "2024-06-01T10:00:00Z"),
+		starred("unknown2", "a", ""),
+	}
+
+	sortStarredSongs(songs, false)
+	assert.Equal(t, []string{"new", "old", "unknown1", "unknown2"}, starredSongIds(songs))
+
+	sortStarredSongs(songs, true)
+	assert.Equal(t, []string{"unknown2", "old", "new", "unknown1"}, starredSongIds(songs))
+}
+
+func TestSortStarredSongsWithoutDates(t *testing.T) {
+	songs := []starredSong{
+		{song: subsonic.SubsonicEntity{Id: "2"}},
+		{song: subsonic.SubsonicEntity{Id: "1"}},
+	}
+	sortStarredSongs(songs, false)
+	// the server's order
+	assert.Equal(t, []string{"2", "1"}, starredSongIds(songs))
+}
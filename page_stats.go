@@ -0,0 +1,208 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/logger"
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+const (
+	// length of the top lists
+	statsTopCount = 10
+	// number of most played albums fetched from the server
+	statsAlbumCount = 50
+	// songs of this many of the most played albums are ranked for top songs
+	statsSongAlbumCount = 10
+)
+
+// StatsPage shows play statistics: what was played in this session, and the
+// all-time top lists from the server's play counts.
+type StatsPage struct {
+	Root        *tview.Flex
+	ExportModal tview.Primitive
+
+	textView    *tview.TextView
+	exportInput *tview.InputField
+
+	allTime   *allTimeStats
+	fetchedAt time.Time
+	loading   bool
+
+	// external refs
+	ui     *Ui
+	logger logger.LoggerInterface
+}
+
+func (ui *Ui) createStatsPage() *StatsPage {
+	statsPage := StatsPage{
+		ui:     ui,
+		logger: ui.logger,
+	}
+
+	statsPage.textView = tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(false)
+	statsPage.textView.Box.
+		SetTitle(" stats ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+	statsPage.textView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'R':
+			statsPage.Update(true)
+			return nil
+		case 'x':
+			statsPage.exportInput.SetText(defaultHistoryExportPath(time.Now()))
+			ui.pages.ShowPage(PageStatsExport)
+			ui.app.SetFocus(statsPage.exportInput)
+			return nil
+		}
+		return event
+	})
+
+	// "export history" modal
+	statsPage.exportInput = tview.NewInputField().
+		SetLabel("File: ").
+		SetFieldWidth(60)
+	statsPage.exportInput.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEnter:
+			statsPage.exportHistory(statsPage.exportInput.GetText())
+			fallthrough
+		case tcell.KeyEscape:
+			ui.pages.HidePage(PageStatsExport)
+			ui.app.SetFocus(statsPage.textView)
+			return nil
+		}
+		return event
+	})
+	exportFlex := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(statsPage.exportInput, 0, 1, true)
+	exportFlex.SetTitle("Export session history (.csv or .json)").
+		SetBorder(true)
+	statsPage.ExportModal = makeModal(exportFlex, 68, 3)
+
+	statsPage.Root = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(statsPage.textView, 0, 1, true)
+
+	return &statsPage
+}
+
+// Update shows the current session stats. The all-time stats are fetched in
+// the background the first time and when force is set. Must be called from
+// the gui goroutine.
+func (s *StatsPage) Update(force bool) {
+	s.render()
+	if s.loading || (s.allTime != nil && !force) {
+		return
+	}
+
+	s.loading = true
+	s.textView.Box.SetTitle(" stats (loading...) ")
+
+	go func() {
+		stats, err := s.fetchAllTimeStats()
+		s.ui.app.QueueUpdateDraw(func() {
+			s.loading = false
+			s.textView.Box.SetTitle(" stats ")
+			if err != nil {
+				s.logger.PrintError("StatsPage.Update", err)
+				s.textView.Box.SetTitle(" stats (failed) ")
+				return
+			}
+			s.allTime = &stats
+			s.fetchedAt = time.Now()
+			s.render()
+		})
+	}()
+}
+
+func (s *StatsPage) IsExportInputFocused(focused tview.Primitive) bool {
+	return focused == s.exportInput
+}
+
+// exportHistory writes the songs played in this session to path.
+func (s *StatsPage) exportHistory(path string) {
+	history := s.ui.sessionStats.history
+	written, err := exportHistory(path, history)
+	if err != nil {
+		s.logger.PrintError("exportHistory", err)
+		s.ui.showNotice("Export failed: " + err.Error())
+		return
+	}
+	s.ui.showNotice(fmt.Sprintf("Exported %d songs to %s", len(history), written))
+}
+
+func (s *StatsPage) fetchAllTimeStats() (allTimeStats, error) {
+	response, err := s.ui.connection.GetAlbumList2("frequent", statsAlbumCount, 0)
+	if err != nil {
+		return allTimeStats{}, err
+	}
+	albums := response.AlbumList2.Albums
+
+	var songs []subsonic.SubsonicEntity
+	for i, album := range albums {
+		if i >= statsSongAlbumCount {
+			break
+		}
+		// play counts change, don't use cached albums
+		s.ui.connection.RemoveCacheEntry(album.Id)
+		albumResponse, err := s.ui.connection.GetAlbum(album.Id)
+		if err != nil {
+			s.logger.Printf("fetchAllTimeStats: GetAlbum %s -- %v", album.Id, err)
+			continue
+		}
+		songs = append(songs, albumResponse.Album.Song...)
+	}
+
+	return aggregateAllTimeStats(albums, songs, statsTopCount), nil
+}
+
+func (s *StatsPage) render() {
+	var b strings.Builder
+	session := s.ui.sessionStats
+
+	fmt.Fprintf(&b, "[::b]This session[::-] [gray](since %s)[-]\n", session.started.Format("15:04"))
+	fmt.Fprintf(&b, "  Songs played:    %d\n", len(session.plays))
+	fmt.Fprintf(&b, "  Listening time:  %s\n", (time.Duration(session.listened) * time.Second).String())
+	if artists := session.topArtists(statsTopCount); len(artists) > 0 {
+		b.WriteString("  Top artists:\n")
+		for i, artist := range artists {
+			fmt.Fprintf(&b, "    %2d. %s [gray](%d)[-]\n", i+1, tview.Escape(artist.name), artist.count)
+		}
+	}
+
+	b.WriteString("\n[::b]All time[::-] ")
+	if s.allTime == nil {
+		b.WriteString("[gray](loading from the server)[-]\n")
+		s.textView.SetText(b.String())
+		return
+	}
+	fmt.Fprintf(&b, "[gray](from the server at %s, R to refresh)[-]\n", s.fetchedAt.Format("15:04"))
+
+	b.WriteString("  Top artists:\n")
+	for i, artist := range s.allTime.artists {
+		fmt.Fprintf(&b, "    %2d. %s [gray](%d plays)[-]\n", i+1, tview.Escape(artist.name), artist.count)
+	}
+	b.WriteString("  Top albums:\n")
+	for i, album := range s.allTime.albums {
+		fmt.Fprintf(&b, "    %2d. %s - %s [gray](%d plays)[-]\n", i+1, tview.Escape(album.Artist), tview.Escape(album.Name), album.PlayCount)
+	}
+	b.WriteString("  Top songs:\n")
+	for i, song := range s.allTime.songs {
+		fmt.Fprintf(&b, "    %2d. %s - %s [gray](%d plays)[-]\n", i+1, tview.Escape(song.Artist), tview.Escape(song.GetSongTitle()), song.PlayCount)
+	}
+
+	s.textView.SetText(b.String())
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"strings"
+
+	"github.com/spezifisch/stmps/remote"
+)
+
+// pauseOthers pauses other media players when stmps starts playing, see
+// player.pause-others-on-play.
+type pauseOthers struct {
+	enabled bool
+	// stmps is playing, further songs and status updates don't pause again
+	playing bool
+}
+
+// playbackStarted reports whether playback starts, as opposed to the next
+// song of a running playback.
+func (p *pauseOthers) playbackStarted() bool {
+	started := !p.playing
+	p.playing = true
+	return started
+}
+
+func (p *pauseOthers) playbackHalted() {
+	p.playing = false
+}
+
+// onPlaying is called when a song starts or playback resumes. Other players
+// are paused in the background, so a slow bus doesn't block the UI.
+func (ui *Ui) onPlaying() {
+	if !ui.pauseOthers.playbackStarted() || !ui.pauseOthers.enabled {
+		return
+	}
+	go func() {
+		paused, err := remote.PauseOtherPlayers()
+		if err != nil {
+			ui.logger.PrintError("PauseOtherPlayers", err)
+		}
+		if len(paused) == 0 {
+			return
+		}
+		ui.logger.Printf("paused other players: %s", strings.Join(paused, ", "))
+		ui.app.QueueUpdateDraw(func() {
+			ui.showNotice("Paused " + strings.Join(paused, ", "))
+		})
+	}()
+}
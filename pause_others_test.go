@@ -0,0 +1,17 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPauseOthersPlaybackStarted(t *testing.T) {
+	var p pauseOthers
+	assert.True(t, p.playbackStarted())
+	// next song of the same playback
+	assert.False(t, p.playbackStarted())
+
+	p.playbackHalted()
+	assert.True(t, p.playbackStarted())
+}
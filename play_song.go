@@ -0,0 +1,85 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// queue source of songs started with --play-song
+const queueSourceCommandLine = "command line"
+
+// fetchSong returns the song with the ID from the server, an error if there's
+// none. Used by --play-song and --stream-url.
+func fetchSong(connection *subsonic.SubsonicConnection, id string) (*subsonic.SubsonicEntity, error) {
+	response, err := connection.GetSong(id)
+	if err != nil {
+		return nil, err
+	}
+	if response.Song.Id == "" {
+		return nil, fmt.Errorf("no song %s on the server", id)
+	}
+	return &response.Song, nil
+}
+
+// playSongHeadless plays the song from the start without the UI, for
+// --play-song. It returns once the song ended or playback was stopped, e.g.
+// with the OS media controls.
+func playSongHeadless(connection *subsonic.SubsonicConnection, player *mpvplayer.Player, song *subsonic.SubsonicEntity) error {
+	ended := make(songEnd, 1)
+	player.RegisterEventConsumer(ended)
+	go player.EventLoop()
+	defer player.Quit()
+
+	player.ClearQueue()
+	player.AddToQueue(&mpvplayer.QueueItem{
+		Id:          song.Id,
+		Uri:         connection.GetPlayUrl(song),
+		Title:       song.GetSongTitle(),
+		Artist:      song.Artist,
+		AlbumArtist: song.GetAlbumArtist(),
+		Duration:    song.Duration,
+		Album:       song.Album,
+		AlbumId:     stringOr(song.AlbumId, song.Parent),
+		ArtistId:    song.ArtistId,
+		TrackNumber: song.Track,
+		CoverArtId:  song.CoverArtId,
+		DiscNumber:  song.DiscNumber,
+		Year:        song.Year,
+		Genres:      song.GetGenres(),
+		Type:        song.Type,
+		Source:      queueSourceCommandLine,
+	})
+	if err := player.PlayFrom(0, false); err != nil {
+		return err
+	}
+	return <-ended
+}
+
+// songEnd is the event consumer of playSongHeadless. It receives nil when
+// playback stopped, or the error if mpv failed.
+type songEnd chan error
+
+func (e songEnd) SendEvent(event mpvplayer.UiEvent) {
+	var err error
+	switch event.Type {
+	case mpvplayer.EventStopped:
+	case mpvplayer.EventFailed:
+		err, _ = event.Data.(error)
+		if err == nil {
+			err = errors.New("mpv failed")
+		}
+	default:
+		return
+	}
+	// only the first end counts
+	select {
+	case e <- err:
+	default:
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSongEnd(t *testing.T) {
+	ended := make(songEnd, 1)
+	ended.SendEvent(mpvplayer.UiEvent{Type: mpvplayer.EventPlaying})
+	ended.SendEvent(mpvplayer.UiEvent{Type: mpvplayer.EventTrackEnded})
+	assert.Len(t, ended, 0)
+
+	ended.SendEvent(mpvplayer.UiEvent{Type: mpvplayer.EventStopped})
+	// later events don't block the event loop
+	ended.SendEvent(mpvplayer.UiEvent{Type: mpvplayer.EventFailed, Data: errors.New("gone")})
+	assert.NoError(t, <-ended)
+
+	ended.SendEvent(mpvplayer.UiEvent{Type: mpvplayer.EventFailed, Data: errors.New("gone")})
+	assert.EqualError(t, <-ended, "gone")
+}
@@ -0,0 +1,397 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// importEntry is a song of a playlist file. Any of the fields may be empty.
+type importEntry struct {
+	// file path or URL
+	location string
+	title    string
+	artist   string
+}
+
+// String describes the entry for reports of unresolved entries.
+func (e importEntry) String() string {
+	if e.title != "" && e.artist != "" {
+		return e.artist + " - " + e.title
+	}
+	return stringOr(e.title, e.location)
+}
+
+// splitArtistTitle splits "Artist - Title", as written by most players to
+// M3U and PLS titles.
+func splitArtistTitle(text string) (artist, title string) {
+	if artist, title, found := strings.Cut(text, " - "); found {
+		return strings.TrimSpace(artist), strings.TrimSpace(title)
+	}
+	return "", strings.TrimSpace(text)
+}
+
+// parseM3U reads M3U and extended M3U playlists. #EXTINF lines give the
+// title of the following entry, other comments are skipped.
+func parseM3U(r io.Reader) ([]importEntry, error) {
+	var entries []importEntry
+	var next importEntry
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// #EXTINF:duration,Artist - Title
+			if _, info, found := strings.Cut(line, ","); found {
+				next.artist, next.title = splitArtistTitle(info)
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			next.location = line
+			entries = append(entries, next)
+			next = importEntry{}
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// parsePLS reads PLS playlists. The FileN, TitleN keys are matched case
+// insensitively and ordered by N; NumberOfEntries is often wrong and ignored.
+func parsePLS(r io.Reader) ([]importEntry, error) {
+	byNumber := make(map[int]*importEntry)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			// [playlist] header, comments
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		var field string
+		for _, prefix := range []string{"file", "title"} {
+			if strings.HasPrefix(key, prefix) {
+				field = prefix
+				key = strings.TrimPrefix(key, prefix)
+				break
+			}
+		}
+		number, err := strconv.Atoi(key)
+		if field == "" || err != nil {
+			// Length, NumberOfEntries, Version
+			continue
+		}
+
+		entry := byNumber[number]
+		if entry == nil {
+			entry = &importEntry{}
+			byNumber[number] = entry
+		}
+		if field == "file" {
+			entry.location = value
+		} else {
+			entry.artist, entry.title = splitArtistTitle(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	numbers := make([]int, 0, len(byNumber))
+	for number, entry := range byNumber {
+		if entry.location != "" || entry.title != "" {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+	entries := make([]importEntry, len(numbers))
+	for i, number := range numbers {
+		entries[i] = *byNumber[number]
+	}
+	return entries, nil
+}
+
+// parseXSPF reads XSPF playlists. Namespaces are ignored, so files that
+// leave out or misspell the XSPF namespace are read as well.
+func parseXSPF(r io.Reader) ([]importEntry, error) {
+	var playlist struct {
+		Tracks []struct {
+			Locations []string `xml:"location"`
+			Title     string   `xml:"title"`
+			Creator   string   `xml:"creator"`
+		} `xml:"trackList>track"`
+	}
+	if err := xml.NewDecoder(r).Decode(&playlist); err != nil {
+		return nil, err
+	}
+
+	entries := make([]importEntry, 0, len(playlist.Tracks))
+	for _, track := range playlist.Tracks {
+		entry := importEntry{
+			title:  strings.TrimSpace(track.Title),
+			artist: strings.TrimSpace(track.Creator),
+		}
+		if len(track.Locations) > 0 {
+			entry.location = strings.TrimSpace(track.Locations[0])
+		}
+		if entry.location != "" || entry.title != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// parsePlaylistFile reads an M3U, PLS or XSPF playlist. The format is taken
+// from the file extension, or guessed from the content for other files.
+func parsePlaylistFile(name string, content []byte) ([]importEntry, error) {
+	parse := parseM3U
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".m3u", ".m3u8":
+	case ".pls":
+		parse = parsePLS
+	case ".xspf":
+		parse = parseXSPF
+	default:
+		start := bytes.ToLower(bytes.TrimSpace(bytes.TrimPrefix(content, []byte("\ufeff"))))
+		if bytes.HasPrefix(start, []byte("[playlist]")) {
+			parse = parsePLS
+		} else if bytes.HasPrefix(start, []byte("<")) {
+			parse = parseXSPF
+		}
+	}
+	return parse(bytes.NewReader(content))
+}
+
+// locationPath returns the path of an entry's location for comparing it with
+// the server's song paths: file URLs are decoded, backslashes become slashes,
+// drive letters and leading ./ and ../ are dropped, and it's lower case.
+func locationPath(location string) string {
+	if u, err := url.Parse(location); err == nil && u.Scheme == "file" {
+		location = u.Path
+	}
+	location = strings.ReplaceAll(location, "\\", "/")
+	if len(location) >= 2 && location[1] == ':' {
+		// C:/Music/...
+		location = location[2:]
+	}
+	for {
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(location, "./"), "../")
+		if trimmed == location {
+			break
+		}
+		location = trimmed
+	}
+	return strings.ToLower(path.Clean("/" + location))[1:]
+}
+
+// trimExt removes the file extension of the last path element.
+func trimExt(p string) string {
+	if ext := path.Ext(p); ext != "" && !strings.Contains(ext, " ") {
+		return strings.TrimSuffix(p, ext)
+	}
+	return p
+}
+
+// endsWithPath reports whether p is suffix or ends with it at a path
+// element boundary.
+func endsWithPath(p, suffix string) bool {
+	return p == suffix || strings.HasSuffix(p, "/"+suffix)
+}
+
+// pathMatches reports whether an entry path is the server path of a song.
+// Server paths are relative to the library, so an absolute entry path ends
+// with the server path, while a relative one may be the end of it. The
+// extensions don't have to match, for entries without one or libraries that
+// were converted to another format.
+func pathMatches(serverPath, entryPath string) bool {
+	if entryPath == "" {
+		return false
+	}
+	serverPath = strings.ToLower(strings.ReplaceAll(serverPath, "\\", "/"))
+	if serverPath == "" {
+		return false
+	}
+	serverPath, entryPath = trimExt(serverPath), trimExt(entryPath)
+	return endsWithPath(serverPath, entryPath) || endsWithPath(entryPath, serverPath)
+}
+
+var leadingTrackNumber = regexp.MustCompile(`^\d+(-\d+)?[\s._-]+`)
+
+// searchQuery returns the text to search the server for an entry: its title,
+// or the file name without extension and track number.
+func (e importEntry) searchQuery() string {
+	if e.title != "" {
+		return e.title
+	}
+	name := trimExt(path.Base(locationPath(e.location)))
+	if stripped := leadingTrackNumber.ReplaceAllString(name, ""); stripped != "" {
+		name = stripped
+	}
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// streamId returns the song ID of entries that are Subsonic stream URLs,
+// e.g. from a queue saved by another client.
+func streamId(location string) string {
+	u, err := url.Parse(location)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !strings.HasSuffix(u.Path, "/stream") {
+		return ""
+	}
+	return u.Query().Get("id")
+}
+
+// matchEntry picks the search result that is the entry's song: the one at
+// the entry's path, or else the first with its title and, if known, artist.
+func matchEntry(entry importEntry, songs []subsonic.SubsonicEntity) (subsonic.SubsonicEntity, bool) {
+	entryPath := locationPath(entry.location)
+	for _, song := range songs {
+		if pathMatches(song.Path, entryPath) {
+			return song, true
+		}
+	}
+
+	title := entry.searchQuery()
+	for _, song := range songs {
+		if !strings.EqualFold(song.Title, title) {
+			continue
+		}
+		if entry.artist == "" || strings.EqualFold(song.Artist, entry.artist) || strings.EqualFold(song.GetAlbumArtist(), entry.artist) {
+			return song, true
+		}
+	}
+	return subsonic.SubsonicEntity{}, false
+}
+
+// resolveEntry returns the ID of the entry's song on the server, or "" if
+// it can't be found. It only makes uncached requests, so it may be called
+// from any goroutine.
+func resolveEntry(connection *subsonic.SubsonicConnection, entry importEntry) (string, error) {
+	if id := streamId(entry.location); id != "" {
+		return id, nil
+	}
+	query := entry.searchQuery()
+	if query == "" {
+		return "", nil
+	}
+	response, err := connection.Search(query, 0, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	if song, found := matchEntry(entry, response.SearchResults.Song); found {
+		return song.Id, nil
+	}
+	return "", nil
+}
+
+// playlistImport is the outcome of importing a playlist file.
+type playlistImport struct {
+	name       string
+	songIds    []string
+	unresolved []importEntry
+}
+
+// importPlaylistFile reads a playlist file and looks up its songs on the
+// server, see resolveEntry. progress is called after each entry. The
+// playlist is named after the file.
+func importPlaylistFile(connection *subsonic.SubsonicConnection, file string, progress func(done, total int)) (playlistImport, error) {
+	file, err := expandHome(strings.TrimSpace(file))
+	if err != nil {
+		return playlistImport{}, err
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return playlistImport{}, err
+	}
+	entries, err := parsePlaylistFile(file, content)
+	if err != nil {
+		return playlistImport{}, fmt.Errorf("%s: %v", file, err)
+	}
+	if len(entries) == 0 {
+		return playlistImport{}, fmt.Errorf("%s: no songs found", file)
+	}
+
+	result := playlistImport{name: strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))}
+	for i, entry := range entries {
+		id, err := resolveEntry(connection, entry)
+		if err != nil {
+			return result, err
+		}
+		if id == "" {
+			result.unresolved = append(result.unresolved, entry)
+		} else {
+			result.songIds = append(result.songIds, id)
+		}
+		progress(i+1, len(entries))
+	}
+	return result, nil
+}
+
+// importPlaylist imports a playlist file in the background and creates a
+// server playlist with the songs that were found. Entries that weren't found
+// are logged.
+func (p *PlaylistPage) importPlaylist(file string) {
+	if p.importing {
+		p.ui.showNotice("Still importing, try again when it's done")
+		return
+	}
+	p.importing = true
+	p.ui.showNotice("Importing " + file)
+
+	p.ui.pendingWork.run(func() {
+		result, err := importPlaylistFile(p.ui.connection, file, func(done, total int) {
+			if done%10 == 0 && done < total {
+				p.ui.app.QueueUpdateDraw(func() {
+					p.ui.showNotice(fmt.Sprintf("Importing %s: %d/%d songs", file, done, total))
+				})
+			}
+		})
+		if err == nil && len(result.songIds) > 0 {
+			_, err = p.ui.connection.ImportPlaylist(result.name, result.songIds)
+		}
+
+		p.ui.app.QueueUpdateDraw(func() {
+			p.importing = false
+			if err != nil {
+				p.logger.PrintError("importPlaylist", err)
+				p.ui.showNotice("Import failed: " + err.Error())
+				return
+			}
+			for _, entry := range result.unresolved {
+				p.logger.Printf("importPlaylist: %s: not found: %s", result.name, entry)
+			}
+			switch {
+			case len(result.songIds) == 0:
+				p.ui.showNotice(fmt.Sprintf("None of the %d songs of %s were found", len(result.unresolved), result.name))
+				return
+			case len(result.unresolved) > 0:
+				p.ui.showNotice(fmt.Sprintf("Imported %s with %d songs, %d not found (see log)", result.name, len(result.songIds), len(result.unresolved)))
+			default:
+				p.ui.showNotice(fmt.Sprintf("Imported %s with %d songs", result.name, len(result.songIds)))
+			}
+			p.UpdatePlaylists()
+		})
+	})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseM3U(t *testing.T) {
+	content := "\ufeff#EXTM3U\r\n#EXTINF:245,Miles Davis - So What\r\n../Music/Miles Davis/Kind of Blue/01 So What.flac\r\n\r\n# comment\r\nC:\\Music\\Blue Train.mp3\r\n"
+	entries, err := parseM3U(strings.NewReader(content))
+	assert.NoError(t, err)
+	assert.Equal(t, []importEntry{
+		{location: "../Music/Miles Davis/Kind of Blue/01 So What.flac", title: "So What", artist: "Miles Davis"},
+		{location: "C:\\Music\\Blue Train.mp3"},
+	}, entries)
+}
+
+func TestParsePLS(t *testing.T) {
+	content := "[playlist]\nNumberOfEntries=5\nfile2=/music/b.ogg\nFile1=/music/a.ogg\nTitle1=Artist - A\nLength1=120\nTitle3=Only a title\nVersion=2\n"
+	entries, err := parsePLS(strings.NewReader(content))
+	assert.NoError(t, err)
+	assert.Equal(t, []importEntry{
+		{location: "/music/a.ogg", title: "A", artist: "Artist"},
+		{location: "/music/b.ogg"},
+		{title: "Only a title"},
+	}, entries)
+}
+
+func TestParseXSPF(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1">
+  <trackList>
+    <track><location>file:///home/me/Music/Song%20One.flac</location><title>Song One</title><creator>Band</creator></track>
+    <track><title>Song Two</title></track>
+    <track></track>
+  </trackList>
+</playlist>`
+	entries, err := parseXSPF(strings.NewReader(content))
+	assert.NoError(t, err)
+	assert.Equal(t, []importEntry{
+		{location: "file:///home/me/Music/Song%20One.flac", title: "Song One", artist: "Band"},
+		{title: "Song Two"},
+	}, entries)
+
+	_, err = parseXSPF(strings.NewReader("not xml"))
+	assert.Error(t, err)
+}
+
+func TestParsePlaylistFileSniffs(t *testing.T) {
+	entries, err := parsePlaylistFile("list.txt", []byte("  [Playlist]\nFile1=a.mp3\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []importEntry{{location: "a.mp3"}}, entries)
+
+	entries, err = parsePlaylistFile("list", []byte("<playlist><trackList><track><title>T</title></track></trackList></playlist>"))
+	assert.NoError(t, err)
+	assert.Equal(t, []importEntry{{title: "T"}}, entries)
+
+	entries, err = parsePlaylistFile("list", []byte("a.mp3\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []importEntry{{location: "a.mp3"}}, entries)
+}
+
+func TestLocationPath(t *testing.T) {
+	assert.Equal(t, "home/me/music/song one.flac", locationPath("file:///home/me/Music/Song%20One.flac"))
+	assert.Equal(t, "music/artist/song.mp3", locationPath("C:\\Music\\Artist\\Song.mp3"))
+	assert.Equal(t, "music/artist/song", locationPath("../../Music/Artist/Song"))
+	assert.Equal(t, "artist/song.mp3", locationPath("./Artist/./Song.mp3"))
+}
+
+func TestPathMatches(t *testing.T) {
+	server := "Artist/Album/01 Song.flac"
+	// absolute entry
+	assert.True(t, pathMatches(server, locationPath("/home/me/Music/Artist/Album/01 Song.flac")))
+	// relative entry without extension
+	assert.True(t, pathMatches(server, locationPath("../Album/01 Song")))
+	// converted library
+	assert.True(t, pathMatches(server, locationPath("Artist/Album/01 Song.mp3")))
+	// only whole path elements
+	assert.False(t, pathMatches(server, locationPath("1 Song.flac")))
+	assert.False(t, pathMatches(server, locationPath("Other/01 Song.flac")))
+	assert.False(t, pathMatches("", "song.flac"))
+}
+
+func TestSearchQuery(t *testing.T) {
+	assert.Equal(t, "So What", importEntry{title: "So What", location: "x.flac"}.searchQuery())
+	assert.Equal(t, "so what", importEntry{location: "/Music/01 - So What.flac"}.searchQuery())
+	assert.Equal(t, "blue in green", importEntry{location: "1-03. Blue in Green.mp3"}.searchQuery())
+	assert.Equal(t, "mr. brightside", importEntry{location: "Mr. Brightside"}.searchQuery())
+	assert.Equal(t, "", importEntry{}.searchQuery())
+}
+
+func TestStreamId(t *testing.T) {
+	assert.Equal(t, "42", streamId("https://music.example.com/rest/stream?id=42&u=me"))
+	assert.Equal(t, "", streamId("https://example.com/song.mp3?id=42"))
+	assert.Equal(t, "", streamId("/rest/stream?id=42"))
+}
+
+func TestMatchEntry(t *testing.T) {
+	songs := []subsonic.SubsonicEntity{
+		{Id: "1", Title: "Song", Artist: "Cover Band", Path: "Cover Band/Song.mp3"},
+		{Id: "2", Title: "Song", Artist: "Original", Path: "Original/Hits/Song.flac"},
+	}
+
+	song, found := matchEntry(importEntry{location: "/music/Original/Hits/Song.flac"}, songs)
+	assert.True(t, found)
+	assert.Equal(t, "2", song.Id)
+
+	song, found = matchEntry(importEntry{title: "song", artist: "original"}, songs)
+	assert.True(t, found)
+	assert.Equal(t, "2", song.Id)
+
+	song, found = matchEntry(importEntry{title: "Song"}, songs)
+	assert.True(t, found)
+	assert.Equal(t, "1", song.Id)
+
+	_, found = matchEntry(importEntry{title: "Song", artist: "Someone Else"}, songs)
+	assert.False(t, found)
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// songs shown or queued per step when loading a playlist, the first step
+// fills a screen
+const playlistChunkSize = 100
+
+// chunkBounds returns the end of the chunk of size items that starts at
+// from.
+func chunkBounds(from, n, size int) int {
+	return min(from+size, n)
+}
+
+// loadInChunks calls step for the items [from, to) of n items, size items at
+// a time. The first chunk is handled right away, the others are queued to
+// the gui goroutine one after the other, so input is handled in between.
+// step returns false to cancel the rest. Must be called from the gui
+// goroutine.
+func (ui *Ui) loadInChunks(n, size int, step func(from, to int) bool) {
+	to := chunkBounds(0, n, size)
+	if !step(0, to) || to >= n {
+		return
+	}
+
+	go func() {
+		for from := to; from < n; from = chunkBounds(from, n, size) {
+			from := from
+			done := make(chan bool, 1)
+			ui.app.QueueUpdateDraw(func() {
+				done <- step(from, chunkBounds(from, n, size))
+			})
+			if !<-done {
+				return
+			}
+		}
+	}()
+}
+
+// queueInChunks adds the songs of n items to the queue, size items at a time,
+// see loadInChunks. songsOf returns the songs of the items [from, to). The
+// first songs are added with mode, so playback starts with them, the rest
+// follow in order after the songs added before. The songs are tagged with
+// source. cancelled reports whether this was replaced by another one,
+// progress is called after each chunk.
+func (ui *Ui) queueInChunks(n, size int, mode queueMode, source string, cancelled func() bool, songsOf func(from, to int) []subsonic.SubsonicEntity, progress func(to int)) {
+	// the last song added, the next ones go after it
+	lastId := ""
+
+	ui.loadInChunks(n, size, func(from, to int) bool {
+		if cancelled() {
+			return false
+		}
+		lastId = ui.queueChunk(from, songsOf(from, to), mode, source, lastId)
+		progress(to)
+		return true
+	})
+}
+
+// fetchAndQueueInChunks is queueInChunks for items whose songs are fetched
+// from the server. fetchSongs is called in the background, the songs are
+// added from the gui goroutine. cancelled and progress are called from the
+// gui goroutine.
+func (ui *Ui) fetchAndQueueInChunks(n, size int, mode queueMode, source string, cancelled func() bool, fetchSongs func(from, to int) []subsonic.SubsonicEntity, progress func(to int)) {
+	go func() {
+		// the last song added, the next ones go after it
+		lastId := ""
+
+		for from := 0; from < n; from = chunkBounds(from, n, size) {
+			from, to := from, chunkBounds(from, n, size)
+			songs := fetchSongs(from, to)
+			done := make(chan bool, 1)
+			ui.app.QueueUpdateDraw(func() {
+				if cancelled() {
+					done <- false
+					return
+				}
+				lastId = ui.queueChunk(from, songs, mode, source, lastId)
+				progress(to)
+				done <- true
+			})
+			if !<-done {
+				return
+			}
+		}
+	}()
+}
+
+// queueChunk adds the songs of the chunk that starts at item from. The first
+// chunk is added with mode, the others after lastId, the last song added
+// before. It returns the last song added now.
+func (ui *Ui) queueChunk(from int, songs []subsonic.SubsonicEntity, mode queueMode, source, lastId string) string {
+	if from == 0 {
+		ui.startQueueAdd(mode)
+	} else if index := ui.player.QueueIndex(lastId); index >= 0 {
+		ui.startQueueInsert(index + 1)
+	} else {
+		// the songs were removed or played already
+		ui.startQueueAdd(queueAppend)
+	}
+	ui.setQueueSource(source)
+	for i := range songs {
+		ui.addSongToQueue(&songs[i])
+	}
+	ui.finishQueueAdd()
+
+	if len(songs) > 0 {
+		return songs[len(songs)-1].Id
+	}
+	return lastId
+}
+
+// songsTitle returns the title of the song list while loaded songs of total
+// are shown.
+func songsTitle(loaded, total int) string {
+	if loaded >= total {
+		return " songs "
+	}
+	return fmt.Sprintf(" songs (loading %d/%d) ", loaded, total)
+}
+
+// showPlaylistSongs fills the song list. The first songs are shown right
+// away, the rest follow in the background until another playlist is
+// selected or the page is left.
+func (p *PlaylistPage) showPlaylistSongs(playlist subsonic.SubsonicPlaylist) {
+	p.loadSeq++
+	seq := p.loadSeq
+	p.songsLoaded = false
+	entries := playlist.Entries
+
+	// the columns are aligned over all songs, not only the shown ones
+	layout := p.columns.layout()
+	for _, entity := range entries {
+		layout.add(p.columns.cells(entity, formatSongForPlaylistEntry(entity, p.ui.artistDisplay)))
+	}
+	lines := layout.lines()
+
+	p.ui.loadInChunks(len(entries), playlistChunkSize, func(from, to int) bool {
+		if seq != p.loadSeq {
+			return false
+		}
+		for i, entity := range entries[from:to] {
+			handler := makeSongHandler(&entity, p.ui, entity.Artist)
+			p.selectedPlaylist.AddItem(lines[from+i], "", 0, handler)
+		}
+		p.selectedPlaylist.SetTitle(songsTitle(to, len(entries)))
+		p.songsLoaded = to >= len(entries)
+		return true
+	})
+}
+
+// cancelLoad stops filling the song list, e.g. when the page is left.
+func (p *PlaylistPage) cancelLoad() {
+	if !p.songsLoaded {
+		p.loadSeq++
+	}
+}
+
+// resumeLoad fills the song list again if loading it was cancelled.
+func (p *PlaylistPage) resumeLoad() {
+	index := p.playlistList.GetCurrentItem()
+	if p.songsLoaded || index < 0 || index >= len(p.ui.playlists) {
+		return
+	}
+	p.handlePlaylistSelected(p.ui.playlists[index])
+}
+
+// queuePlaylistSongs adds the songs to the queue. The first songs are added
+// right away, so playback starts with them, the rest follow in the
+// background in order. Queueing another playlist stops the previous one.
+func (p *PlaylistPage) queuePlaylistSongs(entries []subsonic.SubsonicEntity, mode queueMode, source string) {
+	p.queueSeq++
+	seq := p.queueSeq
+
+	p.ui.queueInChunks(len(entries), playlistChunkSize, mode, source,
+		func() bool {
+			return seq != p.queueSeq
+		},
+		func(from, to int) []subsonic.SubsonicEntity {
+			return entries[from:to]
+		},
+		func(to int) {
+			if to < len(entries) {
+				p.ui.showNotice(fmt.Sprintf("Queueing playlist: %d/%d songs", to, len(entries)))
+			}
+		})
+}
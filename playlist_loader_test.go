@@ -0,0 +1,21 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkBounds(t *testing.T) {
+	assert.Equal(t, playlistChunkSize, chunkBounds(0, 1000, playlistChunkSize))
+	assert.Equal(t, 2*playlistChunkSize, chunkBounds(playlistChunkSize, 1000, playlistChunkSize))
+	assert.Equal(t, 30, chunkBounds(0, 30, playlistChunkSize))
+	assert.Equal(t, 0, chunkBounds(0, 0, playlistChunkSize))
+	assert.Equal(t, 10, chunkBounds(5, 12, 5))
+}
+
+func TestSongsTitle(t *testing.T) {
+	assert.Equal(t, " songs (loading 100/2500) ", songsTitle(100, 2500))
+	assert.Equal(t, " songs ", songsTitle(2500, 2500))
+	assert.Equal(t, " songs ", songsTitle(0, 0))
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// songs fetched between progress notices of a queue refresh
+const queueRefreshProgressStep = 20
+
+// refreshQueueItem updates the tags of a queued song from the server's
+// current song entry. Where it was queued from and the stream URL are kept,
+// so a playing song isn't disturbed.
+func refreshQueueItem(item *mpvplayer.QueueItem, song *subsonic.SubsonicEntity) {
+	item.Title = song.GetSongTitle()
+	item.Artist = stringOr(song.Artist, item.Artist)
+	item.AlbumArtist = stringOr(song.GetAlbumArtist(), item.AlbumArtist)
+	item.Album = stringOr(song.Album, item.Album)
+	item.AlbumId = stringOr(song.AlbumId, stringOr(song.Parent, item.AlbumId))
+	item.ArtistId = song.ArtistId
+	item.Duration = song.Duration
+	item.TrackNumber = song.Track
+	item.DiscNumber = song.DiscNumber
+	item.Year = song.Year
+	item.Genres = song.GetGenres()
+	item.Comment = song.Comment
+	item.CoverArtId = song.CoverArtId
+	item.Played = song.LastPlayed()
+	item.Type = song.Type
+}
+
+// handleRefreshQueue fetches the songs of the queue again, e.g. after their
+// tags were edited on the server, and updates them in place. Songs the server
+// no longer has are left as they are. Must be called from the gui goroutine.
+func (q *QueuePage) handleRefreshQueue() {
+	ui := q.ui
+	if q.refreshing {
+		ui.showNotice("The queue is still being refreshed")
+		return
+	}
+
+	var ids []string
+	seen := map[string]bool{}
+	for _, item := range ui.player.GetQueueCopy() {
+		if item.Id != "" && !seen[item.Id] {
+			seen[item.Id] = true
+			ids = append(ids, item.Id)
+		}
+	}
+	if len(ids) == 0 {
+		ui.showNotice("The queue is empty")
+		return
+	}
+
+	q.refreshing = true
+	ui.showNotice(fmt.Sprintf("Refreshing %d songs...", len(ids)))
+
+	go func() {
+		songs := map[string]*subsonic.SubsonicEntity{}
+		failed := 0
+		for i, id := range ids {
+			if song, err := fetchSong(ui.connection, id); err != nil {
+				ui.logger.PrintError("handleRefreshQueue", err)
+				failed++
+			} else {
+				songs[id] = song
+			}
+			if done := i + 1; done%queueRefreshProgressStep == 0 && done < len(ids) {
+				ui.app.QueueUpdateDraw(func() {
+					ui.showNotice(fmt.Sprintf("Refreshing songs: %d/%d", done, len(ids)))
+				})
+			}
+		}
+
+		ui.app.QueueUpdateDraw(func() {
+			q.refreshing = false
+			for id, song := range songs {
+				ui.player.UpdateQueueItems(id, func(item *mpvplayer.QueueItem) {
+					refreshQueueItem(item, song)
+				})
+			}
+			q.UpdateQueue()
+
+			notice := fmt.Sprintf("Refreshed %d songs", len(songs))
+			if failed > 0 {
+				notice += fmt.Sprintf(", %d couldn't be fetched, see log", failed)
+			}
+			ui.showNotice(notice)
+		})
+	}()
+}
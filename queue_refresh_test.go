@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshQueueItem(t *testing.T) {
+	item := mpvplayer.QueueItem{
+		Id:       "1",
+		Uri:      "http://server/rest/stream?id=1&maxBitRate=192",
+		Title:    "Old Title",
+		Artist:   "Old Artist",
+		Album:    "Old Album",
+		Duration: 100,
+		Source:   "playlist:Mix",
+	}
+	refreshQueueItem(&item, &subsonic.SubsonicEntity{
+		Id:       "1",
+		Title:    "New Title",
+		Artist:   "New Artist",
+		Duration: 120,
+		Year:     1999,
+	})
+
+	assert.Equal(t, "New Title", item.Title)
+	assert.Equal(t, "New Artist", item.Artist)
+	assert.Equal(t, 120, item.Duration)
+	assert.Equal(t, 1999, item.Year)
+	// not reported, kept
+	assert.Equal(t, "Old Album", item.Album)
+	// playback isn't touched
+	assert.Equal(t, "http://server/rest/stream?id=1&maxBitRate=192", item.Uri)
+	assert.Equal(t, "playlist:Mix", item.Source)
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package remote
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// FallbackArt decides what OS media controls show for songs without art.
+type FallbackArt string
+
+const (
+	// FallbackArtIcon shows a generic music icon that ships with stmps
+	FallbackArtIcon FallbackArt = "icon"
+	// FallbackArtColor shows a block of a color derived from the album, so
+	// songs of the same album look alike
+	FallbackArtColor FallbackArt = "color"
+	// FallbackArtNone shows no art
+	FallbackArtNone FallbackArt = "none"
+)
+
+//go:embed fallback_art.png
+var fallbackArtIcon []byte
+
+// size of the color block in pixels
+const fallbackArtColorSize = 64
+
+// fallbackArtImage returns the PNG to show for a song of album, nil for
+// FallbackArtNone.
+func fallbackArtImage(mode FallbackArt, album string) ([]byte, error) {
+	switch mode {
+	case FallbackArtNone:
+		return nil, nil
+	case FallbackArtColor:
+		img := image.NewNRGBA(image.Rect(0, 0, fallbackArtColorSize, fallbackArtColorSize))
+		draw.Draw(img, img.Bounds(), image.NewUniform(albumColor(album)), image.Point{}, draw.Src)
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	// unset is FallbackArtIcon
+	return fallbackArtIcon, nil
+}
+
+// albumColor derives a muted color from the album name. The hue comes from
+// a hash of the name, saturation and brightness are fixed.
+func albumColor(album string) color.NRGBA {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(album))
+	hue := float64(h.Sum32()%360) / 60
+
+	const saturation, value = 0.45, 0.6
+	chroma := value * saturation
+	x := chroma * (1 - abs(mod2(hue)-1))
+	m := value - chroma
+
+	var r, g, b float64
+	switch int(hue) {
+	case 0:
+		r, g, b = chroma, x, 0
+	case 1:
+		r, g, b = x, chroma, 0
+	case 2:
+		r, g, b = 0, chroma, x
+	case 3:
+		r, g, b = 0, x, chroma
+	case 4:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+	return color.NRGBA{
+		R: uint8((r + m) * 255),
+		G: uint8((g + m) * 255),
+		B: uint8((b + m) * 255),
+		A: 0xff,
+	}
+}
+
+func mod2(f float64) float64 {
+	return f - 2*float64(int(f/2))
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+// fallbackArtURL writes the fallback art for album to the temp directory
+// and returns its file URL, "" for FallbackArtNone. Files are reused, the
+// name depends on the content.
+func fallbackArtURL(mode FallbackArt, album string) (string, error) {
+	data, err := fallbackArtImage(mode, album)
+	if err != nil || data == nil {
+		return "", err
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("stmps-art-%x.png", h.Sum64()))
+	if _, err := os.Stat(path); err != nil {
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return "", err
+		}
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String(), nil
+}
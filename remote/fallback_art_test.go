@@ -0,0 +1,67 @@
+package remote
+
+import (
+	"bytes"
+	"image/png"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestFallbackArtImage(t *testing.T) {
+	data, err := fallbackArtImage(FallbackArtNone, "Album")
+	if err != nil || data != nil {
+		t.Errorf("expected no art, got %d bytes, %v", len(data), err)
+	}
+
+	data, err = fallbackArtImage(FallbackArtIcon, "Album")
+	if err != nil || !bytes.Equal(data, fallbackArtIcon) {
+		t.Errorf("expected the bundled icon, got %d bytes, %v", len(data), err)
+	}
+	if _, err := png.Decode(bytes.NewReader(fallbackArtIcon)); err != nil {
+		t.Errorf("bundled icon isn't a PNG: %v", err)
+	}
+
+	data, err = fallbackArtImage(FallbackArtColor, "Album")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("color block isn't a PNG: %v", err)
+	}
+	r, g, b, _ := img.At(0, 0).RGBA()
+	want := albumColor("Album")
+	if uint8(r>>8) != want.R || uint8(g>>8) != want.G || uint8(b>>8) != want.B {
+		t.Errorf("expected color %v, got %d,%d,%d", want, r>>8, g>>8, b>>8)
+	}
+}
+
+func TestAlbumColor(t *testing.T) {
+	if albumColor("Album") != albumColor("Album") {
+		t.Error("expected the same color for the same album")
+	}
+	if albumColor("Album") == albumColor("Other Album") {
+		t.Error("expected different colors for different albums")
+	}
+}
+
+func TestFallbackArtURL(t *testing.T) {
+	artURL, err := fallbackArtURL(FallbackArtNone, "Album")
+	if err != nil || artURL != "" {
+		t.Errorf("expected no URL, got %q, %v", artURL, err)
+	}
+
+	artURL, err = fallbackArtURL(FallbackArtIcon, "Album")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	u, err := url.Parse(artURL)
+	if err != nil || u.Scheme != "file" {
+		t.Fatalf("expected a file URL, got %q", artURL)
+	}
+	data, err := os.ReadFile(u.Path)
+	if err != nil || !bytes.Equal(data, fallbackArtIcon) {
+		t.Errorf("expected the icon at %s, %v", u.Path, err)
+	}
+}
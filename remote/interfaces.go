@@ -27,6 +27,8 @@ type ControlledPlayer interface {
 
 	Play() error
 	Pause() error
+	// Pauses if playing, resumes or starts playing otherwise.
+	TogglePlayPause() error
 	Stop() error
 	SeekAbsolute(int) error
 	NextTrack() error
@@ -30,6 +30,10 @@ import (
 //
 //export os_remote_command_callback
 func os_remote_command_callback(command C.Command, value C.double) {
+	if mpMediaEventRecipient == nil {
+		// deregistered, the player is shutting down
+		return
+	}
 	switch command {
 	case C.PLAY:
 		mpMediaEventRecipient.OnCommandPlay()
@@ -52,8 +56,15 @@ func os_remote_command_callback(command C.Command, value C.double) {
 
 // MPMediaHandler is the handler for MacOS media controls and system events.
 type MPMediaHandler struct {
-	player ControlledPlayer
-	logger logger.LoggerInterface
+	player      ControlledPlayer
+	logger      logger.LoggerInterface
+	fallbackArt FallbackArt
+	// clear the "Now Playing" info on stop, see ui.media-controls-clear-on-stop
+	clearOnStop bool
+
+	// the song shown until stopped, to show it again when playback resumes
+	track   TrackInterface
+	cleared bool
 }
 
 // global recipient for Object-C callbacks from command center.
@@ -62,10 +73,12 @@ var mpMediaEventRecipient *MPMediaHandler
 
 // NewMPMediaHandler creates a new MPMediaHandler instances and sets it as the current recipient
 // for incoming system events.
-func RegisterMPMediaHandler(player ControlledPlayer, logger_ logger.LoggerInterface) error {
+func RegisterMPMediaHandler(player ControlledPlayer, logger_ logger.LoggerInterface, fallbackArt FallbackArt, clearOnStop bool) error {
 	mp := &MPMediaHandler{
-		player: player,
-		logger: logger_,
+		player:      player,
+		logger:      logger_,
+		fallbackArt: fallbackArt,
+		clearOnStop: clearOnStop,
 	}
 
 	// register remote commands and set callback target
@@ -80,6 +93,10 @@ func RegisterMPMediaHandler(player ControlledPlayer, logger_ logger.LoggerInterf
 	mp.player.OnStopped(func() {
 		mp.logger.Print("OnStopped")
 		C.set_os_playback_state_stopped()
+		if mp.clearOnStop {
+			C.clear_os_now_playing_info()
+			mp.cleared = true
+		}
 	})
 
 	mp.player.OnSeek(func() {
@@ -89,6 +106,10 @@ func RegisterMPMediaHandler(player ControlledPlayer, logger_ logger.LoggerInterf
 
 	mp.player.OnPlaying(func() {
 		mp.logger.Print("OnPlaying")
+		if mp.cleared {
+			// resumed without a song change
+			mp.updateMetadata(mp.track)
+		}
 		C.set_os_playback_state_playing()
 		C.update_os_now_playing_info_position(C.double(mp.player.GetTimePos()))
 	})
@@ -102,12 +123,26 @@ func RegisterMPMediaHandler(player ControlledPlayer, logger_ logger.LoggerInterf
 	return nil
 }
 
+// UnregisterMPMediaHandler removes the remote commands and the "Now Playing"
+// info, e.g. before quitting.
+func UnregisterMPMediaHandler() {
+	if mpMediaEventRecipient == nil {
+		return
+	}
+	mpMediaEventRecipient = nil
+	C.unregister_os_remote_commands()
+}
+
 func (mp *MPMediaHandler) updateMetadata(track TrackInterface) {
-	var title, artist string
+	mp.track = track
+	mp.cleared = false
+
+	var title, artist, album string
 	var duration int
 	if track != nil && track.IsValid() {
 		title = track.GetTitle()
 		artist = track.GetArtist()
+		album = track.GetAlbum()
 		duration = track.GetDuration()
 	}
 
@@ -117,8 +152,12 @@ func (mp *MPMediaHandler) updateMetadata(track TrackInterface) {
 	cArtist := C.CString(artist)
 	defer C.free(unsafe.Pointer(cArtist))
 
-	// HACK because we don't have cover art
-	cArtURL := C.CString("https://support.apple.com/library/content/dam/edam/applecare/images/en_US/osx/mac-apple-logo-screen-icon.png")
+	// songs' cover art isn't passed on yet, so this is always the fallback
+	artURL, err := fallbackArtURL(mp.fallbackArt, album)
+	if err != nil {
+		mp.logger.PrintError("fallbackArtURL", err)
+	}
+	cArtURL := C.CString(artURL)
 	defer C.free(unsafe.Pointer(cArtURL))
 
 	cTrackDuration := C.double(duration)
@@ -135,8 +174,13 @@ func (mp *MPMediaHandler) OnCommandPause() {
 	if mp == nil || mp.player == nil {
 		return
 	}
-	if err := mp.player.Pause(); err != nil {
-		mp.logger.PrintError("Pause", err)
+	// Pause toggles, don't resume if already paused
+	if playing, err := mp.player.IsPlaying(); err != nil {
+		mp.logger.PrintError("IsPlaying", err)
+	} else if playing {
+		if err := mp.player.Pause(); err != nil {
+			mp.logger.PrintError("Pause", err)
+		}
 	}
 }
 
@@ -165,8 +209,8 @@ func (mp *MPMediaHandler) OnCommandTogglePlayPause() {
 	if mp == nil || mp.player == nil {
 		return
 	}
-	if err := mp.player.Pause(); err != nil {
-		mp.logger.PrintError("Pause", err)
+	if err := mp.player.TogglePlayPause(); err != nil {
+		mp.logger.PrintError("TogglePlayPause", err)
 	}
 }
 
@@ -0,0 +1,39 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+//go:build darwin
+
+package remote
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spezifisch/stmps/logger"
+)
+
+func TestOnCommandTogglePlayPause(t *testing.T) {
+	player := &testPlayer{playing: true}
+	mp := &MPMediaHandler{player: player, logger: logger.Init()}
+
+	mp.OnCommandTogglePlayPause()
+	if player.playing {
+		t.Error("OnCommandTogglePlayPause() didn't pause")
+	}
+	mp.OnCommandTogglePlayPause()
+	if !player.playing {
+		t.Error("OnCommandTogglePlayPause() didn't resume")
+	}
+
+	// pause doesn't resume
+	mp.OnCommandPause()
+	mp.OnCommandPause()
+	if player.playing {
+		t.Error("OnCommandPause() resumed")
+	}
+
+	want := []string{"TogglePlayPause", "TogglePlayPause", "Pause"}
+	if !reflect.DeepEqual(player.calls, want) {
+		t.Errorf("calls = %v, want %v", player.calls, want)
+	}
+}
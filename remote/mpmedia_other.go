@@ -12,7 +12,9 @@ import (
 	"github.com/spezifisch/stmps/logger"
 )
 
-func RegisterMPMediaHandler(_ ControlledPlayer, _ logger.LoggerInterface) error {
+func RegisterMPMediaHandler(_ ControlledPlayer, _ logger.LoggerInterface, _ FallbackArt, _ bool) error {
 	// MPMediaHandler only supports macOS.
 	return errors.New("unsupported platform")
 }
+
+func UnregisterMPMediaHandler() {}
@@ -33,6 +33,11 @@ typedef enum {
 */
 void register_os_remote_commands();
 
+/**
+* removes the targets added by 'register_os_remote_commands' and clears the "Now Playing" info.
+*/
+void unregister_os_remote_commands();
+
 /**
 * Go-backed callback to static function that is called when OS remote commands are received.
 * If a value is anticipated with the specified command, the 'value' argument will be non-zero.
@@ -47,6 +52,12 @@ void os_remote_command_callback(Command command, double value);
 void set_os_now_playing_info(const char *title, const char *artist, const char *coverArtFileURL, double trackDuration);
 void update_os_now_playing_info_position(double positionSeconds);
 
+/**
+ * Clears the "Now Playing" metadata (title, artist, art) so the system's
+ * "Now Playing" interface doesn't keep showing a stopped song.
+ */
+void clear_os_now_playing_info();
+
 /**
  * Setter functions for updating the global playback state.
  */
@@ -48,28 +48,44 @@ void register_os_remote_commands() {
     }];
 }
 
+void unregister_os_remote_commands() {
+    MPRemoteCommandCenter *commandCenter = [MPRemoteCommandCenter sharedCommandCenter];
+    [commandCenter.playCommand removeTarget:nil];
+    [commandCenter.pauseCommand removeTarget:nil];
+    [commandCenter.togglePlayPauseCommand removeTarget:nil];
+    [commandCenter.stopCommand removeTarget:nil];
+    [commandCenter.nextTrackCommand removeTarget:nil];
+    [commandCenter.previousTrackCommand removeTarget:nil];
+    [commandCenter.changePlaybackPositionCommand removeTarget:nil];
+
+    clear_os_now_playing_info();
+}
+
 /**
  * C bridge setting "Now Playing" information on macOS for media playback using the native APIs.
  */
 void set_os_now_playing_info(const char *title, const char *artist, const char *coverArtFileURL, double trackDuration) {
-    NSString *coverArtLocationString = [NSString stringWithUTF8String:coverArtFileURL];
-    NSURL *coverArtURL = [NSURL URLWithString:coverArtLocationString];
-    NSImage *coverArtImage = [[NSImage alloc] initWithContentsOfURL:coverArtURL];
-
-    MPMediaItemArtwork *coverArt = [[MPMediaItemArtwork alloc] initWithBoundsSize:coverArtImage.size requestHandler:^NSImage * _Nonnull(CGSize size) {
-        return coverArtImage;
-    }];
-
     MPNowPlayingInfoCenter *infoCenter = [MPNowPlayingInfoCenter defaultCenter];
-    NSDictionary *nowPlayingInfo = @{
+    NSMutableDictionary *nowPlayingInfo = [@{
         MPMediaItemPropertyTitle: [NSString stringWithUTF8String:title],
         MPMediaItemPropertyArtist: [NSString stringWithUTF8String:artist],
-                MPMediaItemPropertyArtwork: coverArt,
         MPNowPlayingInfoPropertyElapsedPlaybackTime: @(0),
         MPMediaItemPropertyPlaybackDuration: @(trackDuration) // Expects 'NSNumber'
-    };
-    
-    infoCenter.nowPlayingInfo = nowPlayingInfo;
+    } mutableCopy];
+
+    // an empty URL means no art
+    NSString *coverArtLocationString = [NSString stringWithUTF8String:coverArtFileURL];
+    if (coverArtLocationString.length > 0) {
+        NSURL *coverArtURL = [NSURL URLWithString:coverArtLocationString];
+        NSImage *coverArtImage = [[NSImage alloc] initWithContentsOfURL:coverArtURL];
+        if (coverArtImage != nil) {
+            nowPlayingInfo[MPMediaItemPropertyArtwork] = [[MPMediaItemArtwork alloc] initWithBoundsSize:coverArtImage.size requestHandler:^NSImage * _Nonnull(CGSize size) {
+                return coverArtImage;
+            }];
+        }
+    }
+
+    infoCenter.nowPlayingInfo = [nowPlayingInfo copy];
 }
 
 /**
@@ -78,11 +94,22 @@ void set_os_now_playing_info(const char *title, const char *artist, const char *
  */
 void update_os_now_playing_info_position(double positionSeconds) {
     MPNowPlayingInfoCenter *infoCenter = [MPNowPlayingInfoCenter defaultCenter];
+    if (infoCenter.nowPlayingInfo == nil) {
+        // cleared, there's nothing to update
+        return;
+    }
     NSMutableDictionary *updatedInfo = [infoCenter.nowPlayingInfo mutableCopy];
     updatedInfo[MPNowPlayingInfoPropertyElapsedPlaybackTime] = @(positionSeconds);
     infoCenter.nowPlayingInfo = [updatedInfo copy];
 }
 
+/**
+ * C bridge clearing the "Now Playing" information.
+ */
+void clear_os_now_playing_info() {
+    [MPNowPlayingInfoCenter defaultCenter].nowPlayingInfo = nil;
+}
+
 /**
  * C bridge setting the OS playback state to 'playing'.
  */
@@ -13,6 +13,12 @@ import (
 	"github.com/spezifisch/stmps/logger"
 )
 
+// bus name of the stmps MPRIS player
+const mprisName = mprisPrefix + "stmps"
+
+// MPRIS players own a bus name with this prefix
+const mprisPrefix = "org.mpris.MediaPlayer2."
+
 type MprisPlayer struct {
 	dbus   *dbus.Conn
 	player ControlledPlayer
@@ -50,7 +56,7 @@ func RegisterMprisPlayer(player ControlledPlayer, logger_ logger.LoggerInterface
 		"CanPause":       {Value: true, Writable: false, Emit: prop.EmitFalse, Callback: nil},
 		"CanPlay":        {Value: true, Writable: false, Emit: prop.EmitFalse, Callback: nil},
 		"CanSeek":        {Value: false, Writable: false, Emit: prop.EmitFalse, Callback: nil},
-		"CanGoPrevious":  {Value: false, Writable: false, Emit: prop.EmitFalse, Callback: nil},
+		"CanGoPrevious":  {Value: true, Writable: false, Emit: prop.EmitFalse, Callback: nil},
 		"Metadata":       {Value: mpp.metadata, Writable: false, Emit: prop.EmitTrue, Callback: nil},
 		"Volume":         {Value: float64(0.0), Writable: true, Emit: prop.EmitTrue, Callback: mpp.volumeChange},
 		"PlaybackStatus": {Value: "", Writable: false, Emit: prop.EmitFalse, Callback: nil},
@@ -139,8 +145,7 @@ func RegisterMprisPlayer(player ControlledPlayer, logger_ logger.LoggerInterface
 	}
 
 	// our unique name
-	name := "org.mpris.MediaPlayer2.stmps"
-	reply, err := conn.RequestName(name, dbus.NameFlagDoNotQueue)
+	reply, err := conn.RequestName(mprisName, dbus.NameFlagDoNotQueue)
 	if err != nil {
 		logger_.PrintError("conn.RequestName error", err)
 		return
@@ -203,25 +208,18 @@ func (m *MprisPlayer) Play() *dbus.Error {
 }
 
 func (m *MprisPlayer) PlayPause() *dbus.Error {
-	if playing, err := m.player.IsPlaying(); err != nil {
-		m.logger.PrintError("mpp IsPlaying", err)
+	if err := m.player.TogglePlayPause(); err != nil {
+		m.logger.PrintError("mpp TogglePlayPause", err)
 		return dbus.MakeFailedError(err)
-	} else if playing {
-		if err = m.player.Pause(); err != nil {
-			m.logger.PrintError("mpp Pause", err)
-			return dbus.MakeFailedError(err)
-		}
-	} else {
-		if err = m.player.Play(); err != nil {
-			m.logger.PrintError("mpp Play", err)
-			return dbus.MakeFailedError(err)
-		}
 	}
 	return nil
 }
 
 func (m *MprisPlayer) Previous() *dbus.Error {
-	// TODO not implemented
+	if err := m.player.PreviousTrack(); err != nil {
+		m.logger.PrintError("mpp Previous", err)
+		return dbus.MakeFailedError(err)
+	}
 	return nil
 }
 
@@ -0,0 +1,34 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package remote
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spezifisch/stmps/logger"
+)
+
+func TestMprisPlayPause(t *testing.T) {
+	player := &testPlayer{playing: true}
+	m := &MprisPlayer{player: player, logger: logger.Init()}
+
+	if err := m.PlayPause(); err != nil {
+		t.Fatalf("PlayPause() = %v", err)
+	}
+	if player.playing {
+		t.Error("PlayPause() didn't pause")
+	}
+	if err := m.PlayPause(); err != nil {
+		t.Fatalf("PlayPause() = %v", err)
+	}
+	if !player.playing {
+		t.Error("PlayPause() didn't resume")
+	}
+
+	want := []string{"TogglePlayPause", "TogglePlayPause"}
+	if !reflect.DeepEqual(player.calls, want) {
+		t.Errorf("calls = %v, want %v", player.calls, want)
+	}
+}
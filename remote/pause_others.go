@@ -0,0 +1,23 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package remote
+
+import "strings"
+
+// otherMprisPlayers returns the MPRIS players among the bus names, without
+// stmps itself.
+func otherMprisPlayers(names []string) []string {
+	var players []string
+	for _, name := range names {
+		if !strings.HasPrefix(name, mprisPrefix) {
+			continue
+		}
+		// instances may append a suffix, e.g. .instance1234
+		if name == mprisName || strings.HasPrefix(name, mprisName+".") {
+			continue
+		}
+		players = append(players, name)
+	}
+	return players
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+//go:build darwin
+
+package remote
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// macOS has no public API to control other apps' playback, so the players
+// that can be scripted are asked via AppleScript.
+var scriptablePlayers = []string{"Spotify", "Music"}
+
+// PauseOtherPlayers pauses the scriptable players that are playing. Players
+// that aren't running are not started. It returns the names of the players
+// it paused.
+func PauseOtherPlayers() (paused []string, err error) {
+	for _, app := range scriptablePlayers {
+		script := `if application "` + app + `" is running then
+	tell application "` + app + `"
+		if player state is playing then
+			pause
+			return "paused"
+		end if
+	end tell
+end if`
+		out, err := exec.Command("osascript", "-e", script).Output()
+		if err != nil {
+			return paused, err
+		}
+		if strings.TrimSpace(string(out)) == "paused" {
+			paused = append(paused, app)
+		}
+	}
+	return paused, nil
+}
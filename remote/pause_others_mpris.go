@@ -0,0 +1,40 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+//go:build !darwin
+
+package remote
+
+import (
+	"strings"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// PauseOtherPlayers pauses the other MPRIS players on the session bus that
+// are playing. It returns the names of the players it paused.
+func PauseOtherPlayers() (paused []string, err error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var names []string
+	if err = conn.BusObject().Call("org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		return nil, err
+	}
+
+	for _, name := range otherMprisPlayers(names) {
+		player := conn.Object(name, "/org/mpris/MediaPlayer2")
+		status, err := player.GetProperty("org.mpris.MediaPlayer2.Player.PlaybackStatus")
+		if err != nil || status.Value() != "Playing" {
+			continue
+		}
+		if err = player.Call("org.mpris.MediaPlayer2.Player.Pause", 0).Err; err != nil {
+			return paused, err
+		}
+		paused = append(paused, strings.TrimPrefix(name, mprisPrefix))
+	}
+	return paused, nil
+}
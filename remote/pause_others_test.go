@@ -0,0 +1,26 @@
+package remote
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOtherMprisPlayers(t *testing.T) {
+	names := []string{
+		"org.freedesktop.DBus",
+		":1.42",
+		"org.mpris.MediaPlayer2.spotify",
+		"org.mpris.MediaPlayer2.stmps",
+		"org.mpris.MediaPlayer2.stmps.instance1234",
+		"org.mpris.MediaPlayer2.stmpsx",
+		"org.mpris.MediaPlayer2.vlc",
+	}
+	want := []string{
+		"org.mpris.MediaPlayer2.spotify",
+		"org.mpris.MediaPlayer2.stmpsx",
+		"org.mpris.MediaPlayer2.vlc",
+	}
+	if got := otherMprisPlayers(names); !reflect.DeepEqual(got, want) {
+		t.Errorf("otherMprisPlayers() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package remote
+
+// testPlayer is a ControlledPlayer that only keeps the play state. Pause
+// toggles it, like mpvplayer.Player.Pause does.
+type testPlayer struct {
+	playing   bool
+	calls     []string
+	onPlaying []func()
+}
+
+func (p *testPlayer) IsSeeking() (bool, error) { return false, nil }
+func (p *testPlayer) IsPaused() (bool, error)  { return !p.playing, nil }
+func (p *testPlayer) IsPlaying() (bool, error) { return p.playing, nil }
+
+func (p *testPlayer) OnPaused(cb func())                         {}
+func (p *testPlayer) OnStopped(cb func())                        {}
+func (p *testPlayer) OnPlaying(cb func())                        { p.onPlaying = append(p.onPlaying, cb) }
+func (p *testPlayer) OnSeek(cb func())                           {}
+func (p *testPlayer) OnSongChange(cb func(track TrackInterface)) {}
+
+func (p *testPlayer) GetTimePos() float64 { return 0 }
+
+func (p *testPlayer) Play() error {
+	p.calls = append(p.calls, "Play")
+	p.playing = true
+	return nil
+}
+
+func (p *testPlayer) Pause() error {
+	p.calls = append(p.calls, "Pause")
+	p.playing = !p.playing
+	return nil
+}
+
+func (p *testPlayer) TogglePlayPause() error {
+	p.calls = append(p.calls, "TogglePlayPause")
+	p.playing = !p.playing
+	return nil
+}
+
+func (p *testPlayer) Stop() error                      { return nil }
+func (p *testPlayer) SeekAbsolute(int) error           { return nil }
+func (p *testPlayer) NextTrack() error                 { return nil }
+func (p *testPlayer) PreviousTrack() error             { return nil }
+func (p *testPlayer) SetVolume(percentValue int) error { return nil }
@@ -0,0 +1,126 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package remote
+
+import "sync"
+
+// SwitchedPlayer is a ControlledPlayer which forwards to the player that is
+// currently in control, e.g. a cast device instead of the local player. Only
+// callbacks of the player in control are passed on.
+type SwitchedPlayer struct {
+	mutex  sync.Mutex
+	player ControlledPlayer
+	// players the callbacks are registered on
+	registered map[ControlledPlayer]bool
+
+	cbOnPaused     []func()
+	cbOnStopped    []func()
+	cbOnPlaying    []func()
+	cbOnSeek       []func()
+	cbOnSongChange []func(TrackInterface)
+}
+
+var _ ControlledPlayer = (*SwitchedPlayer)(nil)
+
+func NewSwitchedPlayer(player ControlledPlayer) *SwitchedPlayer {
+	s := &SwitchedPlayer{registered: map[ControlledPlayer]bool{}}
+	s.Switch(player)
+	return s
+}
+
+// Switch puts player in control.
+func (s *SwitchedPlayer) Switch(player ControlledPlayer) {
+	s.mutex.Lock()
+	s.player = player
+	register := !s.registered[player]
+	s.registered[player] = true
+	s.mutex.Unlock()
+
+	if !register {
+		return
+	}
+	player.OnPaused(func() { s.invoke(player, func() []func() { return s.cbOnPaused }) })
+	player.OnStopped(func() { s.invoke(player, func() []func() { return s.cbOnStopped }) })
+	player.OnPlaying(func() { s.invoke(player, func() []func() { return s.cbOnPlaying }) })
+	player.OnSeek(func() { s.invoke(player, func() []func() { return s.cbOnSeek }) })
+	player.OnSongChange(func(track TrackInterface) {
+		s.mutex.Lock()
+		if s.player != player {
+			s.mutex.Unlock()
+			return
+		}
+		callbacks := s.cbOnSongChange
+		s.mutex.Unlock()
+		for _, cb := range callbacks {
+			cb(track)
+		}
+	})
+}
+
+// invoke calls the callbacks returned by get if from is in control.
+func (s *SwitchedPlayer) invoke(from ControlledPlayer, get func() []func()) {
+	s.mutex.Lock()
+	if s.player != from {
+		s.mutex.Unlock()
+		return
+	}
+	callbacks := get()
+	s.mutex.Unlock()
+	for _, cb := range callbacks {
+		cb()
+	}
+}
+
+func (s *SwitchedPlayer) current() ControlledPlayer {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.player
+}
+
+func (s *SwitchedPlayer) IsSeeking() (bool, error) { return s.current().IsSeeking() }
+func (s *SwitchedPlayer) IsPaused() (bool, error)  { return s.current().IsPaused() }
+func (s *SwitchedPlayer) IsPlaying() (bool, error) { return s.current().IsPlaying() }
+
+func (s *SwitchedPlayer) OnPaused(cb func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cbOnPaused = append(s.cbOnPaused, cb)
+}
+
+func (s *SwitchedPlayer) OnStopped(cb func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cbOnStopped = append(s.cbOnStopped, cb)
+}
+
+func (s *SwitchedPlayer) OnPlaying(cb func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cbOnPlaying = append(s.cbOnPlaying, cb)
+}
+
+func (s *SwitchedPlayer) OnSeek(cb func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cbOnSeek = append(s.cbOnSeek, cb)
+}
+
+func (s *SwitchedPlayer) OnSongChange(cb func(track TrackInterface)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cbOnSongChange = append(s.cbOnSongChange, cb)
+}
+
+func (s *SwitchedPlayer) GetTimePos() float64 { return s.current().GetTimePos() }
+
+func (s *SwitchedPlayer) Play() error                     { return s.current().Play() }
+func (s *SwitchedPlayer) Pause() error                    { return s.current().Pause() }
+func (s *SwitchedPlayer) TogglePlayPause() error          { return s.current().TogglePlayPause() }
+func (s *SwitchedPlayer) Stop() error                     { return s.current().Stop() }
+func (s *SwitchedPlayer) SeekAbsolute(position int) error { return s.current().SeekAbsolute(position) }
+func (s *SwitchedPlayer) NextTrack() error                { return s.current().NextTrack() }
+func (s *SwitchedPlayer) PreviousTrack() error            { return s.current().PreviousTrack() }
+func (s *SwitchedPlayer) SetVolume(percentValue int) error {
+	return s.current().SetVolume(percentValue)
+}
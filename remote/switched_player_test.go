@@ -0,0 +1,36 @@
+package remote
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSwitchedPlayer(t *testing.T) {
+	local, device := &testPlayer{}, &testPlayer{}
+	s := NewSwitchedPlayer(local)
+	playing := 0
+	s.OnPlaying(func() { playing++ })
+
+	_ = s.TogglePlayPause()
+	s.Switch(device)
+	_ = s.Play()
+	s.Switch(local)
+	_ = s.Pause()
+
+	if want := []string{"TogglePlayPause", "Pause"}; !reflect.DeepEqual(local.calls, want) {
+		t.Errorf("local calls = %v, want %v", local.calls, want)
+	}
+	if want := []string{"Play"}; !reflect.DeepEqual(device.calls, want) {
+		t.Errorf("device calls = %v, want %v", device.calls, want)
+	}
+
+	// callbacks are registered once and only passed on from the player in control
+	if len(local.onPlaying) != 1 || len(device.onPlaying) != 1 {
+		t.Fatalf("callbacks registered %d and %d times, want once", len(local.onPlaying), len(device.onPlaying))
+	}
+	device.onPlaying[0]()
+	local.onPlaying[0]()
+	if playing != 1 {
+		t.Errorf("OnPlaying invoked %d times, want 1", playing)
+	}
+}
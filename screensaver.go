@@ -0,0 +1,229 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"image"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/mpvplayer"
+)
+
+// how often the screensaver moves and idleness is checked
+const idleTickInterval = 2 * time.Second
+
+// size of the cover art on the screensaver in cells, it's shrunk to fit
+const (
+	screensaverCoverWidth  = 30
+	screensaverCoverHeight = 15
+)
+
+// idleState tracks the time without playback or input, see
+// ui.idle-timeout-s.
+type idleState struct {
+	timeout      time.Duration
+	lastActivity time.Time
+
+	screensaver *Screensaver
+	// focus of the main screen while the screensaver is shown
+	focus tview.Primitive
+}
+
+// Screensaver is a clock with the current song and its cover art, which
+// slowly wanders over the screen. It's shown instead of the main screen, so
+// nothing else is redrawn while it's up.
+type Screensaver struct {
+	*tview.Box
+
+	cover *tview.Image
+	song  string
+	// advanced every idleTickInterval, moves the content
+	tick  int
+	shown bool
+}
+
+func newScreensaver() *Screensaver {
+	return &Screensaver{
+		Box:   tview.NewBox().SetBackgroundColor(tcell.ColorBlack),
+		cover: tview.NewImage(),
+	}
+}
+
+// bounce moves back and forth between 0 and max as step grows.
+func bounce(max, step int) int {
+	if max <= 0 {
+		return 0
+	}
+	pos := step % (2 * max)
+	if pos > max {
+		return 2*max - pos
+	}
+	return pos
+}
+
+// screensaverSongText describes the song at the head of the queue.
+func screensaverSongText(queue mpvplayer.PlayerQueue, display ArtistDisplay) string {
+	if len(queue) == 0 {
+		return "Nothing queued"
+	}
+	song := queue[0]
+	text := song.Title
+	if artist := display.Artist(song.Artist, song.AlbumArtist); artist != "" {
+		text += " by " + artist
+	}
+	return text
+}
+
+func (s *Screensaver) Draw(screen tcell.Screen) {
+	s.Box.DrawForSubclass(screen, s)
+	x, y, width, height := s.GetInnerRect()
+
+	clock := time.Now().Format("15:04")
+	coverWidth := min(screensaverCoverWidth, width)
+	coverHeight := min(screensaverCoverHeight, height-3)
+	if coverHeight < 3 {
+		coverHeight = 0
+	}
+	blockWidth := min(width, max(coverWidth, tview.TaggedStringWidth(tview.Escape(s.song))))
+	blockHeight := coverHeight + 3
+
+	// horizontal and vertical moves at different speeds make it wander
+	left := x + bounce(width-blockWidth, s.tick)
+	top := y + bounce(height-blockHeight, s.tick/2)
+
+	if coverHeight > 0 {
+		s.cover.SetRect(left+(blockWidth-coverWidth)/2, top, coverWidth, coverHeight)
+		s.cover.Draw(screen)
+	}
+	tview.Print(screen, clock, left, top+coverHeight+1, blockWidth, tview.AlignCenter, tcell.ColorWhite)
+	tview.Print(screen, tview.Escape(s.song), left, top+coverHeight+2, blockWidth, tview.AlignCenter, tcell.ColorGray)
+}
+
+// startIdleTimer shows the screensaver after ui.idle-timeout-s without
+// playback or input. Any input hides it.
+func (ui *Ui) startIdleTimer() {
+	if ui.idle.timeout <= 0 {
+		return
+	}
+	ui.idle.lastActivity = time.Now()
+	ui.idle.screensaver = newScreensaver()
+
+	ui.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if ui.idleActivity() {
+			// the key only wakes up
+			return nil
+		}
+		return event
+	})
+	ui.app.SetMouseCapture(func(event *tcell.EventMouse, action tview.MouseAction) (*tcell.EventMouse, tview.MouseAction) {
+		if action == tview.MouseMove {
+			return event, action
+		}
+		if ui.idleActivity() {
+			return nil, action
+		}
+		return event, action
+	})
+
+	go func() {
+		ticker := time.NewTicker(idleTickInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			redraw := make(chan bool, 1)
+			ui.app.QueueUpdate(func() {
+				redraw <- ui.idleTick()
+			})
+			if <-redraw {
+				ui.app.Draw()
+			}
+		}
+	}()
+}
+
+// idleActivity records input and hides the screensaver. It returns whether
+// the screensaver was shown. Must be called from the gui goroutine.
+func (ui *Ui) idleActivity() bool {
+	ui.idle.lastActivity = time.Now()
+	if !ui.idle.screensaver.shown {
+		return false
+	}
+	ui.hideScreensaver()
+	return true
+}
+
+// idleTick shows, moves or hides the screensaver and returns whether the
+// screen must be redrawn. Must be called from the gui goroutine.
+func (ui *Ui) idleTick() bool {
+	playing := ui.isPlaybackActive()
+	if ui.idle.screensaver.shown {
+		if playing {
+			ui.idle.lastActivity = time.Now()
+			ui.hideScreensaver()
+		} else {
+			ui.idle.screensaver.tick++
+		}
+		return true
+	}
+
+	if playing {
+		ui.idle.lastActivity = time.Now()
+		return false
+	}
+	if time.Since(ui.idle.lastActivity) < ui.idle.timeout {
+		return false
+	}
+	ui.showScreensaver()
+	return true
+}
+
+// isPlaybackActive reports whether music is playing, here or on a cast
+// device.
+func (ui *Ui) isPlaybackActive() bool {
+	if ui.castRenderer != nil {
+		return true
+	}
+	playing, err := ui.player.IsPlaying()
+	return err == nil && playing
+}
+
+func (ui *Ui) showScreensaver() {
+	s := ui.idle.screensaver
+	queue := ui.player.GetQueueCopy()
+	s.song = screensaverSongText(queue, ui.artistDisplay)
+	s.tick = 0
+
+	// a single fetch, hidden cover art isn't downloaded at all
+	var art image.Image = STMPS_LOGO
+	if len(queue) > 0 && queue[0].CoverArtId != "" && ui.queuePage.IsCoverArtVisible() {
+		if cover, err := ui.connection.GetCoverArt(queue[0].CoverArtId); err == nil && cover != nil {
+			art = cover
+		}
+	}
+	s.cover.SetImage(art)
+
+	ui.idle.focus = ui.app.GetFocus()
+	s.shown = true
+	ui.app.SetRoot(s, true)
+}
+
+func (ui *Ui) hideScreensaver() {
+	s := ui.idle.screensaver
+	s.shown = false
+	s.cover.SetImage(nil)
+
+	ui.app.SetRoot(ui.rootFlex, true)
+	if ui.idle.focus != nil {
+		ui.app.SetFocus(ui.idle.focus)
+	}
+	// catch up on the cover art skipped while idle
+	ui.queuePage.changeSelection(ui.queuePage.queueList.GetSelection())
+}
+
+// isIdle reports whether the screensaver is shown, cover art isn't fetched
+// then.
+func (ui *Ui) isIdle() bool {
+	return ui.idle.screensaver != nil && ui.idle.screensaver.shown
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBounce(t *testing.T) {
+	var positions []int
+	for step := 0; step < 9; step++ {
+		positions = append(positions, bounce(3, step))
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 2, 1, 0, 1, 2}, positions)
+
+	// no room to move
+	assert.Equal(t, 0, bounce(0, 5))
+	assert.Equal(t, 0, bounce(-2, 5))
+}
+
+func TestScreensaverSongText(t *testing.T) {
+	assert.Equal(t, "Nothing queued", screensaverSongText(nil, ArtistDisplayTrack))
+
+	queue := mpvplayer.PlayerQueue{
+		{Title: "Song", Artist: "Band", AlbumArtist: "Various Artists"},
+		{Title: "Next"},
+	}
+	assert.Equal(t, "Song by Band", screensaverSongText(queue, ArtistDisplayTrack))
+	assert.Equal(t, "Song by Various Artists", screensaverSongText(queue, ArtistDisplayAlbum))
+	assert.Equal(t, "Untitled", screensaverSongText(mpvplayer.PlayerQueue{{Title: "Untitled"}}, ArtistDisplayTrack))
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/viper"
+)
+
+// name of the file with unsent scrobbles, kept next to the config file
+const scrobbleQueueFileName = "stmps-scrobbles.toml"
+
+// number of unsent scrobbles kept, unless server.scrobble-queue-size is set
+const defaultScrobbleQueueSize = 500
+
+// how often unsent scrobbles are retried
+const scrobbleRetryInterval = time.Minute
+
+// pendingScrobble is a play that couldn't be submitted yet.
+type pendingScrobble struct {
+	Id       string    `toml:"id"`
+	PlayedAt time.Time `toml:"played-at"`
+}
+
+// scrobbleQueue keeps submissions that failed, e.g. because the server
+// wasn't reachable, so they can be retried later with their original time.
+// The queue is saved after every change, so scrobbles survive a restart.
+type scrobbleQueue struct {
+	// "" keeps the queue in memory only
+	path string
+	size int
+
+	mutex   sync.Mutex
+	pending []pendingScrobble
+}
+
+type scrobbleQueueFile struct {
+	Pending []pendingScrobble `toml:"pending"`
+}
+
+func scrobbleQueuePath() string {
+	if viper.ConfigFileUsed() == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(viper.ConfigFileUsed()), scrobbleQueueFileName)
+}
+
+// loadScrobbleQueue reads the unsent scrobbles from path. A missing file is
+// an empty queue.
+func loadScrobbleQueue(path string, size int) (*scrobbleQueue, error) {
+	q := &scrobbleQueue{path: path, size: size}
+	if path == "" {
+		return q, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	} else if err != nil {
+		return q, err
+	}
+	var file scrobbleQueueFile
+	if err := toml.Unmarshal(data, &file); err != nil {
+		return q, err
+	}
+	q.pending = file.Pending
+	q.trim()
+	return q, nil
+}
+
+// Len returns the number of unsent scrobbles.
+func (q *scrobbleQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.pending)
+}
+
+// Add queues a scrobble. The oldest ones are dropped once the queue is full.
+func (q *scrobbleQueue) Add(scrobble pendingScrobble) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.pending = append(q.pending, scrobble)
+	q.trim()
+	return q.save()
+}
+
+// Flush submits the queued scrobbles, oldest first. It stops at the first
+// failed submission and keeps it and the ones after it queued. The number of
+// submitted scrobbles is returned.
+func (q *scrobbleQueue) Flush(submit func(pendingScrobble) error) (int, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	sent := 0
+	var submitErr error
+	for _, scrobble := range q.pending {
+		if submitErr = submit(scrobble); submitErr != nil {
+			break
+		}
+		sent++
+	}
+	if sent == 0 {
+		return 0, submitErr
+	}
+
+	q.pending = q.pending[sent:]
+	if err := q.save(); err != nil {
+		return sent, err
+	}
+	return sent, submitErr
+}
+
+func (q *scrobbleQueue) trim() {
+	if q.size < 0 {
+		q.size = 0
+	}
+	if len(q.pending) > q.size {
+		q.pending = q.pending[len(q.pending)-q.size:]
+	}
+}
+
+func (q *scrobbleQueue) save() error {
+	if q.path == "" {
+		return nil
+	}
+	if len(q.pending) == 0 {
+		err := os.Remove(q.path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	data, err := toml.Marshal(scrobbleQueueFile{Pending: q.pending})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(q.path, data, 0600)
+}
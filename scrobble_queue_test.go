@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrobbleQueueCap(t *testing.T) {
+	q, err := loadScrobbleQueue("", 2)
+	assert.NoError(t, err)
+
+	played := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, q.Add(pendingScrobble{Id: "1", PlayedAt: played}))
+	assert.NoError(t, q.Add(pendingScrobble{Id: "2", PlayedAt: played}))
+	assert.NoError(t, q.Add(pendingScrobble{Id: "3", PlayedAt: played}))
+	assert.Equal(t, 2, q.Len())
+
+	// the oldest is dropped
+	var sent []string
+	n, err := q.Flush(func(s pendingScrobble) error {
+		sent = append(sent, s.Id)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"2", "3"}, sent)
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestScrobbleQueueFlushStopsAtFailure(t *testing.T) {
+	q, _ := loadScrobbleQueue("", 10)
+	for _, id := range []string{"1", "2", "3"} {
+		assert.NoError(t, q.Add(pendingScrobble{Id: id}))
+	}
+
+	n, err := q.Flush(func(s pendingScrobble) error {
+		if s.Id == "2" {
+			return errors.New("offline")
+		}
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 2, q.Len())
+}
+
+func TestScrobbleQueuePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), scrobbleQueueFileName)
+	played := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	q, err := loadScrobbleQueue(path, 10)
+	assert.NoError(t, err)
+	assert.NoError(t, q.Add(pendingScrobble{Id: "1", PlayedAt: played}))
+
+	q, err = loadScrobbleQueue(path, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, q.Len())
+
+	var got pendingScrobble
+	_, err = q.Flush(func(s pendingScrobble) error {
+		got = s
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", got.Id)
+	assert.True(t, played.Equal(got.PlayedAt))
+
+	// an empty queue removes the file
+	q, err = loadScrobbleQueue(path, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, q.Len())
+}
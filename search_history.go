@@ -0,0 +1,97 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"strings"
+)
+
+// number of remembered search queries, unless client.search-history is set
+const defaultSearchHistorySize = 20
+
+// number of queries shown in the empty search field
+const searchHistoryPreview = 5
+
+// searchHistory keeps recent search queries, newest first. Like in a shell,
+// older queries can be recalled one at a time and the text typed before
+// recalling started is restored at the end.
+type searchHistory struct {
+	queries []string
+	size    int
+
+	// index of the recalled query, -1 while not recalling
+	cursor int
+	// text in the search field when recalling started
+	draft string
+}
+
+func newSearchHistory(size int, queries []string) *searchHistory {
+	h := &searchHistory{size: size, cursor: -1}
+	for i := len(queries) - 1; i >= 0; i-- {
+		h.add(queries[i])
+	}
+	return h
+}
+
+// add puts a query in front of the history. A query that's already in the
+// history is moved to the front instead of being added twice, the oldest
+// queries are dropped once the history is full.
+func (h *searchHistory) add(query string) {
+	h.cursor = -1
+	h.draft = ""
+
+	query = strings.TrimSpace(query)
+	if query == "" || h.size <= 0 {
+		return
+	}
+
+	queries := []string{query}
+	for _, q := range h.queries {
+		if q != query {
+			queries = append(queries, q)
+		}
+	}
+	if len(queries) > h.size {
+		queries = queries[:h.size]
+	}
+	h.queries = queries
+}
+
+// older returns the query before the recalled one, or false if there is none.
+// current is the text in the search field.
+func (h *searchHistory) older(current string) (string, bool) {
+	if h.cursor+1 >= len(h.queries) {
+		return "", false
+	}
+	if h.cursor < 0 {
+		h.draft = current
+	}
+	h.cursor++
+	return h.queries[h.cursor], true
+}
+
+// newer returns the query after the recalled one, or the text typed before
+// recalling started. It returns false if nothing is recalled.
+func (h *searchHistory) newer() (string, bool) {
+	if h.cursor < 0 {
+		return "", false
+	}
+	h.cursor--
+	if h.cursor < 0 {
+		return h.draft, true
+	}
+	return h.queries[h.cursor], true
+}
+
+// placeholder returns the text shown in the empty search field.
+func (h *searchHistory) placeholder() string {
+	if len(h.queries) == 0 {
+		return ""
+	}
+	recent := h.queries
+	if len(recent) > searchHistoryPreview {
+		recent = recent[:searchHistoryPreview]
+	}
+	return "recent: " + strings.Join(recent, ", ") + " (Up to recall)"
+}
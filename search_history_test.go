@@ -0,0 +1,65 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchHistoryAdd(t *testing.T) {
+	h := newSearchHistory(3, nil)
+	h.add("foo")
+	h.add("bar")
+	h.add(" foo ")
+	h.add("")
+	assert.Equal(t, []string{"foo", "bar"}, h.queries)
+
+	h.add("baz")
+	h.add("qux")
+	assert.Equal(t, []string{"qux", "baz", "foo"}, h.queries)
+
+	disabled := newSearchHistory(0, []string{"foo"})
+	assert.Empty(t, disabled.queries)
+}
+
+func TestSearchHistoryRecall(t *testing.T) {
+	h := newSearchHistory(defaultSearchHistorySize, []string{"new", "old"})
+
+	_, ok := h.newer()
+	assert.False(t, ok)
+
+	query, ok := h.older("typed")
+	assert.True(t, ok)
+	assert.Equal(t, "new", query)
+	query, ok = h.older("new")
+	assert.True(t, ok)
+	assert.Equal(t, "old", query)
+	_, ok = h.older("old")
+	assert.False(t, ok)
+
+	query, ok = h.newer()
+	assert.True(t, ok)
+	assert.Equal(t, "new", query)
+	query, ok = h.newer()
+	assert.True(t, ok)
+	assert.Equal(t, "typed", query)
+	_, ok = h.newer()
+	assert.False(t, ok)
+}
+
+func TestSearchHistoryStored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), serverStateFileName)
+	settings := serverSettings{Volume: 50, ReplayGain: "track"}
+
+	assert.NoError(t, saveServerSettings(path, "admin@http://local", settings))
+	assert.NoError(t, saveSearchHistory(path, []string{"foo", "bar"}))
+
+	queries, err := loadSearchHistory(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo", "bar"}, queries)
+
+	stored, err := loadServerSettings(path, "admin@http://local", serverSettings{})
+	assert.NoError(t, err)
+	assert.Equal(t, settings, stored)
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import "github.com/spf13/viper"
+
+// Units of the seek steps, see player.seek-unit.
+const (
+	SeekUnitSeconds = "seconds"
+	SeekUnitPercent = "percent"
+)
+
+// What percent steps do for songs of unknown length, see
+// player.seek-unknown-length.
+const (
+	SeekUnknownLengthOff     = "off"
+	SeekUnknownLengthSeconds = "seconds"
+)
+
+// default steps per unit
+var defaultSeekSteps = map[string]seekSteps{
+	SeekUnitSeconds: {unit: SeekUnitSeconds, short: 10, long: 60},
+	SeekUnitPercent: {unit: SeekUnitPercent, short: 1, long: 5},
+}
+
+// seekSteps are how far the seek keys jump: , and . by short, ; and ' by
+// long, in seconds or percent of the song.
+type seekSteps struct {
+	unit  string
+	short int
+	long  int
+	// percent steps of songs of unknown length fall back to the default
+	// seconds instead of not seeking
+	unknownLengthSeconds bool
+}
+
+// loadSeekSteps reads player.seek-unit, player.seek-short,
+// player.seek-long and player.seek-unknown-length. The config was validated
+// at startup.
+func loadSeekSteps() seekSteps {
+	unit := SeekUnitSeconds
+	if viper.GetString("player.seek-unit") == SeekUnitPercent {
+		unit = SeekUnitPercent
+	}
+	steps := defaultSeekSteps[unit]
+	if viper.IsSet("player.seek-short") {
+		steps.short = viper.GetInt("player.seek-short")
+	}
+	if viper.IsSet("player.seek-long") {
+		steps.long = viper.GetInt("player.seek-long")
+	}
+	if unit == SeekUnitPercent {
+		steps.short = min(steps.short, 100)
+		steps.long = min(steps.long, 100)
+		steps.unknownLengthSeconds = viper.GetString("player.seek-unknown-length") == SeekUnknownLengthSeconds
+	}
+	return steps
+}
+
+// seconds returns the short or long step in seconds for a song of duration
+// seconds. Percent steps of songs without a duration, e.g. radio streams,
+// are 0, or the default seconds with player.seek-unknown-length = 'seconds'.
+func (s seekSteps) seconds(long bool, duration int) int {
+	step := s.short
+	if long {
+		step = s.long
+	}
+	if s.unit != SeekUnitPercent {
+		return step
+	}
+	if duration <= 0 {
+		if !s.unknownLengthSeconds {
+			return 0
+		}
+		return defaultSeekSteps[SeekUnitSeconds].seconds(long, 0)
+	}
+	return max(1, duration*step/100)
+}
+
+// handleSeekKey seeks the current song by a step, backwards with back.
+func (ui *Ui) handleSeekKey(long, back bool) {
+	duration := 0
+	if song, err := ui.player.GetQueueItem(0); err == nil {
+		duration = song.Duration
+	}
+	if duration <= 0 {
+		// the server doesn't know the length, mpv might
+		duration = int(ui.progressWidget.duration)
+	}
+	step := ui.seekSteps.seconds(long, duration)
+	if step == 0 {
+		ui.showNotice("Can't seek by percent, the song's length is unknown")
+		return
+	}
+	if back {
+		step = -step
+	}
+
+	if ui.castRenderer != nil {
+		ui.castSeek(step)
+	} else if err := ui.player.Seek(step); err != nil {
+		ui.logger.PrintError("handleSeekKey", err)
+	}
+}
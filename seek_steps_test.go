@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeekStepsSeconds(t *testing.T) {
+	steps := seekSteps{unit: SeekUnitSeconds, short: 5, long: 30}
+	assert.Equal(t, 5, steps.seconds(false, 200))
+	assert.Equal(t, 30, steps.seconds(true, 0))
+}
+
+func TestSeekStepsPercent(t *testing.T) {
+	steps := seekSteps{unit: SeekUnitPercent, short: 1, long: 5}
+	// a 10 hour audiobook
+	assert.Equal(t, 360, steps.seconds(false, 36000))
+	assert.Equal(t, 1800, steps.seconds(true, 36000))
+	// short songs still move
+	assert.Equal(t, 1, steps.seconds(false, 30))
+	// unknown duration
+	assert.Equal(t, 0, steps.seconds(false, 0))
+	assert.Equal(t, 0, steps.seconds(true, 0))
+	steps.unknownLengthSeconds = true
+	assert.Equal(t, 10, steps.seconds(false, 0))
+	assert.Equal(t, 60, steps.seconds(true, 0))
+}
+
+func TestLoadSeekSteps(t *testing.T) {
+	loadTestConfig(t, "")
+	assert.Equal(t, defaultSeekSteps[SeekUnitSeconds], loadSeekSteps())
+
+	loadTestConfig(t, "[player]\nseek-unit = 'percent'\nseek-long = 500\n")
+	assert.Equal(t, seekSteps{unit: SeekUnitPercent, short: 1, long: 100}, loadSeekSteps())
+
+	loadTestConfig(t, "[player]\nseek-unit = 'percent'\nseek-unknown-length = 'seconds'\n")
+	assert.True(t, loadSeekSteps().unknownLengthSeconds)
+
+	// steps must be positive
+	assert.Error(t, knownConfigKeys["player.seek-short"](int64(0)))
+}
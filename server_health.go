@@ -0,0 +1,142 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+const (
+	// pings slower than this show the server as degraded
+	pingSlowLatency = time.Second
+	// failed pings in a row until the server counts as disconnected
+	pingFailuresToDisconnect = 3
+	// ping interval while disconnected, to notice the server is back soon
+	pingRetryInterval = 10 * time.Second
+)
+
+type healthState int
+
+const (
+	// no ping was answered yet
+	serverUnknown healthState = iota
+	serverConnected
+	// slow or single failed pings
+	serverDegraded
+	serverDisconnected
+)
+
+// serverHealth follows the server's reachability by pinging it, see
+// server.ping-interval-s. It's only used by the background loop.
+type serverHealth struct {
+	state   healthState
+	latency time.Duration
+	// failed pings in a row
+	failures int
+}
+
+// update records the result of a ping and returns the previous state.
+func (h *serverHealth) update(latency time.Duration, err error) healthState {
+	previous := h.state
+	if err != nil {
+		h.failures++
+		if h.failures >= pingFailuresToDisconnect {
+			h.state = serverDisconnected
+		} else if h.state != serverDisconnected {
+			h.state = serverDegraded
+		}
+		return previous
+	}
+
+	h.failures = 0
+	h.latency = latency
+	if latency > pingSlowLatency {
+		h.state = serverDegraded
+	} else {
+		h.state = serverConnected
+	}
+	return previous
+}
+
+// nextPing returns when to ping again.
+func (h *serverHealth) nextPing(interval time.Duration) time.Duration {
+	if h.state == serverDisconnected {
+		return min(interval, pingRetryInterval)
+	}
+	return interval
+}
+
+func formatLatency(latency time.Duration) string {
+	if latency < time.Second {
+		return fmt.Sprintf("%d ms", latency.Milliseconds())
+	}
+	return fmt.Sprintf("%.1f s", latency.Seconds())
+}
+
+// text returns the indicator for the top bar.
+func (h *serverHealth) text() string {
+	switch h.state {
+	case serverConnected:
+		return "[green]●[-] " + formatLatency(h.latency)
+	case serverDegraded:
+		if h.failures > 0 {
+			return "[yellow]●[-] no reply"
+		}
+		return "[yellow]●[-] " + formatLatency(h.latency)
+	case serverDisconnected:
+		return "[red]● offline[-]"
+	}
+	return ""
+}
+
+// pingServer pings the server, shows the result in the top bar and reports
+// when the connection is lost or back. Once it's back, unsent scrobbles are
+// retried. Called from the background loop, it returns when to ping again.
+func (ui *Ui) pingServer(interval time.Duration) time.Duration {
+	health := &ui.eventLoop.serverHealth
+	latency, err := ui.connection.Ping()
+	previous := health.update(latency, err)
+	if err != nil {
+		ui.logger.Printf("ping: %v", err)
+	}
+
+	text := health.text()
+	state := health.state
+	ui.app.QueueUpdateDraw(func() {
+		ui.updateAutoQuality(latency, err)
+		ui.updateServerStatus(text)
+		if state == serverDisconnected && previous != serverDisconnected {
+			ui.showNotice("Lost the connection to the server, retrying")
+		} else if previous == serverDisconnected && state != serverDisconnected {
+			ui.showNotice("Connected to the server again")
+		}
+	})
+
+	if previous == serverDisconnected && state != serverDisconnected && ui.scrobbleQueue.Len() > 0 {
+		ui.retryScrobbles()
+	}
+	return health.nextPing(interval)
+}
+
+// updateServerStatus shows the server health and the auto quality in the top
+// bar. Must be called from the gui goroutine.
+func (ui *Ui) updateServerStatus(health string) {
+	ui.serverHealthText = health
+	text := health
+	if quality := ui.autoQuality.text(ui.serverSettings.MaxBitRate); quality != "" {
+		if text != "" {
+			text += "  "
+		}
+		text += quality
+	}
+	ui.serverStatus.SetText(text)
+	if text == "" {
+		ui.topBarFlex.ResizeItem(ui.serverStatus, 0, 0)
+		return
+	}
+	ui.topBarFlex.ResizeItem(ui.serverStatus, tview.TaggedStringWidth(text)+2, 0)
+}
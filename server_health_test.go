@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerHealth(t *testing.T) {
+	var health serverHealth
+	failed := errors.New("timeout")
+
+	assert.Equal(t, serverUnknown, health.update(40*time.Millisecond, nil))
+	assert.Equal(t, serverConnected, health.state)
+	assert.Equal(t, "[green]●[-] 40 ms", health.text())
+
+	health.update(1500*time.Millisecond, nil)
+	assert.Equal(t, serverDegraded, health.state)
+	assert.Equal(t, "[yellow]●[-] 1.5 s", health.text())
+
+	// a single failure degrades, repeated ones disconnect
+	health.update(0, failed)
+	assert.Equal(t, serverDegraded, health.state)
+	assert.Equal(t, "[yellow]●[-] no reply", health.text())
+	health.update(0, failed)
+	assert.Equal(t, serverDegraded, health.update(0, failed))
+	assert.Equal(t, serverDisconnected, health.state)
+	assert.Equal(t, "[red]● offline[-]", health.text())
+	assert.Equal(t, pingRetryInterval, health.nextPing(time.Minute))
+	assert.Equal(t, 5*time.Second, health.nextPing(5*time.Second))
+
+	assert.Equal(t, serverDisconnected, health.update(0, failed))
+	assert.Equal(t, serverDisconnected, health.update(20*time.Millisecond, nil))
+	assert.Equal(t, serverConnected, health.state)
+	assert.Equal(t, time.Minute, health.nextPing(time.Minute))
+}
@@ -0,0 +1,242 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spf13/viper"
+)
+
+// name of the state file, kept next to the config file
+const serverStateFileName = "stmps-state.toml"
+
+const defaultVolume = 100
+
+// bitrates cycled through with 'b', 0 streams the original files
+var maxBitRatePresets = []int{0, 320, 192, 128}
+
+var replayGainModes = []string{mpvplayer.ReplayGainOff, mpvplayer.ReplayGainTrack, mpvplayer.ReplayGainAlbum}
+
+// serverSettings are playback settings that are remembered per server
+// profile, so that e.g. a remote server can keep transcoding while a local one
+// streams lossless.
+type serverSettings struct {
+	Volume     int    `toml:"volume"`
+	ReplayGain string `toml:"replaygain"`
+	MaxBitRate int    `toml:"max-bitrate"`
+	// IDs of songs that are never played in the random modes, sorted
+	Blacklist []string `toml:"blacklist,omitempty"`
+	// remembered gains in dB by song ID, see track_gain.go
+	TrackGains map[string]float64 `toml:"track-gains,omitempty"`
+	// playback was paused or stopped when stmps quit, see
+	// client.resume-last-session
+	Paused bool `toml:"paused,omitempty"`
+	// positions of the views by page name, only stored with
+	// ui.remember-positions
+	ViewPositions map[string]viewPosition `toml:"view-positions,omitempty"`
+}
+
+// serverState is the content of the state file.
+type serverState struct {
+	Servers map[string]serverSettings `toml:"servers"`
+	// newest first, only stored with client.save-search-history
+	SearchHistory []string `toml:"search-history,omitempty"`
+	// visibility of the panels toggled at runtime, by panel name
+	Panels map[string]bool `toml:"panels,omitempty"`
+}
+
+// names of the panels that can be hidden
+const (
+	panelCoverArt = "cover-art"
+	panelWaveform = "waveform"
+)
+
+// serverProfile identifies the server and account the settings belong to.
+func serverProfile(username, host string) string {
+	return username + "@" + host
+}
+
+// serverStatePath returns the path of the state file, or "" if no config file
+// is in use.
+func serverStatePath() string {
+	if viper.ConfigFileUsed() == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(viper.ConfigFileUsed()), serverStateFileName)
+}
+
+// defaultServerSettings returns the settings from the config, which apply to
+// servers that have no stored state yet.
+func defaultServerSettings() serverSettings {
+	settings := serverSettings{
+		Volume:     defaultVolume,
+		ReplayGain: mpvplayer.ReplayGainOff,
+		MaxBitRate: viper.GetInt("server.max-bitrate"),
+	}
+	if viper.IsSet("player.volume") {
+		settings.Volume = viper.GetInt("player.volume")
+	}
+	if viper.IsSet("player.replaygain") {
+		settings.ReplayGain = viper.GetString("player.replaygain")
+	}
+	return settings
+}
+
+func readServerState(path string) (state serverState, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return
+	}
+	err = toml.Unmarshal(data, &state)
+	return
+}
+
+// loadServerSettings returns the stored settings of a profile. defaults is
+// returned for profiles without stored settings, and in place of invalid
+// stored values.
+func loadServerSettings(path, profile string, defaults serverSettings) (serverSettings, error) {
+	if path == "" {
+		return defaults, nil
+	}
+
+	state, err := readServerState(path)
+	if err != nil {
+		return defaults, fmt.Errorf("reading %s: %v", path, err)
+	}
+	settings, ok := state.Servers[profile]
+	if !ok {
+		return defaults, nil
+	}
+
+	if settings.Volume < 0 || settings.Volume > 100 {
+		settings.Volume = defaults.Volume
+	}
+	if !containsString(replayGainModes, settings.ReplayGain) {
+		settings.ReplayGain = defaults.ReplayGain
+	}
+	if settings.MaxBitRate < 0 {
+		settings.MaxBitRate = defaults.MaxBitRate
+	}
+	for id, gain := range settings.TrackGains {
+		if math.IsNaN(gain) || math.Abs(gain) > maxTrackGain {
+			delete(settings.TrackGains, id)
+		}
+	}
+	return settings, nil
+}
+
+// saveServerSettings stores the settings of a profile. Other profiles in the
+// state file are kept.
+func saveServerSettings(path, profile string, settings serverSettings) error {
+	if path == "" {
+		return errors.New("no config file in use")
+	}
+
+	state, err := readServerState(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", path, err)
+	}
+	if state.Servers == nil {
+		state.Servers = map[string]serverSettings{}
+	}
+	state.Servers[profile] = settings
+	return writeServerState(path, state)
+}
+
+// loadSearchHistory returns the stored search queries, newest first.
+func loadSearchHistory(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	state, err := readServerState(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return state.SearchHistory, nil
+}
+
+// saveSearchHistory stores the search queries. The server settings in the
+// state file are kept.
+func saveSearchHistory(path string, queries []string) error {
+	if path == "" {
+		return errors.New("no config file in use")
+	}
+
+	state, err := readServerState(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", path, err)
+	}
+	state.SearchHistory = queries
+	return writeServerState(path, state)
+}
+
+// loadPanelVisibility returns whether a panel was last shown. stored is false
+// if the panel was never toggled.
+func loadPanelVisibility(path, panel string) (visible bool, stored bool, err error) {
+	if path == "" {
+		return
+	}
+	state, err := readServerState(path)
+	if err != nil {
+		return false, false, fmt.Errorf("reading %s: %v", path, err)
+	}
+	visible, stored = state.Panels[panel]
+	return
+}
+
+// savePanelVisibility stores whether a panel is shown. The rest of the state
+// file is kept.
+func savePanelVisibility(path, panel string, visible bool) error {
+	if path == "" {
+		return errors.New("no config file in use")
+	}
+
+	state, err := readServerState(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", path, err)
+	}
+	if state.Panels == nil {
+		state.Panels = map[string]bool{}
+	}
+	state.Panels[panel] = visible
+	return writeServerState(path, state)
+}
+
+func writeServerState(path string, state serverState) error {
+	data, err := toml.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// nextMaxBitRate returns the preset following current. Values that aren't a
+// preset continue with the first one.
+func nextMaxBitRate(current int) int {
+	for i, preset := range maxBitRatePresets {
+		if preset == current {
+			return maxBitRatePresets[(i+1)%len(maxBitRatePresets)]
+		}
+	}
+	return maxBitRatePresets[0]
+}
+
+// nextReplayGainMode returns the mode following current.
+func nextReplayGainMode(current string) string {
+	for i, mode := range replayGainModes {
+		if mode == current {
+			return replayGainModes[(i+1)%len(replayGainModes)]
+		}
+	}
+	return replayGainModes[0]
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerSettingsDefaults(t *testing.T) {
+	loadTestConfig(t, `
+[server]
+host = 'https://example.com'
+max-bitrate = 192
+
+[player]
+replaygain = 'album'
+`)
+
+	assert.Equal(t, serverSettings{Volume: defaultVolume, ReplayGain: mpvplayer.ReplayGainAlbum, MaxBitRate: 192}, defaultServerSettings())
+}
+
+func TestServerSettingsPerProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), serverStateFileName)
+	defaults := serverSettings{Volume: 80, ReplayGain: mpvplayer.ReplayGainOff}
+	local := serverSettings{Volume: 60, ReplayGain: mpvplayer.ReplayGainTrack}
+	remote := serverSettings{Volume: 90, ReplayGain: mpvplayer.ReplayGainAlbum, MaxBitRate: 128}
+
+	// no state file yet
+	settings, err := loadServerSettings(path, "admin@http://local", defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, defaults, settings)
+
+	assert.NoError(t, saveServerSettings(path, "admin@http://local", local))
+	assert.NoError(t, saveServerSettings(path, "me@https://remote", remote))
+
+	settings, err = loadServerSettings(path, "admin@http://local", defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, local, settings)
+
+	settings, err = loadServerSettings(path, "me@https://remote", defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, remote, settings)
+
+	// unknown profile
+	settings, err = loadServerSettings(path, "other@https://remote", defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, defaults, settings)
+}
+
+func TestServerSettingsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), serverStateFileName)
+	content := `
+[servers.'admin@http://local']
+volume = 150
+replaygain = 'loud'
+max-bitrate = 320
+`
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	defaults := serverSettings{Volume: 80, ReplayGain: mpvplayer.ReplayGainOff}
+	settings, err := loadServerSettings(path, "admin@http://local", defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, serverSettings{Volume: 80, ReplayGain: mpvplayer.ReplayGainOff, MaxBitRate: 320}, settings)
+}
+
+func TestNextMaxBitRate(t *testing.T) {
+	assert.Equal(t, 320, nextMaxBitRate(0))
+	assert.Equal(t, 0, nextMaxBitRate(128))
+	assert.Equal(t, 0, nextMaxBitRate(256))
+}
+
+func TestPanelVisibility(t *testing.T) {
+	path := filepath.Join(t.TempDir(), serverStateFileName)
+
+	_, stored, err := loadPanelVisibility(path, panelCoverArt)
+	assert.NoError(t, err)
+	assert.False(t, stored)
+
+	assert.NoError(t, saveServerSettings(path, "admin@http://local", serverSettings{Volume: 50}))
+	assert.NoError(t, savePanelVisibility(path, panelCoverArt, false))
+	assert.NoError(t, savePanelVisibility(path, panelWaveform, true))
+
+	visible, stored, err := loadPanelVisibility(path, panelCoverArt)
+	assert.NoError(t, err)
+	assert.True(t, stored)
+	assert.False(t, visible)
+
+	visible, stored, err = loadPanelVisibility(path, panelWaveform)
+	assert.NoError(t, err)
+	assert.True(t, stored)
+	assert.True(t, visible)
+
+	// other state is kept
+	settings, err := loadServerSettings(path, "admin@http://local", serverSettings{})
+	assert.NoError(t, err)
+	assert.Equal(t, 50, settings.Volume)
+}
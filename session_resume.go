@@ -0,0 +1,100 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
+)
+
+// ResumeMode is what client.resume-last-session restores on startup. The
+// queue and position are the play queue stmps saves on the server when it
+// quits.
+type ResumeMode string
+
+const (
+	ResumeOff ResumeMode = "off"
+	// ResumeOn restores the queue, the position in the current song and
+	// whether it was playing or paused
+	ResumeOn ResumeMode = "on"
+	// ResumePaused restores the queue and position, but starts paused
+	ResumePaused ResumeMode = "paused"
+)
+
+// resumeEntries returns the songs of a saved play queue to restore, from the
+// current one on, and the position in it. If the current song is gone, e.g.
+// because it was deleted on the server, the queue starts with its first
+// song from the start.
+func resumeEntries(queue subsonic.PlayQueue) ([]subsonic.SubsonicEntity, int) {
+	var entries []subsonic.SubsonicEntity
+	current := -1
+	for _, entry := range queue.Entries {
+		if entry.Id == "" || entry.IsDirectory {
+			continue
+		}
+		if entry.Id == queue.Current && current < 0 {
+			current = len(entries)
+		}
+		entries = append(entries, entry)
+	}
+	if current < 0 {
+		return entries, 0
+	}
+	// the queue drops played songs, see PlayNextTrack
+	return entries[current:], queue.Position
+}
+
+// resumeLastSession restores the previous session on startup, see
+// client.resume-last-session.
+func (ui *Ui) resumeLastSession() {
+	switch ResumeMode(viper.GetString("client.resume-last-session")) {
+	case ResumeOn:
+		ui.resumeSession(ui.serverSettings.Paused)
+	case ResumePaused:
+		ui.resumeSession(true)
+	}
+}
+
+// resumeSession replaces the queue with the play queue saved on the server
+// and continues the current song where it was left, paused if paused is set.
+// The queue is fetched in the background.
+func (ui *Ui) resumeSession(paused bool) {
+	go func() {
+		response, err := ui.connection.LoadPlayQueue()
+		ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ui.logger.Printf("unable to load play queue from server: %s", err)
+				ui.showNotice("Couldn't load the last queue from the server")
+				return
+			}
+			entries, position := resumeEntries(response.PlayQueue)
+			if len(entries) == 0 {
+				ui.showNotice("No saved queue to resume")
+				return
+			}
+
+			ui.player.ClearQueue()
+			// restore the queue as saved, regardless of the duplicate policy
+			restored := 0
+			for i := range entries {
+				queueItem := ui.makeQueueItem(&entries[i], "")
+				if !ui.player.AddToQueue(&queueItem) {
+					break
+				}
+				restored++
+			}
+			ui.queuePage.UpdateQueue()
+			if err := ui.player.PlayFrom(position, paused); err != nil {
+				ui.logger.PrintError("resumeSession", err)
+			}
+			if restored < len(entries) {
+				ui.showNotice(fmt.Sprintf("Resumed the first %d of %d songs, the queue is full", restored, len(entries)))
+				return
+			}
+			ui.showNotice(fmt.Sprintf("Resumed the last queue, %d songs", len(entries)))
+		})
+	}()
+}
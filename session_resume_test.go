@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResumeEntries(t *testing.T) {
+	queue := subsonic.PlayQueue{
+		Current:  "2",
+		Position: 42,
+		Entries: subsonic.SubsonicEntities{
+			{Id: "1"},
+			{Id: "dir", IsDirectory: true},
+			{Id: "2"},
+			{Id: "3"},
+		},
+	}
+	entries, position := resumeEntries(queue)
+	assert.Equal(t, subsonic.SubsonicEntities{{Id: "2"}, {Id: "3"}}, subsonic.SubsonicEntities(entries))
+	assert.Equal(t, 42, position)
+
+	// the current song is gone
+	queue.Current = "4"
+	entries, position = resumeEntries(queue)
+	assert.Equal(t, subsonic.SubsonicEntities{{Id: "1"}, {Id: "2"}, {Id: "3"}}, subsonic.SubsonicEntities(entries))
+	assert.Equal(t, 0, position)
+
+	entries, _ = resumeEntries(subsonic.PlayQueue{})
+	assert.Empty(t, entries)
+}
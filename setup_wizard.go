@@ -0,0 +1,195 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// errSetupCancelled is returned when the wizard is quit without saving.
+var errSetupCancelled = errors.New("setup cancelled")
+
+// setupSettings are the answers of the first-run wizard.
+type setupSettings struct {
+	Host          string
+	Username      string
+	Password      string
+	PlaintextAuth bool
+	Scrobble      bool
+	ConfirmQuit   bool
+}
+
+// normalize checks the settings and completes the server URL: a missing
+// scheme becomes https, a trailing slash is removed.
+func (s *setupSettings) normalize() error {
+	s.Host = strings.TrimSpace(s.Host)
+	s.Username = strings.TrimSpace(s.Username)
+	if s.Host == "" {
+		return errors.New("the server URL is missing")
+	}
+	if s.Username == "" {
+		return errors.New("the username is missing")
+	}
+
+	if !strings.Contains(s.Host, "://") {
+		s.Host = "https://" + s.Host
+	}
+	u, err := url.Parse(s.Host)
+	if err != nil {
+		return fmt.Errorf("invalid server URL: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("the server URL must look like https://music.example.com")
+	}
+	s.Host = strings.TrimSuffix(u.String(), "/")
+	return nil
+}
+
+// marshal returns the settings as a config file.
+func (s setupSettings) marshal() ([]byte, error) {
+	return toml.Marshal(map[string]any{
+		"auth": map[string]any{
+			"username":  s.Username,
+			"password":  s.Password,
+			"plaintext": s.PlaintextAuth,
+		},
+		"server": map[string]any{
+			"host":     s.Host,
+			"scrobble": s.Scrobble,
+		},
+		"ui": map[string]any{
+			"confirm-quit": s.ConfirmQuit,
+		},
+	})
+}
+
+// ping checks that the server accepts the settings.
+func (s setupSettings) ping() error {
+	connection := subsonic.Init(nil)
+	connection.SetClientInfo(clientName, clientVersion)
+	connection.Host = s.Host
+	connection.Username = s.Username
+	connection.Password = s.Password
+	connection.PlaintextAuth = s.PlaintextAuth
+
+	response, err := connection.GetServerInfo()
+	if err != nil {
+		return err
+	}
+	if response.Status != "ok" {
+		return fmt.Errorf("server error %d: %s", response.Error.Code, response.Error.Message)
+	}
+	return nil
+}
+
+// defaultConfigPath is where the wizard saves the config, one of the places
+// readConfig looks.
+func defaultConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "stmps", "stmp.toml"), nil
+}
+
+// writeSetupConfig saves the settings to path. The file contains the
+// password, so only the user may read it.
+func writeSetupConfig(path string, settings setupSettings) error {
+	data, err := settings.marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// runSetupWizard asks for the server and credentials, tests them and writes
+// the config to path. It runs its own tview application before the main one
+// is started.
+func runSetupWizard(path string) error {
+	app := tview.NewApplication()
+	saved := false
+
+	status := tview.NewTextView().
+		SetDynamicColors(true).
+		SetText("No config file was found. Enter your server's details, they're tested before saving.")
+
+	form := tview.NewForm().
+		AddInputField("Server URL", "", 40, nil, nil).
+		AddInputField("Username", "", 40, nil, nil).
+		AddPasswordField("Password", "", 40, '*', nil).
+		AddCheckbox("Plaintext auth", false, nil).
+		AddCheckbox("Scrobble plays", true, nil).
+		AddCheckbox("Confirm quit", false, nil)
+
+	settings := func() setupSettings {
+		return setupSettings{
+			Host:          form.GetFormItem(0).(*tview.InputField).GetText(),
+			Username:      form.GetFormItem(1).(*tview.InputField).GetText(),
+			Password:      form.GetFormItem(2).(*tview.InputField).GetText(),
+			PlaintextAuth: form.GetFormItem(3).(*tview.Checkbox).IsChecked(),
+			Scrobble:      form.GetFormItem(4).(*tview.Checkbox).IsChecked(),
+			ConfirmQuit:   form.GetFormItem(5).(*tview.Checkbox).IsChecked(),
+		}
+	}
+	pinging := false
+	form.AddButton("Test & Save", func() {
+		if pinging {
+			return
+		}
+		s := settings()
+		if err := s.normalize(); err != nil {
+			status.SetText("[red]" + tview.Escape(err.Error()))
+			return
+		}
+		pinging = true
+		status.SetText("Connecting to " + tview.Escape(s.Host) + "...")
+
+		go func() {
+			err := s.ping()
+			if err == nil {
+				err = writeSetupConfig(path, s)
+			}
+			app.QueueUpdateDraw(func() {
+				pinging = false
+				if err != nil {
+					status.SetText("[red]" + tview.Escape(err.Error()))
+					return
+				}
+				saved = true
+				app.Stop()
+			})
+		}()
+	})
+	form.AddButton("Quit", app.Stop)
+	form.SetCancelFunc(app.Stop)
+
+	root := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(form, 0, 1, true).
+		AddItem(status, 3, 0, false)
+	root.Box.
+		SetTitle(" stmps setup ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+
+	if err := app.SetRoot(makeModal(root, 70, 20), true).Run(); err != nil {
+		return err
+	}
+	if !saved {
+		return errSetupCancelled
+	}
+	return nil
+}
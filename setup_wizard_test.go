@@ -0,0 +1,47 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetupSettingsNormalize(t *testing.T) {
+	s := setupSettings{Host: " music.example.com/ ", Username: "alice"}
+	assert.NoError(t, s.normalize())
+	assert.Equal(t, "https://music.example.com", s.Host)
+
+	s = setupSettings{Host: "http://localhost:4533/sub/", Username: "alice"}
+	assert.NoError(t, s.normalize())
+	assert.Equal(t, "http://localhost:4533/sub", s.Host)
+
+	s = setupSettings{Host: "ftp://example.com", Username: "alice"}
+	assert.Error(t, s.normalize())
+
+	s = setupSettings{Host: "example.com", Username: " "}
+	assert.Error(t, s.normalize())
+}
+
+func TestWriteSetupConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stmps", "stmp.toml")
+	settings := setupSettings{
+		Host:        "https://music.example.com",
+		Username:    "alice",
+		Password:    "it's \"secret\"",
+		Scrobble:    true,
+		ConfirmQuit: true,
+	}
+	assert.NoError(t, writeSetupConfig(path, settings))
+
+	config := viper.New()
+	config.SetConfigFile(path)
+	assert.NoError(t, config.ReadInConfig())
+	assert.Equal(t, "alice", config.GetString("auth.username"))
+	assert.Equal(t, settings.Password, config.GetString("auth.password"))
+	assert.False(t, config.GetBool("auth.plaintext"))
+	assert.Equal(t, "https://music.example.com", config.GetString("server.host"))
+	assert.True(t, config.GetBool("server.scrobble"))
+	assert.True(t, config.GetBool("ui.confirm-quit"))
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/spezifisch/stmps/remote"
+	"github.com/spf13/viper"
+)
+
+// how long quitting waits for background work, see client.shutdown-timeout-s
+const defaultShutdownTimeout = 5 * time.Second
+
+// pendingWork tracks goroutines that quitting waits for, e.g. the event
+// loops or submissions to the server.
+type pendingWork struct {
+	wg sync.WaitGroup
+}
+
+// run calls f in a new goroutine that's waited for.
+func (w *pendingWork) run(f func()) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		f()
+	}()
+}
+
+// waitUntil waits for the goroutines until deadline and returns whether they
+// all finished.
+func (w *pendingWork) waitUntil(deadline time.Time) bool {
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func shutdownTimeout() time.Duration {
+	if viper.IsSet("client.shutdown-timeout-s") {
+		return time.Duration(viper.GetInt("client.shutdown-timeout-s")) * time.Second
+	}
+	return defaultShutdownTimeout
+}
+
+// stopBackground makes the event loops return once they're done with what
+// they're doing, and waits for them and the pending work up to timeout. It
+// returns whether everything finished.
+func (ui *Ui) stopBackground(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	ui.eventLoop.stop()
+	if !ui.eventLoop.loops.waitUntil(deadline) {
+		return false
+	}
+	ui.eventLoop.stopTimers()
+	return ui.pendingWork.waitUntil(deadline)
+}
+
+// closeRemotes deregisters the media controls, so they don't control the
+// player while it shuts down.
+func (ui *Ui) closeRemotes() {
+	if ui.mprisPlayer != nil {
+		ui.mprisPlayer.Close()
+	}
+	if runtime.GOOS == "darwin" {
+		remote.UnregisterMPMediaHandler()
+	}
+}
+
+// shutdown is called after the gui quit. It deregisters the media controls,
+// waits for the background work, e.g. scrobble submissions, and shuts mpv
+// down. If the work doesn't finish within timeout, it returns false without
+// touching mpv, which the work may still use; the caller exits anyway.
+func (ui *Ui) shutdown(timeout time.Duration) bool {
+	ui.closeRemotes()
+	if !ui.stopBackground(timeout) {
+		return false
+	}
+	ui.player.Quit()
+	return true
+}
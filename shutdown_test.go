@@ -0,0 +1,57 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/spezifisch/stmps/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func newShutdownTestUi(t *testing.T) *Ui {
+	queue, err := loadScrobbleQueue("", 10)
+	assert.NoError(t, err)
+	ui := &Ui{
+		logger:        logger.Init(),
+		scrobbleQueue: queue,
+	}
+	ui.initEventLoops()
+	return ui
+}
+
+func TestStopBackgroundWaitsForPendingWork(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ui := newShutdownTestUi(t)
+	ui.eventLoop.loops.run(ui.backgroundEventLoop)
+	finished := false
+	ui.pendingWork.run(func() {
+		time.Sleep(50 * time.Millisecond)
+		finished = true
+	})
+
+	assert.True(t, ui.stopBackground(time.Second))
+	assert.True(t, finished)
+
+	// no goroutine is left behind, they may take a moment to exit
+	after := runtime.NumGoroutine()
+	for i := 0; i < 100 && after > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+		after = runtime.NumGoroutine()
+	}
+	assert.LessOrEqual(t, after, before)
+}
+
+func TestStopBackgroundTimeout(t *testing.T) {
+	ui := newShutdownTestUi(t)
+	release := make(chan struct{})
+	ui.pendingWork.run(func() {
+		<-release
+	})
+
+	start := time.Now()
+	assert.False(t, ui.stopBackground(20*time.Millisecond))
+	assert.Less(t, time.Since(start), time.Second)
+	close(release)
+}
@@ -0,0 +1,275 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultSmartMixCount = 100
+	maxSmartMixCount     = 500
+	// maximum number of getRandomSongs requests made to fill up a mix
+	smartMixMaxRequests = 5
+	maxRating           = 5
+	// the longest not-played-months accepted, a century
+	maxNotPlayedMonths = 1200
+)
+
+// neglectedMix is offered in the builder with servers that report when songs
+// were played, see playedDatesSupported.
+var neglectedMix = smartMix{Name: "Neglected tracks", NotPlayedMonths: 6}
+
+// smartMix is a set of filters a queue is assembled from. Zero values disable
+// the respective filter. Named mixes are stored as [[smart-mix]] tables in the
+// config.
+type smartMix struct {
+	Name      string `toml:"name"`
+	Genre     string `toml:"genre,omitempty"`
+	FromYear  int    `toml:"from-year,omitempty"`
+	ToYear    int    `toml:"to-year,omitempty"`
+	MinRating int    `toml:"min-rating,omitempty"`
+	// songs played within this many months are left out, needs OpenSubsonic
+	NotPlayedMonths int `toml:"not-played-months,omitempty"`
+	Count           int `toml:"count,omitempty"`
+}
+
+func (m smartMix) String() string {
+	var parts []string
+	if m.Genre != "" {
+		parts = append(parts, m.Genre)
+	}
+	switch {
+	case m.FromYear > 0 && m.ToYear > 0:
+		parts = append(parts, fmt.Sprintf("%d-%d", m.FromYear, m.ToYear))
+	case m.FromYear > 0:
+		parts = append(parts, fmt.Sprintf("from %d", m.FromYear))
+	case m.ToYear > 0:
+		parts = append(parts, fmt.Sprintf("until %d", m.ToYear))
+	}
+	if m.MinRating > 0 {
+		parts = append(parts, fmt.Sprintf("rating >= %d", m.MinRating))
+	}
+	if m.NotPlayedMonths > 0 {
+		parts = append(parts, fmt.Sprintf("not played in %d months", m.NotPlayedMonths))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "any song")
+	}
+	return fmt.Sprintf("%s, %d songs", strings.Join(parts, ", "), m.count())
+}
+
+// count returns the number of songs to add, defaulting and capped.
+func (m smartMix) count() int {
+	switch {
+	case m.Count <= 0:
+		return defaultSmartMixCount
+	case m.Count > maxSmartMixCount:
+		return maxSmartMixCount
+	}
+	return m.Count
+}
+
+func (m smartMix) validate() error {
+	if m.FromYear < 0 || m.ToYear < 0 {
+		return errors.New("years must not be negative")
+	}
+	if m.FromYear > 0 && m.ToYear > 0 && m.FromYear > m.ToYear {
+		return fmt.Errorf("from-year %d is after to-year %d", m.FromYear, m.ToYear)
+	}
+	if m.MinRating < 0 || m.MinRating > maxRating {
+		return fmt.Errorf("min-rating %d is out of range [0, %d]", m.MinRating, maxRating)
+	}
+	if m.NotPlayedMonths < 0 || m.NotPlayedMonths > maxNotPlayedMonths {
+		return fmt.Errorf("not-played-months %d is out of range [0, %d]", m.NotPlayedMonths, maxNotPlayedMonths)
+	}
+	if m.Count < 0 || m.Count > maxSmartMixCount {
+		return fmt.Errorf("count %d is out of range [0, %d]", m.Count, maxSmartMixCount)
+	}
+	return nil
+}
+
+// parseSmartMixes decodes the smart-mix config value, which is a list of
+// tables.
+func parseSmartMixes(value interface{}) ([]smartMix, error) {
+	tables, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list of [[smart-mix]] tables, got %v", value)
+	}
+
+	var mixes []smartMix
+	names := map[string]bool{}
+	for i, item := range tables {
+		table, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("entry %d: expected a table, got %v", i+1, item)
+		}
+
+		var mix smartMix
+		for key, v := range table {
+			var err error
+			switch key {
+			case "name":
+				var isStr bool
+				if mix.Name, isStr = v.(string); !isStr {
+					err = fmt.Errorf("expected a string, got %v", v)
+				}
+			case "genre":
+				var isStr bool
+				if mix.Genre, isStr = v.(string); !isStr {
+					err = fmt.Errorf("expected a string, got %v", v)
+				}
+			case "from-year":
+				mix.FromYear, err = toInt(v)
+			case "to-year":
+				mix.ToYear, err = toInt(v)
+			case "min-rating":
+				mix.MinRating, err = toInt(v)
+			case "not-played-months":
+				mix.NotPlayedMonths, err = toInt(v)
+			case "count":
+				mix.Count, err = toInt(v)
+			default:
+				err = errors.New("unknown property")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("entry %d: %s: %v", i+1, key, err)
+			}
+		}
+
+		if mix.Name == "" {
+			return nil, fmt.Errorf("entry %d: name is required", i+1)
+		}
+		if names[mix.Name] {
+			return nil, fmt.Errorf("entry %d: name %q is used twice", i+1, mix.Name)
+		}
+		names[mix.Name] = true
+		if err := mix.validate(); err != nil {
+			return nil, fmt.Errorf("entry %d: %v", i+1, err)
+		}
+		mixes = append(mixes, mix)
+	}
+	return mixes, nil
+}
+
+func toInt(value interface{}) (int, error) {
+	i, err := toInt64(value)
+	return int(i), err
+}
+
+func isSmartMixList(value interface{}) error {
+	_, err := parseSmartMixes(value)
+	return err
+}
+
+// loadSmartMixes returns the named mixes from the config. Errors have already
+// been reported by validateConfig.
+func loadSmartMixes() []smartMix {
+	if !viper.IsSet("smart-mix") {
+		return nil
+	}
+	mixes, _ := parseSmartMixes(viper.Get("smart-mix"))
+	return mixes
+}
+
+// appendSmartMixToConfig adds a [[smart-mix]] table to the end of the config
+// file. The file is appended to instead of rewritten to keep its comments and
+// formatting.
+func appendSmartMixToConfig(path string, mix smartMix) error {
+	if path == "" {
+		return errors.New("no config file in use")
+	}
+
+	data, err := toml.Marshal(map[string][]smartMix{"smart-mix": {mix}})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append([]byte("\n"), data...)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// fetchSmartMixSongs assembles the songs of a mix. A genre-only mix starts
+// from all songs of the genre, otherwise random songs matching genre and
+// years are requested until enough songs pass the rating filter. Songs the
+// content filter blocks are left out.
+func fetchSmartMixSongs(connection *subsonic.SubsonicConnection, mix smartMix, filter contentFilter) ([]subsonic.SubsonicEntity, error) {
+	var candidates []subsonic.SubsonicEntity
+
+	if mix.Genre != "" && mix.FromYear == 0 && mix.ToYear == 0 {
+		response, err := connection.GetSongsByGenre(mix.Genre, maxSmartMixCount, 0)
+		if err != nil {
+			return nil, err
+		}
+		candidates = filter.filterSongs(response.SongsByGenre.Song)
+	} else {
+		seen := map[string]bool{}
+		for i := 0; i < smartMixMaxRequests; i++ {
+			response, err := connection.GetFilteredRandomSongs(mix.Genre, mix.FromYear, mix.ToYear, maxSmartMixCount)
+			if err != nil {
+				return nil, err
+			}
+
+			added := 0
+			for _, song := range response.RandomSongs.Song {
+				if !seen[song.Id] {
+					seen[song.Id] = true
+					added++
+					if filter.allowsSong(&song) {
+						candidates = append(candidates, song)
+					}
+				}
+			}
+			// the server has no more matching songs
+			if added == 0 || len(filterSmartMixSongs(candidates, mix)) >= mix.count() {
+				break
+			}
+		}
+	}
+
+	songs := filterSmartMixSongs(candidates, mix)
+	rand.Shuffle(len(songs), func(i, j int) {
+		songs[i], songs[j] = songs[j], songs[i]
+	})
+	if len(songs) > mix.count() {
+		songs = songs[:mix.count()]
+	}
+	return songs, nil
+}
+
+// filterSmartMixSongs drops directories, duplicates, songs rated below the
+// minimum rating of the mix and songs played recently. Unrated songs count as
+// rating 0, songs without a played date as never played.
+func filterSmartMixSongs(songs []subsonic.SubsonicEntity, mix smartMix) []subsonic.SubsonicEntity {
+	var filtered []subsonic.SubsonicEntity
+	seen := map[string]bool{}
+	playedBefore := time.Now().AddDate(0, -mix.NotPlayedMonths, 0)
+	for _, song := range songs {
+		if song.IsDirectory || seen[song.Id] || song.UserRating < mix.MinRating {
+			continue
+		}
+		if mix.NotPlayedMonths > 0 && song.LastPlayed().After(playedBefore) {
+			continue
+		}
+		seen[song.Id] = true
+		filtered = append(filtered, song)
+	}
+	return filtered
+}
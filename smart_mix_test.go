@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const smartMixTestConfig = `
+[auth]
+username = 'admin'
+password = 'password'
+
+[server]
+host = 'https://example.com'
+
+[[smart-mix]]
+name = 'rock'
+genre = 'Rock'
+min-rating = 3
+`
+
+func TestSmartMixConfig(t *testing.T) {
+	path := loadTestConfig(t, smartMixTestConfig)
+
+	warnings, err := validateConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, []smartMix{{Name: "rock", Genre: "Rock", MinRating: 3}}, loadSmartMixes())
+
+	saved := smartMix{Name: "90s", FromYear: 1990, ToYear: 1999, Count: 20}
+	assert.NoError(t, appendSmartMixToConfig(path, saved))
+	assert.NoError(t, viper.ReadInConfig())
+	assert.Equal(t, []smartMix{{Name: "rock", Genre: "Rock", MinRating: 3}, saved}, loadSmartMixes())
+}
+
+func TestSmartMixConfigInvalid(t *testing.T) {
+	loadTestConfig(t, smartMixTestConfig+`
+[[smart-mix]]
+name = 'rock'
+from-year = 2000
+to-year = 1990
+`)
+
+	_, err := validateConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "smart-mix: entry 2")
+}
+
+func TestFilterSmartMixSongs(t *testing.T) {
+	songs := []subsonic.SubsonicEntity{
+		{Id: "1", UserRating: 5},
+		{Id: "2", UserRating: 2},
+		{Id: "1", UserRating: 5},
+		{Id: "3"},
+		{Id: "4", UserRating: 3, IsDirectory: true},
+		{Id: "5", UserRating: 3},
+	}
+
+	ids := func(songs []subsonic.SubsonicEntity) (ids []string) {
+		for _, song := range songs {
+			ids = append(ids, song.Id)
+		}
+		return
+	}
+	assert.Equal(t, []string{"1", "5"}, ids(filterSmartMixSongs(songs, smartMix{MinRating: 3})))
+	assert.Equal(t, []string{"1", "2", "3", "5"}, ids(filterSmartMixSongs(songs, smartMix{})))
+}
+
+func TestFilterSmartMixSongsNotPlayed(t *testing.T) {
+	recently := time.Now().AddDate(0, -1, 0).UTC().Format(time.RFC3339)
+	longAgo := time.Now().AddDate(-1, 0, 0).UTC().Format(time.RFC3339)
+	songs := []subsonic.SubsonicEntity{
+		{Id: "1", Played: recently},
+		{Id: "2", Played: longAgo},
+		// never played
+		{Id: "3"},
+	}
+
+	var ids []string
+	for _, song := range filterSmartMixSongs(songs, smartMix{NotPlayedMonths: 6}) {
+		ids = append(ids, song.Id)
+	}
+	assert.Equal(t, []string{"2", "3"}, ids)
+
+	mixes, err := parseSmartMixes([]interface{}{map[string]interface{}{"name": "old", "not-played-months": int64(12)}})
+	assert.NoError(t, err)
+	assert.Equal(t, []smartMix{{Name: "old", NotPlayedMonths: 12}}, mixes)
+	assert.Equal(t, "not played in 12 months, 100 songs", mixes[0].String())
+}
@@ -0,0 +1,269 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
+)
+
+// Sort keys available for each kind of list. The first key is the default.
+var (
+	artistSortKeys = []string{"name", "album-count"}
+	albumSortKeys  = []string{"name", "year", "artist"}
+	songSortKeys   = []string{"track", "title", "artist", "duration", sortKeyPlayed}
+)
+
+// sorts songs by when they were last played, never played songs first.
+// Only OpenSubsonic servers report it, see hide.
+const sortKeyPlayed = "played"
+
+const (
+	sortAscending  = "asc"
+	sortDescending = "desc"
+)
+
+// sortOrder is the sort key and direction of one kind of list.
+type sortOrder struct {
+	keys       []string // available keys
+	key        string
+	descending bool
+	// keys the server doesn't support, skipped by cycle
+	hidden []string
+}
+
+// sortOrders holds the sort order of the artist, album and song lists of a
+// page. Every page has its own copy, so changing the order on one page
+// doesn't affect the others.
+type sortOrders struct {
+	artists sortOrder
+	albums  sortOrder
+	songs   sortOrder
+
+	// leading articles skipped when comparing artist names, see
+	// sort.ignore-articles
+	ignoreArticles  bool
+	ignoredArticles []string
+}
+
+// loadSortOrders reads the [sort] config section. Invalid values have already
+// been reported by validateConfig, they fall back to the defaults here.
+func loadSortOrders() sortOrders {
+	return sortOrders{
+		artists: loadSortOrder("artists", artistSortKeys),
+		albums:  loadSortOrder("albums", albumSortKeys),
+		songs:   loadSortOrder("songs", songSortKeys),
+
+		// on unless turned off
+		ignoreArticles: !viper.IsSet("sort.ignore-articles") || viper.GetBool("sort.ignore-articles"),
+	}
+}
+
+func loadSortOrder(name string, keys []string) sortOrder {
+	order := sortOrder{keys: keys, key: keys[0]}
+	if key := viper.GetString("sort." + name); containsString(keys, key) {
+		order.key = key
+	}
+	order.descending = viper.GetString("sort."+name+"-direction") == sortDescending
+	return order
+}
+
+// cycle switches to the next sort key that isn't hidden, keeping the
+// direction.
+func (o *sortOrder) cycle() {
+	for i, key := range o.keys {
+		if key == o.key {
+			for j := 1; j < len(o.keys); j++ {
+				if next := o.keys[(i+j)%len(o.keys)]; !containsString(o.hidden, next) {
+					o.key = next
+					return
+				}
+			}
+			return
+		}
+	}
+	o.key = o.keys[0]
+}
+
+// hide leaves key out when cycling, e.g. because the server lacks the data
+// to sort by it. If it's the current key, the default key is used instead.
+func (o *sortOrder) hide(key string) {
+	o.hidden = append(o.hidden, key)
+	if o.key == key {
+		o.key = o.keys[0]
+	}
+}
+
+func (o *sortOrder) reverse() {
+	o.descending = !o.descending
+}
+
+func (o sortOrder) String() string {
+	direction := sortAscending
+	if o.descending {
+		direction = sortDescending
+	}
+	return fmt.Sprintf("%s %s", o.key, direction)
+}
+
+// sortStable sorts slice with the compare function, which returns a
+// negative number, zero or a positive number like strings.Compare. Elements
+// which compare equal keep their previous (server) order in both directions.
+func (o sortOrder) sortStable(slice interface{}, compare func(i, j int) int) {
+	sort.SliceStable(slice, func(i, j int) bool {
+		if o.descending {
+			return compare(i, j) > 0
+		}
+		return compare(i, j) < 0
+	})
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFold(a, b string) int {
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// sortArtists returns a sorted copy of artists.
+func (o sortOrders) sortArtists(artists []subsonic.SubsonicArtist) []subsonic.SubsonicArtist {
+	sorted := append([]subsonic.SubsonicArtist(nil), artists...)
+	o.artists.sortStable(sorted, func(i, j int) int {
+		return o.compareArtists(sorted[i].Name, sorted[i].AlbumCount, sorted[j].Name, sorted[j].AlbumCount)
+	})
+	return sorted
+}
+
+// sortSearchArtists sorts artist search results in place.
+func (o sortOrders) sortSearchArtists(artists []*subsonic.Artist) {
+	o.artists.sortStable(artists, func(i, j int) int {
+		return o.compareArtists(artists[i].Name, artists[i].AlbumCount, artists[j].Name, artists[j].AlbumCount)
+	})
+}
+
+func (o sortOrders) compareArtists(nameA string, albumCountA int, nameB string, albumCountB int) int {
+	if o.artists.key == "album-count" {
+		return compareInt(albumCountA, albumCountB)
+	}
+	return o.compareArtistNames(nameA, nameB)
+}
+
+// compareArtistNames compares artist names without their leading articles,
+// so "The Beatles" sorts as "Beatles".
+func (o sortOrders) compareArtistNames(a, b string) int {
+	return compareFold(o.artistSortName(a), o.artistSortName(b))
+}
+
+// artistSortName returns name without the first of the ignored articles it
+// starts with. Names that are only an article are kept.
+func (o sortOrders) artistSortName(name string) string {
+	if !o.ignoreArticles {
+		return name
+	}
+	for _, article := range o.ignoredArticles {
+		if len(name) > len(article)+1 && name[len(article)] == ' ' && strings.EqualFold(name[:len(article)], article) {
+			return strings.TrimLeft(name[len(article)+1:], " ")
+		}
+	}
+	return name
+}
+
+// parseIgnoredArticles splits the ignoredArticles of getIndexes and
+// getArtists, e.g. "The El La Los Las Le Les".
+func parseIgnoredArticles(text string) []string {
+	return strings.Fields(text)
+}
+
+// sortSearchAlbums sorts album search results in place.
+func (o sortOrders) sortSearchAlbums(albums []*subsonic.Album) {
+	o.albums.sortStable(albums, func(i, j int) int {
+		a, b := albums[i], albums[j]
+		switch o.albums.key {
+		case "year":
+			return compareInt(a.Year, b.Year)
+		case "artist":
+			return o.compareArtistNames(a.Artist, b.Artist)
+		}
+		return compareFold(a.Name, b.Name)
+	})
+}
+
+// sortEntities returns a sorted copy of a directory listing. Directories
+// (albums) come first and are sorted with the album order, songs follow and
+// are sorted with the song order.
+func (o sortOrders) sortEntities(entities subsonic.SubsonicEntities) subsonic.SubsonicEntities {
+	var directories, songs subsonic.SubsonicEntities
+	for _, entity := range entities {
+		if entity.IsDirectory {
+			directories = append(directories, entity)
+		} else {
+			songs = append(songs, entity)
+		}
+	}
+
+	o.albums.sortStable(directories, func(i, j int) int {
+		a, b := directories[i], directories[j]
+		switch o.albums.key {
+		case "year":
+			return compareInt(a.Year, b.Year)
+		case "artist":
+			return o.compareArtistNames(a.Artist, b.Artist)
+		}
+		return compareFold(a.Title, b.Title)
+	})
+	o.sortSongs(songs)
+
+	return append(directories, songs...)
+}
+
+// sortSongs sorts songs in place.
+func (o sortOrders) sortSongs(songs subsonic.SubsonicEntities) {
+	o.songs.sortStable(songs, func(i, j int) int {
+		return compareSongs(o.songs.key, &songs[i], &songs[j])
+	})
+}
+
+// sortSearchSongs sorts song search results in place.
+func (o sortOrders) sortSearchSongs(songs []*subsonic.SubsonicEntity) {
+	o.songs.sortStable(songs, func(i, j int) int {
+		return compareSongs(o.songs.key, songs[i], songs[j])
+	})
+}
+
+func compareSongs(key string, a, b *subsonic.SubsonicEntity) int {
+	switch key {
+	case "title":
+		return compareFold(a.GetSongTitle(), b.GetSongTitle())
+	case "artist":
+		return compareFold(a.Artist, b.Artist)
+	case "duration":
+		return compareInt(a.Duration, b.Duration)
+	case sortKeyPlayed:
+		return a.LastPlayed().Compare(b.LastPlayed())
+	}
+	if a.DiscNumber != b.DiscNumber {
+		return compareInt(a.DiscNumber, b.DiscNumber)
+	}
+	return compareInt(a.Track, b.Track)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
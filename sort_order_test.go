@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func entityIds(entities subsonic.SubsonicEntities) (ids []string) {
+	for _, e := range entities {
+		ids = append(ids, e.Id)
+	}
+	return
+}
+
+func TestSortEntitiesIsStable(t *testing.T) {
+	entities := subsonic.SubsonicEntities{
+		{Id: "s1", Title: "b", Track: 2},
+		{Id: "a1", Title: "Later", Year: 2001, IsDirectory: true},
+		{Id: "s2", Title: "a", Track: 1},
+		{Id: "a2", Title: "earlier", Year: 1999, IsDirectory: true},
+		{Id: "a3", Title: "also later", Year: 2001, IsDirectory: true},
+	}
+
+	orders := sortOrders{
+		albums: sortOrder{keys: albumSortKeys, key: "year"},
+		songs:  sortOrder{keys: songSortKeys, key: "track"},
+	}
+	sorted := orders.sortEntities(entities)
+	assert.Equal(t, []string{"a2", "a1", "a3", "s2", "s1"}, entityIds(sorted))
+
+	// equal keys keep server order in descending order, too
+	orders.albums.reverse()
+	sorted = orders.sortEntities(entities)
+	assert.Equal(t, []string{"a1", "a3", "a2", "s2", "s1"}, entityIds(sorted))
+
+	// the input isn't modified
+	assert.Equal(t, []string{"s1", "a1", "s2", "a2", "a3"}, entityIds(entities))
+
+	orders.albums.cycle()
+	assert.Equal(t, "artist desc", orders.albums.String())
+}
+
+func TestSortSongsByPlayed(t *testing.T) {
+	songs := subsonic.SubsonicEntities{
+		{Id: "recent", Played: "2024-05-01T10:00:00Z"},
+		{Id: "never"},
+		{Id: "old", Played: "2021-01-01T10:00:00.123Z"},
+	}
+	orders := sortOrders{songs: sortOrder{keys: songSortKeys, key: sortKeyPlayed}}
+	orders.sortSongs(songs)
+	assert.Equal(t, []string{"never", "old", "recent"}, entityIds(songs))
+}
+
+func TestSortOrderHide(t *testing.T) {
+	order := sortOrder{keys: songSortKeys, key: sortKeyPlayed}
+	order.hide(sortKeyPlayed)
+	assert.Equal(t, "track", order.key)
+
+	order.key = "duration"
+	order.cycle()
+	assert.Equal(t, "track", order.key)
+}
+
+func TestSortArtistsIgnoresArticles(t *testing.T) {
+	artists := []subsonic.SubsonicArtist{
+		{Id: "the-beatles", Name: "The Beatles"},
+		{Id: "abba", Name: "ABBA"},
+		{Id: "la-oreja", Name: "la Oreja de Van Gogh"},
+		{Id: "the", Name: "The"},
+		{Id: "theory", Name: "Theory of a Deadman"},
+	}
+	orders := sortOrders{
+		artists:         sortOrder{keys: artistSortKeys, key: "name"},
+		ignoreArticles:  true,
+		ignoredArticles: parseIgnoredArticles("The El La Los Las Le Les"),
+	}
+	ids := func(artists []subsonic.SubsonicArtist) (ids []string) {
+		for _, artist := range artists {
+			ids = append(ids, artist.Id)
+		}
+		return
+	}
+	assert.Equal(t, []string{"abba", "the-beatles", "la-oreja", "the", "theory"}, ids(orders.sortArtists(artists)))
+
+	orders.ignoreArticles = false
+	assert.Equal(t, []string{"abba", "la-oreja", "the", "the-beatles", "theory"}, ids(orders.sortArtists(artists)))
+}
+
+func TestSortAlbumsByArtistIgnoresArticles(t *testing.T) {
+	entities := subsonic.SubsonicEntities{
+		{Id: "who", Artist: "The Who", IsDirectory: true},
+		{Id: "pixies", Artist: "Pixies", IsDirectory: true},
+	}
+	orders := sortOrders{
+		albums:          sortOrder{keys: albumSortKeys, key: "artist"},
+		ignoreArticles:  true,
+		ignoredArticles: []string{"The"},
+	}
+	assert.Equal(t, []string{"pixies", "who"}, entityIds(orders.sortEntities(entities)))
+}
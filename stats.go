@@ -0,0 +1,180 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// session play counts that are announced with a notice
+var sessionMilestones = []int{10, 25, 50, 100, 250, 500, 1000}
+
+// sessionPlay is a song played in this session.
+type sessionPlay struct {
+	song mpvplayer.QueueItem
+	// seconds the song was heard
+	seconds int
+}
+
+// historyEntry is a song that stopped playing in this session, also when it
+// was skipped.
+type historyEntry struct {
+	song mpvplayer.QueueItem
+	// when the song started playing
+	started time.Time
+	// seconds the song was heard
+	seconds   int
+	completed bool
+}
+
+// sessionStats collects what was played since stmps started. Songs count as
+// played once they were heard for scrobbleMinDuration seconds or until
+// their end. Must only be used from the gui goroutine.
+type sessionStats struct {
+	started time.Time
+	// seconds of audio heard in total
+	listened int
+	plays    []sessionPlay
+	// every song that ended, for the history export
+	history []historyEntry
+
+	// song being heard, the seconds heard and its last position
+	currentId      string
+	currentStarted time.Time
+	currentSeconds int
+	lastPosition   int64
+}
+
+func newSessionStats() *sessionStats {
+	return &sessionStats{started: time.Now()}
+}
+
+// observePosition is called with the playback position of the current song.
+// Time is counted when the position moves forward by a small step, so that
+// seeks don't count as listening.
+func (s *sessionStats) observePosition(songId string, position int64) {
+	if songId != s.currentId {
+		s.currentId = songId
+		s.currentStarted = time.Now()
+		s.currentSeconds = 0
+		s.lastPosition = position
+		return
+	}
+
+	delta := position - s.lastPosition
+	s.lastPosition = position
+	if delta > 0 && delta <= 2 {
+		s.listened += int(delta)
+		s.currentSeconds += int(delta)
+	}
+}
+
+// trackEnded records a song that stopped playing. It returns true if the
+// number of played songs reached a milestone.
+func (s *sessionStats) trackEnded(song mpvplayer.QueueItem, completed bool) bool {
+	heard := 0
+	started := time.Now()
+	if song.Id == s.currentId {
+		heard = s.currentSeconds
+		started = s.currentStarted
+	}
+	s.currentId = ""
+	s.currentSeconds = 0
+	s.history = append(s.history, historyEntry{song: song, started: started, seconds: heard, completed: completed})
+
+	if !completed && heard < scrobbleMinDuration {
+		return false
+	}
+	s.plays = append(s.plays, sessionPlay{song: song, seconds: heard})
+	return containsInt(sessionMilestones, len(s.plays))
+}
+
+// topArtists returns the most played artists of the session.
+func (s *sessionStats) topArtists(n int) []namedCount {
+	counts := map[string]int{}
+	for _, play := range s.plays {
+		counts[play.song.Artist]++
+	}
+	return topCounts(counts, n)
+}
+
+// namedCount is an entry of a top list.
+type namedCount struct {
+	name  string
+	count int
+}
+
+// topCounts returns the n entries with the highest counts, ties sorted by
+// name.
+func topCounts(counts map[string]int, n int) []namedCount {
+	entries := make([]namedCount, 0, len(counts))
+	for name, count := range counts {
+		if count > 0 {
+			entries = append(entries, namedCount{name: name, count: count})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+		return entries[i].name < entries[j].name
+	})
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// allTimeStats are play counts from the server.
+type allTimeStats struct {
+	albums  []subsonic.Album
+	artists []namedCount
+	songs   []subsonic.SubsonicEntity
+}
+
+// aggregateAllTimeStats builds the top lists from the most played albums and
+// their songs. Artist counts are the sums of their albums' play counts.
+func aggregateAllTimeStats(albums []subsonic.Album, songs []subsonic.SubsonicEntity, n int) allTimeStats {
+	stats := allTimeStats{}
+
+	stats.albums = append([]subsonic.Album(nil), albums...)
+	sort.SliceStable(stats.albums, func(i, j int) bool {
+		return stats.albums[i].PlayCount > stats.albums[j].PlayCount
+	})
+	if len(stats.albums) > n {
+		stats.albums = stats.albums[:n]
+	}
+
+	artistCounts := map[string]int{}
+	for _, album := range albums {
+		artistCounts[album.Artist] += album.PlayCount
+	}
+	stats.artists = topCounts(artistCounts, n)
+
+	for _, song := range songs {
+		if song.PlayCount > 0 {
+			stats.songs = append(stats.songs, song)
+		}
+	}
+	sort.SliceStable(stats.songs, func(i, j int) bool {
+		return stats.songs[i].PlayCount > stats.songs[j].PlayCount
+	})
+	if len(stats.songs) > n {
+		stats.songs = stats.songs[:n]
+	}
+	return stats
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionStats(t *testing.T) {
+	s := newSessionStats()
+	song := mpvplayer.QueueItem{Id: "1", Artist: "A"}
+
+	// listened for 40 seconds, with a seek in between
+	s.observePosition("1", 0)
+	for pos := int64(1); pos <= 20; pos++ {
+		s.observePosition("1", pos)
+	}
+	s.observePosition("1", 100)
+	for pos := int64(101); pos <= 120; pos++ {
+		s.observePosition("1", pos)
+	}
+	assert.False(t, s.trackEnded(song, false))
+	assert.Equal(t, 40, s.listened)
+	assert.Len(t, s.plays, 1)
+
+	// skipped after 5 seconds: time counts, the play doesn't
+	s.observePosition("2", 0)
+	for pos := int64(1); pos <= 5; pos++ {
+		s.observePosition("2", pos)
+	}
+	s.trackEnded(mpvplayer.QueueItem{Id: "2", Artist: "B"}, false)
+	assert.Equal(t, 45, s.listened)
+	assert.Len(t, s.plays, 1)
+
+	// completed songs always count
+	s.trackEnded(mpvplayer.QueueItem{Id: "3", Artist: "B"}, true)
+	s.trackEnded(mpvplayer.QueueItem{Id: "4", Artist: "B"}, true)
+	assert.Equal(t, []namedCount{{"B", 2}, {"A", 1}}, s.topArtists(10))
+
+	// the history has the skipped song as well
+	assert.Len(t, s.history, 4)
+	assert.Equal(t, "2", s.history[1].song.Id)
+	assert.Equal(t, 5, s.history[1].seconds)
+	assert.False(t, s.history[1].completed)
+	assert.True(t, s.history[2].completed)
+}
+
+func TestSessionMilestone(t *testing.T) {
+	s := newSessionStats()
+	for i := 1; i < 10; i++ {
+		assert.False(t, s.trackEnded(mpvplayer.QueueItem{Id: "x"}, true))
+	}
+	assert.True(t, s.trackEnded(mpvplayer.QueueItem{Id: "x"}, true))
+}
+
+func TestAggregateAllTimeStats(t *testing.T) {
+	albums := []subsonic.Album{
+		{Id: "a1", Name: "One", Artist: "X", PlayCount: 10},
+		{Id: "a2", Name: "Two", Artist: "Y", PlayCount: 30},
+		{Id: "a3", Name: "Three", Artist: "X", PlayCount: 25},
+	}
+	songs := []subsonic.SubsonicEntity{
+		{Id: "s1", Title: "s1", PlayCount: 3},
+		{Id: "s2", Title: "s2", PlayCount: 0},
+		{Id: "s3", Title: "s3", PlayCount: 9},
+	}
+
+	stats := aggregateAllTimeStats(albums, songs, 2)
+	assert.Equal(t, []string{"a2", "a3"}, []string{stats.albums[0].Id, stats.albums[1].Id})
+	assert.Equal(t, []namedCount{{"X", 35}, {"Y", 30}}, stats.artists)
+	assert.Equal(t, []string{"s3", "s1"}, []string{stats.songs[0].Id, stats.songs[1].Id})
+}
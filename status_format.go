@@ -0,0 +1,88 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/logger"
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/spf13/viper"
+)
+
+const defaultStatusFormat = "[white]{{.Title}}{{with .Artist}} [gray]by [white]{{.}}{{end}}{{with .Year}} [gray]({{.}}){{end}}{{with .Genre}} [gray]{{.}}{{end}}"
+
+// statusSong is what the status format is executed with. The texts are
+// escaped, the template's own color tags work.
+type statusSong struct {
+	Title string
+	// the artist picked by ui.display-artist
+	Artist string
+	Album  string
+	// the first genre, empty if there's none
+	Genre       string
+	Year        int
+	TrackNumber int
+	Duration    int
+}
+
+func parseStatusFormat(format string) (*template.Template, error) {
+	return template.New("status").Funcs(formatFuncs).Parse(format)
+}
+
+func isStatusFormat(value interface{}) error {
+	format, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("expected a string, got %T", value)
+	}
+	_, err := parseStatusFormat(format)
+	return err
+}
+
+// loadStatusFormat returns the template of ui.status-format, or the default
+// one. The config was validated at startup.
+func loadStatusFormat(logger logger.LoggerInterface) *template.Template {
+	if viper.IsSet("ui.status-format") {
+		tmpl, err := parseStatusFormat(viper.GetString("ui.status-format"))
+		if err == nil {
+			return tmpl
+		}
+		logger.PrintError("loadStatusFormat", err)
+	}
+	tmpl, _ := parseStatusFormat(defaultStatusFormat)
+	return tmpl
+}
+
+func makeStatusSong(song *mpvplayer.QueueItem, display ArtistDisplay) statusSong {
+	genre := ""
+	if len(song.Genres) > 0 {
+		genre = song.Genres[0]
+	}
+	return statusSong{
+		Title:       tview.Escape(song.Title),
+		Artist:      tview.Escape(display.Artist(song.Artist, song.AlbumArtist)),
+		Album:       tview.Escape(song.Album),
+		Genre:       tview.Escape(genre),
+		Year:        song.Year,
+		TrackNumber: song.TrackNumber,
+		Duration:    song.Duration,
+	}
+}
+
+// formatSongForStatusBar returns the song as shown after the playback state,
+// formatted with ui.status-format.
+func (ui *Ui) formatSongForStatusBar(currentSong *mpvplayer.QueueItem) string {
+	if currentSong == nil || currentSong.Id == "" {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := ui.statusFormat.Execute(&buf, makeStatusSong(currentSong, ui.artistDisplay)); err != nil {
+		// e.g. a field of a different type, show the title at least
+		return "[::-] [white]" + tview.Escape(currentSong.Title)
+	}
+	return "[::-] " + buf.String()
+}
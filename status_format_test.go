@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatSongForStatusBar(t *testing.T) {
+	ui := &Ui{artistDisplay: ArtistDisplayAlbum}
+	ui.statusFormat, _ = parseStatusFormat(defaultStatusFormat)
+
+	song := mpvplayer.QueueItem{Id: "1", Title: "So What", Artist: "Miles Davis", Year: 1959, Genres: []string{"Jazz", "Modal"}}
+	assert.Equal(t, "[::-] [white]So What [gray]by [white]Miles Davis [gray](1959) [gray]Jazz", ui.formatSongForStatusBar(&song))
+
+	// unknown fields are left out, texts are escaped
+	song = mpvplayer.QueueItem{Id: "2", Title: "[Intro]", Artist: "Band"}
+	assert.Equal(t, "[::-] [white][Intro[] [gray]by [white]Band", ui.formatSongForStatusBar(&song))
+
+	assert.Equal(t, "", ui.formatSongForStatusBar(&mpvplayer.QueueItem{}))
+
+	ui.statusFormat, _ = parseStatusFormat("{{.Title}} ({{minutes .Duration}})")
+	song = mpvplayer.QueueItem{Id: "3", Title: "Blue", Duration: 125}
+	assert.Equal(t, "[::-] Blue (2:05)", ui.formatSongForStatusBar(&song))
+
+	assert.Error(t, isStatusFormat("{{.Title"))
+}
@@ -4,6 +4,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -12,6 +13,7 @@ import (
 	"runtime"
 	"runtime/debug"
 	"runtime/pprof"
+	"time"
 
 	"github.com/spezifisch/stmps/logger"
 	"github.com/spezifisch/stmps/mpvplayer"
@@ -28,9 +30,11 @@ const DEVELOPMENT = "development"
 
 var Version string = DEVELOPMENT
 
-func readConfig(configFile *string) error {
-	required_properties := []string{"auth.username", "auth.password", "server.host"}
+// configWarnings are non-fatal findings of the config validation. They're
+// printed at startup and logged once the logger is up.
+var configWarnings []string
 
+func readConfig(configFile *string) error {
 	if configFile != nil && *configFile != "" {
 		// use custom config file
 		viper.SetConfigFile(*configFile)
@@ -46,19 +50,86 @@ func readConfig(configFile *string) error {
 	// read it
 	err := viper.ReadInConfig()
 	if err != nil {
-		return fmt.Errorf("Config file error: %s\n", err)
+		return fmt.Errorf("Config file error: %w\n", err)
 	}
 
-	// validate
-	for _, prop := range required_properties {
-		if !viper.IsSet(prop) {
-			return fmt.Errorf("Config property %s is required\n", prop)
+	// rename deprecated keys
+	if renamed, err := migrateConfig(viper.ConfigFileUsed()); err != nil {
+		return fmt.Errorf("Config migration error: %s\n", err)
+	} else if len(renamed) > 0 {
+		fmt.Fprintf(os.Stderr, "Migrated config file %s (backup in %s.bak):\n", viper.ConfigFileUsed(), viper.ConfigFileUsed())
+		for _, r := range renamed {
+			fmt.Fprintf(os.Stderr, "  %s\n", r)
+		}
+		if err := viper.ReadInConfig(); err != nil {
+			return fmt.Errorf("Config file error: %s\n", err)
 		}
 	}
 
+	// validate
+	warnings, err := validateConfig()
+	configWarnings = warnings
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Config warning: %s\n", w)
+	}
+	if err != nil {
+		return fmt.Errorf("Config error: %s\n", err)
+	}
+
 	return nil
 }
 
+// set if the password was given in the server URL, it overrides the config
+var passwordFromArgs bool
+
+// rereadPassword returns the password from the config file, which may have
+// been changed since stmps started. It's used when the server rejects the
+// credentials.
+func rereadPassword() (string, error) {
+	current := viper.GetString("auth.password")
+	if passwordFromArgs || viper.ConfigFileUsed() == "" {
+		return current, nil
+	}
+
+	// a separate instance, the global config may be in use
+	config := viper.New()
+	config.SetConfigFile(viper.ConfigFileUsed())
+	config.SetConfigType("toml")
+	if err := config.ReadInConfig(); err != nil {
+		return "", fmt.Errorf("re-reading %s: %v", viper.ConfigFileUsed(), err)
+	}
+	return config.GetString("auth.password"), nil
+}
+
+// shouldRunSetup decides whether the first-run wizard is shown: no config
+// file was found in the default places, and stmps runs interactively
+// without a server given on the command line.
+func shouldRunSetup(readErr error, configFile string) bool {
+	if !errors.As(readErr, &viper.ConfigFileNotFoundError{}) {
+		return false
+	}
+	if configFile != "" || len(flag.Args()) > 0 || headlessMode || testMode {
+		return false
+	}
+	stat, err := os.Stdin.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// setupConfig runs the first-run wizard and reads the config it saved.
+func setupConfig(configFile *string) error {
+	path, err := defaultConfigPath()
+	if err != nil {
+		return err
+	}
+	if err := runSetupWizard(path); err != nil {
+		return err
+	}
+	fmt.Printf("Saved config to %s\n", path)
+
+	*configFile = path
+	return readConfig(configFile)
+}
+
 // parseConfig takes the first non-flag arguments from flags and parses it
 // into the viper config.
 func parseConfig() {
@@ -69,6 +140,7 @@ func parseConfig() {
 			// If the password wasn't provided, the program will fail as normal
 			if p, s := u.User.Password(); s {
 				viper.Set("auth.password", p)
+				passwordFromArgs = true
 			}
 		}
 		// Blank out the credentials so we can use the URL formatting
@@ -113,6 +185,9 @@ func main() {
 	memprofile := flag.String("memprofile", "", "write memory profile to `file`")
 	configFile := flag.String("config", "", "use config `file`")
 	version := flag.Bool("version", false, "print the stmps version and exit")
+	resumeBookmark := flag.String("resume-bookmark", "", "play the song `id` from its bookmarked position")
+	streamUrl := flag.String("stream-url", "", "print the stream URL of the song `id` and exit")
+	playSong := flag.String("play-song", "", "play the song `id` without the UI and exit when it ends")
 
 	flag.Parse()
 	if *help {
@@ -148,7 +223,11 @@ func main() {
 		parseConfig()
 	}
 
-	if err := readConfig(configFile); err != nil {
+	err := readConfig(configFile)
+	if shouldRunSetup(err, *configFile) {
+		err = setupConfig(configFile)
+	}
+	if err != nil {
 		if configFile == nil {
 			fmt.Fprintf(os.Stderr, "Failed to read configuration: configuration file is nil\n")
 		} else {
@@ -158,30 +237,115 @@ func main() {
 	}
 
 	logger := logger.Init()
+	for _, w := range configWarnings {
+		logger.Printf("config warning: %s", w)
+	}
 	initCommandHandler(logger)
 
 	// init mpv engine
-	player, err := mpvplayer.NewPlayer(logger)
+	latency := loadLatencySettings()
+	decodingOptions, rejected := loadDecodingOptions()
+	for _, r := range rejected {
+		logger.Printf("config warning: %s, using mpv's default", r)
+	}
+	player, err := mpvplayer.NewPlayerWithConfig(logger, mpvplayer.MpvConfig{
+		Options:    append(latency.mpvOptions(), decodingOptions...),
+		ConfigFile: viper.GetString("player.mpv-config"),
+		ScriptDir:  viper.GetString("player.mpv-scripts"),
+	})
 	if err != nil {
 		fmt.Println("Unable to initialize mpv. Is mpv installed?")
 		osExit(1)
 	}
+	if viper.IsSet("player.skip-debounce-ms") {
+		player.SkipDebounce = time.Duration(viper.GetInt("player.skip-debounce-ms")) * time.Millisecond
+	}
+	if viper.IsSet("ui.refresh-ms") {
+		player.StatusInterval = time.Duration(viper.GetInt("ui.refresh-ms")) * time.Millisecond
+	}
+	if viper.IsSet("player.stall-timeout-s") {
+		player.StallTimeout = time.Duration(viper.GetInt("player.stall-timeout-s")) * time.Second
+	}
+	if viper.IsSet("player.stall-action") {
+		player.StallAction = viper.GetString("player.stall-action")
+	}
+	if viper.IsSet("player.previous-restart-s") {
+		player.PreviousRestartThreshold = time.Duration(viper.GetInt("player.previous-restart-s")) * time.Second
+	}
+	if viper.IsSet("player.seek-wraps-tracks") {
+		player.SeekWrapsTracks = viper.GetBool("player.seek-wraps-tracks")
+	}
+	player.MaxQueueLength = viper.GetInt("client.max-queue-length")
+	if viper.IsSet("player.max-restarts") {
+		player.MaxRestarts = viper.GetInt("player.max-restarts")
+	}
+	if viper.IsSet("player.pause-during-seek") {
+		player.PauseDuringSeek = viper.GetBool("player.pause-during-seek")
+	}
+	if viper.IsSet("player.seek-mode") {
+		if err := player.SetSeekMode(viper.GetString("player.seek-mode")); err != nil {
+			logger.PrintError("SetSeekMode", err)
+		}
+	}
+	player.Gapless = latency.gapless
+	player.GaplessWithinAlbumOnly = viper.GetBool("player.gapless-within-album-only")
+	player.TrimSilence = viper.GetBool("player.trim-silence")
+	if viper.IsSet("player.silence-threshold-db") {
+		player.SilenceThreshold = viper.GetFloat64("player.silence-threshold-db")
+	}
+	if viper.IsSet("player.silence-duration-ms") {
+		player.SilenceDuration = time.Duration(viper.GetInt("player.silence-duration-ms")) * time.Millisecond
+	}
+	player.VoiceBoost = viper.GetBool("player.voice-boost")
+	if viper.IsSet("player.voice-boost-spoken-word") {
+		player.VoiceBoostSpokenWord = viper.GetBool("player.voice-boost-spoken-word")
+	}
+	if viper.IsSet("player.voice-threshold-db") {
+		player.VoiceCompressor.Threshold = viper.GetFloat64("player.voice-threshold-db")
+	}
+	if viper.IsSet("player.voice-ratio") {
+		player.VoiceCompressor.Ratio = viper.GetFloat64("player.voice-ratio")
+	}
+	if viper.IsSet("player.voice-makeup-db") {
+		player.VoiceCompressor.Makeup = viper.GetFloat64("player.voice-makeup-db")
+	}
+	player.FadeIn = time.Duration(viper.GetInt("player.fade-in-ms")) * time.Millisecond
+	player.FadeOut = time.Duration(viper.GetInt("player.fade-out-ms")) * time.Millisecond
+	if outputs := loadAudioOutputs(); len(outputs) > 0 {
+		devices, listErr := player.AudioDevices()
+		if listErr != nil {
+			logger.PrintError("AudioDevices", listErr)
+		}
+		outputs, missing := availableOutputs(outputs, devices, listErr)
+		for _, device := range missing {
+			logger.Printf("audio output %s isn't available, leaving it out", device)
+		}
+		if err := player.SetOutputs(outputs); err != nil {
+			logger.PrintError("SetOutputs", err)
+		}
+	}
+
+	// the media controls follow playback to a cast device
+	remotePlayer := remote.NewSwitchedPlayer(player)
 
 	var mprisPlayer *remote.MprisPlayer
 	// init mpris2 player control (linux only but fails gracefully on other systems)
 	if *enableMpris {
-		mprisPlayer, err = remote.RegisterMprisPlayer(player, logger)
+		mprisPlayer, err = remote.RegisterMprisPlayer(remotePlayer, logger)
 		if err != nil {
 			fmt.Printf("Unable to register MPRIS with DBUS: %s\n", err)
 			fmt.Println("Try running without MPRIS")
 			osExit(1)
 		}
-		defer mprisPlayer.Close()
 	}
 
 	// init macos mediaplayer control
 	if runtime.GOOS == "darwin" {
-		if err = remote.RegisterMPMediaHandler(player, logger); err != nil {
+		clearOnStop := true
+		if viper.IsSet("ui.media-controls-clear-on-stop") {
+			clearOnStop = viper.GetBool("ui.media-controls-clear-on-stop")
+		}
+		if err = remote.RegisterMPMediaHandler(remotePlayer, logger, remote.FallbackArt(viper.GetString("ui.media-controls-art")), clearOnStop); err != nil {
 			fmt.Printf("Unable to initialize MediaPlayer bindings: %s\n", err)
 			osExit(1)
 		} else {
@@ -201,9 +365,44 @@ func main() {
 	connection.Password = viper.GetString("auth.password")
 	connection.Host = viper.GetString("server.host")
 	connection.PlaintextAuth = viper.GetBool("auth.plaintext")
+	connection.Reauthenticate = rereadPassword
 	connection.Scrobble = viper.GetBool("server.scrobble")
+	connection.OriginalStreams = latency.originalStreams
+	connection.DisableCompression = viper.IsSet("server.compression") && !viper.GetBool("server.compression")
+	connection.SupportedFormats = viper.GetStringSlice("client.supported-formats")
 	connection.RandomSongNumber = viper.GetUint("client.random-songs")
 
+	if *streamUrl != "" {
+		if err := printStreamUrl(connection, *streamUrl); err != nil {
+			fmt.Printf("Error fetching song from server: %s\n", err)
+			osExit(1)
+			return
+		}
+		osExit(0)
+		return
+	}
+
+	if *playSong != "" {
+		if *resumeBookmark != "" {
+			fmt.Println("Only one of -play-song and -resume-bookmark can be used")
+			osExit(2)
+			return
+		}
+		song, err := fetchSong(connection, *playSong)
+		if err != nil {
+			fmt.Printf("Error fetching song from server: %s\n", err)
+			osExit(1)
+			return
+		}
+		if err := playSongHeadless(connection, player, song); err != nil {
+			fmt.Printf("Error playing song: %s\n", err)
+			osExit(1)
+			return
+		}
+		osExit(0)
+		return
+	}
+
 	indexResponse, err := connection.GetIndexes()
 	if err != nil {
 		fmt.Printf("Error fetching playlists from server: %s\n", err)
@@ -252,17 +451,51 @@ func main() {
 		return
 	}
 
-	ui := InitGui(&indexResponse.Indexes.Index,
+	var bookmark *subsonic.Bookmark
+	if *resumeBookmark != "" {
+		if bookmark, err = fetchBookmark(connection, *resumeBookmark); err != nil {
+			fmt.Printf("Error fetching bookmarks from server: %s\n", err)
+			osExit(1)
+		}
+		if bookmark == nil {
+			fmt.Printf("No bookmark for song %s on the server\n", *resumeBookmark)
+			osExit(1)
+		}
+		// the bookmark takes the place of the last session
+		viper.Set("client.resume-last-session", string(ResumeOff))
+	}
+
+	indexes := indexResponse.Indexes
+	if browseMode := BrowseMode(viper.GetString("client.browse-mode")); browseMode == BrowseId3 {
+		if indexes, err = fetchBrowseIndexes(connection, browseMode); err != nil {
+			fmt.Printf("Error fetching artists from server: %s\n", err)
+			osExit(1)
+		}
+	}
+
+	ui := InitGui(&indexes,
 		connection,
 		player,
 		logger,
-		mprisPlayer)
+		mprisPlayer,
+		remotePlayer)
+
+	if bookmark != nil {
+		ui.playBookmark(*bookmark)
+	}
 
 	// run main loop
 	if err := ui.Run(); err != nil {
 		panic(err)
 	}
 
+	// let scrobbles and other writes finish, but don't hang on them
+	timeout := shutdownTimeout()
+	if !ui.shutdown(timeout) {
+		fmt.Printf("Background work didn't finish within %s, exiting anyway.\n", timeout)
+		osExit(1)
+	}
+
 	if *memprofile != "" {
 		f, err := os.Create(*memprofile)
 		if err != nil {
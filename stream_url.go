@@ -0,0 +1,46 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// stream URLs carry the login, either the password or a token derived from it
+const streamUrlWarning = "The stream URL contains your credentials, don't share it."
+
+// printStreamUrl prints the stream URL of the song with the ID, as stmps
+// would pass it to mpv with the stored max bitrate of the server. Used by
+// --stream-url.
+func printStreamUrl(connection *subsonic.SubsonicConnection, id string) error {
+	song, err := fetchSong(connection, id)
+	if err != nil {
+		return err
+	}
+
+	settings, err := loadServerSettings(serverStatePath(), serverProfile(connection.Username, connection.Host), defaultServerSettings())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	connection.MaxBitRate = settings.MaxBitRate
+
+	fmt.Fprintln(os.Stderr, streamUrlWarning)
+	fmt.Println(connection.GetPlayUrl(song))
+	return nil
+}
+
+// handleCopyStreamUrl copies the URL mpv streams the current song from, with
+// the transcoding parameters it was queued with.
+func (ui *Ui) handleCopyStreamUrl() {
+	song, err := ui.player.GetQueueItem(0)
+	if err != nil || song.Uri == "" {
+		ui.showNotice("Nothing playing")
+		return
+	}
+	ui.logger.Printf("copying stream URL of %q. %s", song.Title, streamUrlWarning)
+	ui.copyOrShow("stream URL (contains your credentials)", song.Uri)
+}
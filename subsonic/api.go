@@ -15,9 +15,10 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/spezifisch/stmps/logger"
 )
@@ -29,13 +30,58 @@ type SubsonicConnection struct {
 	PlaintextAuth    bool
 	Scrobble         bool
 	RandomSongNumber uint
+	// MaxBitRate makes the server transcode streams to at most this many
+	// kbps. Zero streams the original files.
+	MaxBitRate int
+	// OriginalStreams asks the server not to transcode at all while
+	// MaxBitRate is zero, also if it would by default
+	OriginalStreams bool
+	// SupportedFormats are the file suffixes the client plays, in order of
+	// preference. Others are transcoded to the first one. Empty means all,
+	// so the original files are preferred.
+	SupportedFormats []string
+	// DisableCompression stops asking the server for gzip compressed
+	// responses, for servers that get it wrong
+	DisableCompression bool
+
+	// Reauthenticate is called when the server rejects the credentials. It
+	// returns the password to retry the request with, e.g. re-read from the
+	// config. If it's nil, the request is retried with a new salt.
+	Reauthenticate func() (string, error)
+	// AuthFailed is called with the error when a request failed because the
+	// credentials were still rejected after re-authenticating.
+	AuthFailed func(error)
+	// guards Password, which Reauthenticate may change
+	authLock sync.Mutex
 
 	clientName    string
 	clientVersion string
 
-	logger         logger.LoggerInterface
-	directoryCache map[string]SubsonicResponse
-	coverArts      map[string]image.Image
+	logger logger.LoggerInterface
+	// artists are fetched in the background by the browser
+	directoryCacheLock sync.Mutex
+	directoryCache     map[string]SubsonicResponse
+	coverArts          map[string]image.Image
+
+	// artist images are fetched in the background, unlike cover arts
+	artistImagesLock sync.Mutex
+	artistImages     map[string]image.Image
+}
+
+// uncompressedClient is the HTTP client with DisableCompression. The default
+// client asks for gzip and decompresses the responses transparently.
+var uncompressedClient = func() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableCompression = true
+	return &http.Client{Transport: transport}
+}()
+
+// client returns the HTTP client for requests to the server.
+func (connection *SubsonicConnection) client() *http.Client {
+	if connection.DisableCompression {
+		return uncompressedClient
+	}
+	return http.DefaultClient
 }
 
 func Init(logger logger.LoggerInterface) *SubsonicConnection {
@@ -46,6 +92,7 @@ func Init(logger logger.LoggerInterface) *SubsonicConnection {
 		logger:         logger,
 		directoryCache: make(map[string]SubsonicResponse),
 		coverArts:      make(map[string]image.Image),
+		artistImages:   make(map[string]image.Image),
 	}
 }
 
@@ -55,22 +102,40 @@ func (s *SubsonicConnection) SetClientInfo(name, version string) {
 }
 
 func (s *SubsonicConnection) ClearCache() {
+	s.directoryCacheLock.Lock()
+	defer s.directoryCacheLock.Unlock()
 	s.directoryCache = make(map[string]SubsonicResponse)
 }
 
 func (s *SubsonicConnection) RemoveCacheEntry(key string) {
+	s.directoryCacheLock.Lock()
+	defer s.directoryCacheLock.Unlock()
 	delete(s.directoryCache, key)
 }
 
+// IsCached reports whether the artist, album or directory with the ID was
+// fetched already, i.e. getting it doesn't make a request.
+func (s *SubsonicConnection) IsCached(id string) bool {
+	_, present := s.cachedDirectory(id)
+	return present
+}
+
+func (s *SubsonicConnection) cachedDirectory(id string) (SubsonicResponse, bool) {
+	s.directoryCacheLock.Lock()
+	defer s.directoryCacheLock.Unlock()
+	response, present := s.directoryCache[id]
+	return response, present
+}
+
+func (s *SubsonicConnection) cacheDirectory(id string, response SubsonicResponse) {
+	s.directoryCacheLock.Lock()
+	defer s.directoryCacheLock.Unlock()
+	s.directoryCache[id] = response
+}
+
 func defaultQuery(connection *SubsonicConnection) url.Values {
 	query := url.Values{}
-	if connection.PlaintextAuth {
-		query.Set("p", connection.Password)
-	} else {
-		token, salt := authToken(connection.Password)
-		query.Set("t", token)
-		query.Set("s", salt)
-	}
+	connection.setAuth(query)
 	query.Set("u", connection.Username)
 	query.Set("v", connection.clientVersion)
 	query.Set("c", connection.clientName)
@@ -90,9 +155,10 @@ type SubsonicError struct {
 }
 
 type SubsonicArtist struct {
-	Id         string `json:"id"`
-	Name       string `json:"name"`
-	AlbumCount int    `json:"albumCount"`
+	Id             string `json:"id"`
+	Name           string `json:"name"`
+	AlbumCount     int    `json:"albumCount"`
+	ArtistImageUrl string `json:"artistImageUrl"` // OpenSubsonic only
 }
 
 func (s SubsonicArtist) ID() string {
@@ -125,6 +191,36 @@ type ScanStatus struct {
 	Count    int  `json:"count"`
 }
 
+// SubsonicUser is the getUser entry, only the roles stmps checks are
+// decoded.
+type SubsonicUser struct {
+	Username  string `json:"username"`
+	AdminRole bool   `json:"adminRole"`
+}
+
+// SubsonicLicense is the server's license state. Servers without licensing,
+// e.g. most OpenSubsonic ones, report a valid license.
+type SubsonicLicense struct {
+	Valid bool   `json:"valid"`
+	Email string `json:"email"`
+	// ISO 8601 timestamps, empty if the server doesn't report them
+	LicenseExpires string `json:"licenseExpires"`
+	TrialExpires   string `json:"trialExpires"`
+}
+
+// Bookmark is a position saved in a song, e.g. in an audiobook.
+type Bookmark struct {
+	// in milliseconds
+	Position int64          `json:"position"`
+	Comment  string         `json:"comment"`
+	Changed  string         `json:"changed"`
+	Entry    SubsonicEntity `json:"entry"`
+}
+
+type SubsonicBookmarks struct {
+	Bookmarks []Bookmark `json:"bookmark"`
+}
+
 type PlayQueue struct {
 	Current  string           `json:"current"`
 	Position int              `json:"position"`
@@ -132,10 +228,18 @@ type PlayQueue struct {
 }
 
 type Artist struct {
-	Id         string  `json:"id"`
-	Name       string  `json:"name"`
-	AlbumCount int     `json:"albumCount"`
-	Album      []Album `json:"album"`
+	Id             string  `json:"id"`
+	Name           string  `json:"name"`
+	AlbumCount     int     `json:"albumCount"`
+	ArtistImageUrl string  `json:"artistImageUrl"` // OpenSubsonic only
+	Album          []Album `json:"album"`
+}
+
+type ArtistInfo struct {
+	Biography      string `json:"biography"`
+	SmallImageUrl  string `json:"smallImageUrl"`
+	MediumImageUrl string `json:"mediumImageUrl"`
+	LargeImageUrl  string `json:"largeImageUrl"`
 }
 
 func (s Artist) ID() string {
@@ -170,25 +274,107 @@ type Genre struct {
 	Name string `json:"name"`
 }
 
+type SubsonicGenres struct {
+	Genres []GenreInfo `json:"genre"`
+}
+
+// GenreInfo is an entry of the getGenres list, unlike Genre which is used in
+// album and song entries.
+type GenreInfo struct {
+	Value      string `json:"value"`
+	SongCount  int    `json:"songCount"`
+	AlbumCount int    `json:"albumCount"`
+}
+
 type SubsonicEntity struct {
 	Id          string   `json:"id"`
 	IsDirectory bool     `json:"isDir"`
 	Parent      string   `json:"parent"`
 	Title       string   `json:"title"`
+	AlbumId     string   `json:"albumId"`
+	Album       string   `json:"album"`
 	ArtistId    string   `json:"artistId"`
 	Artist      string   `json:"artist"`
 	Artists     []Artist `json:"artists"`
-	Duration    int      `json:"duration"`
-	Track       int      `json:"track"`
-	DiscNumber  int      `json:"discNumber"`
-	Path        string   `json:"path"`
-	CoverArtId  string   `json:"coverArt"`
+	// OpenSubsonic album artist, empty with plain Subsonic servers
+	DisplayAlbumArtist string   `json:"displayAlbumArtist"`
+	AlbumArtists       []Artist `json:"albumArtists"`
+	Duration           int      `json:"duration"`
+	Year               int      `json:"year"`
+	Genre              string   `json:"genre"`
+	// OpenSubsonic only, the first one is usually Genre
+	Genres     []Genre `json:"genres"`
+	UserRating int     `json:"userRating"`
+	PlayCount  int     `json:"playCount"`
+	Created    string  `json:"created"`
+	Track      int     `json:"track"`
+	DiscNumber int     `json:"discNumber"`
+	Path       string  `json:"path"`
+	CoverArtId string  `json:"coverArt"`
+	// OpenSubsonic only: "explicit", "clean" or empty if unknown
+	ExplicitStatus string `json:"explicitStatus"`
+	// comment tag of the song, OpenSubsonic only
+	Comment string `json:"comment"`
+	// file format of the original file, e.g. "flac" and "audio/flac"
+	Suffix      string `json:"suffix"`
+	ContentType string `json:"contentType"`
+	// bitrate of the original file in kbps, 0 if unknown
+	BitRate int `json:"bitRate"`
+	// when the user last played the song, OpenSubsonic only, e.g.
+	// "2024-03-01T20:15:00Z"; empty if it was never played
+	Played string `json:"played"`
+	// "music", "podcast", "audiobook" or "video"
+	Type string `json:"type"`
+	// when the user starred the song, e.g. "2024-03-01T20:15:00Z"; empty if
+	// it isn't starred or the server doesn't report it
+	Starred string `json:"starred"`
 }
 
 func (s SubsonicEntity) ID() string {
 	return s.Id
 }
 
+// IsExplicit reports whether the server flags the song as explicit.
+func (e SubsonicEntity) IsExplicit() bool {
+	return e.ExplicitStatus == "explicit"
+}
+
+// LastPlayed returns when the user last played the song, or the zero time
+// if it was never played or the server doesn't report it.
+func (e SubsonicEntity) LastPlayed() time.Time {
+	played, err := time.Parse(time.RFC3339, e.Played)
+	if err != nil {
+		return time.Time{}
+	}
+	return played
+}
+
+// GetGenres returns the genres of the song, Genre first.
+func (e SubsonicEntity) GetGenres() []string {
+	genres := make([]string, 0, len(e.Genres)+1)
+	if e.Genre != "" {
+		genres = append(genres, e.Genre)
+	}
+	for _, genre := range e.Genres {
+		if genre.Name != e.Genre {
+			genres = append(genres, genre.Name)
+		}
+	}
+	return genres
+}
+
+// GetAlbumArtist returns the album artist if the server reports it.
+func (e SubsonicEntity) GetAlbumArtist() string {
+	if e.DisplayAlbumArtist != "" {
+		return e.DisplayAlbumArtist
+	}
+	names := make([]string, 0, len(e.AlbumArtists))
+	for _, artist := range e.AlbumArtists {
+		names = append(names, artist.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
 // Return the title if present, otherwise fallback to the file path
 func (e SubsonicEntity) GetSongTitle() string {
 	if e.Title != "" {
@@ -244,6 +430,8 @@ func (s SubsonicEntities) Less(i, j int) bool {
 
 type SubsonicIndexes struct {
 	Index []SubsonicIndex
+	// space separated articles, e.g. "The El La", to ignore when sorting
+	IgnoredArticles string `json:"ignoredArticles"`
 }
 
 type SubsonicIndex struct {
@@ -262,14 +450,38 @@ type SubsonicPlaylist struct {
 	Entries   SubsonicEntities `json:"entry"`
 }
 
+type SubsonicAlbumList struct {
+	Albums []Album `json:"album"`
+}
+
+type SubsonicShares struct {
+	Shares []SubsonicShare `json:"share"`
+}
+
+type SubsonicShare struct {
+	Id          string           `json:"id"`
+	Url         string           `json:"url"`
+	Description string           `json:"description"`
+	Entries     SubsonicEntities `json:"entry"`
+}
+
 type SubsonicResponse struct {
 	Status        string            `json:"status"`
 	Version       string            `json:"version"`
+	Type          string            `json:"type"`          // OpenSubsonic only
+	ServerVersion string            `json:"serverVersion"` // OpenSubsonic only
+	OpenSubsonic  bool              `json:"openSubsonic"`
 	Indexes       SubsonicIndexes   `json:"indexes"`
+	Artists       SubsonicIndexes   `json:"artists"`
 	Directory     SubsonicDirectory `json:"directory"`
 	RandomSongs   SubsonicSongs     `json:"randomSongs"`
 	SimilarSongs  SubsonicSongs     `json:"similarSongs"`
+	SimilarSongs2 SubsonicSongs     `json:"similarSongs2"`
+	SongsByGenre  SubsonicSongs     `json:"songsByGenre"`
+	TopSongs      SubsonicSongs     `json:"topSongs"`
+	Genres        SubsonicGenres    `json:"genres"`
 	Starred       SubsonicResults   `json:"starred"`
+	Starred2      SubsonicResults   `json:"starred2"`
 	Playlists     SubsonicPlaylists `json:"playlists"`
 	Playlist      SubsonicPlaylist  `json:"playlist"`
 	Error         SubsonicError     `json:"error"`
@@ -278,6 +490,23 @@ type SubsonicResponse struct {
 	SearchResults SubsonicResults   `json:"searchResult3"`
 	ScanStatus    ScanStatus        `json:"scanStatus"`
 	PlayQueue     PlayQueue         `json:"playQueue"`
+	Bookmarks     SubsonicBookmarks `json:"bookmarks"`
+	Shares        SubsonicShares    `json:"shares"`
+	AlbumList2    SubsonicAlbumList `json:"albumList2"`
+	ArtistInfo    ArtistInfo        `json:"artistInfo"`
+	ArtistInfo2   ArtistInfo        `json:"artistInfo2"`
+	User          SubsonicUser      `json:"user"`
+	Song          SubsonicEntity    `json:"song"`
+	License       SubsonicLicense   `json:"license"`
+
+	OpenSubsonicExtensions []OpenSubsonicExtension `json:"openSubsonicExtensions"`
+}
+
+// OpenSubsonicExtension is an API extension the server supports, with the
+// versions of it.
+type OpenSubsonicExtension struct {
+	Name     string `json:"name"`
+	Versions []int  `json:"versions"`
 }
 
 type responseWrapper struct {
@@ -306,14 +535,46 @@ func (connection *SubsonicConnection) GetServerInfo() (*SubsonicResponse, error)
 	return connection.getResponse("GetServerInfo", requestUrl)
 }
 
+// GetOpenSubsonicExtensions returns the OpenSubsonic extensions the server
+// supports. Only OpenSubsonic servers know this request.
+// https://opensubsonic.netlify.app/docs/endpoints/getopensubsonicextensions/
+func (connection *SubsonicConnection) GetOpenSubsonicExtensions() (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	requestUrl := connection.Host + "/rest/getOpenSubsonicExtensions" + "?" + query.Encode()
+	return connection.getResponse("GetOpenSubsonicExtensions", requestUrl)
+}
+
+// Ping checks that the server is reachable and accepts the credentials. It
+// returns the round-trip time of the request.
+func (connection *SubsonicConnection) Ping() (time.Duration, error) {
+	query := defaultQuery(connection)
+	requestUrl := connection.Host + "/rest/ping" + "?" + query.Encode()
+	start := time.Now()
+	resp, err := connection.getResponse("Ping", requestUrl)
+	latency := time.Since(start)
+	if err != nil {
+		return latency, err
+	}
+	return latency, responseError(resp)
+}
+
 func (connection *SubsonicConnection) GetIndexes() (*SubsonicResponse, error) {
 	query := defaultQuery(connection)
 	requestUrl := connection.Host + "/rest/getIndexes" + "?" + query.Encode()
 	return connection.getResponse("GetIndexes", requestUrl)
 }
 
+// GetArtists returns the artists by their tags, unlike GetIndexes which
+// returns the top-level folders.
+// https://www.subsonic.org/pages/api.jsp#getArtists
+func (connection *SubsonicConnection) GetArtists() (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	requestUrl := connection.Host + "/rest/getArtists" + "?" + query.Encode()
+	return connection.getResponse("GetArtists", requestUrl)
+}
+
 func (connection *SubsonicConnection) GetArtist(id string) (*SubsonicResponse, error) {
-	if cachedResponse, present := connection.directoryCache[id]; present {
+	if cachedResponse, present := connection.cachedDirectory(id); present {
 		return &cachedResponse, nil
 	}
 
@@ -327,16 +588,14 @@ func (connection *SubsonicConnection) GetArtist(id string) (*SubsonicResponse, e
 
 	// on a sucessful request, cache the response
 	if resp.Status == "ok" {
-		connection.directoryCache[id] = *resp
+		connection.cacheDirectory(id, *resp)
 	}
 
-	sort.Sort(resp.Directory.Entities)
-
 	return resp, nil
 }
 
 func (connection *SubsonicConnection) GetAlbum(id string) (*SubsonicResponse, error) {
-	if cachedResponse, present := connection.directoryCache[id]; present {
+	if cachedResponse, present := connection.cachedDirectory(id); present {
 		// This is because Albums that were fetched as Directories aren't populated correctly
 		if cachedResponse.Album.Name != "" {
 			return &cachedResponse, nil
@@ -353,16 +612,14 @@ func (connection *SubsonicConnection) GetAlbum(id string) (*SubsonicResponse, er
 
 	// on a sucessful request, cache the response
 	if resp.Status == "ok" {
-		connection.directoryCache[id] = *resp
+		connection.cacheDirectory(id, *resp)
 	}
 
-	sort.Sort(resp.Directory.Entities)
-
 	return resp, nil
 }
 
 func (connection *SubsonicConnection) GetMusicDirectory(id string) (*SubsonicResponse, error) {
-	if cachedResponse, present := connection.directoryCache[id]; present {
+	if cachedResponse, present := connection.cachedDirectory(id); present {
 		return &cachedResponse, nil
 	}
 
@@ -376,11 +633,9 @@ func (connection *SubsonicConnection) GetMusicDirectory(id string) (*SubsonicRes
 
 	// on a sucessful request, cache the response
 	if resp.Status == "ok" {
-		connection.directoryCache[id] = *resp
+		connection.cacheDirectory(id, *resp)
 	}
 
-	sort.Sort(resp.Directory.Entities)
-
 	return resp, nil
 }
 
@@ -399,8 +654,17 @@ func (connection *SubsonicConnection) GetCoverArt(id string) (image.Image, error
 	query := defaultQuery(connection)
 	query.Set("id", id)
 	query.Set("f", "image/png")
-	caller := "GetCoverArt"
-	res, err := http.Get(connection.Host + "/rest/getCoverArt" + "?" + query.Encode())
+	art, err := fetchImage("GetCoverArt", connection.Host+"/rest/getCoverArt"+"?"+query.Encode())
+	if art != nil {
+		// FIXME connection.coverArts shouldn't grow indefinitely. Add some LRU cleanup after loading a few hundred cover arts.
+		connection.coverArts[id] = art
+	}
+	return art, err
+}
+
+// fetchImage downloads and decodes a GIF, JPEG, or PNG image.
+func fetchImage(caller, imageUrl string) (image.Image, error) {
+	res, err := http.Get(imageUrl)
 	if err != nil {
 		return nil, fmt.Errorf("[%s] failed to make GET request: %v", caller, err)
 	}
@@ -422,22 +686,93 @@ func (connection *SubsonicConnection) GetCoverArt(id string) (image.Image, error
 	if err != nil {
 		return nil, fmt.Errorf("[%s] failed to read response body: %v", caller, err)
 	}
-	var art image.Image
-	switch res.Header["Content-Type"][0] {
+	// strip parameters like "; charset=binary"
+	contentType, _, _ := strings.Cut(res.Header["Content-Type"][0], ";")
+	switch strings.TrimSpace(contentType) {
 	case "image/png":
-		art, err = png.Decode(bytes.NewReader(responseBody))
+		return png.Decode(bytes.NewReader(responseBody))
 	case "image/jpeg":
-		art, err = jpeg.Decode(bytes.NewReader(responseBody))
+		return jpeg.Decode(bytes.NewReader(responseBody))
 	case "image/gif":
-		art, err = gif.Decode(bytes.NewReader(responseBody))
-	default:
-		return nil, fmt.Errorf("[%s] unhandled image type %s: %v", caller, res.Header["Content-Type"][0], err)
+		return gif.Decode(bytes.NewReader(responseBody))
 	}
-	if art != nil {
-		// FIXME connection.coverArts shouldn't grow indefinitely. Add some LRU cleanup after loading a few hundred cover arts.
-		connection.coverArts[id] = art
+	return nil, fmt.Errorf("[%s] unhandled image type %s", caller, contentType)
+}
+
+// GetArtistInfo2 returns biography and image URLs of an artist.
+// https://www.subsonic.org/pages/api.jsp#getArtistInfo2
+func (connection *SubsonicConnection) GetArtistInfo2(id string) (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	query.Set("id", id)
+	requestUrl := connection.Host + "/rest/getArtistInfo2" + "?" + query.Encode()
+	return connection.getResponse("GetArtistInfo2", requestUrl)
+}
+
+// GetArtistInfo is GetArtistInfo2 for an artist folder, by folder ID.
+// https://www.subsonic.org/pages/api.jsp#getArtistInfo
+func (connection *SubsonicConnection) GetArtistInfo(id string) (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	query.Set("id", id)
+	requestUrl := connection.Host + "/rest/getArtistInfo" + "?" + query.Encode()
+	return connection.getResponse("GetArtistInfo", requestUrl)
+}
+
+// GetArtistImage fetches the image of an artist, by ID. id3 tells whether
+// id is an ID3 artist ID or an artist folder ID from the folder browser. The
+// image URL is taken from getArtistInfo2, or from the OpenSubsonic
+// artistImageUrl field of getArtist; folders only have getArtistInfo. If the
+// server has no image for the artist, nil is returned without error. Results
+// (including missing images) are cached, failed requests aren't; unlike
+// GetCoverArt, this function may be called from any goroutine.
+func (connection *SubsonicConnection) GetArtistImage(id string, id3 bool) (image.Image, error) {
+	if id == "" {
+		return nil, fmt.Errorf("GetArtistImage: no ID provided")
 	}
-	return art, err
+	connection.artistImagesLock.Lock()
+	art, ok := connection.artistImages[id]
+	connection.artistImagesLock.Unlock()
+	if ok {
+		return art, nil
+	}
+
+	imageUrl := ""
+	if id3 {
+		resp, err := connection.GetArtistInfo2(id)
+		if err != nil {
+			return nil, err
+		}
+		imageUrl = resp.ArtistInfo2.LargeImageUrl
+		if imageUrl == "" {
+			imageUrl = resp.ArtistInfo2.MediumImageUrl
+		}
+		if imageUrl == "" {
+			if resp, err = connection.GetArtist(id); err != nil {
+				return nil, err
+			}
+			imageUrl = resp.Artist.ArtistImageUrl
+		}
+	} else {
+		resp, err := connection.GetArtistInfo(id)
+		if err != nil {
+			return nil, err
+		}
+		imageUrl = resp.ArtistInfo.LargeImageUrl
+		if imageUrl == "" {
+			imageUrl = resp.ArtistInfo.MediumImageUrl
+		}
+	}
+
+	if imageUrl != "" {
+		var err error
+		if art, err = fetchImage("GetArtistImage", imageUrl); err != nil {
+			return nil, err
+		}
+	}
+
+	connection.artistImagesLock.Lock()
+	connection.artistImages[id] = art
+	connection.artistImagesLock.Unlock()
+	return art, nil
 }
 
 func (connection *SubsonicConnection) GetRandomSongs(Id string, randomType string) (*SubsonicResponse, error) {
@@ -468,6 +803,66 @@ func (connection *SubsonicConnection) GetRandomSongs(Id string, randomType strin
 	}
 }
 
+// GetFilteredRandomSongs returns up to size random songs. Empty genre and zero
+// years disable the respective filter.
+// https://www.subsonic.org/pages/api.jsp#getRandomSongs
+func (connection *SubsonicConnection) GetFilteredRandomSongs(genre string, fromYear, toYear, size int) (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	query.Set("size", strconv.Itoa(size))
+	if genre != "" {
+		query.Set("genre", genre)
+	}
+	if fromYear > 0 {
+		query.Set("fromYear", strconv.Itoa(fromYear))
+	}
+	if toYear > 0 {
+		query.Set("toYear", strconv.Itoa(toYear))
+	}
+	requestUrl := connection.Host + "/rest/getRandomSongs?" + query.Encode()
+	return connection.getResponse("GetFilteredRandomSongs", requestUrl)
+}
+
+// GetSongsByGenre returns songs of a genre, count is clamped to 500 by the
+// server.
+// https://www.subsonic.org/pages/api.jsp#getSongsByGenre
+func (connection *SubsonicConnection) GetSongsByGenre(genre string, count, offset int) (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	query.Set("genre", genre)
+	query.Set("count", strconv.Itoa(count))
+	query.Set("offset", strconv.Itoa(offset))
+	requestUrl := connection.Host + "/rest/getSongsByGenre?" + query.Encode()
+	return connection.getResponse("GetSongsByGenre", requestUrl)
+}
+
+// GetSimilarSongs2 returns songs of artists similar to the ID3 artist, as
+// known to last.fm.
+// https://www.subsonic.org/pages/api.jsp#getSimilarSongs2
+func (connection *SubsonicConnection) GetSimilarSongs2(artistId string, count int) (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	query.Set("id", artistId)
+	query.Set("count", strconv.Itoa(count))
+	requestUrl := connection.Host + "/rest/getSimilarSongs2?" + query.Encode()
+	return connection.getResponse("GetSimilarSongs2", requestUrl)
+}
+
+// GetTopSongs returns the most popular songs of an artist, as known to
+// last.fm. The server may return nothing, e.g. if it has no last.fm access.
+// https://www.subsonic.org/pages/api.jsp#getTopSongs
+func (connection *SubsonicConnection) GetTopSongs(artistName string, count int) (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	query.Set("artist", artistName)
+	query.Set("count", strconv.Itoa(count))
+	requestUrl := connection.Host + "/rest/getTopSongs?" + query.Encode()
+	return connection.getResponse("GetTopSongs", requestUrl)
+}
+
+// https://www.subsonic.org/pages/api.jsp#getGenres
+func (connection *SubsonicConnection) GetGenres() (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	requestUrl := connection.Host + "/rest/getGenres?" + query.Encode()
+	return connection.getResponse("GetGenres", requestUrl)
+}
+
 func (connection *SubsonicConnection) ScrobbleSubmission(id string, isSubmission bool) (resp *SubsonicResponse, err error) {
 	query := defaultQuery(connection)
 	query.Set("id", id)
@@ -480,6 +875,18 @@ func (connection *SubsonicConnection) ScrobbleSubmission(id string, isSubmission
 	return
 }
 
+// ScrobbleSubmissionAt submits a play that happened at the given time, e.g.
+// one that couldn't be submitted earlier.
+func (connection *SubsonicConnection) ScrobbleSubmissionAt(id string, playedAt time.Time) (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	query.Set("id", id)
+	query.Set("submission", "true")
+	query.Set("time", strconv.FormatInt(playedAt.UnixMilli(), 10))
+
+	requestUrl := connection.Host + "/rest/scrobble" + "?" + query.Encode()
+	return connection.getResponse("ScrobbleSubmissionAt", requestUrl)
+}
+
 func (connection *SubsonicConnection) GetStarred() (*SubsonicResponse, error) {
 	query := defaultQuery(connection)
 	requestUrl := connection.Host + "/rest/getStarred" + "?" + query.Encode()
@@ -490,6 +897,15 @@ func (connection *SubsonicConnection) GetStarred() (*SubsonicResponse, error) {
 	return resp, nil
 }
 
+// GetStarred2 returns the starred artists, albums and songs by their tags,
+// with when they were starred.
+// https://www.subsonic.org/pages/api.jsp#getStarred2
+func (connection *SubsonicConnection) GetStarred2() (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	requestUrl := connection.Host + "/rest/getStarred2?" + query.Encode()
+	return connection.getResponse("GetStarred2", requestUrl)
+}
+
 func (connection *SubsonicConnection) ToggleStar(id string, starredItems map[string]struct{}) (*SubsonicResponse, error) {
 	query := defaultQuery(connection)
 	query.Set("id", id)
@@ -514,6 +930,100 @@ func (connection *SubsonicConnection) ToggleStar(id string, starredItems map[str
 	return resp, nil
 }
 
+// SetRating rates a song, album or artist from 1 to 5 stars, 0 removes the
+// rating.
+// https://www.subsonic.org/pages/api.jsp#setRating
+func (connection *SubsonicConnection) SetRating(id string, rating int) error {
+	query := defaultQuery(connection)
+	query.Set("id", id)
+	query.Set("rating", strconv.Itoa(rating))
+	requestUrl := connection.Host + "/rest/setRating?" + query.Encode()
+	_, err := connection.getResponse("SetRating", requestUrl)
+	return err
+}
+
+// StarBatchSize is the most IDs sent in one star or unstar request, more are
+// split into several requests to keep the URLs short.
+const StarBatchSize = 100
+
+// StarIds are the items of a star or unstar request: songs and folders by
+// ID, albums and artists of the tag based layout by their album and artist
+// IDs.
+type StarIds struct {
+	Ids       []string
+	AlbumIds  []string
+	ArtistIds []string
+}
+
+// Len returns the number of IDs.
+func (s StarIds) Len() int {
+	return len(s.Ids) + len(s.AlbumIds) + len(s.ArtistIds)
+}
+
+// batches splits the IDs into parts of up to size IDs, artists first, then
+// albums and songs.
+func (s StarIds) batches(size int) []StarIds {
+	var batches []StarIds
+	var batch StarIds
+	next := func() {
+		if batch.Len() == size {
+			batches = append(batches, batch)
+			batch = StarIds{}
+		}
+	}
+
+	for _, id := range s.ArtistIds {
+		batch.ArtistIds = append(batch.ArtistIds, id)
+		next()
+	}
+	for _, id := range s.AlbumIds {
+		batch.AlbumIds = append(batch.AlbumIds, id)
+		next()
+	}
+	for _, id := range s.Ids {
+		batch.Ids = append(batch.Ids, id)
+		next()
+	}
+	if batch.Len() > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// SetStarred stars or unstars the items, up to StarBatchSize per request.
+// done is called with the items of each successful request. It stops at the
+// first failed request.
+// https://www.subsonic.org/pages/api.jsp#star
+func (connection *SubsonicConnection) SetStarred(star bool, items StarIds, done func(batch StarIds)) error {
+	action := "unstar"
+	if star {
+		action = "star"
+	}
+
+	for _, batch := range items.batches(StarBatchSize) {
+		query := defaultQuery(connection)
+		for _, id := range batch.Ids {
+			query.Add("id", id)
+		}
+		for _, id := range batch.AlbumIds {
+			query.Add("albumId", id)
+		}
+		for _, id := range batch.ArtistIds {
+			query.Add("artistId", id)
+		}
+		requestUrl := connection.Host + "/rest/" + action + "?" + query.Encode()
+		resp, err := connection.getResponse("SetStarred", requestUrl)
+		if err != nil {
+			return err
+		}
+		if err := responseError(resp); err != nil {
+			return err
+		}
+		done(batch)
+	}
+	return nil
+}
+
 func (connection *SubsonicConnection) GetPlaylists() (*SubsonicResponse, error) {
 	query := defaultQuery(connection)
 	requestUrl := connection.Host + "/rest/getPlaylists" + "?" + query.Encode()
@@ -573,41 +1083,109 @@ func (connection *SubsonicConnection) CreatePlaylist(id, name string, songIds []
 	return connection.getResponse("GetPlaylist", requestUrl)
 }
 
+// PlaylistBatchSize is the most songs added to a playlist in one request,
+// see ImportPlaylist.
+const PlaylistBatchSize = 100
+
+// ImportPlaylist creates a playlist with the songs and returns its ID. The
+// first songs are sent with createPlaylist, the others are appended with
+// updatePlaylist, up to PlaylistBatchSize per request to keep the URLs
+// short.
+func (connection *SubsonicConnection) ImportPlaylist(name string, songIds []string) (string, error) {
+	first := songIds[:min(len(songIds), PlaylistBatchSize)]
+	resp, err := connection.CreatePlaylist("", name, first)
+	if err != nil {
+		return "", err
+	}
+	if err := responseError(resp); err != nil {
+		return "", err
+	}
+	id := string(resp.Playlist.Id)
+	if len(songIds) <= PlaylistBatchSize {
+		return id, nil
+	}
+	if id == "" {
+		// servers before API 1.14 don't return the playlist
+		return "", fmt.Errorf("[ImportPlaylist] the server didn't return the new playlist, only %d of %d songs were added", len(first), len(songIds))
+	}
+
+	for from := PlaylistBatchSize; from < len(songIds); from += PlaylistBatchSize {
+		query := defaultQuery(connection)
+		query.Set("playlistId", id)
+		for _, songId := range songIds[from:min(from+PlaylistBatchSize, len(songIds))] {
+			query.Add("songIdToAdd", songId)
+		}
+		requestUrl := connection.Host + "/rest/updatePlaylist" + "?" + query.Encode()
+		resp, err := connection.getResponse("ImportPlaylist", requestUrl)
+		if err != nil {
+			return id, err
+		}
+		if err := responseError(resp); err != nil {
+			return id, err
+		}
+	}
+	return id, nil
+}
+
+// getResponse makes a request and decodes the response. If the server
+// rejects the credentials, they are renewed and the request is retried once.
 func (connection *SubsonicConnection) getResponse(caller, requestUrl string) (*SubsonicResponse, error) {
-	res, err := http.Get(requestUrl)
+	statusCode, resp, err := connection.fetchResponse(caller, requestUrl)
+	if !isAuthError(statusCode, resp) {
+		return resp, err
+	}
+
+	retryUrl, reauthErr := connection.reauthenticate(requestUrl)
+	if reauthErr != nil {
+		return resp, connection.authFailed(caller, reauthErr)
+	}
+	statusCode, resp, err = connection.fetchResponse(caller, retryUrl)
+	if isAuthError(statusCode, resp) {
+		if err == nil {
+			err = fmt.Errorf("server error %d: %s", resp.Error.Code, resp.Error.Message)
+		}
+		return resp, connection.authFailed(caller, err)
+	}
+	return resp, err
+}
+
+// fetchResponse makes a request and decodes the response. The HTTP status
+// code is returned as well, 0 if there was no response.
+func (connection *SubsonicConnection) fetchResponse(caller, requestUrl string) (int, *SubsonicResponse, error) {
+	res, err := connection.client().Get(requestUrl)
 	if err != nil {
-		return nil, fmt.Errorf("[%s] failed to make GET request: %v", caller, err)
+		return 0, nil, fmt.Errorf("[%s] failed to make GET request: %v", caller, err)
 	}
 
 	if res.Body != nil {
 		defer res.Body.Close()
 	} else {
-		return nil, fmt.Errorf("[%s] response body is nil", caller)
+		return res.StatusCode, nil, fmt.Errorf("[%s] response body is nil", caller)
 	}
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("[%s] unexpected status code: %d, status: %s", caller, res.StatusCode, res.Status)
+		return res.StatusCode, nil, fmt.Errorf("[%s] unexpected status code: %d, status: %s", caller, res.StatusCode, res.Status)
 	}
 
 	responseBody, readErr := io.ReadAll(res.Body)
 	if readErr != nil {
-		return nil, fmt.Errorf("[%s] failed to read response body: %v", caller, readErr)
+		return res.StatusCode, nil, fmt.Errorf("[%s] failed to read response body: %v", caller, readErr)
 	}
 
 	var decodedBody responseWrapper
 	err = json.Unmarshal(responseBody, &decodedBody)
 	if err != nil {
-		return nil, fmt.Errorf("[%s] failed to unmarshal response body: %v", caller, err)
+		return res.StatusCode, nil, fmt.Errorf("[%s] failed to unmarshal response body: %v", caller, err)
 	}
 
-	return &decodedBody.Response, nil
+	return res.StatusCode, &decodedBody.Response, nil
 }
 
 func (connection *SubsonicConnection) DeletePlaylist(id string) error {
 	query := defaultQuery(connection)
 	query.Set("id", id)
 	requestUrl := connection.Host + "/rest/deletePlaylist" + "?" + query.Encode()
-	_, err := http.Get(requestUrl)
+	_, err := connection.client().Get(requestUrl)
 	return err
 }
 
@@ -616,7 +1194,7 @@ func (connection *SubsonicConnection) AddSongToPlaylist(playlistId string, songI
 	query.Set("playlistId", playlistId)
 	query.Set("songIdToAdd", songId)
 	requestUrl := connection.Host + "/rest/updatePlaylist" + "?" + query.Encode()
-	_, err := http.Get(requestUrl)
+	_, err := connection.client().Get(requestUrl)
 	return err
 }
 
@@ -625,7 +1203,7 @@ func (connection *SubsonicConnection) RemoveSongFromPlaylist(playlistId string,
 	query.Set("playlistId", playlistId)
 	query.Set("songIndexToRemove", strconv.Itoa(songIndex))
 	requestUrl := connection.Host + "/rest/updatePlaylist" + "?" + query.Encode()
-	_, err := http.Get(requestUrl)
+	_, err := connection.client().Get(requestUrl)
 	return err
 }
 
@@ -639,9 +1217,70 @@ func (connection *SubsonicConnection) GetPlayUrl(entity *SubsonicEntity) string
 
 	query := defaultQuery(connection)
 	query.Set("id", entity.Id)
+	if connection.MaxBitRate > 0 {
+		query.Set("maxBitRate", strconv.Itoa(connection.MaxBitRate))
+	}
+	if format := connection.streamFormat(entity); format != "" {
+		query.Set("format", format)
+	}
 	return connection.Host + "/rest/stream" + "?" + query.Encode()
 }
 
+// streamFormat returns the format parameter of the stream of entity, or ""
+// to leave the choice to the server. Songs in a supported format are
+// streamed as they are unless they exceed MaxBitRate; others are transcoded
+// to the preferred format.
+func (connection *SubsonicConnection) streamFormat(entity *SubsonicEntity) string {
+	if len(connection.SupportedFormats) == 0 {
+		if connection.MaxBitRate == 0 && connection.OriginalStreams {
+			// servers may transcode by default, e.g. for the client or user
+			return "raw"
+		}
+		return ""
+	}
+
+	supported := false
+	for _, format := range connection.SupportedFormats {
+		if strings.EqualFold(format, entity.Suffix) {
+			supported = true
+			break
+		}
+	}
+	// songs of unknown bitrate may be over the limit
+	overLimit := connection.MaxBitRate > 0 && (entity.BitRate == 0 || entity.BitRate > connection.MaxBitRate)
+	if supported && !overLimit {
+		return "raw"
+	}
+	return strings.ToLower(connection.SupportedFormats[0])
+}
+
+// GetAlbumList2 returns a list of albums organized by ID3 tags. listType is
+// e.g. "newest", "recent" or "frequent".
+// https://www.subsonic.org/pages/api.jsp#getAlbumList2
+func (connection *SubsonicConnection) GetAlbumList2(listType string, size, offset int) (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	query.Set("type", listType)
+	query.Set("size", strconv.Itoa(size))
+	query.Set("offset", strconv.Itoa(offset))
+	requestUrl := connection.Host + "/rest/getAlbumList2" + "?" + query.Encode()
+	return connection.getResponse("GetAlbumList2", requestUrl)
+}
+
+// GetAlbumListByYear returns the albums released from fromYear to toYear,
+// both included, oldest first. If fromYear is after toYear the newest come
+// first.
+// https://www.subsonic.org/pages/api.jsp#getAlbumList2
+func (connection *SubsonicConnection) GetAlbumListByYear(fromYear, toYear, size, offset int) (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	query.Set("type", "byYear")
+	query.Set("fromYear", strconv.Itoa(fromYear))
+	query.Set("toYear", strconv.Itoa(toYear))
+	query.Set("size", strconv.Itoa(size))
+	query.Set("offset", strconv.Itoa(offset))
+	requestUrl := connection.Host + "/rest/getAlbumList2" + "?" + query.Encode()
+	return connection.getResponse("GetAlbumListByYear", requestUrl)
+}
+
 // Search uses the Subsonic search3 API to query a server for all songs that have
 // ID3 tags that match the query. The query is global, in that it matches in any
 // ID3 field.
@@ -659,18 +1298,86 @@ func (connection *SubsonicConnection) Search(searchTerm string, artistOffset, al
 
 // StartScan tells the Subsonic server to initiate a media library scan. Whether
 // this is a deep or surface scan is dependent on the server implementation.
+// Users without the permission get ErrNotAuthorized.
 // https://subsonic.org/pages/api.jsp#startScan
 func (connection *SubsonicConnection) StartScan() error {
 	query := defaultQuery(connection)
 	requestUrl := fmt.Sprintf("%s/rest/startScan?%s", connection.Host, query.Encode())
 	if res, err := connection.getResponse("StartScan", requestUrl); err != nil {
 		return err
+	} else if err := responseError(res); err != nil {
+		return err
 	} else if !res.ScanStatus.Scanning {
 		return fmt.Errorf("server returned false for scan status on scan attempt")
 	}
 	return nil
 }
 
+// GetScanStatus returns whether the server is scanning the library and how
+// many items it has scanned.
+// https://subsonic.org/pages/api.jsp#getScanStatus
+func (connection *SubsonicConnection) GetScanStatus() (ScanStatus, error) {
+	query := defaultQuery(connection)
+	requestUrl := connection.Host + "/rest/getScanStatus?" + query.Encode()
+	res, err := connection.getResponse("GetScanStatus", requestUrl)
+	if err != nil {
+		return ScanStatus{}, err
+	}
+	if err := responseError(res); err != nil {
+		return ScanStatus{}, err
+	}
+	return res.ScanStatus, nil
+}
+
+// GetUser returns the details and roles of a user. Non-admins may only get
+// themselves.
+// https://subsonic.org/pages/api.jsp#getUser
+func (connection *SubsonicConnection) GetUser(username string) (SubsonicUser, error) {
+	query := defaultQuery(connection)
+	query.Set("username", username)
+	requestUrl := connection.Host + "/rest/getUser?" + query.Encode()
+	res, err := connection.getResponse("GetUser", requestUrl)
+	if err != nil {
+		return SubsonicUser{}, err
+	}
+	if err := responseError(res); err != nil {
+		return SubsonicUser{}, err
+	}
+	return res.User, nil
+}
+
+// GetLicense returns the server's license state.
+// https://www.subsonic.org/pages/api.jsp#getLicense
+func (connection *SubsonicConnection) GetLicense() (SubsonicLicense, error) {
+	query := defaultQuery(connection)
+	requestUrl := connection.Host + "/rest/getLicense?" + query.Encode()
+	res, err := connection.getResponse("GetLicense", requestUrl)
+	if err != nil {
+		return SubsonicLicense{}, err
+	}
+	if err := responseError(res); err != nil {
+		return SubsonicLicense{}, err
+	}
+	return res.License, nil
+}
+
+// GetBookmarks returns the bookmarks of the user.
+// https://www.subsonic.org/pages/api.jsp#getBookmarks
+func (connection *SubsonicConnection) GetBookmarks() (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	requestUrl := connection.Host + "/rest/getBookmarks?" + query.Encode()
+	return connection.getResponse("GetBookmarks", requestUrl)
+}
+
+// GetSong returns the song with the ID.
+// https://www.subsonic.org/pages/api.jsp#getSong
+func (connection *SubsonicConnection) GetSong(id string) (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	query.Set("id", id)
+	requestUrl := connection.Host + "/rest/getSong?" + query.Encode()
+	return connection.getResponse("GetSong", requestUrl)
+}
+
 func (connection *SubsonicConnection) SavePlayQueue(queueIds []string, current string, position int) error {
 	query := defaultQuery(connection)
 	for _, songId := range queueIds {
@@ -688,3 +1395,52 @@ func (connection *SubsonicConnection) LoadPlayQueue() (*SubsonicResponse, error)
 	requestUrl := fmt.Sprintf("%s/rest/getPlayQueue?%s", connection.Host, query.Encode())
 	return connection.getResponse("GetPlayQueue", requestUrl)
 }
+
+func (connection *SubsonicConnection) GetShares() (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	requestUrl := connection.Host + "/rest/getShares" + "?" + query.Encode()
+	return connection.getResponse("GetShares", requestUrl)
+}
+
+// CreateShare creates a public share for a song, album or artist.
+// https://www.subsonic.org/pages/api.jsp#createShare
+func (connection *SubsonicConnection) CreateShare(id, description string) (*SubsonicResponse, error) {
+	query := defaultQuery(connection)
+	query.Set("id", id)
+	if description != "" {
+		query.Set("description", description)
+	}
+	requestUrl := connection.Host + "/rest/createShare" + "?" + query.Encode()
+	resp, err := connection.getResponse("CreateShare", requestUrl)
+	if err != nil {
+		return resp, err
+	}
+	if resp.Status != "ok" {
+		return resp, fmt.Errorf("[CreateShare] server error %d: %s", resp.Error.Code, resp.Error.Message)
+	}
+	return resp, nil
+}
+
+// GetShareUrl returns the URL of a share for the item with the given ID. An
+// existing share is reused if there is one, otherwise a new one is created.
+func (connection *SubsonicConnection) GetShareUrl(id string) (string, error) {
+	description := "stmps share " + id
+
+	if resp, err := connection.GetShares(); err == nil {
+		for _, share := range resp.Shares.Shares {
+			if share.Description == description ||
+				(len(share.Entries) == 1 && share.Entries[0].Id == id) {
+				return share.Url, nil
+			}
+		}
+	}
+
+	resp, err := connection.CreateShare(id, description)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Shares.Shares) == 0 || resp.Shares.Shares[0].Url == "" {
+		return "", errors.New("[CreateShare] server returned no share")
+	}
+	return resp.Shares.Shares[0].Url, nil
+}
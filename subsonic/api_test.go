@@ -1,8 +1,15 @@
 package subsonic
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"image"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -81,7 +88,638 @@ func TestGetResponse(t *testing.T) {
 	}
 }
 
+func TestGetResponseReauthenticates(t *testing.T) {
+	// the server accepts only the new password, the first request fails
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("p") != "new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if _, err := w.Write([]byte(`{"subsonic-response": {"status": "ok"}}`)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	reauthCalls := 0
+	connection := &SubsonicConnection{
+		Host:          server.URL,
+		Password:      "old",
+		PlaintextAuth: true,
+		Reauthenticate: func() (string, error) {
+			reauthCalls++
+			return "new", nil
+		},
+	}
+	response, err := connection.GetServerInfo()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if response.Status != "ok" {
+		t.Errorf("expected status ok, got %q", response.Status)
+	}
+	if reauthCalls != 1 {
+		t.Errorf("expected 1 reauthentication, got %d", reauthCalls)
+	}
+	if connection.Password != "new" {
+		t.Errorf("expected the new password to be kept, got %q", connection.Password)
+	}
+}
+
+func TestGetResponseReauthenticationFails(t *testing.T) {
+	// wrong credentials as a Subsonic error, whatever the password
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := `{"subsonic-response": {"status": "failed", "error": {"code": 40, "message": "Wrong username or password"}}}`
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	var reported error
+	connection := &SubsonicConnection{
+		Host:       server.URL,
+		Password:   "old",
+		AuthFailed: func(err error) { reported = err },
+	}
+	_, err := connection.GetServerInfo()
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("expected ErrAuthFailed, got: %v", err)
+	}
+	if !containsCallerInError(err, "GetServerInfo") {
+		t.Errorf("expected error to contain caller, got: %v", err)
+	}
+	if reported != err {
+		t.Errorf("expected AuthFailed to be called with %v, got %v", err, reported)
+	}
+}
+
 // Helper function to check if the error contains the caller
 func containsCallerInError(err error, caller string) bool {
 	return err != nil && (caller == "" || strings.Contains(err.Error(), "["+caller+"]"))
 }
+
+func TestScanRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch r.URL.Path {
+		case "/rest/getScanStatus":
+			body = `{"subsonic-response": {"status": "ok", "scanStatus": {"scanning": true, "count": 1234}}}`
+		case "/rest/startScan":
+			body = `{"subsonic-response": {"status": "failed", "error": {"code": 50, "message": "User not authorized"}}}`
+		case "/rest/getUser":
+			body = `{"subsonic-response": {"status": "ok", "user": {"username": "` + r.URL.Query().Get("username") + `", "adminRole": true}}}`
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+
+	status, err := connection.GetScanStatus()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if !status.Scanning || status.Count != 1234 {
+		t.Errorf("unexpected scan status %+v", status)
+	}
+
+	if err := connection.StartScan(); !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("expected ErrNotAuthorized, got %v", err)
+	}
+
+	user, err := connection.GetUser("alice")
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if user.Username != "alice" || !user.AdminRole {
+		t.Errorf("unexpected user %+v", user)
+	}
+}
+
+func TestGetPlayUrlOriginalStreams(t *testing.T) {
+	connection := Init(nil)
+	connection.Host = "http://local"
+	song := &SubsonicEntity{Id: "1"}
+
+	if url := connection.GetPlayUrl(song); strings.Contains(url, "format=") {
+		t.Errorf("unexpected format in %s", url)
+	}
+
+	connection.OriginalStreams = true
+	if url := connection.GetPlayUrl(song); !strings.Contains(url, "format=raw") {
+		t.Errorf("expected format=raw in %s", url)
+	}
+
+	// an explicit bitrate still transcodes
+	connection.MaxBitRate = 128
+	url := connection.GetPlayUrl(song)
+	if strings.Contains(url, "format=") || !strings.Contains(url, "maxBitRate=128") {
+		t.Errorf("expected maxBitRate=128 without format in %s", url)
+	}
+}
+
+func TestGetPlayUrlSupportedFormats(t *testing.T) {
+	connection := &SubsonicConnection{Host: "http://localhost", PlaintextAuth: true}
+	connection.SupportedFormats = []string{"Opus", "mp3"}
+
+	for _, c := range []struct {
+		song       SubsonicEntity
+		maxBitRate int
+		format     string
+	}{
+		{SubsonicEntity{Id: "1", Suffix: "mp3", BitRate: 320}, 0, "format=raw"},
+		{SubsonicEntity{Id: "2", Suffix: "FLAC", BitRate: 900}, 0, "format=opus"},
+		{SubsonicEntity{Id: "3", Suffix: "mp3", BitRate: 128}, 192, "format=raw"},
+		// over the limit or unknown bitrate
+		{SubsonicEntity{Id: "4", Suffix: "mp3", BitRate: 320}, 192, "format=opus"},
+		{SubsonicEntity{Id: "5", Suffix: "mp3"}, 192, "format=opus"},
+	} {
+		connection.MaxBitRate = c.maxBitRate
+		if url := connection.GetPlayUrl(&c.song); !strings.Contains(url, c.format) {
+			t.Errorf("song %s: expected %s in %s", c.song.Id, c.format, url)
+		}
+	}
+}
+
+func TestGetAlbumListByYear(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/rest/getAlbumList2" || query.Get("type") != "byYear" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if query.Get("fromYear") != "1989" || query.Get("toYear") != "1980" || query.Get("size") != "500" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		body := `{"subsonic-response": {"status": "ok", "albumList2": {"album": [{"id": "2", "name": "Later", "year": 1988}, {"id": "1", "name": "Earlier", "year": 1981}]}}}`
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+
+	response, err := connection.GetAlbumListByYear(1989, 1980, 500, 0)
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	albums := response.AlbumList2.Albums
+	if len(albums) != 2 || albums[0].Year != 1988 || albums[1].Id != "1" {
+		t.Errorf("unexpected albums %+v", albums)
+	}
+}
+
+func TestGetSimilarSongs2(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/rest/getSimilarSongs2" || query.Get("id") != "ar-1" || query.Get("count") != "20" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		body := `{"subsonic-response": {"status": "ok", "similarSongs2": {"song": [{"id": "s1", "artist": "Other"}]}}}`
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+
+	response, err := connection.GetSimilarSongs2("ar-1", 20)
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	songs := response.SimilarSongs2.Song
+	if len(songs) != 1 || songs[0].Id != "s1" {
+		t.Errorf("unexpected songs %+v", songs)
+	}
+}
+
+func TestGetBookmarks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/getBookmarks" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		body := `{"subsonic-response": {"status": "ok", "bookmarks": {"bookmark": [{"position": 754000, "comment": "", "entry": {"id": "ch3", "title": "Chapter 3"}}]}}}`
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+
+	response, err := connection.GetBookmarks()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	bookmarks := response.Bookmarks.Bookmarks
+	if len(bookmarks) != 1 || bookmarks[0].Position != 754000 || bookmarks[0].Entry.Id != "ch3" {
+		t.Errorf("unexpected bookmarks %+v", bookmarks)
+	}
+}
+
+func TestGetOpenSubsonicExtensions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/getOpenSubsonicExtensions" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		body := `{"subsonic-response": {"status": "ok", "openSubsonic": true, "openSubsonicExtensions": [{"name": "songLyrics", "versions": [1]}, {"name": "transcodeOffset", "versions": [1, 2]}]}}`
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+
+	response, err := connection.GetOpenSubsonicExtensions()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	extensions := response.OpenSubsonicExtensions
+	if len(extensions) != 2 || extensions[0].Name != "songLyrics" || len(extensions[1].Versions) != 2 || extensions[1].Versions[1] != 2 {
+		t.Errorf("unexpected extensions %+v", extensions)
+	}
+}
+
+func TestGetSong(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/getSong" || r.URL.Query().Get("id") != "s1" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		body := `{"subsonic-response": {"status": "ok", "song": {"id": "s1", "title": "Song", "suffix": "flac"}}}`
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+
+	response, err := connection.GetSong("s1")
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if response.Song.Id != "s1" || response.Song.Suffix != "flac" {
+		t.Errorf("unexpected song %+v", response.Song)
+	}
+}
+
+func TestGetStarred2(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/getStarred2" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		body := `{"subsonic-response": {"status": "ok", "starred2": {"song": [{"id": "s1", "title": "Song", "starred": "2024-03-01T20:15:00Z"}]}}}`
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+
+	response, err := connection.GetStarred2()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	songs := response.Starred2.Song
+	if len(songs) != 1 || songs[0].Id != "s1" || songs[0].Starred != "2024-03-01T20:15:00Z" {
+		t.Errorf("unexpected songs %+v", songs)
+	}
+}
+
+func TestSetRating(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/setRating" || r.URL.Query().Get("id") != "al1" || r.URL.Query().Get("rating") != "4" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if _, err := w.Write([]byte(`{"subsonic-response": {"status": "ok"}}`)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+	if err := connection.SetRating("al1", 4); err != nil {
+		t.Errorf("expected no error but got: %v", err)
+	}
+}
+
+func TestResponseCompression(t *testing.T) {
+	// a large artist list compresses well
+	var artists []string
+	for i := 0; i < 2000; i++ {
+		artists = append(artists, fmt.Sprintf(`{"id": "ar%d", "name": "Artist %d", "albumCount": 3}`, i, i))
+	}
+	body := `{"subsonic-response": {"status": "ok", "artists": {"index": [{"name": "A", "artist": [` + strings.Join(artists, ", ") + `]}]}}}`
+
+	var compressedSize int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			compressedSize = 0
+			if _, err := w.Write([]byte(body)); err != nil {
+				t.Fatalf("failed to write server response: %v", err)
+			}
+			return
+		}
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		if _, err := writer.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to compress server response: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("failed to compress server response: %v", err)
+		}
+		compressedSize = compressed.Len()
+		w.Header().Set("Content-Encoding", "gzip")
+		if _, err := w.Write(compressed.Bytes()); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+	response, err := connection.GetArtists()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if len(response.Artists.Index) != 1 || len(response.Artists.Index[0].Artists) != 2000 {
+		t.Fatalf("unexpected artists %+v", response.Artists.Index)
+	}
+	if compressedSize == 0 || compressedSize > len(body)/5 {
+		t.Errorf("expected a compressed response much smaller than %d bytes, got %d", len(body), compressedSize)
+	}
+
+	connection.DisableCompression = true
+	if _, err = connection.GetArtists(); err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if compressedSize != 0 {
+		t.Errorf("expected an uncompressed response with DisableCompression")
+	}
+}
+
+func TestStarIdsBatches(t *testing.T) {
+	items := StarIds{
+		Ids:       []string{"s1", "s2", "s3"},
+		AlbumIds:  []string{"al1"},
+		ArtistIds: []string{"ar1"},
+	}
+	batches := items.batches(2)
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches, got %+v", batches)
+	}
+	if batches[0].Len() != 2 || batches[0].ArtistIds[0] != "ar1" || batches[0].AlbumIds[0] != "al1" {
+		t.Errorf("unexpected first batch %+v", batches[0])
+	}
+	if batches[2].Len() != 1 || batches[2].Ids[0] != "s3" {
+		t.Errorf("unexpected last batch %+v", batches[2])
+	}
+	if len(StarIds{}.batches(2)) != 0 {
+		t.Errorf("expected no batches without IDs")
+	}
+}
+
+func TestSetStarred(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		requests = append(requests, r.URL.Path+" "+strings.Join(query["id"], ",")+" "+strings.Join(query["albumId"], ","))
+		body := `{"subsonic-response": {"status": "ok"}}`
+		if len(requests) == 3 {
+			body = `{"subsonic-response": {"status": "failed", "error": {"code": 70, "message": "Not found"}}}`
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+
+	items := StarIds{AlbumIds: []string{"al1"}}
+	for i := 0; i < 2*StarBatchSize; i++ {
+		items.Ids = append(items.Ids, "s"+strconv.Itoa(i))
+	}
+	done := 0
+	err := connection.SetStarred(true, items, func(batch StarIds) {
+		done += batch.Len()
+	})
+	if err == nil {
+		t.Errorf("expected the third request to fail")
+	}
+	if done != 2*StarBatchSize {
+		t.Errorf("expected %d starred before the failure, got %d", 2*StarBatchSize, done)
+	}
+	if len(requests) != 3 || !strings.HasPrefix(requests[0], "/rest/star s0,") || !strings.HasSuffix(requests[0], " al1") {
+		t.Errorf("unexpected requests %v", requests)
+	}
+
+	requests = nil
+	if err := connection.SetStarred(false, StarIds{Ids: []string{"s1"}}, func(StarIds) {}); err != nil {
+		t.Errorf("expected no error but got: %v", err)
+	}
+	if len(requests) != 1 || requests[0] != "/rest/unstar s1 " {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestPing(t *testing.T) {
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/ping" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		body := `{"subsonic-response": {"status": "ok"}}`
+		if failing {
+			body = `{"subsonic-response": {"status": "failed", "error": {"code": 0, "message": "busy"}}}`
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+
+	latency, err := connection.Ping()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if latency <= 0 {
+		t.Errorf("expected a latency, got %v", latency)
+	}
+
+	failing = true
+	if _, err := connection.Ping(); err == nil {
+		t.Error("expected an error for a failed response")
+	}
+
+	server.Close()
+	if _, err := connection.Ping(); err == nil {
+		t.Error("expected an error for an unreachable server")
+	}
+}
+
+func TestImportPlaylist(t *testing.T) {
+	var created, added []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch r.URL.Path {
+		case "/rest/createPlaylist":
+			if query.Get("name") != "Road Trip" {
+				t.Errorf("unexpected name %q", query.Get("name"))
+			}
+			created = append(created, query["songId"]...)
+		case "/rest/updatePlaylist":
+			if query.Get("playlistId") != "7" {
+				t.Errorf("unexpected playlist %q", query.Get("playlistId"))
+			}
+			added = append(added, query["songIdToAdd"]...)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		body := `{"subsonic-response": {"status": "ok", "playlist": {"id": "7", "name": "Road Trip"}}}`
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+
+	songIds := make([]string, PlaylistBatchSize+5)
+	for i := range songIds {
+		songIds[i] = strconv.Itoa(i)
+	}
+	id, err := connection.ImportPlaylist("Road Trip", songIds)
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if id != "7" {
+		t.Errorf("expected playlist 7, got %q", id)
+	}
+	if len(created) != PlaylistBatchSize || len(added) != 5 || added[0] != strconv.Itoa(PlaylistBatchSize) {
+		t.Errorf("unexpected batches: %d created, %v added", len(created), added)
+	}
+}
+
+func TestGetArtistCached(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body := `{"subsonic-response": {"status": "ok", "artist": {"id": "ar1", "name": "Artist", "album": [{"id": "al1", "name": "Album"}]}}}`
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true, directoryCache: map[string]SubsonicResponse{}}
+	if connection.IsCached("ar1") {
+		t.Errorf("expected the artist not to be cached yet")
+	}
+
+	// fetched in the background by the browser
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := connection.GetArtist("ar1"); err != nil {
+			t.Errorf("expected no error but got: %v", err)
+		}
+	}()
+	<-done
+
+	if !connection.IsCached("ar1") {
+		t.Errorf("expected the artist to be cached")
+	}
+	if _, err := connection.GetArtist("ar1"); err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+
+	connection.RemoveCacheEntry("ar1")
+	if connection.IsCached("ar1") {
+		t.Errorf("expected the artist to be removed from the cache")
+	}
+}
+
+func TestGetLicense(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/getLicense" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		body := `{"subsonic-response": {"status": "ok", "license": {"valid": false, "trialExpires": "2024-01-31T00:00:00.000Z"}}}`
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{Host: server.URL, PlaintextAuth: true}
+
+	license, err := connection.GetLicense()
+	if err != nil {
+		t.Fatalf("expected no error but got: %v", err)
+	}
+	if license.Valid || license.TrialExpires != "2024-01-31T00:00:00.000Z" {
+		t.Errorf("unexpected license %+v", license)
+	}
+}
+
+func TestGetArtistImageWithoutImage(t *testing.T) {
+	var paths []string
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body := `{"subsonic-response": {"status": "ok", "artistInfo": {}, "artistInfo2": {}, "artist": {"id": "ar1"}}}`
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("failed to write server response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	connection := &SubsonicConnection{
+		Host:           server.URL,
+		PlaintextAuth:  true,
+		directoryCache: map[string]SubsonicResponse{},
+		artistImages:   map[string]image.Image{},
+	}
+
+	// a failed request is retried next time
+	if _, err := connection.GetArtistImage("ar1", true); err == nil {
+		t.Errorf("expected an error")
+	}
+	failing = false
+	art, err := connection.GetArtistImage("ar1", true)
+	if err != nil || art != nil {
+		t.Errorf("expected no image and no error, got %v, %v", art, err)
+	}
+	// artist folders are looked up with getArtistInfo
+	if _, err := connection.GetArtistImage("dir1", false); err != nil {
+		t.Errorf("expected no error but got: %v", err)
+	}
+	want := []string{"/rest/getArtistInfo2", "/rest/getArtistInfo2", "/rest/getArtist", "/rest/getArtistInfo"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("requests = %v, want %v", paths, want)
+	}
+
+	// missing images are cached
+	paths = nil
+	if _, err := connection.GetArtistImage("ar1", true); err != nil {
+		t.Errorf("expected no error but got: %v", err)
+	}
+	if len(paths) != 0 {
+		t.Errorf("expected no requests, got %v", paths)
+	}
+}
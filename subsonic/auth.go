@@ -0,0 +1,111 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package subsonic
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ErrAuthFailed is returned when the server rejected the credentials and
+// re-authenticating didn't help.
+var ErrAuthFailed = errors.New("authentication failed")
+
+// ErrNotAuthorized is returned when the user lacks the role for a request,
+// e.g. library scans for non-admins.
+var ErrNotAuthorized = errors.New("not authorized")
+
+// Subsonic error codes for rejected credentials
+// https://www.subsonic.org/pages/api.jsp#error
+const (
+	errorCodeWrongCredentials = 40
+	errorCodeInvalidApiKey    = 44 // OpenSubsonic only
+	errorCodeNotAuthorized    = 50
+)
+
+// isAuthError reports whether a response means the credentials were
+// rejected, either by HTTP status or by a Subsonic error.
+func isAuthError(statusCode int, response *SubsonicResponse) bool {
+	if statusCode == http.StatusUnauthorized {
+		return true
+	}
+	if response == nil || response.Status != "failed" {
+		return false
+	}
+	switch response.Error.Code {
+	case errorCodeWrongCredentials, errorCodeInvalidApiKey:
+		return true
+	}
+	return false
+}
+
+// responseError returns the error of a failed response, wrapping
+// ErrNotAuthorized for missing permissions.
+func responseError(response *SubsonicResponse) error {
+	if response == nil || response.Status != "failed" {
+		return nil
+	}
+	if response.Error.Code == errorCodeNotAuthorized {
+		return fmt.Errorf("%w: %s", ErrNotAuthorized, response.Error.Message)
+	}
+	return fmt.Errorf("server error %d: %s", response.Error.Code, response.Error.Message)
+}
+
+// setAuth sets the credential parameters of query, with a new salt for
+// token authentication.
+func (connection *SubsonicConnection) setAuth(query url.Values) {
+	connection.authLock.Lock()
+	password := connection.Password
+	connection.authLock.Unlock()
+
+	query.Del("p")
+	query.Del("t")
+	query.Del("s")
+	if connection.PlaintextAuth {
+		query.Set("p", password)
+	} else {
+		token, salt := authToken(password)
+		query.Set("t", token)
+		query.Set("s", salt)
+	}
+}
+
+// reauthenticate gets new credentials from Reauthenticate, if set, and
+// returns requestUrl with them. Without Reauthenticate the password is
+// salted again.
+func (connection *SubsonicConnection) reauthenticate(requestUrl string) (string, error) {
+	if connection.Reauthenticate != nil {
+		password, err := connection.Reauthenticate()
+		if err != nil {
+			return "", err
+		}
+		connection.authLock.Lock()
+		connection.Password = password
+		connection.authLock.Unlock()
+	}
+
+	u, err := url.Parse(requestUrl)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	connection.setAuth(query)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// authFailed reports rejected credentials to AuthFailed and returns the
+// error for the caller.
+func (connection *SubsonicConnection) authFailed(caller string, cause error) error {
+	err := fmt.Errorf("[%s] %w: %v", caller, ErrAuthFailed, cause)
+	if connection.logger != nil {
+		connection.logger.PrintError("reauthenticate", err)
+	}
+	if connection.AuthFailed != nil {
+		connection.AuthFailed(err)
+	}
+	return err
+}
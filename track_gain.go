@@ -0,0 +1,99 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/spezifisch/stmps/mpvplayer"
+)
+
+// largest remembered gain in dB, more is surely a mistake
+const maxTrackGain = 40
+
+// trackGains are remembered volume offsets in dB by song ID, applied on top
+// of the volume and ReplayGain whenever the song plays.
+type trackGains map[string]float64
+
+// volumeOffset returns the gain in dB between two volumes in percent. mpv's
+// volume is cubic, so 50% is about -18 dB.
+func volumeOffset(from, to int) float64 {
+	if from <= 0 || to <= 0 {
+		return 0
+	}
+	return 60 * math.Log10(float64(to)/float64(from))
+}
+
+// roundGain rounds to a tenth of a dB, smaller differences aren't audible.
+func roundGain(db float64) float64 {
+	return math.Round(db*10) / 10
+}
+
+// remember adds the volume change from one volume to another to the gain of
+// the song, or forgets the gain if there was no change. It returns the gain
+// now applied.
+func (g trackGains) remember(id string, from, to int) float64 {
+	if from == to {
+		delete(g, id)
+		return 0
+	}
+	gain := roundGain(g[id] + volumeOffset(from, to))
+	gain = max(-maxTrackGain, min(gain, maxTrackGain))
+	if gain == 0 {
+		delete(g, id)
+	} else {
+		g[id] = gain
+	}
+	return gain
+}
+
+// handleRememberTrackGain remembers the volume change since the current song
+// started as its gain, and sets the volume back so the song sounds the same.
+// Without a volume change, the song's gain is forgotten. The gains are saved
+// right away. The queued songs carry their gain, the player applies it when
+// they start.
+func (ui *Ui) handleRememberTrackGain() {
+	song, err := ui.player.GetQueueItem(0)
+	if err != nil {
+		ui.showNotice("Nothing playing")
+		return
+	}
+	volume, err := ui.player.GetVolume()
+	if err != nil {
+		ui.logger.PrintError("handleRememberTrackGain", err)
+		return
+	}
+
+	startVolume := ui.player.StartVolume()
+	if startVolume < 0 {
+		startVolume = volume
+	}
+	_, hadGain := ui.trackGains[song.Id]
+	if volume == startVolume && !hadGain {
+		ui.showNotice("Change the volume first to remember it for " + song.Title)
+		return
+	}
+	gain := ui.trackGains.remember(song.Id, startVolume, volume)
+	if err := ui.player.SetVolume(startVolume); err != nil {
+		ui.logger.PrintError("handleRememberTrackGain: SetVolume", err)
+	}
+	if err := ui.player.SetTrackGain(gain); err != nil {
+		ui.logger.PrintError("SetTrackGain", err)
+		ui.showNotice("Setting the song's gain failed, it needs mpv 0.36 or newer")
+	}
+	ui.player.UpdateQueueItems(song.Id, func(item *mpvplayer.QueueItem) {
+		item.TrackGain = gain
+	})
+
+	if gain == 0 {
+		ui.showNotice("Forgot the volume of " + song.Title)
+	} else {
+		ui.showNotice(fmt.Sprintf("%s now plays at %+.1f dB", song.Title, gain))
+	}
+	ui.serverSettings.TrackGains = ui.trackGains
+	if serverStatePath() != "" {
+		ui.storeServerSettings()
+	}
+}
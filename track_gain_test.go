@@ -0,0 +1,45 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestVolumeOffset(t *testing.T) {
+	if offset := volumeOffset(100, 50); math.Abs(offset+18.06) > 0.01 {
+		t.Errorf("expected about -18 dB from 100%% to 50%%, got %f", offset)
+	}
+	if offset := volumeOffset(50, 100); math.Abs(offset-18.06) > 0.01 {
+		t.Errorf("expected about +18 dB from 50%% to 100%%, got %f", offset)
+	}
+	if offset := volumeOffset(0, 50); offset != 0 {
+		t.Errorf("expected no offset from a muted volume, got %f", offset)
+	}
+}
+
+func TestTrackGainsRemember(t *testing.T) {
+	gains := trackGains{}
+
+	if gain := gains.remember("s1", 100, 50); gain != -18.1 || gains["s1"] != -18.1 {
+		t.Errorf("expected -18.1 dB, got %f (%v)", gain, gains)
+	}
+	// changes add up
+	if gain := gains.remember("s1", 50, 100); gain != 0 {
+		t.Errorf("expected the gain to cancel out, got %f", gain)
+	}
+	if _, ok := gains["s1"]; ok {
+		t.Errorf("expected a zero gain to be forgotten, got %v", gains)
+	}
+
+	gains.remember("s2", 80, 40)
+	if gain := gains.remember("s2", 80, 80); gain != 0 || len(gains) != 0 {
+		t.Errorf("expected the gain to be forgotten without a volume change, got %f (%v)", gain, gains)
+	}
+
+	if gain := gains.remember("s3", 1, 100); gain != maxTrackGain {
+		t.Errorf("expected the gain to be capped at %d dB, got %f", maxTrackGain, gain)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"github.com/rivo/tview"
+	"github.com/spf13/viper"
+)
+
+// viewPosition is the selected entry and scroll offset of a view's main list.
+type viewPosition struct {
+	Selected int `toml:"selected"`
+	Offset   int `toml:"offset"`
+}
+
+// clamp keeps the position within a list of count entries, which may have
+// shrunk since the position was remembered.
+func (p viewPosition) clamp(count int) viewPosition {
+	if count <= 0 {
+		return viewPosition{}
+	}
+	p.Selected = max(0, min(p.Selected, count-1))
+	p.Offset = max(0, min(p.Offset, p.Selected))
+	return p
+}
+
+// positionedList is the part of tview.List and tview.Table a view position
+// is read from and restored to.
+type positionedList interface {
+	count() int
+	position() viewPosition
+	setPosition(p viewPosition)
+}
+
+type positionedTviewList struct{ *tview.List }
+
+func (l positionedTviewList) count() int {
+	return l.GetItemCount()
+}
+
+func (l positionedTviewList) position() viewPosition {
+	offset, _ := l.GetOffset()
+	return viewPosition{Selected: l.GetCurrentItem(), Offset: offset}
+}
+
+func (l positionedTviewList) setPosition(p viewPosition) {
+	l.SetCurrentItem(p.Selected)
+	l.SetOffset(p.Offset, 0)
+}
+
+type positionedTviewTable struct{ *tview.Table }
+
+func (t positionedTviewTable) count() int {
+	return t.GetRowCount()
+}
+
+func (t positionedTviewTable) position() viewPosition {
+	row, _ := t.GetSelection()
+	offset, _ := t.GetOffset()
+	return viewPosition{Selected: row, Offset: offset}
+}
+
+func (t positionedTviewTable) setPosition(p viewPosition) {
+	t.Select(p.Selected, 0)
+	t.SetOffset(p.Offset, 0)
+}
+
+// positionedView returns the main list of a view, nil if the view has none
+// whose position is remembered.
+func (ui *Ui) positionedView(name string) positionedList {
+	switch name {
+	case PageBrowser:
+		return positionedTviewList{ui.browserPage.artistList}
+	case PageQueue:
+		return positionedTviewTable{ui.queuePage.queueList}
+	case PagePlaylists:
+		return positionedTviewList{ui.playlistPage.playlistList}
+	case PageNew:
+		return positionedTviewTable{ui.newPage.songTable}
+	case PageDecades:
+		return positionedTviewList{ui.decadesPage.decadeList}
+	case PageStarred:
+		return positionedTviewTable{ui.starredPage.songTable}
+	}
+	return nil
+}
+
+// rememberViewPosition stores the position of a view, e.g. before leaving it
+// or rebuilding its list. Empty lists, e.g. while loading, are skipped so
+// they don't overwrite the position to return to.
+func (ui *Ui) rememberViewPosition(name string) {
+	view := ui.positionedView(name)
+	if view == nil || view.count() == 0 {
+		return
+	}
+	if ui.viewPositions == nil {
+		ui.viewPositions = map[string]viewPosition{}
+	}
+	ui.viewPositions[name] = view.position()
+}
+
+// restoreViewPosition moves a view back to its remembered position, clamped
+// to its current length. The position is kept for empty lists, so it can be
+// restored once they're loaded.
+func (ui *Ui) restoreViewPosition(name string) {
+	view := ui.positionedView(name)
+	if view == nil || view.count() == 0 {
+		return
+	}
+	position, ok := ui.viewPositions[name]
+	if !ok {
+		return
+	}
+	view.setPosition(position.clamp(view.count()))
+}
+
+// restoreViewPositions restores the positions stored with
+// ui.remember-positions at startup.
+func (ui *Ui) restoreViewPositions() {
+	if !viper.GetBool("ui.remember-positions") {
+		return
+	}
+	ui.viewPositions = map[string]viewPosition{}
+	for name, position := range ui.serverSettings.ViewPositions {
+		ui.viewPositions[name] = position
+	}
+	for _, name := range allViews {
+		ui.restoreViewPosition(name)
+	}
+}
+
+// storeViewPositions copies the positions to the server settings, so they
+// are saved for the next start with ui.remember-positions.
+func (ui *Ui) storeViewPositions() {
+	if !viper.GetBool("ui.remember-positions") {
+		ui.serverSettings.ViewPositions = nil
+		return
+	}
+	ui.rememberViewPosition(ui.menuWidget.GetActivePage())
+	ui.serverSettings.ViewPositions = ui.viewPositions
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewPositionClamp(t *testing.T) {
+	position := viewPosition{Selected: 40, Offset: 30}
+	assert.Equal(t, position, position.clamp(100))
+	// the list shrank
+	assert.Equal(t, viewPosition{Selected: 9, Offset: 9}, position.clamp(10))
+	assert.Equal(t, viewPosition{}, position.clamp(0))
+	assert.Equal(t, viewPosition{}, viewPosition{Selected: -1, Offset: -1}.clamp(5))
+}
+
+func TestPositionedTviewList(t *testing.T) {
+	list := positionedTviewList{tview.NewList()}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		list.AddItem(name, "", 0, nil)
+	}
+	list.setPosition(viewPosition{Selected: 2, Offset: 1})
+	assert.Equal(t, viewPosition{Selected: 2, Offset: 1}, list.position())
+
+	table := positionedTviewTable{tview.NewTable().SetSelectable(true, false)}
+	for row := 0; row < 4; row++ {
+		table.SetCellSimple(row, 0, "x")
+	}
+	table.setPosition(viewPosition{Selected: 3, Offset: 2})
+	assert.Equal(t, viewPosition{Selected: 3, Offset: 2}, table.position())
+}
+
+func TestViewPositionsStored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), serverStateFileName)
+	settings := serverSettings{Volume: 80, ViewPositions: map[string]viewPosition{
+		PageBrowser: {Selected: 12, Offset: 3},
+	}}
+	assert.NoError(t, saveServerSettings(path, "admin@http://local", settings))
+
+	loaded, err := loadServerSettings(path, "admin@http://local", serverSettings{})
+	assert.NoError(t, err)
+	assert.Equal(t, settings.ViewPositions, loaded.ViewPositions)
+}
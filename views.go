@@ -0,0 +1,91 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// allViews are the main pages in their default order.
+var allViews = []string{PageBrowser, PageQueue, PagePlaylists, PageSearch, PageLog, PageNew, PageStats, PageDecades, PageStarred}
+
+// parseViews checks a ui.views list: known pages, each at most once.
+func parseViews(value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a list of views, got %v", value)
+	}
+	if len(list) == 0 {
+		return nil, errors.New("at least one view must be enabled")
+	}
+
+	var views []string
+	for _, entry := range list {
+		view, ok := entry.(string)
+		if !ok || !containsString(allViews, view) {
+			return nil, fmt.Errorf("expected one of %s, got %v", strings.Join(allViews, ", "), entry)
+		}
+		if containsString(views, view) {
+			return nil, fmt.Errorf("%s is listed twice", view)
+		}
+		views = append(views, view)
+	}
+	return views, nil
+}
+
+func isViewList(value interface{}) error {
+	_, err := parseViews(value)
+	return err
+}
+
+// loadViews returns the enabled views in the order of ui.views, all views
+// if it isn't set. The config was validated at startup.
+func loadViews() []string {
+	if !viper.IsSet("ui.views") {
+		return allViews
+	}
+	views, err := parseViews(viper.Get("ui.views"))
+	if err != nil {
+		return allViews
+	}
+	return views
+}
+
+// startupView returns ui.startup-view if it's one of the views, the first
+// view otherwise.
+func startupView(views []string) string {
+	view := viper.GetString("ui.startup-view")
+	if containsString(views, view) {
+		return view
+	}
+	return views[0]
+}
+
+// isViewEnabled reports whether the page is one of the enabled views.
+func (ui *Ui) isViewEnabled(name string) bool {
+	return containsString(ui.views, name)
+}
+
+// showViewAt shows the enabled view at index, for the number keys.
+func (ui *Ui) showViewAt(index int) {
+	if index >= 0 && index < len(ui.views) {
+		ui.ShowPage(ui.views[index])
+	}
+}
+
+// cycleView shows the next (step 1) or previous (step -1) enabled view,
+// wrapping around at the ends.
+func (ui *Ui) cycleView(step int) {
+	index := 0
+	for i, view := range ui.views {
+		if view == ui.menuWidget.GetActivePage() {
+			index = i
+		}
+	}
+	ui.ShowPage(ui.views[(index+step+len(ui.views))%len(ui.views)])
+}
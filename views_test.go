@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseViews(t *testing.T) {
+	views, err := parseViews([]interface{}{"queue", "browser"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{PageQueue, PageBrowser}, views)
+
+	_, err = parseViews([]interface{}{"queue", "library"})
+	assert.ErrorContains(t, err, "library")
+	_, err = parseViews([]interface{}{"queue", "queue"})
+	assert.ErrorContains(t, err, "twice")
+	_, err = parseViews([]interface{}{})
+	assert.Error(t, err)
+	_, err = parseViews("queue")
+	assert.Error(t, err)
+}
+
+func TestLoadViewsDefault(t *testing.T) {
+	loadTestConfig(t, ``)
+
+	views := loadViews()
+	assert.Equal(t, allViews, views)
+	assert.Equal(t, PageBrowser, startupView(views))
+}
+
+func TestStartupView(t *testing.T) {
+	loadTestConfig(t, `
+[ui]
+views = ['queue', 'search', 'stats']
+startup-view = 'search'
+`)
+
+	views := loadViews()
+	assert.Equal(t, []string{PageQueue, PageSearch, PageStats}, views)
+	assert.Equal(t, PageSearch, startupView(views))
+
+	// a disabled view falls back to the first one
+	loadTestConfig(t, `
+[ui]
+views = ['queue', 'search']
+startup-view = 'browser'
+`)
+	assert.Equal(t, PageQueue, startupView(loadViews()))
+}
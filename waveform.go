@@ -0,0 +1,117 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+)
+
+const (
+	// number of peaks computed per song, they are resampled to the widget width
+	waveformPeakCount = 400
+	// the pre-scan decodes to mono at this sample rate, which is plenty for
+	// a coarse overview and keeps the amount of data small
+	waveformSampleRate = 2000
+)
+
+var errNoFfmpeg = errors.New("ffmpeg not found")
+
+// computePeaks pre-scans a stream with ffmpeg and returns count peak levels
+// in the range [0, 1]. No Subsonic API exposes waveform data, so the stream is
+// fetched a second time for this.
+func computePeaks(ctx context.Context, uri string, count int) ([]float64, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, errNoFfmpeg
+	}
+
+	cmd := exec.CommandContext(ctx, ffmpeg,
+		"-nostdin", "-v", "error",
+		"-i", uri,
+		"-vn", "-ac", "1", "-ar", fmt.Sprint(waveformSampleRate),
+		"-f", "s16le", "-")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	pcm, readErr := io.ReadAll(stdout)
+	if err = cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("ffmpeg: %v", err)
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	samples := make([]int16, len(pcm)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(pcm[2*i:]))
+	}
+	return peaksFromSamples(samples, count), nil
+}
+
+// peaksFromSamples splits samples into count buckets and returns the absolute
+// peak of each bucket, normalized so that the loudest bucket is 1.
+func peaksFromSamples(samples []int16, count int) []float64 {
+	if len(samples) == 0 || count <= 0 {
+		return nil
+	}
+	if count > len(samples) {
+		count = len(samples)
+	}
+
+	peaks := make([]float64, count)
+	max := 0.0
+	for i, sample := range samples {
+		level := float64(sample)
+		if level < 0 {
+			level = -level
+		}
+		bucket := i * count / len(samples)
+		if level > peaks[bucket] {
+			peaks[bucket] = level
+		}
+		if level > max {
+			max = level
+		}
+	}
+
+	if max > 0 {
+		for i := range peaks {
+			peaks[i] /= max
+		}
+	}
+	return peaks
+}
+
+// resamplePeaks maps peaks onto width cells, each cell gets the maximum of
+// the peaks it covers.
+func resamplePeaks(peaks []float64, width int) []float64 {
+	if len(peaks) == 0 || width <= 0 {
+		return nil
+	}
+
+	cells := make([]float64, width)
+	for i := range cells {
+		start := i * len(peaks) / width
+		end := (i + 1) * len(peaks) / width
+		if end <= start {
+			end = start + 1
+		}
+		for _, peak := range peaks[start:end] {
+			if peak > cells[i] {
+				cells[i] = peak
+			}
+		}
+	}
+	return cells
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeaksFromSamples(t *testing.T) {
+	samples := []int16{0, 100, -200, 50, 400, -400, 0, 0}
+
+	assert.Equal(t, []float64{0.25, 0.5, 1, 0}, peaksFromSamples(samples, 4))
+	assert.Len(t, peaksFromSamples(samples, 100), len(samples))
+	assert.Nil(t, peaksFromSamples(nil, 4))
+}
+
+func TestResamplePeaks(t *testing.T) {
+	peaks := []float64{0.1, 0.2, 0.9, 0.4}
+
+	assert.Equal(t, []float64{0.2, 0.9}, resamplePeaks(peaks, 2))
+	assert.Equal(t, []float64{0.1, 0.1, 0.2, 0.2, 0.9, 0.9, 0.4, 0.4}, resamplePeaks(peaks, 8))
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/spf13/viper"
+)
+
+// the kind of item a web UI link should point to
+const (
+	webUIArtist = "artist"
+	webUIAlbum  = "album"
+)
+
+// webUIDeepLink builds a link to an artist or album on the server's web
+// interface. serverType is the OpenSubsonic server type reported by ping. If
+// the server doesn't have predictable URLs, the base URL is returned as is.
+func webUIDeepLink(baseUrl, serverType, kind, id string) string {
+	if id == "" {
+		return baseUrl
+	}
+
+	base := strings.TrimSuffix(baseUrl, "/")
+	switch strings.ToLower(serverType) {
+	case "navidrome":
+		// single page app with hash routing, e.g. https://host/app/#/album/<id>/show
+		return base + "/#/" + kind + "/" + url.PathEscape(id) + "/show"
+
+	case "", "subsonic", "airsonic", "airsonic-advanced":
+		// classic Subsonic web interface, works for both artist and album directories
+		return base + "/main.view?id=" + url.QueryEscape(id)
+	}
+
+	return baseUrl
+}
+
+// getServerInfo returns the (cached) ping response with the OpenSubsonic
+// server details, nil if the server can't be reached.
+func (ui *Ui) getServerInfo() *subsonic.SubsonicResponse {
+	if ui.serverInfo == nil {
+		response, err := ui.connection.GetServerInfo()
+		if err != nil {
+			ui.logger.PrintError("GetServerInfo", err)
+			return nil
+		}
+		ui.serverInfo = response
+	}
+	return ui.serverInfo
+}
+
+// serverType returns the OpenSubsonic server type.
+func (ui *Ui) serverType() string {
+	if info := ui.getServerInfo(); info != nil {
+		return info.Type
+	}
+	return ""
+}
+
+// playedDatesSupported reports whether the server tells when songs were last
+// played, which OpenSubsonic servers do.
+func (ui *Ui) playedDatesSupported() bool {
+	info := ui.getServerInfo()
+	return info != nil && info.OpenSubsonic
+}
+
+// webUITarget returns which item should be opened in the web UI: the selected
+// item on the browser page or the currently playing track's album elsewhere.
+func (ui *Ui) webUITarget() (kind, id string) {
+	if ui.menuWidget.GetActivePage() == PageBrowser {
+		if kind, id = ui.browserPage.selectedWebUITarget(); id != "" {
+			return
+		}
+	}
+
+	if len(ui.queuePage.queueData.playerQueue) > 0 {
+		return webUIAlbum, ui.queuePage.queueData.playerQueue[0].AlbumId
+	}
+	return "", ""
+}
+
+// handleOpenWebUI opens the current item on the server's web interface. If
+// there's no browser to open it with, the link is copied to the clipboard, or
+// shown if that doesn't work either.
+func (ui *Ui) handleOpenWebUI() {
+	baseUrl := viper.GetString("server.web-ui-url")
+	if baseUrl == "" {
+		ui.showMessageBox("Set server.web-ui-url in the config to open items in the web interface.")
+		return
+	}
+
+	kind, id := ui.webUITarget()
+	link := webUIDeepLink(baseUrl, ui.serverType(), kind, id)
+	if err := ui.openLink(link); err != nil {
+		ui.logger.PrintError("handleOpenWebUI", err)
+	}
+}
+
+// openLink opens link in a browser, and falls back to the clipboard and
+// finally to just showing the link.
+func (ui *Ui) openLink(link string) error {
+	errOpen := openInBrowser(link)
+	if errOpen == nil {
+		ui.logger.Printf("opened %s", link)
+		return nil
+	}
+
+	if errCopy := copyToClipboard(link); errCopy == nil {
+		ui.showMessageBox("No browser available, link copied to clipboard:\n" + link)
+		return nil
+	} else {
+		ui.showMessageBox(link)
+		return errors.Join(errOpen, errCopy)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// aboutInfo is what the about popup shows, e.g. for bug reports.
+type aboutInfo struct {
+	version   string
+	goVersion string
+	// commit stmps was built from, empty if unknown
+	revision   string
+	commitTime string
+	// built with uncommitted changes
+	modified bool
+
+	mpvVersion string
+
+	host string
+	// ping response, nil while loading or if the server can't be reached
+	server     *subsonic.SubsonicResponse
+	serverErr  error
+	extensions []subsonic.OpenSubsonicExtension
+	// license state, empty if the server doesn't report one
+	license string
+}
+
+// readBuildInfo fills in the Go version and the commit stmps was built from.
+func (a *aboutInfo) readBuildInfo() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	a.goVersion = info.GoVersion
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			a.revision = setting.Value
+		case "vcs.time":
+			a.commitTime = setting.Value
+		case "vcs.modified":
+			a.modified = setting.Value == "true"
+		}
+	}
+}
+
+func (a aboutInfo) text() string {
+	var text strings.Builder
+	line := func(label, value string) {
+		if value == "" {
+			value = "[gray]unknown[-]"
+		} else {
+			value = tview.Escape(value)
+		}
+		fmt.Fprintf(&text, "  %-14s%s\n", label, value)
+	}
+
+	text.WriteString("[::b]stmps[::-]\n")
+	line("Version", a.version)
+	line("Go", a.goVersion)
+	commit := a.revision
+	if commit != "" && a.modified {
+		commit += " (modified)"
+	}
+	line("Commit", commit)
+	if a.commitTime != "" {
+		line("Commit time", a.commitTime)
+	}
+	line("mpv", a.mpvVersion)
+
+	text.WriteString("\n[::b]Server[::-]\n")
+	line("URL", a.host)
+	switch {
+	case a.serverErr != nil:
+		fmt.Fprintf(&text, "  [red]%s[-]\n", tview.Escape(a.serverErr.Error()))
+	case a.server == nil:
+		text.WriteString("  [gray]Loading…[-]\n")
+	default:
+		line("Type", a.server.Type)
+		line("Version", a.server.ServerVersion)
+		line("API version", a.server.Version)
+		line("License", a.license)
+		if !a.server.OpenSubsonic {
+			line("OpenSubsonic", "no")
+			break
+		}
+		line("OpenSubsonic", "yes")
+		extensions := make([]string, len(a.extensions))
+		for i, extension := range a.extensions {
+			versions := make([]string, len(extension.Versions))
+			for j, version := range extension.Versions {
+				versions[j] = fmt.Sprint(version)
+			}
+			extensions[i] = fmt.Sprintf("%s (%s)", extension.Name, strings.Join(versions, ", "))
+		}
+		if len(extensions) == 0 {
+			line("Extensions", "none")
+		} else {
+			line("Extensions", extensions[0])
+			for _, extension := range extensions[1:] {
+				line("", extension)
+			}
+		}
+	}
+	return text.String()
+}
+
+// AboutWidget shows the versions of stmps, the server and mpv.
+type AboutWidget struct {
+	Root *tview.TextView
+
+	visible bool
+
+	// external refs
+	ui *Ui
+}
+
+func (ui *Ui) createAboutWidget() (w *AboutWidget) {
+	w = &AboutWidget{
+		ui: ui,
+	}
+
+	w.Root = tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true)
+	w.Root.Box.
+		SetTitle(" about ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+	w.Root.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Rune() == 'w' {
+			ui.CloseAbout()
+			return nil
+		}
+		return event
+	})
+
+	return
+}
+
+// ShowAbout shows the about popup. The server details are fetched in the
+// background.
+func (ui *Ui) ShowAbout() {
+	info := aboutInfo{
+		version: Version,
+		host:    ui.connection.Host,
+		server:  ui.serverInfo,
+		license: ui.licenseText,
+	}
+	info.readBuildInfo()
+	if version, err := ui.player.MpvVersion(); err != nil {
+		ui.logger.PrintError("MpvVersion", err)
+	} else {
+		info.mpvVersion = version
+	}
+
+	ui.aboutWidget.Root.SetText(info.text())
+	ui.pages.ShowPage(PageAbout)
+	ui.pages.SendToFront(PageAbout)
+	ui.app.SetFocus(ui.aboutWidget.Root)
+	ui.aboutWidget.visible = true
+
+	go func() {
+		if info.server == nil {
+			info.server, info.serverErr = ui.connection.GetServerInfo()
+		}
+		if info.serverErr == nil && info.server.OpenSubsonic {
+			if response, err := ui.connection.GetOpenSubsonicExtensions(); err != nil {
+				ui.logger.PrintError("GetOpenSubsonicExtensions", err)
+			} else {
+				info.extensions = response.OpenSubsonicExtensions
+			}
+		}
+
+		ui.app.QueueUpdateDraw(func() {
+			if info.serverErr == nil && ui.serverInfo == nil {
+				ui.serverInfo = info.server
+			}
+			if ui.aboutWidget.visible {
+				ui.aboutWidget.Root.SetText(info.text())
+			}
+		})
+	}()
+}
+
+func (ui *Ui) CloseAbout() {
+	ui.aboutWidget.visible = false
+	ui.pages.HidePage(PageAbout)
+	_, prim := ui.pages.GetFrontPage()
+	ui.app.SetFocus(prim)
+}
@@ -0,0 +1,354 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/cast"
+	"github.com/spezifisch/stmps/mpvplayer"
+)
+
+// how long to wait for media renderers to answer the search
+const castDiscoveryTimeout = 3 * time.Second
+
+// calls to the cast device that can wait before the UI has to, see castCall
+const castCallsSize = 16
+
+// CastWidget lists the media renderers on the network. Choosing one sends
+// playback there, the first entry switches back to local playback.
+type CastWidget struct {
+	Root *tview.List
+
+	devices []cast.Device
+
+	visible bool
+
+	// external refs
+	ui *Ui
+}
+
+func (ui *Ui) createCastWidget() (w *CastWidget) {
+	w = &CastWidget{
+		ui: ui,
+	}
+
+	w.Root = tview.NewList()
+	w.Root.Box.
+		SetTitle(" cast to ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+	w.Root.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			ui.CloseCast()
+			return nil
+		}
+		if event.Rune() == 'r' {
+			w.discover()
+			return nil
+		}
+		return event
+	})
+
+	return
+}
+
+// discover searches for devices in the background and fills the list.
+func (w *CastWidget) discover() {
+	w.devices = nil
+	w.updateList("Searching for devices...")
+
+	go func() {
+		devices, err := cast.Discover(castDiscoveryTimeout)
+		w.ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				w.ui.logger.PrintError("cast: discover", err)
+				w.updateList("Search failed, see log")
+				return
+			}
+			w.devices = devices
+			if len(devices) == 0 {
+				w.updateList("No devices found, r to search again")
+			} else {
+				w.updateList("")
+			}
+		})
+	}()
+}
+
+func (w *CastWidget) updateList(status string) {
+	w.Root.Clear()
+
+	local := "Local playback (mpv)"
+	if w.ui.castRenderer == nil {
+		local += " *"
+	}
+	w.Root.AddItem(local, "", '0', func() {
+		w.ui.CloseCast()
+		w.ui.stopCasting()
+	})
+
+	for i, device := range w.devices {
+		device := device
+		var shortcut rune
+		if i < 9 {
+			shortcut = rune('1' + i)
+		}
+		name := device.Name
+		if w.ui.castRenderer != nil && w.ui.castRenderer.Name() == device.Name {
+			name += " *"
+		}
+		w.Root.AddItem(name, device.Location, shortcut, func() {
+			w.ui.CloseCast()
+			w.ui.startCasting(device)
+		})
+	}
+
+	if status != "" {
+		w.Root.AddItem(status, "", 0, nil)
+	}
+}
+
+func (ui *Ui) ShowCast() {
+	ui.castWidget.discover()
+	ui.pages.ShowPage(PageCast)
+	ui.pages.SendToFront(PageCast)
+	ui.app.SetFocus(ui.castWidget.Root)
+	ui.castWidget.visible = true
+}
+
+func (ui *Ui) CloseCast() {
+	ui.castWidget.visible = false
+	ui.pages.HidePage(PageCast)
+	_, prim := ui.pages.GetFrontPage()
+	ui.app.SetFocus(prim)
+}
+
+// startCasting hands playback over to device. The current song continues
+// there at the same position, local playback is muted until casting stops.
+func (ui *Ui) startCasting(device cast.Device) {
+	ui.stopCasting()
+
+	renderer := cast.NewRenderer(device, ui.logger)
+	renderer.NextTrackHandler = func() error {
+		ui.app.QueueUpdateDraw(ui.castNextTrack)
+		return nil
+	}
+	renderer.PreviousTrackHandler = func() error {
+		ui.app.QueueUpdateDraw(ui.castPreviousTrack)
+		return nil
+	}
+	renderer.OnTrackEnd(func() {
+		ui.app.QueueUpdateDraw(ui.castNextTrack)
+	})
+	renderer.OnPlaying(func() {
+		ui.app.QueueUpdateDraw(func() {
+			ui.setPlaybackStatus(fmt.Sprintf("[green::b]Casting to %s[::-]", tview.Escape(renderer.Name())))
+		})
+	})
+	renderer.OnPaused(func() {
+		ui.app.QueueUpdateDraw(func() {
+			ui.setPlaybackStatus(fmt.Sprintf("[yellow::b]Paused on %s[::-]", tview.Escape(renderer.Name())))
+		})
+	})
+	renderer.OnStopped(func() {
+		ui.app.QueueUpdateDraw(func() {
+			ui.setPlaybackStatus(fmt.Sprintf("[red::b]Stopped on %s[::-]", tview.Escape(renderer.Name())))
+		})
+	})
+	renderer.OnSeek(func() {
+		ui.app.QueueUpdateDraw(func() {
+			if song, err := ui.player.GetQueueItem(0); err == nil {
+				ui.progressWidget.SetProgress(int64(renderer.GetTimePos()), int64(song.Duration))
+			}
+		})
+	})
+	ui.castRenderer = renderer
+	if ui.remotePlayer != nil {
+		ui.remotePlayer.Switch(renderer)
+	}
+	ui.castCalls = make(chan func(), castCallsSize)
+	go runCastCalls(ui.castCalls)
+
+	if volume, err := ui.player.GetVolume(); err == nil {
+		ui.castCall("SetVolume", func() error {
+			return renderer.SetVolume(volume)
+		}, nil)
+	}
+
+	// continue the current song on the device
+	position := 0
+	loaded, _ := ui.player.IsSongLoaded()
+	if loaded {
+		position = int(ui.player.GetTimePos())
+	}
+	if err := ui.player.SetMute(true); err != nil {
+		ui.logger.PrintError("cast: SetMute", err)
+	}
+	if loaded {
+		_ = ui.player.Stop()
+		ui.castQueueHead(position)
+	}
+
+	ui.showNotice("Casting to " + device.Name)
+}
+
+// stopCasting stops playback on the cast device and unmutes local playback.
+func (ui *Ui) stopCasting() {
+	if ui.castRenderer == nil {
+		return
+	}
+	renderer := ui.castRenderer
+	ui.castStop()
+	ui.castCall("Close", func() error {
+		renderer.Close()
+		return nil
+	}, nil)
+	close(ui.castCalls)
+	ui.castRenderer = nil
+	ui.castCalls = nil
+	if ui.remotePlayer != nil {
+		ui.remotePlayer.Switch(ui.player)
+	}
+
+	if err := ui.player.SetMute(false); err != nil {
+		ui.logger.PrintError("cast: SetMute", err)
+	}
+	ui.setPlaybackStatus("[red::b]Stopped[::-]")
+	ui.showNotice("Stopped casting to " + renderer.Name())
+}
+
+// runCastCalls makes the calls to a cast device one after the other, until
+// calls is closed.
+func runCastCalls(calls <-chan func()) {
+	for call := range calls {
+		call()
+	}
+}
+
+// castCall calls the cast device in the background, in order with the other
+// calls, so the UI doesn't wait for the network. Failures are logged. done
+// gets the result on the UI goroutine, it may be nil.
+func (ui *Ui) castCall(what string, call func() error, done func(err error)) {
+	ui.castCalls <- func() {
+		err := call()
+		if err != nil {
+			ui.logger.PrintError("cast: "+what, err)
+		}
+		if done != nil {
+			ui.app.QueueUpdateDraw(func() {
+				done(err)
+			})
+		}
+	}
+}
+
+// castQueueHead plays the first song of the queue on the cast device.
+func (ui *Ui) castQueueHead(position int) {
+	song, err := ui.player.GetQueueItem(0)
+	if err != nil {
+		return
+	}
+	renderer := ui.castRenderer
+	ui.castCall("Load", func() error {
+		return renderer.Load(song.Uri, song, position)
+	}, func(err error) {
+		if err != nil {
+			ui.showNotice("Casting failed: " + err.Error())
+			return
+		}
+		ui.progressWidget.SetProgress(int64(position), int64(song.Duration))
+		ui.waveformWidget.SetSong(song)
+		ui.queuePage.UpdateQueue()
+	})
+}
+
+// castNextTrack advances the queue and plays the next song on the cast
+// device.
+func (ui *Ui) castNextTrack() {
+	if ui.castRenderer == nil {
+		return
+	}
+	// mpv is idle while casting, so this only advances the queue
+	if err := ui.player.PlayNextTrack(); err != nil {
+		ui.logger.PrintError("cast: next", err)
+	}
+	ui.castQueueHead(0)
+}
+
+// castPreviousTrack restarts the song on the cast device, or plays the
+// previous one there near its start, see Player.PreviousTrack.
+func (ui *Ui) castPreviousTrack() {
+	if ui.castRenderer == nil {
+		return
+	}
+	if ui.player.PreviousRestarts(ui.castRenderer.GetTimePos()) || !ui.player.RestorePrevious() {
+		ui.castSeekTo(0)
+		return
+	}
+	ui.castQueueHead(0)
+}
+
+// castLocalSong takes over a song that was started locally, e.g. with play
+// now, while casting.
+func (ui *Ui) castLocalSong(song mpvplayer.QueueItem) {
+	if ui.castRenderer == nil || song.Id == "" {
+		return
+	}
+	_ = ui.player.Stop()
+	ui.castQueueHead(0)
+}
+
+// castTogglePause toggles playing/pause on the cast device, or starts the
+// queue there if nothing is loaded yet.
+func (ui *Ui) castTogglePause() {
+	playing, _ := ui.castRenderer.IsPlaying()
+	paused, _ := ui.castRenderer.IsPaused()
+	if !playing && !paused {
+		ui.castQueueHead(0)
+		return
+	}
+	renderer := ui.castRenderer
+	ui.castCall("TogglePlayPause", renderer.TogglePlayPause, nil)
+}
+
+// castStop stops playback on the cast device.
+func (ui *Ui) castStop() {
+	ui.castCall("Stop", ui.castRenderer.Stop, nil)
+}
+
+func (ui *Ui) castAdjustVolume(increment int) {
+	renderer := ui.castRenderer
+	volume := renderer.GetVolume()
+	if volume < 0 {
+		volume = defaultVolume
+	}
+	ui.castCall("SetVolume", func() error {
+		return renderer.SetVolume(volume + increment)
+	}, func(err error) {
+		if err != nil {
+			return
+		}
+		duration := 0
+		if song, err := ui.player.GetQueueItem(0); err == nil {
+			duration = song.Duration
+		}
+		ui.playerStatus.SetText(formatPlayerStatus(int64(renderer.GetVolume()), int64(renderer.GetTimePos()), int64(duration)))
+	})
+}
+
+func (ui *Ui) castSeek(increment int) {
+	ui.castSeekTo(int(ui.castRenderer.GetTimePos()) + increment)
+}
+
+// castSeekTo seeks to position seconds on the cast device.
+func (ui *Ui) castSeekTo(position int) {
+	renderer := ui.castRenderer
+	ui.castCall("Seek", func() error {
+		return renderer.SeekAbsolute(position)
+	}, nil)
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ContextMenuWidget lists the actions for the selected artist, album or song.
+type ContextMenuWidget struct {
+	Root *tview.List
+
+	visible bool
+	// runs the entry chosen with Enter
+	choose func(index int) error
+	// focused before the menu was shown
+	returnFocus tview.Primitive
+
+	// external refs
+	ui *Ui
+}
+
+func (ui *Ui) createContextMenuWidget() (w *ContextMenuWidget) {
+	w = &ContextMenuWidget{
+		ui: ui,
+	}
+
+	w.Root = tview.NewList().
+		ShowSecondaryText(false)
+	w.Root.Box.
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+	w.Root.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape:
+			ui.CloseContextMenu()
+			return nil
+		case tcell.KeyEnter:
+			w.chooseCurrent()
+			return nil
+		}
+		if event.Rune() == 'x' {
+			ui.CloseContextMenu()
+			return nil
+		}
+		return event
+	})
+
+	return
+}
+
+// showChoices shows the menu with the entries, choose runs the one picked.
+// Actions call it for a submenu.
+func (w *ContextMenuWidget) showChoices(title string, labels []string, choose func(index int) error) {
+	w.Root.Clear()
+	for _, label := range labels {
+		w.Root.AddItem(tview.Escape(label), "", 0, nil)
+	}
+	w.Root.SetTitle(" " + tview.Escape(title) + " ")
+	w.choose = choose
+
+	w.ui.pages.ShowPage(PageContextMenu)
+	w.ui.pages.SendToFront(PageContextMenu)
+	w.ui.app.SetFocus(w.Root)
+	w.visible = true
+}
+
+// chooseCurrent closes the menu and runs the selected entry, which may open
+// a submenu.
+func (w *ContextMenuWidget) chooseCurrent() {
+	index := w.Root.GetCurrentItem()
+	choose := w.choose
+	if choose == nil || index < 0 {
+		return
+	}
+	w.ui.CloseContextMenu()
+	if err := choose(index); err != nil {
+		w.ui.logger.PrintError("context menu", err)
+		w.ui.showNotice("Failed: " + err.Error())
+	}
+}
+
+// ShowContextMenu shows the actions for the item selected on the active
+// page. It returns false if there's no item to show them for.
+func (ui *Ui) ShowContextMenu() bool {
+	item, ok := ui.selectedContextItem()
+	if !ok {
+		return false
+	}
+
+	actions := ui.contextActionsFor(item)
+	labels := make([]string, len(actions))
+	for i, action := range actions {
+		labels[i] = action.label
+	}
+	ui.contextMenuWidget.returnFocus = ui.app.GetFocus()
+	ui.contextMenuWidget.showChoices(item.kind.String()+": "+item.name, labels, func(index int) error {
+		return actions[index].run(ui, item)
+	})
+	return true
+}
+
+// CloseContextMenu hides the menu and focuses the list it was opened on.
+func (ui *Ui) CloseContextMenu() {
+	ui.contextMenuWidget.visible = false
+	ui.contextMenuWidget.choose = nil
+	ui.pages.HidePage(PageContextMenu)
+	if ui.contextMenuWidget.returnFocus != nil {
+		ui.app.SetFocus(ui.contextMenuWidget.returnFocus)
+	} else {
+		_, prim := ui.pages.GetFrontPage()
+		ui.app.SetFocus(prim)
+	}
+}
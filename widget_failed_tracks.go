@@ -0,0 +1,83 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// FailedTracksWidget lists the songs that couldn't be played in this
+// session with the reason. Enter queues the selected song again, 'r' all of
+// them.
+type FailedTracksWidget struct {
+	Root *tview.List
+
+	visible bool
+
+	// external refs
+	ui *Ui
+}
+
+func (ui *Ui) createFailedTracksWidget() (w *FailedTracksWidget) {
+	w = &FailedTracksWidget{
+		ui: ui,
+	}
+
+	w.Root = tview.NewList().
+		ShowSecondaryText(false)
+	w.Root.Box.
+		SetTitle(" failed songs - enter: retry, r: retry all ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+	w.Root.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyEscape || event.Rune() == 'F':
+			ui.CloseFailedTracks()
+			return nil
+		case event.Rune() == 'r':
+			ui.retryFailedTracks(ui.failedTracks.takeAll())
+			w.updateList()
+			return nil
+		}
+		return event
+	})
+
+	return
+}
+
+func (w *FailedTracksWidget) updateList() {
+	current := w.Root.GetCurrentItem()
+	w.Root.Clear()
+
+	if len(w.ui.failedTracks.entries) == 0 {
+		w.Root.AddItem("[gray]No songs failed to play[-]", "", 0, nil)
+		return
+	}
+	for i, track := range w.ui.failedTracks.entries {
+		i := i
+		w.Root.AddItem(track.format(), "", 0, func() {
+			if track, ok := w.ui.failedTracks.take(i); ok {
+				w.ui.retryFailedTracks([]failedTrack{track})
+				w.updateList()
+			}
+		})
+	}
+	w.Root.SetCurrentItem(min(current, w.Root.GetItemCount()-1))
+}
+
+func (ui *Ui) ShowFailedTracks() {
+	ui.failedTracksWidget.updateList()
+	ui.pages.ShowPage(PageFailedTracks)
+	ui.pages.SendToFront(PageFailedTracks)
+	ui.app.SetFocus(ui.failedTracksWidget.Root)
+	ui.failedTracksWidget.visible = true
+}
+
+func (ui *Ui) CloseFailedTracks() {
+	ui.failedTracksWidget.visible = false
+	ui.pages.HidePage(PageFailedTracks)
+	_, prim := ui.pages.GetFrontPage()
+	ui.app.SetFocus(prim)
+}
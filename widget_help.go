@@ -64,6 +64,18 @@ func (h *HelpWidget) RenderHelp(context string) {
 	case PageSearch:
 		rightText = "[::b]Search[::-]\n" + tview.Escape(strings.TrimSpace(helpSearchPage))
 
+	case PageNew:
+		rightText = "[::b]Recently added[::-]\n" + tview.Escape(strings.TrimSpace(helpPageNew))
+
+	case PageStats:
+		rightText = "[::b]Stats[::-]\n" + tview.Escape(strings.TrimSpace(helpPageStats))
+
+	case PageDecades:
+		rightText = "[::b]Decades[::-]\n" + tview.Escape(strings.TrimSpace(helpPageDecades))
+
+	case PageStarred:
+		rightText = "[::b]Starred[::-]\n" + tview.Escape(strings.TrimSpace(helpPageStarred))
+
 	case PageLog:
 		fallthrough
 	default:
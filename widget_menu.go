@@ -26,19 +26,9 @@ type MenuWidget struct {
 	ui *Ui
 }
 
-const (
-	PAGE_BROWSER = iota
-	PAGE_QUEUE
-	PAGE_PLAYLISTS
-	PAGE_SEARCH
-	PAGE_LOG
-)
-
-var buttonOrder = []string{PageBrowser, PageQueue, PagePlaylists, PageSearch, PageLog}
-
 func (ui *Ui) createMenuWidget() (m *MenuWidget) {
 	m = &MenuWidget{
-		activeButton: buttonOrder[PAGE_BROWSER],
+		activeButton: ui.views[0],
 		buttons:      make(map[string]*tview.Button),
 
 		buttonStyle:     tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorWhite),
@@ -58,7 +48,7 @@ func (ui *Ui) createMenuWidget() (m *MenuWidget) {
 		SetStyle(m.buttonStyle).
 		SetActivatedStyle(m.quitActiveStyle).
 		SetSelectedFunc(func() {
-			ui.Quit()
+			ui.requestQuit()
 		})
 
 	helpButton := tview.NewButton("?: help").
@@ -84,8 +74,10 @@ func (ui *Ui) createMenuWidget() (m *MenuWidget) {
 	return
 }
 
+// createPageButtons adds a button for each enabled view, in the order of
+// ui.views.
 func (m *MenuWidget) createPageButtons() {
-	for i, page := range buttonOrder {
+	for i, page := range m.ui.views {
 		button := tview.NewButton(page)
 		button.SetStyle(m.buttonStyle)
 		// HACK because I couldn't find a way to un-focus a button after switching with 1,2,3,4 keys:
@@ -102,14 +94,14 @@ func (m *MenuWidget) createPageButtons() {
 		m.buttonsLeft.AddItem(button, 15, 0, false)
 
 		// add spacer
-		if i < len(buttonOrder)-1 {
+		if i < len(m.ui.views)-1 {
 			m.buttonsLeft.AddItem(nil, 1, 0, false)
 		}
 	}
 }
 
 func (m *MenuWidget) updatePageButtons() {
-	for i, page := range buttonOrder {
+	for i, page := range m.ui.views {
 		var text string
 		if page == m.activeButton {
 			text = fmt.Sprintf("%d: [::b]%s[::-]", i+1, page)
@@ -130,6 +122,16 @@ func (m *MenuWidget) SetActivePage(name string) {
 	m.updatePageButtons()
 }
 
+// pageNumber returns the number key of the page, 0 if it isn't enabled.
+func (m *MenuWidget) pageNumber(page string) int {
+	for i, view := range m.ui.views {
+		if view == page {
+			return i + 1
+		}
+	}
+	return 0
+}
+
 func (m *MenuWidget) GetActivePage() string {
 	return m.activeButton
 }
@@ -0,0 +1,69 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// NotificationsWidget lists the recent notices and errors, so messages that
+// flashed by in the status bar can be read again.
+type NotificationsWidget struct {
+	Root *tview.TextView
+
+	visible bool
+
+	// external refs
+	ui *Ui
+}
+
+func (ui *Ui) createNotificationsWidget() (w *NotificationsWidget) {
+	w = &NotificationsWidget{
+		ui: ui,
+	}
+
+	w.Root = tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(true)
+	w.Root.Box.
+		SetTitle(" notifications ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+	w.Root.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Rune() == 'E' {
+			ui.CloseNotifications()
+			return nil
+		}
+		return event
+	})
+
+	return
+}
+
+// update shows the notifications, scrolled to the newest one.
+func (w *NotificationsWidget) update() {
+	if len(w.ui.notifications.entries) == 0 {
+		w.Root.SetText("[gray]No notifications yet[-]")
+		return
+	}
+	w.Root.SetText(w.ui.notifications.text())
+	w.Root.ScrollToEnd()
+}
+
+func (ui *Ui) ShowNotifications() {
+	ui.notificationsWidget.update()
+	ui.pages.ShowPage(PageNotifications)
+	ui.pages.SendToFront(PageNotifications)
+	ui.app.SetFocus(ui.notificationsWidget.Root)
+	ui.notificationsWidget.visible = true
+}
+
+func (ui *Ui) CloseNotifications() {
+	ui.notificationsWidget.visible = false
+	ui.pages.HidePage(PageNotifications)
+	_, prim := ui.pages.GetFrontPage()
+	ui.app.SetFocus(prim)
+}
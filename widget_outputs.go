@@ -0,0 +1,130 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/mpvplayer"
+)
+
+// OutputsWidget lists mpv's audio devices. Choosing a device adds it to or
+// removes it from the outputs that play at the same time.
+type OutputsWidget struct {
+	Root *tview.List
+
+	devices []mpvplayer.AudioDevice
+	// latency offsets of the devices in the [[outputs]] config
+	offsets map[string]time.Duration
+
+	visible bool
+
+	// external refs
+	ui *Ui
+}
+
+func (ui *Ui) createOutputsWidget() (w *OutputsWidget) {
+	w = &OutputsWidget{
+		ui:      ui,
+		offsets: map[string]time.Duration{},
+	}
+	for _, output := range loadAudioOutputs() {
+		w.offsets[output.Device] = output.Offset
+	}
+
+	w.Root = tview.NewList()
+	w.Root.Box.
+		SetTitle(" audio outputs ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+	w.Root.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			ui.CloseOutputs()
+			return nil
+		}
+		return event
+	})
+
+	return
+}
+
+func (w *OutputsWidget) updateList() {
+	current := w.Root.GetCurrentItem()
+	w.Root.Clear()
+
+	devices, err := w.ui.player.AudioDevices()
+	if err != nil {
+		w.ui.logger.PrintError("outputs: AudioDevices", err)
+		w.Root.AddItem("Can't list audio devices, playing through the default one", "", 0, nil)
+		return
+	}
+	w.devices = devices
+
+	outputs := w.ui.player.Outputs()
+	for i, device := range devices {
+		device := device
+		mark := "[ ]"
+		secondary := device.Name
+		for j, output := range outputs {
+			if output.Device == device.Name {
+				mark = "[x[]"
+				if j == 0 {
+					secondary += ", main output"
+				}
+				if output.Offset > 0 {
+					secondary += fmt.Sprintf(", delayed %dms", output.Offset.Milliseconds())
+				}
+			}
+		}
+		var shortcut rune
+		if i < 9 {
+			shortcut = rune('1' + i)
+		}
+		w.Root.AddItem(mark+" "+tview.Escape(device.Description), tview.Escape(secondary), shortcut, func() {
+			w.toggle(device.Name)
+		})
+	}
+	w.Root.SetCurrentItem(current)
+}
+
+// toggle adds the device to the outputs or removes it. Without outputs the
+// default device is used.
+func (w *OutputsWidget) toggle(device string) {
+	var outputs []mpvplayer.AudioOutput
+	found := false
+	for _, output := range w.ui.player.Outputs() {
+		if output.Device == device {
+			found = true
+			continue
+		}
+		outputs = append(outputs, output)
+	}
+	if !found {
+		outputs = append(outputs, mpvplayer.AudioOutput{Device: device, Offset: w.offsets[device]})
+	}
+
+	if err := w.ui.player.SetOutputs(outputs); err != nil {
+		w.ui.logger.PrintError("outputs: SetOutputs", err)
+		w.ui.showNotice("Couldn't open an audio output, see log")
+	}
+	w.updateList()
+}
+
+func (ui *Ui) ShowOutputs() {
+	ui.outputsWidget.updateList()
+	ui.pages.ShowPage(PageOutputs)
+	ui.pages.SendToFront(PageOutputs)
+	ui.app.SetFocus(ui.outputsWidget.Root)
+	ui.outputsWidget.visible = true
+}
+
+func (ui *Ui) CloseOutputs() {
+	ui.outputsWidget.visible = false
+	ui.pages.HidePage(PageOutputs)
+	_, prim := ui.pages.GetFrontPage()
+	ui.app.SetFocus(prim)
+}
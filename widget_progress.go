@@ -0,0 +1,283 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ProgressWidget draws the playback position as a horizontal bar. It also
+// implements the seek preview mode: a seek cursor can be moved along the bar
+// (with the keyboard or by dragging with the mouse) and the target time is
+// shown before the seek is committed.
+type ProgressWidget struct {
+	*tview.Box
+
+	// last reported player state, in seconds
+	position int64
+	duration int64
+
+	// seek preview state
+	previewing      bool
+	previewPosition int64
+
+	barStyle     tcell.Style
+	playedStyle  tcell.Style
+	previewStyle tcell.Style
+
+	// external references
+	ui *Ui
+}
+
+func (ui *Ui) createProgressWidget() (p *ProgressWidget) {
+	p = &ProgressWidget{
+		Box: tview.NewBox(),
+
+		barStyle:     tcell.StyleDefault.Foreground(tcell.ColorGray),
+		playedStyle:  tcell.StyleDefault.Foreground(tcell.ColorWhite),
+		previewStyle: tcell.StyleDefault.Foreground(tcell.ColorYellow).Bold(true),
+
+		ui: ui,
+	}
+	return
+}
+
+// SetProgress updates the bar with the current player position. Must be
+// called from the gui goroutine.
+func (p *ProgressWidget) SetProgress(position, duration int64) {
+	if position < 0 {
+		position = 0
+	}
+	if duration < 0 {
+		duration = 0
+	}
+	p.position = position
+	p.duration = duration
+
+	if p.previewing && p.previewPosition > duration {
+		p.previewPosition = duration
+	}
+}
+
+// IsPreviewing reflects whether the seek preview mode is active.
+func (p *ProgressWidget) IsPreviewing() bool {
+	return p.previewing
+}
+
+// StartSeekPreview enters seek preview mode with the cursor at the current
+// playback position. Nothing happens if there's no track with a known length.
+func (p *ProgressWidget) StartSeekPreview() {
+	if p.duration <= 0 {
+		return
+	}
+	p.previewing = true
+	p.previewPosition = p.position
+}
+
+// CancelSeekPreview leaves seek preview mode without seeking.
+func (p *ProgressWidget) CancelSeekPreview() {
+	p.previewing = false
+}
+
+// CommitSeekPreview leaves seek preview mode and seeks to the cursor position.
+func (p *ProgressWidget) CommitSeekPreview() {
+	if !p.previewing {
+		return
+	}
+	p.previewing = false
+
+	if p.ui.castRenderer != nil {
+		p.ui.castSeekTo(int(p.previewPosition))
+	} else if err := p.ui.player.SeekAbsolute(int(p.previewPosition)); err != nil {
+		p.ui.logger.PrintError("CommitSeekPreview", err)
+		return
+	}
+	p.position = p.previewPosition
+}
+
+// moveSeekPreview moves the seek cursor by the given number of bar cells.
+func (p *ProgressWidget) moveSeekPreview(cells int) {
+	p.setSeekPreview(p.previewPosition + int64(cells)*p.secondsPerCell())
+}
+
+func (p *ProgressWidget) setSeekPreview(position int64) {
+	if position < 0 {
+		position = 0
+	} else if position > p.duration {
+		position = p.duration
+	}
+	p.previewPosition = position
+}
+
+// secondsPerCell returns how many seconds a single cell of the bar
+// represents, but at least one.
+func (p *ProgressWidget) secondsPerCell() int64 {
+	_, _, width := p.barRect()
+	if width <= 0 {
+		return 1
+	}
+	step := p.duration / int64(width)
+	if step < 1 {
+		step = 1
+	}
+	return step
+}
+
+// HandleSeekPreviewInput handles keys while the seek preview is active. All
+// keys are swallowed so that they don't trigger other actions by accident.
+func (p *ProgressWidget) HandleSeekPreviewInput(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Key() {
+	case tcell.KeyLeft:
+		p.moveSeekPreview(-1)
+	case tcell.KeyRight:
+		p.moveSeekPreview(1)
+	case tcell.KeyHome:
+		p.setSeekPreview(0)
+	case tcell.KeyEnd:
+		p.setSeekPreview(p.duration)
+	case tcell.KeyEnter:
+		p.CommitSeekPreview()
+	case tcell.KeyEscape:
+		p.CancelSeekPreview()
+	}
+	return nil
+}
+
+// barRect returns the x, y position and width of the bar itself, excluding
+// the time label.
+func (p *ProgressWidget) barRect() (int, int, int) {
+	x, y, width, _ := p.GetInnerRect()
+	labelWidth := len(p.label()) + 1
+	return x + labelWidth, y, width - labelWidth
+}
+
+func (p *ProgressWidget) label() string {
+	if p.previewing {
+		min, sec := secondsToMinAndSec(p.previewPosition)
+		return fmt.Sprintf("seek %02d:%02d", min, sec)
+	}
+	min, sec := secondsToMinAndSec(p.position)
+	if p.isLive() {
+		return fmt.Sprintf("live %02d:%02d", min, sec)
+	}
+	return fmt.Sprintf("     %02d:%02d", min, sec)
+}
+
+// isLive reflects whether a song of unknown length, e.g. a radio stream, is
+// playing. The bar can't show its progress.
+func (p *ProgressWidget) isLive() bool {
+	return p.duration <= 0 && p.position > 0
+}
+
+func (p *ProgressWidget) Draw(screen tcell.Screen) {
+	p.Box.DrawForSubclass(screen, p)
+
+	x, y, width, height := p.GetInnerRect()
+	if width <= 0 || height <= 0 {
+		return
+	}
+
+	labelColor := tcell.ColorWhite
+	if p.previewing {
+		labelColor = tcell.ColorYellow
+	}
+	tview.Print(screen, p.label(), x, y, width, tview.AlignLeft, labelColor)
+
+	barX, barY, barWidth := p.barRect()
+	if barWidth <= 0 {
+		return
+	}
+
+	played := playedCells(p.position, p.duration, barWidth)
+	cursor := -1
+	if p.previewing {
+		cursor = cellForPosition(p.previewPosition, p.duration, barWidth)
+	}
+
+	for i := 0; i < barWidth; i++ {
+		ch, style := '─', p.barStyle
+		if p.isLive() {
+			ch = '╌'
+		} else if i < played {
+			ch, style = '━', p.playedStyle
+		}
+		if i == cursor {
+			ch, style = '┃', p.previewStyle
+		}
+		screen.SetContent(barX+i, barY, ch, nil, style)
+	}
+}
+
+// cellForPosition maps a position to a cell index on a bar of the given width.
+func cellForPosition(position, duration int64, width int) int {
+	if duration <= 0 || width <= 0 {
+		return 0
+	}
+	cell := int(position * int64(width) / duration)
+	if cell >= width {
+		cell = width - 1
+	}
+	return cell
+}
+
+// playedCells returns how many cells of a bar of the given width are filled
+// at a position, all of them at the end of the song.
+func playedCells(position, duration int64, width int) int {
+	if duration <= 0 || width <= 0 {
+		return 0
+	}
+	return int(min(position, duration) * int64(width) / duration)
+}
+
+// positionForCell maps a cell index on a bar of the given width back to a
+// position.
+func positionForCell(cell, width int, duration int64) int64 {
+	if width <= 1 {
+		return 0
+	}
+	if cell < 0 {
+		cell = 0
+	} else if cell >= width {
+		cell = width - 1
+	}
+	return int64(cell) * duration / int64(width-1)
+}
+
+// MouseHandler starts the seek preview when the bar is clicked, moves the
+// cursor while dragging and commits the seek when the button is released.
+func (p *ProgressWidget) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+	return p.WrapMouseHandler(func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+		mouseX, mouseY := event.Position()
+		barX, _, barWidth := p.barRect()
+
+		switch action {
+		case tview.MouseLeftDown:
+			if !p.InRect(mouseX, mouseY) || p.duration <= 0 {
+				return false, nil
+			}
+			p.StartSeekPreview()
+			p.setSeekPreview(positionForCell(mouseX-barX, barWidth, p.duration))
+			return true, p
+
+		case tview.MouseMove:
+			if !p.previewing || event.Buttons()&tcell.Button1 == 0 {
+				return false, nil
+			}
+			p.setSeekPreview(positionForCell(mouseX-barX, barWidth, p.duration))
+			return true, p
+
+		case tview.MouseLeftUp:
+			if !p.previewing {
+				return false, nil
+			}
+			p.CommitSeekPreview()
+			return true, nil
+		}
+
+		return false, nil
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknownLengthProgress(t *testing.T) {
+	assert.Equal(t, "[80%][::b][01:05/03:20]", formatPlayerStatus(80, 65, 200))
+	assert.Equal(t, "[80%][::b][01:05 live]", formatPlayerStatus(80, 65, 0))
+	// nothing playing
+	assert.Equal(t, "[0%][::b][00:00/00:00]", formatPlayerStatus(0, 0, 0))
+
+	p := &ProgressWidget{}
+	p.SetProgress(65, 200)
+	assert.False(t, p.isLive())
+	assert.Equal(t, "     01:05", p.label())
+	p.SetProgress(65, 0)
+	assert.True(t, p.isLive())
+	assert.Equal(t, "live 01:05", p.label())
+	// no seek preview without a length
+	p.StartSeekPreview()
+	assert.False(t, p.IsPreviewing())
+}
+
+// drawProgress draws p on a screen of the given width and returns its line.
+func drawProgress(t *testing.T, p *ProgressWidget, width int) string {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer screen.Fini()
+	screen.SetSize(width, 1)
+
+	p.SetRect(0, 0, width, 1)
+	p.Draw(screen)
+
+	line := make([]rune, width)
+	for x := range line {
+		line[x], _, _, _ = screen.GetContent(x, 0)
+	}
+	return string(line)
+}
+
+func TestProgressRendering(t *testing.T) {
+	tests := []struct {
+		name     string
+		position int64
+		duration int64
+		label    string
+		line     string
+	}{
+		{"start", 0, 200, "     00:00", "     00:00 ──────────"},
+		{"middle", 100, 200, "     01:40", "     01:40 ━━━━━─────"},
+		{"end", 200, 200, "     03:20", "     03:20 ━━━━━━━━━━"},
+		{"past the end", 210, 200, "     03:30", "     03:30 ━━━━━━━━━━"},
+		{"unknown length", 65, 0, "live 01:05", "live 01:05 ╌╌╌╌╌╌╌╌╌╌"},
+		{"nothing playing", 0, 0, "     00:00", "     00:00 ──────────"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &ProgressWidget{Box: tview.NewBox()}
+			p.SetProgress(tt.position, tt.duration)
+			assert.Equal(t, tt.label, p.label())
+			assert.Equal(t, tt.line, drawProgress(t, p, 21))
+		})
+	}
+}
@@ -28,12 +28,11 @@ func (ui *Ui) createPlaylistSelectionWidget() (m *PlaylistSelectionWidget) {
 	m.overwrite = tview.NewCheckbox()
 	m.overwrite.SetDisabled(true)
 	m.overwriteEnabled = false
-	m.overwrite.SetLabel("Overwrite?").SetFieldTextColor(tcell.ColorBlack)
+	m.overwrite.SetLabel("Overwrite? (else a new one is created) ").SetFieldTextColor(tcell.ColorBlack)
 	m.overwrite.SetBackgroundColor(tcell.ColorGray)
 	m.overwrite.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Rune() == ' ' {
 			m.overwrite.SetChecked(!m.overwrite.IsChecked())
-			m.accept.SetDisabled(!m.overwrite.IsChecked())
 			return nil
 		}
 		return event
@@ -51,15 +50,8 @@ func (ui *Ui) createPlaylistSelectionWidget() (m *PlaylistSelectionWidget) {
 				exactMatch = true
 			}
 		}
-		if exactMatch {
-			m.overwrite.SetDisabled(false)
-			m.overwriteEnabled = true
-			m.accept.SetDisabled(!m.overwrite.IsChecked())
-		} else {
-			m.overwrite.SetDisabled(true)
-			m.overwriteEnabled = false
-			m.accept.SetDisabled(false)
-		}
+		m.overwrite.SetDisabled(!exactMatch)
+		m.overwriteEnabled = exactMatch
 		return rv
 	}).SetFieldTextColor(tcell.ColorBlack)
 	m.inputField.SetDoneFunc(func(key tcell.Key) {
@@ -80,25 +72,27 @@ func (ui *Ui) createPlaylistSelectionWidget() (m *PlaylistSelectionWidget) {
 	// 	m.overwriteEnabled = false
 	// 	return false
 	// })
+	reset := func() {
+		m.inputField.SetText("")
+		m.overwrite.SetDisabled(true)
+		m.overwriteEnabled = false
+		m.overwrite.SetChecked(false)
+	}
 	acceptFunc := func() {
-		inputText := m.inputField.GetText()
-		if !m.overwrite.IsChecked() {
-			for _, p := range ui.playlists {
-				if p.Name == inputText {
-					return
-				}
-			}
+		inputText := strings.TrimSpace(m.inputField.GetText())
+		if inputText == "" {
+			return
 		}
-		ui.queuePage.saveQueue(inputText)
+		// an existing playlist of that name is replaced if overwrite is
+		// checked, otherwise another one with the same name is created
+		overwrite := m.overwriteEnabled && m.overwrite.IsChecked()
+		reset()
 		ui.CloseSelectPlaylist()
+		ui.queuePage.saveQueue(inputText, overwrite)
 	}
 	m.accept.SetSelectedFunc(acceptFunc)
 	cancelFunc := func() {
-		m.inputField.SetText("")
-		m.overwrite.SetDisabled(true)
-		m.overwriteEnabled = false
-		m.overwrite.SetChecked(false)
-		m.accept.SetDisabled(!m.overwrite.IsChecked())
+		reset()
 		ui.CloseSelectPlaylist()
 	}
 	m.cancel.SetSelectedFunc(cancelFunc)
@@ -192,7 +186,6 @@ func (m *PlaylistSelectionWidget) focusNext(event *tcell.EventKey) *tcell.EventK
 			if p.Name == st {
 				m.overwrite.SetDisabled(false)
 				m.overwriteEnabled = true
-				m.accept.SetDisabled(!m.overwrite.IsChecked())
 				m.ui.app.SetFocus(m.overwrite)
 				found = true
 			}
@@ -200,15 +193,10 @@ func (m *PlaylistSelectionWidget) focusNext(event *tcell.EventKey) *tcell.EventK
 		if !found {
 			m.overwrite.SetDisabled(true)
 			m.overwriteEnabled = false
-			m.accept.SetDisabled(false)
 			m.ui.app.SetFocus(m.accept)
 		}
 	case m.overwrite:
-		if m.overwrite.IsChecked() {
-			m.ui.app.SetFocus(m.accept)
-		} else {
-			m.ui.app.SetFocus(m.cancel)
-		}
+		m.ui.app.SetFocus(m.accept)
 	case m.accept:
 		m.ui.app.SetFocus(m.cancel)
 	case m.cancel:
@@ -232,16 +220,7 @@ func (m PlaylistSelectionWidget) focusPrev(event *tcell.EventKey) *tcell.EventKe
 			m.ui.app.SetFocus(m.inputField)
 		}
 	case m.cancel:
-		// FIXME There's some bug in back-tabbing from cancel; _something_ is disabling the overwriteEnabled field, and I can't find it. Tabbing forward works fine, but tabbing backward fails to work properly when the playlist name matches an existing playlist
-		if m.overwriteEnabled {
-			if m.overwrite.IsChecked() {
-				m.ui.app.SetFocus(m.accept)
-			} else {
-				m.ui.app.SetFocus(m.overwrite)
-			}
-		} else {
-			m.ui.app.SetFocus(m.accept)
-		}
+		m.ui.app.SetFocus(m.accept)
 	default:
 		return event
 	}
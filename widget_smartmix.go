@@ -0,0 +1,313 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spf13/viper"
+)
+
+// SmartMixWidget is the smart mix builder: a list of the named mixes from the
+// config, which can be started with their number key, and a form to build a
+// mix from ad hoc filters or save it under a name.
+type SmartMixWidget struct {
+	Root *tview.Flex
+
+	mixList *tview.List
+	form    *tview.Form
+
+	nameField      *tview.InputField
+	genreField     *tview.InputField
+	fromYearField  *tview.InputField
+	toYearField    *tview.InputField
+	minRatingField *tview.InputField
+	notPlayedField *tview.InputField
+	countField     *tview.InputField
+
+	// genre names for autocompletion, fetched when the widget is first shown
+	genres        []string
+	genresFetched bool
+
+	visible bool
+
+	// external refs
+	ui *Ui
+}
+
+func (ui *Ui) createSmartMixWidget() (w *SmartMixWidget) {
+	w = &SmartMixWidget{
+		ui: ui,
+	}
+
+	w.mixList = tview.NewList()
+	w.mixList.Box.
+		SetTitle(" smart mixes ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+	w.mixList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab {
+			ui.app.SetFocus(w.form)
+			return nil
+		}
+		return event
+	})
+
+	w.nameField = tview.NewInputField().SetLabel("Name").SetFieldWidth(30)
+	w.genreField = tview.NewInputField().SetLabel("Genre").SetFieldWidth(30).
+		SetAutocompleteFunc(w.completeGenre)
+	w.fromYearField = tview.NewInputField().SetLabel("From year").SetFieldWidth(6).
+		SetAcceptanceFunc(tview.InputFieldInteger)
+	w.toYearField = tview.NewInputField().SetLabel("To year").SetFieldWidth(6).
+		SetAcceptanceFunc(tview.InputFieldInteger)
+	w.minRatingField = tview.NewInputField().SetLabel("Min rating").SetFieldWidth(2).
+		SetAcceptanceFunc(tview.InputFieldInteger)
+	w.notPlayedField = tview.NewInputField().SetLabel("Not played (months)").SetFieldWidth(4).
+		SetAcceptanceFunc(tview.InputFieldInteger)
+	w.countField = tview.NewInputField().SetLabel("Songs").SetFieldWidth(4).
+		SetAcceptanceFunc(tview.InputFieldInteger).
+		SetPlaceholder(strconv.Itoa(defaultSmartMixCount))
+
+	w.form = tview.NewForm().
+		AddButton("Build", w.handleBuild).
+		AddButton("Save", w.handleSave).
+		AddButton("Cancel", ui.CloseSmartMix)
+	w.form.Box.
+		SetTitle(" new mix ").
+		SetTitleAlign(tview.AlignLeft).
+		SetBorder(true)
+	w.form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// back to the list from the first field
+		if event.Key() == tcell.KeyBacktab {
+			if item, _ := w.form.GetFocusedItemIndex(); item == 0 {
+				ui.app.SetFocus(w.mixList)
+				return nil
+			}
+		}
+		return event
+	})
+
+	w.Root = tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(w.mixList, 0, 1, false).
+		AddItem(w.form, 0, 0, true)
+	w.Root.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape {
+			ui.CloseSmartMix()
+			return nil
+		}
+		return event
+	})
+
+	w.layoutForm(ui.playedDatesSupported())
+	w.updateMixList()
+	return
+}
+
+// layoutForm fills the form. The not played field is only shown for servers
+// that report when songs were played.
+func (w *SmartMixWidget) layoutForm(withPlayed bool) {
+	w.form.Clear(false)
+	items := []tview.FormItem{w.genreField, w.fromYearField, w.toYearField, w.minRatingField}
+	if withPlayed {
+		items = append(items, w.notPlayedField)
+	}
+	items = append(items, w.countField, w.nameField)
+	for _, item := range items {
+		w.form.AddFormItem(item)
+	}
+	// a row and a gap per item, the buttons and the border
+	w.Root.ResizeItem(w.form, 2*len(items)+3, 0)
+}
+
+// mixes returns the mixes of the list: the named ones from the config and,
+// if the server supports it, the neglected tracks mix.
+func (w *SmartMixWidget) mixes() []smartMix {
+	mixes := w.ui.smartMixes
+	if w.ui.playedDatesSupported() {
+		mixes = append(mixes[:len(mixes):len(mixes)], neglectedMix)
+	}
+	return mixes
+}
+
+// updateMixList shows the named mixes, the first nine get number shortcuts.
+func (w *SmartMixWidget) updateMixList() {
+	w.mixList.Clear()
+	mixes := w.mixes()
+	if len(mixes) == 0 {
+		w.mixList.AddItem("No saved mixes yet", "Fill in the form below and choose Save", 0, nil)
+		return
+	}
+
+	for i, mix := range mixes {
+		var shortcut rune
+		if i < 9 {
+			shortcut = rune('1' + i)
+		}
+		mix := mix
+		w.mixList.AddItem(tview.Escape(mix.Name), tview.Escape(mix.String()), shortcut, func() {
+			w.ui.CloseSmartMix()
+			w.ui.buildSmartMix(mix)
+		})
+	}
+}
+
+func (w *SmartMixWidget) completeGenre(current string) []string {
+	if current == "" {
+		return nil
+	}
+	var matches []string
+	for _, genre := range w.genres {
+		if strings.Contains(strings.ToLower(genre), strings.ToLower(current)) {
+			matches = append(matches, genre)
+		}
+	}
+	return matches
+}
+
+// fetchGenres loads the genre list for autocompletion in the background.
+func (w *SmartMixWidget) fetchGenres() {
+	if w.genresFetched {
+		return
+	}
+	w.genresFetched = true
+
+	go func() {
+		response, err := w.ui.connection.GetGenres()
+		if err != nil {
+			w.ui.logger.PrintError("fetchGenres", err)
+			return
+		}
+		genres := make([]string, 0, len(response.Genres.Genres))
+		for _, genre := range response.Genres.Genres {
+			genres = append(genres, genre.Value)
+		}
+		w.ui.app.QueueUpdate(func() {
+			w.genres = genres
+		})
+	}()
+}
+
+// formMix returns the mix described by the form.
+func (w *SmartMixWidget) formMix() (smartMix, error) {
+	mix := smartMix{
+		Name:  strings.TrimSpace(w.nameField.GetText()),
+		Genre: strings.TrimSpace(w.genreField.GetText()),
+	}
+
+	fields := []struct {
+		field *tview.InputField
+		value *int
+	}{
+		{w.fromYearField, &mix.FromYear},
+		{w.toYearField, &mix.ToYear},
+		{w.minRatingField, &mix.MinRating},
+		{w.notPlayedField, &mix.NotPlayedMonths},
+		{w.countField, &mix.Count},
+	}
+	for _, f := range fields {
+		text := strings.TrimSpace(f.field.GetText())
+		if text == "" {
+			continue
+		}
+		i, err := strconv.Atoi(text)
+		if err != nil {
+			return mix, fmt.Errorf("%s: %q is not a number", f.field.GetLabel(), text)
+		}
+		*f.value = i
+	}
+
+	return mix, mix.validate()
+}
+
+func (w *SmartMixWidget) handleBuild() {
+	mix, err := w.formMix()
+	if err != nil {
+		w.ui.showNotice(err.Error())
+		return
+	}
+	w.ui.CloseSmartMix()
+	w.ui.buildSmartMix(mix)
+}
+
+// handleSave appends the form's mix to the config file.
+func (w *SmartMixWidget) handleSave() {
+	mix, err := w.formMix()
+	if err != nil {
+		w.ui.showNotice(err.Error())
+		return
+	}
+	if mix.Name == "" {
+		w.ui.showNotice("Enter a name to save the mix")
+		return
+	}
+	for _, existing := range w.ui.smartMixes {
+		if existing.Name == mix.Name {
+			w.ui.showNotice(fmt.Sprintf("A mix named %s already exists", mix.Name))
+			return
+		}
+	}
+
+	if err := appendSmartMixToConfig(viper.ConfigFileUsed(), mix); err != nil {
+		w.ui.logger.PrintError("handleSave", err)
+		w.ui.showNotice("Failed to save the mix: " + err.Error())
+		return
+	}
+	w.ui.smartMixes = append(w.ui.smartMixes, mix)
+	w.updateMixList()
+	w.ui.showNotice(fmt.Sprintf("Saved mix %s", mix.Name))
+}
+
+func (ui *Ui) ShowSmartMix() {
+	ui.smartMixWidget.fetchGenres()
+	ui.pages.ShowPage(PageSmartMix)
+	ui.pages.SendToFront(PageSmartMix)
+	if len(ui.smartMixWidget.mixes()) > 0 {
+		ui.app.SetFocus(ui.smartMixWidget.mixList)
+	} else {
+		ui.app.SetFocus(ui.smartMixWidget.form)
+	}
+	ui.smartMixWidget.visible = true
+}
+
+func (ui *Ui) CloseSmartMix() {
+	ui.smartMixWidget.visible = false
+	ui.pages.HidePage(PageSmartMix)
+	_, prim := ui.pages.GetFrontPage()
+	ui.app.SetFocus(prim)
+}
+
+// buildSmartMix fetches the songs of a mix in the background and adds them to
+// the queue.
+func (ui *Ui) buildSmartMix(mix smartMix) {
+	ui.showNotice("Building smart mix: " + mix.String())
+
+	go func() {
+		songs, err := fetchSmartMixSongs(ui.connection, mix, ui.contentFilter)
+		ui.app.QueueUpdateDraw(func() {
+			if err != nil {
+				ui.logger.PrintError("buildSmartMix", err)
+				ui.showNotice("Failed to build the smart mix")
+				return
+			}
+			if len(songs) == 0 {
+				ui.showNotice("No songs match the smart mix")
+				return
+			}
+
+			ui.startQueueAdd(queueAppend)
+			ui.setQueueSource(queueSourceRadio)
+			for i := range songs {
+				ui.addSongToQueue(&songs[i])
+			}
+			if report := ui.finishQueueAdd(); report.skipped == 0 {
+				ui.showNotice(fmt.Sprintf("Added %d songs to the queue", report.added))
+			}
+		})
+	}()
+}
@@ -0,0 +1,214 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"context"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/spezifisch/stmps/mpvplayer"
+)
+
+const defaultWaveformHeight = 2
+
+// vertical eighth blocks, index 0 is an empty cell
+var waveformBlocks = []rune{' ', '▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'}
+
+// WaveformWidget draws a peak overview of the current track above the
+// progress bar, with the played part and the playhead highlighted. Peaks are
+// computed in the background and cached per song ID for the session.
+type WaveformWidget struct {
+	*tview.Box
+
+	enabled bool
+	height  int
+
+	songId   string
+	songUri  string
+	peaks    []float64
+	loading  bool
+	message  string // shown instead of the waveform, e.g. errors
+	position int64
+	duration int64
+
+	cache  map[string][]float64
+	cancel context.CancelFunc
+
+	playedStyle   tcell.Style
+	unplayedStyle tcell.Style
+	playheadStyle tcell.Style
+
+	// external references
+	ui *Ui
+}
+
+func (ui *Ui) createWaveformWidget(enabled bool, height int) *WaveformWidget {
+	if height <= 0 {
+		height = defaultWaveformHeight
+	}
+	return &WaveformWidget{
+		Box: tview.NewBox(),
+
+		enabled: enabled,
+		height:  height,
+		cache:   map[string][]float64{},
+
+		playedStyle:   tcell.StyleDefault.Foreground(tcell.ColorWhite),
+		unplayedStyle: tcell.StyleDefault.Foreground(tcell.ColorGray),
+		playheadStyle: tcell.StyleDefault.Foreground(tcell.ColorYellow),
+
+		ui: ui,
+	}
+}
+
+// Height returns the number of rows used in the layout, 0 while disabled.
+func (w *WaveformWidget) Height() int {
+	if !w.enabled {
+		return 0
+	}
+	return w.height
+}
+
+// Toggle shows or hides the waveform. Must be called from the gui goroutine.
+func (w *WaveformWidget) Toggle() {
+	w.enabled = !w.enabled
+	if w.enabled {
+		w.load()
+	} else {
+		w.stopLoading()
+	}
+}
+
+func (w *WaveformWidget) IsEnabled() bool {
+	return w.enabled
+}
+
+// SetSong switches the waveform to a new track. Must be called from the gui
+// goroutine.
+func (w *WaveformWidget) SetSong(item mpvplayer.QueueItem) {
+	if item.Id == w.songId {
+		return
+	}
+	w.stopLoading()
+	w.songId = item.Id
+	w.songUri = item.Uri
+	w.peaks = nil
+	w.message = ""
+	w.load()
+}
+
+// ClearSong removes the waveform when playback stops.
+func (w *WaveformWidget) ClearSong() {
+	w.stopLoading()
+	w.songId = ""
+	w.songUri = ""
+	w.peaks = nil
+	w.message = ""
+}
+
+func (w *WaveformWidget) SetProgress(position, duration int64) {
+	w.position = position
+	w.duration = duration
+}
+
+func (w *WaveformWidget) stopLoading() {
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+	w.loading = false
+}
+
+// load gets the peaks of the current song from the cache or starts computing
+// them. Nothing is computed while the widget is hidden.
+func (w *WaveformWidget) load() {
+	if !w.enabled || w.songId == "" || w.peaks != nil || w.loading {
+		return
+	}
+	if peaks, ok := w.cache[w.songId]; ok {
+		w.peaks = peaks
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.loading = true
+	w.message = ""
+
+	songId, songUri := w.songId, w.songUri
+	go func() {
+		peaks, err := computePeaks(ctx, songUri, waveformPeakCount)
+		if ctx.Err() != nil {
+			// superseded by another song or hidden
+			return
+		}
+		w.ui.app.QueueUpdateDraw(func() {
+			if songId != w.songId {
+				return
+			}
+			w.loading = false
+			w.cancel = nil
+			if err != nil {
+				w.ui.logger.PrintError("waveform", err)
+				w.message = "waveform unavailable: " + err.Error()
+				return
+			}
+			w.cache[songId] = peaks
+			w.peaks = peaks
+		})
+		cancel()
+	}()
+}
+
+func (w *WaveformWidget) Draw(screen tcell.Screen) {
+	w.Box.DrawForSubclass(screen, w)
+
+	x, y, width, height := w.GetInnerRect()
+	if width <= 0 || height <= 0 {
+		return
+	}
+
+	switch {
+	case w.loading:
+		tview.Print(screen, "computing waveform...", x, y+height-1, width, tview.AlignCenter, tcell.ColorGray)
+		return
+	case w.message != "":
+		tview.Print(screen, tview.Escape(w.message), x, y+height-1, width, tview.AlignCenter, tcell.ColorGray)
+		return
+	case w.peaks == nil:
+		return
+	}
+
+	cells := resamplePeaks(w.peaks, width)
+	played := cellForPosition(w.position, w.duration, width)
+	eighths := len(waveformBlocks) - 1
+
+	for i, level := range cells {
+		style := w.unplayedStyle
+		switch {
+		case w.duration > 0 && i == played:
+			style = w.playheadStyle
+		case w.duration > 0 && i < played:
+			style = w.playedStyle
+		}
+
+		// fill the column bottom-up, at least one eighth so quiet parts
+		// stay visible
+		filled := int(level*float64(height*eighths) + 0.5)
+		if filled < 1 {
+			filled = 1
+		}
+		for row := 0; row < height; row++ {
+			rest := filled - row*eighths
+			if rest <= 0 {
+				break
+			}
+			if rest > eighths {
+				rest = eighths
+			}
+			screen.SetContent(x+i, y+height-1-row, waveformBlocks[rest], nil, style)
+		}
+	}
+}
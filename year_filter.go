@@ -0,0 +1,117 @@
+// Copyright 2023 The STMPS Authors
+// SPDX-License-Identifier: GPL-3.0-only
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spezifisch/stmps/subsonic"
+)
+
+// yearFilter hides albums and songs of the browser outside a range of
+// years. Entries without a year are hidden too while it's on.
+type yearFilter struct {
+	// zero for an open end, both zero turns the filter off
+	from int
+	to   int
+}
+
+// parseYearFilter parses a year ("1994"), a range ("1990-1999") or an open
+// range ("1990-", "-1979"). An empty text turns the filter off.
+func parseYearFilter(text string) (yearFilter, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return yearFilter{}, nil
+	}
+
+	parseYear := func(s string) (int, error) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return 0, nil
+		}
+		year, err := strconv.Atoi(s)
+		if err != nil || year < 1 || year > 9999 {
+			return 0, fmt.Errorf("not a year: %s", s)
+		}
+		return year, nil
+	}
+
+	fromText, toText, isRange := strings.Cut(text, "-")
+	from, err := parseYear(fromText)
+	if err != nil {
+		return yearFilter{}, err
+	}
+	if !isRange {
+		return yearFilter{from: from, to: from}, nil
+	}
+	to, err := parseYear(toText)
+	if err != nil {
+		return yearFilter{}, err
+	}
+	if from == 0 && to == 0 {
+		return yearFilter{}, fmt.Errorf("not a year range: %s", text)
+	}
+	if to != 0 && from > to {
+		from, to = to, from
+	}
+	return yearFilter{from: from, to: to}, nil
+}
+
+func (f yearFilter) isOff() bool {
+	return f.from == 0 && f.to == 0
+}
+
+func (f yearFilter) allows(entity subsonic.SubsonicEntity) bool {
+	if f.isOff() {
+		return true
+	}
+	if entity.Year == 0 {
+		return false
+	}
+	return (f.from == 0 || entity.Year >= f.from) && (f.to == 0 || entity.Year <= f.to)
+}
+
+// filter returns the entities the filter allows.
+func (f yearFilter) filter(entities []subsonic.SubsonicEntity) []subsonic.SubsonicEntity {
+	if f.isOff() {
+		return entities
+	}
+	var allowed []subsonic.SubsonicEntity
+	for _, entity := range entities {
+		if f.allows(entity) {
+			allowed = append(allowed, entity)
+		}
+	}
+	return allowed
+}
+
+// input returns the filter as it's typed in, see parseYearFilter.
+func (f yearFilter) input() string {
+	year := func(year int) string {
+		if year == 0 {
+			return ""
+		}
+		return strconv.Itoa(year)
+	}
+	if f.from == f.to {
+		return year(f.from)
+	}
+	return year(f.from) + "-" + year(f.to)
+}
+
+func (f yearFilter) String() string {
+	switch {
+	case f.isOff():
+		return "all years"
+	case f.from == f.to:
+		return strconv.Itoa(f.from)
+	case f.from == 0:
+		return fmt.Sprintf("until %d", f.to)
+	case f.to == 0:
+		return fmt.Sprintf("from %d", f.from)
+	}
+	return fmt.Sprintf("%d-%d", f.from, f.to)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spezifisch/stmps/subsonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseYearFilter(t *testing.T) {
+	for text, expected := range map[string]yearFilter{
+		"":          {},
+		"1994":      {from: 1994, to: 1994},
+		"1990-1999": {from: 1990, to: 1999},
+		"1999-1990": {from: 1990, to: 1999},
+		" 1990 - ":  {from: 1990},
+		"-1979":     {to: 1979},
+	} {
+		filter, err := parseYearFilter(text)
+		assert.NoError(t, err, text)
+		assert.Equal(t, expected, filter, text)
+		// typed in again it's the same filter
+		again, err := parseYearFilter(filter.input())
+		assert.NoError(t, err, text)
+		assert.Equal(t, filter, again, text)
+	}
+
+	for _, text := range []string{"nineties", "-", "1990-x", "0"} {
+		_, err := parseYearFilter(text)
+		assert.Error(t, err, text)
+	}
+}
+
+func TestYearFilter(t *testing.T) {
+	entities := []subsonic.SubsonicEntity{
+		{Id: "1", Year: 1985},
+		{Id: "2", Year: 1994},
+		{Id: "3"},
+		{Id: "4", Year: 2003, IsDirectory: true},
+	}
+	ids := func(entities []subsonic.SubsonicEntity) (ids []string) {
+		for _, entity := range entities {
+			ids = append(ids, entity.Id)
+		}
+		return
+	}
+
+	assert.Equal(t, []string{"1", "2", "3", "4"}, ids(yearFilter{}.filter(entities)))
+	assert.Equal(t, []string{"2", "4"}, ids(yearFilter{from: 1990}.filter(entities)))
+	assert.Equal(t, []string{"1", "2"}, ids(yearFilter{from: 1980, to: 1999}.filter(entities)))
+	assert.Equal(t, "1980-1999", yearFilter{from: 1980, to: 1999}.String())
+	assert.Equal(t, "until 1979", yearFilter{to: 1979}.String())
+}